@@ -0,0 +1,126 @@
+package httphelpers
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"reflect"
+	"strconv"
+	"strings"
+
+	"github.com/bombsimon/http-helpers/bind"
+)
+
+// BindError is returned when a request can't be bound, e.g. if the body isn't
+// valid JSON or a query parameter has the wrong type.
+type BindError struct {
+	Err error
+}
+
+func (e *BindError) Error() string {
+	return fmt.Sprintf("invalid request: %s", e.Err)
+}
+
+func (e *BindError) Unwrap() error {
+	return e.Err
+}
+
+// BindJSON decodes the JSON request body into v with bind.JSON. Unknown fields
+// are not allowed and the body is limited to bind.DefaultMaxBytes.
+func BindJSON(r *http.Request, v interface{}) error {
+	if err := bind.JSON(r, v, bind.WithDisallowUnknownFields()); err != nil {
+		return &BindError{Err: err}
+	}
+
+	return nil
+}
+
+// BindQuery sets the fields of the struct pointed to by v from the query
+// parameters. The parameter name is taken from the `query` struct tag and
+// fields without the tag are ignored. Strings, booleans, numbers and slices of
+// them are supported.
+func BindQuery(r *http.Request, v interface{}) error {
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Pointer || rv.Elem().Kind() != reflect.Struct {
+		return errors.New("BindQuery requires a pointer to a struct")
+	}
+
+	rv = rv.Elem()
+	query := r.URL.Query()
+
+	for i := 0; i < rv.NumField(); i++ {
+		field := rv.Type().Field(i)
+
+		name, _, _ := strings.Cut(field.Tag.Get("query"), ",")
+		if name == "" || name == "-" || !field.IsExported() {
+			continue
+		}
+
+		values, ok := query[name]
+		if !ok || len(values) == 0 {
+			continue
+		}
+
+		if err := setValue(rv.Field(i), values); err != nil {
+			return &BindError{Err: fmt.Errorf("query parameter %s: %w", name, err)}
+		}
+	}
+
+	return nil
+}
+
+func setValue(v reflect.Value, values []string) error {
+	if v.Kind() != reflect.Slice {
+		return setScalar(v, values[0])
+	}
+
+	slice := reflect.MakeSlice(v.Type(), len(values), len(values))
+	for i, value := range values {
+		if err := setScalar(slice.Index(i), value); err != nil {
+			return err
+		}
+	}
+
+	v.Set(slice)
+
+	return nil
+}
+
+func setScalar(v reflect.Value, value string) error {
+	switch v.Kind() {
+	case reflect.String:
+		v.SetString(value)
+	case reflect.Bool:
+		b, err := strconv.ParseBool(value)
+		if err != nil {
+			return err
+		}
+
+		v.SetBool(b)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		n, err := strconv.ParseInt(value, 10, v.Type().Bits())
+		if err != nil {
+			return err
+		}
+
+		v.SetInt(n)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		n, err := strconv.ParseUint(value, 10, v.Type().Bits())
+		if err != nil {
+			return err
+		}
+
+		v.SetUint(n)
+	case reflect.Float32, reflect.Float64:
+		f, err := strconv.ParseFloat(value, v.Type().Bits())
+		if err != nil {
+			return err
+		}
+
+		v.SetFloat(f)
+	default:
+		return fmt.Errorf("unsupported type %s", v.Type())
+	}
+
+	return nil
+}
@@ -0,0 +1,131 @@
+package bind
+
+/*
+Helpers to bind request bodies with size limits and error messages that can be
+returned to the client.
+
+	var req CreateUserRequest
+	if err := bind.JSON(r, &req, bind.WithDisallowUnknownFields()); err != nil {
+		var bindErr *bind.Error
+		errors.As(err, &bindErr)
+
+		http.Error(w, bindErr.Message, bindErr.Status)
+		return
+	}
+*/
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+)
+
+// DefaultMaxBytes is the default maximum size of the request body.
+const DefaultMaxBytes = 1 << 20
+
+// Error is returned when a request can't be bound. The message is safe to
+// return to the client.
+type Error struct {
+	Status  int
+	Message string
+	Err     error
+}
+
+func (e *Error) Error() string {
+	return e.Message
+}
+
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+// JSON decodes the JSON request body into v. If the request has a content
+// type it must be application/json, or 415 Unsupported Media Type is
+// returned. All errors are of type *Error with a status and a message
+// describing what's wrong with the body.
+func JSON(r *http.Request, v interface{}, opts ...Option) error {
+	options := newOptions(opts...)
+
+	if ct := r.Header.Get("Content-Type"); ct != "" {
+		if err := requireContentType(ct, "application/json"); err != nil {
+			return err
+		}
+	}
+
+	if r.Body == nil {
+		return badRequest("request body is empty", io.EOF)
+	}
+
+	decoder := json.NewDecoder(http.MaxBytesReader(nil, r.Body, options.maxBodySize(r.Context())))
+
+	if options.disallowUnknownFields {
+		decoder.DisallowUnknownFields()
+	}
+
+	if err := decoder.Decode(v); err != nil {
+		return decodeError(err)
+	}
+
+	if decoder.More() {
+		return badRequest("request body must only contain a single JSON value", nil)
+	}
+
+	return nil
+}
+
+func decodeError(err error) error {
+	var (
+		syntaxErr   *json.SyntaxError
+		typeErr     *json.UnmarshalTypeError
+		maxBytesErr *http.MaxBytesError
+	)
+
+	switch {
+	case errors.As(err, &syntaxErr):
+		return badRequest(fmt.Sprintf("malformed JSON at position %d", syntaxErr.Offset), err)
+	case errors.As(err, &typeErr):
+		if typeErr.Field == "" {
+			return badRequest(fmt.Sprintf("body must be %s, got %s", typeErr.Type, typeErr.Value), err)
+		}
+
+		return badRequest(fmt.Sprintf("field %s must be %s, got %s", typeErr.Field, typeErr.Type, typeErr.Value), err)
+	case errors.As(err, &maxBytesErr):
+		return &Error{
+			Status:  http.StatusRequestEntityTooLarge,
+			Message: fmt.Sprintf("request body must not be larger than %d bytes", maxBytesErr.Limit),
+			Err:     err,
+		}
+	case errors.Is(err, io.EOF):
+		return badRequest("request body is empty", err)
+	case errors.Is(err, io.ErrUnexpectedEOF):
+		return badRequest("malformed JSON", err)
+	}
+
+	// The JSON decoder doesn't have a typed error for unknown fields.
+	var field string
+	if _, scanErr := fmt.Sscanf(err.Error(), "json: unknown field %q", &field); scanErr == nil {
+		return badRequest(fmt.Sprintf("unknown field %s", field), err)
+	}
+
+	return badRequest(err.Error(), err)
+}
+
+func requireContentType(contentType, expected string) error {
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil || mediaType != expected {
+		return &Error{
+			Status:  http.StatusUnsupportedMediaType,
+			Message: fmt.Sprintf("content type must be %s, got %s", expected, contentType),
+			Err:     err,
+		}
+	}
+
+	return nil
+}
+
+func badRequest(message string, err error) error {
+	return &Error{Status: http.StatusBadRequest, Message: message, Err: err}
+}
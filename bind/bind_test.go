@@ -0,0 +1,134 @@
+package bind
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/bombsimon/http-helpers/httpctx"
+)
+
+type user struct {
+	Name string `json:"name"`
+	Age  int    `json:"age"`
+}
+
+func Test_JSON(t *testing.T) {
+	cases := []struct {
+		description     string
+		body            string
+		contentType     string
+		opts            []Option
+		expectedStatus  int
+		expectedMessage string
+	}{
+		{
+			description: "valid",
+			body:        `{"name":"bob","age":42,"extra":true}`,
+		},
+		{
+			description:     "unknown field",
+			body:            `{"name":"bob","extra":true}`,
+			opts:            []Option{WithDisallowUnknownFields()},
+			expectedStatus:  http.StatusBadRequest,
+			expectedMessage: "unknown field extra",
+		},
+		{
+			description:     "wrong type",
+			body:            `{"age":"old"}`,
+			expectedStatus:  http.StatusBadRequest,
+			expectedMessage: "field age must be int, got string",
+		},
+		{
+			description:     "syntax error",
+			body:            `{"name":}`,
+			expectedStatus:  http.StatusBadRequest,
+			expectedMessage: "malformed JSON at position 9",
+		},
+		{
+			description:     "truncated",
+			body:            `{"name":"bob"`,
+			expectedStatus:  http.StatusBadRequest,
+			expectedMessage: "malformed JSON",
+		},
+		{
+			description:     "empty",
+			expectedStatus:  http.StatusBadRequest,
+			expectedMessage: "request body is empty",
+		},
+		{
+			description:     "multiple values",
+			body:            `{"name":"bob"}{"name":"alice"}`,
+			expectedStatus:  http.StatusBadRequest,
+			expectedMessage: "request body must only contain a single JSON value",
+		},
+		{
+			description:     "too large",
+			body:            `{"name":"` + strings.Repeat("a", 100) + `"}`,
+			opts:            []Option{WithMaxBytes(10)},
+			expectedStatus:  http.StatusRequestEntityTooLarge,
+			expectedMessage: "request body must not be larger than 10 bytes",
+		},
+		{
+			description:     "wrong content type",
+			body:            `{"name":"bob"}`,
+			contentType:     "text/plain",
+			expectedStatus:  http.StatusUnsupportedMediaType,
+			expectedMessage: "content type must be application/json, got text/plain",
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.description, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(tc.body))
+			if tc.contentType != "" {
+				req.Header.Set("Content-Type", tc.contentType)
+			}
+
+			var u user
+
+			err := JSON(req, &u, tc.opts...)
+			if tc.expectedStatus == 0 {
+				if err != nil {
+					t.Fatalf("unexpected error: %s", err)
+				}
+
+				if u.Name != "bob" || u.Age != 42 {
+					t.Fatalf("unexpected user: %+v", u)
+				}
+
+				return
+			}
+
+			var bindErr *Error
+			if !errors.As(err, &bindErr) {
+				t.Fatalf("expected *Error, got: %v", err)
+			}
+
+			if bindErr.Status != tc.expectedStatus || bindErr.Message != tc.expectedMessage {
+				t.Fatalf("unexpected error, got: %d %s, expected: %d %s", bindErr.Status, bindErr.Message, tc.expectedStatus, tc.expectedMessage)
+			}
+		})
+	}
+}
+
+func Test_JSONMaxBodySizeFromContext(t *testing.T) {
+	r := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(`{"name":"bob"}`))
+	r = r.WithContext(httpctx.WithMaxBodySize(r.Context(), 5))
+
+	var u user
+
+	var bindErr *Error
+	if err := JSON(r, &u); !errors.As(err, &bindErr) || bindErr.Status != http.StatusRequestEntityTooLarge {
+		t.Fatalf("expected limit from context, got: %v", err)
+	}
+
+	r = httptest.NewRequest(http.MethodPost, "/", strings.NewReader(`{"name":"bob"}`))
+	r = r.WithContext(httpctx.WithMaxBodySize(r.Context(), 5))
+
+	if err := JSON(r, &u, WithMaxBytes(100)); err != nil {
+		t.Fatalf("WithMaxBytes should override the context, got: %v", err)
+	}
+}
@@ -0,0 +1,91 @@
+package bind
+
+import (
+	"bufio"
+	"context"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"net"
+	"strings"
+)
+
+// clamAVChunkSize is the size of each chunk streamed to clamd.
+const clamAVChunkSize = 32 << 10
+
+// ClamAV returns a Scanner streaming the content to clamd with the INSTREAM
+// command. The network and address are passed to net.Dial, e.g. "tcp" and
+// "localhost:3310" or "unix" and "/run/clamav/clamd.ctl".
+func ClamAV(network, address string) Scanner {
+	return ScannerFunc(func(ctx context.Context, _ string, content io.Reader) error {
+		var dialer net.Dialer
+
+		conn, err := dialer.DialContext(ctx, network, address)
+		if err != nil {
+			return err
+		}
+
+		defer conn.Close()
+
+		if deadline, ok := ctx.Deadline(); ok {
+			_ = conn.SetDeadline(deadline)
+		}
+
+		if _, err := io.WriteString(conn, "zINSTREAM\x00"); err != nil {
+			return err
+		}
+
+		buf := make([]byte, clamAVChunkSize)
+		size := make([]byte, 4)
+
+		for {
+			n, err := content.Read(buf)
+			if n > 0 {
+				binary.BigEndian.PutUint32(size, uint32(n))
+
+				if _, err := conn.Write(size); err != nil {
+					return err
+				}
+
+				if _, err := conn.Write(buf[:n]); err != nil {
+					return err
+				}
+			}
+
+			if err == io.EOF {
+				break
+			}
+
+			if err != nil {
+				return err
+			}
+		}
+
+		// A zero length chunk ends the stream.
+		if _, err := conn.Write([]byte{0, 0, 0, 0}); err != nil {
+			return err
+		}
+
+		reply, err := bufio.NewReader(conn).ReadString(0)
+		if err != nil && err != io.EOF {
+			return err
+		}
+
+		return parseClamAVReply(strings.TrimRight(reply, "\x00\n"))
+	})
+}
+
+// parseClamAVReply parses replies such as "stream: OK" and
+// "stream: Eicar-Signature FOUND".
+func parseClamAVReply(reply string) error {
+	result := strings.TrimPrefix(reply, "stream: ")
+
+	switch {
+	case result == "OK":
+		return nil
+	case strings.HasSuffix(result, " FOUND"):
+		return &InfectedError{Signature: strings.TrimSuffix(result, " FOUND")}
+	default:
+		return fmt.Errorf("clamav: %s", reply)
+	}
+}
@@ -0,0 +1,57 @@
+package bind
+
+import (
+	"errors"
+	"fmt"
+	"mime/multipart"
+	"net/http"
+)
+
+// Multipart parses the multipart form in the request body, limited to
+// WithMaxBytes, and returns it. Files are validated with ValidateUpload if a
+// policy is set with WithUploadPolicy. If a Scanner is set with WithScanner
+// each file is scanned and infected files are rejected with 422 Unprocessable
+// Entity. All errors are of type *Error.
+func Multipart(r *http.Request, opts ...Option) (*multipart.Form, error) {
+	options := newOptions(opts...)
+
+	if err := requireContentType(r.Header.Get("Content-Type"), "multipart/form-data"); err != nil {
+		return nil, err
+	}
+
+	r.Body = http.MaxBytesReader(nil, r.Body, options.maxBodySize(r.Context()))
+
+	if err := r.ParseMultipartForm(options.maxMemory); err != nil {
+		var maxBytesErr *http.MaxBytesError
+		if errors.As(err, &maxBytesErr) {
+			return nil, decodeError(err)
+		}
+
+		return nil, badRequest(fmt.Sprintf("malformed multipart form: %s", err), err)
+	}
+
+	for field, files := range r.MultipartForm.File {
+		for _, file := range files {
+			if err := checkFile(r, field, file, options); err != nil {
+				_ = r.MultipartForm.RemoveAll()
+				return nil, err
+			}
+		}
+	}
+
+	return r.MultipartForm, nil
+}
+
+func checkFile(r *http.Request, field string, file *multipart.FileHeader, options *options) error {
+	if options.uploadPolicy != nil {
+		if err := validateFile(file, *options.uploadPolicy); err != nil {
+			return err
+		}
+	}
+
+	if options.scanner != nil {
+		return scanFile(r, field, file, options)
+	}
+
+	return nil
+}
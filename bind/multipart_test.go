@@ -0,0 +1,175 @@
+package bind
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"errors"
+	"io"
+	"mime/multipart"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func multipartRequest(t *testing.T, files map[string]string) *http.Request {
+	t.Helper()
+
+	body := &bytes.Buffer{}
+	writer := multipart.NewWriter(body)
+
+	for name, content := range files {
+		part, err := writer.CreateFormFile("file", name)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		_, _ = io.WriteString(part, content)
+	}
+
+	_ = writer.WriteField("title", "hello")
+	_ = writer.Close()
+
+	req := httptest.NewRequest(http.MethodPost, "/", body)
+	req.Header.Set("Content-Type", writer.FormDataContentType())
+
+	return req
+}
+
+func Test_Multipart(t *testing.T) {
+	scanner := ScannerFunc(func(_ context.Context, _ string, content io.Reader) error {
+		b, err := io.ReadAll(content)
+		if err != nil {
+			return err
+		}
+
+		if strings.Contains(string(b), "EICAR") {
+			return &InfectedError{Signature: "Eicar-Signature"}
+		}
+
+		return nil
+	})
+
+	var events []ScanEvent
+
+	audit := WithScanAudit(func(_ context.Context, event ScanEvent) {
+		events = append(events, event)
+	})
+
+	form, err := Multipart(multipartRequest(t, map[string]string{"a.txt": "hello"}), WithScanner(scanner), audit)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	if form.Value["title"][0] != "hello" || form.File["file"][0].Filename != "a.txt" {
+		t.Fatalf("unexpected form: %+v", form)
+	}
+
+	_, err = Multipart(multipartRequest(t, map[string]string{"virus.txt": "EICAR"}), WithScanner(scanner), audit)
+
+	var bindErr *Error
+	if !errors.As(err, &bindErr) || bindErr.Status != http.StatusUnprocessableEntity {
+		t.Fatalf("expected infected file to be rejected, got: %v", err)
+	}
+
+	if len(events) != 2 || events[0].Infected || !events[1].Infected || events[1].Signature != "Eicar-Signature" || events[1].Field != "file" {
+		t.Fatalf("unexpected audit events: %+v", events)
+	}
+
+	_, err = Multipart(multipartRequest(t, map[string]string{"a.txt": strings.Repeat("a", 100)}), WithMaxBytes(50))
+	if !errors.As(err, &bindErr) || bindErr.Status != http.StatusRequestEntityTooLarge {
+		t.Fatalf("expected too large form to be rejected, got: %v", err)
+	}
+
+	_, err = Multipart(httptest.NewRequest(http.MethodPost, "/", nil))
+	if !errors.As(err, &bindErr) || bindErr.Status != http.StatusUnsupportedMediaType {
+		t.Fatalf("expected non multipart request to be rejected, got: %v", err)
+	}
+}
+
+func Test_ClamAV(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer listener.Close()
+
+	// A fake clamd reading the INSTREAM chunks.
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+
+			go func() {
+				defer conn.Close()
+
+				cmd := make([]byte, len("zINSTREAM\x00"))
+				if _, err := io.ReadFull(conn, cmd); err != nil {
+					return
+				}
+
+				var content []byte
+
+				for {
+					var size uint32
+					if err := binary.Read(conn, binary.BigEndian, &size); err != nil || size == 0 {
+						break
+					}
+
+					chunk := make([]byte, size)
+					if _, err := io.ReadFull(conn, chunk); err != nil {
+						return
+					}
+
+					content = append(content, chunk...)
+				}
+
+				reply := "stream: OK\x00"
+				if bytes.Contains(content, []byte("EICAR")) {
+					reply = "stream: Eicar-Signature FOUND\x00"
+				}
+
+				_, _ = io.WriteString(conn, reply)
+			}()
+		}
+	}()
+
+	scanner := ClamAV("tcp", listener.Addr().String())
+
+	if err := scanner.Scan(context.Background(), "a.txt", strings.NewReader(strings.Repeat("a", 100<<10))); err != nil {
+		t.Fatalf("unexpected error for clean file: %s", err)
+	}
+
+	err = scanner.Scan(context.Background(), "virus.txt", strings.NewReader("EICAR"))
+
+	var infectedErr *InfectedError
+	if !errors.As(err, &infectedErr) || infectedErr.Signature != "Eicar-Signature" {
+		t.Fatalf("expected infected error, got: %v", err)
+	}
+}
+
+func Test_MultipartUploadPolicy(t *testing.T) {
+	policy := WithUploadPolicy(UploadPolicy{AllowedTypes: []string{"image/png"}})
+
+	form, err := Multipart(multipartRequest(t, map[string]string{"../cat.png": string(pngHeader)}), policy)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	file := form.File["file"][0]
+	if file.Filename != "cat.png" || file.Header.Get("Content-Type") != "image/png" {
+		t.Fatalf("unexpected file header: %s %v", file.Filename, file.Header)
+	}
+
+	_, err = Multipart(multipartRequest(t, map[string]string{"cat.png": "<html></html>"}), policy)
+
+	var bindErr *Error
+	if !errors.As(err, &bindErr) || bindErr.Status != http.StatusUnprocessableEntity {
+		t.Fatalf("expected mismatching file to be rejected, got: %v", err)
+	}
+}
@@ -0,0 +1,93 @@
+package bind
+
+import (
+	"context"
+
+	"github.com/bombsimon/http-helpers/httpctx"
+)
+
+// Option is an option used to configure the binding.
+type Option func(*options)
+
+type options struct {
+	maxBytes              int64
+	maxMemory             int64
+	disallowUnknownFields bool
+	uploadPolicy          *UploadPolicy
+	scanner               Scanner
+	scanAudit             func(ctx context.Context, event ScanEvent)
+}
+
+func newOptions(opts ...Option) *options {
+	o := &options{
+		maxMemory: 32 << 20,
+	}
+
+	for _, opt := range opts {
+		opt(o)
+	}
+
+	return o
+}
+
+// WithMaxBytes sets the maximum size of the request body. Larger bodies are
+// rejected with 413 Request Entity Too Large. Defaults to the size set with
+// httpctx.WithMaxBodySize, e.g. by the openapi middleware, or DefaultMaxBytes.
+func WithMaxBytes(n int64) Option {
+	return func(o *options) {
+		o.maxBytes = n
+	}
+}
+
+// WithDisallowUnknownFields rejects bodies with fields not in the destination
+// struct.
+func WithDisallowUnknownFields() Option {
+	return func(o *options) {
+		o.disallowUnknownFields = true
+	}
+}
+
+// WithMaxMemory sets how much of a multipart form is kept in memory, the rest
+// is stored in temporary files. Defaults to 32 MiB.
+func WithMaxMemory(n int64) Option {
+	return func(o *options) {
+		o.maxMemory = n
+	}
+}
+
+// WithUploadPolicy validates each file in Multipart with ValidateUpload. The
+// filename and Content-Type of each file header are replaced with the
+// sanitized filename and the validated content type.
+func WithUploadPolicy(policy UploadPolicy) Option {
+	return func(o *options) {
+		o.uploadPolicy = &policy
+	}
+}
+
+// WithScanner sets the scanner used to scan uploaded files in Multipart.
+func WithScanner(scanner Scanner) Option {
+	return func(o *options) {
+		o.scanner = scanner
+	}
+}
+
+// WithScanAudit sets a function called with the result of each file scanned,
+// e.g. to write an audit log of rejected uploads.
+func WithScanAudit(fn func(ctx context.Context, event ScanEvent)) Option {
+	return func(o *options) {
+		o.scanAudit = fn
+	}
+}
+
+// maxBodySize returns the maximum size of the request body.
+func (o *options) maxBodySize(ctx context.Context) int64 {
+	if o.maxBytes > 0 {
+		return o.maxBytes
+	}
+
+	if n, ok := httpctx.MaxBodySize(ctx); ok {
+		return n
+	}
+
+	return DefaultMaxBytes
+}
@@ -0,0 +1,94 @@
+package bind
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/http"
+)
+
+// Scanner scans uploaded content, e.g. for viruses. Scan should return an
+// *InfectedError if the content must be rejected. Any other error is treated as
+// a failure to scan and the upload is rejected with 500 Internal Server Error.
+type Scanner interface {
+	Scan(ctx context.Context, filename string, content io.Reader) error
+}
+
+// ScannerFunc is a function implementing Scanner.
+type ScannerFunc func(ctx context.Context, filename string, content io.Reader) error
+
+// Scan calls fn.
+func (fn ScannerFunc) Scan(ctx context.Context, filename string, content io.Reader) error {
+	return fn(ctx, filename, content)
+}
+
+// InfectedError is returned by a Scanner when the content is infected.
+type InfectedError struct {
+	Signature string
+}
+
+func (e *InfectedError) Error() string {
+	return fmt.Sprintf("infected with %s", e.Signature)
+}
+
+// ScanEvent describes the result of scanning an uploaded file.
+type ScanEvent struct {
+	Field     string
+	Filename  string
+	Size      int64
+	Infected  bool
+	Signature string
+	Err       error
+}
+
+func scanFile(r *http.Request, field string, file *multipart.FileHeader, options *options) error {
+	event := ScanEvent{
+		Field:    field,
+		Filename: file.Filename,
+		Size:     file.Size,
+	}
+
+	err := scan(r.Context(), file, options.scanner)
+
+	var infectedErr *InfectedError
+	if errors.As(err, &infectedErr) {
+		event.Infected = true
+		event.Signature = infectedErr.Signature
+	} else {
+		event.Err = err
+	}
+
+	if options.scanAudit != nil {
+		options.scanAudit(r.Context(), event)
+	}
+
+	switch {
+	case event.Infected:
+		return &Error{
+			Status:  http.StatusUnprocessableEntity,
+			Message: fmt.Sprintf("file %s was rejected by the content scanner", file.Filename),
+			Err:     err,
+		}
+	case err != nil:
+		return &Error{
+			Status:  http.StatusInternalServerError,
+			Message: fmt.Sprintf("file %s could not be scanned", file.Filename),
+			Err:     err,
+		}
+	}
+
+	return nil
+}
+
+func scan(ctx context.Context, file *multipart.FileHeader, scanner Scanner) error {
+	f, err := file.Open()
+	if err != nil {
+		return err
+	}
+
+	defer f.Close()
+
+	return scanner.Scan(ctx, file.Filename, f)
+}
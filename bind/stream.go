@@ -0,0 +1,284 @@
+package bind
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path"
+)
+
+// errFileTooLarge is returned to the sink when a file is larger than the
+// MaxSize of the upload policy.
+var errFileTooLarge = errors.New("bind: file too large")
+
+// BlobSink stores uploaded files streamed by StreamMultipart, e.g. on disk or
+// in S3-compatible object storage.
+type BlobSink interface {
+	// Put stores the content and returns the location of the stored file,
+	// e.g. a path or an object key. The content is streamed from the request
+	// so Put must fail if reading it fails.
+	Put(ctx context.Context, upload *Upload, content io.Reader) (string, error)
+
+	// Delete removes the file at the location returned by Put.
+	Delete(ctx context.Context, location string) error
+}
+
+// DiskSink is a BlobSink storing files in a directory with random names
+// keeping the extension of the uploaded file.
+type DiskSink struct {
+	Dir string
+}
+
+// Put writes the content to a new file in the directory.
+func (s DiskSink) Put(_ context.Context, upload *Upload, content io.Reader) (string, error) {
+	f, err := os.CreateTemp(s.Dir, "upload-*"+path.Ext(upload.Filename))
+	if err != nil {
+		return "", err
+	}
+
+	_, err = io.Copy(f, content)
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+
+	if err != nil {
+		_ = os.Remove(f.Name())
+		return "", err
+	}
+
+	return f.Name(), nil
+}
+
+// Delete removes the file.
+func (s DiskSink) Delete(_ context.Context, location string) error {
+	return os.Remove(location)
+}
+
+// StoredFile is a file stored by StreamMultipart.
+type StoredFile struct {
+	Upload
+
+	// Size is the size of the file in bytes.
+	Size int64
+
+	// Location is the location returned by the sink.
+	Location string
+}
+
+// StreamedForm is a multipart form with files stored in a BlobSink.
+type StreamedForm struct {
+	Value map[string][]string
+	File  map[string][]*StoredFile
+}
+
+// Remove deletes all files in the form from the sink, e.g. if the request
+// fails after the form has been streamed.
+func (f *StreamedForm) Remove(ctx context.Context, sink BlobSink) error {
+	var errs []error
+
+	for _, files := range f.File {
+		for _, file := range files {
+			errs = append(errs, sink.Delete(ctx, file.Location))
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+// StreamMultipart reads the multipart form in the request body part by part
+// and streams each file to the sink instead of buffering it in memory or in
+// temporary files like Multipart. The content type of each file is sniffed
+// from the start of the content and validated with the policy set with
+// WithUploadPolicy before the file is stored, files of types that aren't
+// allowed are rejected with 415 Unsupported Media Type and files larger than
+// the MaxSize of the policy with 413 Request Entity Too Large. The whole body
+// is limited to WithMaxBytes and the form values to WithMaxMemory, both with
+// 413 Request Entity Too Large. Files are not scanned with WithScanner, scan
+// them in the sink instead.
+//
+// If the request fails all files already stored are deleted from the sink.
+// All errors are of type *Error.
+func StreamMultipart(r *http.Request, sink BlobSink, opts ...Option) (*StreamedForm, error) {
+	options := newOptions(opts...)
+	ctx := r.Context()
+
+	if err := requireContentType(r.Header.Get("Content-Type"), "multipart/form-data"); err != nil {
+		return nil, err
+	}
+
+	body := &trackingReader{ReadCloser: http.MaxBytesReader(nil, r.Body, options.maxBodySize(ctx))}
+	r.Body = body
+
+	reader, err := r.MultipartReader()
+	if err != nil {
+		return nil, badRequest(fmt.Sprintf("malformed multipart form: %s", err), err)
+	}
+
+	var (
+		form = &StreamedForm{
+			Value: map[string][]string{},
+			File:  map[string][]*StoredFile{},
+		}
+		policy     UploadPolicy
+		valueBytes int64
+	)
+
+	if options.uploadPolicy != nil {
+		policy = *options.uploadPolicy
+	}
+
+	fail := func(err error) (*StreamedForm, error) {
+		_ = form.Remove(ctx, sink)
+		return nil, err
+	}
+
+	for {
+		part, err := reader.NextPart()
+		if errors.Is(err, io.EOF) {
+			return form, nil
+		}
+
+		if err != nil {
+			return fail(readError(err))
+		}
+
+		field := part.FormName()
+
+		if part.FileName() == "" {
+			value, err := io.ReadAll(io.LimitReader(part, options.maxMemory-valueBytes+1))
+			if err != nil {
+				return fail(readError(err))
+			}
+
+			if valueBytes += int64(len(value)); valueBytes > options.maxMemory {
+				return fail(&Error{
+					Status:  http.StatusRequestEntityTooLarge,
+					Message: fmt.Sprintf("form values must not be larger than %d bytes", options.maxMemory),
+				})
+			}
+
+			form.Value[field] = append(form.Value[field], string(value))
+
+			continue
+		}
+
+		file, err := storePart(ctx, part.FileName(), part.Header.Get("Content-Type"), part, sink, policy, body)
+		if err != nil {
+			return fail(err)
+		}
+
+		form.File[field] = append(form.File[field], file)
+	}
+}
+
+// storePart validates the file and streams it to the sink.
+func storePart(
+	ctx context.Context,
+	filename, declaredType string,
+	content io.Reader,
+	sink BlobSink,
+	policy UploadPolicy,
+	body *trackingReader,
+) (*StoredFile, error) {
+	filename = SanitizeFilename(filename)
+
+	head := make([]byte, 512)
+
+	n, err := io.ReadFull(content, head)
+	if err != nil && !errors.Is(err, io.EOF) && !errors.Is(err, io.ErrUnexpectedEOF) {
+		return nil, readError(err)
+	}
+
+	upload, err := checkUpload(filename, declaredType, mediaType(http.DetectContentType(head[:n])), policy)
+	if err != nil {
+		return nil, err
+	}
+
+	file := &sizeReader{
+		Reader:  io.MultiReader(bytes.NewReader(head[:n]), content),
+		maxSize: policy.MaxSize,
+	}
+
+	location, err := sink.Put(ctx, upload, file)
+
+	switch {
+	case file.tooLarge:
+		if err == nil {
+			_ = sink.Delete(ctx, location)
+		}
+
+		return nil, &Error{
+			Status:  http.StatusRequestEntityTooLarge,
+			Message: fmt.Sprintf("file %s must not be larger than %d bytes", filename, policy.MaxSize),
+			Err:     errFileTooLarge,
+		}
+	case body.err != nil:
+		// The sink may not wrap the error, use the error from the body.
+		if err == nil {
+			_ = sink.Delete(ctx, location)
+		}
+
+		return nil, readError(body.err)
+	case err != nil:
+		return nil, &Error{
+			Status:  http.StatusInternalServerError,
+			Message: fmt.Sprintf("file %s could not be stored", filename),
+			Err:     err,
+		}
+	}
+
+	return &StoredFile{Upload: *upload, Size: file.n, Location: location}, nil
+}
+
+// readError returns an error for a failure to read the multipart body.
+func readError(err error) error {
+	var maxBytesErr *http.MaxBytesError
+	if errors.As(err, &maxBytesErr) {
+		return decodeError(err)
+	}
+
+	return badRequest(fmt.Sprintf("malformed multipart form: %s", err), err)
+}
+
+// trackingReader keeps the first error other than io.EOF from reading the
+// request body.
+type trackingReader struct {
+	io.ReadCloser
+
+	err error
+}
+
+func (r *trackingReader) Read(p []byte) (int, error) {
+	n, err := r.ReadCloser.Read(p)
+	if err != nil && !errors.Is(err, io.EOF) && r.err == nil {
+		r.err = err
+	}
+
+	return n, err
+}
+
+// sizeReader counts the bytes read and fails once more than maxSize bytes are
+// read, if maxSize is set.
+type sizeReader struct {
+	io.Reader
+
+	maxSize  int64
+	n        int64
+	tooLarge bool
+}
+
+func (r *sizeReader) Read(p []byte) (int, error) {
+	n, err := r.Reader.Read(p)
+	r.n += int64(n)
+
+	if r.maxSize > 0 && r.n > r.maxSize {
+		r.tooLarge = true
+		return n, errFileTooLarge
+	}
+
+	return n, err
+}
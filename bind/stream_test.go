@@ -0,0 +1,92 @@
+package bind
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func Test_StreamMultipart(t *testing.T) {
+	policy := WithUploadPolicy(UploadPolicy{AllowedTypes: []string{"image/png"}, MaxSize: 1 << 10})
+
+	t.Run("stored", func(t *testing.T) {
+		sink := DiskSink{Dir: t.TempDir()}
+
+		form, err := StreamMultipart(multipartRequest(t, map[string]string{"../cat.png": string(pngHeader)}), sink, policy)
+		if err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+
+		file := form.File["file"][0]
+		if file.Filename != "cat.png" || file.ContentType != "image/png" || file.Size != int64(len(pngHeader)) {
+			t.Fatalf("unexpected file: %+v", file)
+		}
+
+		if filepath.Dir(file.Location) != sink.Dir || filepath.Ext(file.Location) != ".png" {
+			t.Fatalf("unexpected location: %s", file.Location)
+		}
+
+		if content, _ := os.ReadFile(file.Location); string(content) != string(pngHeader) {
+			t.Fatalf("unexpected content: %q", content)
+		}
+
+		if form.Value["title"][0] != "hello" {
+			t.Fatalf("unexpected values: %v", form.Value)
+		}
+
+		if err := form.Remove(context.Background(), sink); err != nil {
+			t.Fatalf("unexpected error removing files: %s", err)
+		}
+	})
+
+	for _, tc := range []struct {
+		description    string
+		files          map[string]string
+		opts           []Option
+		expectedStatus int
+	}{
+		{
+			description:    "unsupported type",
+			files:          map[string]string{"cat.png": string(pngHeader), "doc.pdf": "%PDF-1.4"},
+			opts:           []Option{policy},
+			expectedStatus: http.StatusUnsupportedMediaType,
+		},
+		{
+			description:    "file too large",
+			files:          map[string]string{"cat.png": string(pngHeader) + strings.Repeat("x", 2<<10)},
+			opts:           []Option{policy},
+			expectedStatus: http.StatusRequestEntityTooLarge,
+		},
+		{
+			description:    "body too large",
+			files:          map[string]string{"cat.txt": strings.Repeat("x", 2<<10)},
+			opts:           []Option{WithMaxBytes(1 << 10)},
+			expectedStatus: http.StatusRequestEntityTooLarge,
+		},
+		{
+			description:    "values too large",
+			files:          map[string]string{},
+			opts:           []Option{WithMaxMemory(2)},
+			expectedStatus: http.StatusRequestEntityTooLarge,
+		},
+	} {
+		t.Run(tc.description, func(t *testing.T) {
+			sink := DiskSink{Dir: t.TempDir()}
+
+			_, err := StreamMultipart(multipartRequest(t, tc.files), sink, tc.opts...)
+
+			var bindErr *Error
+			if !errors.As(err, &bindErr) || bindErr.Status != tc.expectedStatus {
+				t.Fatalf("expected status %d, got: %v", tc.expectedStatus, err)
+			}
+
+			if entries, _ := os.ReadDir(sink.Dir); len(entries) != 0 {
+				t.Fatalf("expected stored files to be removed, got: %v", entries)
+			}
+		})
+	}
+}
@@ -0,0 +1,250 @@
+package bind
+
+import (
+	"fmt"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net/http"
+	"path"
+	"slices"
+	"strings"
+	"unicode"
+)
+
+// maxFilenameLength is the longest filename returned by ValidateUpload.
+const maxFilenameLength = 255
+
+// UploadPolicy describes which uploads ValidateUpload accepts.
+type UploadPolicy struct {
+	// AllowedTypes are the allowed media types. A type may end with "/*" to
+	// allow all subtypes, e.g. "image/*". All types are allowed if empty.
+	AllowedTypes []string
+
+	// AllowedExtensions are the allowed filename extensions, including the
+	// dot, e.g. ".png". All extensions are allowed if empty.
+	AllowedExtensions []string
+
+	// MaxSize is the maximum size of the file in bytes. There's no limit if
+	// zero.
+	MaxSize int64
+}
+
+// Upload is an upload validated by ValidateUpload.
+type Upload struct {
+	// Filename is the sanitized filename without any path.
+	Filename string
+
+	// ContentType is the media type of the content. It's the sniffed type
+	// unless the content only could be sniffed as a generic type, such as
+	// text/plain, in which case the declared type is used.
+	ContentType string
+}
+
+// ValidateUpload sniffs the content type from the content and compares it to
+// the declared Content-Type and the filename extension before checking it
+// against the policy. Mismatches are rejected with 422 Unprocessable Entity,
+// types and extensions not allowed by the policy with 415 Unsupported Media
+// Type and too large files with 413 Request Entity Too Large. The content is
+// rewound after sniffing. All errors are of type *Error.
+func ValidateUpload(header *multipart.FileHeader, content io.ReadSeeker, policy UploadPolicy) (*Upload, error) {
+	filename := SanitizeFilename(header.Filename)
+
+	if policy.MaxSize > 0 && header.Size > policy.MaxSize {
+		return nil, &Error{
+			Status:  http.StatusRequestEntityTooLarge,
+			Message: fmt.Sprintf("file %s must not be larger than %d bytes", filename, policy.MaxSize),
+		}
+	}
+
+	sniffed, err := sniff(content)
+	if err != nil {
+		return nil, badRequest(fmt.Sprintf("could not read file %s", filename), err)
+	}
+
+	return checkUpload(filename, header.Header.Get("Content-Type"), sniffed, policy)
+}
+
+// checkUpload checks the sniffed and declared content types and the filename
+// extension against each other and the policy.
+func checkUpload(filename, declaredType, sniffed string, policy UploadPolicy) (*Upload, error) {
+	contentType := sniffed
+
+	if declared := mediaType(declaredType); declared != "" && declared != "application/octet-stream" {
+		if !compatible(sniffed, declared) {
+			return nil, unprocessable("file %s is %s but was declared as %s", filename, sniffed, declared)
+		}
+
+		contentType = declared
+	}
+
+	ext := strings.ToLower(path.Ext(filename))
+
+	if extType := mediaType(mime.TypeByExtension(ext)); extType != "" && !compatible(contentType, extType) && !compatible(sniffed, extType) {
+		return nil, unprocessable("file %s is %s which doesn't match the extension %s", filename, contentType, ext)
+	}
+
+	if len(policy.AllowedTypes) > 0 && !slices.ContainsFunc(policy.AllowedTypes, func(allowed string) bool {
+		return matchesType(allowed, contentType)
+	}) {
+		return nil, &Error{
+			Status:  http.StatusUnsupportedMediaType,
+			Message: fmt.Sprintf("file %s has unsupported type %s", filename, contentType),
+		}
+	}
+
+	if len(policy.AllowedExtensions) > 0 && !slices.ContainsFunc(policy.AllowedExtensions, func(allowed string) bool {
+		return strings.EqualFold(allowed, ext)
+	}) {
+		return nil, &Error{
+			Status:  http.StatusUnsupportedMediaType,
+			Message: fmt.Sprintf("file %s has unsupported extension %s", filename, ext),
+		}
+	}
+
+	return &Upload{Filename: filename, ContentType: contentType}, nil
+}
+
+// validateFile validates the file with ValidateUpload and updates the header
+// with the sanitized filename and content type.
+func validateFile(file *multipart.FileHeader, policy UploadPolicy) error {
+	f, err := file.Open()
+	if err != nil {
+		return badRequest(fmt.Sprintf("could not read file %s", file.Filename), err)
+	}
+
+	defer f.Close()
+
+	upload, err := ValidateUpload(file, f, policy)
+	if err != nil {
+		return err
+	}
+
+	file.Filename = upload.Filename
+	file.Header.Set("Content-Type", upload.ContentType)
+
+	return nil
+}
+
+// SanitizeFilename returns the filename without any path, control characters
+// or characters that are unsafe in file systems and headers. Leading dots are
+// removed so the file can't be hidden. If nothing is left "file" is returned.
+func SanitizeFilename(filename string) string {
+	// Clients on Windows may send the full path with backslashes.
+	filename = path.Base(strings.ReplaceAll(filename, `\`, "/"))
+
+	filename = strings.Map(func(r rune) rune {
+		switch {
+		case unicode.IsLetter(r), unicode.IsDigit(r), strings.ContainsRune("._- ", r):
+			return r
+		default:
+			return '_'
+		}
+	}, filename)
+
+	filename = strings.TrimLeft(filename, ". ")
+	filename = strings.TrimRight(filename, ". ")
+
+	if len(filename) > maxFilenameLength {
+		ext := path.Ext(filename)
+		if len(ext) > 16 {
+			ext = ""
+		}
+
+		filename = strings.ToValidUTF8(filename[:maxFilenameLength-len(ext)], "") + ext
+	}
+
+	if filename == "" {
+		return "file"
+	}
+
+	return filename
+}
+
+func sniff(content io.ReadSeeker) (string, error) {
+	buf := make([]byte, 512)
+
+	n, err := io.ReadFull(content, buf)
+	if err != nil && err != io.EOF && err != io.ErrUnexpectedEOF {
+		return "", err
+	}
+
+	if _, err := content.Seek(0, io.SeekStart); err != nil {
+		return "", err
+	}
+
+	return mediaType(http.DetectContentType(buf[:n])), nil
+}
+
+// compatible returns true if content sniffed as sniffed can be of type other.
+// Generic types such as text/plain can be any type that isn't sniffable itself,
+// e.g. text/csv.
+func compatible(sniffed, other string) bool {
+	if sniffed == other {
+		return true
+	}
+
+	switch sniffed {
+	case "text/plain", "application/octet-stream":
+		return !sniffable(other)
+	case "text/xml":
+		return other == "application/xml" || strings.HasSuffix(other, "+xml")
+	case "application/zip":
+		// Office documents, jars etc. are zip files.
+		return !sniffable(other) && !strings.HasPrefix(other, "text/")
+	}
+
+	return false
+}
+
+// sniffable returns true for types http.DetectContentType recognizes by their
+// content.
+func sniffable(mediaType string) bool {
+	// SVG is XML and sniffed as text.
+	if mediaType == "image/svg+xml" {
+		return false
+	}
+
+	for _, prefix := range []string{"image/", "audio/", "video/", "font/"} {
+		if strings.HasPrefix(mediaType, prefix) {
+			return true
+		}
+	}
+
+	switch mediaType {
+	case "text/html", "text/xml", "application/pdf", "application/postscript",
+		"application/zip", "application/x-gzip", "application/x-rar-compressed",
+		"application/wasm", "application/vnd.ms-fontobject":
+		return true
+	}
+
+	return false
+}
+
+func matchesType(allowed, contentType string) bool {
+	if prefix, ok := strings.CutSuffix(allowed, "/*"); ok {
+		return strings.HasPrefix(contentType, prefix+"/")
+	}
+
+	return allowed == contentType
+}
+
+func mediaType(contentType string) string {
+	if contentType == "" {
+		return ""
+	}
+
+	parsed, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return ""
+	}
+
+	return parsed
+}
+
+func unprocessable(format string, args ...interface{}) error {
+	return &Error{
+		Status:  http.StatusUnprocessableEntity,
+		Message: fmt.Sprintf(format, args...),
+	}
+}
@@ -0,0 +1,141 @@
+package bind
+
+import (
+	"bytes"
+	"errors"
+	"mime/multipart"
+	"net/http"
+	"net/textproto"
+	"strings"
+	"testing"
+)
+
+var pngHeader = []byte("\x89PNG\r\n\x1a\n\x00\x00\x00\rIHDR")
+
+func Test_ValidateUpload(t *testing.T) {
+	images := UploadPolicy{
+		AllowedTypes:      []string{"image/*"},
+		AllowedExtensions: []string{".png", ".jpg"},
+		MaxSize:           1 << 10,
+	}
+
+	cases := []struct {
+		description         string
+		filename            string
+		declared            string
+		content             []byte
+		policy              UploadPolicy
+		expectedStatus      int
+		expectedFilename    string
+		expectedContentType string
+	}{
+		{
+			description:         "valid image",
+			filename:            "cat.png",
+			declared:            "image/png",
+			content:             pngHeader,
+			policy:              images,
+			expectedFilename:    "cat.png",
+			expectedContentType: "image/png",
+		},
+		{
+			description:    "declared type mismatch",
+			filename:       "cat.png",
+			declared:       "image/png",
+			content:        []byte("<html><script>alert(1)</script></html>"),
+			policy:         images,
+			expectedStatus: http.StatusUnprocessableEntity,
+		},
+		{
+			description:    "extension mismatch",
+			filename:       "cat.jpg",
+			content:        pngHeader,
+			policy:         images,
+			expectedStatus: http.StatusUnprocessableEntity,
+		},
+		{
+			description:    "type not allowed",
+			filename:       "doc.pdf",
+			content:        []byte("%PDF-1.4"),
+			policy:         images,
+			expectedStatus: http.StatusUnsupportedMediaType,
+		},
+		{
+			description:    "too large",
+			filename:       "cat.png",
+			content:        append(pngHeader, make([]byte, 2<<10)...),
+			policy:         images,
+			expectedStatus: http.StatusRequestEntityTooLarge,
+		},
+		{
+			description:         "generic sniffed type uses declared type",
+			filename:            "data.csv",
+			declared:            "text/csv",
+			content:             []byte("a,b,c\n1,2,3\n"),
+			expectedFilename:    "data.csv",
+			expectedContentType: "text/csv",
+		},
+		{
+			description:         "dangerous filename",
+			filename:            `..\..\windows\system32\.evil<name>.png`,
+			content:             pngHeader,
+			expectedFilename:    "evil_name_.png",
+			expectedContentType: "image/png",
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.description, func(t *testing.T) {
+			header := &multipart.FileHeader{
+				Filename: tc.filename,
+				Header:   textproto.MIMEHeader{},
+				Size:     int64(len(tc.content)),
+			}
+
+			if tc.declared != "" {
+				header.Header.Set("Content-Type", tc.declared)
+			}
+
+			content := bytes.NewReader(tc.content)
+
+			upload, err := ValidateUpload(header, content, tc.policy)
+			if tc.expectedStatus != 0 {
+				var bindErr *Error
+				if !errors.As(err, &bindErr) || bindErr.Status != tc.expectedStatus {
+					t.Fatalf("unexpected error, got: %v, expected status: %d", err, tc.expectedStatus)
+				}
+
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("unexpected error: %s", err)
+			}
+
+			if upload.Filename != tc.expectedFilename || upload.ContentType != tc.expectedContentType {
+				t.Fatalf("unexpected upload: %+v", upload)
+			}
+
+			if content.Len() != len(tc.content) {
+				t.Fatal("content not rewound after sniffing")
+			}
+		})
+	}
+}
+
+func Test_SanitizeFilename(t *testing.T) {
+	for filename, expected := range map[string]string{
+		"report.pdf":                      "report.pdf",
+		"../../etc/passwd":                "passwd",
+		`C:\Users\bob\photo.jpg`:          "photo.jpg",
+		".htaccess":                       "htaccess",
+		"name\x00.png":                    "name_.png",
+		"..":                              "file",
+		"résumé.pdf":                      "résumé.pdf",
+		strings.Repeat("a", 300) + ".txt": strings.Repeat("a", 251) + ".txt",
+	} {
+		if got := SanitizeFilename(filename); got != expected {
+			t.Fatalf("unexpected filename for %q, got: %q, expected: %q", filename, got, expected)
+		}
+	}
+}
@@ -0,0 +1,46 @@
+package httphelpers
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+)
+
+func Test_BindQuery(t *testing.T) {
+	type query struct {
+		Name    string   `query:"name"`
+		Limit   int      `query:"limit"`
+		Ratio   float64  `query:"ratio"`
+		Enabled bool     `query:"enabled"`
+		Tags    []string `query:"tag"`
+		Ignored string
+	}
+
+	var q query
+
+	r := httptest.NewRequest(http.MethodGet, "/?name=foo&limit=10&ratio=0.5&enabled=true&tag=a&tag=b&Ignored=x", nil)
+	if err := BindQuery(r, &q); err != nil {
+		t.Fatal(err)
+	}
+
+	expected := query{
+		Name:    "foo",
+		Limit:   10,
+		Ratio:   0.5,
+		Enabled: true,
+		Tags:    []string{"a", "b"},
+	}
+
+	if !reflect.DeepEqual(q, expected) {
+		t.Fatalf("unexpected result, got: %+v, expected: %+v", q, expected)
+	}
+
+	r = httptest.NewRequest(http.MethodGet, "/?limit=many", nil)
+
+	var bindErr *BindError
+	if err := BindQuery(r, &q); !errors.As(err, &bindErr) {
+		t.Fatalf("expected bind error, got: %v", err)
+	}
+}
@@ -0,0 +1,127 @@
+package buildinfo
+
+/*
+The name, version, commit and build date of the service, set with -ldflags at
+build time or read from the build information embedded by the Go toolchain,
+served as JSON on a version endpoint for fleet-wide debugging.
+
+	go build -ldflags "-X github.com/bombsimon/http-helpers/buildinfo.Version=v1.2.3 \
+		-X github.com/bombsimon/http-helpers/buildinfo.Date=$(date -u +%FT%TZ)"
+
+	mux.Handle("GET /version", buildinfo.Handler())
+*/
+
+import (
+	"encoding/json"
+	"net/http"
+	"os"
+	"path"
+	"path/filepath"
+	"runtime"
+	rdebug "runtime/debug"
+	"sync"
+)
+
+// Set with -ldflags "-X github.com/bombsimon/http-helpers/buildinfo.Version=..."
+// or from main before calling Read. Empty values are read from the build
+// information embedded by the Go toolchain.
+var (
+	// Name is the name of the service. Defaults to the last element of the
+	// main module path.
+	Name string
+
+	// Version is the version of the service. Defaults to the version of the
+	// main module, set when it's built with go install module@version.
+	Version string
+
+	// Commit is the VCS revision. Defaults to the revision stamped by go
+	// build.
+	Commit string
+
+	// Date is when the service was built. Defaults to the time of the VCS
+	// revision stamped by go build.
+	Date string
+)
+
+// Info is the build information served by Handler.
+type Info struct {
+	Name      string `json:"name"`
+	Version   string `json:"version"`
+	Commit    string `json:"commit,omitempty"`
+	Date      string `json:"date,omitempty"`
+	Modified  bool   `json:"modified,omitempty"`
+	GoVersion string `json:"go_version"`
+	OS        string `json:"os"`
+	Arch      string `json:"arch"`
+}
+
+var readBuildInfo = sync.OnceValues(rdebug.ReadBuildInfo)
+
+// Read returns the build information, with the values set with -ldflags taking
+// precedence over the embedded build information.
+func Read() Info {
+	info := Info{
+		GoVersion: runtime.Version(),
+		OS:        runtime.GOOS,
+		Arch:      runtime.GOARCH,
+	}
+
+	if bi, ok := readBuildInfo(); ok {
+		info.Name = path.Base(bi.Main.Path)
+
+		// Binaries built from a checkout have the version (devel).
+		if bi.Main.Version != "(devel)" {
+			info.Version = bi.Main.Version
+		}
+
+		for _, setting := range bi.Settings {
+			switch setting.Key {
+			case "vcs.revision":
+				info.Commit = setting.Value
+			case "vcs.time":
+				info.Date = setting.Value
+			case "vcs.modified":
+				info.Modified = setting.Value == "true"
+			}
+		}
+	}
+
+	// Tests and binaries built without module information have no main
+	// module path.
+	if info.Name == "" || info.Name == "." {
+		info.Name = filepath.Base(os.Args[0])
+	}
+
+	if Name != "" {
+		info.Name = Name
+	}
+
+	if Version != "" {
+		info.Version = Version
+	}
+
+	if Commit != "" {
+		info.Commit = Commit
+	}
+
+	if Date != "" {
+		info.Date = Date
+	}
+
+	return info
+}
+
+// Handler returns a handler serving the build information as JSON. Unlike the
+// build endpoint of the debug package it doesn't list the dependencies, so it
+// may be served publicly.
+func Handler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("Cache-Control", "no-store")
+
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+
+		_ = enc.Encode(Read())
+	})
+}
@@ -0,0 +1,48 @@
+package buildinfo
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"runtime"
+	"testing"
+)
+
+func Test_Handler(t *testing.T) {
+	Version, Commit = "v1.2.3", "abc123"
+
+	defer func() {
+		Version, Commit = "", ""
+	}()
+
+	rec := httptest.NewRecorder()
+	Handler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/version", nil))
+
+	if rec.Header().Get("Content-Type") != "application/json" {
+		t.Fatalf("unexpected content type, got: %s", rec.Header().Get("Content-Type"))
+	}
+
+	info := Info{}
+	if err := json.Unmarshal(rec.Body.Bytes(), &info); err != nil {
+		t.Fatal(err)
+	}
+
+	expected := Info{
+		Name:      info.Name,
+		Version:   "v1.2.3",
+		Commit:    "abc123",
+		Date:      info.Date,
+		Modified:  info.Modified,
+		GoVersion: runtime.Version(),
+		OS:        runtime.GOOS,
+		Arch:      runtime.GOARCH,
+	}
+
+	if info != expected {
+		t.Fatalf("unexpected info, got: %+v, expected: %+v", info, expected)
+	}
+
+	if info.Name == "" {
+		t.Fatal("expected name to default to the binary")
+	}
+}
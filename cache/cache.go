@@ -0,0 +1,372 @@
+package cache
+
+/*
+Cached HTTP responses and the stores keeping them, shared by the Cache
+middleware, the caching mode of the reverse proxy and the Cache tripperware in
+the client package. Use NewKVStore with a shared backend, e.g. Redis, to share
+the cache between instances.
+
+	store := cache.NewMemoryStore(10_000)
+
+	handler := middleware.Cache(time.Minute, middleware.WithCacheStore(store))
+	upstream := proxy.New(target, proxy.WithCache(store))
+*/
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/bombsimon/http-helpers/kv"
+)
+
+// Entry is a cached response. Entries are shared between requests and must not
+// be modified once stored.
+type Entry struct {
+	Status int
+	Header http.Header
+	Body   []byte
+
+	// Stored is when the response was generated or last validated, used for
+	// the Age header.
+	Stored time.Time
+
+	// Expires is when the response becomes stale.
+	Expires time.Time
+
+	// StaleWhileRevalidate is how long after Expires the stale response may
+	// be served while it's regenerated in the background.
+	StaleWhileRevalidate time.Duration
+
+	// StaleIfError is how long after Expires the stale response may be
+	// served if regenerating it fails.
+	StaleIfError time.Duration
+}
+
+// Policy is the freshness applied to responses that don't set it with their
+// Cache-Control header.
+type Policy struct {
+	TTL                  time.Duration
+	StaleWhileRevalidate time.Duration
+	StaleIfError         time.Duration
+
+	// Private is set for caches used by a single client, which may store
+	// responses with Cache-Control private and ignore s-maxage.
+	Private bool
+}
+
+// NewEntry returns the response as an entry if a shared cache, or a private
+// cache if set in the policy, may store it. Only statuses that are cacheable
+// by default are stored, and not responses with Set-Cookie, Vary or
+// Cache-Control no-store, no-cache or, for shared caches, private. The
+// freshness is taken from the s-maxage, max-age, stale-while-revalidate and
+// stale-if-error directives, or the Expires header, falling back to the
+// policy. must-revalidate and proxy-revalidate disable serving stale
+// responses. Responses without a freshness lifetime are only stored if they
+// have an ETag or Last-Modified header to revalidate them with.
+func NewEntry(status int, header http.Header, body []byte, now time.Time, policy Policy) (*Entry, bool) {
+	switch status {
+	case http.StatusOK, http.StatusNonAuthoritativeInfo, http.StatusNoContent,
+		http.StatusMultipleChoices, http.StatusMovedPermanently, http.StatusNotFound,
+		http.StatusMethodNotAllowed, http.StatusGone, http.StatusRequestURITooLong,
+		http.StatusNotImplemented:
+	default:
+		return nil, false
+	}
+
+	if header.Get("Set-Cookie") != "" || header.Get("Vary") != "" {
+		return nil, false
+	}
+
+	entry := &Entry{
+		Status:               status,
+		Header:               header,
+		Body:                 body,
+		Stored:               now,
+		StaleWhileRevalidate: policy.StaleWhileRevalidate,
+		StaleIfError:         policy.StaleIfError,
+	}
+
+	ttl := policy.TTL
+	maxAge, sharedMaxAge := time.Duration(-1), time.Duration(-1)
+	mustRevalidate := false
+
+	for _, directive := range strings.Split(header.Get("Cache-Control"), ",") {
+		name, value, _ := strings.Cut(strings.TrimSpace(directive), "=")
+		name = strings.ToLower(name)
+
+		switch name {
+		case "no-store", "no-cache":
+			return nil, false
+		case "private":
+			if !policy.Private {
+				return nil, false
+			}
+		case "must-revalidate", "proxy-revalidate":
+			mustRevalidate = true
+		}
+
+		seconds, err := strconv.Atoi(strings.Trim(value, `"`))
+		if err != nil || seconds < 0 {
+			continue
+		}
+
+		d := time.Duration(seconds) * time.Second
+
+		switch name {
+		case "max-age":
+			maxAge = d
+		case "s-maxage":
+			if !policy.Private {
+				sharedMaxAge = d
+			}
+		case "stale-while-revalidate":
+			entry.StaleWhileRevalidate = d
+		case "stale-if-error":
+			entry.StaleIfError = d
+		}
+	}
+
+	switch {
+	case sharedMaxAge >= 0:
+		ttl = sharedMaxAge
+	case maxAge >= 0:
+		ttl = maxAge
+	case header.Get("Expires") != "":
+		ttl = 0
+
+		if expires, err := http.ParseTime(header.Get("Expires")); err == nil {
+			date, err := http.ParseTime(header.Get("Date"))
+			if err != nil {
+				date = now
+			}
+
+			ttl = max(expires.Sub(date), 0)
+		}
+	}
+
+	if mustRevalidate {
+		entry.StaleWhileRevalidate, entry.StaleIfError = 0, 0
+	}
+
+	entry.Expires = now.Add(ttl)
+
+	if ttl <= 0 && entry.StaleWhileRevalidate <= 0 && entry.StaleIfError <= 0 && !entry.Revalidatable() {
+		return nil, false
+	}
+
+	return entry, true
+}
+
+// Fresh returns true if the entry hasn't expired.
+func (e *Entry) Fresh(now time.Time) bool {
+	return now.Before(e.Expires)
+}
+
+// ServableWhileRevalidating returns true if the entry may be served while
+// it's regenerated in the background.
+func (e *Entry) ServableWhileRevalidating(now time.Time) bool {
+	return now.Before(e.Expires.Add(e.StaleWhileRevalidate))
+}
+
+// ServableOnError returns true if the entry may be served when regenerating it
+// fails.
+func (e *Entry) ServableOnError(now time.Time) bool {
+	return now.Before(e.Expires.Add(e.StaleIfError))
+}
+
+// Revalidatable returns true if the entry has an ETag or Last-Modified header
+// to make a conditional request with.
+func (e *Entry) Revalidatable() bool {
+	return e.Header.Get("ETag") != "" || e.Header.Get("Last-Modified") != ""
+}
+
+// Age returns the time since the entry was stored.
+func (e *Entry) Age(now time.Time) time.Duration {
+	return max(now.Sub(e.Stored), 0)
+}
+
+// WriteTo writes the response with the Age header and the X-Cache header set
+// to cacheStatus, e.g. "HIT".
+func (e *Entry) WriteTo(w http.ResponseWriter, now time.Time, cacheStatus string) {
+	header := w.Header()
+	for k, v := range e.Header {
+		header[k] = append([]string(nil), v...)
+	}
+
+	header.Set("Age", strconv.Itoa(int(e.Age(now).Seconds())))
+	header.Set("X-Cache", cacheStatus)
+
+	if e.Status >= 200 && e.Status != http.StatusNoContent && e.Status != http.StatusNotModified {
+		header.Set("Content-Length", strconv.Itoa(len(e.Body)))
+	}
+
+	w.WriteHeader(e.Status)
+	_, _ = w.Write(e.Body)
+}
+
+// Response returns the entry as a response to the request, with the Age
+// header and the X-Cache header set to cacheStatus, for use in transports.
+func (e *Entry) Response(r *http.Request, now time.Time, cacheStatus string) *http.Response {
+	header := e.Header.Clone()
+	header.Set("Age", strconv.Itoa(int(e.Age(now).Seconds())))
+	header.Set("X-Cache", cacheStatus)
+
+	return &http.Response{
+		Status:        fmt.Sprintf("%d %s", e.Status, http.StatusText(e.Status)),
+		StatusCode:    e.Status,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        header,
+		Body:          io.NopCloser(bytes.NewReader(e.Body)),
+		ContentLength: int64(len(e.Body)),
+		Request:       r,
+	}
+}
+
+// servableUntil returns when the entry can't be served anymore without
+// revalidating it.
+func (e *Entry) servableUntil() time.Time {
+	return e.Expires.Add(max(e.StaleWhileRevalidate, e.StaleIfError))
+}
+
+// Store stores cached responses by key. Backends that fail should treat it as
+// a miss and log the error, the response is then regenerated.
+type Store interface {
+	Get(ctx context.Context, key string) (*Entry, bool)
+	Set(ctx context.Context, key string, entry *Entry)
+}
+
+// NewMemoryStore returns an in-process store keeping up to maxEntries entries.
+// When it's full the entry that can be served without revalidation for the
+// shortest time is evicted.
+func NewMemoryStore(maxEntries int) Store {
+	return &memoryStore{
+		maxEntries: maxEntries,
+		entries:    make(map[string]*Entry),
+	}
+}
+
+type memoryStore struct {
+	maxEntries int
+
+	mu      sync.Mutex
+	entries map[string]*Entry
+}
+
+func (s *memoryStore) Get(_ context.Context, key string) (*Entry, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	entry, ok := s.entries[key]
+
+	return entry, ok
+}
+
+func (s *memoryStore) Set(_ context.Context, key string, entry *Entry) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.entries[key]; !ok && len(s.entries) >= s.maxEntries {
+		var (
+			evict  string
+			oldest time.Time
+			found  bool
+		)
+
+		for k, e := range s.entries {
+			if until := e.servableUntil(); !found || until.Before(oldest) {
+				evict, oldest, found = k, until, true
+			}
+		}
+
+		delete(s.entries, evict)
+	}
+
+	s.entries[key] = entry
+}
+
+// NewKVStore returns a store keeping the entries in the key-value store, e.g.
+// Redis or memcached, until they can't be served without revalidation.
+// Entries with an ETag or Last-Modified header are kept for the retention set
+// with WithRetention after that, so they can be revalidated. Failing to read or
+// write an entry is logged with slog.Default() and treated as a miss.
+func NewKVStore(store kv.Store, opts ...KVOption) Store {
+	s := &kvStore{
+		store:     store,
+		retention: time.Hour,
+	}
+
+	for _, opt := range opts {
+		opt(s)
+	}
+
+	return s
+}
+
+// KVOption configures NewKVStore.
+type KVOption func(*kvStore)
+
+// WithRetention sets how long entries that can be revalidated are kept after
+// they can't be served without revalidation. Defaults to an hour.
+func WithRetention(retention time.Duration) KVOption {
+	return func(s *kvStore) {
+		s.retention = retention
+	}
+}
+
+type kvStore struct {
+	store     kv.Store
+	retention time.Duration
+}
+
+func (s *kvStore) Get(ctx context.Context, key string) (*Entry, bool) {
+	data, found, err := s.store.Get(ctx, "cache:"+key)
+	if err != nil {
+		slog.Default().ErrorContext(ctx, "could not get cached response", "key", key, "error", err)
+		return nil, false
+	}
+
+	if !found {
+		return nil, false
+	}
+
+	entry := &Entry{}
+	if err := json.Unmarshal(data, entry); err != nil {
+		slog.Default().ErrorContext(ctx, "could not decode cached response", "key", key, "error", err)
+		return nil, false
+	}
+
+	return entry, true
+}
+
+func (s *kvStore) Set(ctx context.Context, key string, entry *Entry) {
+	// The entry is stored when it's generated or validated so the TTL is
+	// counted from then, and not with the clock of the cache using it.
+	ttl := entry.servableUntil().Sub(entry.Stored)
+	if entry.Revalidatable() {
+		ttl = max(ttl, 0) + s.retention
+	}
+
+	if ttl <= 0 {
+		return
+	}
+
+	data, err := json.Marshal(entry)
+	if err == nil {
+		err = s.store.Set(ctx, "cache:"+key, data, ttl)
+	}
+
+	if err != nil {
+		slog.Default().ErrorContext(ctx, "could not set cached response", "key", key, "error", err)
+	}
+}
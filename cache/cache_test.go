@@ -0,0 +1,180 @@
+package cache
+
+import (
+	"context"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/bombsimon/http-helpers/kv"
+)
+
+func Test_NewEntry(t *testing.T) {
+	var (
+		now    = time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+		policy = Policy{TTL: time.Minute, StaleWhileRevalidate: time.Minute}
+	)
+
+	for _, tc := range []struct {
+		description string
+		status      int
+		header      http.Header
+		cacheable   bool
+		ttl         time.Duration
+		stale       time.Duration
+	}{
+		{
+			description: "policy",
+			status:      http.StatusOK,
+			cacheable:   true,
+			ttl:         time.Minute,
+			stale:       time.Minute,
+		},
+		{
+			description: "max-age",
+			status:      http.StatusOK,
+			header:      http.Header{"Cache-Control": {"public, max-age=10, stale-while-revalidate=5"}},
+			cacheable:   true,
+			ttl:         10 * time.Second,
+			stale:       5 * time.Second,
+		},
+		{
+			description: "s-maxage wins",
+			status:      http.StatusOK,
+			header:      http.Header{"Cache-Control": {"max-age=10, s-maxage=30"}},
+			cacheable:   true,
+			ttl:         30 * time.Second,
+			stale:       time.Minute,
+		},
+		{
+			description: "expires",
+			status:      http.StatusOK,
+			header: http.Header{
+				"Date":    {now.Format(http.TimeFormat)},
+				"Expires": {now.Add(time.Hour).Format(http.TimeFormat)},
+			},
+			cacheable: true,
+			ttl:       time.Hour,
+			stale:     time.Minute,
+		},
+		{
+			description: "must-revalidate",
+			status:      http.StatusOK,
+			header:      http.Header{"Cache-Control": {"max-age=10, must-revalidate"}},
+			cacheable:   true,
+			ttl:         10 * time.Second,
+		},
+		{
+			description: "no-store",
+			status:      http.StatusOK,
+			header:      http.Header{"Cache-Control": {"no-store"}},
+		},
+		{
+			description: "private",
+			status:      http.StatusOK,
+			header:      http.Header{"Cache-Control": {"private, max-age=60"}},
+		},
+		{
+			description: "set-cookie",
+			status:      http.StatusOK,
+			header:      http.Header{"Set-Cookie": {"session=1"}},
+		},
+		{
+			description: "server error",
+			status:      http.StatusInternalServerError,
+		},
+	} {
+		t.Run(tc.description, func(t *testing.T) {
+			header := tc.header
+			if header == nil {
+				header = http.Header{}
+			}
+
+			entry, ok := NewEntry(tc.status, header, nil, now, policy)
+			if ok != tc.cacheable {
+				t.Fatalf("unexpected cacheable, got: %t, expected: %t", ok, tc.cacheable)
+			}
+
+			if !ok {
+				return
+			}
+
+			if entry.Expires.Sub(now) != tc.ttl || entry.StaleWhileRevalidate != tc.stale {
+				t.Fatalf(
+					"unexpected freshness, got: %s stale %s, expected: %s stale %s",
+					entry.Expires.Sub(now), entry.StaleWhileRevalidate, tc.ttl, tc.stale,
+				)
+			}
+		})
+	}
+}
+
+func Test_NewEntryRevalidatable(t *testing.T) {
+	now := time.Now()
+
+	if _, ok := NewEntry(http.StatusOK, http.Header{}, nil, now, Policy{}); ok {
+		t.Fatal("expected response without freshness or validators to not be cacheable")
+	}
+
+	entry, ok := NewEntry(http.StatusOK, http.Header{"Etag": {`"v1"`}}, nil, now, Policy{})
+	if !ok || entry.Fresh(now) || !entry.Revalidatable() {
+		t.Fatal("expected stale response with an ETag to be cacheable for revalidation")
+	}
+}
+
+func Test_MemoryStore(t *testing.T) {
+	var (
+		ctx   = context.Background()
+		now   = time.Now()
+		store = NewMemoryStore(2)
+	)
+
+	store.Set(ctx, "long", &Entry{Expires: now.Add(time.Hour)})
+	store.Set(ctx, "short", &Entry{Expires: now.Add(time.Minute)})
+	store.Set(ctx, "new", &Entry{Expires: now.Add(time.Hour)})
+
+	for key, expected := range map[string]bool{"long": true, "short": false, "new": true} {
+		if _, ok := store.Get(ctx, key); ok != expected {
+			t.Fatalf("unexpected entry for %s, got: %t, expected: %t", key, ok, expected)
+		}
+	}
+}
+
+func Test_KVStore(t *testing.T) {
+	var (
+		ctx   = context.Background()
+		now   = time.Now()
+		store = NewKVStore(kv.NewMemoryStore())
+	)
+
+	store.Set(ctx, "fresh", &Entry{
+		Status:  http.StatusOK,
+		Header:  http.Header{"Etag": []string{`"v1"`}},
+		Body:    []byte("body"),
+		Stored:  now,
+		Expires: now.Add(time.Minute),
+	})
+	store.Set(ctx, "expired", &Entry{Stored: now, Expires: now.Add(-time.Minute)})
+	store.Set(ctx, "revalidate", &Entry{
+		Header:  http.Header{"Etag": []string{`"v1"`}},
+		Stored:  now,
+		Expires: now,
+	})
+
+	entry, ok := store.Get(ctx, "fresh")
+	if !ok {
+		t.Fatal("expected entry to be stored")
+	}
+
+	if entry.Status != http.StatusOK || entry.Header.Get("ETag") != `"v1"` || string(entry.Body) != "body" || !entry.Fresh(now) {
+		t.Fatalf("unexpected entry: %+v", entry)
+	}
+
+	if _, ok := store.Get(ctx, "expired"); ok {
+		t.Fatal("expected expired entry to not be stored")
+	}
+
+	if _, ok := store.Get(ctx, "revalidate"); !ok {
+		t.Fatal("expected entry that can be revalidated to be stored")
+	}
+}
@@ -0,0 +1,184 @@
+package chain
+
+/*
+Chaining of HTTP middlewares without any dependencies outside the standard
+library. The middleware package re-exports these so both can be used
+interchangeably.
+
+	handler := chain.AddMiddlewares(
+		router,
+		myMiddleware,
+		myOtherMiddleware,
+	)
+
+A Chain can also be used to build the handler, which supports applying
+middlewares only to some requests:
+
+	handler := chain.New(recovery, logger).
+		Group(chain.PathPrefix("/api/")).
+		Use(auth).
+		Except(chain.Path("/api/healthz")).
+		Then(router)
+*/
+
+import "net/http"
+
+// Middleware represents a middleware function which will add a handler before
+// the final http serve handler.
+type Middleware func(http.Handler) http.Handler
+
+// AddMiddlewares will add all middlewares in the passed order and return a
+// handler which may be used for the http server. Since they're added in the
+// order they're passed, they will be executed in the reverse order.
+func AddMiddlewares(h http.Handler, middlewares ...Middleware) http.Handler {
+	for _, middleware := range middlewares {
+		h = middleware(h)
+	}
+
+	return h
+}
+
+// Chain is a list of middlewares used to build a handler. The middlewares are
+// divided into groups which only apply to requests matching the group. A Chain
+// is immutable, all methods return a new Chain. Unlike AddMiddlewares, the
+// middlewares are executed in the order they're added.
+type Chain struct {
+	groups []group
+}
+
+type group struct {
+	match       []Matcher
+	except      []Matcher
+	middlewares []Middleware
+	names       []string
+}
+
+// applies returns true if the group should be applied for the request.
+func (g group) applies(r *http.Request) bool {
+	for _, m := range g.except {
+		if m(r) {
+			return false
+		}
+	}
+
+	if len(g.match) == 0 {
+		return true
+	}
+
+	for _, m := range g.match {
+		if m(r) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// New creates a new Chain with the passed middlewares applied to all requests.
+func New(middlewares ...Middleware) Chain {
+	return Chain{}.Use(middlewares...)
+}
+
+// Use adds middlewares to the current group. The middlewares are named after
+// their function in Describe, use UseNamed to set a better name.
+func (c Chain) Use(middlewares ...Middleware) Chain {
+	for _, m := range middlewares {
+		c = c.UseNamed(funcName(m), m)
+	}
+
+	if len(middlewares) == 0 {
+		c = c.clone()
+	}
+
+	return c
+}
+
+// UseNamed adds a middleware with a name to the current group. The name is
+// used by Describe, Effective and DebugHandler.
+func (c Chain) UseNamed(name string, m Middleware) Chain {
+	c = c.clone()
+	last := &c.groups[len(c.groups)-1]
+	last.middlewares = append(last.middlewares, m)
+	last.names = append(last.names, name)
+
+	return c
+}
+
+// Group starts a new group. Middlewares added to the group are only applied to
+// requests matching any of the matchers, or all requests if no matchers are
+// passed. Middlewares added before the group are not affected.
+func (c Chain) Group(matchers ...Matcher) Chain {
+	c = c.clone()
+	c.groups = append(c.groups, group{match: append([]Matcher(nil), matchers...)})
+
+	return c
+}
+
+// Except skips the middlewares in the current group for requests matching any
+// of the matchers.
+func (c Chain) Except(matchers ...Matcher) Chain {
+	c = c.clone()
+	last := &c.groups[len(c.groups)-1]
+	last.except = append(last.except, matchers...)
+
+	return c
+}
+
+// Then returns a handler executing the middlewares in the chain before h.
+func (c Chain) Then(h http.Handler) http.Handler {
+	for i := len(c.groups) - 1; i >= 0; i-- {
+		g := c.groups[i]
+		if len(g.middlewares) == 0 {
+			continue
+		}
+
+		next := h
+		wrapped := next
+
+		for j := len(g.middlewares) - 1; j >= 0; j-- {
+			wrapped = g.middlewares[j](wrapped)
+		}
+
+		h = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if g.applies(r) {
+				wrapped.ServeHTTP(w, r)
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+
+	return h
+}
+
+// ThenFunc works like Then but takes a handler function.
+func (c Chain) ThenFunc(fn http.HandlerFunc) http.Handler {
+	return c.Then(fn)
+}
+
+// Middleware returns the chain as a single middleware.
+func (c Chain) Middleware() Middleware {
+	return c.Then
+}
+
+// clone copies the chain so the original isn't modified. The copy always has
+// at least one group so the zero value Chain is usable.
+func (c Chain) clone() Chain {
+	if len(c.groups) == 0 {
+		return Chain{groups: []group{{}}}
+	}
+
+	groups := make([]group, len(c.groups))
+
+	for i, g := range c.groups {
+		groups[i] = group{
+			match:       append([]Matcher(nil), g.match...),
+			except:      append([]Matcher(nil), g.except...),
+			middlewares: append([]Middleware(nil), g.middlewares...),
+			names:       append([]string(nil), g.names...),
+		}
+	}
+
+	return Chain{groups: groups}
+}
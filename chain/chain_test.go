@@ -0,0 +1,117 @@
+package chain
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func Test_AddMiddlewares(t *testing.T) {
+	var order []string
+
+	named := func(name string) Middleware {
+		return func(h http.Handler) http.Handler {
+			return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				order = append(order, name)
+				h.ServeHTTP(w, r)
+			})
+		}
+	}
+
+	handler := AddMiddlewares(
+		http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			order = append(order, "handler")
+		}),
+		named("first"),
+		named("second"),
+	)
+
+	handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil))
+
+	expected := []string{"second", "first", "handler"}
+	if len(order) != len(expected) {
+		t.Fatalf("unexpected order: %v", order)
+	}
+
+	for i := range expected {
+		if order[i] != expected[i] {
+			t.Fatalf("unexpected order: %v", order)
+		}
+	}
+}
+
+func Test_Chain(t *testing.T) {
+	var order []string
+
+	named := func(name string) Middleware {
+		return func(h http.Handler) http.Handler {
+			return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				order = append(order, name)
+				h.ServeHTTP(w, r)
+			})
+		}
+	}
+
+	base := New(named("recovery"), named("logger"))
+	api := base.
+		Group(PathPrefix("/api/")).
+		Use(named("auth")).
+		Except(Path("/api/healthz"))
+
+	handler := func(c Chain) http.Handler {
+		return c.ThenFunc(func(w http.ResponseWriter, r *http.Request) {
+			order = append(order, "handler")
+		})
+	}
+
+	tests := []struct {
+		name     string
+		chain    Chain
+		path     string
+		expected []string
+	}{
+		{
+			name:     "group matches",
+			chain:    api,
+			path:     "/api/users",
+			expected: []string{"recovery", "logger", "auth", "handler"},
+		},
+		{
+			name:     "group doesn't match",
+			chain:    api,
+			path:     "/about",
+			expected: []string{"recovery", "logger", "handler"},
+		},
+		{
+			name:     "excluded from group",
+			chain:    api,
+			path:     "/api/healthz",
+			expected: []string{"recovery", "logger", "handler"},
+		},
+		{
+			name:     "base chain not modified",
+			chain:    base,
+			path:     "/api/users",
+			expected: []string{"recovery", "logger", "handler"},
+		},
+		{
+			name:     "zero value",
+			chain:    Chain{}.Use(named("only")),
+			path:     "/",
+			expected: []string{"only", "handler"},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			order = nil
+
+			handler(tc.chain).ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, tc.path, nil))
+
+			if strings.Join(order, ",") != strings.Join(tc.expected, ",") {
+				t.Fatalf("unexpected order, got: %v, expected: %v", order, tc.expected)
+			}
+		})
+	}
+}
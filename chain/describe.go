@@ -0,0 +1,110 @@
+package chain
+
+import (
+	"fmt"
+	"net/http"
+	"reflect"
+	"runtime"
+	"strings"
+)
+
+// Describe returns a description of the chain with the middlewares in the
+// order they're executed and the groups they belong to.
+func (c Chain) Describe() string {
+	var (
+		sb strings.Builder
+		n  int
+	)
+
+	for i, g := range c.groups {
+		if i > 0 || len(g.match) > 0 || len(g.except) > 0 {
+			fmt.Fprintf(&sb, "group %d: %s\n", i+1, g.describe())
+		}
+
+		for _, name := range g.names {
+			n++
+			fmt.Fprintf(&sb, "%d. %s\n", n, name)
+		}
+	}
+
+	return sb.String()
+}
+
+// Effective returns the names of the middlewares applied to the request in the
+// order they're executed.
+func (c Chain) Effective(r *http.Request) []string {
+	var names []string
+
+	for _, g := range c.groups {
+		if g.applies(r) {
+			names = append(names, g.names...)
+		}
+	}
+
+	return names
+}
+
+// DebugHandler returns a handler describing the chain. Pass the method and
+// path query parameters to also get the middlewares applied to that route,
+// e.g. /debug/middlewares?method=POST&path=/api/users. The handler should not
+// be exposed publicly.
+func (c Chain) DebugHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+
+		fmt.Fprint(w, c.Describe())
+
+		path := r.URL.Query().Get("path")
+		if path == "" {
+			return
+		}
+
+		method := r.URL.Query().Get("method")
+		if method == "" {
+			method = http.MethodGet
+		}
+
+		route, err := http.NewRequestWithContext(r.Context(), method, path, nil)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		route.Header = r.Header.Clone()
+
+		fmt.Fprintf(w, "\neffective order for %s %s:\n", method, path)
+
+		for i, name := range c.Effective(route) {
+			fmt.Fprintf(w, "%d. %s\n", i+1, name)
+		}
+	})
+}
+
+func (g group) describe() string {
+	condition := "all requests"
+	if len(g.match) > 0 {
+		condition = fmt.Sprintf("requests matching any of %d matchers", len(g.match))
+	}
+
+	if len(g.except) > 0 {
+		condition += fmt.Sprintf(" except %d matchers", len(g.except))
+	}
+
+	return condition
+}
+
+// funcName returns the name of the function without the package path, e.g.
+// middleware.NewLogger.func1.
+func funcName(fn interface{}) string {
+	f := runtime.FuncForPC(reflect.ValueOf(fn).Pointer())
+	if f == nil {
+		return "unknown"
+	}
+
+	name := f.Name()
+	if i := strings.LastIndex(name, "/"); i >= 0 {
+		name = name[i+1:]
+	}
+
+	return name
+}
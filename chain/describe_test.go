@@ -0,0 +1,49 @@
+package chain
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func passthrough(h http.Handler) http.Handler {
+	return h
+}
+
+func Test_Describe(t *testing.T) {
+	c := New(passthrough).
+		UseNamed("logger", passthrough).
+		Group(PathPrefix("/api/")).
+		UseNamed("auth", passthrough).
+		Except(Path("/api/healthz"))
+
+	expected := `1. chain.passthrough
+2. logger
+group 2: requests matching any of 1 matchers except 1 matchers
+3. auth
+`
+
+	if c.Describe() != expected {
+		t.Fatalf("unexpected description, got:\n%s\nexpected:\n%s", c.Describe(), expected)
+	}
+
+	effective := c.Effective(httptest.NewRequest(http.MethodGet, "/api/users", nil))
+	if strings.Join(effective, ",") != "chain.passthrough,logger,auth" {
+		t.Fatalf("unexpected effective middlewares: %v", effective)
+	}
+
+	effective = c.Effective(httptest.NewRequest(http.MethodGet, "/api/healthz", nil))
+	if strings.Join(effective, ",") != "chain.passthrough,logger" {
+		t.Fatalf("unexpected effective middlewares: %v", effective)
+	}
+
+	rec := httptest.NewRecorder()
+	c.DebugHandler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/?method=POST&path=/api/users", nil))
+
+	body, _ := io.ReadAll(rec.Body)
+	if !strings.HasSuffix(string(body), "effective order for POST /api/users:\n1. chain.passthrough\n2. logger\n3. auth\n") {
+		t.Fatalf("unexpected debug output:\n%s", body)
+	}
+}
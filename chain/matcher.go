@@ -0,0 +1,78 @@
+package chain
+
+import (
+	"net/http"
+	"strings"
+)
+
+// Matcher reports whether a request matches.
+type Matcher func(r *http.Request) bool
+
+// Path matches requests with any of the paths.
+func Path(paths ...string) Matcher {
+	return func(r *http.Request) bool {
+		for _, path := range paths {
+			if r.URL.Path == path {
+				return true
+			}
+		}
+
+		return false
+	}
+}
+
+// PathPrefix matches requests with a path starting with any of the prefixes.
+func PathPrefix(prefixes ...string) Matcher {
+	return func(r *http.Request) bool {
+		for _, prefix := range prefixes {
+			if strings.HasPrefix(r.URL.Path, prefix) {
+				return true
+			}
+		}
+
+		return false
+	}
+}
+
+// Method matches requests with any of the methods.
+func Method(methods ...string) Matcher {
+	return func(r *http.Request) bool {
+		for _, method := range methods {
+			if r.Method == method {
+				return true
+			}
+		}
+
+		return false
+	}
+}
+
+// HeaderPresent matches requests with the header set, even if it's empty.
+func HeaderPresent(name string) Matcher {
+	return func(r *http.Request) bool {
+		_, ok := r.Header[http.CanonicalHeaderKey(name)]
+		return ok
+	}
+}
+
+// When applies the middleware only to requests matching pred. Other requests
+// are passed to the next handler directly.
+func When(pred Matcher, m Middleware) Middleware {
+	return func(h http.Handler) http.Handler {
+		wrapped := m(h)
+
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if pred(r) {
+				wrapped.ServeHTTP(w, r)
+				return
+			}
+
+			h.ServeHTTP(w, r)
+		})
+	}
+}
+
+// Unless applies the middleware only to requests not matching pred.
+func Unless(pred Matcher, m Middleware) Middleware {
+	return When(func(r *http.Request) bool { return !pred(r) }, m)
+}
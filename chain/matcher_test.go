@@ -0,0 +1,79 @@
+package chain
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func Test_WhenUnless(t *testing.T) {
+	applied := false
+
+	mark := func(h http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			applied = true
+			h.ServeHTTP(w, r)
+		})
+	}
+
+	next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {})
+
+	withHeader := httptest.NewRequest(http.MethodPost, "/api/users", nil)
+	withHeader.Header.Set("X-Debug", "")
+
+	tests := []struct {
+		name     string
+		m        Middleware
+		r        *http.Request
+		expected bool
+	}{
+		{
+			name:     "when path prefix matches",
+			m:        When(PathPrefix("/api/"), mark),
+			r:        httptest.NewRequest(http.MethodGet, "/api/users", nil),
+			expected: true,
+		},
+		{
+			name:     "when path prefix doesn't match",
+			m:        When(PathPrefix("/api/"), mark),
+			r:        httptest.NewRequest(http.MethodGet, "/", nil),
+			expected: false,
+		},
+		{
+			name:     "when method matches",
+			m:        When(Method(http.MethodPost, http.MethodPut), mark),
+			r:        httptest.NewRequest(http.MethodPut, "/", nil),
+			expected: true,
+		},
+		{
+			name:     "when header present",
+			m:        When(HeaderPresent("x-debug"), mark),
+			r:        withHeader,
+			expected: true,
+		},
+		{
+			name:     "unless path matches",
+			m:        Unless(Path("/healthz"), mark),
+			r:        httptest.NewRequest(http.MethodGet, "/healthz", nil),
+			expected: false,
+		},
+		{
+			name:     "unless path doesn't match",
+			m:        Unless(Path("/healthz"), mark),
+			r:        httptest.NewRequest(http.MethodGet, "/users", nil),
+			expected: true,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			applied = false
+
+			tc.m(next).ServeHTTP(httptest.NewRecorder(), tc.r)
+
+			if applied != tc.expected {
+				t.Fatalf("unexpected result, got: %t, expected: %t", applied, tc.expected)
+			}
+		})
+	}
+}
@@ -0,0 +1,310 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"maps"
+	"net/http"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ErrCircuitOpen is returned, wrapped with the host, for requests rejected by
+// an open circuit breaker.
+var ErrCircuitOpen = errors.New("client: circuit breaker is open")
+
+// BreakerState is the state of the circuit breaker for a host.
+type BreakerState int
+
+// States of a circuit breaker.
+const (
+	// StateClosed lets requests through and counts consecutive failures.
+	StateClosed BreakerState = iota
+
+	// StateOpen rejects requests with ErrCircuitOpen.
+	StateOpen
+
+	// StateHalfOpen lets a limited number of probes through to decide if
+	// the breaker should close or open again.
+	StateHalfOpen
+)
+
+func (s BreakerState) String() string {
+	switch s {
+	case StateClosed:
+		return "closed"
+	case StateOpen:
+		return "open"
+	case StateHalfOpen:
+		return "half-open"
+	default:
+		return fmt.Sprintf("BreakerState(%d)", int(s))
+	}
+}
+
+// BreakerPolicy configures a CircuitBreaker.
+type BreakerPolicy struct {
+	// FailureThreshold is the number of consecutive failures opening the
+	// breaker. Defaults to 5.
+	FailureThreshold int
+
+	// OpenTimeout is the time the breaker stays open before letting probes
+	// through. Defaults to 30 seconds.
+	OpenTimeout time.Duration
+
+	// HalfOpenProbes is the number of concurrent probes let through when half
+	// open, all of which must succeed to close the breaker. Defaults to 1.
+	HalfOpenProbes int
+
+	// IsFailure decides if a request failed. Defaults to errors and 5xx
+	// responses. Canceled requests are neither failures nor successes.
+	IsFailure func(*http.Response, error) bool
+
+	// OnStateChange, if set, is called when the breaker for a host changes
+	// state. It's called while holding a lock so it must not send requests
+	// through the breaker.
+	OnStateChange func(host string, from, to BreakerState)
+}
+
+// BreakerStats is a snapshot of the circuit breaker for a host.
+type BreakerStats struct {
+	Host                string
+	State               BreakerState
+	ConsecutiveFailures int
+
+	// Rejected is the number of requests rejected since the breaker was
+	// created.
+	Rejected uint64
+
+	// Transitions is the number of times the breaker has changed to each
+	// state since it was created.
+	Transitions map[BreakerState]uint64
+}
+
+// CircuitBreaker keeps a circuit breaker per host to fail fast instead of
+// sending requests to a dependency that's down. Use Tripperware to add it to
+// a client.
+type CircuitBreaker struct {
+	policy  BreakerPolicy
+	options *options
+
+	mu    sync.Mutex
+	hosts map[string]*breaker
+}
+
+type breaker struct {
+	state      BreakerState
+	failures   int
+	openedAt   time.Time
+	probes     int
+	successes  int
+	generation uint64
+	rejected   uint64
+
+	transitions map[BreakerState]uint64
+}
+
+// NewCircuitBreaker creates a circuit breaker with the policy. The clock set
+// with WithClock is used for the open timeout.
+func NewCircuitBreaker(policy BreakerPolicy, opts ...Option) *CircuitBreaker {
+	if policy.FailureThreshold == 0 {
+		policy.FailureThreshold = 5
+	}
+
+	if policy.OpenTimeout == 0 {
+		policy.OpenTimeout = 30 * time.Second
+	}
+
+	if policy.HalfOpenProbes == 0 {
+		policy.HalfOpenProbes = 1
+	}
+
+	if policy.IsFailure == nil {
+		policy.IsFailure = defaultIsFailure
+	}
+
+	return &CircuitBreaker{
+		policy:  policy,
+		options: newOptions(opts...),
+		hosts:   make(map[string]*breaker),
+	}
+}
+
+// Tripperware returns a tripperware sending requests through the breaker for
+// the host of the request. Requests rejected by an open breaker return an
+// error wrapping ErrCircuitOpen without being sent. Add it before Retry, so
+// it's executed after it, to count each attempt and stop retrying when the
+// breaker opens.
+func (cb *CircuitBreaker) Tripperware() Tripperware {
+	return func(rt http.RoundTripper) http.RoundTripper {
+		return RoundTripperFunc(func(r *http.Request) (*http.Response, error) {
+			host := r.URL.Host
+
+			generation, err := cb.allow(host)
+			if err != nil {
+				return nil, err
+			}
+
+			resp, err := rt.RoundTrip(r)
+			if errors.Is(err, context.Canceled) {
+				cb.release(host, generation)
+				return resp, err
+			}
+
+			cb.record(host, generation, cb.policy.IsFailure(resp, err))
+
+			return resp, err
+		})
+	}
+}
+
+// State returns the current state of the breaker for the host.
+func (cb *CircuitBreaker) State(host string) BreakerState {
+	cb.mu.Lock()
+	defer cb.mu.Unlock()
+
+	if b, ok := cb.hosts[host]; ok {
+		return b.state
+	}
+
+	return StateClosed
+}
+
+// Stats returns a snapshot of the breakers for all hosts that have been
+// requested, sorted by host.
+func (cb *CircuitBreaker) Stats() []BreakerStats {
+	cb.mu.Lock()
+	defer cb.mu.Unlock()
+
+	stats := make([]BreakerStats, 0, len(cb.hosts))
+	for host, b := range cb.hosts {
+		stats = append(stats, BreakerStats{
+			Host:                host,
+			State:               b.state,
+			ConsecutiveFailures: b.failures,
+			Rejected:            b.rejected,
+			Transitions:         maps.Clone(b.transitions),
+		})
+	}
+
+	slices.SortFunc(stats, func(a, b BreakerStats) int {
+		return strings.Compare(a.Host, b.Host)
+	})
+
+	return stats
+}
+
+// allow returns the generation of the breaker the request is sent in, or an
+// error if the request is rejected.
+func (cb *CircuitBreaker) allow(host string) (uint64, error) {
+	cb.mu.Lock()
+	defer cb.mu.Unlock()
+
+	b, ok := cb.hosts[host]
+	if !ok {
+		b = &breaker{}
+		cb.hosts[host] = b
+	}
+
+	if b.state == StateOpen && cb.options.clock.Since(b.openedAt) >= cb.policy.OpenTimeout {
+		cb.setState(host, b, StateHalfOpen)
+	}
+
+	switch b.state {
+	case StateOpen:
+		b.rejected++
+		return 0, fmt.Errorf("%w: %s", ErrCircuitOpen, host)
+	case StateHalfOpen:
+		if b.probes >= cb.policy.HalfOpenProbes {
+			b.rejected++
+			return 0, fmt.Errorf("%w: %s", ErrCircuitOpen, host)
+		}
+
+		b.probes++
+	}
+
+	return b.generation, nil
+}
+
+// record records the result of a request. Results of requests sent before
+// the last state change are ignored.
+func (cb *CircuitBreaker) record(host string, generation uint64, failed bool) {
+	cb.mu.Lock()
+	defer cb.mu.Unlock()
+
+	b := cb.hosts[host]
+	if b.generation != generation {
+		return
+	}
+
+	switch b.state {
+	case StateClosed:
+		if !failed {
+			b.failures = 0
+			return
+		}
+
+		b.failures++
+		if b.failures >= cb.policy.FailureThreshold {
+			cb.setState(host, b, StateOpen)
+		}
+	case StateHalfOpen:
+		if failed {
+			b.failures++
+			cb.setState(host, b, StateOpen)
+
+			return
+		}
+
+		b.successes++
+		if b.successes >= cb.policy.HalfOpenProbes {
+			cb.setState(host, b, StateClosed)
+		}
+	}
+}
+
+// release frees the probe of a canceled request without recording a result.
+func (cb *CircuitBreaker) release(host string, generation uint64) {
+	cb.mu.Lock()
+	defer cb.mu.Unlock()
+
+	if b := cb.hosts[host]; b.generation == generation && b.state == StateHalfOpen {
+		b.probes--
+	}
+}
+
+func (cb *CircuitBreaker) setState(host string, b *breaker, state BreakerState) {
+	from := b.state
+
+	if b.transitions == nil {
+		b.transitions = make(map[BreakerState]uint64)
+	}
+
+	b.state = state
+	b.transitions[state]++
+	b.generation++
+	b.probes = 0
+	b.successes = 0
+
+	switch state {
+	case StateOpen:
+		b.openedAt = cb.options.clock.Now()
+	case StateClosed:
+		b.failures = 0
+	}
+
+	if cb.policy.OnStateChange != nil {
+		cb.policy.OnStateChange(host, from, state)
+	}
+}
+
+func defaultIsFailure(resp *http.Response, err error) bool {
+	if err != nil {
+		return true
+	}
+
+	return resp.StatusCode >= http.StatusInternalServerError
+}
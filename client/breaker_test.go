@@ -0,0 +1,135 @@
+package client
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/bombsimon/http-helpers/clock"
+)
+
+func Test_CircuitBreaker(t *testing.T) {
+	var (
+		clk         = clock.NewFake(time.Now())
+		status      = http.StatusServiceUnavailable
+		sent        int
+		transitions []string
+	)
+
+	cb := NewCircuitBreaker(BreakerPolicy{
+		FailureThreshold: 3,
+		OpenTimeout:      10 * time.Second,
+		OnStateChange: func(host string, from, to BreakerState) {
+			transitions = append(transitions, host+":"+from.String()+"->"+to.String())
+		},
+	}, WithClock(clk))
+
+	rt := WrapTransport(
+		RoundTripperFunc(func(r *http.Request) (*http.Response, error) {
+			sent++
+			return &http.Response{StatusCode: status, Body: io.NopCloser(strings.NewReader("")), Request: r}, nil
+		}),
+		cb.Tripperware(),
+	)
+
+	do := func() error {
+		req, err := http.NewRequest(http.MethodGet, "http://users.internal/", nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		resp, err := rt.RoundTrip(req)
+		if err == nil {
+			resp.Body.Close()
+		}
+
+		return err
+	}
+
+	for range 3 {
+		if err := do(); err != nil {
+			t.Fatalf("unexpected error while closed: %s", err)
+		}
+	}
+
+	if cb.State("users.internal") != StateOpen {
+		t.Fatalf("expected breaker to be open, got: %s", cb.State("users.internal"))
+	}
+
+	if err := do(); !errors.Is(err, ErrCircuitOpen) {
+		t.Fatalf("expected ErrCircuitOpen, got: %v", err)
+	}
+
+	if sent != 3 {
+		t.Fatalf("expected open breaker to fail fast, sent: %d", sent)
+	}
+
+	// A failing probe opens the breaker again.
+	clk.Advance(10 * time.Second)
+
+	if err := do(); err != nil {
+		t.Fatalf("unexpected error for probe: %s", err)
+	}
+
+	if err := do(); !errors.Is(err, ErrCircuitOpen) {
+		t.Fatalf("expected ErrCircuitOpen after failed probe, got: %v", err)
+	}
+
+	// A successful probe closes it.
+	clk.Advance(10 * time.Second)
+
+	status = http.StatusOK
+
+	if err := do(); err != nil {
+		t.Fatalf("unexpected error for probe: %s", err)
+	}
+
+	if cb.State("users.internal") != StateClosed {
+		t.Fatalf("expected breaker to be closed, got: %s", cb.State("users.internal"))
+	}
+
+	expected := []string{
+		"users.internal:closed->open",
+		"users.internal:open->half-open",
+		"users.internal:half-open->open",
+		"users.internal:open->half-open",
+		"users.internal:half-open->closed",
+	}
+
+	if got := strings.Join(transitions, ","); got != strings.Join(expected, ",") {
+		t.Fatalf("unexpected transitions: %s", got)
+	}
+
+	stats := cb.Stats()
+	if len(stats) != 1 || stats[0].Rejected != 2 || stats[0].State != StateClosed {
+		t.Fatalf("unexpected stats: %+v", stats)
+	}
+}
+
+func Test_CircuitBreakerHalfOpenProbes(t *testing.T) {
+	clk := clock.NewFake(time.Now())
+	cb := NewCircuitBreaker(BreakerPolicy{FailureThreshold: 1, OpenTimeout: time.Second}, WithClock(clk))
+
+	generation, err := cb.allow("a")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cb.record("a", generation, true)
+	clk.Advance(time.Second)
+
+	if _, err := cb.allow("a"); err != nil {
+		t.Fatalf("expected first probe to be allowed, got: %s", err)
+	}
+
+	if _, err := cb.allow("a"); !errors.Is(err, ErrCircuitOpen) {
+		t.Fatalf("expected concurrent probe to be rejected, got: %v", err)
+	}
+
+	if cb.State("b") != StateClosed {
+		t.Fatal("expected breakers to be per host")
+	}
+}
@@ -0,0 +1,208 @@
+package client
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/bombsimon/http-helpers/cache"
+	"github.com/bombsimon/http-helpers/clock"
+)
+
+// maxCachedBodySize is the largest response body Cache stores or shares
+// between concurrent requests, larger responses are only returned to the
+// request that sent them.
+const maxCachedBodySize = 10 << 20
+
+// Cache returns a tripperware caching GET responses per URL and coalescing
+// concurrent identical requests into a single request, cutting duplicate
+// traffic to e.g. rate limited third-party APIs. Responses are cached for ttl
+// unless they set their own freshness with Cache-Control or Expires, and
+// aren't cached if they set Cache-Control no-store or no-cache. Requests with
+// different credentials, the Authorization, Proxy-Authorization and Cookie
+// headers and the headers set with WithCacheCredentialHeaders, are cached and
+// coalesced separately, and requests with Cache-Control no-cache or no-store
+// are always sent. Responses setting cookies aren't shared with waiting
+// requests. If the shared request fails, the waiting requests are sent on
+// their own. The X-Cache response header is set to HIT, MISS or COALESCED.
+// Use WithCacheStore to share the cache between clients, responses with
+// Cache-Control private are then not cached.
+func Cache(ttl time.Duration, opts ...Option) Tripperware {
+	options := newOptions(opts...)
+
+	store := options.cacheStore
+	if store == nil {
+		store = cache.NewMemoryStore(1000)
+	}
+
+	credentialHeaders := append(
+		[]string{"Authorization", "Proxy-Authorization", "Cookie"},
+		options.cacheCredentialHeaders...,
+	)
+
+	return func(rt http.RoundTripper) http.RoundTripper {
+		return &cachingTransport{
+			base:  rt,
+			store: store,
+			// Only the in-process store is private to this client.
+			policy:            cache.Policy{TTL: ttl, Private: options.cacheStore == nil},
+			credentialHeaders: credentialHeaders,
+			clock:             options.clock,
+			calls:             map[string]*sharedCall{},
+		}
+	}
+}
+
+// sharedCall is a request in flight that concurrent identical requests wait
+// for.
+type sharedCall struct {
+	done  chan struct{}
+	entry *cache.Entry
+}
+
+type cachingTransport struct {
+	base              http.RoundTripper
+	store             cache.Store
+	policy            cache.Policy
+	credentialHeaders []string
+	clock             clock.Clock
+
+	mu    sync.Mutex
+	calls map[string]*sharedCall
+}
+
+func (t *cachingTransport) RoundTrip(r *http.Request) (*http.Response, error) {
+	if r.Method != http.MethodGet || noCache(r.Header.Get("Cache-Control")) {
+		return t.base.RoundTrip(r)
+	}
+
+	key := t.cacheKey(r)
+
+	if entry, ok := t.store.Get(r.Context(), key); ok && entry.Fresh(t.clock.Now()) {
+		return entry.Response(r, t.clock.Now(), "HIT"), nil
+	}
+
+	t.mu.Lock()
+	if call, ok := t.calls[key]; ok {
+		t.mu.Unlock()
+
+		select {
+		case <-call.done:
+		case <-r.Context().Done():
+			return nil, r.Context().Err()
+		}
+
+		if call.entry == nil {
+			return t.base.RoundTrip(r)
+		}
+
+		return call.entry.Response(r, t.clock.Now(), "COALESCED"), nil
+	}
+
+	call := &sharedCall{done: make(chan struct{})}
+	t.calls[key] = call
+	t.mu.Unlock()
+
+	defer func() {
+		t.mu.Lock()
+		delete(t.calls, key)
+		t.mu.Unlock()
+
+		close(call.done)
+	}()
+
+	resp, err := t.base.RoundTrip(r)
+	if err != nil {
+		return nil, err
+	}
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, maxCachedBodySize+1))
+	if err != nil {
+		_ = resp.Body.Close()
+		return nil, err
+	}
+
+	if len(body) > maxCachedBodySize {
+		resp.Body = &multiReadCloser{Reader: io.MultiReader(bytes.NewReader(body), resp.Body), Closer: resp.Body}
+		return resp, nil
+	}
+
+	_ = resp.Body.Close()
+
+	now := t.clock.Now()
+
+	// Cookies set for the request sending it aren't shared with the waiting
+	// requests.
+	if len(resp.Header.Values("Set-Cookie")) == 0 {
+		call.entry = &cache.Entry{Status: resp.StatusCode, Header: resp.Header.Clone(), Body: body, Stored: now}
+	}
+
+	if entry, ok := cache.NewEntry(resp.StatusCode, resp.Header.Clone(), body, now, t.policy); ok {
+		t.store.Set(context.WithoutCancel(r.Context()), key, entry)
+		resp.Header.Set("X-Cache", "MISS")
+	}
+
+	resp.Body = io.NopCloser(bytes.NewReader(body))
+	resp.ContentLength = int64(len(body))
+
+	return resp, nil
+}
+
+// cacheKey returns the key for the request, the URL and a hash of the
+// credential headers so credentials aren't stored in the key.
+func (t *cachingTransport) cacheKey(r *http.Request) string {
+	var (
+		hash           = sha256.New()
+		hasCredentials bool
+	)
+
+	for _, name := range t.credentialHeaders {
+		values := r.Header.Values(name)
+		if len(values) == 0 {
+			continue
+		}
+
+		hasCredentials = true
+
+		// The name and the number of values are written so values can't be
+		// moved between headers to get the same hash.
+		fmt.Fprintf(hash, "%s:%d\n", http.CanonicalHeaderKey(name), len(values))
+
+		for _, value := range values {
+			fmt.Fprintf(hash, "%d:%s\n", len(value), value)
+		}
+	}
+
+	key := r.URL.String()
+	if hasCredentials {
+		key += " " + hex.EncodeToString(hash.Sum(nil))
+	}
+
+	return key
+}
+
+// noCache returns true if the Cache-Control header has the no-cache or
+// no-store directive.
+func noCache(cacheControl string) bool {
+	for _, directive := range strings.Split(cacheControl, ",") {
+		switch strings.ToLower(strings.TrimSpace(directive)) {
+		case "no-cache", "no-store":
+			return true
+		}
+	}
+
+	return false
+}
+
+// multiReadCloser reads the buffered start of a body and then the rest of it.
+type multiReadCloser struct {
+	io.Reader
+	io.Closer
+}
@@ -0,0 +1,184 @@
+package client
+
+import (
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/bombsimon/http-helpers/cache"
+	"github.com/bombsimon/http-helpers/clock"
+)
+
+func Test_Cache(t *testing.T) {
+	var (
+		clk     = clock.NewFake(time.Now())
+		sent    atomic.Int32
+		release = make(chan struct{})
+	)
+
+	rt := WrapTransport(
+		RoundTripperFunc(func(r *http.Request) (*http.Response, error) {
+			sent.Add(1)
+
+			header := http.Header{}
+
+			switch r.URL.Path {
+			case "/slow":
+				<-release
+			case "/private":
+				header.Set("Cache-Control", "private, max-age=5")
+			case "/no-store":
+				header.Set("Cache-Control", "no-store")
+			}
+
+			return &http.Response{
+				StatusCode: http.StatusOK,
+				Header:     header,
+				Body:       io.NopCloser(strings.NewReader("body " + r.URL.Path)),
+				Request:    r,
+			}, nil
+		}),
+		Cache(time.Minute, WithClock(clk)),
+	)
+
+	do := func(path, authorization string) string {
+		req, err := http.NewRequest(http.MethodGet, "http://api.example.com"+path, nil)
+		if err != nil {
+			t.Error(err)
+			return ""
+		}
+
+		if authorization != "" {
+			req.Header.Set("Authorization", authorization)
+		}
+
+		resp, err := rt.RoundTrip(req)
+		if err != nil {
+			t.Error(err)
+			return ""
+		}
+
+		defer resp.Body.Close()
+
+		body, _ := io.ReadAll(resp.Body)
+		if string(body) != "body "+path {
+			t.Errorf("unexpected body: %s", body)
+		}
+
+		return resp.Header.Get("X-Cache")
+	}
+
+	// Concurrent identical requests share the request in flight.
+	wg := sync.WaitGroup{}
+
+	for range 5 {
+		wg.Add(1)
+
+		go func() {
+			defer wg.Done()
+			do("/slow", "")
+		}()
+	}
+
+	time.Sleep(20 * time.Millisecond)
+	close(release)
+	wg.Wait()
+
+	if sent.Load() != 1 {
+		t.Fatalf("expected concurrent requests to be coalesced, sent: %d", sent.Load())
+	}
+
+	for i, step := range []struct {
+		advance       time.Duration
+		path          string
+		authorization string
+		expectedCache string
+		expectedSent  int32
+	}{
+		{path: "/slow", expectedCache: "HIT", expectedSent: 1},
+		{path: "/slow", authorization: "Bearer other", expectedCache: "MISS", expectedSent: 2},
+		{path: "/private", expectedCache: "MISS", expectedSent: 3},
+		{path: "/private", expectedCache: "HIT", expectedSent: 3},
+		{path: "/no-store", expectedCache: "", expectedSent: 4},
+		{path: "/no-store", expectedCache: "", expectedSent: 5},
+		{advance: 10 * time.Second, path: "/private", expectedCache: "MISS", expectedSent: 6},
+		{advance: time.Minute, path: "/slow", expectedCache: "MISS", expectedSent: 7},
+	} {
+		clk.Advance(step.advance)
+
+		if cacheStatus := do(step.path, step.authorization); cacheStatus != step.expectedCache {
+			t.Fatalf("unexpected X-Cache for step %d, got: %q, expected: %q", i, cacheStatus, step.expectedCache)
+		}
+
+		if sent.Load() != step.expectedSent {
+			t.Fatalf("unexpected requests sent for step %d, got: %d, expected: %d", i, sent.Load(), step.expectedSent)
+		}
+	}
+}
+
+func Test_CacheCredentials(t *testing.T) {
+	base := RoundTripperFunc(func(r *http.Request) (*http.Response, error) {
+		header := http.Header{}
+		header.Set("Cache-Control", "private, max-age=60")
+
+		return &http.Response{
+			StatusCode: http.StatusOK,
+			Header:     header,
+			Body:       io.NopCloser(strings.NewReader("data for " + r.Header.Get("Cookie") + r.Header.Get("X-Api-Key"))),
+			Request:    r,
+		}, nil
+	})
+
+	do := func(rt http.RoundTripper, header, value string) (string, string) {
+		req, _ := http.NewRequest(http.MethodGet, "http://api.example.com/me", nil)
+		req.Header.Set(header, value)
+
+		resp, err := rt.RoundTrip(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		defer resp.Body.Close()
+
+		body, _ := io.ReadAll(resp.Body)
+
+		return string(body), resp.Header.Get("X-Cache")
+	}
+
+	rt := WrapTransport(base, Cache(time.Minute, WithCacheCredentialHeaders("X-Api-Key")))
+
+	for i, step := range []struct {
+		header        string
+		value         string
+		expectedBody  string
+		expectedCache string
+	}{
+		{header: "Cookie", value: "session=alice", expectedBody: "data for session=alice", expectedCache: "MISS"},
+		{header: "Cookie", value: "session=bob", expectedBody: "data for session=bob", expectedCache: "MISS"},
+		{header: "Cookie", value: "session=alice", expectedBody: "data for session=alice", expectedCache: "HIT"},
+		{header: "X-Api-Key", value: "alice", expectedBody: "data for alice", expectedCache: "MISS"},
+		{header: "X-Api-Key", value: "bob", expectedBody: "data for bob", expectedCache: "MISS"},
+	} {
+		body, cacheStatus := do(rt, step.header, step.value)
+		if body != step.expectedBody || cacheStatus != step.expectedCache {
+			t.Fatalf(
+				"unexpected response for step %d, got: %q (%s), expected: %q (%s)",
+				i, body, cacheStatus, step.expectedBody, step.expectedCache,
+			)
+		}
+	}
+
+	// A store that may be shared between clients doesn't keep private
+	// responses.
+	shared := WrapTransport(base, Cache(time.Minute, WithCacheStore(cache.NewMemoryStore(10))))
+
+	for range 2 {
+		if _, cacheStatus := do(shared, "Cookie", "session=alice"); cacheStatus != "" {
+			t.Fatalf("expected private response to not be cached in a shared store, got: %s", cacheStatus)
+		}
+	}
+}
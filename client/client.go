@@ -0,0 +1,46 @@
+package client
+
+/*
+Middlewares for outgoing requests, mirroring the server side chain package. A
+Tripperware wraps an http.RoundTripper the same way a middleware wraps an
+http.Handler, so logging, metrics, retries and other cross-cutting behavior
+can be added to any http.Client.
+
+	httpClient := &http.Client{
+		Transport: client.WrapTransport(
+			http.DefaultTransport,
+			myTripperware,
+			myOtherTripperware,
+		),
+	}
+*/
+
+import "net/http"
+
+// Tripperware represents a middleware for outgoing requests, wrapping the
+// transport sending the request.
+type Tripperware func(http.RoundTripper) http.RoundTripper
+
+// RoundTripperFunc is a function implementing http.RoundTripper.
+type RoundTripperFunc func(*http.Request) (*http.Response, error)
+
+// RoundTrip calls fn(r).
+func (fn RoundTripperFunc) RoundTrip(r *http.Request) (*http.Response, error) {
+	return fn(r)
+}
+
+// WrapTransport will add all tripperwares in the passed order and return a
+// transport which may be used for an http.Client. Since they're added in the
+// order they're passed, they will be executed in the reverse order, the same
+// way as chain.AddMiddlewares. If rt is nil, http.DefaultTransport is used.
+func WrapTransport(rt http.RoundTripper, tripperwares ...Tripperware) http.RoundTripper {
+	if rt == nil {
+		rt = http.DefaultTransport
+	}
+
+	for _, tripperware := range tripperwares {
+		rt = tripperware(rt)
+	}
+
+	return rt
+}
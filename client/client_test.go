@@ -0,0 +1,51 @@
+package client
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func Test_WrapTransport(t *testing.T) {
+	var order []string
+
+	named := func(name string) Tripperware {
+		return func(rt http.RoundTripper) http.RoundTripper {
+			return RoundTripperFunc(func(r *http.Request) (*http.Response, error) {
+				order = append(order, name)
+				return rt.RoundTrip(r)
+			})
+		}
+	}
+
+	transport := WrapTransport(
+		RoundTripperFunc(func(r *http.Request) (*http.Response, error) {
+			order = append(order, "transport")
+			return &http.Response{StatusCode: http.StatusNoContent, Request: r}, nil
+		}),
+		named("first"),
+		named("second"),
+	)
+
+	req, err := http.NewRequest(http.MethodGet, "http://example.com", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	resp, err := (&http.Client{Transport: transport}).Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if resp.StatusCode != http.StatusNoContent {
+		t.Fatalf("unexpected status: %d", resp.StatusCode)
+	}
+
+	if got := strings.Join(order, ","); got != "second,first,transport" {
+		t.Fatalf("unexpected order: %s", got)
+	}
+
+	if WrapTransport(nil) != http.DefaultTransport {
+		t.Fatal("expected nil transport to default to http.DefaultTransport")
+	}
+}
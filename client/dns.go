@@ -0,0 +1,262 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"net/netip"
+	"sync"
+	"time"
+)
+
+// dnsLookupTimeout bounds lookups, which aren't canceled when the request
+// waiting for them is since other requests may wait for the same lookup.
+const dnsLookupTimeout = 10 * time.Second
+
+// Resolver resolves a host name to addresses. The TTL is the time the
+// addresses may be cached, 0 to use the TTL set with WithDNSTTL.
+type Resolver interface {
+	Resolve(ctx context.Context, host string) ([]netip.Addr, time.Duration, error)
+}
+
+// ResolverFunc is a function implementing Resolver.
+type ResolverFunc func(ctx context.Context, host string) ([]netip.Addr, time.Duration, error)
+
+// Resolve calls fn(ctx, host).
+func (fn ResolverFunc) Resolve(ctx context.Context, host string) ([]netip.Addr, time.Duration, error) {
+	return fn(ctx, host)
+}
+
+// NetResolver adapts a *net.Resolver to a Resolver. The TTL of the records
+// isn't available from net.Resolver so the TTL set with WithDNSTTL is used.
+func NetResolver(resolver *net.Resolver) Resolver {
+	return ResolverFunc(func(ctx context.Context, host string) ([]netip.Addr, time.Duration, error) {
+		addrs, err := resolver.LookupNetIP(ctx, "ip", host)
+		return addrs, 0, err
+	})
+}
+
+// DNSCache caches resolved addresses in-process to avoid a lookup for each new
+// connection. Entries are cached for their TTL and host names that don't exist
+// for the negative TTL. Expired entries are served for up to one more TTL
+// while they're refreshed in the background, so a slow or failing resolver
+// doesn't add latency to requests for hosts that are in use. Concurrent
+// lookups of the same host share a single lookup.
+type DNSCache struct {
+	options *options
+
+	mu      sync.Mutex
+	entries map[string]*dnsEntry
+}
+
+type dnsEntry struct {
+	// ready is closed when the lookup is done, the fields below it are
+	// immutable after that except for refreshing which is guarded by the
+	// cache mutex.
+	ready chan struct{}
+
+	addrs      []netip.Addr
+	err        error
+	expires    time.Time
+	ttl        time.Duration
+	refreshing bool
+}
+
+// NewDNSCache creates a DNS cache. Use WithResolver to set the resolver,
+// WithDNSTTL and WithDNSNegativeTTL to set the TTLs and WithDialer to set the
+// dialer used by DialContext.
+func NewDNSCache(opts ...Option) *DNSCache {
+	return &DNSCache{
+		options: newOptions(opts...),
+		entries: make(map[string]*dnsEntry),
+	}
+}
+
+// LookupNetIP returns the addresses of the host from the cache, resolving it if
+// it's not cached or has expired.
+func (c *DNSCache) LookupNetIP(ctx context.Context, host string) ([]netip.Addr, error) {
+	now := c.options.clock.Now()
+
+	c.mu.Lock()
+
+	entry, ok := c.entries[host]
+
+	switch {
+	case !ok:
+		entry = c.startLookup(host)
+	case !entry.isReady() || now.Before(entry.expires):
+	case entry.err == nil && now.Before(entry.expires.Add(entry.ttl)):
+		if !entry.refreshing {
+			entry.refreshing = true
+
+			go c.refresh(host, entry)
+		}
+	default:
+		entry = c.startLookup(host)
+	}
+
+	c.mu.Unlock()
+
+	select {
+	case <-entry.ready:
+		return entry.addrs, entry.err
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+// DialContext dials the address, resolving the host with the cache. The
+// addresses are tried in order until a connection is established.
+func (c *DNSCache) DialContext(ctx context.Context, network, address string) (net.Conn, error) {
+	host, port, err := net.SplitHostPort(address)
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err := netip.ParseAddr(host); err == nil {
+		return c.options.dialer.DialContext(ctx, network, address)
+	}
+
+	addrs, err := c.LookupNetIP(ctx, host)
+	if err != nil {
+		return nil, err
+	}
+
+	var firstErr error
+
+	for _, addr := range addrs {
+		if network == "tcp4" && !addr.Unmap().Is4() || network == "tcp6" && addr.Unmap().Is4() {
+			continue
+		}
+
+		conn, err := c.options.dialer.DialContext(ctx, network, net.JoinHostPort(addr.String(), port))
+		if err == nil {
+			return conn, nil
+		}
+
+		if firstErr == nil {
+			firstErr = err
+		}
+
+		if ctx.Err() != nil {
+			break
+		}
+	}
+
+	if firstErr == nil {
+		firstErr = &net.DNSError{Err: "no suitable address found", Name: host, IsNotFound: true}
+	}
+
+	return nil, firstErr
+}
+
+// startLookup adds a pending entry for the host and resolves it in the
+// background. The cache mutex must be held.
+func (c *DNSCache) startLookup(host string) *dnsEntry {
+	entry := &dnsEntry{ready: make(chan struct{})}
+	c.entries[host] = entry
+
+	go func() {
+		c.resolve(host, entry)
+		close(entry.ready)
+	}()
+
+	return entry
+}
+
+// refresh resolves a stale entry and replaces it, unless the lookup failed
+// with a temporary error in which case the stale entry is kept until it
+// expires.
+func (c *DNSCache) refresh(host string, stale *dnsEntry) {
+	entry := &dnsEntry{ready: make(chan struct{})}
+	c.resolve(host, entry)
+	close(entry.ready)
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	stale.refreshing = false
+
+	if entry.err != nil && !isNotFound(entry.err) {
+		return
+	}
+
+	if c.entries[host] == stale {
+		c.entries[host] = entry
+	}
+}
+
+// resolve looks up the host and sets the result on the entry.
+func (c *DNSCache) resolve(host string, entry *dnsEntry) {
+	ctx, cancel := context.WithTimeout(context.Background(), dnsLookupTimeout)
+	defer cancel()
+
+	addrs, ttl, err := c.options.resolver.Resolve(ctx, host)
+
+	switch {
+	case err == nil && len(addrs) == 0:
+		err = &net.DNSError{Err: "no such host", Name: host, IsNotFound: true}
+		ttl = c.options.dnsNegativeTTL
+	case isNotFound(err):
+		ttl = c.options.dnsNegativeTTL
+	case err != nil:
+		// Temporary errors aren't cached.
+		ttl = 0
+	case ttl <= 0:
+		ttl = c.options.dnsTTL
+	}
+
+	entry.addrs = addrs
+	entry.err = err
+	entry.ttl = ttl
+	entry.expires = c.options.clock.Now().Add(ttl)
+
+	c.mu.Lock()
+	c.evictExpired()
+	c.mu.Unlock()
+}
+
+// evictExpired removes entries that can't be served anymore. The cache mutex
+// must be held.
+func (c *DNSCache) evictExpired() {
+	now := c.options.clock.Now()
+
+	for host, entry := range c.entries {
+		if entry.isReady() && !entry.refreshing && !now.Before(entry.expires.Add(entry.ttl)) {
+			delete(c.entries, host)
+		}
+	}
+}
+
+func (e *dnsEntry) isReady() bool {
+	select {
+	case <-e.ready:
+		return true
+	default:
+		return false
+	}
+}
+
+func isNotFound(err error) bool {
+	var dnsErr *net.DNSError
+
+	return errors.As(err, &dnsErr) && dnsErr.IsNotFound
+}
+
+// NewTransport returns a clone of http.DefaultTransport dialing with a
+// DNSCache, the one set with WithDNSCache or a new one created with the
+// options.
+func NewTransport(opts ...Option) *http.Transport {
+	options := newOptions(opts...)
+
+	cache := options.dnsCache
+	if cache == nil {
+		cache = NewDNSCache(opts...)
+	}
+
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.DialContext = cache.DialContext
+
+	return transport
+}
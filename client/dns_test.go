@@ -0,0 +1,197 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/bombsimon/http-helpers/clock"
+)
+
+func Test_DNSCache(t *testing.T) {
+	var (
+		clk      = clock.NewFake(time.Now())
+		lookups  = make(chan string, 10)
+		mu       sync.Mutex
+		addr     = netip.MustParseAddr("10.0.0.1")
+		failures bool
+	)
+
+	cache := NewDNSCache(
+		WithClock(clk),
+		WithDNSTTL(time.Minute),
+		WithDNSNegativeTTL(10*time.Second),
+		WithResolver(ResolverFunc(func(ctx context.Context, host string) ([]netip.Addr, time.Duration, error) {
+			mu.Lock()
+			defer mu.Unlock()
+
+			defer func() { lookups <- host }()
+
+			switch {
+			case host == "missing.internal":
+				return nil, 0, &net.DNSError{Err: "no such host", Name: host, IsNotFound: true}
+			case failures:
+				return nil, 0, &net.DNSError{Err: "server misbehaving", Name: host, IsTemporary: true}
+			default:
+				return []netip.Addr{addr}, 0, nil
+			}
+		})),
+	)
+
+	lookup := func(host string) ([]netip.Addr, error) {
+		t.Helper()
+		return cache.LookupNetIP(context.Background(), host)
+	}
+
+	expectLookups := func(n int) {
+		t.Helper()
+
+		for range n {
+			select {
+			case <-lookups:
+			case <-time.After(time.Second):
+				t.Fatal("expected a lookup")
+			}
+		}
+
+		select {
+		case host := <-lookups:
+			t.Fatalf("unexpected lookup of %s", host)
+		default:
+		}
+	}
+
+	// Concurrent lookups share a single lookup.
+	var wg sync.WaitGroup
+	for range 5 {
+		wg.Add(1)
+
+		go func() {
+			defer wg.Done()
+
+			if addrs, err := lookup("users.internal"); err != nil || addrs[0] != addr {
+				t.Errorf("unexpected result: %v, %v", addrs, err)
+			}
+		}()
+	}
+
+	wg.Wait()
+	expectLookups(1)
+
+	// Cached within the TTL.
+	clk.Advance(30 * time.Second)
+
+	if _, err := lookup("users.internal"); err != nil {
+		t.Fatal(err)
+	}
+
+	expectLookups(0)
+
+	// Expired entries are served stale while refreshed in the background.
+	mu.Lock()
+	addr = netip.MustParseAddr("10.0.0.2")
+	mu.Unlock()
+
+	clk.Advance(time.Minute)
+
+	if addrs, err := lookup("users.internal"); err != nil || addrs[0] != netip.MustParseAddr("10.0.0.1") {
+		t.Fatalf("expected stale address, got: %v, %v", addrs, err)
+	}
+
+	expectLookups(1)
+
+	// The refresh replaces the entry once done.
+	for i := 0; ; i++ {
+		addrs, _ := lookup("users.internal")
+		if addrs[0] == netip.MustParseAddr("10.0.0.2") {
+			break
+		}
+
+		if i > 100 {
+			t.Fatal("expected refreshed address")
+		}
+
+		time.Sleep(time.Millisecond)
+	}
+
+	// A failing refresh keeps the stale entry.
+	mu.Lock()
+	failures = true
+	mu.Unlock()
+
+	clk.Advance(90 * time.Second)
+
+	if addrs, err := lookup("users.internal"); err != nil || addrs[0] != netip.MustParseAddr("10.0.0.2") {
+		t.Fatalf("expected stale address, got: %v, %v", addrs, err)
+	}
+
+	expectLookups(1)
+
+	// Hosts that don't exist are cached for the negative TTL.
+	for range 2 {
+		if _, err := lookup("missing.internal"); !isNotFound(err) {
+			t.Fatalf("expected not found error, got: %v", err)
+		}
+	}
+
+	expectLookups(1)
+
+	clk.Advance(10 * time.Second)
+
+	if _, err := lookup("missing.internal"); !isNotFound(err) {
+		t.Fatalf("expected not found error, got: %v", err)
+	}
+
+	expectLookups(1)
+}
+
+func Test_NewTransport(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = io.WriteString(w, "ok")
+	}))
+	defer server.Close()
+
+	_, port, _ := net.SplitHostPort(server.Listener.Addr().String())
+
+	var lookups atomic.Int32
+
+	httpClient := &http.Client{Transport: NewTransport(
+		WithResolver(ResolverFunc(func(ctx context.Context, host string) ([]netip.Addr, time.Duration, error) {
+			lookups.Add(1)
+
+			if host != "users.internal" {
+				return nil, 0, errors.New("unexpected host")
+			}
+
+			return []netip.Addr{netip.MustParseAddr("127.0.0.1")}, 0, nil
+		})),
+	)}
+
+	for range 2 {
+		resp, err := httpClient.Get("http://users.internal:" + port)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		body, _ := io.ReadAll(resp.Body)
+		resp.Body.Close()
+
+		if string(body) != "ok" {
+			t.Fatalf("unexpected body: %s", body)
+		}
+
+		httpClient.CloseIdleConnections()
+	}
+
+	if lookups.Load() != 1 {
+		t.Fatalf("expected a single lookup, got: %d", lookups.Load())
+	}
+}
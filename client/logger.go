@@ -0,0 +1,190 @@
+package client
+
+import (
+	"bytes"
+	"io"
+	"log/slog"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/bombsimon/http-helpers/httpctx"
+)
+
+//nolint:gochecknoglobals // Headers never logged in clear text.
+var redactedHeaders = map[string]struct{}{
+	"Authorization":       {},
+	"Proxy-Authorization": {},
+	"Cookie":              {},
+	"Set-Cookie":          {},
+}
+
+// Logger returns a tripperware logging each outgoing request with the method,
+// host, path, status, elapsed time until the response headers were received
+// and attempt number, the same way the Logger middleware logs incoming
+// requests. Failed requests are logged as errors. Add it before Retry to log
+// each attempt.
+//
+// Use WithHeaderLogging to log headers and WithBodyCapture to log the start of
+// the bodies. With body capture the request is logged when the response body
+// is closed.
+func Logger(opts ...Option) Tripperware {
+	options := newOptions(opts...)
+
+	return func(rt http.RoundTripper) http.RoundTripper {
+		return RoundTripperFunc(func(r *http.Request) (*http.Response, error) {
+			var requestBody, responseBody *capture
+
+			if options.bodyLimit > 0 && r.Body != nil && r.Body != http.NoBody {
+				requestBody = &capture{limit: options.bodyLimit}
+
+				body := r.Body
+				r = r.Clone(r.Context())
+				r.Body = struct {
+					io.Reader
+					io.Closer
+				}{io.TeeReader(body, requestBody), body}
+			}
+
+			startTime := time.Now()
+			resp, err := rt.RoundTrip(r)
+			elapsed := time.Since(startTime)
+
+			log := func() {
+				logClientRequest(r, resp, err, options, elapsed, requestBody, responseBody)
+			}
+
+			if options.bodyLimit <= 0 || err != nil {
+				log()
+				return resp, err
+			}
+
+			responseBody = &capture{limit: options.bodyLimit}
+			resp.Body = &loggedBody{
+				ReadCloser: resp.Body,
+				reader:     io.TeeReader(resp.Body, responseBody),
+				log:        log,
+			}
+
+			return resp, nil
+		})
+	}
+}
+
+// logClientRequest logs the outgoing request when it's done.
+func logClientRequest(
+	r *http.Request,
+	resp *http.Response,
+	err error,
+	options *options,
+	elapsed time.Duration,
+	requestBody, responseBody *capture,
+) {
+	attrs := []slog.Attr{
+		slog.String("method", r.Method),
+		slog.String("host", r.URL.Host),
+		slog.String("path", r.URL.Path),
+		slog.Duration("elapsed", elapsed),
+		slog.Int("attempt", Attempt(r.Context())),
+	}
+
+	if resp != nil {
+		attrs = append(attrs, slog.Int("status", resp.StatusCode))
+	}
+
+	if requestID, ok := httpctx.RequestID(r.Context()); ok {
+		attrs = append(attrs, slog.String("request_id", requestID))
+	}
+
+	if trace, ok := httpctx.TraceFrom(r.Context()); ok {
+		attrs = append(attrs,
+			slog.String("trace_id", trace.TraceID),
+			slog.String("span_id", trace.SpanID),
+		)
+	}
+
+	if options.logHeaders {
+		attrs = append(attrs, headerAttr("request_headers", r.Header))
+
+		if resp != nil {
+			attrs = append(attrs, headerAttr("response_headers", resp.Header))
+		}
+	}
+
+	if requestBody != nil {
+		attrs = append(attrs, slog.String("request_body", requestBody.String()))
+	}
+
+	if responseBody != nil {
+		attrs = append(attrs, slog.String("response_body", responseBody.String()))
+	}
+
+	level := slog.LevelInfo
+	if err != nil {
+		level = slog.LevelError
+		attrs = append(attrs, slog.Any("error", err))
+	}
+
+	options.logger.LogAttrs(r.Context(), level, "request sent", attrs...)
+}
+
+func headerAttr(key string, header http.Header) slog.Attr {
+	attrs := make([]any, 0, len(header))
+
+	for name, values := range header {
+		value := strings.Join(values, ", ")
+		if _, ok := redactedHeaders[http.CanonicalHeaderKey(name)]; ok {
+			value = "REDACTED"
+		}
+
+		attrs = append(attrs, slog.String(name, value))
+	}
+
+	return slog.Group(key, attrs...)
+}
+
+// capture keeps the first limit bytes written to it. It's safe for concurrent
+// use since the transport may write the request body after returning the
+// response.
+type capture struct {
+	mu    sync.Mutex
+	limit int
+	buf   bytes.Buffer
+}
+
+func (c *capture) Write(p []byte) (int, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if room := c.limit - c.buf.Len(); room > 0 {
+		c.buf.Write(p[:min(len(p), room)])
+	}
+
+	return len(p), nil
+}
+
+func (c *capture) String() string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.buf.String()
+}
+
+// loggedBody captures the response body and logs the request when closed.
+type loggedBody struct {
+	io.ReadCloser
+	reader io.Reader
+	once   sync.Once
+	log    func()
+}
+
+func (b *loggedBody) Read(p []byte) (int, error) {
+	return b.reader.Read(p)
+}
+
+func (b *loggedBody) Close() error {
+	defer b.once.Do(b.log)
+
+	return b.ReadCloser.Close()
+}
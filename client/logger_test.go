@@ -0,0 +1,113 @@
+package client
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io"
+	"log/slog"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func Test_Logger(t *testing.T) {
+	transport := RoundTripperFunc(func(r *http.Request) (*http.Response, error) {
+		if r.URL.Path == "/fail" {
+			return nil, errors.New("connection refused")
+		}
+
+		_, _ = io.ReadAll(r.Body)
+
+		return &http.Response{
+			StatusCode: http.StatusCreated,
+			Header:     http.Header{"Set-Cookie": {"session=secret"}},
+			Body:       io.NopCloser(strings.NewReader(`{"id":"123456789"}`)),
+			Request:    r,
+		}, nil
+	})
+
+	logRequest := func(t *testing.T, path string, opts ...Option) map[string]any {
+		t.Helper()
+
+		var buf bytes.Buffer
+
+		opts = append(opts, WithLogger(slog.New(slog.NewJSONHandler(&buf, nil))))
+		rt := WrapTransport(transport, Logger(opts...))
+
+		req, err := http.NewRequest(http.MethodPost, "http://users.internal"+path, strings.NewReader(`{"name":"bob"}`))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		req.Header.Set("Authorization", "Bearer secret")
+
+		if resp, err := rt.RoundTrip(req); err == nil {
+			_, _ = io.ReadAll(resp.Body)
+			resp.Body.Close()
+		}
+
+		var entry map[string]any
+		if err := json.Unmarshal(buf.Bytes(), &entry); err != nil {
+			t.Fatalf("could not decode log entry %q: %s", buf.String(), err)
+		}
+
+		return entry
+	}
+
+	t.Run("request", func(t *testing.T) {
+		entry := logRequest(t, "/users")
+
+		for key, expected := range map[string]any{
+			"level":   "INFO",
+			"msg":     "request sent",
+			"method":  http.MethodPost,
+			"host":    "users.internal",
+			"path":    "/users",
+			"status":  float64(http.StatusCreated),
+			"attempt": float64(1),
+		} {
+			if entry[key] != expected {
+				t.Fatalf("unexpected %s, got: %v, expected: %v", key, entry[key], expected)
+			}
+		}
+
+		if _, ok := entry["request_headers"]; ok {
+			t.Fatal("expected headers not to be logged by default")
+		}
+	})
+
+	t.Run("error", func(t *testing.T) {
+		entry := logRequest(t, "/fail")
+
+		if entry["level"] != "ERROR" || entry["error"] != "connection refused" {
+			t.Fatalf("unexpected log entry: %v", entry)
+		}
+	})
+
+	t.Run("headers are redacted", func(t *testing.T) {
+		entry := logRequest(t, "/users", WithHeaderLogging())
+
+		requestHeaders, _ := entry["request_headers"].(map[string]any)
+		if requestHeaders["Authorization"] != "REDACTED" {
+			t.Fatalf("expected Authorization to be redacted, got: %v", requestHeaders)
+		}
+
+		responseHeaders, _ := entry["response_headers"].(map[string]any)
+		if responseHeaders["Set-Cookie"] != "REDACTED" {
+			t.Fatalf("expected Set-Cookie to be redacted, got: %v", responseHeaders)
+		}
+	})
+
+	t.Run("body capture", func(t *testing.T) {
+		entry := logRequest(t, "/users", WithBodyCapture(8))
+
+		if entry["request_body"] != `{"name":` {
+			t.Fatalf("unexpected request body: %v", entry["request_body"])
+		}
+
+		if entry["response_body"] != `{"id":"1` {
+			t.Fatalf("unexpected response body: %v", entry["response_body"])
+		}
+	})
+}
@@ -0,0 +1,168 @@
+package client
+
+import (
+	"log/slog"
+	"net"
+	"time"
+
+	"github.com/bombsimon/http-helpers/cache"
+	"github.com/bombsimon/http-helpers/clock"
+)
+
+// Option is an option used to configure a tripperware. All tripperwares share
+// the same option type, options not relevant for a tripperware are ignored.
+type Option func(*options)
+
+type options struct {
+	logger *slog.Logger
+	clock  clock.Clock
+
+	// Logger.
+	logHeaders bool
+	bodyLimit  int
+
+	// Tracing.
+	b3 bool
+
+	// Request signing.
+	signedHeaders []string
+
+	// DNS cache.
+	dialer         *net.Dialer
+	resolver       Resolver
+	dnsTTL         time.Duration
+	dnsNegativeTTL time.Duration
+	dnsCache       *DNSCache
+
+	// Cache.
+	cacheStore             cache.Store
+	cacheCredentialHeaders []string
+}
+
+func newOptions(opts ...Option) *options {
+	o := &options{
+		logger: slog.Default(),
+		clock:  clock.Real(),
+		dialer: &net.Dialer{
+			Timeout:   30 * time.Second,
+			KeepAlive: 30 * time.Second,
+		},
+		resolver:       NetResolver(net.DefaultResolver),
+		dnsTTL:         30 * time.Second,
+		dnsNegativeTTL: 5 * time.Second,
+	}
+
+	for _, opt := range opts {
+		opt(o)
+	}
+
+	return o
+}
+
+// WithLogger sets the logger used by the tripperware. Defaults to
+// slog.Default(). Use logrusadapter.New to log with logrus.
+func WithLogger(logger *slog.Logger) Option {
+	return func(o *options) {
+		o.logger = logger
+	}
+}
+
+// WithClock sets the clock used for backoff and timeouts. Defaults to
+// clock.Real().
+func WithClock(c clock.Clock) Option {
+	return func(o *options) {
+		o.clock = c
+	}
+}
+
+// WithHeaderLogging makes Logger log the request and response headers. The
+// Authorization, Proxy-Authorization, Cookie and Set-Cookie headers are
+// redacted.
+func WithHeaderLogging() Option {
+	return func(o *options) {
+		o.logHeaders = true
+	}
+}
+
+// WithBodyCapture makes Logger log up to limit bytes of the request and
+// response bodies. The request is then logged when the response body is
+// closed.
+func WithBodyCapture(limit int) Option {
+	return func(o *options) {
+		o.bodyLimit = limit
+	}
+}
+
+// WithB3Propagation makes Tracing send the B3 headers, used by e.g. Zipkin, in
+// addition to the traceparent header.
+func WithB3Propagation() Option {
+	return func(o *options) {
+		o.b3 = true
+	}
+}
+
+// WithSignedHeaders sets headers signed by NewHMACSigner in addition to the
+// method, request URI, host, timestamp, nonce and payload hash, e.g.
+// Content-Type.
+func WithSignedHeaders(names ...string) Option {
+	return func(o *options) {
+		o.signedHeaders = append(o.signedHeaders, names...)
+	}
+}
+
+// WithDialer sets the dialer used by DNSCache and NewTransport. Defaults to a
+// dialer with the same timeouts as http.DefaultTransport.
+func WithDialer(dialer *net.Dialer) Option {
+	return func(o *options) {
+		o.dialer = dialer
+	}
+}
+
+// WithResolver sets the resolver used by DNSCache. Defaults to
+// net.DefaultResolver.
+func WithResolver(resolver Resolver) Option {
+	return func(o *options) {
+		o.resolver = resolver
+	}
+}
+
+// WithDNSTTL sets the time addresses are cached by DNSCache when the resolver
+// doesn't return a TTL. Defaults to 30 seconds.
+func WithDNSTTL(ttl time.Duration) Option {
+	return func(o *options) {
+		o.dnsTTL = ttl
+	}
+}
+
+// WithDNSNegativeTTL sets the time DNSCache caches that a host doesn't exist.
+// Defaults to 5 seconds.
+func WithDNSNegativeTTL(ttl time.Duration) Option {
+	return func(o *options) {
+		o.dnsNegativeTTL = ttl
+	}
+}
+
+// WithDNSCache sets the cache used by NewTransport, e.g. to share it between
+// transports. Defaults to a new cache created with the options.
+func WithDNSCache(cache *DNSCache) Option {
+	return func(o *options) {
+		o.dnsCache = cache
+	}
+}
+
+// WithCacheStore sets the store used by Cache, e.g. to share the cache between
+// clients. Defaults to an in-process store keeping up to 1000 responses.
+func WithCacheStore(store cache.Store) Option {
+	return func(o *options) {
+		o.cacheStore = store
+	}
+}
+
+// WithCacheCredentialHeaders sets headers, in addition to Authorization,
+// Proxy-Authorization and Cookie, holding credentials that Cache caches and
+// coalesces responses separately for, e.g. X-Api-Key.
+func WithCacheCredentialHeaders(names ...string) Option {
+	return func(o *options) {
+		o.cacheCredentialHeaders = append(o.cacheCredentialHeaders, names...)
+	}
+}
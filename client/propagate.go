@@ -0,0 +1,33 @@
+package client
+
+import (
+	"net/http"
+
+	"github.com/bombsimon/http-helpers/httpctx"
+)
+
+// PropagateHeaders returns a tripperware attaching the headers in the request
+// context, captured from the incoming request by the PropagateHeaders
+// middleware, to outgoing requests. Headers already set on the outgoing
+// request aren't overwritten. Only use it with clients calling your own
+// services since the headers, e.g. auth context, are sent to every host.
+func PropagateHeaders() Tripperware {
+	return func(rt http.RoundTripper) http.RoundTripper {
+		return RoundTripperFunc(func(r *http.Request) (*http.Response, error) {
+			header, ok := httpctx.PropagatedHeaders(r.Context())
+			if !ok || len(header) == 0 {
+				return rt.RoundTrip(r)
+			}
+
+			r = r.Clone(r.Context())
+
+			for name, values := range header {
+				if _, ok := r.Header[name]; !ok {
+					r.Header[name] = values
+				}
+			}
+
+			return rt.RoundTrip(r)
+		})
+	}
+}
@@ -0,0 +1,42 @@
+package client
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	"github.com/bombsimon/http-helpers/httpctx"
+)
+
+func Test_PropagateHeaders(t *testing.T) {
+	var received http.Header
+
+	rt := WrapTransport(RoundTripperFunc(func(r *http.Request) (*http.Response, error) {
+		received = r.Header
+		return &http.Response{StatusCode: http.StatusOK, Body: http.NoBody, Request: r}, nil
+	}), PropagateHeaders())
+
+	ctx := httpctx.WithPropagatedHeaders(context.Background(), http.Header{
+		"X-Request-Id": {"abc"},
+		"X-Tenant-Id":  {"acme"},
+	})
+
+	req, _ := http.NewRequestWithContext(ctx, http.MethodGet, "http://users.internal/", nil)
+	req.Header.Set("X-Tenant-Id", "other")
+
+	if _, err := rt.RoundTrip(req); err != nil {
+		t.Fatal(err)
+	}
+
+	if received.Get("X-Request-Id") != "abc" {
+		t.Fatalf("expected request ID to be propagated, got: %v", received)
+	}
+
+	if received.Get("X-Tenant-Id") != "other" {
+		t.Fatalf("expected header set on the request to be kept, got: %v", received)
+	}
+
+	if req.Header.Get("X-Request-Id") != "" {
+		t.Fatal("expected the original request to be unmodified")
+	}
+}
@@ -0,0 +1,307 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"io"
+	"math/rand/v2"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/bombsimon/http-helpers/clock"
+)
+
+type attemptKey struct{}
+
+// Attempt returns the attempt number, starting at 1, of the request sent with
+// the context. Tripperwares added before Retry, and thereby executed after it,
+// see every attempt.
+func Attempt(ctx context.Context) int {
+	if attempt, ok := ctx.Value(attemptKey{}).(int); ok {
+		return attempt
+	}
+
+	return 1
+}
+
+// RetryPolicy configures Retry. The zero value retries twice with backoff
+// starting at 100 milliseconds.
+type RetryPolicy struct {
+	// MaxAttempts is the maximum number of attempts, including the first.
+	// Defaults to 3.
+	MaxAttempts int
+
+	// BaseDelay is the backoff before the first retry, doubled for each
+	// retry. Defaults to 100 milliseconds.
+	BaseDelay time.Duration
+
+	// MaxDelay caps the backoff. Requests where the server asks to wait
+	// longer with Retry-After aren't retried. Defaults to 10 seconds.
+	MaxDelay time.Duration
+
+	// AttemptTimeout, if set, is the timeout for each attempt, including
+	// reading the response body of the last attempt.
+	AttemptTimeout time.Duration
+
+	// Budget, if set, limits the number of retries so a failing dependency
+	// doesn't get a multiple of the normal traffic.
+	Budget *RetryBudget
+
+	// ShouldRetry decides if an attempt should be retried. Defaults to
+	// retrying errors, except for cancellation of the request and
+	// ErrCircuitOpen, and 429, 502, 503 and 504 responses.
+	ShouldRetry func(*http.Response, error) bool
+}
+
+// Retry returns a tripperware retrying failed requests with exponential
+// backoff and full jitter. Only requests that are safe to send again are
+// retried: the method must be idempotent (GET, HEAD, OPTIONS, TRACE, PUT and
+// DELETE) or the request must have an Idempotency-Key header, and the body, if
+// any, must be rewindable with Request.GetBody, which http.NewRequest sets for
+// bytes and strings readers. Retry-After headers on 429 and 503 responses are
+// honored.
+func Retry(policy RetryPolicy, opts ...Option) Tripperware {
+	options := newOptions(opts...)
+	policy = policy.withDefaults()
+
+	return func(rt http.RoundTripper) http.RoundTripper {
+		return RoundTripperFunc(func(r *http.Request) (*http.Response, error) {
+			if policy.Budget != nil {
+				policy.Budget.deposit()
+			}
+
+			retryable := canRetry(r)
+
+			for attempt := 1; ; attempt++ {
+				resp, err := policy.roundTrip(rt, r, attempt, options.clock)
+
+				if !retryable || attempt >= policy.MaxAttempts || !policy.ShouldRetry(resp, err) {
+					return resp, err
+				}
+
+				delay, ok := policy.delay(attempt, resp, options.clock.Now())
+				if !ok || policy.Budget != nil && !policy.Budget.withdraw() {
+					return resp, err
+				}
+
+				next, bodyErr := rewind(r)
+				if bodyErr != nil {
+					return resp, err
+				}
+
+				if resp != nil {
+					drain(resp)
+				}
+
+				if err := sleep(r.Context(), options.clock, delay); err != nil {
+					return nil, err
+				}
+
+				r = next
+			}
+		})
+	}
+}
+
+func (p RetryPolicy) withDefaults() RetryPolicy {
+	if p.MaxAttempts == 0 {
+		p.MaxAttempts = 3
+	}
+
+	if p.BaseDelay == 0 {
+		p.BaseDelay = 100 * time.Millisecond
+	}
+
+	if p.MaxDelay == 0 {
+		p.MaxDelay = 10 * time.Second
+	}
+
+	if p.ShouldRetry == nil {
+		p.ShouldRetry = defaultShouldRetry
+	}
+
+	return p
+}
+
+func (p RetryPolicy) roundTrip(rt http.RoundTripper, r *http.Request, attempt int, c clock.Clock) (*http.Response, error) {
+	ctx := context.WithValue(r.Context(), attemptKey{}, attempt)
+
+	if p.AttemptTimeout <= 0 {
+		return rt.RoundTrip(r.WithContext(ctx))
+	}
+
+	ctx, cancel := c.WithTimeout(ctx, p.AttemptTimeout)
+
+	resp, err := rt.RoundTrip(r.WithContext(ctx))
+	if err != nil {
+		cancel()
+		return nil, err
+	}
+
+	resp.Body = &cancelBody{ReadCloser: resp.Body, cancel: cancel}
+
+	return resp, nil
+}
+
+// delay returns the time to wait before the next attempt or false if the
+// server asked to wait longer than MaxDelay.
+func (p RetryPolicy) delay(attempt int, resp *http.Response, now time.Time) (time.Duration, bool) {
+	backoff := p.MaxDelay
+	if shift := attempt - 1; shift < 32 {
+		backoff = min(p.BaseDelay<<shift, p.MaxDelay)
+	}
+
+	delay := time.Duration(rand.Int64N(int64(backoff) + 1))
+
+	if resp == nil {
+		return delay, true
+	}
+
+	retryAfter, ok := parseRetryAfter(resp.Header.Get("Retry-After"), now)
+	if !ok {
+		return delay, true
+	}
+
+	if retryAfter > p.MaxDelay {
+		return 0, false
+	}
+
+	return max(delay, retryAfter), true
+}
+
+func defaultShouldRetry(resp *http.Response, err error) bool {
+	if err != nil {
+		return !errors.Is(err, context.Canceled) && !errors.Is(err, ErrCircuitOpen)
+	}
+
+	switch resp.StatusCode {
+	case http.StatusTooManyRequests, http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		return true
+	}
+
+	return false
+}
+
+// parseRetryAfter parses a Retry-After header, either in seconds or as an
+// HTTP date.
+func parseRetryAfter(value string, now time.Time) (time.Duration, bool) {
+	if value == "" {
+		return 0, false
+	}
+
+	if seconds, err := strconv.Atoi(value); err == nil {
+		return time.Duration(max(seconds, 0)) * time.Second, true
+	}
+
+	if t, err := http.ParseTime(value); err == nil {
+		return max(t.Sub(now), 0), true
+	}
+
+	return 0, false
+}
+
+// canRetry returns true if the request is idempotent and the body can be sent
+// again.
+func canRetry(r *http.Request) bool {
+	switch r.Method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions, http.MethodTrace, http.MethodPut, http.MethodDelete:
+	default:
+		if r.Header.Get("Idempotency-Key") == "" {
+			return false
+		}
+	}
+
+	return r.Body == nil || r.Body == http.NoBody || r.GetBody != nil
+}
+
+// rewind returns a copy of the request with a new body.
+func rewind(r *http.Request) (*http.Request, error) {
+	if r.Body == nil || r.Body == http.NoBody {
+		return r, nil
+	}
+
+	body, err := r.GetBody()
+	if err != nil {
+		return nil, err
+	}
+
+	next := r.Clone(r.Context())
+	next.Body = body
+
+	return next, nil
+}
+
+// drain reads the start of the body of a response that won't be used so the
+// connection can be reused.
+func drain(resp *http.Response) {
+	_, _ = io.CopyN(io.Discard, resp.Body, 4<<10)
+	_ = resp.Body.Close()
+}
+
+func sleep(ctx context.Context, c clock.Clock, d time.Duration) error {
+	timer := c.NewTimer(d)
+	defer timer.Stop()
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C():
+		return nil
+	}
+}
+
+// cancelBody cancels the context of the attempt when the response body is
+// closed.
+type cancelBody struct {
+	io.ReadCloser
+	cancel context.CancelFunc
+}
+
+func (b *cancelBody) Close() error {
+	defer b.cancel()
+
+	return b.ReadCloser.Close()
+}
+
+// RetryBudget limits retries to a ratio of the requests, shared by all
+// clients using the budget. Each request deposits the ratio and each retry
+// withdraws one, so with a ratio of 0.1 at most one retry per ten requests is
+// made once the initial balance is spent.
+type RetryBudget struct {
+	mu      sync.Mutex
+	ratio   float64
+	max     float64
+	balance float64
+}
+
+// NewRetryBudget creates a budget allowing retries for the ratio of the
+// requests. The balance starts at, and is capped to, burst retries.
+func NewRetryBudget(ratio float64, burst int) *RetryBudget {
+	return &RetryBudget{
+		ratio:   ratio,
+		max:     float64(burst),
+		balance: float64(burst),
+	}
+}
+
+func (b *RetryBudget) deposit() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.balance = min(b.balance+b.ratio, b.max)
+}
+
+func (b *RetryBudget) withdraw() bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.balance < 1 {
+		return false
+	}
+
+	b.balance--
+
+	return true
+}
@@ -0,0 +1,231 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+)
+
+func Test_Retry(t *testing.T) {
+	errConnect := errors.New("connection refused")
+
+	for _, tc := range []struct {
+		description      string
+		method           string
+		body             io.Reader
+		header           http.Header
+		policy           RetryPolicy
+		responses        []int
+		expectedAttempts int
+		expectedStatus   int
+	}{
+		{
+			description:      "retries until success",
+			method:           http.MethodGet,
+			responses:        []int{http.StatusServiceUnavailable, 0, http.StatusOK},
+			expectedAttempts: 3,
+			expectedStatus:   http.StatusOK,
+		},
+		{
+			description:      "stops at max attempts",
+			method:           http.MethodGet,
+			policy:           RetryPolicy{MaxAttempts: 2},
+			responses:        []int{http.StatusBadGateway, http.StatusBadGateway, http.StatusOK},
+			expectedAttempts: 2,
+			expectedStatus:   http.StatusBadGateway,
+		},
+		{
+			description:      "client errors are not retried",
+			method:           http.MethodGet,
+			responses:        []int{http.StatusNotFound, http.StatusOK},
+			expectedAttempts: 1,
+			expectedStatus:   http.StatusNotFound,
+		},
+		{
+			description:      "post is not retried",
+			method:           http.MethodPost,
+			body:             strings.NewReader("body"),
+			responses:        []int{http.StatusServiceUnavailable, http.StatusOK},
+			expectedAttempts: 1,
+			expectedStatus:   http.StatusServiceUnavailable,
+		},
+		{
+			description:      "post with idempotency key is retried with body",
+			method:           http.MethodPost,
+			body:             strings.NewReader("body"),
+			header:           http.Header{"Idempotency-Key": {"abc"}},
+			responses:        []int{http.StatusServiceUnavailable, http.StatusOK},
+			expectedAttempts: 2,
+			expectedStatus:   http.StatusOK,
+		},
+		{
+			description:      "put without rewindable body is not retried",
+			method:           http.MethodPut,
+			body:             io.MultiReader(strings.NewReader("body")),
+			responses:        []int{http.StatusServiceUnavailable, http.StatusOK},
+			expectedAttempts: 1,
+			expectedStatus:   http.StatusServiceUnavailable,
+		},
+		{
+			description:      "retry budget is respected",
+			method:           http.MethodGet,
+			policy:           RetryPolicy{Budget: NewRetryBudget(0.1, 1)},
+			responses:        []int{http.StatusServiceUnavailable, http.StatusServiceUnavailable, http.StatusOK},
+			expectedAttempts: 2,
+			expectedStatus:   http.StatusServiceUnavailable,
+		},
+	} {
+		t.Run(tc.description, func(t *testing.T) {
+			var attempts []int
+
+			transport := RoundTripperFunc(func(r *http.Request) (*http.Response, error) {
+				attempts = append(attempts, Attempt(r.Context()))
+
+				if r.Body != nil {
+					body, _ := io.ReadAll(r.Body)
+					if string(body) != "body" {
+						t.Fatalf("unexpected body: %q", body)
+					}
+				}
+
+				status := tc.responses[len(attempts)-1]
+				if status == 0 {
+					return nil, errConnect
+				}
+
+				return &http.Response{
+					StatusCode: status,
+					Header:     http.Header{},
+					Body:       io.NopCloser(strings.NewReader("")),
+					Request:    r,
+				}, nil
+			})
+
+			tc.policy.BaseDelay = time.Millisecond
+			rt := WrapTransport(transport, Retry(tc.policy))
+
+			req, err := http.NewRequest(tc.method, "http://example.com", tc.body)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			for k, v := range tc.header {
+				req.Header[k] = v
+			}
+
+			resp, err := rt.RoundTrip(req)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if resp.StatusCode != tc.expectedStatus {
+				t.Fatalf("unexpected status, got: %d, expected: %d", resp.StatusCode, tc.expectedStatus)
+			}
+
+			if len(attempts) != tc.expectedAttempts {
+				t.Fatalf("unexpected attempts, got: %d, expected: %d", len(attempts), tc.expectedAttempts)
+			}
+
+			for i, attempt := range attempts {
+				if attempt != i+1 {
+					t.Fatalf("unexpected attempt number, got: %d, expected: %d", attempt, i+1)
+				}
+			}
+		})
+	}
+}
+
+func Test_RetryAttemptTimeout(t *testing.T) {
+	var calls int
+
+	transport := RoundTripperFunc(func(r *http.Request) (*http.Response, error) {
+		calls++
+
+		if calls == 1 {
+			<-r.Context().Done()
+			return nil, r.Context().Err()
+		}
+
+		return &http.Response{StatusCode: http.StatusOK, Body: http.NoBody, Request: r}, nil
+	})
+
+	rt := WrapTransport(transport, Retry(RetryPolicy{
+		BaseDelay:      time.Millisecond,
+		AttemptTimeout: 10 * time.Millisecond,
+	}))
+
+	req, err := http.NewRequest(http.MethodGet, "http://example.com", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	resp, err := rt.RoundTrip(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer resp.Body.Close()
+
+	if calls != 2 || resp.StatusCode != http.StatusOK {
+		t.Fatalf("expected timed out attempt to be retried, calls: %d, status: %d", calls, resp.StatusCode)
+	}
+}
+
+func Test_RetryCanceled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+
+	transport := RoundTripperFunc(func(r *http.Request) (*http.Response, error) {
+		cancel()
+		return nil, errors.New("connection reset")
+	})
+
+	rt := WrapTransport(transport, Retry(RetryPolicy{MaxAttempts: 10}))
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, "http://example.com", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := rt.RoundTrip(req); !errors.Is(err, context.Canceled) {
+		t.Fatalf("expected context.Canceled, got: %v", err)
+	}
+}
+
+func Test_RetryDelay(t *testing.T) {
+	now := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	policy := RetryPolicy{BaseDelay: 100 * time.Millisecond, MaxDelay: 10 * time.Second}.withDefaults()
+
+	for attempt := 1; attempt <= 40; attempt++ {
+		delay, ok := policy.delay(attempt, nil, now)
+		backoff := min(policy.BaseDelay<<min(attempt-1, 31), policy.MaxDelay)
+
+		if !ok || delay < 0 || delay > backoff {
+			t.Fatalf("unexpected delay for attempt %d: %s", attempt, delay)
+		}
+	}
+
+	for _, tc := range []struct {
+		retryAfter string
+		expected   time.Duration
+		ok         bool
+	}{
+		{retryAfter: "5", expected: 5 * time.Second, ok: true},
+		{retryAfter: now.Add(3 * time.Second).Format(http.TimeFormat), expected: 3 * time.Second, ok: true},
+		{retryAfter: "60", ok: false},
+	} {
+		resp := &http.Response{Header: http.Header{"Retry-After": {tc.retryAfter}}}
+
+		delay, ok := policy.delay(1, resp, now)
+		if ok != tc.ok {
+			t.Fatalf("unexpected ok for Retry-After %q: %t", tc.retryAfter, ok)
+		}
+
+		if ok && delay != tc.expected {
+			t.Fatalf("unexpected delay for Retry-After %q, got: %s, expected: %s", tc.retryAfter, delay, tc.expected)
+		}
+	}
+}
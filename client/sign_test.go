@@ -0,0 +1,153 @@
+package client
+
+import (
+	"context"
+	"crypto/hmac"
+	"encoding/hex"
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/bombsimon/http-helpers/clock"
+)
+
+func Test_SignHMAC(t *testing.T) {
+	clk := clock.NewFake(time.Unix(1700000000, 0))
+
+	var (
+		sent *http.Request
+		body []byte
+	)
+
+	rt := WrapTransport(
+		RoundTripperFunc(func(r *http.Request) (*http.Response, error) {
+			sent = r
+			body, _ = io.ReadAll(r.Body)
+
+			return &http.Response{StatusCode: http.StatusOK, Body: http.NoBody, Request: r}, nil
+		}),
+		Sign(NewHMACSigner(
+			StaticCredentials("key-1", "secret", ""),
+			WithClock(clk),
+			WithSignedHeaders("Content-Type"),
+		)),
+	)
+
+	req, err := http.NewRequest(http.MethodPost, "http://api.example.com/orders?dry_run=true", io.MultiReader(strings.NewReader(`{"id":1}`)))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+
+	if _, err := rt.RoundTrip(req); err != nil {
+		t.Fatal(err)
+	}
+
+	if string(body) != `{"id":1}` {
+		t.Fatalf("expected body to be sent after hashing, got: %q", body)
+	}
+
+	payloadHash := hashHex([]byte(`{"id":1}`))
+	nonce := sent.Header.Get("X-Signature-Nonce")
+	if sent.Header.Get("X-Content-Sha256") != payloadHash || sent.Header.Get("X-Signature-Timestamp") != "1700000000" || len(nonce) != 32 {
+		t.Fatalf("unexpected headers: %v", sent.Header)
+	}
+
+	// Verify the signature the way a server would.
+	stringToSign := "POST\n/orders?dry_run=true\napi.example.com\n1700000000\n" + nonce + "\n" + payloadHash + "\ncontent-type:application/json"
+	expected := "HMAC-SHA256 KeyId=key-1, SignedHeaders=content-type, Signature=" + hex.EncodeToString(hmacSHA256([]byte("secret"), stringToSign))
+
+	if !hmac.Equal([]byte(sent.Header.Get("Authorization")), []byte(expected)) {
+		t.Fatalf("unexpected authorization, got: %s, expected: %s", sent.Header.Get("Authorization"), expected)
+	}
+
+	if req.Header.Get("Authorization") != "" {
+		t.Fatal("expected the original request not to be modified")
+	}
+}
+
+func Test_SignCredentialsError(t *testing.T) {
+	errExpired := errors.New("credentials expired")
+
+	rt := WrapTransport(
+		RoundTripperFunc(func(r *http.Request) (*http.Response, error) {
+			t.Fatal("unexpected request")
+			return nil, nil
+		}),
+		Sign(NewHMACSigner(CredentialsProviderFunc(func(context.Context) (Credentials, error) {
+			return Credentials{}, errExpired
+		}))),
+	)
+
+	req, _ := http.NewRequest(http.MethodGet, "http://api.example.com", nil)
+
+	if _, err := rt.RoundTrip(req); !errors.Is(err, errExpired) {
+		t.Fatalf("expected credentials error, got: %v", err)
+	}
+}
+
+func Test_SignSigV4(t *testing.T) {
+	// Test vectors from the AWS Signature Version 4 documentation and test
+	// suite.
+	clk := clock.NewFake(time.Date(2015, 8, 30, 12, 36, 0, 0, time.UTC))
+	credentials := StaticCredentials("AKIDEXAMPLE", "wJalrXUtnFEMI/K7MDENG+bPxRfiCYEXAMPLEKEY", "")
+
+	for _, tc := range []struct {
+		description string
+		url         string
+		header      http.Header
+		service     string
+		expected    string
+	}{
+		{
+			description: "get vanilla",
+			url:         "https://example.amazonaws.com/",
+			service:     "service",
+			expected: "AWS4-HMAC-SHA256 Credential=AKIDEXAMPLE/20150830/us-east-1/service/aws4_request, " +
+				"SignedHeaders=host;x-amz-date, " +
+				"Signature=5fa00fa31553b73ebf1942676e86291e8372ff2a2260956d9b8aae1d763fbf31",
+		},
+		{
+			description: "iam list users",
+			url:         "https://iam.amazonaws.com/?Action=ListUsers&Version=2010-05-08",
+			header:      http.Header{"Content-Type": {"application/x-www-form-urlencoded; charset=utf-8"}},
+			service:     "iam",
+			expected: "AWS4-HMAC-SHA256 Credential=AKIDEXAMPLE/20150830/us-east-1/iam/aws4_request, " +
+				"SignedHeaders=content-type;host;x-amz-date, " +
+				"Signature=5d672d79c15b13162d9279b0855cfba6789a8edb4c82c400e06b5924a6f2b5d7",
+		},
+	} {
+		t.Run(tc.description, func(t *testing.T) {
+			var sent *http.Request
+
+			rt := WrapTransport(
+				RoundTripperFunc(func(r *http.Request) (*http.Response, error) {
+					sent = r
+					return &http.Response{StatusCode: http.StatusOK, Body: http.NoBody, Request: r}, nil
+				}),
+				Sign(NewSigV4Signer(credentials, "us-east-1", tc.service, WithClock(clk))),
+			)
+
+			req, err := http.NewRequest(http.MethodGet, tc.url, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			for name, values := range tc.header {
+				req.Header[name] = values
+			}
+
+			if _, err := rt.RoundTrip(req); err != nil {
+				t.Fatal(err)
+			}
+
+			if got := sent.Header.Get("Authorization"); got != tc.expected {
+				t.Fatalf("unexpected authorization\ngot:      %s\nexpected: %s", got, tc.expected)
+			}
+		})
+	}
+}
@@ -0,0 +1,191 @@
+package client
+
+import (
+	"encoding/hex"
+	"net/http"
+	"slices"
+	"strings"
+)
+
+const (
+	sigV4Algorithm  = "AWS4-HMAC-SHA256"
+	sigV4TimeFormat = "20060102T150405Z"
+	sigV4DateFormat = "20060102"
+)
+
+// NewSigV4Signer returns a signer implementing AWS Signature Version 4 for the
+// region and service, e.g. "eu-west-1" and "execute-api". The host,
+// Content-Type and X-Amz-* headers are signed. For S3 the path is only escaped
+// once and the X-Amz-Content-Sha256 header is set, as S3 requires. Use
+// WithClock to set the clock.
+func NewSigV4Signer(provider CredentialsProvider, region, service string, opts ...Option) Signer {
+	return &sigV4Signer{
+		provider: provider,
+		region:   region,
+		service:  service,
+		options:  newOptions(opts...),
+	}
+}
+
+type sigV4Signer struct {
+	provider CredentialsProvider
+	region   string
+	service  string
+	options  *options
+}
+
+func (s *sigV4Signer) Sign(r *http.Request, payloadHash string) error {
+	credentials, err := s.provider.Retrieve(r.Context())
+	if err != nil {
+		return err
+	}
+
+	now := s.options.clock.Now().UTC()
+	amzDate := now.Format(sigV4TimeFormat)
+	scope := strings.Join([]string{now.Format(sigV4DateFormat), s.region, s.service, "aws4_request"}, "/")
+
+	r.Header.Set("X-Amz-Date", amzDate)
+
+	if credentials.SessionToken != "" {
+		r.Header.Set("X-Amz-Security-Token", credentials.SessionToken)
+	}
+
+	if s.service == "s3" {
+		r.Header.Set("X-Amz-Content-Sha256", payloadHash)
+	}
+
+	signedHeaders, canonicalHeaders := canonicalHeaders(r)
+
+	canonicalRequest := strings.Join([]string{
+		r.Method,
+		s.canonicalURI(r),
+		canonicalQuery(r),
+		canonicalHeaders,
+		signedHeaders,
+		payloadHash,
+	}, "\n")
+
+	stringToSign := strings.Join([]string{
+		sigV4Algorithm,
+		amzDate,
+		scope,
+		hashHex([]byte(canonicalRequest)),
+	}, "\n")
+
+	key := []byte("AWS4" + credentials.SecretAccessKey)
+	for _, part := range []string{now.Format(sigV4DateFormat), s.region, s.service, "aws4_request"} {
+		key = hmacSHA256(key, part)
+	}
+
+	signature := hex.EncodeToString(hmacSHA256(key, stringToSign))
+
+	r.Header.Set("Authorization", sigV4Algorithm+
+		" Credential="+credentials.AccessKeyID+"/"+scope+
+		", SignedHeaders="+signedHeaders+
+		", Signature="+signature,
+	)
+
+	return nil
+}
+
+// canonicalURI returns the escaped path. All services except S3 escape the
+// already escaped path again.
+func (s *sigV4Signer) canonicalURI(r *http.Request) string {
+	path := r.URL.EscapedPath()
+	if path == "" {
+		return "/"
+	}
+
+	if s.service == "s3" {
+		return path
+	}
+
+	return sigV4Escape(path, false)
+}
+
+func canonicalQuery(r *http.Request) string {
+	query := r.URL.Query()
+	pairs := make([][2]string, 0, len(query))
+
+	for key, values := range query {
+		for _, value := range values {
+			pairs = append(pairs, [2]string{sigV4Escape(key, true), sigV4Escape(value, true)})
+		}
+	}
+
+	// Sorted by key and then value, not by the joined pair.
+	slices.SortFunc(pairs, func(a, b [2]string) int {
+		if c := strings.Compare(a[0], b[0]); c != 0 {
+			return c
+		}
+
+		return strings.Compare(a[1], b[1])
+	})
+
+	joined := make([]string, len(pairs))
+	for i, pair := range pairs {
+		joined[i] = pair[0] + "=" + pair[1]
+	}
+
+	return strings.Join(joined, "&")
+}
+
+// canonicalHeaders returns the signed header names and the canonical headers.
+func canonicalHeaders(r *http.Request) (string, string) {
+	headers := map[string]string{"host": requestHost(r)}
+
+	for name, values := range r.Header {
+		name = strings.ToLower(name)
+		if name != "content-type" && !strings.HasPrefix(name, "x-amz-") {
+			continue
+		}
+
+		trimmed := make([]string, len(values))
+		for i, value := range values {
+			trimmed[i] = strings.Join(strings.Fields(value), " ")
+		}
+
+		headers[name] = strings.Join(trimmed, ",")
+	}
+
+	names := make([]string, 0, len(headers))
+	for name := range headers {
+		names = append(names, name)
+	}
+
+	slices.Sort(names)
+
+	var sb strings.Builder
+	for _, name := range names {
+		sb.WriteString(name)
+		sb.WriteString(":")
+		sb.WriteString(headers[name])
+		sb.WriteString("\n")
+	}
+
+	return strings.Join(names, ";"), sb.String()
+}
+
+// sigV4Escape escapes all bytes except the unreserved characters and, unless
+// escapeSlash is set, slashes.
+func sigV4Escape(s string, escapeSlash bool) string {
+	const hexDigits = "0123456789ABCDEF"
+
+	var sb strings.Builder
+
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+
+		switch {
+		case 'A' <= c && c <= 'Z', 'a' <= c && c <= 'z', '0' <= c && c <= '9',
+			c == '-', c == '_', c == '.', c == '~', c == '/' && !escapeSlash:
+			sb.WriteByte(c)
+		default:
+			sb.WriteByte('%')
+			sb.WriteByte(hexDigits[c>>4])
+			sb.WriteByte(hexDigits[c&0x0f])
+		}
+	}
+
+	return sb.String()
+}
@@ -0,0 +1,73 @@
+package client
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/bombsimon/http-helpers/httpctx"
+	"github.com/bombsimon/http-helpers/tracing"
+)
+
+// Tracing returns a tripperware starting a client span for each outgoing
+// request and sending it to the server in the traceparent header. The span is
+// a child of the trace in the request context, e.g. the server span started
+// by the Tracing middleware, or starts a new trace if there is none. Sampled
+// spans are passed to the exporter when the response headers are received.
+// Use WithB3Propagation to send the B3 headers as well. Add it before Retry to
+// trace each attempt.
+func Tracing(exporter tracing.Exporter, opts ...Option) Tripperware {
+	options := newOptions(opts...)
+
+	return func(rt http.RoundTripper) http.RoundTripper {
+		return RoundTripperFunc(func(r *http.Request) (*http.Response, error) {
+			span := httpctx.Trace{TraceID: tracing.NewTraceID(), Sampled: true}
+
+			parent, ok := httpctx.TraceFrom(r.Context())
+			if ok {
+				span.TraceID, span.Sampled = parent.TraceID, parent.Sampled
+			}
+
+			span.SpanID = tracing.NewSpanID()
+
+			r = r.Clone(httpctx.WithTrace(r.Context(), span))
+			tracing.Inject(r.Header, span)
+
+			if options.b3 {
+				tracing.InjectB3(r.Header, span, parent.SpanID)
+			}
+
+			startTime := time.Now()
+			resp, err := rt.RoundTrip(r)
+
+			if exporter == nil || !span.Sampled {
+				return resp, err
+			}
+
+			finished := tracing.Span{
+				Name:         "HTTP " + r.Method,
+				Kind:         tracing.KindClient,
+				TraceID:      span.TraceID,
+				SpanID:       span.SpanID,
+				ParentSpanID: parent.SpanID,
+				Start:        startTime,
+				End:          time.Now(),
+				Err:          err,
+				Attributes: map[string]string{
+					"http.method": r.Method,
+					"http.host":   r.URL.Host,
+					"http.path":   r.URL.Path,
+					"attempt":     strconv.Itoa(Attempt(r.Context())),
+				},
+			}
+
+			if resp != nil {
+				finished.Status = resp.StatusCode
+			}
+
+			exporter(finished)
+
+			return resp, err
+		})
+	}
+}
@@ -0,0 +1,63 @@
+package client
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	"github.com/bombsimon/http-helpers/httpctx"
+	"github.com/bombsimon/http-helpers/tracing"
+)
+
+func Test_Tracing(t *testing.T) {
+	parent := httpctx.Trace{TraceID: tracing.NewTraceID(), SpanID: tracing.NewSpanID(), Sampled: true}
+
+	var (
+		sent  http.Header
+		spans []tracing.Span
+	)
+
+	rt := WrapTransport(
+		RoundTripperFunc(func(r *http.Request) (*http.Response, error) {
+			sent = r.Header
+			return &http.Response{StatusCode: http.StatusOK, Body: http.NoBody, Request: r}, nil
+		}),
+		Tracing(func(span tracing.Span) {
+			spans = append(spans, span)
+		}, WithB3Propagation()),
+	)
+
+	req, err := http.NewRequestWithContext(
+		httpctx.WithTrace(context.Background(), parent),
+		http.MethodGet, "http://users.internal/users", nil,
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := rt.RoundTrip(req); err != nil {
+		t.Fatal(err)
+	}
+
+	if req.Header.Get(tracing.TraceparentHeader) != "" {
+		t.Fatal("expected the original request not to be modified")
+	}
+
+	if len(spans) != 1 {
+		t.Fatalf("expected one span, got: %d", len(spans))
+	}
+
+	span := spans[0]
+	if span.Kind != tracing.KindClient || span.TraceID != parent.TraceID || span.ParentSpanID != parent.SpanID || span.Status != http.StatusOK {
+		t.Fatalf("unexpected span: %+v", span)
+	}
+
+	sentTrace, ok := tracing.Parse(sent.Get(tracing.TraceparentHeader))
+	if !ok || sentTrace.TraceID != parent.TraceID || sentTrace.SpanID != span.SpanID {
+		t.Fatalf("unexpected traceparent: %s", sent.Get(tracing.TraceparentHeader))
+	}
+
+	if sent.Get(tracing.B3ParentIDHeader) != parent.SpanID {
+		t.Fatalf("unexpected B3 parent: %s", sent.Get(tracing.B3ParentIDHeader))
+	}
+}
@@ -0,0 +1,225 @@
+package clock
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+)
+
+// Fake is a Clock where time only moves when Advance is called. It's safe for
+// concurrent use.
+type Fake struct {
+	mu     sync.Mutex
+	cond   *sync.Cond
+	now    time.Time
+	timers []*fakeTimer
+}
+
+// NewFake returns a Fake clock set to now.
+func NewFake(now time.Time) *Fake {
+	f := &Fake{now: now}
+	f.cond = sync.NewCond(&f.mu)
+
+	return f
+}
+
+// Now returns the current fake time.
+func (f *Fake) Now() time.Time {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	return f.now
+}
+
+// Since returns the fake time elapsed since t.
+func (f *Fake) Since(t time.Time) time.Duration {
+	return f.Now().Sub(t)
+}
+
+// NewTimer creates a timer firing when the clock is advanced past d.
+func (f *Fake) NewTimer(d time.Duration) Timer {
+	t := &fakeTimer{clock: f, c: make(chan time.Time, 1)}
+	t.Reset(d)
+
+	return t
+}
+
+// NewTicker creates a ticker firing every time the clock is advanced past d.
+func (f *Fake) NewTicker(d time.Duration) Ticker {
+	if d <= 0 {
+		panic("clock: non-positive interval for NewTicker")
+	}
+
+	t := &fakeTimer{clock: f, c: make(chan time.Time, 1), period: d}
+	t.Reset(d)
+
+	return fakeTicker{t}
+}
+
+// AfterFunc calls f when the clock is advanced past d. Unlike time.AfterFunc,
+// f is called synchronously by Advance so it has finished when Advance
+// returns.
+func (f *Fake) AfterFunc(d time.Duration, fn func()) Timer {
+	t := &fakeTimer{clock: f, fn: fn}
+	t.Reset(d)
+
+	return t
+}
+
+// WithTimeout returns a copy of ctx that's cancelled with
+// context.DeadlineExceeded when the clock is advanced past d.
+func (f *Fake) WithTimeout(ctx context.Context, d time.Duration) (context.Context, context.CancelFunc) {
+	inner, cancel := context.WithCancel(ctx)
+	tc := &timeoutContext{Context: inner, deadline: f.Now().Add(d)}
+
+	timer := f.AfterFunc(d, func() {
+		tc.mu.Lock()
+		tc.err = context.DeadlineExceeded
+		tc.mu.Unlock()
+
+		cancel()
+	})
+
+	return tc, func() {
+		timer.Stop()
+		cancel()
+	}
+}
+
+// Advance moves the clock forward by d, firing all timers due in order. The
+// clock is set to the time of each timer when it fires.
+func (f *Fake) Advance(d time.Duration) {
+	f.mu.Lock()
+	end := f.now.Add(d)
+
+	for len(f.timers) > 0 && !f.timers[0].when.After(end) {
+		t := f.timers[0]
+		f.now = t.when
+		f.removeLocked(t)
+
+		if t.period > 0 {
+			t.when = t.when.Add(t.period)
+			f.addLocked(t)
+		}
+
+		f.mu.Unlock()
+		t.fire()
+		f.mu.Lock()
+	}
+
+	f.now = end
+	f.mu.Unlock()
+}
+
+// BlockUntil blocks until at least n timers and tickers are waiting to fire.
+// Use this to synchronize with code creating timers in other goroutines before
+// calling Advance.
+func (f *Fake) BlockUntil(n int) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	for len(f.timers) < n {
+		f.cond.Wait()
+	}
+}
+
+func (f *Fake) addLocked(t *fakeTimer) {
+	f.timers = append(f.timers, t)
+	sort.SliceStable(f.timers, func(i, j int) bool {
+		return f.timers[i].when.Before(f.timers[j].when)
+	})
+
+	f.cond.Broadcast()
+}
+
+func (f *Fake) removeLocked(t *fakeTimer) bool {
+	for i, other := range f.timers {
+		if other == t {
+			f.timers = append(f.timers[:i], f.timers[i+1:]...)
+			return true
+		}
+	}
+
+	return false
+}
+
+type fakeTimer struct {
+	clock  *Fake
+	when   time.Time
+	period time.Duration
+	c      chan time.Time
+	fn     func()
+}
+
+func (t *fakeTimer) C() <-chan time.Time {
+	return t.c
+}
+
+func (t *fakeTimer) Stop() bool {
+	t.clock.mu.Lock()
+	defer t.clock.mu.Unlock()
+
+	return t.clock.removeLocked(t)
+}
+
+func (t *fakeTimer) Reset(d time.Duration) bool {
+	t.clock.mu.Lock()
+	defer t.clock.mu.Unlock()
+
+	active := t.clock.removeLocked(t)
+	t.when = t.clock.now.Add(d)
+	t.clock.addLocked(t)
+
+	return active
+}
+
+func (t *fakeTimer) fire() {
+	if t.fn != nil {
+		t.fn()
+		return
+	}
+
+	// Like time.Ticker, drop the tick if the previous one wasn't received.
+	select {
+	case t.c <- t.clock.Now():
+	default:
+	}
+}
+
+type fakeTicker struct {
+	t *fakeTimer
+}
+
+func (t fakeTicker) C() <-chan time.Time {
+	return t.t.c
+}
+
+func (t fakeTicker) Stop() {
+	t.t.Stop()
+}
+
+// timeoutContext is a context returning context.DeadlineExceeded when the fake
+// deadline is reached.
+type timeoutContext struct {
+	context.Context
+	deadline time.Time
+
+	mu  sync.Mutex
+	err error
+}
+
+func (c *timeoutContext) Deadline() (time.Time, bool) {
+	return c.deadline, true
+}
+
+func (c *timeoutContext) Err() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.err != nil {
+		return c.err
+	}
+
+	return c.Context.Err()
+}
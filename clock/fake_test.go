@@ -0,0 +1,92 @@
+package clock
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func Test_Fake(t *testing.T) {
+	start := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+	clk := NewFake(start)
+
+	timer := clk.NewTimer(time.Second)
+	ticker := clk.NewTicker(300 * time.Millisecond)
+
+	var calls []time.Duration
+
+	clk.AfterFunc(500*time.Millisecond, func() {
+		calls = append(calls, clk.Since(start))
+	})
+
+	stopped := clk.AfterFunc(time.Millisecond, func() {
+		t.Error("stopped timer fired")
+	})
+
+	if !stopped.Stop() {
+		t.Fatal("expected stop to return true for active timer")
+	}
+
+	clk.Advance(999 * time.Millisecond)
+
+	select {
+	case <-timer.C():
+		t.Fatal("timer fired too early")
+	default:
+	}
+
+	if len(calls) != 1 || calls[0] != 500*time.Millisecond {
+		t.Fatalf("unexpected AfterFunc calls: %v", calls)
+	}
+
+	// Ticks are dropped if not received, like time.Ticker.
+	if tick := <-ticker.C(); tick.Sub(start) != 300*time.Millisecond {
+		t.Fatalf("unexpected tick: %s", tick.Sub(start))
+	}
+
+	clk.Advance(time.Millisecond)
+
+	if fired := <-timer.C(); fired.Sub(start) != time.Second {
+		t.Fatalf("unexpected timer fire time: %s", fired.Sub(start))
+	}
+
+	if got := clk.Since(start); got != time.Second {
+		t.Fatalf("unexpected elapsed time: %s", got)
+	}
+}
+
+func Test_FakeWithTimeout(t *testing.T) {
+	clk := NewFake(time.Now())
+
+	ctx, cancel := clk.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+
+	if deadline, ok := ctx.Deadline(); !ok || deadline != clk.Now().Add(time.Minute) {
+		t.Fatalf("unexpected deadline: %s", deadline)
+	}
+
+	done := make(chan struct{})
+
+	go func() {
+		defer close(done)
+
+		// Advance once the timeout is waiting to fire.
+		clk.BlockUntil(1)
+		clk.Advance(time.Minute)
+	}()
+
+	<-ctx.Done()
+	<-done
+
+	if !errors.Is(ctx.Err(), context.DeadlineExceeded) {
+		t.Fatalf("unexpected error: %v", ctx.Err())
+	}
+
+	ctx, cancel = clk.WithTimeout(context.Background(), time.Minute)
+	cancel()
+
+	if !errors.Is(ctx.Err(), context.Canceled) {
+		t.Fatalf("unexpected error: %v", ctx.Err())
+	}
+}
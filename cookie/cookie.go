@@ -0,0 +1,185 @@
+// Package cookie sets and reads authenticated and encrypted cookies. Values are
+// encrypted with AES-256-GCM bound to the cookie name, so they can neither be
+// read, modified nor moved to another cookie by the client. Keys can be
+// rotated by adding a new key first, values encrypted with the old keys are
+// still accepted until they're removed.
+package cookie
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+)
+
+// KeySize is the size of the keys, 32 bytes for AES-256.
+const KeySize = 32
+
+// MaxSize is the maximum size of a cookie, including the name and attributes,
+// supported by browsers.
+const MaxSize = 4096
+
+var (
+	// ErrInvalidKey is returned by NewCodec if no keys are passed or a key
+	// isn't KeySize bytes.
+	ErrInvalidKey = errors.New("invalid cookie key")
+
+	// ErrInvalid is returned when a value can't be decrypted with any of the
+	// keys, e.g. because it was modified or encrypted for another cookie.
+	ErrInvalid = errors.New("invalid cookie value")
+
+	// ErrExpired is returned when a value is older than the max age.
+	ErrExpired = errors.New("cookie expired")
+
+	// ErrTooLarge is returned when the cookie would exceed MaxSize.
+	ErrTooLarge = errors.New("cookie too large")
+)
+
+// Codec encrypts and decrypts cookie values. It's safe for concurrent use.
+type Codec struct {
+	aeads   []cipher.AEAD
+	options *options
+}
+
+// NewCodec creates a codec encrypting with the first key and decrypting with
+// any of the keys. Each key must be KeySize random bytes. Cookies are HttpOnly,
+// Secure and SameSite=Lax by default.
+func NewCodec(keys [][]byte, opts ...Option) (*Codec, error) {
+	if len(keys) == 0 {
+		return nil, ErrInvalidKey
+	}
+
+	aeads := make([]cipher.AEAD, 0, len(keys))
+
+	for i, key := range keys {
+		if len(key) != KeySize {
+			return nil, fmt.Errorf("%w: key %d is %d bytes, expected %d", ErrInvalidKey, i, len(key), KeySize)
+		}
+
+		block, err := aes.NewCipher(key)
+		if err != nil {
+			return nil, err
+		}
+
+		aead, err := cipher.NewGCM(block)
+		if err != nil {
+			return nil, err
+		}
+
+		aeads = append(aeads, aead)
+	}
+
+	return &Codec{
+		aeads:   aeads,
+		options: newOptions(opts...),
+	}, nil
+}
+
+// Encode encrypts the value for the cookie with the name. The time it was
+// encoded is included to check the max age when decoded.
+func (c *Codec) Encode(name string, value []byte) (string, error) {
+	aead := c.aeads[0]
+
+	plaintext := make([]byte, 8, 8+len(value))
+	binary.BigEndian.PutUint64(plaintext, uint64(c.options.clock.Now().Unix()))
+	plaintext = append(plaintext, value...)
+
+	nonce := make([]byte, aead.NonceSize(), aead.NonceSize()+len(plaintext)+aead.Overhead())
+	if _, err := rand.Read(nonce); err != nil {
+		return "", err
+	}
+
+	sealed := aead.Seal(nonce, nonce, plaintext, []byte(name))
+
+	return base64.RawURLEncoding.EncodeToString(sealed), nil
+}
+
+// Decode decrypts a value encoded for the cookie with the name. ErrInvalid is
+// returned if it can't be decrypted with any key and ErrExpired if it's older
+// than the max age.
+func (c *Codec) Decode(name, encoded string) ([]byte, error) {
+	sealed, err := base64.RawURLEncoding.DecodeString(encoded)
+	if err != nil {
+		return nil, ErrInvalid
+	}
+
+	for _, aead := range c.aeads {
+		if len(sealed) < aead.NonceSize() {
+			continue
+		}
+
+		nonce, ciphertext := sealed[:aead.NonceSize()], sealed[aead.NonceSize():]
+
+		plaintext, err := aead.Open(nil, nonce, ciphertext, []byte(name))
+		if err != nil || len(plaintext) < 8 {
+			continue
+		}
+
+		issued := time.Unix(int64(binary.BigEndian.Uint64(plaintext)), 0)
+		if c.options.maxAge > 0 && c.options.clock.Since(issued) > c.options.maxAge {
+			return nil, ErrExpired
+		}
+
+		return plaintext[8:], nil
+	}
+
+	return nil, ErrInvalid
+}
+
+// Set encrypts the value and sets the cookie on the response. ErrTooLarge is
+// returned, and nothing is set, if the cookie exceeds MaxSize.
+func (c *Codec) Set(w http.ResponseWriter, name string, value []byte) error {
+	encoded, err := c.Encode(name, value)
+	if err != nil {
+		return err
+	}
+
+	cookie := c.cookie(name, encoded)
+	if c.options.maxAge > 0 {
+		cookie.MaxAge = int(c.options.maxAge.Seconds())
+	}
+
+	if size := len(cookie.String()); size > MaxSize {
+		return fmt.Errorf("%w: %s is %d bytes", ErrTooLarge, name, size)
+	}
+
+	http.SetCookie(w, cookie)
+
+	return nil
+}
+
+// Get reads and decrypts the cookie from the request. http.ErrNoCookie is
+// returned if it isn't set.
+func (c *Codec) Get(r *http.Request, name string) ([]byte, error) {
+	cookie, err := r.Cookie(name)
+	if err != nil {
+		return nil, err
+	}
+
+	return c.Decode(name, cookie.Value)
+}
+
+// Delete expires the cookie in the browser.
+func (c *Codec) Delete(w http.ResponseWriter, name string) {
+	cookie := c.cookie(name, "")
+	cookie.MaxAge = -1
+
+	http.SetCookie(w, cookie)
+}
+
+func (c *Codec) cookie(name, value string) *http.Cookie {
+	return &http.Cookie{
+		Name:     name,
+		Value:    value,
+		Path:     c.options.path,
+		Domain:   c.options.domain,
+		HttpOnly: true,
+		Secure:   c.options.secure,
+		SameSite: c.options.sameSite,
+	}
+}
@@ -0,0 +1,128 @@
+package cookie
+
+import (
+	"bytes"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/bombsimon/http-helpers/clock"
+)
+
+func key(b byte) []byte {
+	return bytes.Repeat([]byte{b}, KeySize)
+}
+
+func Test_Codec(t *testing.T) {
+	clk := clock.NewFake(time.Now())
+
+	codec, err := NewCodec([][]byte{key(1)}, WithMaxAge(time.Hour), WithClock(clk))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	rec := httptest.NewRecorder()
+	if err := codec.Set(rec, "session", []byte("user=1")); err != nil {
+		t.Fatal(err)
+	}
+
+	cookies := rec.Result().Cookies()
+	if len(cookies) != 1 {
+		t.Fatalf("expected one cookie, got: %v", cookies)
+	}
+
+	cookie := cookies[0]
+	if !cookie.HttpOnly || !cookie.Secure || cookie.SameSite != http.SameSiteLaxMode || cookie.Path != "/" || cookie.MaxAge != 3600 {
+		t.Fatalf("unexpected cookie attributes: %+v", cookie)
+	}
+
+	if strings.Contains(cookie.Value, "user") {
+		t.Fatalf("expected value to be encrypted, got: %s", cookie.Value)
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.AddCookie(cookie)
+
+	if value, err := codec.Get(req, "session"); err != nil || string(value) != "user=1" {
+		t.Fatalf("unexpected value: %s, %v", value, err)
+	}
+
+	// The value can't be moved to another cookie or modified.
+	if _, err := codec.Decode("other", cookie.Value); !errors.Is(err, ErrInvalid) {
+		t.Fatalf("expected ErrInvalid for another name, got: %v", err)
+	}
+
+	tampered := []byte(cookie.Value)
+	tampered[len(tampered)/2] ^= 'A' ^ 'B'
+
+	if _, err := codec.Decode("session", string(tampered)); !errors.Is(err, ErrInvalid) {
+		t.Fatalf("expected ErrInvalid for tampered value, got: %v", err)
+	}
+
+	clk.Advance(time.Hour + time.Second)
+
+	if _, err := codec.Get(req, "session"); !errors.Is(err, ErrExpired) {
+		t.Fatalf("expected ErrExpired, got: %v", err)
+	}
+
+	if _, err := codec.Get(httptest.NewRequest(http.MethodGet, "/", nil), "session"); !errors.Is(err, http.ErrNoCookie) {
+		t.Fatalf("expected http.ErrNoCookie, got: %v", err)
+	}
+}
+
+func Test_KeyRotation(t *testing.T) {
+	old, _ := NewCodec([][]byte{key(1)})
+	rotated, _ := NewCodec([][]byte{key(2), key(1)})
+	removed, _ := NewCodec([][]byte{key(2)})
+
+	encoded, err := old.Encode("session", []byte("value"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if value, err := rotated.Decode("session", encoded); err != nil || string(value) != "value" {
+		t.Fatalf("expected old value to be accepted after rotation, got: %s, %v", value, err)
+	}
+
+	if _, err := removed.Decode("session", encoded); !errors.Is(err, ErrInvalid) {
+		t.Fatalf("expected ErrInvalid after the old key is removed, got: %v", err)
+	}
+
+	encoded, _ = rotated.Encode("session", []byte("value"))
+
+	if _, err := removed.Decode("session", encoded); err != nil {
+		t.Fatalf("expected new values to use the new key, got: %v", err)
+	}
+}
+
+func Test_Limits(t *testing.T) {
+	if _, err := NewCodec(nil); !errors.Is(err, ErrInvalidKey) {
+		t.Fatalf("expected ErrInvalidKey without keys, got: %v", err)
+	}
+
+	if _, err := NewCodec([][]byte{[]byte("short")}); !errors.Is(err, ErrInvalidKey) {
+		t.Fatalf("expected ErrInvalidKey for short key, got: %v", err)
+	}
+
+	codec, _ := NewCodec([][]byte{key(1)}, WithInsecure(), WithSameSite(http.SameSiteStrictMode))
+
+	rec := httptest.NewRecorder()
+	if err := codec.Set(rec, "session", make([]byte, MaxSize)); !errors.Is(err, ErrTooLarge) {
+		t.Fatalf("expected ErrTooLarge, got: %v", err)
+	}
+
+	if len(rec.Result().Cookies()) != 0 {
+		t.Fatal("expected no cookie to be set when too large")
+	}
+
+	rec = httptest.NewRecorder()
+	codec.Delete(rec, "session")
+
+	cookie := rec.Result().Cookies()[0]
+	if cookie.MaxAge != -1 || cookie.Secure || cookie.SameSite != http.SameSiteStrictMode {
+		t.Fatalf("unexpected deleted cookie: %+v", cookie)
+	}
+}
@@ -0,0 +1,83 @@
+package cookie
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/bombsimon/http-helpers/clock"
+)
+
+// Option is an option used to configure a Codec.
+type Option func(*options)
+
+type options struct {
+	maxAge   time.Duration
+	path     string
+	domain   string
+	sameSite http.SameSite
+	secure   bool
+	clock    clock.Clock
+}
+
+func newOptions(opts ...Option) *options {
+	o := &options{
+		path:     "/",
+		sameSite: http.SameSiteLaxMode,
+		secure:   true,
+		clock:    clock.Real(),
+	}
+
+	for _, opt := range opts {
+		opt(o)
+	}
+
+	return o
+}
+
+// WithMaxAge sets the max age of the cookies. Values older than the max age
+// are rejected when read, even if the browser still sends them. Defaults to 0,
+// a session cookie without an expiry.
+func WithMaxAge(d time.Duration) Option {
+	return func(o *options) {
+		o.maxAge = d
+	}
+}
+
+// WithPath sets the path of the cookies. Defaults to "/".
+func WithPath(path string) Option {
+	return func(o *options) {
+		o.path = path
+	}
+}
+
+// WithDomain sets the domain of the cookies. Defaults to the host of the
+// request only.
+func WithDomain(domain string) Option {
+	return func(o *options) {
+		o.domain = domain
+	}
+}
+
+// WithSameSite sets the SameSite attribute of the cookies. Defaults to
+// http.SameSiteLaxMode.
+func WithSameSite(sameSite http.SameSite) Option {
+	return func(o *options) {
+		o.sameSite = sameSite
+	}
+}
+
+// WithInsecure allows the cookies to be sent over plain HTTP, e.g. when
+// developing locally. Cookies are only sent over HTTPS by default.
+func WithInsecure() Option {
+	return func(o *options) {
+		o.secure = false
+	}
+}
+
+// WithClock sets the clock used to check the max age. Defaults to
+// clock.Real().
+func WithClock(c clock.Clock) Option {
+	return func(o *options) {
+		o.clock = c
+	}
+}
@@ -0,0 +1,257 @@
+package debug
+
+/*
+Debug endpoints for pprof, expvar, garbage collection statistics and build
+information, mounted on a mux under a prefix. The endpoints expose internals
+of the service and must never be served publicly: mount them on an admin
+server, e.g. guarded by middleware.AdminGuard, or require a token.
+
+	debug.Mount(adminMux, debug.WithToken(os.Getenv("DEBUG_TOKEN")))
+
+	// go tool pprof -http=: -H "Authorization: Bearer $DEBUG_TOKEN" \
+	//     http://localhost:9090/debug/pprof/heap
+*/
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"expvar"
+	"net/http"
+	"net/http/pprof"
+	"runtime"
+	rdebug "runtime/debug"
+	"runtime/metrics"
+	"strings"
+	"time"
+
+	"github.com/bombsimon/http-helpers/buildinfo"
+)
+
+// Mux is implemented by *http.ServeMux and routers with the same Handle
+// method.
+type Mux interface {
+	Handle(pattern string, handler http.Handler)
+}
+
+// Option is an option used to configure Mount.
+type Option func(*options)
+
+type options struct {
+	prefix  string
+	token   string
+	auth    func(*http.Request) bool
+	version string
+}
+
+// WithPrefix sets the path prefix of the endpoints. Defaults to "/debug".
+func WithPrefix(prefix string) Option {
+	return func(o *options) {
+		o.prefix = prefix
+	}
+}
+
+// WithToken requires the token to be sent as a bearer token in the
+// Authorization header. Requests without it get 401 Unauthorized.
+func WithToken(token string) Option {
+	return func(o *options) {
+		o.token = token
+	}
+}
+
+// WithAuthFunc sets a function authorizing each request. Requests where it
+// returns false get 403 Forbidden.
+func WithAuthFunc(fn func(*http.Request) bool) Option {
+	return func(o *options) {
+		o.auth = fn
+	}
+}
+
+// WithVersion sets the version reported by the build endpoint, e.g. set with
+// -ldflags at build time. Defaults to the version from buildinfo.Read.
+func WithVersion(version string) Option {
+	return func(o *options) {
+		o.version = version
+	}
+}
+
+// Mount registers the debug endpoints on the mux under the prefix:
+//
+//   - /pprof/ serves the net/http/pprof profiles.
+//   - /vars serves the expvar variables.
+//   - /gc serves garbage collection and memory statistics as JSON.
+//   - /build serves the Go version, module versions and VCS information as
+//     JSON.
+func Mount(mux Mux, opts ...Option) {
+	options := &options{prefix: "/debug"}
+	for _, opt := range opts {
+		opt(options)
+	}
+
+	prefix := strings.TrimSuffix(options.prefix, "/")
+
+	// The pprof index only works under /debug/pprof/ so rewrite the path for
+	// other prefixes. The links in the index are relative.
+	pprofIndex := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		r2 := r.Clone(r.Context())
+		r2.URL.Path = "/debug/pprof/" + strings.TrimPrefix(r.URL.Path, prefix+"/pprof/")
+		pprof.Index(w, r2)
+	})
+
+	for pattern, handler := range map[string]http.Handler{
+		"/pprof/":        pprofIndex,
+		"/pprof/cmdline": http.HandlerFunc(pprof.Cmdline),
+		"/pprof/profile": http.HandlerFunc(pprof.Profile),
+		"/pprof/symbol":  http.HandlerFunc(pprof.Symbol),
+		"/pprof/trace":   http.HandlerFunc(pprof.Trace),
+		"/vars":          expvar.Handler(),
+		"/gc":            http.HandlerFunc(gcStats),
+		"/build":         buildInfo(options.version),
+	} {
+		mux.Handle(prefix+pattern, options.authorize(handler))
+	}
+}
+
+func (o *options) authorize(h http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if o.token != "" {
+			token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+			if !ok || subtle.ConstantTimeCompare([]byte(token), []byte(o.token)) != 1 {
+				w.Header().Set("WWW-Authenticate", `Bearer realm="debug"`)
+				http.Error(w, http.StatusText(http.StatusUnauthorized), http.StatusUnauthorized)
+
+				return
+			}
+		}
+
+		if o.auth != nil && !o.auth(r) {
+			http.Error(w, http.StatusText(http.StatusForbidden), http.StatusForbidden)
+			return
+		}
+
+		h.ServeHTTP(w, r)
+	})
+}
+
+// recentPauses is the number of recent GC pauses in GCStats.
+const recentPauses = 10
+
+// GCStats is the garbage collection and memory statistics served on /gc.
+type GCStats struct {
+	NumGC         int64           `json:"num_gc"`
+	LastGC        time.Time       `json:"last_gc"`
+	PauseTotal    time.Duration   `json:"pause_total"`
+	RecentPauses  []time.Duration `json:"recent_pauses"` // Most recent first.
+	HeapAlloc     uint64          `json:"heap_alloc"`
+	HeapSys       uint64          `json:"heap_sys"`
+	HeapObjects   uint64          `json:"heap_objects"`
+	NextGC        uint64          `json:"next_gc"`
+	GCCPUFraction float64         `json:"gc_cpu_fraction"`
+	NumGoroutine  int             `json:"num_goroutine"`
+	GOMAXPROCS    int             `json:"gomaxprocs"`
+	GOGC          uint64          `json:"gogc"`
+	MemoryLimit   uint64          `json:"memory_limit"`
+}
+
+func gcStats(w http.ResponseWriter, _ *http.Request) {
+	var (
+		gc  rdebug.GCStats
+		mem runtime.MemStats
+	)
+
+	rdebug.ReadGCStats(&gc)
+	runtime.ReadMemStats(&mem)
+
+	if len(gc.Pause) > recentPauses {
+		gc.Pause = gc.Pause[:recentPauses]
+	}
+
+	settings := []metrics.Sample{
+		{Name: "/gc/gogc:percent"},
+		{Name: "/gc/gomemlimit:bytes"},
+	}
+
+	metrics.Read(settings)
+
+	stats := GCStats{
+		NumGC:         gc.NumGC,
+		LastGC:        gc.LastGC,
+		PauseTotal:    gc.PauseTotal,
+		RecentPauses:  gc.Pause,
+		HeapAlloc:     mem.HeapAlloc,
+		HeapSys:       mem.HeapSys,
+		HeapObjects:   mem.HeapObjects,
+		NextGC:        mem.NextGC,
+		GCCPUFraction: mem.GCCPUFraction,
+		NumGoroutine:  runtime.NumGoroutine(),
+		GOMAXPROCS:    runtime.GOMAXPROCS(0),
+		GOGC:          uint64Value(settings[0]),
+		MemoryLimit:   uint64Value(settings[1]),
+	}
+
+	writeJSON(w, stats)
+}
+
+func uint64Value(sample metrics.Sample) uint64 {
+	if sample.Value.Kind() != metrics.KindUint64 {
+		return 0
+	}
+
+	return sample.Value.Uint64()
+}
+
+// BuildInfo is the build information served on /build.
+type BuildInfo struct {
+	GoVersion   string            `json:"go_version"`
+	Path        string            `json:"path"`
+	Version     string            `json:"version"`
+	VCSRevision string            `json:"vcs_revision,omitempty"`
+	VCSTime     string            `json:"vcs_time,omitempty"`
+	VCSModified bool              `json:"vcs_modified,omitempty"`
+	Settings    map[string]string `json:"settings,omitempty"`
+	Deps        map[string]string `json:"deps,omitempty"`
+}
+
+func buildInfo(version string) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		info := BuildInfo{GoVersion: runtime.Version(), Version: version}
+		if info.Version == "" {
+			info.Version = buildinfo.Read().Version
+		}
+
+		bi, ok := rdebug.ReadBuildInfo()
+		if ok {
+			info.Path = bi.Main.Path
+			info.Settings = map[string]string{}
+			info.Deps = map[string]string{}
+
+			for _, setting := range bi.Settings {
+				switch setting.Key {
+				case "vcs.revision":
+					info.VCSRevision = setting.Value
+				case "vcs.time":
+					info.VCSTime = setting.Value
+				case "vcs.modified":
+					info.VCSModified = setting.Value == "true"
+				default:
+					info.Settings[setting.Key] = setting.Value
+				}
+			}
+
+			for _, dep := range bi.Deps {
+				info.Deps[dep.Path] = dep.Version
+			}
+		}
+
+		writeJSON(w, info)
+	})
+}
+
+func writeJSON(w http.ResponseWriter, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+
+	_ = enc.Encode(v)
+}
@@ -0,0 +1,84 @@
+package debug
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func Test_Mount(t *testing.T) {
+	mux := http.NewServeMux()
+	Mount(mux, WithPrefix("/internal/"), WithToken("secret"), WithVersion("v1.2.3"))
+
+	get := func(path, token string) *httptest.ResponseRecorder {
+		r := httptest.NewRequest(http.MethodGet, path, nil)
+		if token != "" {
+			r.Header.Set("Authorization", "Bearer "+token)
+		}
+
+		rr := httptest.NewRecorder()
+		mux.ServeHTTP(rr, r)
+
+		return rr
+	}
+
+	tests := []struct {
+		path           string
+		token          string
+		expectedStatus int
+		expectedBody   string
+	}{
+		{path: "/internal/pprof/", token: "secret", expectedStatus: http.StatusOK, expectedBody: "goroutine"},
+		{path: "/internal/pprof/goroutine?debug=1", token: "secret", expectedStatus: http.StatusOK, expectedBody: "goroutine profile"},
+		{path: "/internal/vars", token: "secret", expectedStatus: http.StatusOK, expectedBody: "memstats"},
+		{path: "/internal/gc", token: "secret", expectedStatus: http.StatusOK, expectedBody: "num_goroutine"},
+		{path: "/internal/build", token: "secret", expectedStatus: http.StatusOK, expectedBody: `"version": "v1.2.3"`},
+		{path: "/internal/vars", expectedStatus: http.StatusUnauthorized},
+		{path: "/internal/pprof/", token: "wrong", expectedStatus: http.StatusUnauthorized},
+		{path: "/debug/pprof/", token: "secret", expectedStatus: http.StatusNotFound},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.path, func(t *testing.T) {
+			rr := get(tc.path, tc.token)
+
+			if rr.Code != tc.expectedStatus {
+				t.Fatalf("unexpected status, got: %d, expected: %d", rr.Code, tc.expectedStatus)
+			}
+
+			if !strings.Contains(rr.Body.String(), tc.expectedBody) {
+				t.Fatalf("expected body to contain %q, got: %s", tc.expectedBody, rr.Body.String())
+			}
+		})
+	}
+
+	var stats GCStats
+	if err := json.NewDecoder(get("/internal/gc", "secret").Body).Decode(&stats); err != nil {
+		t.Fatal(err)
+	}
+
+	if stats.NumGoroutine == 0 || stats.GOMAXPROCS == 0 || len(stats.RecentPauses) > recentPauses {
+		t.Fatalf("unexpected stats: %+v", stats)
+	}
+}
+
+func Test_MountAuthFunc(t *testing.T) {
+	mux := http.NewServeMux()
+	Mount(mux, WithAuthFunc(func(r *http.Request) bool {
+		return r.Header.Get("X-Admin") == "yes"
+	}))
+
+	for header, expected := range map[string]int{"yes": http.StatusOK, "no": http.StatusForbidden} {
+		r := httptest.NewRequest(http.MethodGet, "/debug/build", nil)
+		r.Header.Set("X-Admin", header)
+
+		rr := httptest.NewRecorder()
+		mux.ServeHTTP(rr, r)
+
+		if rr.Code != expected {
+			t.Fatalf("unexpected status with %s, got: %d, expected: %d", header, rr.Code, expected)
+		}
+	}
+}
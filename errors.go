@@ -0,0 +1,92 @@
+package httphelpers
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/bombsimon/http-helpers/bind"
+	"github.com/bombsimon/http-helpers/validate"
+)
+
+// HTTPError is an error with a status code which will be returned to the
+// client by the default error mapper.
+type HTTPError struct {
+	Status int
+	Err    error
+}
+
+// Error creates a new HTTPError with the passed status.
+func Error(status int, err error) error {
+	return &HTTPError{Status: status, Err: err}
+}
+
+func (e *HTTPError) Error() string {
+	return fmt.Sprintf("%d %s: %s", e.Status, http.StatusText(e.Status), e.Err)
+}
+
+func (e *HTTPError) Unwrap() error {
+	return e.Err
+}
+
+// ValidationError is returned when a request fails validation.
+type ValidationError struct {
+	Err error
+}
+
+func (e *ValidationError) Error() string {
+	return fmt.Sprintf("validation failed: %s", e.Err)
+}
+
+func (e *ValidationError) Unwrap() error {
+	return e.Err
+}
+
+// ErrorResponse is the body written by the default error mapper.
+type ErrorResponse struct {
+	Error string `json:"error"`
+}
+
+// ErrorMapper maps an error to the status code and body returned to the
+// client.
+type ErrorMapper func(err error) (status int, body interface{})
+
+// ValidationErrorResponse is the body written by the default error mapper for
+// validate.Errors.
+type ValidationErrorResponse struct {
+	Error  string          `json:"error"`
+	Fields validate.Errors `json:"fields"`
+}
+
+// DefaultErrorMapper maps bind and validation errors to 400 Bad Request, or
+// the status of the bind.Error, validate.Errors to 422 Unprocessable Entity
+// with the field errors and HTTPError to its status. All other errors are
+// mapped to 500 Internal Server Error without exposing the error to the
+// client.
+func DefaultErrorMapper(err error) (int, interface{}) {
+	var (
+		httpErr       *HTTPError
+		bodyErr       *bind.Error
+		fieldErrs     validate.Errors
+		bindErr       *BindError
+		validationErr *ValidationError
+	)
+
+	switch {
+	case errors.As(err, &httpErr):
+		return httpErr.Status, ErrorResponse{Error: httpErr.Err.Error()}
+	case errors.As(err, &fieldErrs):
+		return http.StatusUnprocessableEntity, ValidationErrorResponse{
+			Error:  "validation failed",
+			Fields: fieldErrs,
+		}
+	case errors.As(err, &bodyErr):
+		return bodyErr.Status, ErrorResponse{Error: err.Error()}
+	case errors.As(err, &bindErr), errors.As(err, &validationErr):
+		return http.StatusBadRequest, ErrorResponse{Error: err.Error()}
+	default:
+		return http.StatusInternalServerError, ErrorResponse{
+			Error: http.StatusText(http.StatusInternalServerError),
+		}
+	}
+}
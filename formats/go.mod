@@ -0,0 +1,18 @@
+module github.com/bombsimon/http-helpers/formats
+
+go 1.22
+
+require (
+	github.com/bombsimon/http-helpers v0.0.0-20261016131149-d6f19dc9ad2b
+	github.com/vmihailenco/msgpack/v5 v5.4.1
+	google.golang.org/protobuf v1.36.5
+)
+
+require (
+	github.com/vmihailenco/tagparser/v2 v2.0.0 // indirect
+	golang.org/x/text v0.22.0 // indirect
+)
+
+// Use the parent module from the same checkout during development. Dependents
+// ignore replace directives and get the version required above.
+replace github.com/bombsimon/http-helpers => ../
@@ -0,0 +1,33 @@
+package msgpack
+
+/*
+Registers a MessagePack encoder for render.Render and render.As. Import the
+package for its side effect:
+
+	import _ "github.com/bombsimon/http-helpers/formats/msgpack"
+
+Struct fields are named by their `json` tags so the same types can be used for
+both formats.
+*/
+
+import (
+	"io"
+
+	"github.com/bombsimon/http-helpers/render"
+	"github.com/vmihailenco/msgpack/v5"
+)
+
+// MediaType is the media type registered for MessagePack.
+const MediaType = "application/msgpack"
+
+func init() {
+	render.Register(MediaType, MediaType, Encode)
+}
+
+// Encode encodes v as MessagePack to w.
+func Encode(w io.Writer, v interface{}) error {
+	encoder := msgpack.NewEncoder(w)
+	encoder.SetCustomStructTag("json")
+
+	return encoder.Encode(v)
+}
@@ -0,0 +1,35 @@
+package msgpack
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/bombsimon/http-helpers/render"
+	"github.com/vmihailenco/msgpack/v5"
+)
+
+type user struct {
+	Name string `json:"name"`
+}
+
+func Test_Render(t *testing.T) {
+	rec := httptest.NewRecorder()
+
+	if err := render.As(rec, http.StatusOK, MediaType, user{Name: "bob"}); err != nil {
+		t.Fatal(err)
+	}
+
+	if ct := rec.Header().Get("Content-Type"); ct != MediaType {
+		t.Fatalf("unexpected content type: %s", ct)
+	}
+
+	var decoded map[string]string
+	if err := msgpack.Unmarshal(rec.Body.Bytes(), &decoded); err != nil {
+		t.Fatal(err)
+	}
+
+	if decoded["name"] != "bob" {
+		t.Fatalf("unexpected body: %v", decoded)
+	}
+}
@@ -0,0 +1,42 @@
+package protobuf
+
+/*
+Registers a Protocol Buffers encoder for render.Render and render.As. Import
+the package for its side effect:
+
+	import _ "github.com/bombsimon/http-helpers/formats/protobuf"
+
+Only values implementing proto.Message can be encoded.
+*/
+
+import (
+	"fmt"
+	"io"
+
+	"github.com/bombsimon/http-helpers/render"
+	"google.golang.org/protobuf/proto"
+)
+
+// MediaType is the media type registered for Protocol Buffers.
+const MediaType = "application/protobuf"
+
+func init() {
+	render.Register(MediaType, MediaType, Encode)
+}
+
+// Encode encodes v, which must be a proto.Message, to w.
+func Encode(w io.Writer, v interface{}) error {
+	message, ok := v.(proto.Message)
+	if !ok {
+		return fmt.Errorf("protobuf: %T is not a proto.Message", v)
+	}
+
+	b, err := proto.Marshal(message)
+	if err != nil {
+		return err
+	}
+
+	_, err = w.Write(b)
+
+	return err
+}
@@ -0,0 +1,42 @@
+package protobuf
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/bombsimon/http-helpers/render"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/wrapperspb"
+)
+
+func Test_Render(t *testing.T) {
+	rec := httptest.NewRecorder()
+
+	if err := render.As(rec, http.StatusOK, MediaType, wrapperspb.String("bob")); err != nil {
+		t.Fatal(err)
+	}
+
+	if ct := rec.Header().Get("Content-Type"); ct != MediaType {
+		t.Fatalf("unexpected content type: %s", ct)
+	}
+
+	decoded := &wrapperspb.StringValue{}
+	if err := proto.Unmarshal(rec.Body.Bytes(), decoded); err != nil {
+		t.Fatal(err)
+	}
+
+	if decoded.GetValue() != "bob" {
+		t.Fatalf("unexpected body: %v", decoded)
+	}
+
+	rec = httptest.NewRecorder()
+
+	if err := render.As(rec, http.StatusOK, MediaType, "not a message"); err == nil {
+		t.Fatal("expected error for non proto message")
+	}
+
+	if rec.Code != http.StatusInternalServerError {
+		t.Fatalf("unexpected status: %d", rec.Code)
+	}
+}
@@ -0,0 +1,151 @@
+package httphelpers
+
+/*
+Generic helpers to build JSON APIs with less boilerplate. Handle adapts a typed
+function to a http.Handler by binding the request, validating it, calling the
+function and writing the response or error as JSON.
+
+	type CreateUserRequest struct {
+		Name string `json:"name"`
+	}
+
+	func (r CreateUserRequest) Validate() error {
+		if r.Name == "" {
+			return errors.New("name is required")
+		}
+
+		return nil
+	}
+
+	router.Handle("/users", httphelpers.Handle(
+		func(ctx context.Context, req CreateUserRequest) (*User, error) {
+			return store.CreateUser(ctx, req.Name)
+		},
+	))
+*/
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"reflect"
+
+	"github.com/bombsimon/http-helpers/render"
+	"github.com/bombsimon/http-helpers/validate"
+)
+
+// Validator is implemented by requests that should be validated after being
+// bound.
+type Validator interface {
+	Validate() error
+}
+
+// HandleOption is an option used to configure Handle.
+type HandleOption func(*handleOptions)
+
+type handleOptions struct {
+	errorMapper ErrorMapper
+	status      int
+	validator   func(v interface{}) error
+}
+
+// WithErrorMapper sets the function used to map errors to a status code and
+// body. Defaults to DefaultErrorMapper.
+func WithErrorMapper(mapper ErrorMapper) HandleOption {
+	return func(o *handleOptions) {
+		o.errorMapper = mapper
+	}
+}
+
+// WithValidator sets the function used to validate struct requests after
+// they're bound. Defaults to validate.Struct validating `validate` struct tags.
+// Return validate.Errors to respond with 422 Unprocessable Entity and the field
+// errors, e.g. by converting the errors from another validation library.
+func WithValidator(fn func(v interface{}) error) HandleOption {
+	return func(o *handleOptions) {
+		o.validator = fn
+	}
+}
+
+// WithStatus sets the status code used for successful responses. Defaults to
+// 200 OK.
+func WithStatus(status int) HandleOption {
+	return func(o *handleOptions) {
+		o.status = status
+	}
+}
+
+// Handle returns a http.Handler calling fn with the request bound to Req. The
+// query parameters are bound with BindQuery and the body, if any, with
+// BindJSON. Struct requests are validated with the validator, see
+// WithValidator, and if Req implements Validator it's validated before fn is
+// called.
+// The response from fn is written as JSON and errors are written with the
+// error mapper.
+func Handle[Req, Resp any](fn func(ctx context.Context, req Req) (Resp, error), opts ...HandleOption) http.Handler {
+	options := &handleOptions{
+		errorMapper: DefaultErrorMapper,
+		status:      http.StatusOK,
+		validator:   validate.Struct,
+	}
+
+	for _, opt := range opts {
+		opt(options)
+	}
+
+	writeError := func(w http.ResponseWriter, err error) {
+		status, body := options.errorMapper(err)
+		_ = render.JSON(w, status, body)
+	}
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		req, err := bindRequest[Req](r, options)
+		if err != nil {
+			writeError(w, err)
+			return
+		}
+
+		resp, err := fn(r.Context(), req)
+		if err != nil {
+			writeError(w, err)
+			return
+		}
+
+		_ = render.JSON(w, options.status, resp)
+	})
+}
+
+func bindRequest[Req any](r *http.Request, options *handleOptions) (Req, error) {
+	var req Req
+
+	isStruct := reflect.TypeOf(&req).Elem().Kind() == reflect.Struct
+
+	if isStruct {
+		if err := BindQuery(r, &req); err != nil {
+			return req, err
+		}
+	}
+
+	// An empty body is allowed even if the request has a body, e.g. when
+	// using chunked transfer encoding.
+	if r.Body != nil && r.Body != http.NoBody {
+		if err := BindJSON(r, &req); err != nil && !errors.Is(err, io.EOF) {
+			return req, err
+		}
+	}
+
+	if isStruct && options.validator != nil {
+		if err := options.validator(&req); err != nil {
+			return req, err
+		}
+	}
+
+	if v, ok := any(&req).(Validator); ok {
+		if err := v.Validate(); err != nil {
+			return req, &ValidationError{Err: err}
+		}
+	}
+
+	return req, nil
+}
@@ -0,0 +1,173 @@
+package httphelpers
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+type greetRequest struct {
+	Name     string `json:"name"`
+	Greeting string `json:"-" query:"greeting"`
+}
+
+func (r greetRequest) Validate() error {
+	if r.Name == "" {
+		return errors.New("name is required")
+	}
+
+	return nil
+}
+
+type greetResponse struct {
+	Message string `json:"message"`
+}
+
+func Test_Handle(t *testing.T) {
+	handler := Handle(func(_ context.Context, req greetRequest) (greetResponse, error) {
+		switch req.Name {
+		case "teapot":
+			return greetResponse{}, Error(http.StatusTeapot, errors.New("i'm a teapot"))
+		case "secret":
+			return greetResponse{}, errors.New("database password is hunter2")
+		}
+
+		return greetResponse{Message: req.Greeting + ", " + req.Name}, nil
+	})
+
+	tests := []struct {
+		name           string
+		target         string
+		body           string
+		expectedStatus int
+		expectedBody   string
+	}{
+		{
+			name:           "success",
+			target:         "/?greeting=hello",
+			body:           `{"name": "world"}`,
+			expectedStatus: http.StatusOK,
+			expectedBody:   `{"message":"hello, world"}`,
+		},
+		{
+			name:           "invalid json",
+			target:         "/",
+			body:           `{"name":`,
+			expectedStatus: http.StatusBadRequest,
+			expectedBody:   `{"error":"invalid request: malformed JSON"}`,
+		},
+		{
+			name:           "validation error",
+			target:         "/",
+			body:           `{}`,
+			expectedStatus: http.StatusBadRequest,
+			expectedBody:   `{"error":"validation failed: name is required"}`,
+		},
+		{
+			name:           "http error",
+			target:         "/",
+			body:           `{"name": "teapot"}`,
+			expectedStatus: http.StatusTeapot,
+			expectedBody:   `{"error":"i'm a teapot"}`,
+		},
+		{
+			name:           "internal error is not exposed",
+			target:         "/",
+			body:           `{"name": "secret"}`,
+			expectedStatus: http.StatusInternalServerError,
+			expectedBody:   `{"error":"Internal Server Error"}`,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			req := httptest.NewRequest(http.MethodPost, tc.target, strings.NewReader(tc.body))
+
+			handler.ServeHTTP(rec, req)
+
+			if rec.Code != tc.expectedStatus {
+				t.Fatalf("unexpected status, got: %d, expected: %d", rec.Code, tc.expectedStatus)
+			}
+
+			if body := strings.TrimSpace(rec.Body.String()); body != tc.expectedBody {
+				t.Fatalf("unexpected body, got: %s, expected: %s", body, tc.expectedBody)
+			}
+		})
+	}
+}
+
+func Test_HandleWithOptions(t *testing.T) {
+	handler := Handle(
+		func(_ context.Context, _ struct{}) (map[string]bool, error) {
+			return map[string]bool{"created": true}, nil
+		},
+		WithStatus(http.StatusCreated),
+	)
+
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/", nil))
+
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("unexpected status, got: %d, expected: %d", rec.Code, http.StatusCreated)
+	}
+
+	handler = Handle(
+		func(_ context.Context, _ struct{}) (struct{}, error) {
+			return struct{}{}, errors.New("boom")
+		},
+		WithErrorMapper(func(err error) (int, interface{}) {
+			return http.StatusServiceUnavailable, err.Error()
+		}),
+	)
+
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+
+	if rec.Code != http.StatusServiceUnavailable {
+		t.Fatalf("unexpected status, got: %d, expected: %d", rec.Code, http.StatusServiceUnavailable)
+	}
+}
+
+type createUserRequest struct {
+	Name  string `json:"name" validate:"required,max=8"`
+	Email string `json:"email" validate:"required,email"`
+}
+
+func Test_HandleValidation(t *testing.T) {
+	handler := Handle(func(_ context.Context, req createUserRequest) (createUserRequest, error) {
+		return req, nil
+	})
+
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/", strings.NewReader(`{"name": "a very long name", "email": "nope"}`)))
+
+	if rec.Code != http.StatusUnprocessableEntity {
+		t.Fatalf("unexpected status, got: %d, expected: %d", rec.Code, http.StatusUnprocessableEntity)
+	}
+
+	expectedBody := `{"error":"validation failed","fields":[` +
+		`{"field":"name","rule":"max","param":"8","message":"length must be at most 8"},` +
+		`{"field":"email","rule":"email","message":"must be a valid email address"}]}`
+
+	if body := strings.TrimSpace(rec.Body.String()); body != expectedBody {
+		t.Fatalf("unexpected body, got: %s, expected: %s", body, expectedBody)
+	}
+
+	handler = Handle(
+		func(_ context.Context, req createUserRequest) (createUserRequest, error) {
+			return req, nil
+		},
+		WithValidator(func(interface{}) error { return nil }),
+	)
+
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/", strings.NewReader(`{}`)))
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("unexpected status, got: %d, expected: %d", rec.Code, http.StatusOK)
+	}
+}
@@ -0,0 +1,246 @@
+package httpctx
+
+/*
+Typed accessors for values stored in the request context by the middlewares in
+this module. The keys are unexported so they can't collide with keys from other
+packages, and consumers never have to use context.Value directly. Example
+usage:
+
+	func handler(w http.ResponseWriter, r *http.Request) {
+		requestID, ok := httpctx.RequestID(r.Context())
+		if !ok {
+			requestID = "unknown"
+		}
+
+		user, ok := httpctx.Principal[*User](r.Context())
+		if !ok {
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+	}
+*/
+
+import (
+	"context"
+	"net/http"
+
+	"golang.org/x/text/language"
+)
+
+type contextKey int
+
+const (
+	requestIDKey contextKey = iota
+	principalKey
+	tenantKey
+	loggerKey
+	localeKey
+	routePatternKey
+	realIPKey
+	traceKey
+	mediaTypeKey
+	maxBodySizeKey
+	flagsKey
+	propagatedHeadersKey
+	bodyKey
+)
+
+// Trace holds the trace and span ID of the current request, e.g. parsed from a
+// W3C traceparent header.
+type Trace struct {
+	TraceID string
+	SpanID  string
+
+	// Sampled is true if the trace is recorded, the sampled flag of the
+	// traceparent header.
+	Sampled bool
+}
+
+// WithRequestID returns a copy of the context with the request ID set.
+func WithRequestID(ctx context.Context, requestID string) context.Context {
+	return context.WithValue(ctx, requestIDKey, requestID)
+}
+
+// RequestID returns the request ID from the context, if any.
+func RequestID(ctx context.Context) (string, bool) {
+	return value[string](ctx, requestIDKey)
+}
+
+// WithPrincipal returns a copy of the context with the authenticated principal
+// set. The principal can be of any type, e.g. a user struct.
+func WithPrincipal[T any](ctx context.Context, principal T) context.Context {
+	return context.WithValue(ctx, principalKey, principal)
+}
+
+// Principal returns the authenticated principal from the context. The second
+// return value is false if no principal is set or if it's not of type T.
+func Principal[T any](ctx context.Context) (T, bool) {
+	return value[T](ctx, principalKey)
+}
+
+// WithTenant returns a copy of the context with the tenant set.
+func WithTenant(ctx context.Context, tenant string) context.Context {
+	return context.WithValue(ctx, tenantKey, tenant)
+}
+
+// Tenant returns the tenant from the context, if any.
+func Tenant(ctx context.Context) (string, bool) {
+	return value[string](ctx, tenantKey)
+}
+
+// WithLogger returns a copy of the context with a per request logger set. The
+// logger can be of any type so the package doesn't depend on a specific
+// logging library.
+func WithLogger[T any](ctx context.Context, logger T) context.Context {
+	return context.WithValue(ctx, loggerKey, logger)
+}
+
+// Logger returns the per request logger from the context. The second return
+// value is false if no logger is set or if it's not of type T.
+func Logger[T any](ctx context.Context) (T, bool) {
+	return value[T](ctx, loggerKey)
+}
+
+// WithLocale returns a copy of the context with the locale, e.g. "en-US", set.
+// The locale and the language set with WithLanguage are the same value, the
+// one set last is returned by both Locale and Language.
+func WithLocale(ctx context.Context, locale string) context.Context {
+	return context.WithValue(ctx, localeKey, locale)
+}
+
+// Locale returns the locale from the context, if any. If it's set with
+// WithLanguage the tag is returned as a string.
+func Locale(ctx context.Context) (string, bool) {
+	switch locale := ctx.Value(localeKey).(type) {
+	case string:
+		return locale, true
+	case language.Tag:
+		return locale.String(), true
+	}
+
+	return "", false
+}
+
+// WithLanguage returns a copy of the context with the language negotiated for
+// the response set. It replaces the locale set with WithLocale.
+func WithLanguage(ctx context.Context, tag language.Tag) context.Context {
+	return context.WithValue(ctx, localeKey, tag)
+}
+
+// Language returns the language negotiated for the response, if any. If it's
+// set with WithLocale the locale is parsed, and false is returned if it's not
+// a valid BCP 47 tag.
+func Language(ctx context.Context) (language.Tag, bool) {
+	switch locale := ctx.Value(localeKey).(type) {
+	case language.Tag:
+		return locale, true
+	case string:
+		tag, err := language.Parse(locale)
+		return tag, err == nil
+	}
+
+	return language.Und, false
+}
+
+// WithRoutePattern returns a copy of the context with the matched route
+// pattern, e.g. "/users/{id}", set.
+func WithRoutePattern(ctx context.Context, pattern string) context.Context {
+	return context.WithValue(ctx, routePatternKey, pattern)
+}
+
+// RoutePattern returns the matched route pattern from the context, if any.
+func RoutePattern(ctx context.Context) (string, bool) {
+	return value[string](ctx, routePatternKey)
+}
+
+// WithRealIP returns a copy of the context with the real client IP set, i.e.
+// the IP after resolving trusted proxy headers.
+func WithRealIP(ctx context.Context, ip string) context.Context {
+	return context.WithValue(ctx, realIPKey, ip)
+}
+
+// RealIP returns the real client IP from the context, if any.
+func RealIP(ctx context.Context) (string, bool) {
+	return value[string](ctx, realIPKey)
+}
+
+// WithTrace returns a copy of the context with the trace and span ID set.
+func WithTrace(ctx context.Context, trace Trace) context.Context {
+	return context.WithValue(ctx, traceKey, trace)
+}
+
+// TraceFrom returns the trace and span ID from the context, if any.
+func TraceFrom(ctx context.Context) (Trace, bool) {
+	return value[Trace](ctx, traceKey)
+}
+
+// WithMediaType returns a copy of the context with the negotiated response
+// media type, e.g. "application/json", set.
+func WithMediaType(ctx context.Context, mediaType string) context.Context {
+	return context.WithValue(ctx, mediaTypeKey, mediaType)
+}
+
+// MediaType returns the negotiated response media type from the context, if
+// any.
+func MediaType(ctx context.Context) (string, bool) {
+	return value[string](ctx, mediaTypeKey)
+}
+
+// WithMaxBodySize returns a copy of the context with the maximum request body
+// size for the route set, e.g. from an OpenAPI spec.
+func WithMaxBodySize(ctx context.Context, n int64) context.Context {
+	return context.WithValue(ctx, maxBodySizeKey, n)
+}
+
+// MaxBodySize returns the maximum request body size from the context, if any.
+func MaxBodySize(ctx context.Context) (int64, bool) {
+	return value[int64](ctx, maxBodySizeKey)
+}
+
+// WithBody returns a copy of the context with the buffered request body set,
+// e.g. by the BufferBody middleware.
+func WithBody(ctx context.Context, body []byte) context.Context {
+	return context.WithValue(ctx, bodyKey, body)
+}
+
+// Body returns the buffered request body from the context, if any. The body
+// is shared and must not be modified.
+func Body(ctx context.Context) ([]byte, bool) {
+	return value[[]byte](ctx, bodyKey)
+}
+
+// WithFlags returns a copy of the context with the feature flags evaluated for
+// the request set.
+func WithFlags(ctx context.Context, flags map[string]bool) context.Context {
+	return context.WithValue(ctx, flagsKey, flags)
+}
+
+// Flags returns the feature flags evaluated for the request, if any.
+func Flags(ctx context.Context) (map[string]bool, bool) {
+	return value[map[string]bool](ctx, flagsKey)
+}
+
+// FlagEnabled returns true if the feature flag is enabled for the request.
+// Flags not in the context are disabled.
+func FlagEnabled(ctx context.Context, flag string) bool {
+	flags, _ := Flags(ctx)
+	return flags[flag]
+}
+
+// WithPropagatedHeaders returns a copy of the context with headers to attach to
+// outgoing requests set, e.g. the request ID and tenant of the incoming
+// request.
+func WithPropagatedHeaders(ctx context.Context, header http.Header) context.Context {
+	return context.WithValue(ctx, propagatedHeadersKey, header)
+}
+
+// PropagatedHeaders returns the headers to attach to outgoing requests, if any.
+// The header must not be modified, clone it first.
+func PropagatedHeaders(ctx context.Context) (http.Header, bool) {
+	return value[http.Header](ctx, propagatedHeadersKey)
+}
+
+func value[T any](ctx context.Context, key contextKey) (T, bool) {
+	v, ok := ctx.Value(key).(T)
+	return v, ok
+}
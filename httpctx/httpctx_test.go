@@ -0,0 +1,108 @@
+package httpctx
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	"golang.org/x/text/language"
+)
+
+type user struct {
+	name string
+}
+
+func Test_Accessors(t *testing.T) {
+	ctx := context.Background()
+
+	if _, ok := RequestID(ctx); ok {
+		t.Fatal("expected no request ID in empty context")
+	}
+
+	ctx = WithRequestID(ctx, "abc")
+	ctx = WithTenant(ctx, "acme")
+	ctx = WithLocale(ctx, "sv-SE")
+	ctx = WithRoutePattern(ctx, "/users/{id}")
+	ctx = WithPrincipal(ctx, &user{name: "bob"})
+	ctx = WithRealIP(ctx, "192.0.2.1")
+	ctx = WithMediaType(ctx, "application/xml")
+	ctx = WithTrace(ctx, Trace{TraceID: "4bf92f3577b34da6a3ce929d0e0e4736", SpanID: "00f067aa0ba902b7"})
+
+	for _, tc := range []struct {
+		name     string
+		get      func(context.Context) (string, bool)
+		expected string
+	}{
+		{name: "request id", get: RequestID, expected: "abc"},
+		{name: "tenant", get: Tenant, expected: "acme"},
+		{name: "locale", get: Locale, expected: "sv-SE"},
+		{name: "route pattern", get: RoutePattern, expected: "/users/{id}"},
+		{name: "real ip", get: RealIP, expected: "192.0.2.1"},
+		{name: "media type", get: MediaType, expected: "application/xml"},
+	} {
+		got, ok := tc.get(ctx)
+		if !ok || got != tc.expected {
+			t.Fatalf("unexpected %s, got: %s, expected: %s", tc.name, got, tc.expected)
+		}
+	}
+
+	principal, ok := Principal[*user](ctx)
+	if !ok || principal.name != "bob" {
+		t.Fatal("could not get principal")
+	}
+
+	trace, ok := TraceFrom(ctx)
+	if !ok || trace.TraceID != "4bf92f3577b34da6a3ce929d0e0e4736" || trace.SpanID != "00f067aa0ba902b7" {
+		t.Fatalf("unexpected trace: %+v", trace)
+	}
+
+	if size, ok := MaxBodySize(WithMaxBodySize(ctx, 1024)); !ok || size != 1024 {
+		t.Fatalf("unexpected max body size: %d", size)
+	}
+
+	if body, ok := Body(WithBody(ctx, []byte("payload"))); !ok || string(body) != "payload" {
+		t.Fatalf("unexpected body: %s", body)
+	}
+
+	if FlagEnabled(ctx, "beta") {
+		t.Fatal("expected flag to be disabled without flags in context")
+	}
+
+	if !FlagEnabled(WithFlags(ctx, map[string]bool{"beta": true}), "beta") {
+		t.Fatal("expected flag to be enabled")
+	}
+
+	if tag, ok := Language(WithLanguage(ctx, language.Swedish)); !ok || tag != language.Swedish {
+		t.Fatalf("unexpected language: %s", tag)
+	}
+
+	// The locale and language are the same value, the one set last wins.
+	if locale, ok := Locale(WithLanguage(ctx, language.German)); !ok || locale != "de" {
+		t.Fatalf("unexpected locale from language: %s", locale)
+	}
+
+	if tag, ok := Language(ctx); !ok || tag != language.MustParse("sv-SE") {
+		t.Fatalf("unexpected language from locale: %s", tag)
+	}
+
+	if _, ok := Language(WithLocale(ctx, "not a locale")); ok {
+		t.Fatal("expected invalid locale to not be a language")
+	}
+
+	header := http.Header{"X-Tenant-Id": {"acme"}}
+	if got, ok := PropagatedHeaders(WithPropagatedHeaders(ctx, header)); !ok || got.Get("X-Tenant-Id") != "acme" {
+		t.Fatalf("unexpected propagated headers: %v", got)
+	}
+
+	if _, ok := Principal[string](ctx); ok {
+		t.Fatal("expected principal of wrong type to not be found")
+	}
+
+	// Ensure we don't collide with string keys from other packages.
+	//nolint:staticcheck // We want to test collisions with string keys.
+	ctx = context.WithValue(ctx, "0", "collision")
+
+	if got, _ := RequestID(ctx); got != "abc" {
+		t.Fatal("request ID collided with string key")
+	}
+}
@@ -0,0 +1,264 @@
+package httptesting
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+
+	"github.com/getkin/kin-openapi/openapi3"
+)
+
+// maxDepth limits how deep nested schemas are generated to handle recursive
+// schemas.
+const maxDepth = 8
+
+// Example returns an example value valid for the schema. The example or default
+// from the schema is used if set. Otherwise a value is generated from the type,
+// format and constraints of the schema. Patterns are not supported.
+func Example(schema *openapi3.Schema) interface{} {
+	return example(schema, 0)
+}
+
+func example(schema *openapi3.Schema, depth int) interface{} {
+	switch {
+	case schema == nil:
+		return nil
+	case schema.Example != nil:
+		return schema.Example
+	case schema.Default != nil:
+		return schema.Default
+	case len(schema.Enum) > 0:
+		return schema.Enum[0]
+	case len(schema.AllOf) > 0:
+		merged := map[string]interface{}{}
+
+		for _, ref := range schema.AllOf {
+			if m, ok := example(ref.Value, depth+1).(map[string]interface{}); ok {
+				for k, v := range m {
+					merged[k] = v
+				}
+			}
+		}
+
+		return merged
+	case len(schema.OneOf) > 0:
+		return example(schema.OneOf[0].Value, depth+1)
+	case len(schema.AnyOf) > 0:
+		return example(schema.AnyOf[0].Value, depth+1)
+	}
+
+	typ := ""
+	if types := schema.Type.Slice(); len(types) > 0 {
+		typ = types[0]
+	} else if len(schema.Properties) > 0 {
+		typ = openapi3.TypeObject
+	}
+
+	switch typ {
+	case openapi3.TypeObject:
+		return exampleObject(schema, depth)
+	case openapi3.TypeArray:
+		return exampleArray(schema, depth)
+	case openapi3.TypeString:
+		return exampleString(schema)
+	case openapi3.TypeInteger:
+		return int64(exampleNumber(schema, true))
+	case openapi3.TypeNumber:
+		return exampleNumber(schema, false)
+	case openapi3.TypeBoolean:
+		return true
+	default:
+		return nil
+	}
+}
+
+func exampleObject(schema *openapi3.Schema, depth int) map[string]interface{} {
+	object := map[string]interface{}{}
+	if depth >= maxDepth {
+		return object
+	}
+
+	names := make([]string, 0, len(schema.Properties))
+	for name := range schema.Properties {
+		names = append(names, name)
+	}
+
+	sort.Strings(names)
+
+	for _, name := range names {
+		property := schema.Properties[name].Value
+
+		// Read only properties are not allowed in requests.
+		if property == nil || property.ReadOnly {
+			continue
+		}
+
+		object[name] = example(property, depth+1)
+	}
+
+	return object
+}
+
+func exampleArray(schema *openapi3.Schema, depth int) []interface{} {
+	n := int(schema.MinItems)
+	if n == 0 && (schema.MaxItems == nil || *schema.MaxItems > 0) {
+		n = 1
+	}
+
+	if depth >= maxDepth || schema.Items == nil || schema.Items.Value == nil {
+		return []interface{}{}
+	}
+
+	items := make([]interface{}, 0, n)
+
+	for i := 0; i < n; i++ {
+		item := example(schema.Items.Value, depth+1)
+
+		// Make items unique by using other enum values, numbers or suffixes
+		// if required.
+		if enum := schema.Items.Value.Enum; schema.UniqueItems && i > 0 && i < len(enum) {
+			item = enum[i]
+		} else if schema.UniqueItems && i > 0 {
+			switch v := item.(type) {
+			case string:
+				item = fmt.Sprintf("%s%d", v, i)
+			case int64:
+				item = v + int64(i)
+			case float64:
+				item = v + float64(i)
+			}
+		}
+
+		items = append(items, item)
+	}
+
+	return items
+}
+
+func exampleString(schema *openapi3.Schema) string {
+	switch schema.Format {
+	case "date-time":
+		return "2006-01-02T15:04:05Z"
+	case "date":
+		return "2006-01-02"
+	case "time":
+		return "15:04:05"
+	case "email":
+		return "user@example.com"
+	case "uuid":
+		return "123e4567-e89b-42d3-a456-426614174000"
+	case "uri", "url":
+		return "https://example.com"
+	case "hostname":
+		return "example.com"
+	case "ipv4":
+		return "192.0.2.1"
+	case "ipv6":
+		return "2001:db8::1"
+	case "byte":
+		return "ZXhhbXBsZQ=="
+	}
+
+	s := "string"
+
+	if n := int(schema.MinLength); len(s) < n {
+		s += strings.Repeat("x", n-len(s))
+	}
+
+	if schema.MaxLength != nil && uint64(len(s)) > *schema.MaxLength {
+		s = s[:*schema.MaxLength]
+	}
+
+	return s
+}
+
+func exampleNumber(schema *openapi3.Schema, integer bool) float64 {
+	value := 1.0
+
+	if schema.Min != nil {
+		value = *schema.Min
+		if schema.ExclusiveMin {
+			value++
+		}
+	}
+
+	if schema.Max != nil && value > *schema.Max {
+		value = *schema.Max
+		if schema.ExclusiveMax {
+			value--
+		}
+	}
+
+	if integer {
+		value = math.Ceil(value)
+	}
+
+	if schema.MultipleOf != nil && *schema.MultipleOf > 0 {
+		value = math.Ceil(value / *schema.MultipleOf) * *schema.MultipleOf
+	}
+
+	return value
+}
+
+// parameterValues returns example values for the parameter. Arrays return one
+// value per item.
+func parameterValues(param *openapi3.Parameter) []string {
+	var value interface{}
+
+	switch {
+	case param.Example != nil:
+		value = param.Example
+	case len(param.Examples) > 0:
+		names := make([]string, 0, len(param.Examples))
+		for name := range param.Examples {
+			names = append(names, name)
+		}
+
+		sort.Strings(names)
+
+		if ex := param.Examples[names[0]].Value; ex != nil {
+			value = ex.Value
+		}
+	case param.Schema != nil:
+		value = Example(param.Schema.Value)
+	}
+
+	if items, ok := value.([]interface{}); ok {
+		values := make([]string, 0, len(items))
+		for _, item := range items {
+			values = append(values, fmt.Sprint(item))
+		}
+
+		return values
+	}
+
+	return []string{fmt.Sprint(value)}
+}
+
+// mediaExample returns the example for the media type or generates one from
+// the schema.
+func mediaExample(media *openapi3.MediaType) interface{} {
+	if media.Example != nil {
+		return media.Example
+	}
+
+	if len(media.Examples) > 0 {
+		names := make([]string, 0, len(media.Examples))
+		for name := range media.Examples {
+			names = append(names, name)
+		}
+
+		sort.Strings(names)
+
+		if ex := media.Examples[names[0]].Value; ex != nil {
+			return ex.Value
+		}
+	}
+
+	if media.Schema == nil {
+		return nil
+	}
+
+	return Example(media.Schema.Value)
+}
@@ -0,0 +1,126 @@
+package httptesting
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"runtime/debug"
+	"testing"
+)
+
+// seedCorpus contains raw HTTP requests used as the seed corpus by
+// FuzzHandler, covering malformed methods, headers, chunked bodies and
+// encodings.
+var seedCorpus = []string{
+	"GET / HTTP/1.1\r\nHost: example.com\r\n\r\n",
+	"HEAD /index.html HTTP/1.0\r\n\r\n",
+	"OPTIONS * HTTP/1.1\r\nHost: example.com\r\n\r\n",
+	"PURGE /cache HTTP/1.1\r\nHost: example.com\r\n\r\n",
+	"get /lowercase HTTP/1.1\r\nHost: example.com\r\n\r\n",
+	"POST /users HTTP/1.1\r\nHost: example.com\r\nContent-Type: application/json\r\nContent-Length: 16\r\n\r\n{\"name\":\"user\"}\n",
+	"POST /users HTTP/1.1\r\nHost: example.com\r\nContent-Type: application/json\r\nContent-Length: 5\r\n\r\n{\"na",
+	"POST /users HTTP/1.1\r\nHost: example.com\r\nContent-Type: application/json\r\nContent-Length: 2\r\n\r\n{\x00",
+	"POST /upload HTTP/1.1\r\nHost: example.com\r\nTransfer-Encoding: chunked\r\n\r\n5\r\nhello\r\n0\r\n\r\n",
+	"POST /upload HTTP/1.1\r\nHost: example.com\r\nTransfer-Encoding: chunked\r\n\r\nzz\r\nhello\r\n0\r\n\r\n",
+	"POST /upload HTTP/1.1\r\nHost: example.com\r\nTransfer-Encoding: chunked\r\n\r\nffffffff\r\nhello",
+	"POST /upload HTTP/1.1\r\nHost: example.com\r\nTransfer-Encoding: chunked\r\n\r\n5\r\nhello\r\n0\r\nX-Trailer: value\r\n\r\n",
+	"POST /upload HTTP/1.1\r\nHost: example.com\r\nContent-Encoding: gzip\r\nContent-Length: 5\r\n\r\nhello",
+	"POST /upload HTTP/1.1\r\nHost: example.com\r\nContent-Encoding: br, gzip, identity\r\nContent-Length: 3\r\n\r\n\x1f\x8b\x08",
+	"GET / HTTP/1.1\r\nHost: example.com\r\nAccept-Encoding: gzip;q=0, *;q=1.5, br;q=abc\r\n\r\n",
+	"GET / HTTP/1.1\r\nHost: example.com\r\nAccept: */*;q=0.5, application/json;q=, text/*\r\n\r\n",
+	"GET / HTTP/1.1\r\nHost: example.com\r\nRange: bytes=-1-2,5-\r\n\r\n",
+	"GET / HTTP/1.1\r\nHost: example.com\r\nIf-None-Match: \"unterminated\r\nIf-Modified-Since: yesterday\r\n\r\n",
+	"GET / HTTP/1.1\r\nHost: example.com\r\nAuthorization: Bearer\r\nCookie: =;;a=\x7f\r\n\r\n",
+	"GET / HTTP/1.1\r\nHost: example.com\r\nX-Forwarded-For: , unknown, 999.1.1.1\r\nX-Request-Id: \xff\xfe\r\n\r\n",
+	"GET /%ff%fe/../../etc/passwd?a=%zz&b;c HTTP/1.1\r\nHost: example.com\r\n\r\n",
+	"GET http://other.example.com/absolute HTTP/1.1\r\nHost: example.com\r\n\r\n",
+	"GET //double//slashes/ HTTP/1.1\r\n\r\n",
+	"POST / HTTP/1.1\r\nHost: example.com\r\nExpect: 100-continue\r\nContent-Length: 0\r\n\r\n",
+	"DELETE / HTTP/1.1\r\nHost: example.com\r\nContent-Length: 10\r\n\r\nshort",
+}
+
+// FuzzHandler fuzzes the handler with raw HTTP requests. Requests that can't be
+// parsed are skipped since they're rejected by the server before reaching the
+// handler. The test fails if the handler panics or writes an invalid status.
+// The seed corpus covers malformed methods, headers, chunked bodies and
+// encodings.
+//
+//	func FuzzHandler(f *testing.F) {
+//		httptesting.FuzzHandler(f, middleware.AddMiddlewares(router, middlewares...))
+//	}
+func FuzzHandler(f *testing.F, handler http.Handler) {
+	for _, seed := range seedCorpus {
+		f.Add([]byte(seed))
+	}
+
+	f.Fuzz(func(t *testing.T, raw []byte) {
+		r, err := http.ReadRequest(bufio.NewReader(bytes.NewReader(raw)))
+		if err != nil {
+			t.Skip()
+		}
+
+		if err := serveFuzzRequest(handler, r); err != nil {
+			t.Fatalf("%s\nrequest:\n%q", err, raw)
+		}
+	})
+}
+
+// serveFuzzRequest serves the request and returns an error if the handler
+// panics or doesn't write a valid status.
+func serveFuzzRequest(handler http.Handler, r *http.Request) (err error) {
+	r.RemoteAddr = "192.0.2.1:1234"
+
+	rec := &statusRecorder{ResponseRecorder: httptest.NewRecorder()}
+
+	defer func() {
+		if p := recover(); p != nil {
+			err = fmt.Errorf("handler panicked: %v\n%s", p, debug.Stack())
+		}
+	}()
+
+	handler.ServeHTTP(rec, r)
+
+	if rec.wroteHeader && (rec.status < 100 || rec.status > 599) {
+		return fmt.Errorf("handler wrote invalid status %d", rec.status)
+	}
+
+	return nil
+}
+
+// statusRecorder records the status the handler passes to WriteHeader. The
+// httptest.ResponseRecorder panics on some invalid statuses and accepts
+// others, so the status is checked before it's passed on.
+type statusRecorder struct {
+	*httptest.ResponseRecorder
+	status      int
+	wroteHeader bool
+}
+
+func (r *statusRecorder) WriteHeader(status int) {
+	if r.wroteHeader {
+		return
+	}
+
+	r.status = status
+
+	// Informational statuses may be followed by the final status.
+	r.wroteHeader = status < 100 || status > 199 || status == http.StatusSwitchingProtocols
+
+	if status >= 100 && status <= 599 {
+		r.ResponseRecorder.WriteHeader(status)
+	}
+}
+
+func (r *statusRecorder) Write(b []byte) (int, error) {
+	r.WriteHeader(http.StatusOK)
+
+	return r.ResponseRecorder.Write(b)
+}
+
+func (r *statusRecorder) WriteString(s string) (int, error) {
+	r.WriteHeader(http.StatusOK)
+
+	return r.ResponseRecorder.WriteString(s)
+}
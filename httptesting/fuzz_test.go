@@ -0,0 +1,98 @@
+package httptesting
+
+import (
+	"compress/gzip"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func FuzzStack(f *testing.F) {
+	FuzzHandler(f, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body := r.Body
+
+		if r.Header.Get("Content-Encoding") == "gzip" {
+			gz, err := gzip.NewReader(r.Body)
+			if err != nil {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+
+			body = gz
+		}
+
+		var v interface{}
+		if err := json.NewDecoder(body).Decode(&v); err != nil && err != io.EOF {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		w.WriteHeader(http.StatusNoContent)
+	}))
+}
+
+func Test_ServeFuzzRequest(t *testing.T) {
+	tests := []struct {
+		name          string
+		handler       http.HandlerFunc
+		expectedError string
+	}{
+		{
+			name:    "nothing written",
+			handler: func(w http.ResponseWriter, r *http.Request) {},
+		},
+		{
+			name: "panic",
+			handler: func(w http.ResponseWriter, r *http.Request) {
+				panic("oh no")
+			},
+			expectedError: "handler panicked: oh no",
+		},
+		{
+			name: "invalid status",
+			handler: func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(700)
+			},
+			expectedError: "handler wrote invalid status 700",
+		},
+		{
+			name: "status below 100",
+			handler: func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(42)
+			},
+			expectedError: "handler wrote invalid status 42",
+		},
+		{
+			name: "invalid status after write",
+			handler: func(w http.ResponseWriter, r *http.Request) {
+				_, _ = w.Write([]byte("ok"))
+				w.WriteHeader(700)
+			},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			err := serveFuzzRequest(tc.handler, httptest.NewRequest(http.MethodGet, "/", nil))
+
+			if tc.expectedError == "" {
+				if err != nil {
+					t.Fatalf("unexpected error: %s", err)
+				}
+
+				return
+			}
+
+			if err == nil {
+				t.Fatalf("expected error: %s", tc.expectedError)
+			}
+
+			if !strings.Contains(err.Error(), tc.expectedError) {
+				t.Fatalf("unexpected error: %s", err)
+			}
+		})
+	}
+}
@@ -0,0 +1,213 @@
+package httptesting
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"testing"
+)
+
+// Update makes AssertGolden write the golden files instead of comparing them.
+// It's set if UPDATE_GOLDEN is, e.g. UPDATE_GOLDEN=1 go test ./..., and
+// AssertGolden also updates the files if the test binary defines an -update
+// flag that is set. No flag is registered by this package.
+var Update = os.Getenv("UPDATE_GOLDEN") != ""
+
+// updateGolden returns true if the golden files should be written.
+func updateGolden() bool {
+	if Update {
+		return true
+	}
+
+	f := flag.Lookup("update")
+	if f == nil {
+		return false
+	}
+
+	getter, ok := f.Value.(flag.Getter)
+	if !ok {
+		return false
+	}
+
+	update, _ := getter.Get().(bool)
+
+	return update
+}
+
+// GoldenOption configures AssertGolden.
+type GoldenOption func(*goldenOptions)
+
+type goldenOptions struct {
+	path         string
+	headers      []string
+	replacements []replacement
+}
+
+type replacement struct {
+	pattern *regexp.Regexp
+	with    string
+}
+
+// WithGoldenFile sets the path of the golden file. Defaults to the name of the
+// test in the testdata directory, e.g. testdata/Test_Users/get.golden.
+func WithGoldenFile(path string) GoldenOption {
+	return func(o *goldenOptions) {
+		o.path = path
+	}
+}
+
+// WithGoldenHeaders adds headers to include in the golden file. Only
+// Content-Type is included by default since most headers, e.g. Date, change
+// between runs.
+func WithGoldenHeaders(names ...string) GoldenOption {
+	return func(o *goldenOptions) {
+		o.headers = append(o.headers, names...)
+	}
+}
+
+// WithGoldenReplace replaces all matches of the pattern in the body and headers
+// before comparing, e.g. to replace generated IDs and timestamps with a
+// placeholder.
+func WithGoldenReplace(pattern, with string) GoldenOption {
+	return func(o *goldenOptions) {
+		o.replacements = append(o.replacements, replacement{
+			pattern: regexp.MustCompile(pattern),
+			with:    with,
+		})
+	}
+}
+
+// AssertGolden serializes the status, the selected headers and the body of the
+// response and fails the test if it differs from the golden file. JSON bodies
+// are indented to make the golden files readable and diffs small. Run the test
+// with UPDATE_GOLDEN=1, or set Update, to write the golden file instead.
+//
+//	rec := httptest.NewRecorder()
+//	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/users/1", nil))
+//
+//	httptesting.AssertGolden(t, rec.Result(), httptesting.WithGoldenReplace(`"created_at": "[^"]*"`, `"created_at": "<time>"`))
+func AssertGolden(t testing.TB, resp *http.Response, opts ...GoldenOption) {
+	t.Helper()
+
+	options := &goldenOptions{
+		path:    filepath.Join("testdata", filepath.FromSlash(t.Name())+".golden"),
+		headers: []string{"Content-Type"},
+	}
+
+	for _, opt := range opts {
+		opt(options)
+	}
+
+	got, err := serializeResponse(resp, options)
+	if err != nil {
+		t.Fatalf("could not serialize response: %s", err)
+	}
+
+	if updateGolden() {
+		if err := os.MkdirAll(filepath.Dir(options.path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+
+		if err := os.WriteFile(options.path, got, 0o644); err != nil {
+			t.Fatal(err)
+		}
+
+		return
+	}
+
+	expected, err := os.ReadFile(options.path)
+	if err != nil {
+		t.Fatalf("could not read golden file, run with UPDATE_GOLDEN=1 to create it: %s", err)
+	}
+
+	if !bytes.Equal(got, expected) {
+		t.Fatalf("response differs from %s, run with UPDATE_GOLDEN=1 to update it:\n%s", options.path, lineDiff(string(expected), string(got)))
+	}
+}
+
+// serializeResponse returns the status line, the selected headers in the order
+// they were added and the normalized body.
+func serializeResponse(resp *http.Response, options *goldenOptions) ([]byte, error) {
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	_ = resp.Body.Close()
+
+	var buf bytes.Buffer
+
+	fmt.Fprintf(&buf, "%d %s\n", resp.StatusCode, http.StatusText(resp.StatusCode))
+
+	for _, name := range options.headers {
+		for _, value := range resp.Header.Values(name) {
+			fmt.Fprintf(&buf, "%s: %s\n", http.CanonicalHeaderKey(name), value)
+		}
+	}
+
+	buf.WriteString("\n")
+
+	if mediaType, _, _ := mime.ParseMediaType(resp.Header.Get("Content-Type")); strings.HasSuffix(mediaType, "json") {
+		var indented bytes.Buffer
+		if err := json.Indent(&indented, body, "", "  "); err == nil {
+			body = indented.Bytes()
+		}
+	}
+
+	buf.Write(body)
+
+	if len(body) > 0 && body[len(body)-1] != '\n' {
+		buf.WriteString("\n")
+	}
+
+	serialized := buf.Bytes()
+	for _, r := range options.replacements {
+		serialized = r.pattern.ReplaceAll(serialized, []byte(r.with))
+	}
+
+	return serialized, nil
+}
+
+// lineDiff returns the lines differing between expected and got, prefixed with
+// - and + respectively.
+func lineDiff(expected, got string) string {
+	var (
+		expectedLines = strings.Split(expected, "\n")
+		gotLines      = strings.Split(got, "\n")
+		sb            strings.Builder
+	)
+
+	for i := range max(len(expectedLines), len(gotLines)) {
+		var e, g string
+
+		if i < len(expectedLines) {
+			e = expectedLines[i]
+		}
+
+		if i < len(gotLines) {
+			g = gotLines[i]
+		}
+
+		if e == g {
+			continue
+		}
+
+		if i < len(expectedLines) {
+			fmt.Fprintf(&sb, "%d: - %s\n", i+1, e)
+		}
+
+		if i < len(gotLines) {
+			fmt.Fprintf(&sb, "%d: + %s\n", i+1, g)
+		}
+	}
+
+	return sb.String()
+}
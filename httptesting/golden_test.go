@@ -0,0 +1,87 @@
+package httptesting
+
+import (
+	"encoding/json"
+	"flag"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// A test package defining its own -update flag, which would panic with "flag
+// redefined" if the package registered one.
+var _ = flag.Bool("update", false, "update the golden files")
+
+func Test_AssertGolden(t *testing.T) {
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("X-Request-Id", "8f14e45f")
+		w.WriteHeader(http.StatusCreated)
+
+		_ = json.NewEncoder(w).Encode(map[string]any{
+			"id":         1,
+			"name":       "user",
+			"created_at": "2024-05-01T12:00:00Z",
+		})
+	})
+
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/users", nil))
+
+	AssertGolden(t, rec.Result(),
+		WithGoldenHeaders("X-Request-Id"),
+		WithGoldenReplace(`"created_at": "[^"]*"`, `"created_at": "<time>"`),
+	)
+}
+
+func Test_AssertGoldenUpdate(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "nested", "response.golden")
+
+	Update = true
+	defer func() { Update = false }()
+
+	rec := httptest.NewRecorder()
+	rec.WriteHeader(http.StatusNotFound)
+	_, _ = rec.WriteString("not found")
+
+	AssertGolden(t, rec.Result(), WithGoldenFile(path))
+
+	written, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if expected := "404 Not Found\n\nnot found\n"; string(written) != expected {
+		t.Fatalf("unexpected golden file, got: %q, expected: %q", written, expected)
+	}
+}
+
+func Test_AssertGoldenUpdateFlag(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "response.golden")
+
+	if err := flag.Set("update", "true"); err != nil {
+		t.Fatal(err)
+	}
+
+	defer func() { _ = flag.Set("update", "false") }()
+
+	rec := httptest.NewRecorder()
+	_, _ = rec.WriteString("ok")
+
+	AssertGolden(t, rec.Result(), WithGoldenFile(path))
+
+	if _, err := os.Stat(path); err != nil {
+		t.Fatalf("expected golden file to be written, got: %v", err)
+	}
+}
+
+func Test_LineDiff(t *testing.T) {
+	diff := lineDiff("200 OK\n\nhello\n", "500 Internal Server Error\n\nhello\nworld\n")
+
+	expected := "1: - 200 OK\n1: + 500 Internal Server Error\n4: - \n4: + world\n"
+	if diff != expected {
+		t.Fatalf("unexpected diff, got: %q, expected: %q", diff, expected)
+	}
+}
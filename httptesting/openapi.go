@@ -0,0 +1,282 @@
+package httptesting
+
+/*
+Helpers to test HTTP handlers. Fixtures are generated from an OpenAPI spec to
+get valid example requests for each operation and validate the responses
+against the spec:
+
+	func TestAPI(t *testing.T) {
+		fixtures, err := httptesting.LoadFixtures("openapi.yaml")
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		for _, fixture := range fixtures {
+			t.Run(fixture.Name(), func(t *testing.T) {
+				rec := httptest.NewRecorder()
+				handler.ServeHTTP(rec, fixture.NewRequest())
+
+				fixture.AssertResponse(t, rec)
+			})
+		}
+	}
+*/
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/getkin/kin-openapi/openapi3"
+	"github.com/getkin/kin-openapi/openapi3filter"
+	"github.com/getkin/kin-openapi/routers"
+)
+
+// Fixture is a valid example request for an operation in an OpenAPI spec. The
+// response to the request can be validated against the spec.
+type Fixture struct {
+	// OperationID is the operation ID from the spec, if any.
+	OperationID string
+
+	// Method is the HTTP method of the operation.
+	Method string
+
+	// Path is the path with the path parameters set to example values.
+	Path string
+
+	// Query is the query parameters with example values.
+	Query url.Values
+
+	// Header is the request headers with example values.
+	Header http.Header
+
+	// Body is the JSON encoded example request body or nil if the operation
+	// doesn't have a JSON request body.
+	Body []byte
+
+	route      *routers.Route
+	pathParams map[string]string
+}
+
+// LoadFixtures loads and validates the OpenAPI spec at path and generates
+// fixtures for all operations.
+func LoadFixtures(path string) ([]*Fixture, error) {
+	doc, err := openapi3.NewLoader().LoadFromFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := doc.Validate(context.Background()); err != nil {
+		return nil, err
+	}
+
+	return GenerateFixtures(doc)
+}
+
+// GenerateFixtures generates a fixture for each operation in the spec, ordered
+// by path and method. Example values from the spec are used when available.
+// Otherwise values are generated from the schemas.
+func GenerateFixtures(doc *openapi3.T) ([]*Fixture, error) {
+	var fixtures []*Fixture
+
+	paths := doc.Paths.Map()
+	keys := make([]string, 0, len(paths))
+
+	for path := range paths {
+		keys = append(keys, path)
+	}
+
+	sort.Strings(keys)
+
+	for _, path := range keys {
+		pathItem := paths[path]
+		operations := pathItem.Operations()
+		methods := make([]string, 0, len(operations))
+
+		for method := range operations {
+			methods = append(methods, method)
+		}
+
+		sort.Strings(methods)
+
+		for _, method := range methods {
+			fixture, err := newFixture(doc, path, pathItem, method, operations[method])
+			if err != nil {
+				return nil, fmt.Errorf("%s %s: %w", method, path, err)
+			}
+
+			fixtures = append(fixtures, fixture)
+		}
+	}
+
+	return fixtures, nil
+}
+
+func newFixture(
+	doc *openapi3.T,
+	path string,
+	pathItem *openapi3.PathItem,
+	method string,
+	operation *openapi3.Operation,
+) (*Fixture, error) {
+	fixture := &Fixture{
+		OperationID: operation.OperationID,
+		Method:      method,
+		Path:        path,
+		Query:       url.Values{},
+		Header:      http.Header{},
+		pathParams:  map[string]string{},
+		route: &routers.Route{
+			Spec:      doc,
+			Path:      path,
+			PathItem:  pathItem,
+			Method:    method,
+			Operation: operation,
+		},
+	}
+
+	parameters := append(openapi3.Parameters{}, pathItem.Parameters...)
+	parameters = append(parameters, operation.Parameters...)
+
+	for _, ref := range parameters {
+		param := ref.Value
+		if param == nil || (!param.Required && param.In != openapi3.ParameterInPath) {
+			continue
+		}
+
+		values := parameterValues(param)
+
+		switch param.In {
+		case openapi3.ParameterInPath:
+			value := strings.Join(values, ",")
+			fixture.pathParams[param.Name] = value
+			fixture.Path = strings.ReplaceAll(fixture.Path, "{"+param.Name+"}", url.PathEscape(value))
+		case openapi3.ParameterInQuery:
+			fixture.Query[param.Name] = values
+		case openapi3.ParameterInHeader:
+			fixture.Header.Set(param.Name, strings.Join(values, ","))
+		case openapi3.ParameterInCookie:
+			fixture.Header.Add("Cookie", (&http.Cookie{Name: param.Name, Value: values[0]}).String())
+		}
+	}
+
+	if operation.RequestBody != nil && operation.RequestBody.Value != nil {
+		contentType, media := jsonMediaType(operation.RequestBody.Value.Content)
+		if media != nil {
+			body, err := json.Marshal(mediaExample(media))
+			if err != nil {
+				return nil, err
+			}
+
+			fixture.Body = body
+			fixture.Header.Set("Content-Type", contentType)
+		}
+	}
+
+	return fixture, nil
+}
+
+// Name returns a name for the fixture, suitable for sub tests.
+func (f *Fixture) Name() string {
+	if f.OperationID != "" {
+		return f.OperationID
+	}
+
+	return f.Method + " " + f.route.Path
+}
+
+// NewRequest creates a new request for the fixture. A new request is created
+// for each call so the body can be read again.
+func (f *Fixture) NewRequest() *http.Request {
+	target := f.Path
+	if len(f.Query) > 0 {
+		target += "?" + f.Query.Encode()
+	}
+
+	var body io.Reader
+	if f.Body != nil {
+		body = bytes.NewReader(f.Body)
+	}
+
+	r := httptest.NewRequest(f.Method, target, body)
+
+	for k, v := range f.Header {
+		r.Header[k] = append([]string(nil), v...)
+	}
+
+	return r
+}
+
+// ValidateRequest validates the request against the operation in the spec.
+// Security requirements are not validated.
+func (f *Fixture) ValidateRequest(r *http.Request) error {
+	return openapi3filter.ValidateRequest(r.Context(), f.requestInput(r))
+}
+
+// ValidateResponse validates the response against the operation in the spec.
+// Status codes not documented for the operation are reported as errors.
+func (f *Fixture) ValidateResponse(status int, header http.Header, body []byte) error {
+	input := &openapi3filter.ResponseValidationInput{
+		RequestValidationInput: f.requestInput(f.NewRequest()),
+		Status:                 status,
+		Header:                 header,
+		Options: &openapi3filter.Options{
+			IncludeResponseStatus: true,
+		},
+	}
+
+	input.SetBodyBytes(body)
+
+	return openapi3filter.ValidateResponse(context.Background(), input)
+}
+
+// AssertResponse fails the test if the recorded response doesn't match the
+// spec.
+func (f *Fixture) AssertResponse(t testing.TB, rec *httptest.ResponseRecorder) {
+	t.Helper()
+
+	if err := f.ValidateResponse(rec.Code, rec.Header(), rec.Body.Bytes()); err != nil {
+		t.Errorf("%s: response doesn't match the spec: %s", f.Name(), err)
+	}
+}
+
+func (f *Fixture) requestInput(r *http.Request) *openapi3filter.RequestValidationInput {
+	return &openapi3filter.RequestValidationInput{
+		Request:    r,
+		PathParams: f.pathParams,
+		Route:      f.route,
+		Options: &openapi3filter.Options{
+			AuthenticationFunc: openapi3filter.NoopAuthenticationFunc,
+		},
+	}
+}
+
+// jsonMediaType returns the first JSON media type in the content, if any.
+func jsonMediaType(content openapi3.Content) (string, *openapi3.MediaType) {
+	if media, ok := content["application/json"]; ok {
+		return "application/json", media
+	}
+
+	contentTypes := make([]string, 0, len(content))
+	for contentType := range content {
+		contentTypes = append(contentTypes, contentType)
+	}
+
+	sort.Strings(contentTypes)
+
+	for _, contentType := range contentTypes {
+		if strings.HasSuffix(contentType, "+json") {
+			return contentType, content[contentType]
+		}
+	}
+
+	return "", nil
+}
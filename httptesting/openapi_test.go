@@ -0,0 +1,179 @@
+package httptesting
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/getkin/kin-openapi/openapi3"
+)
+
+const spec = `
+openapi: 3.0.3
+info:
+  title: Users
+  version: 1.0.0
+paths:
+  /users:
+    post:
+      operationId: createUser
+      requestBody:
+        required: true
+        content:
+          application/json:
+            schema:
+              $ref: '#/components/schemas/NewUser'
+      responses:
+        '201':
+          description: Created
+          content:
+            application/json:
+              schema:
+                $ref: '#/components/schemas/User'
+  /users/{id}:
+    get:
+      operationId: getUser
+      parameters:
+        - name: id
+          in: path
+          required: true
+          schema:
+            type: integer
+            minimum: 10
+        - name: fields
+          in: query
+          required: true
+          schema:
+            type: array
+            minItems: 2
+            uniqueItems: true
+            items:
+              type: string
+              enum: [name, email]
+      responses:
+        '200':
+          description: OK
+          content:
+            application/json:
+              schema:
+                $ref: '#/components/schemas/User'
+components:
+  schemas:
+    NewUser:
+      type: object
+      required: [name, email]
+      properties:
+        name:
+          type: string
+          minLength: 10
+        email:
+          type: string
+          format: email
+        age:
+          type: integer
+          minimum: 18
+          exclusiveMinimum: true
+        tags:
+          type: array
+          items:
+            type: string
+    User:
+      allOf:
+        - $ref: '#/components/schemas/NewUser'
+        - type: object
+          required: [id]
+          properties:
+            id:
+              type: integer
+              readOnly: true
+`
+
+func loadFixtures(t *testing.T) []*Fixture {
+	t.Helper()
+
+	doc, err := openapi3.NewLoader().LoadFromData([]byte(spec))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := doc.Validate(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	fixtures, err := GenerateFixtures(doc)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return fixtures
+}
+
+func Test_GenerateFixtures(t *testing.T) {
+	fixtures := loadFixtures(t)
+
+	if len(fixtures) != 2 {
+		t.Fatalf("unexpected number of fixtures: %d", len(fixtures))
+	}
+
+	for _, fixture := range fixtures {
+		t.Run(fixture.Name(), func(t *testing.T) {
+			if err := fixture.ValidateRequest(fixture.NewRequest()); err != nil {
+				t.Fatalf("generated request is not valid: %s", err)
+			}
+		})
+	}
+
+	if fixtures[1].Path != "/users/10" {
+		t.Fatalf("unexpected path: %s", fixtures[1].Path)
+	}
+}
+
+func Test_AssertResponse(t *testing.T) {
+	fixtures := loadFixtures(t)
+
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		user := map[string]interface{}{}
+
+		if r.Method == http.MethodPost {
+			_ = json.NewDecoder(r.Body).Decode(&user)
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusCreated)
+		} else {
+			id, _ := strconv.Atoi(strings.TrimPrefix(r.URL.Path, "/users/"))
+			user["name"] = "some long name"
+			user["email"] = "user@example.com"
+			user["id"] = id
+
+			w.Header().Set("Content-Type", "application/json")
+		}
+
+		user["id"] = 1
+		_ = json.NewEncoder(w).Encode(user)
+	})
+
+	for _, fixture := range fixtures {
+		t.Run(fixture.Name(), func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, fixture.NewRequest())
+
+			fixture.AssertResponse(t, rec)
+		})
+	}
+
+	// Responses not matching the spec are reported.
+	if err := fixtures[0].ValidateResponse(
+		http.StatusCreated,
+		http.Header{"Content-Type": []string{"application/json"}},
+		[]byte(`{"name": "short"}`),
+	); err == nil {
+		t.Fatal("expected invalid response to fail validation")
+	}
+
+	if err := fixtures[0].ValidateResponse(http.StatusTeapot, http.Header{}, nil); err == nil {
+		t.Fatal("expected undocumented status to fail validation")
+	}
+}
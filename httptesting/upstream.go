@@ -0,0 +1,195 @@
+package httptesting
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+)
+
+// Response is a canned response served by an Upstream.
+type Response struct {
+	// Status is the status written. Defaults to 200 OK.
+	Status int
+
+	// Header is added to the response headers.
+	Header http.Header
+
+	// Body is the response body.
+	Body string
+
+	// Latency is the time to wait before responding. The wait is aborted if
+	// the request is canceled.
+	Latency time.Duration
+
+	// CloseConnection closes the connection without writing a response, which
+	// makes the client fail with a network error.
+	CloseConnection bool
+
+	// Times is the number of consecutive calls the response is served for
+	// before moving on to the next response. Defaults to 1. The last response
+	// is served for all remaining calls.
+	Times int
+}
+
+// Upstream is a mock upstream server serving canned responses for declared
+// routes, e.g. to test retries, circuit breakers and proxies against
+// failure sequences. Keep-alives are disabled so clients don't transparently
+// retry requests, which would consume the next response. The embedded server is
+// closed when the test finishes and the test fails if any request didn't match
+// a route.
+type Upstream struct {
+	*httptest.Server
+
+	mux *http.ServeMux
+
+	mu        sync.Mutex
+	unmatched []string
+}
+
+// NewUpstream starts a mock upstream server, closed when the test finishes.
+//
+//	upstream := httptesting.NewUpstream(t)
+//	users := upstream.Handle("GET /users/{id}",
+//		httptesting.Response{Status: http.StatusServiceUnavailable, Times: 2},
+//		httptesting.Response{Body: `{"id":1}`},
+//	)
+//
+//	// Send requests to upstream.URL...
+//
+//	users.AssertCalls(t, 3)
+func NewUpstream(t testing.TB) *Upstream {
+	t.Helper()
+
+	u := &Upstream{mux: http.NewServeMux()}
+	u.Server = httptest.NewUnstartedServer(http.HandlerFunc(u.serveHTTP))
+
+	// Clients transparently retry idempotent requests once if a reused
+	// connection is closed, which would make CloseConnection serve the next
+	// response too.
+	u.Config.SetKeepAlivesEnabled(false)
+	u.Start()
+
+	t.Cleanup(func() {
+		u.Close()
+
+		u.mu.Lock()
+		defer u.mu.Unlock()
+
+		if len(u.unmatched) > 0 {
+			t.Errorf("unexpected requests to upstream: %v", u.unmatched)
+		}
+	})
+
+	return u
+}
+
+// Handle registers the responses for the pattern, using the same patterns as
+// http.ServeMux, e.g. "GET /users/{id}". The responses are served in order,
+// each for its number of Times, and the last one for all remaining calls.
+// Without responses 200 OK with an empty body is served.
+func (u *Upstream) Handle(pattern string, responses ...Response) *Route {
+	route := &Route{responses: responses}
+	u.mux.Handle(pattern, route)
+
+	return route
+}
+
+func (u *Upstream) serveHTTP(w http.ResponseWriter, r *http.Request) {
+	if _, pattern := u.mux.Handler(r); pattern == "" {
+		u.mu.Lock()
+		u.unmatched = append(u.unmatched, r.Method+" "+r.URL.RequestURI())
+		u.mu.Unlock()
+	}
+
+	u.mux.ServeHTTP(w, r)
+}
+
+// Route is a route registered with Handle.
+type Route struct {
+	responses []Response
+
+	mu    sync.Mutex
+	calls int
+}
+
+// Calls returns the number of requests served by the route.
+func (r *Route) Calls() int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return r.calls
+}
+
+// AssertCalls fails the test unless the route has served the expected number
+// of requests.
+func (r *Route) AssertCalls(t testing.TB, expected int) {
+	t.Helper()
+
+	if calls := r.Calls(); calls != expected {
+		t.Fatalf("unexpected number of calls, got: %d, expected: %d", calls, expected)
+	}
+}
+
+// ServeHTTP counts the call and serves the next response.
+func (r *Route) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	response := r.next()
+
+	if response.Latency > 0 {
+		timer := time.NewTimer(response.Latency)
+		defer timer.Stop()
+
+		select {
+		case <-timer.C:
+		case <-req.Context().Done():
+			return
+		}
+	}
+
+	if response.CloseConnection {
+		conn, _, err := http.NewResponseController(w).Hijack()
+		if err == nil {
+			_ = conn.Close()
+			return
+		}
+
+		panic(http.ErrAbortHandler)
+	}
+
+	for name, values := range response.Header {
+		for _, value := range values {
+			w.Header().Add(name, value)
+		}
+	}
+
+	if response.Status != 0 {
+		w.WriteHeader(response.Status)
+	}
+
+	_, _ = w.Write([]byte(response.Body))
+}
+
+// next counts the call and returns the response to serve for it.
+func (r *Route) next() Response {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.calls++
+
+	n := r.calls
+	for _, response := range r.responses {
+		times := max(response.Times, 1)
+		if n <= times {
+			return response
+		}
+
+		n -= times
+	}
+
+	if len(r.responses) == 0 {
+		return Response{}
+	}
+
+	return r.responses[len(r.responses)-1]
+}
@@ -0,0 +1,97 @@
+package httptesting
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"testing"
+	"time"
+)
+
+func Test_Upstream(t *testing.T) {
+	upstream := NewUpstream(t)
+
+	users := upstream.Handle("GET /users/{id}",
+		Response{Status: http.StatusServiceUnavailable, Times: 2},
+		Response{CloseConnection: true},
+		Response{Header: http.Header{"Content-Type": {"application/json"}}, Body: `{"id":1}`},
+	)
+
+	slow := upstream.Handle("GET /slow", Response{Latency: time.Minute})
+
+	get := func(ctx context.Context, path string) (*http.Response, string, error) {
+		t.Helper()
+
+		req, _ := http.NewRequestWithContext(ctx, http.MethodGet, upstream.URL+path, nil)
+
+		resp, err := upstream.Client().Do(req)
+		if err != nil {
+			return nil, "", err
+		}
+
+		defer resp.Body.Close()
+
+		body, _ := io.ReadAll(resp.Body)
+
+		return resp, string(body), nil
+	}
+
+	for range 2 {
+		if resp, _, err := get(context.Background(), "/users/1"); err != nil || resp.StatusCode != http.StatusServiceUnavailable {
+			t.Fatalf("expected 503, got: %v, %v", resp, err)
+		}
+	}
+
+	if _, _, err := get(context.Background(), "/users/1"); err == nil {
+		t.Fatal("expected the connection to be closed")
+	}
+
+	for range 2 {
+		resp, body, err := get(context.Background(), "/users/1")
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if resp.StatusCode != http.StatusOK || resp.Header.Get("Content-Type") != "application/json" || body != `{"id":1}` {
+			t.Fatalf("unexpected response: %d, %v, %s", resp.StatusCode, resp.Header, body)
+		}
+	}
+
+	users.AssertCalls(t, 5)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+
+	if _, _, err := get(ctx, "/slow"); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("expected deadline exceeded, got: %v", err)
+	}
+
+	slow.AssertCalls(t, 1)
+}
+
+func Test_UpstreamUnmatched(t *testing.T) {
+	upstream := NewUpstream(t)
+	upstream.Handle("GET /users", Response{})
+
+	resp, err := upstream.Client().Post(upstream.URL+"/users", "text/plain", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	resp.Body.Close()
+
+	if resp.StatusCode != http.StatusMethodNotAllowed {
+		t.Fatalf("unexpected status: %d", resp.StatusCode)
+	}
+
+	// Clear the unmatched request so the cleanup doesn't fail the test.
+	upstream.mu.Lock()
+	defer upstream.mu.Unlock()
+
+	if len(upstream.unmatched) != 1 || upstream.unmatched[0] != "POST /users" {
+		t.Fatalf("expected unmatched request, got: %v", upstream.unmatched)
+	}
+
+	upstream.unmatched = nil
+}
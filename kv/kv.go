@@ -0,0 +1,227 @@
+package kv
+
+/*
+A key-value store shared by the stateful features, the rate limiter, the nonce
+store of VerifySignature and the response caches, so one backend is configured
+for all of them. Stores are in-process, Redis or memcached.
+
+	store := kv.NewRedisStore(kv.RedisClientFunc(func(ctx context.Context, args ...any) (any, error) {
+		v, err := rdb.Do(ctx, args...).Result()
+		if errors.Is(err, redis.Nil) {
+			return nil, nil
+		}
+
+		return v, err
+	}))
+
+	limiters := middleware.NewKVLimiterStore(store, 100, time.Minute)
+	nonces := middleware.NewKVNonceStore(store)
+	responses := cache.NewKVStore(store)
+*/
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/bombsimon/http-helpers/clock"
+)
+
+// Store stores values by key. A TTL of 0 stores the value without expiring it.
+type Store interface {
+	// Get returns the value for the key and false if it doesn't exist.
+	Get(ctx context.Context, key string) ([]byte, bool, error)
+
+	// Set sets the value for the key.
+	Set(ctx context.Context, key string, value []byte, ttl time.Duration) error
+
+	// Delete deletes the key. Deleting a key that doesn't exist isn't an
+	// error.
+	Delete(ctx context.Context, key string) error
+
+	// Increment atomically adds delta to the integer value of the key and
+	// returns the new value. A key that doesn't exist is created with the
+	// TTL, the TTL of an existing key isn't changed.
+	Increment(ctx context.Context, key string, delta int64, ttl time.Duration) (int64, error)
+
+	// CompareAndSwap atomically sets the value for the key to value if the
+	// current value is old and returns true if it was set. A nil old value
+	// only sets the value if the key doesn't exist.
+	CompareAndSwap(ctx context.Context, key string, old, value []byte, ttl time.Duration) (bool, error)
+}
+
+// Option is an option used to configure a store.
+type Option func(*options)
+
+type options struct {
+	clock        clock.Clock
+	maxIdleConns int
+}
+
+func newOptions(opts ...Option) *options {
+	o := &options{
+		clock:        clock.Real(),
+		maxIdleConns: 8,
+	}
+
+	for _, opt := range opts {
+		opt(o)
+	}
+
+	return o
+}
+
+// WithClock sets the clock used by the in-process store to expire keys.
+// Defaults to clock.Real().
+func WithClock(c clock.Clock) Option {
+	return func(o *options) {
+		o.clock = c
+	}
+}
+
+// WithMaxIdleConns sets the number of connections kept open to memcached
+// between requests. Defaults to 8.
+func WithMaxIdleConns(n int) Option {
+	return func(o *options) {
+		o.maxIdleConns = n
+	}
+}
+
+// NewMemoryStore returns an in-process store. Expired keys are removed when
+// they're read and, at most once per minute, when keys are set.
+func NewMemoryStore(opts ...Option) Store {
+	return &memoryStore{
+		clock:  newOptions(opts...).clock,
+		values: make(map[string]memoryValue),
+	}
+}
+
+type memoryStore struct {
+	clock clock.Clock
+
+	mu        sync.Mutex
+	values    map[string]memoryValue
+	nextSweep time.Time
+}
+
+type memoryValue struct {
+	value   []byte
+	expires time.Time
+}
+
+func (s *memoryStore) Get(_ context.Context, key string) ([]byte, bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	v, ok := s.getLocked(key)
+
+	return v.value, ok, nil
+}
+
+func (s *memoryStore) Set(_ context.Context, key string, value []byte, ttl time.Duration) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.setLocked(key, memoryValue{value: slices.Clone(value)}, ttl)
+
+	return nil
+}
+
+func (s *memoryStore) Delete(_ context.Context, key string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.values, key)
+
+	return nil
+}
+
+func (s *memoryStore) Increment(_ context.Context, key string, delta int64, ttl time.Duration) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	v, ok := s.getLocked(key)
+	if !ok {
+		s.setLocked(key, memoryValue{value: formatInt(delta)}, ttl)
+		return delta, nil
+	}
+
+	counter, err := parseInt(v.value)
+	if err != nil {
+		return 0, err
+	}
+
+	v.value = formatInt(counter + delta)
+	s.values[key] = v
+
+	return counter + delta, nil
+}
+
+func (s *memoryStore) CompareAndSwap(_ context.Context, key string, old, value []byte, ttl time.Duration) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	v, ok := s.getLocked(key)
+
+	switch {
+	case old == nil && ok:
+		return false, nil
+	case old != nil && (!ok || string(v.value) != string(old)):
+		return false, nil
+	}
+
+	s.setLocked(key, memoryValue{value: slices.Clone(value)}, ttl)
+
+	return true, nil
+}
+
+// getLocked returns the value for the key, deleting it if it has expired.
+func (s *memoryStore) getLocked(key string) (memoryValue, bool) {
+	v, ok := s.values[key]
+	if !ok {
+		return memoryValue{}, false
+	}
+
+	if !v.expires.IsZero() && !s.clock.Now().Before(v.expires) {
+		delete(s.values, key)
+		return memoryValue{}, false
+	}
+
+	return v, true
+}
+
+func (s *memoryStore) setLocked(key string, v memoryValue, ttl time.Duration) {
+	now := s.clock.Now()
+
+	if ttl > 0 {
+		v.expires = now.Add(ttl)
+	}
+
+	if !now.Before(s.nextSweep) {
+		for k, existing := range s.values {
+			if !existing.expires.IsZero() && !now.Before(existing.expires) {
+				delete(s.values, k)
+			}
+		}
+
+		s.nextSweep = now.Add(time.Minute)
+	}
+
+	s.values[key] = v
+}
+
+func formatInt(n int64) []byte {
+	return strconv.AppendInt(nil, n, 10)
+}
+
+func parseInt(value []byte) (int64, error) {
+	n, err := strconv.ParseInt(string(value), 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("value is not an integer: %w", err)
+	}
+
+	return n, nil
+}
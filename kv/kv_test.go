@@ -0,0 +1,100 @@
+package kv
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/bombsimon/http-helpers/clock"
+)
+
+// testStore tests the store with keys expiring when the clock is advanced.
+func testStore(t *testing.T, store Store, clk *clock.Fake) {
+	t.Helper()
+
+	ctx := context.Background()
+
+	if _, found, err := store.Get(ctx, "missing"); err != nil || found {
+		t.Fatalf("expected missing key to not be found, got: %v, %v", found, err)
+	}
+
+	if err := store.Set(ctx, "key", []byte("value"), 2*time.Second); err != nil {
+		t.Fatal(err)
+	}
+
+	if value, found, err := store.Get(ctx, "key"); err != nil || !found || string(value) != "value" {
+		t.Fatalf("unexpected value, got: %q, %v, %v, expected: value", value, found, err)
+	}
+
+	for i, step := range []struct {
+		delta    int64
+		expected int64
+	}{
+		{delta: 1, expected: 1},
+		{delta: 5, expected: 6},
+		{delta: -2, expected: 4},
+	} {
+		n, err := store.Increment(ctx, "counter", step.delta, time.Second)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if n != step.expected {
+			t.Fatalf("unexpected counter for step %d, got: %d, expected: %d", i, n, step.expected)
+		}
+	}
+
+	for i, step := range []struct {
+		old      []byte
+		value    string
+		expected bool
+	}{
+		{old: nil, value: "first", expected: true},
+		{old: nil, value: "second", expected: false},
+		{old: []byte("other"), value: "second", expected: false},
+		{old: []byte("first"), value: "second", expected: true},
+	} {
+		swapped, err := store.CompareAndSwap(ctx, "cas", step.old, []byte(step.value), time.Minute)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if swapped != step.expected {
+			t.Fatalf("unexpected swap for step %d, got: %v, expected: %v", i, swapped, step.expected)
+		}
+	}
+
+	if value, _, _ := store.Get(ctx, "cas"); string(value) != "second" {
+		t.Fatalf("unexpected value after swap, got: %q, expected: second", value)
+	}
+
+	clk.Advance(time.Second)
+
+	if n, err := store.Increment(ctx, "counter", 1, time.Second); err != nil || n != 1 {
+		t.Fatalf("expected expired counter to be recreated, got: %d, %v", n, err)
+	}
+
+	clk.Advance(time.Second)
+
+	if _, found, _ := store.Get(ctx, "key"); found {
+		t.Fatal("expected key to expire")
+	}
+
+	if err := store.Delete(ctx, "cas"); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := store.Delete(ctx, "cas"); err != nil {
+		t.Fatalf("expected deleting a missing key to succeed, got: %v", err)
+	}
+
+	if _, found, _ := store.Get(ctx, "cas"); found {
+		t.Fatal("expected key to be deleted")
+	}
+}
+
+func Test_MemoryStore(t *testing.T) {
+	clk := clock.NewFake(time.Now())
+
+	testStore(t, NewMemoryStore(WithClock(clk)), clk)
+}
@@ -0,0 +1,381 @@
+package kv
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/bombsimon/http-helpers/clock"
+)
+
+// maxMemcachedTTL is the longest TTL memcached accepts as a duration, longer
+// TTLs are sent as a Unix timestamp.
+const maxMemcachedTTL = 30 * 24 * time.Hour
+
+// NewMemcachedStore returns a store keeping the values in the memcached server
+// at addr, speaking the text protocol. Keys longer than 250 bytes or with
+// spaces or control characters are hashed. Memcached counters are unsigned, so
+// decrementing below 0 with Increment sets the value to 0, and TTLs are
+// rounded up to whole seconds.
+func NewMemcachedStore(addr string, opts ...Option) Store {
+	options := newOptions(opts...)
+
+	return &memcachedStore{
+		addr:  addr,
+		clock: options.clock,
+		idle:  make(chan *memcachedConn, max(options.maxIdleConns, 0)),
+	}
+}
+
+type memcachedStore struct {
+	addr  string
+	clock clock.Clock
+	idle  chan *memcachedConn
+}
+
+type memcachedConn struct {
+	net.Conn
+	rw *bufio.ReadWriter
+}
+
+func (s *memcachedStore) Get(ctx context.Context, key string) ([]byte, bool, error) {
+	var (
+		value []byte
+		found bool
+	)
+
+	err := s.do(ctx, func(c *memcachedConn) error {
+		var err error
+
+		value, _, found, err = c.get("get", memcachedKey(key))
+
+		return err
+	})
+
+	return value, found, err
+}
+
+func (s *memcachedStore) Set(ctx context.Context, key string, value []byte, ttl time.Duration) error {
+	return s.do(ctx, func(c *memcachedConn) error {
+		reply, err := c.store("set", memcachedKey(key), value, s.exptime(ttl), "")
+		if err != nil {
+			return err
+		}
+
+		return expectReply(reply, "STORED")
+	})
+}
+
+func (s *memcachedStore) Delete(ctx context.Context, key string) error {
+	return s.do(ctx, func(c *memcachedConn) error {
+		reply, err := c.command("delete " + memcachedKey(key))
+		if err != nil {
+			return err
+		}
+
+		if reply == "NOT_FOUND" {
+			return nil
+		}
+
+		return expectReply(reply, "DELETED")
+	})
+}
+
+func (s *memcachedStore) Increment(ctx context.Context, key string, delta int64, ttl time.Duration) (int64, error) {
+	var value int64
+
+	err := s.do(ctx, func(c *memcachedConn) error {
+		command := "incr"
+		if delta < 0 {
+			command = "decr"
+		}
+
+		// A key that doesn't exist is added, if another client adds it
+		// first it's incremented again.
+		for {
+			reply, err := c.command(fmt.Sprintf("%s %s %d", command, memcachedKey(key), abs(delta)))
+			if err != nil {
+				return err
+			}
+
+			if reply != "NOT_FOUND" {
+				if err := replyError(reply); err != nil {
+					return err
+				}
+
+				value, err = strconv.ParseInt(reply, 10, 64)
+
+				return err
+			}
+
+			value = max(delta, 0)
+
+			reply, err = c.store("add", memcachedKey(key), formatInt(value), s.exptime(ttl), "")
+			if err != nil {
+				return err
+			}
+
+			if reply != "NOT_STORED" {
+				return expectReply(reply, "STORED")
+			}
+		}
+	})
+
+	return value, err
+}
+
+func (s *memcachedStore) CompareAndSwap(ctx context.Context, key string, old, value []byte, ttl time.Duration) (bool, error) {
+	var swapped bool
+
+	err := s.do(ctx, func(c *memcachedConn) error {
+		key := memcachedKey(key)
+
+		if old == nil {
+			reply, err := c.store("add", key, value, s.exptime(ttl), "")
+			if err != nil {
+				return err
+			}
+
+			swapped = reply == "STORED"
+
+			return replyError(reply)
+		}
+
+		current, casUnique, found, err := c.get("gets", key)
+		if err != nil || !found || !bytes.Equal(current, old) {
+			return err
+		}
+
+		reply, err := c.store("cas", key, value, s.exptime(ttl), casUnique)
+		if err != nil {
+			return err
+		}
+
+		swapped = reply == "STORED"
+
+		return replyError(reply)
+	})
+
+	return swapped, err
+}
+
+// do runs fn with an idle connection, or a new one if there's none. The
+// connection is closed instead of reused if fn fails, since it may be in the
+// middle of a reply.
+func (s *memcachedStore) do(ctx context.Context, fn func(c *memcachedConn) error) error {
+	var c *memcachedConn
+
+	select {
+	case c = <-s.idle:
+	default:
+		conn, err := (&net.Dialer{}).DialContext(ctx, "tcp", s.addr)
+		if err != nil {
+			return err
+		}
+
+		c = &memcachedConn{
+			Conn: conn,
+			rw:   bufio.NewReadWriter(bufio.NewReader(conn), bufio.NewWriter(conn)),
+		}
+	}
+
+	deadline, _ := ctx.Deadline()
+	if err := c.SetDeadline(deadline); err != nil {
+		c.Close()
+		return err
+	}
+
+	err := fn(c)
+
+	var replyErr *MemcachedError
+	if err != nil && !errors.As(err, &replyErr) {
+		c.Close()
+		return err
+	}
+
+	s.release(c)
+
+	return err
+}
+
+// release returns the connection to the idle connections, or closes it if
+// there are enough idle connections.
+func (s *memcachedStore) release(c *memcachedConn) {
+	select {
+	case s.idle <- c:
+	default:
+		c.Close()
+	}
+}
+
+// exptime returns the TTL as a memcached expiration time.
+func (s *memcachedStore) exptime(ttl time.Duration) int64 {
+	switch {
+	case ttl <= 0:
+		return 0
+	case ttl > maxMemcachedTTL:
+		return s.clock.Now().Add(ttl).Unix()
+	default:
+		return int64((ttl + time.Second - 1) / time.Second)
+	}
+}
+
+// MemcachedError is an error reply from memcached.
+type MemcachedError struct {
+	Reply string
+}
+
+func (e *MemcachedError) Error() string {
+	return "memcached: " + e.Reply
+}
+
+// command sends the command and returns the reply line.
+func (c *memcachedConn) command(command string) (string, error) {
+	if _, err := c.rw.WriteString(command + "\r\n"); err != nil {
+		return "", err
+	}
+
+	if err := c.rw.Flush(); err != nil {
+		return "", err
+	}
+
+	return c.readLine()
+}
+
+// store sends a storage command, with the CAS unique value for cas, and
+// returns the reply line.
+func (c *memcachedConn) store(command, key string, value []byte, exptime int64, casUnique string) (string, error) {
+	line := fmt.Sprintf("%s %s 0 %d %d", command, key, exptime, len(value))
+	if casUnique != "" {
+		line += " " + casUnique
+	}
+
+	if _, err := c.rw.WriteString(line + "\r\n"); err != nil {
+		return "", err
+	}
+
+	if _, err := c.rw.Write(value); err != nil {
+		return "", err
+	}
+
+	if _, err := c.rw.WriteString("\r\n"); err != nil {
+		return "", err
+	}
+
+	if err := c.rw.Flush(); err != nil {
+		return "", err
+	}
+
+	return c.readLine()
+}
+
+// get sends get or gets for the key and returns the value and, for gets, the
+// CAS unique value.
+func (c *memcachedConn) get(command, key string) ([]byte, string, bool, error) {
+	reply, err := c.command(command + " " + key)
+	if err != nil {
+		return nil, "", false, err
+	}
+
+	if reply == "END" {
+		return nil, "", false, nil
+	}
+
+	if err := replyError(reply); err != nil {
+		return nil, "", false, err
+	}
+
+	// VALUE <key> <flags> <bytes> [<cas unique>]
+	fields := strings.Fields(reply)
+	if len(fields) < 4 || fields[0] != "VALUE" {
+		return nil, "", false, fmt.Errorf("unexpected reply to %s: %q", command, reply)
+	}
+
+	size, err := strconv.Atoi(fields[3])
+	if err != nil {
+		return nil, "", false, fmt.Errorf("unexpected reply to %s: %q", command, reply)
+	}
+
+	var casUnique string
+	if len(fields) > 4 {
+		casUnique = fields[4]
+	}
+
+	value := make([]byte, size+2)
+	if _, err := io.ReadFull(c.rw, value); err != nil {
+		return nil, "", false, err
+	}
+
+	end, err := c.readLine()
+	if err != nil {
+		return nil, "", false, err
+	}
+
+	if end != "END" {
+		return nil, "", false, fmt.Errorf("unexpected reply to %s: %q", command, end)
+	}
+
+	return value[:size], casUnique, true, nil
+}
+
+func (c *memcachedConn) readLine() (string, error) {
+	line, err := c.rw.ReadString('\n')
+	if err != nil {
+		return "", err
+	}
+
+	return strings.TrimSuffix(line, "\r\n"), nil
+}
+
+// replyError returns the reply as a MemcachedError if it's an error reply.
+func replyError(reply string) error {
+	if reply == "ERROR" || strings.HasPrefix(reply, "CLIENT_ERROR") || strings.HasPrefix(reply, "SERVER_ERROR") {
+		return &MemcachedError{Reply: reply}
+	}
+
+	return nil
+}
+
+func expectReply(reply, expected string) error {
+	if err := replyError(reply); err != nil {
+		return err
+	}
+
+	if reply != expected {
+		return fmt.Errorf("unexpected reply from memcached: %q", reply)
+	}
+
+	return nil
+}
+
+// memcachedKey returns the key, hashed if it's not a valid memcached key.
+func memcachedKey(key string) string {
+	valid := len(key) > 0 && len(key) <= 250 && strings.IndexFunc(key, func(r rune) bool {
+		return r <= ' ' || r == 0x7f
+	}) < 0
+
+	if valid {
+		return key
+	}
+
+	sum := sha256.Sum256([]byte(key))
+
+	return "sha256:" + hex.EncodeToString(sum[:])
+}
+
+func abs(n int64) int64 {
+	if n < 0 {
+		return -n
+	}
+
+	return n
+}
@@ -0,0 +1,170 @@
+package kv
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/bombsimon/http-helpers/clock"
+)
+
+// fakeMemcached serves the commands used by the store from a map, expiring
+// items with a fake clock.
+type fakeMemcached struct {
+	clock *clock.Fake
+
+	mu      sync.Mutex
+	items   map[string]fakeItem
+	nextCAS int
+}
+
+type fakeItem struct {
+	value   string
+	cas     int
+	expires time.Time
+}
+
+func (m *fakeMemcached) serve(t *testing.T, listener net.Listener) {
+	for {
+		conn, err := listener.Accept()
+		if err != nil {
+			return
+		}
+
+		go func() {
+			defer conn.Close()
+
+			r := bufio.NewReader(conn)
+
+			for {
+				line, err := r.ReadString('\n')
+				if err != nil {
+					return
+				}
+
+				fields := strings.Fields(line)
+
+				var value string
+				if len(fields) >= 5 {
+					size, _ := strconv.Atoi(fields[4])
+					data := make([]byte, size+2)
+
+					if _, err := io.ReadFull(r, data); err != nil {
+						t.Error(err)
+						return
+					}
+
+					value = string(data[:size])
+				}
+
+				_, _ = io.WriteString(conn, m.handle(fields, value)+"\r\n")
+			}
+		}()
+	}
+}
+
+func (m *fakeMemcached) handle(fields []string, value string) string {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	key := fields[1]
+
+	item, found := m.items[key]
+	if found && !item.expires.IsZero() && !m.clock.Now().Before(item.expires) {
+		delete(m.items, key)
+		found = false
+	}
+
+	store := func() string {
+		m.nextCAS++
+
+		item := fakeItem{value: value, cas: m.nextCAS}
+		if exptime, _ := strconv.Atoi(fields[3]); exptime > 0 {
+			item.expires = m.clock.Now().Add(time.Duration(exptime) * time.Second)
+		}
+
+		m.items[key] = item
+
+		return "STORED"
+	}
+
+	switch fields[0] {
+	case "get", "gets":
+		if !found {
+			return "END"
+		}
+
+		return fmt.Sprintf("VALUE %s 0 %d %d\r\n%s\r\nEND", key, len(item.value), item.cas, item.value)
+	case "set":
+		return store()
+	case "add":
+		if found {
+			return "NOT_STORED"
+		}
+
+		return store()
+	case "cas":
+		switch {
+		case !found:
+			return "NOT_FOUND"
+		case fields[5] != strconv.Itoa(item.cas):
+			return "EXISTS"
+		}
+
+		return store()
+	case "delete":
+		if !found {
+			return "NOT_FOUND"
+		}
+
+		delete(m.items, key)
+
+		return "DELETED"
+	case "incr", "decr":
+		if !found {
+			return "NOT_FOUND"
+		}
+
+		n, _ := strconv.ParseInt(item.value, 10, 64)
+		delta, _ := strconv.ParseInt(fields[2], 10, 64)
+
+		if fields[0] == "decr" {
+			delta = -delta
+		}
+
+		item.value = strconv.FormatInt(max(n+delta, 0), 10)
+		m.items[key] = item
+
+		return item.value
+	}
+
+	return "ERROR"
+}
+
+func Test_MemcachedStore(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer listener.Close()
+
+	server := &fakeMemcached{
+		clock: clock.NewFake(time.Now()),
+		items: make(map[string]fakeItem),
+	}
+
+	go server.serve(t, listener)
+
+	testStore(t, NewMemcachedStore(listener.Addr().String(), WithClock(server.clock)), server.clock)
+
+	if key := memcachedKey("with space"); key == "with space" || len(key) > 250 {
+		t.Fatalf("expected invalid key to be hashed, got: %q", key)
+	}
+}
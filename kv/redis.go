@@ -0,0 +1,146 @@
+package kv
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// RedisClient sends a command to Redis and returns the reply. Bulk strings are
+// returned as string or []byte, integers as int64 and a nil reply as nil
+// without an error. It's implemented with any Redis client, e.g. go-redis or
+// redigo, so authentication, TLS and clustering are configured the same way as
+// for the rest of the service.
+type RedisClient interface {
+	Do(ctx context.Context, args ...any) (any, error)
+}
+
+// RedisClientFunc is a function implementing RedisClient.
+type RedisClientFunc func(ctx context.Context, args ...any) (any, error)
+
+// Do implements RedisClient.
+func (f RedisClientFunc) Do(ctx context.Context, args ...any) (any, error) {
+	return f(ctx, args...)
+}
+
+// incrementScript increments the key and sets the TTL if the key has none,
+// i.e. if it was created by the increment.
+const incrementScript = `
+local value = redis.call('INCRBY', KEYS[1], ARGV[1])
+if tonumber(ARGV[2]) > 0 and redis.call('PTTL', KEYS[1]) == -1 then
+	redis.call('PEXPIRE', KEYS[1], ARGV[2])
+end
+return value
+`
+
+// compareAndSwapScript sets the key if the current value is ARGV[2], or if the
+// key doesn't exist when ARGV[1] is 0.
+const compareAndSwapScript = `
+local current = redis.call('GET', KEYS[1])
+if ARGV[1] == '1' then
+	if current ~= ARGV[2] then
+		return 0
+	end
+elseif current then
+	return 0
+end
+if tonumber(ARGV[4]) > 0 then
+	redis.call('SET', KEYS[1], ARGV[3], 'PX', ARGV[4])
+else
+	redis.call('SET', KEYS[1], ARGV[3])
+end
+return 1
+`
+
+// NewRedisStore returns a store keeping the values in Redis. Increment and
+// CompareAndSwap are atomic Lua scripts sent with EVAL.
+func NewRedisStore(client RedisClient) Store {
+	return &redisStore{client: client}
+}
+
+type redisStore struct {
+	client RedisClient
+}
+
+func (s *redisStore) Get(ctx context.Context, key string) ([]byte, bool, error) {
+	reply, err := s.client.Do(ctx, "GET", key)
+	if err != nil {
+		return nil, false, err
+	}
+
+	switch v := reply.(type) {
+	case nil:
+		return nil, false, nil
+	case string:
+		return []byte(v), true, nil
+	case []byte:
+		return v, true, nil
+	default:
+		return nil, false, fmt.Errorf("unexpected reply to GET: %T", reply)
+	}
+}
+
+func (s *redisStore) Set(ctx context.Context, key string, value []byte, ttl time.Duration) error {
+	args := []any{"SET", key, value}
+	if ttl > 0 {
+		args = append(args, "PX", redisMilliseconds(ttl))
+	}
+
+	_, err := s.client.Do(ctx, args...)
+
+	return err
+}
+
+func (s *redisStore) Delete(ctx context.Context, key string) error {
+	_, err := s.client.Do(ctx, "DEL", key)
+
+	return err
+}
+
+func (s *redisStore) Increment(ctx context.Context, key string, delta int64, ttl time.Duration) (int64, error) {
+	reply, err := s.client.Do(ctx, "EVAL", incrementScript, 1, key, delta, redisMilliseconds(ttl))
+	if err != nil {
+		return 0, err
+	}
+
+	return redisInt(reply)
+}
+
+func (s *redisStore) CompareAndSwap(ctx context.Context, key string, old, value []byte, ttl time.Duration) (bool, error) {
+	exists := 1
+	if old == nil {
+		exists = 0
+	}
+
+	reply, err := s.client.Do(
+		ctx, "EVAL", compareAndSwapScript, 1, key, exists, old, value, redisMilliseconds(ttl),
+	)
+	if err != nil {
+		return false, err
+	}
+
+	swapped, err := redisInt(reply)
+
+	return swapped == 1, err
+}
+
+// redisMilliseconds returns the TTL in milliseconds, rounding positive TTLs
+// below a millisecond up so they're not stored without expiring.
+func redisMilliseconds(ttl time.Duration) int64 {
+	if ttl <= 0 {
+		return 0
+	}
+
+	return max(ttl.Milliseconds(), 1)
+}
+
+func redisInt(reply any) (int64, error) {
+	switch v := reply.(type) {
+	case int64:
+		return v, nil
+	case int:
+		return int64(v), nil
+	default:
+		return 0, fmt.Errorf("unexpected integer reply: %T", reply)
+	}
+}
@@ -0,0 +1,66 @@
+package kv
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/bombsimon/http-helpers/clock"
+)
+
+func Test_RedisStore(t *testing.T) {
+	var (
+		clk     = clock.NewFake(time.Now())
+		backend = NewMemoryStore(WithClock(clk))
+	)
+
+	// The fake runs the commands against the in-process store, checking the
+	// arguments and replies but not the scripts themselves.
+	client := RedisClientFunc(func(ctx context.Context, args ...any) (any, error) {
+		key := args[1].(string)
+
+		switch args[0] {
+		case "GET":
+			value, found, err := backend.Get(ctx, key)
+			if !found {
+				return nil, err
+			}
+
+			return string(value), err
+		case "SET":
+			var ttl time.Duration
+			if len(args) == 5 && args[3] == "PX" {
+				ttl = time.Duration(args[4].(int64)) * time.Millisecond
+			}
+
+			return "OK", backend.Set(ctx, key, args[2].([]byte), ttl)
+		case "DEL":
+			return int64(1), backend.Delete(ctx, key)
+		case "EVAL":
+			key = args[3].(string)
+			ttl := time.Duration(args[len(args)-1].(int64)) * time.Millisecond
+
+			switch args[1] {
+			case incrementScript:
+				return backend.Increment(ctx, key, args[4].(int64), ttl)
+			case compareAndSwapScript:
+				var old []byte
+				if args[4] == 1 {
+					old = args[5].([]byte)
+				}
+
+				swapped, err := backend.CompareAndSwap(ctx, key, old, args[6].([]byte), ttl)
+				if swapped {
+					return int64(1), err
+				}
+
+				return int64(0), err
+			}
+		}
+
+		return nil, fmt.Errorf("unexpected command: %v", args)
+	})
+
+	testStore(t, NewRedisStore(client), clk)
+}
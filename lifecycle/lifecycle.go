@@ -0,0 +1,249 @@
+package lifecycle
+
+/*
+Background tasks, such as queue consumers and tickers, started with the server
+and stopped during the graceful shutdown, instead of ad-hoc WaitGroups in every
+service.
+
+	tasks := lifecycle.New()
+	tasks.Go("consumer", 10*time.Second, consumer.Run)
+	tasks.Add("scheduler", 5*time.Second, scheduler.Start, scheduler.Stop)
+
+	err := server.Run(ctx, srv, tasks.ServerOptions()...)
+*/
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/bombsimon/http-helpers/server"
+)
+
+// TaskError is returned by Start and Stop for each task failing to start or
+// stop.
+type TaskError struct {
+	Task string
+	Err  error
+}
+
+func (e *TaskError) Error() string {
+	return fmt.Sprintf("task %s failed: %s", e.Task, e.Err)
+}
+
+func (e *TaskError) Unwrap() error {
+	return e.Err
+}
+
+// Option is an option used to configure the manager.
+type Option func(*options)
+
+type options struct {
+	logger *slog.Logger
+}
+
+func newOptions(opts ...Option) *options {
+	o := &options{
+		logger: slog.Default(),
+	}
+
+	for _, opt := range opts {
+		opt(o)
+	}
+
+	return o
+}
+
+// WithLogger sets the logger used to log tasks failing to start or stopping
+// unexpectedly. Defaults to slog.Default().
+func WithLogger(logger *slog.Logger) Option {
+	return func(o *options) {
+		o.logger = logger
+	}
+}
+
+// Manager starts background tasks in the order they're added and stops them
+// in the reverse order, each with its own timeout.
+type Manager struct {
+	options *options
+
+	mu    sync.Mutex
+	tasks []*task
+}
+
+type task struct {
+	name    string
+	timeout time.Duration
+	start   func(ctx context.Context) error
+	stop    func(ctx context.Context) error
+	started bool
+}
+
+// New creates a manager without tasks.
+func New(opts ...Option) *Manager {
+	return &Manager{options: newOptions(opts...)}
+}
+
+// Add adds a task started with start and stopped with stop. The context passed
+// to stop is done when the timeout has passed.
+func (m *Manager) Add(name string, timeout time.Duration, start, stop func(ctx context.Context) error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.tasks = append(m.tasks, &task{
+		name:    name,
+		timeout: timeout,
+		start:   start,
+		stop:    stop,
+	})
+}
+
+// Go adds a task running run in a goroutine until the context is canceled when
+// the task is stopped, waiting up to the timeout for it to return. Errors
+// returned while running are logged and returned when the task is stopped.
+func (m *Manager) Go(name string, timeout time.Duration, run func(ctx context.Context) error) {
+	var (
+		cancel context.CancelFunc
+		done   chan error
+	)
+
+	start := func(ctx context.Context) error {
+		ctx, cancel = context.WithCancel(ctx)
+		done = make(chan error, 1)
+
+		go func() {
+			err := run(ctx)
+			if err != nil && ctx.Err() == nil {
+				m.options.logger.Error("task stopped unexpectedly", "task", name, "error", err)
+			}
+
+			done <- err
+		}()
+
+		return nil
+	}
+
+	stop := func(ctx context.Context) error {
+		cancel()
+
+		select {
+		case err := <-done:
+			if errors.Is(err, context.Canceled) {
+				return nil
+			}
+
+			return err
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+
+	m.Add(name, timeout, start, stop)
+}
+
+// Start starts the tasks not already started, in the order they were added.
+// Tasks started with Go run until the context is canceled or they're stopped.
+// If a task fails to start, the started tasks are stopped and the errors are
+// returned as TaskError.
+func (m *Manager) Start(ctx context.Context) error {
+	m.mu.Lock()
+	tasks := slices.Clone(m.tasks)
+	m.mu.Unlock()
+
+	for _, t := range tasks {
+		if m.started(t) {
+			continue
+		}
+
+		if err := t.start(ctx); err != nil {
+			startErr := &TaskError{Task: t.name, Err: err}
+
+			return errors.Join(startErr, m.Stop(context.WithoutCancel(ctx)))
+		}
+
+		m.mu.Lock()
+		t.started = true
+		m.mu.Unlock()
+	}
+
+	return nil
+}
+
+// Stop stops the started tasks in the reverse order they were added, each
+// with its own timeout. A task failing to stop doesn't stop the remaining
+// tasks from being stopped. The errors are returned as TaskError.
+func (m *Manager) Stop(ctx context.Context) error {
+	m.mu.Lock()
+	tasks := slices.Clone(m.tasks)
+	m.mu.Unlock()
+
+	var errs []error
+
+	for i := len(tasks) - 1; i >= 0; i-- {
+		t := tasks[i]
+
+		if err := m.stopTask(ctx, t); err != nil {
+			errs = append(errs, &TaskError{Task: t.name, Err: err})
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+// ServerOptions returns options for server.Run and server.RunTLS starting the
+// tasks when the server is ready and stopping them, as shutdown hooks with the
+// task timeouts, when the connections are drained. Tasks failing to start are
+// logged, call Start before Run to fail the startup instead. Tasks must be
+// added before calling ServerOptions.
+func (m *Manager) ServerOptions() []server.Option {
+	m.mu.Lock()
+	tasks := slices.Clone(m.tasks)
+	m.mu.Unlock()
+
+	opts := []server.Option{
+		server.OnReady(func(net.Addr) {
+			if err := m.Start(context.Background()); err != nil {
+				m.options.logger.Error("could not start tasks", "error", err)
+			}
+		}),
+	}
+
+	for i := len(tasks) - 1; i >= 0; i-- {
+		t := tasks[i]
+
+		opts = append(opts, server.OnDrainComplete(t.name, t.timeout, func(ctx context.Context) error {
+			return m.stopTask(ctx, t)
+		}))
+	}
+
+	return opts
+}
+
+func (m *Manager) started(t *task) bool {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return t.started
+}
+
+// stopTask stops the task with its timeout if it's started.
+func (m *Manager) stopTask(ctx context.Context, t *task) error {
+	m.mu.Lock()
+	started := t.started
+	t.started = false
+	m.mu.Unlock()
+
+	if !started {
+		return nil
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, t.timeout)
+	defer cancel()
+
+	return t.stop(ctx)
+}
@@ -0,0 +1,138 @@
+package lifecycle
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"net"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/bombsimon/http-helpers/server"
+)
+
+func Test_Manager(t *testing.T) {
+	var (
+		events  []string
+		tasks   = New(WithLogger(slog.New(slog.NewTextHandler(io.Discard, nil))))
+		stopped = make(chan struct{})
+	)
+
+	record := func(event string) func(context.Context) error {
+		return func(context.Context) error {
+			events = append(events, event)
+			return nil
+		}
+	}
+
+	tasks.Add("db", time.Second, record("start db"), record("stop db"))
+
+	tasks.Go("consumer", time.Second, func(ctx context.Context) error {
+		<-ctx.Done()
+		close(stopped)
+
+		return ctx.Err()
+	})
+
+	tasks.Add("scheduler", time.Second, record("start scheduler"), record("stop scheduler"))
+
+	tasks.Go("stuck", 10*time.Millisecond, func(ctx context.Context) error {
+		select {}
+	})
+
+	if err := tasks.Start(context.Background()); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	err := tasks.Stop(context.Background())
+
+	var taskErr *TaskError
+	if !errors.As(err, &taskErr) || taskErr.Task != "stuck" || !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("expected stuck task to time out, got: %v", err)
+	}
+
+	select {
+	case <-stopped:
+	default:
+		t.Fatal("consumer not stopped")
+	}
+
+	expected := []string{"start db", "start scheduler", "stop scheduler", "stop db"}
+	if strings.Join(events, ",") != strings.Join(expected, ",") {
+		t.Fatalf("unexpected order, got: %v, expected: %v", events, expected)
+	}
+}
+
+func Test_ManagerStartFailure(t *testing.T) {
+	var stopped []string
+
+	tasks := New()
+	noop := func(context.Context) error { return nil }
+
+	tasks.Add("first", time.Second, noop, func(context.Context) error {
+		stopped = append(stopped, "first")
+		return nil
+	})
+	tasks.Add("failing", time.Second, func(context.Context) error {
+		return errors.New("connection refused")
+	}, func(context.Context) error {
+		stopped = append(stopped, "failing")
+		return nil
+	})
+
+	err := tasks.Start(context.Background())
+
+	var taskErr *TaskError
+	if !errors.As(err, &taskErr) || taskErr.Task != "failing" {
+		t.Fatalf("expected failing task error, got: %v", err)
+	}
+
+	if strings.Join(stopped, ",") != "first" {
+		t.Fatalf("expected only started tasks to be stopped, got: %v", stopped)
+	}
+}
+
+func Test_ManagerServerOptions(t *testing.T) {
+	var (
+		running = make(chan struct{})
+		events  []string
+		tasks   = New()
+	)
+
+	tasks.Go("consumer", time.Second, func(ctx context.Context) error {
+		close(running)
+		<-ctx.Done()
+
+		events = append(events, "consumer stopped")
+
+		return nil
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+
+	opts := append(
+		tasks.ServerOptions(),
+		server.WithLogger(nil),
+		server.WithSignals(),
+		server.OnReady(func(net.Addr) {
+			<-running
+			cancel()
+		}),
+		server.OnDrainComplete("after tasks", time.Second, func(context.Context) error {
+			events = append(events, "drained")
+			return nil
+		}),
+	)
+
+	if err := server.Run(ctx, &http.Server{Addr: "127.0.0.1:0"}, opts...); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	expected := []string{"consumer stopped", "drained"}
+	if strings.Join(events, ",") != strings.Join(expected, ",") {
+		t.Fatalf("unexpected order, got: %v, expected: %v", events, expected)
+	}
+}
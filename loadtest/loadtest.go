@@ -0,0 +1,293 @@
+package loadtest
+
+/*
+Deterministic load generation for capacity testing, e.g. to validate rate limit
+and load shedding settings in CI or staging. Requests are sent open-loop: they
+are started at fixed points in time regardless of how long previous requests
+take, so a slow server results in more concurrent requests instead of a lower
+rate.
+
+	summary, err := loadtest.Run(
+		ctx,
+		loadtest.Get("https://staging.example.com/api/users"),
+		loadtest.WithStages(
+			loadtest.Stage{Rate: 100, Ramp: 10 * time.Second, Duration: time.Minute},
+			loadtest.Stage{Rate: 500, Ramp: 30 * time.Second, Duration: time.Minute},
+		),
+	)
+
+	fmt.Println(summary)
+*/
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Target creates the request to send. It's called once per request.
+type Target func(ctx context.Context) (*http.Request, error)
+
+// Get returns a Target sending GET requests to url.
+func Get(url string) Target {
+	return func(ctx context.Context) (*http.Request, error) {
+		return http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	}
+}
+
+// Stage is a period of the load test. The rate changes linearly from the rate
+// of the previous stage (zero for the first stage) to Rate during Ramp and is
+// then kept at Rate for Duration.
+type Stage struct {
+	// Rate is the number of requests per second.
+	Rate float64
+
+	// Ramp is the time to change to Rate.
+	Ramp time.Duration
+
+	// Duration is the time to keep the rate after the ramp.
+	Duration time.Duration
+}
+
+// Option is an option used to configure the load test.
+type Option func(*options)
+
+type options struct {
+	client      *http.Client
+	stages      []Stage
+	maxInFlight int
+}
+
+// WithClient sets the client used to send requests, e.g. a client with the
+// same transport and middlewares as the service under test. Defaults to
+// http.DefaultClient.
+func WithClient(client *http.Client) Option {
+	return func(o *options) {
+		o.client = client
+	}
+}
+
+// WithRate sends requests at a constant rate for the duration.
+func WithRate(rate float64, duration time.Duration) Option {
+	return WithStages(Stage{Rate: rate, Duration: duration})
+}
+
+// WithStages sets the stages of the load test.
+func WithStages(stages ...Stage) Option {
+	return func(o *options) {
+		o.stages = stages
+	}
+}
+
+// WithMaxInFlight limits the number of concurrent requests. Requests scheduled
+// when the limit is reached are dropped and counted in the summary. Defaults
+// to 1000, 0 or less means no limit.
+func WithMaxInFlight(n int) Option {
+	return func(o *options) {
+		o.maxInFlight = n
+	}
+}
+
+// Summary is the result of a load test.
+type Summary struct {
+	// Requests is the number of requests sent.
+	Requests int
+
+	// Dropped is the number of requests not sent because the max number of
+	// requests in flight was reached.
+	Dropped int
+
+	// Errors is the number of requests that failed without a response.
+	Errors int
+
+	// StatusCodes is the number of responses per status code.
+	StatusCodes map[int]int
+
+	// Duration is the time from the first request until the last response.
+	Duration time.Duration
+
+	// Latency is the latency distribution of requests with a response.
+	Latency Latency
+}
+
+// Latency describes a latency distribution.
+type Latency struct {
+	Mean time.Duration
+	P50  time.Duration
+	P90  time.Duration
+	P99  time.Duration
+	Max  time.Duration
+}
+
+// Rate returns the achieved number of requests per second.
+func (s *Summary) Rate() float64 {
+	if s.Duration <= 0 {
+		return 0
+	}
+
+	return float64(s.Requests) / s.Duration.Seconds()
+}
+
+// String returns a human readable summary.
+func (s *Summary) String() string {
+	codes := make([]int, 0, len(s.StatusCodes))
+	for code := range s.StatusCodes {
+		codes = append(codes, code)
+	}
+
+	sort.Ints(codes)
+
+	statuses := make([]string, 0, len(codes))
+	for _, code := range codes {
+		statuses = append(statuses, fmt.Sprintf("%d: %d", code, s.StatusCodes[code]))
+	}
+
+	return fmt.Sprintf(
+		"requests: %d (%.1f/s), dropped: %d, errors: %d, status codes: [%s], latency: mean %s, p50 %s, p90 %s, p99 %s, max %s",
+		s.Requests, s.Rate(), s.Dropped, s.Errors, strings.Join(statuses, ", "),
+		s.Latency.Mean, s.Latency.P50, s.Latency.P90, s.Latency.P99, s.Latency.Max,
+	)
+}
+
+// Run runs the load test and returns the summary when all requests are done.
+// If the context is cancelled no more requests are started but the summary
+// for the requests sent so far is returned together with the context error.
+func Run(ctx context.Context, target Target, opts ...Option) (*Summary, error) {
+	options := &options{
+		client:      http.DefaultClient,
+		maxInFlight: 1000,
+	}
+
+	for _, opt := range opts {
+		opt(options)
+	}
+
+	var (
+		mu        sync.Mutex
+		wg        sync.WaitGroup
+		latencies []time.Duration
+		inFlight  chan struct{}
+		summary   = &Summary{StatusCodes: map[int]int{}}
+		start     = time.Now()
+		timer     = time.NewTimer(0)
+		err       error
+	)
+
+	defer timer.Stop()
+
+	<-timer.C
+
+	// A nil channel means no limit.
+	if options.maxInFlight > 0 {
+		inFlight = make(chan struct{}, options.maxInFlight)
+	}
+
+schedule:
+	for _, at := range Schedule(options.stages...) {
+		timer.Reset(time.Until(start.Add(at)))
+
+		select {
+		case <-ctx.Done():
+			err = ctx.Err()
+			break schedule
+		case <-timer.C:
+		}
+
+		if inFlight != nil {
+			select {
+			case inFlight <- struct{}{}:
+			default:
+				summary.Dropped++
+				continue
+			}
+		}
+
+		summary.Requests++
+
+		wg.Add(1)
+
+		go func() {
+			defer func() {
+				if inFlight != nil {
+					<-inFlight
+				}
+
+				wg.Done()
+			}()
+
+			latency, status, reqErr := send(ctx, options.client, target)
+
+			mu.Lock()
+			defer mu.Unlock()
+
+			if reqErr != nil {
+				summary.Errors++
+				return
+			}
+
+			summary.StatusCodes[status]++
+			latencies = append(latencies, latency)
+		}()
+	}
+
+	wg.Wait()
+
+	summary.Duration = time.Since(start)
+	summary.Latency = latencyDistribution(latencies)
+
+	return summary, err
+}
+
+func send(ctx context.Context, client *http.Client, target Target) (time.Duration, int, error) {
+	req, err := target(ctx)
+	if err != nil {
+		return 0, 0, err
+	}
+
+	start := time.Now()
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return 0, 0, err
+	}
+
+	defer resp.Body.Close()
+
+	// Read the body so the latency includes the full response and the
+	// connection can be reused.
+	if _, err := io.Copy(io.Discard, resp.Body); err != nil {
+		return 0, 0, err
+	}
+
+	return time.Since(start), resp.StatusCode, nil
+}
+
+func latencyDistribution(latencies []time.Duration) Latency {
+	if len(latencies) == 0 {
+		return Latency{}
+	}
+
+	sort.Slice(latencies, func(i, j int) bool { return latencies[i] < latencies[j] })
+
+	var total time.Duration
+	for _, l := range latencies {
+		total += l
+	}
+
+	quantile := func(q float64) time.Duration {
+		return latencies[int(q*float64(len(latencies)-1))]
+	}
+
+	return Latency{
+		Mean: total / time.Duration(len(latencies)),
+		P50:  quantile(0.5),
+		P90:  quantile(0.9),
+		P99:  quantile(0.99),
+		Max:  latencies[len(latencies)-1],
+	}
+}
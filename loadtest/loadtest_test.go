@@ -0,0 +1,129 @@
+package loadtest
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func Test_Schedule(t *testing.T) {
+	tests := []struct {
+		name          string
+		stages        []Stage
+		expectedCount int
+	}{
+		{
+			name:          "constant rate",
+			stages:        []Stage{{Rate: 100, Duration: time.Second}},
+			expectedCount: 100,
+		},
+		{
+			name:          "ramp up",
+			stages:        []Stage{{Rate: 100, Ramp: time.Second}},
+			expectedCount: 50,
+		},
+		{
+			name: "ramp between stages",
+			stages: []Stage{
+				{Rate: 10, Duration: time.Second},
+				{Rate: 30, Ramp: time.Second, Duration: time.Second},
+			},
+			expectedCount: 10 + 20 + 30,
+		},
+		{
+			name:          "zero rate",
+			stages:        []Stage{{Rate: 0, Duration: time.Second}},
+			expectedCount: 0,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			schedule := Schedule(tc.stages...)
+
+			if len(schedule) != tc.expectedCount {
+				t.Fatalf("unexpected number of requests, got: %d, expected: %d", len(schedule), tc.expectedCount)
+			}
+
+			for i := 1; i < len(schedule); i++ {
+				if schedule[i] < schedule[i-1] {
+					t.Fatalf("schedule not in order at %d: %v", i, schedule)
+				}
+			}
+		})
+	}
+}
+
+func Test_Run(t *testing.T) {
+	var count atomic.Int64
+
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if count.Add(1)%4 == 0 {
+			w.WriteHeader(http.StatusTooManyRequests)
+		}
+	}))
+
+	defer ts.Close()
+
+	summary, err := Run(
+		context.Background(),
+		Get(ts.URL),
+		WithClient(ts.Client()),
+		WithRate(200, 200*time.Millisecond),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if summary.Requests != 40 || summary.Errors != 0 || summary.Dropped != 0 {
+		t.Fatalf("unexpected summary: %s", summary)
+	}
+
+	if summary.StatusCodes[http.StatusOK] != 30 || summary.StatusCodes[http.StatusTooManyRequests] != 10 {
+		t.Fatalf("unexpected status codes: %v", summary.StatusCodes)
+	}
+
+	if summary.Latency.Max == 0 || summary.Latency.P50 > summary.Latency.Max {
+		t.Fatalf("unexpected latency: %+v", summary.Latency)
+	}
+}
+
+func Test_RunCancelled(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer ts.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+
+	summary, err := Run(ctx, Get(ts.URL), WithClient(ts.Client()), WithRate(10, time.Minute))
+	if err != context.DeadlineExceeded {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if summary.Requests == 0 || summary.Requests > 2 {
+		t.Fatalf("unexpected number of requests: %d", summary.Requests)
+	}
+}
+
+func Test_RunUnlimited(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer ts.Close()
+
+	summary, err := Run(
+		context.Background(),
+		Get(ts.URL),
+		WithClient(ts.Client()),
+		WithRate(100, 100*time.Millisecond),
+		WithMaxInFlight(0),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if summary.Requests != 10 || summary.Dropped != 0 {
+		t.Fatalf("unexpected summary: %s", summary)
+	}
+}
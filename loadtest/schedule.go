@@ -0,0 +1,57 @@
+package loadtest
+
+import (
+	"math"
+	"time"
+)
+
+// Schedule returns the time from the start of the load test when each request
+// is sent. The schedule only depends on the stages so the same stages always
+// result in the same requests.
+func Schedule(stages ...Stage) []time.Duration {
+	var (
+		schedule []time.Duration
+		offset   time.Duration
+		rate     float64
+
+		// carry is the fraction of a request accumulated but not yet sent at
+		// the start of a segment.
+		carry float64
+	)
+
+	segment := func(from, to float64, d time.Duration) {
+		if d <= 0 {
+			return
+		}
+
+		seconds := d.Seconds()
+		a := (to - from) / (2 * seconds)
+		total := carry + from*seconds + a*seconds*seconds
+
+		for k := 1.0; k <= total; k++ {
+			// Solve a*t^2 + from*t = k - carry for t.
+			n := k - carry
+
+			var t float64
+			if a == 0 {
+				t = n / from
+			} else {
+				t = (-from + math.Sqrt(from*from+4*a*n)) / (2 * a)
+			}
+
+			schedule = append(schedule, offset+time.Duration(t*float64(time.Second)))
+		}
+
+		carry = total - math.Floor(total)
+		offset += d
+	}
+
+	for _, stage := range stages {
+		segment(rate, stage.Rate, stage.Ramp)
+		segment(stage.Rate, stage.Rate, stage.Duration)
+
+		rate = stage.Rate
+	}
+
+	return schedule
+}
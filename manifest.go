@@ -0,0 +1,224 @@
+package httphelpers
+
+import (
+	"encoding/json"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/bombsimon/http-helpers/chain"
+)
+
+// Route describes a route in the manifest.
+type Route struct {
+	// Method is the HTTP method, empty if the route matches all methods.
+	Method string `json:"method,omitempty"`
+
+	// Pattern is the path pattern, e.g. "/users/{id}".
+	Pattern string `json:"pattern"`
+
+	// Middlewares is the names of the middlewares applied to the route in the
+	// order they're executed.
+	Middlewares []string `json:"middlewares,omitempty"`
+
+	// Auth is the authentication schemes accepted by the route, e.g. "bearer".
+	// An empty list means the route is public.
+	Auth []string `json:"auth,omitempty"`
+
+	// RateLimit is the rate limit for the route, if any.
+	RateLimit *RateLimit `json:"rate_limit,omitempty"`
+}
+
+// RateLimit describes the rate limit of a route.
+type RateLimit struct {
+	Limit    int           `json:"limit"`
+	Interval time.Duration `json:"-"`
+	Burst    int           `json:"burst,omitempty"`
+}
+
+// MarshalJSON encodes the interval as a string, e.g. "1m0s".
+func (rl RateLimit) MarshalJSON() ([]byte, error) {
+	type rateLimit RateLimit
+
+	return json.Marshal(struct {
+		rateLimit
+		Interval string `json:"interval"`
+	}{
+		rateLimit: rateLimit(rl),
+		Interval:  rl.Interval.String(),
+	})
+}
+
+// RouteSource is implemented by router adapters listing the registered routes,
+// such as Mux.
+type RouteSource interface {
+	Routes() []Route
+}
+
+// Manifest is the effective routes of a service.
+type Manifest struct {
+	Routes []Route `json:"routes"`
+}
+
+// ManifestOption is an option used to configure RouteManifest.
+type ManifestOption func(*manifestOptions)
+
+type manifestOptions struct {
+	chain *chain.Chain
+}
+
+// WithManifestChain adds the middlewares in the chain applied to each route
+// before the middlewares of the route itself. The chain should be the one
+// wrapping the router.
+func WithManifestChain(c chain.Chain) ManifestOption {
+	return func(o *manifestOptions) {
+		o.chain = &c
+	}
+}
+
+// RouteManifest returns the manifest of the routes in the source, sorted by
+// pattern and method. The manifest can be encoded as JSON for API gateways,
+// documentation or security reviews, or served with Handler.
+func RouteManifest(source RouteSource, opts ...ManifestOption) *Manifest {
+	options := &manifestOptions{}
+	for _, opt := range opts {
+		opt(options)
+	}
+
+	manifest := &Manifest{Routes: []Route{}}
+
+	for _, route := range source.Routes() {
+		if options.chain != nil {
+			route.Middlewares = append(options.chain.Effective(routeRequest(route)), route.Middlewares...)
+		}
+
+		manifest.Routes = append(manifest.Routes, route)
+	}
+
+	sort.SliceStable(manifest.Routes, func(i, j int) bool {
+		a, b := manifest.Routes[i], manifest.Routes[j]
+		if a.Pattern != b.Pattern {
+			return a.Pattern < b.Pattern
+		}
+
+		return a.Method < b.Method
+	})
+
+	return manifest
+}
+
+// Handler returns a handler serving the manifest as JSON. The handler should
+// not be exposed publicly.
+func (m *Manifest) Handler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+
+		_ = enc.Encode(m)
+	})
+}
+
+// routeRequest returns a request for the route used to find the middlewares
+// applied to it. Wildcards in the pattern are kept as is.
+func routeRequest(route Route) *http.Request {
+	method := route.Method
+	if method == "" {
+		method = http.MethodGet
+	}
+
+	host, path := "", route.Pattern
+	if i := strings.Index(path, "/"); i > 0 {
+		host, path = path[:i], path[i:]
+	}
+
+	r, err := http.NewRequest(method, "/", nil)
+	if err != nil {
+		panic(err)
+	}
+
+	r.URL.Path = path
+	r.Host = host
+
+	return r
+}
+
+// RouteOption is an option used to describe a route registered on a Mux.
+type RouteOption func(*Route, *[]chain.Middleware)
+
+// WithAuth sets the authentication schemes accepted by the route.
+func WithAuth(schemes ...string) RouteOption {
+	return func(route *Route, _ *[]chain.Middleware) {
+		route.Auth = append(route.Auth, schemes...)
+	}
+}
+
+// WithRateLimitDescription sets the rate limit of the route in the manifest.
+// The rate limit is only described, use WithRouteMiddleware to apply a rate
+// limiter.
+func WithRateLimitDescription(limit int, interval time.Duration, burst int) RouteOption {
+	return func(route *Route, _ *[]chain.Middleware) {
+		route.RateLimit = &RateLimit{Limit: limit, Interval: interval, Burst: burst}
+	}
+}
+
+// WithRouteMiddleware applies the middleware to the route and adds its name to
+// the manifest. Middlewares are executed in the order they're added.
+func WithRouteMiddleware(name string, m chain.Middleware) RouteOption {
+	return func(route *Route, middlewares *[]chain.Middleware) {
+		route.Middlewares = append(route.Middlewares, name)
+		*middlewares = append(*middlewares, m)
+	}
+}
+
+// Mux is an http.ServeMux keeping track of the registered routes so they can
+// be exported with RouteManifest.
+//
+//	mux := httphelpers.NewMux()
+//	mux.Handle("GET /users/{id}", getUser,
+//		httphelpers.WithAuth("bearer"),
+//		httphelpers.WithRouteMiddleware("ratelimit", limiter),
+//		httphelpers.WithRateLimitDescription(100, time.Minute, 10),
+//	)
+type Mux struct {
+	*http.ServeMux
+	routes []Route
+}
+
+// NewMux creates a new Mux.
+func NewMux() *Mux {
+	return &Mux{ServeMux: http.NewServeMux()}
+}
+
+// Handle registers the handler for the pattern, see http.ServeMux for the
+// pattern syntax.
+func (m *Mux) Handle(pattern string, h http.Handler, opts ...RouteOption) {
+	route := Route{Pattern: pattern}
+	if method, rest, ok := strings.Cut(pattern, " "); ok {
+		route.Method, route.Pattern = method, strings.TrimSpace(rest)
+	}
+
+	var middlewares []chain.Middleware
+
+	for _, opt := range opts {
+		opt(&route, &middlewares)
+	}
+
+	m.ServeMux.Handle(pattern, chain.New(middlewares...).Then(h))
+	m.routes = append(m.routes, route)
+}
+
+// HandleFunc registers the handler function for the pattern.
+func (m *Mux) HandleFunc(pattern string, h func(http.ResponseWriter, *http.Request), opts ...RouteOption) {
+	m.Handle(pattern, http.HandlerFunc(h), opts...)
+}
+
+// Routes returns the routes registered on the mux.
+func (m *Mux) Routes() []Route {
+	routes := make([]Route, len(m.routes))
+	copy(routes, m.routes)
+
+	return routes
+}
@@ -0,0 +1,89 @@
+package httphelpers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/bombsimon/http-helpers/chain"
+)
+
+func Test_RouteManifest(t *testing.T) {
+	noop := func(h http.Handler) http.Handler { return h }
+
+	var called []string
+
+	track := func(name string) chain.Middleware {
+		return func(h http.Handler) http.Handler {
+			return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				called = append(called, name)
+				h.ServeHTTP(w, r)
+			})
+		}
+	}
+
+	mux := NewMux()
+	mux.HandleFunc("GET /users/{id}", func(w http.ResponseWriter, r *http.Request) {},
+		WithAuth("bearer"),
+		WithRouteMiddleware("ratelimit", track("ratelimit")),
+		WithRouteMiddleware("audit", track("audit")),
+		WithRateLimitDescription(100, time.Minute, 10),
+	)
+	mux.HandleFunc("/healthz", func(w http.ResponseWriter, r *http.Request) {})
+
+	c := chain.New().
+		UseNamed("logger", noop).
+		Group(chain.PathPrefix("/users/")).
+		UseNamed("auth", noop)
+
+	manifest := RouteManifest(mux, WithManifestChain(c))
+
+	expected := []Route{
+		{Pattern: "/healthz", Middlewares: []string{"logger"}},
+		{
+			Method:      http.MethodGet,
+			Pattern:     "/users/{id}",
+			Middlewares: []string{"logger", "auth", "ratelimit", "audit"},
+			Auth:        []string{"bearer"},
+			RateLimit:   &RateLimit{Limit: 100, Interval: time.Minute, Burst: 10},
+		},
+	}
+
+	if !reflect.DeepEqual(manifest.Routes, expected) {
+		t.Fatalf("unexpected routes, got: %+v, expected: %+v", manifest.Routes, expected)
+	}
+
+	rr := httptest.NewRecorder()
+	mux.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/users/1", nil))
+
+	if !reflect.DeepEqual(called, []string{"ratelimit", "audit"}) {
+		t.Fatalf("route middlewares not applied in order, got: %v", called)
+	}
+
+	rr = httptest.NewRecorder()
+	manifest.Handler().ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/", nil))
+
+	var body struct {
+		Routes []struct {
+			Method    string                 `json:"method"`
+			Pattern   string                 `json:"pattern"`
+			RateLimit map[string]interface{} `json:"rate_limit"`
+		} `json:"routes"`
+	}
+
+	if err := json.NewDecoder(rr.Body).Decode(&body); err != nil {
+		t.Fatalf("could not decode manifest: %v", err)
+	}
+
+	if len(body.Routes) != 2 || body.Routes[1].Method != http.MethodGet {
+		t.Fatalf("unexpected manifest: %+v", body)
+	}
+
+	expectedRateLimit := map[string]interface{}{"limit": 100.0, "burst": 10.0, "interval": "1m0s"}
+	if got := body.Routes[1].RateLimit; !reflect.DeepEqual(got, expectedRateLimit) {
+		t.Fatalf("unexpected rate limit: %v", got)
+	}
+}
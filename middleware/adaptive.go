@@ -0,0 +1,199 @@
+package middleware
+
+import (
+	"math"
+	"net/http"
+	"sync"
+	"time"
+)
+
+const (
+	// adaptiveTolerance is how much slower recent requests can be than the
+	// long term latency before the limit is decreased.
+	adaptiveTolerance = 1.5
+
+	// adaptiveSmoothing is how much of each new limit is applied.
+	adaptiveSmoothing = 0.2
+
+	// adaptiveBackoff is the multiplicative decrease of the limit for each
+	// failed request.
+	adaptiveBackoff = 0.9
+
+	// adaptiveShortWindow and adaptiveLongWindow are the number of requests
+	// the short and long term latency averages are taken over.
+	adaptiveShortWindow = 10
+	adaptiveLongWindow  = 600
+)
+
+// AdaptiveLimiter limits the number of requests in flight to a limit adjusted
+// from the latency and errors of recent requests. The limit is decreased when
+// the short term latency rises above the long term latency, i.e. requests are
+// queueing, and multiplicatively for each 5xx response or panic. It's
+// increased while the latency is stable and the limit is used. Requests above
+// the limit are shed with 503 Service Unavailable so a degrading service sheds
+// load early instead of queueing until everything times out.
+type AdaptiveLimiter struct {
+	options *options
+
+	mu       sync.Mutex
+	limit    float64
+	inFlight int
+	shortRTT float64
+	longRTT  float64
+	shed     uint64
+}
+
+// AdaptiveLimiterStats are the current stats of an AdaptiveLimiter.
+type AdaptiveLimiterStats struct {
+	Limit    int
+	InFlight int
+	Shed     uint64
+}
+
+// NewAdaptiveLimiter creates a new AdaptiveLimiter. Set the initial, minimum
+// and maximum limit with WithConcurrencyLimits, they default to 20, 1 and
+// 1000. The initial limit is clamped to the minimum and maximum.
+func NewAdaptiveLimiter(opts ...Option) *AdaptiveLimiter {
+	limiter := &AdaptiveLimiter{
+		options: newOptions(opts...),
+	}
+
+	limiter.setLimit(float64(limiter.options.concurrencyInitial))
+
+	return limiter
+}
+
+// AdaptiveLimit returns a middleware shedding load with a new AdaptiveLimiter.
+func AdaptiveLimit(opts ...Option) Middleware {
+	return NewAdaptiveLimiter(opts...).Middleware()
+}
+
+// WithConcurrencyLimits sets the initial, minimum and maximum number of
+// requests in flight allowed by AdaptiveLimiter.
+func WithConcurrencyLimits(initial, minimum, maximum int) Option {
+	return func(o *options) {
+		o.concurrencyInitial = initial
+		o.concurrencyMin = minimum
+		o.concurrencyMax = maximum
+	}
+}
+
+// Stats returns the current limit, requests in flight and the number of shed
+// requests.
+func (l *AdaptiveLimiter) Stats() AdaptiveLimiterStats {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	return AdaptiveLimiterStats{
+		Limit:    int(l.limit),
+		InFlight: l.inFlight,
+		Shed:     l.shed,
+	}
+}
+
+// Middleware returns the middleware limiting the requests in flight.
+func (l *AdaptiveLimiter) Middleware() Middleware {
+	overload := l.options.overloadSignal()
+
+	return l.options.skippable(func(h http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			inFlight, ok := l.acquire()
+			if !ok {
+				overload.reject(w, http.StatusServiceUnavailable, l.retryAfter())
+				return
+			}
+
+			var (
+				rw        = NewResponseWriter(w)
+				start     = l.options.clock.Now()
+				completed bool
+			)
+
+			// A panicking handler counts as failed, the panic is left to
+			// the recovery middleware.
+			defer func() {
+				l.release(l.options.clock.Since(start), !completed || rw.Status() >= 500, inFlight)
+			}()
+
+			h.ServeHTTP(rw.WithInterfaces(), r)
+
+			completed = true
+		})
+	})
+}
+
+// acquire adds a request in flight and returns the number of requests in
+// flight, or false if the limit is reached.
+func (l *AdaptiveLimiter) acquire() (int, bool) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.inFlight >= int(l.limit) {
+		l.shed++
+		return 0, false
+	}
+
+	l.inFlight++
+
+	return l.inFlight, true
+}
+
+// retryAfter returns the short term latency, the expected time until a request
+// in flight completes.
+func (l *AdaptiveLimiter) retryAfter() time.Duration {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	return time.Duration(l.shortRTT)
+}
+
+// release removes a request in flight and adjusts the limit from its latency
+// and whether it failed.
+func (l *AdaptiveLimiter) release(rtt time.Duration, failed bool, inFlight int) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.inFlight--
+	l.observe(rtt, failed, inFlight)
+}
+
+// observe adjusts the limit for a request that had inFlight requests in flight
+// when it started.
+func (l *AdaptiveLimiter) observe(rtt time.Duration, failed bool, inFlight int) {
+	if failed {
+		l.setLimit(l.limit * adaptiveBackoff)
+		return
+	}
+
+	sample := float64(rtt)
+	if sample <= 0 {
+		return
+	}
+
+	if l.longRTT == 0 {
+		l.shortRTT, l.longRTT = sample, sample
+	}
+
+	l.shortRTT += (sample - l.shortRTT) * 2 / (adaptiveShortWindow + 1)
+	l.longRTT += (sample - l.longRTT) * 2 / (adaptiveLongWindow + 1)
+
+	// Let the long term latency follow quickly when the latency returns to
+	// normal after an overload.
+	if l.longRTT/l.shortRTT > 2 {
+		l.longRTT *= 0.95
+	}
+
+	// Don't grow the limit when it isn't used.
+	if float64(inFlight) < l.limit/2 {
+		return
+	}
+
+	gradient := math.Max(0.5, math.Min(1, adaptiveTolerance*l.longRTT/l.shortRTT))
+	limit := l.limit*gradient + math.Sqrt(l.limit)
+
+	l.setLimit(l.limit*(1-adaptiveSmoothing) + limit*adaptiveSmoothing)
+}
+
+func (l *AdaptiveLimiter) setLimit(limit float64) {
+	l.limit = math.Max(float64(l.options.concurrencyMin), math.Min(float64(l.options.concurrencyMax), limit))
+}
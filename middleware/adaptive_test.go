@@ -0,0 +1,142 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+)
+
+func Test_AdaptiveLimit(t *testing.T) {
+	limiter := NewAdaptiveLimiter(WithConcurrencyLimits(2, 1, 10))
+
+	var (
+		started = make(chan struct{})
+		done    = make(chan struct{})
+		wg      sync.WaitGroup
+	)
+
+	handler := AddMiddlewares(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/block" {
+			started <- struct{}{}
+			<-done
+		}
+	}), limiter.Middleware())
+
+	for range 2 {
+		wg.Add(1)
+
+		go func() {
+			defer wg.Done()
+			handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/block", nil))
+		}()
+
+		<-started
+	}
+
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+
+	if rec.Code != http.StatusServiceUnavailable {
+		t.Fatalf("expected request above the limit to be shed, got: %d", rec.Code)
+	}
+
+	close(done)
+	wg.Wait()
+
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected request to be served, got: %d", rec.Code)
+	}
+
+	if stats := limiter.Stats(); stats.InFlight != 0 || stats.Shed != 1 {
+		t.Fatalf("unexpected stats: %+v", stats)
+	}
+}
+
+func Test_AdaptiveLimiterAdjusts(t *testing.T) {
+	limiter := NewAdaptiveLimiter(WithConcurrencyLimits(50, 5, 200))
+
+	observe := func(n int, rtt time.Duration, failed bool) {
+		for range n {
+			limiter.observe(rtt, failed, limiter.Stats().Limit)
+		}
+	}
+
+	observe(100, 10*time.Millisecond, false)
+
+	healthy := limiter.Stats().Limit
+	if healthy <= 50 {
+		t.Fatalf("expected limit to grow while the latency is stable, got: %d", healthy)
+	}
+
+	observe(50, 100*time.Millisecond, false)
+
+	degraded := limiter.Stats().Limit
+	if degraded >= healthy {
+		t.Fatalf("expected limit to decrease when the latency rises, got: %d, was: %d", degraded, healthy)
+	}
+
+	observe(5, 0, true)
+
+	if failing := limiter.Stats().Limit; failing >= degraded {
+		t.Fatalf("expected limit to decrease on errors, got: %d, was: %d", failing, degraded)
+	}
+
+	observe(1000, 100*time.Millisecond, true)
+
+	if minimum := limiter.Stats().Limit; minimum != 5 {
+		t.Fatalf("expected limit to stop at the minimum, got: %d", minimum)
+	}
+
+	// Requests using less than half the limit don't grow it.
+	for range 100 {
+		limiter.observe(10*time.Millisecond, false, 1)
+	}
+
+	if idle := limiter.Stats().Limit; idle != 5 {
+		t.Fatalf("expected unused limit to not grow, got: %d", idle)
+	}
+}
+
+func Test_AdaptiveLimiterInitialLimit(t *testing.T) {
+	for _, step := range []struct {
+		initial, minimum, maximum int
+		expected                  int
+	}{
+		{initial: 0, minimum: 1, maximum: 10, expected: 1},
+		{initial: 20, minimum: 1, maximum: 10, expected: 10},
+		{initial: 5, minimum: 1, maximum: 10, expected: 5},
+	} {
+		limiter := NewAdaptiveLimiter(WithConcurrencyLimits(step.initial, step.minimum, step.maximum))
+
+		if limit := limiter.Stats().Limit; limit != step.expected {
+			t.Fatalf("unexpected limit, got: %d, expected: %d", limit, step.expected)
+		}
+	}
+}
+
+func Test_AdaptiveLimiterPanic(t *testing.T) {
+	limiter := NewAdaptiveLimiter(WithConcurrencyLimits(10, 1, 10))
+
+	handler := AddMiddlewares(http.HandlerFunc(func(http.ResponseWriter, *http.Request) {
+		panic("boom")
+	}), limiter.Middleware())
+
+	func() {
+		defer func() {
+			if recover() == nil {
+				t.Fatal("expected the panic to propagate")
+			}
+		}()
+
+		handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil))
+	}()
+
+	if stats := limiter.Stats(); stats.Limit != 9 || stats.InFlight != 0 {
+		t.Fatalf("expected panic to count as a failure, got: %+v", stats)
+	}
+}
@@ -0,0 +1,94 @@
+package middleware
+
+import (
+	"crypto/subtle"
+	"net"
+	"net/http"
+	"net/netip"
+	"slices"
+	"strings"
+)
+
+// AdminGuard protects admin and debug endpoints, such as metrics and pprof,
+// which should only be reachable from the local machine or network.
+//
+// Requests must come from a loopback or private address, or a network set
+// with WithAdminNetworks, and the Host header must be localhost, an IP address
+// or a host added with WithAllowedHosts. Checking the Host header prevents DNS
+// rebinding where a web page makes the browser send requests to a local
+// server under the attacker's domain. Requests failing these checks get 403
+// Forbidden. If a token is set with WithAdminToken it must also be sent as a
+// bearer token or 401 Unauthorized is returned.
+//
+// The remote address of the connection is used as is, so the guarded server
+// shouldn't be behind a proxy.
+func AdminGuard(opts ...Option) Middleware {
+	options := newOptions(opts...)
+
+	return options.skippable(func(h http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if !options.adminRemoteAllowed(r.RemoteAddr) || !options.adminHostAllowed(r.Host) {
+				http.Error(w, http.StatusText(http.StatusForbidden), http.StatusForbidden)
+				return
+			}
+
+			if options.adminToken != "" && !validBearerToken(r, options.adminToken) {
+				w.Header().Set("WWW-Authenticate", `Bearer realm="admin"`)
+				http.Error(w, http.StatusText(http.StatusUnauthorized), http.StatusUnauthorized)
+
+				return
+			}
+
+			h.ServeHTTP(w, r)
+		})
+	})
+}
+
+func (o *options) adminRemoteAllowed(remoteAddr string) bool {
+	host, _, err := net.SplitHostPort(remoteAddr)
+	if err != nil {
+		host = remoteAddr
+	}
+
+	addr, err := netip.ParseAddr(host)
+	if err != nil {
+		return false
+	}
+
+	addr = addr.Unmap()
+
+	if len(o.adminNetworks) == 0 {
+		return addr.IsLoopback() || addr.IsPrivate()
+	}
+
+	return isTrusted(addr, o.adminNetworks)
+}
+
+func (o *options) adminHostAllowed(host string) bool {
+	if h, _, err := net.SplitHostPort(host); err == nil {
+		host = h
+	}
+
+	host = strings.ToLower(strings.TrimSuffix(host, "."))
+
+	if host == "localhost" || strings.HasSuffix(host, ".localhost") {
+		return true
+	}
+
+	if _, err := netip.ParseAddr(strings.Trim(host, "[]")); err == nil {
+		return true
+	}
+
+	return slices.ContainsFunc(o.allowedHosts, func(allowed string) bool {
+		return strings.EqualFold(allowed, host)
+	})
+}
+
+func validBearerToken(r *http.Request, token string) bool {
+	got, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+	if !ok {
+		return false
+	}
+
+	return subtle.ConstantTimeCompare([]byte(got), []byte(token)) == 1
+}
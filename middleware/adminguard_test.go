@@ -0,0 +1,105 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"testing"
+)
+
+func Test_AdminGuard(t *testing.T) {
+	tests := []struct {
+		name           string
+		opts           []Option
+		remoteAddr     string
+		host           string
+		authorization  string
+		expectedStatus int
+	}{
+		{
+			name:           "localhost",
+			remoteAddr:     "127.0.0.1:1234",
+			host:           "localhost:9090",
+			expectedStatus: http.StatusOK,
+		},
+		{
+			name:           "private network with ip host",
+			remoteAddr:     "10.0.0.5:1234",
+			host:           "10.0.0.1:9090",
+			expectedStatus: http.StatusOK,
+		},
+		{
+			name:           "public remote address",
+			remoteAddr:     "203.0.113.1:1234",
+			host:           "localhost:9090",
+			expectedStatus: http.StatusForbidden,
+		},
+		{
+			name:           "dns rebinding",
+			remoteAddr:     "127.0.0.1:1234",
+			host:           "attacker.example.com:9090",
+			expectedStatus: http.StatusForbidden,
+		},
+		{
+			name:           "allowed host",
+			opts:           []Option{WithAllowedHosts("admin.internal")},
+			remoteAddr:     "[::1]:1234",
+			host:           "admin.internal",
+			expectedStatus: http.StatusOK,
+		},
+		{
+			name:           "custom network",
+			opts:           []Option{WithAdminNetworks(netip.MustParsePrefix("192.0.2.0/24"))},
+			remoteAddr:     "127.0.0.1:1234",
+			host:           "localhost",
+			expectedStatus: http.StatusForbidden,
+		},
+		{
+			name:           "missing token",
+			opts:           []Option{WithAdminToken("secret")},
+			remoteAddr:     "127.0.0.1:1234",
+			host:           "localhost",
+			expectedStatus: http.StatusUnauthorized,
+		},
+		{
+			name:           "wrong token",
+			opts:           []Option{WithAdminToken("secret")},
+			remoteAddr:     "127.0.0.1:1234",
+			host:           "localhost",
+			authorization:  "Bearer guess",
+			expectedStatus: http.StatusUnauthorized,
+		},
+		{
+			name:           "valid token",
+			opts:           []Option{WithAdminToken("secret")},
+			remoteAddr:     "127.0.0.1:1234",
+			host:           "localhost",
+			authorization:  "Bearer secret",
+			expectedStatus: http.StatusOK,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			handler := AddMiddlewares(
+				http.HandlerFunc(func(http.ResponseWriter, *http.Request) {}),
+				AdminGuard(tc.opts...),
+			)
+
+			req := httptest.NewRequest(http.MethodGet, "/metrics", nil)
+			req.RemoteAddr = tc.remoteAddr
+			req.Host = tc.host
+
+			if tc.authorization != "" {
+				req.Header.Set("Authorization", tc.authorization)
+			}
+
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, req)
+
+			if rec.Code != tc.expectedStatus {
+				t.Fatalf("unexpected status, got: %d, expected: %d", rec.Code, tc.expectedStatus)
+			}
+		})
+	}
+}
@@ -0,0 +1,87 @@
+package middleware
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"net/http"
+
+	"github.com/bombsimon/http-helpers/httpctx"
+)
+
+// BufferBody reads the request body, up to maxSize bytes, before the handler
+// so middlewares that need it, e.g. VerifySignature or an audit log, don't
+// each consume or copy it. The body is stored in the context, see
+// httpctx.Body, and the request body is replaced with a reader of the buffer
+// which can be recreated with Request.GetBody. The maximum size is stored with
+// httpctx.WithMaxBodySize so the bind package uses the same limit. Requests
+// with a larger body are rejected with 413 Request Entity Too Large and
+// requests with a body that can't be read with 400 Bad Request. Requests
+// already buffered are passed through as is.
+func BufferBody(maxSize int64, opts ...Option) Middleware {
+	options := newOptions(opts...)
+
+	return options.skippable(func(h http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if _, ok := httpctx.Body(r.Context()); ok {
+				h.ServeHTTP(w, r)
+				return
+			}
+
+			if r.ContentLength > maxSize {
+				http.Error(w, http.StatusText(http.StatusRequestEntityTooLarge), http.StatusRequestEntityTooLarge)
+				return
+			}
+
+			body, err := readBody(w, r, maxSize)
+			if err != nil {
+				var maxBytesErr *http.MaxBytesError
+				if errors.As(err, &maxBytesErr) {
+					http.Error(w, http.StatusText(http.StatusRequestEntityTooLarge), http.StatusRequestEntityTooLarge)
+					return
+				}
+
+				http.Error(w, http.StatusText(http.StatusBadRequest), http.StatusBadRequest)
+
+				return
+			}
+
+			ctx := httpctx.WithBody(r.Context(), body)
+			ctx = httpctx.WithMaxBodySize(ctx, maxSize)
+
+			h.ServeHTTP(w, r.WithContext(ctx))
+		})
+	})
+}
+
+// readBody returns the request body, from the context if it's buffered by
+// BufferBody. Otherwise the body is read, up to maxSize bytes if maxSize is
+// positive, and replaced with a reader of the buffer.
+func readBody(w http.ResponseWriter, r *http.Request, maxSize int64) ([]byte, error) {
+	if body, ok := httpctx.Body(r.Context()); ok {
+		return body, nil
+	}
+
+	if r.Body == nil || r.Body == http.NoBody {
+		return []byte{}, nil
+	}
+
+	reader := r.Body
+	if maxSize > 0 {
+		reader = http.MaxBytesReader(w, r.Body, maxSize)
+	}
+
+	body, err := io.ReadAll(reader)
+	_ = r.Body.Close()
+
+	if err != nil {
+		return nil, err
+	}
+
+	r.Body = io.NopCloser(bytes.NewReader(body))
+	r.GetBody = func() (io.ReadCloser, error) {
+		return io.NopCloser(bytes.NewReader(body)), nil
+	}
+
+	return body, nil
+}
@@ -0,0 +1,101 @@
+package middleware
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/bombsimon/http-helpers/httpctx"
+)
+
+type failingReader struct{}
+
+func (failingReader) Read([]byte) (int, error) {
+	return 0, errors.New("connection reset")
+}
+
+func Test_BufferBody(t *testing.T) {
+	for _, tc := range []struct {
+		description    string
+		body           io.Reader
+		contentLength  int64
+		expectedStatus int
+		expectedBody   string
+	}{
+		{
+			description:    "buffered",
+			body:           strings.NewReader("payload"),
+			contentLength:  7,
+			expectedStatus: http.StatusOK,
+			expectedBody:   "payload",
+		},
+		{
+			description:    "no body",
+			expectedStatus: http.StatusOK,
+		},
+		{
+			description:    "content length too large",
+			body:           strings.NewReader("too large payload"),
+			contentLength:  17,
+			expectedStatus: http.StatusRequestEntityTooLarge,
+		},
+		{
+			description:    "chunked body too large",
+			body:           strings.NewReader("too large payload"),
+			contentLength:  -1,
+			expectedStatus: http.StatusRequestEntityTooLarge,
+		},
+		{
+			description:    "read error",
+			body:           failingReader{},
+			contentLength:  -1,
+			expectedStatus: http.StatusBadRequest,
+		},
+	} {
+		t.Run(tc.description, func(t *testing.T) {
+			// The handler reads the body from the context, the request and
+			// the recreated request body.
+			handler := AddMiddlewares(
+				http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+					buffered, _ := httpctx.Body(r.Context())
+					read, _ := io.ReadAll(r.Body)
+					bodies := [][]byte{buffered, read}
+
+					if r.GetBody != nil {
+						again, err := r.GetBody()
+						if err != nil {
+							t.Fatalf("expected body to be recreatable: %s", err)
+						}
+
+						reread, _ := io.ReadAll(again)
+						bodies = append(bodies, reread)
+					}
+
+					for _, body := range bodies {
+						if string(body) != tc.expectedBody {
+							t.Fatalf("unexpected body, got: %q, expected: %q", body, tc.expectedBody)
+						}
+					}
+
+					if size, _ := httpctx.MaxBodySize(r.Context()); size != 10 {
+						t.Fatalf("unexpected max body size, got: %d, expected: 10", size)
+					}
+				}),
+				BufferBody(10),
+			)
+
+			req := httptest.NewRequest(http.MethodPost, "/", tc.body)
+			req.ContentLength = tc.contentLength
+
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, req)
+
+			if rec.Code != tc.expectedStatus {
+				t.Fatalf("unexpected status, got: %d, expected: %d", rec.Code, tc.expectedStatus)
+			}
+		})
+	}
+}
@@ -0,0 +1,291 @@
+package middleware
+
+import (
+	"bytes"
+	"context"
+	"log/slog"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/bombsimon/http-helpers/cache"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// WithStaleWhileRevalidate sets how long Cache serves an expired response
+// while it's regenerated in the background. Overridden by the
+// stale-while-revalidate directive of the response Cache-Control header.
+func WithStaleWhileRevalidate(d time.Duration) Option {
+	return func(o *options) {
+		o.staleWhileRevalidate = d
+	}
+}
+
+// WithStaleIfError sets how long Cache serves an expired response if
+// regenerating it fails with a 5xx status or another request is already
+// regenerating it. Overridden by the stale-if-error directive of the response
+// Cache-Control header.
+func WithStaleIfError(d time.Duration) Option {
+	return func(o *options) {
+		o.staleIfError = d
+	}
+}
+
+// WithCacheKey sets the function returning the key responses are cached by.
+// Defaults to the host and the request URI. Requests varying by headers, e.g.
+// Accept-Language, must include them in the key.
+func WithCacheKey(fn func(*http.Request) string) Option {
+	return func(o *options) {
+		o.cacheKey = fn
+	}
+}
+
+// WithCacheMaxEntries sets the maximum number of responses kept by Cache in
+// the default store. Defaults to 1000.
+func WithCacheMaxEntries(n int) Option {
+	return func(o *options) {
+		o.cacheMaxEntries = n
+	}
+}
+
+// WithCacheStore sets the store Cache keeps responses in, e.g. to share the
+// cache with a caching proxy or between instances. Defaults to a
+// cache.NewMemoryStore with the size set with WithCacheMaxEntries.
+func WithCacheStore(store cache.Store) Option {
+	return func(o *options) {
+		o.cacheStore = store
+	}
+}
+
+// Cache caches responses to GET requests for the ttl, or the s-maxage or
+// max-age of the response Cache-Control header, see cache.NewEntry. Requests
+// with an Authorization header are never served from the cache. The X-Cache
+// response header is set to HIT, STALE or MISS and the results are counted in
+// cache_requests_total, which is only registered if WithRegisterer is passed.
+//
+// Only one request at a time, per process, regenerates an entry. Concurrent
+// requests for an entry that isn't cached wait for it, and expired entries are
+// served to concurrent requests while they're regenerated within the
+// WithStaleWhileRevalidate or WithStaleIfError windows, so hot keys don't
+// cause a thundering herd on expiry. Within the stale-while-revalidate window
+// the entry is regenerated in the background and the stale entry is served
+// right away. Responses are buffered so streaming handlers shouldn't be
+// cached.
+func Cache(ttl time.Duration, opts ...Option) Middleware {
+	options := newOptions(opts...)
+	if options.cacheKey == nil {
+		options.cacheKey = func(r *http.Request) string {
+			return r.Host + r.URL.RequestURI()
+		}
+	}
+
+	if options.cacheStore == nil {
+		options.cacheStore = cache.NewMemoryStore(options.cacheMaxEntries)
+	}
+
+	results := registerOrExisting(options.optInRegisterer(), prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "cache_requests_total",
+			Help: "A counter for requests served by the cache middleware by result, hit, stale or miss.",
+		},
+		[]string{"result"},
+	))
+
+	return options.skippable(func(h http.Handler) http.Handler {
+		c := &responseCache{
+			options: options,
+			policy: cache.Policy{
+				TTL:                  ttl,
+				StaleWhileRevalidate: options.staleWhileRevalidate,
+				StaleIfError:         options.staleIfError,
+			},
+			h:       h,
+			fills:   make(map[string]*cacheFill),
+			results: results,
+		}
+
+		return http.HandlerFunc(c.serveHTTP)
+	})
+}
+
+type responseCache struct {
+	options *options
+	policy  cache.Policy
+	h       http.Handler
+
+	mu    sync.Mutex
+	fills map[string]*cacheFill
+
+	results *prometheus.CounterVec
+}
+
+// cacheFill is a regeneration of an entry in progress. The entry is set, if
+// the response was cacheable, before done is closed.
+type cacheFill struct {
+	done  chan struct{}
+	entry *cache.Entry
+}
+
+func (c *responseCache) serveHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet || r.Header.Get("Authorization") != "" {
+		c.h.ServeHTTP(w, r)
+		return
+	}
+
+	key := c.options.cacheKey(r)
+	entry, ok := c.options.cacheStore.Get(r.Context(), key)
+	now := c.options.clock.Now()
+
+	if ok && entry.Fresh(now) {
+		c.write(w, entry, now, "HIT")
+		return
+	}
+
+	c.mu.Lock()
+
+	fill, filling := c.fills[key]
+
+	switch {
+	case ok && entry.ServableWhileRevalidating(now):
+		if !filling {
+			fill = c.startFill(key)
+
+			// The refresh keeps the request context values but isn't
+			// canceled when this request is done.
+			req := r.Clone(context.WithoutCancel(r.Context()))
+			req.Body = http.NoBody
+
+			go c.refresh(req, key, fill)
+		}
+
+		c.mu.Unlock()
+		c.write(w, entry, now, "STALE")
+
+		return
+	case filling:
+		c.mu.Unlock()
+
+		if ok && entry.ServableOnError(now) {
+			c.write(w, entry, now, "STALE")
+			return
+		}
+
+		select {
+		case <-fill.done:
+		case <-r.Context().Done():
+			return
+		}
+
+		if fill.entry != nil {
+			c.write(w, fill.entry, c.options.clock.Now(), "HIT")
+			return
+		}
+
+		c.h.ServeHTTP(w, r)
+
+		return
+	}
+
+	fill = c.startFill(key)
+	c.mu.Unlock()
+
+	response := c.regenerate(r, key, fill)
+
+	if response.Status >= 500 && ok && entry.ServableOnError(now) {
+		c.write(w, entry, now, "STALE")
+		return
+	}
+
+	c.write(w, response, response.Stored, "MISS")
+}
+
+// write writes the entry with the X-Cache header set to cacheStatus and
+// counts the result.
+func (c *responseCache) write(w http.ResponseWriter, entry *cache.Entry, now time.Time, cacheStatus string) {
+	c.results.WithLabelValues(strings.ToLower(cacheStatus)).Inc()
+	entry.WriteTo(w, now, cacheStatus)
+}
+
+// startFill registers a regeneration of the key. The cache mutex must be held.
+func (c *responseCache) startFill(key string) *cacheFill {
+	fill := &cacheFill{done: make(chan struct{})}
+	c.fills[key] = fill
+
+	return fill
+}
+
+// refresh regenerates an entry in the background, logging if the handler
+// panics.
+func (c *responseCache) refresh(r *http.Request, key string, fill *cacheFill) {
+	defer func() {
+		if p := recover(); p != nil {
+			c.options.logger.Error("panic refreshing cached response", slog.String("key", key), slog.Any("panic", p))
+		}
+	}()
+
+	c.regenerate(r, key, fill)
+}
+
+// regenerate runs the handler, buffering the response, and stores it if it's
+// cacheable. Requests waiting for the fill are released even if the handler
+// panics.
+func (c *responseCache) regenerate(r *http.Request, key string, fill *cacheFill) *cache.Entry {
+	var cached *cache.Entry
+
+	defer func() {
+		c.mu.Lock()
+		delete(c.fills, key)
+		c.mu.Unlock()
+
+		fill.entry = cached
+		close(fill.done)
+	}()
+
+	rec := &cacheRecorder{header: http.Header{}, status: http.StatusOK}
+	c.h.ServeHTTP(rec, r)
+
+	now := c.options.clock.Now()
+	body := rec.buf.Bytes()
+
+	// Responses can't be revalidated by the handler so only entries that can
+	// be served are stored.
+	entry, ok := cache.NewEntry(rec.status, rec.header, body, now, c.policy)
+	if ok && (entry.Fresh(now) || entry.StaleWhileRevalidate > 0 || entry.StaleIfError > 0) {
+		cached = entry
+		c.options.cacheStore.Set(context.WithoutCancel(r.Context()), key, entry)
+
+		return entry
+	}
+
+	return &cache.Entry{Status: rec.status, Header: rec.header, Body: body, Stored: now}
+}
+
+// cacheRecorder buffers a response.
+type cacheRecorder struct {
+	header      http.Header
+	status      int
+	wroteHeader bool
+	buf         bytes.Buffer
+}
+
+func (w *cacheRecorder) Header() http.Header {
+	return w.header
+}
+
+func (w *cacheRecorder) WriteHeader(code int) {
+	if w.wroteHeader || code >= 100 && code < 200 {
+		return
+	}
+
+	w.wroteHeader = true
+	w.status = code
+}
+
+func (w *cacheRecorder) Write(b []byte) (int, error) {
+	if !w.wroteHeader {
+		w.WriteHeader(http.StatusOK)
+	}
+
+	return w.buf.Write(b)
+}
@@ -0,0 +1,175 @@
+package middleware
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/bombsimon/http-helpers/clock"
+)
+
+func cacheGet(t *testing.T, h http.Handler, path string) *httptest.ResponseRecorder {
+	t.Helper()
+
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, path, nil))
+
+	return rec
+}
+
+func Test_Cache(t *testing.T) {
+	var (
+		clk   = clock.NewFake(time.Now())
+		calls atomic.Int32
+	)
+
+	handler := AddMiddlewares(
+		http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			n := calls.Add(1)
+
+			switch r.URL.Path {
+			case "/private":
+				w.Header().Set("Cache-Control", "no-store")
+			case "/short":
+				w.Header().Set("Cache-Control", "public, max-age=10")
+			}
+
+			fmt.Fprintf(w, "v%d", n)
+		}),
+		Cache(time.Minute, WithClock(clk)),
+	)
+
+	for _, step := range []struct {
+		advance        time.Duration
+		path           string
+		expectedBody   string
+		expectedStatus string
+		expectedAge    string
+	}{
+		{path: "/", expectedBody: "v1", expectedStatus: "MISS", expectedAge: "0"},
+		{path: "/", expectedBody: "v1", expectedStatus: "HIT", expectedAge: "0"},
+		{advance: 30 * time.Second, path: "/", expectedBody: "v1", expectedStatus: "HIT", expectedAge: "30"},
+		{path: "/?page=2", expectedBody: "v2", expectedStatus: "MISS", expectedAge: "0"},
+		{path: "/private", expectedBody: "v3", expectedStatus: "MISS", expectedAge: "0"},
+		{path: "/private", expectedBody: "v4", expectedStatus: "MISS", expectedAge: "0"},
+		{path: "/short", expectedBody: "v5", expectedStatus: "MISS", expectedAge: "0"},
+		{advance: 11 * time.Second, path: "/short", expectedBody: "v6", expectedStatus: "MISS", expectedAge: "0"},
+		{advance: 20 * time.Second, path: "/", expectedBody: "v7", expectedStatus: "MISS", expectedAge: "0"},
+	} {
+		clk.Advance(step.advance)
+
+		rec := cacheGet(t, handler, step.path)
+
+		if rec.Body.String() != step.expectedBody ||
+			rec.Header().Get("X-Cache") != step.expectedStatus ||
+			rec.Header().Get("Age") != step.expectedAge {
+			t.Fatalf(
+				"unexpected response for %s, got: %s %s age %s, expected: %s %s age %s",
+				step.path,
+				rec.Body.String(), rec.Header().Get("X-Cache"), rec.Header().Get("Age"),
+				step.expectedBody, step.expectedStatus, step.expectedAge,
+			)
+		}
+	}
+}
+
+func Test_CacheStaleWhileRevalidate(t *testing.T) {
+	var (
+		clk     = clock.NewFake(time.Now())
+		calls   atomic.Int32
+		started = make(chan struct{}, 1)
+		release = make(chan struct{})
+	)
+
+	handler := AddMiddlewares(
+		http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			n := calls.Add(1)
+			if n > 1 {
+				started <- struct{}{}
+				<-release
+			}
+
+			fmt.Fprintf(w, "v%d", n)
+		}),
+		Cache(time.Minute, WithClock(clk), WithStaleWhileRevalidate(time.Minute)),
+	)
+
+	_ = cacheGet(t, handler, "/")
+
+	clk.Advance(90 * time.Second)
+
+	// The stale entry is served right away while one request refreshes it
+	// in the background.
+	for range 3 {
+		rec := cacheGet(t, handler, "/")
+		if rec.Body.String() != "v1" || rec.Header().Get("X-Cache") != "STALE" {
+			t.Fatalf("expected stale response, got: %s %s", rec.Body.String(), rec.Header().Get("X-Cache"))
+		}
+	}
+
+	<-started
+	close(release)
+
+	for i := 0; ; i++ {
+		rec := cacheGet(t, handler, "/")
+		if rec.Header().Get("X-Cache") == "HIT" {
+			if rec.Body.String() != "v2" {
+				t.Fatalf("expected refreshed response, got: %s", rec.Body.String())
+			}
+
+			break
+		}
+
+		if i == 100 {
+			t.Fatal("expected entry to be refreshed")
+		}
+
+		time.Sleep(time.Millisecond)
+	}
+
+	if calls.Load() != 2 {
+		t.Fatalf("expected a single refresh, got %d calls", calls.Load())
+	}
+}
+
+func Test_CacheStaleIfError(t *testing.T) {
+	var (
+		clk    = clock.NewFake(time.Now())
+		calls  atomic.Int32
+		failed atomic.Bool
+	)
+
+	handler := AddMiddlewares(
+		http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			n := calls.Add(1)
+			if failed.Load() {
+				http.Error(w, http.StatusText(http.StatusBadGateway), http.StatusBadGateway)
+				return
+			}
+
+			w.Header().Set("Cache-Control", "max-age=60, stale-if-error=300")
+			fmt.Fprintf(w, "v%d", n)
+		}),
+		Cache(0, WithClock(clk)),
+	)
+
+	_ = cacheGet(t, handler, "/")
+
+	failed.Store(true)
+	clk.Advance(2 * time.Minute)
+
+	rec := cacheGet(t, handler, "/")
+	if rec.Code != http.StatusOK || rec.Body.String() != "v1" || rec.Header().Get("X-Cache") != "STALE" {
+		t.Fatalf("expected stale response on error, got: %d %s %s", rec.Code, rec.Body.String(), rec.Header().Get("X-Cache"))
+	}
+
+	clk.Advance(5 * time.Minute)
+
+	rec = cacheGet(t, handler, "/")
+	if rec.Code != http.StatusBadGateway {
+		t.Fatalf("expected error after the stale-if-error window, got: %d", rec.Code)
+	}
+}
@@ -0,0 +1,109 @@
+package middleware
+
+import (
+	"hash/fnv"
+	"math/rand/v2"
+	"net/http"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// Variants set by Canary.
+const (
+	VariantStable = "stable"
+	VariantCanary = "canary"
+)
+
+// CanaryPolicy configures the Canary middleware.
+type CanaryPolicy struct {
+	// Percent is the percentage of requests, 0 to 100, routed to the canary.
+	Percent float64
+
+	// Header and Cookie is the header or cookie, e.g. a user or session ID,
+	// used to route requests sticky so the same client always gets the same
+	// variant. The header is used if both are set and requests without it are
+	// routed randomly.
+	Header string
+	Cookie string
+
+	// Name is the name of the canary variant in logs and metrics. Defaults to
+	// VariantCanary.
+	Name string
+}
+
+// Canary routes the percentage of requests in the policy to the canary handler
+// instead of the next handler, to support canary releases and A/B tests in the
+// same process. The variant serving the request is logged by the Logger
+// middleware, if applied before, and counted in the
+// http_variant_requests_total metric registered with WithRegisterer.
+//
+// Requests are routed sticky by a hash of the header or cookie in the policy
+// so increasing the percentage only moves clients from the stable variant to
+// the canary.
+func Canary(canary http.Handler, policy CanaryPolicy, opts ...Option) Middleware {
+	options := newOptions(opts...)
+
+	if policy.Name == "" {
+		policy.Name = VariantCanary
+	}
+
+	counter := registerOrExisting(options.registerer, prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "http_variant_requests_total",
+			Help: "A counter for requests served by each variant.",
+		},
+		[]string{"variant"},
+	))
+
+	return options.skippable(func(h http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			handler, variant := h, VariantStable
+			if policy.routeToCanary(r) {
+				handler, variant = canary, policy.Name
+			}
+
+			rw := NewResponseWriter(w)
+			rw.SetVariant(variant)
+			counter.WithLabelValues(variant).Inc()
+
+			handler.ServeHTTP(rw.WithInterfaces(), r)
+		})
+	})
+}
+
+// routeToCanary returns true if the request should be served by the canary.
+func (p CanaryPolicy) routeToCanary(r *http.Request) bool {
+	if p.Percent <= 0 {
+		return false
+	}
+
+	if p.Percent >= 100 {
+		return true
+	}
+
+	// Buckets of a hundredth of a percent so fractional percentages work.
+	var bucket uint64
+	if key := p.stickyKey(r); key != "" {
+		h := fnv.New64a()
+		_, _ = h.Write([]byte(key))
+		bucket = h.Sum64() % 10000
+	} else {
+		bucket = rand.Uint64N(10000)
+	}
+
+	return float64(bucket) < p.Percent*100
+}
+
+func (p CanaryPolicy) stickyKey(r *http.Request) string {
+	if p.Header != "" {
+		return r.Header.Get(p.Header)
+	}
+
+	if p.Cookie != "" {
+		if cookie, err := r.Cookie(p.Cookie); err == nil {
+			return cookie.Value
+		}
+	}
+
+	return ""
+}
@@ -0,0 +1,128 @@
+package middleware
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+func Test_Canary(t *testing.T) {
+	stable := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = io.WriteString(w, VariantStable)
+	})
+
+	canary := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = io.WriteString(w, VariantCanary)
+	})
+
+	serve := func(h http.Handler, r *http.Request) string {
+		rr := httptest.NewRecorder()
+		h.ServeHTTP(rr, r)
+
+		return rr.Body.String()
+	}
+
+	t.Run("percentages", func(t *testing.T) {
+		for _, tc := range []struct {
+			percent  float64
+			expected string
+		}{
+			{percent: 0, expected: VariantStable},
+			{percent: 100, expected: VariantCanary},
+		} {
+			handler := AddMiddlewares(stable, Canary(canary, CanaryPolicy{Percent: tc.percent}, WithRegisterer(prometheus.NewRegistry())))
+
+			for i := 0; i < 20; i++ {
+				if got := serve(handler, httptest.NewRequest(http.MethodGet, "/", nil)); got != tc.expected {
+					t.Fatalf("unexpected variant with %.0f%%, got: %s", tc.percent, got)
+				}
+			}
+		}
+	})
+
+	t.Run("sticky", func(t *testing.T) {
+		registry := prometheus.NewRegistry()
+		handler := AddMiddlewares(stable, Canary(canary, CanaryPolicy{Percent: 30, Header: "X-User-Id"}, WithRegisterer(registry)))
+
+		counts := map[string]int{}
+
+		for i := 0; i < 1000; i++ {
+			r := httptest.NewRequest(http.MethodGet, "/", nil)
+			r.Header.Set("X-User-Id", fmt.Sprintf("user-%d", i))
+
+			variant := serve(handler, r)
+			counts[variant]++
+
+			for j := 0; j < 3; j++ {
+				if got := serve(handler, r); got != variant {
+					t.Fatalf("variant changed for the same user, got: %s, expected: %s", got, variant)
+				}
+			}
+		}
+
+		if counts[VariantCanary] < 200 || counts[VariantCanary] > 400 {
+			t.Fatalf("expected about 30%% canary requests, got: %v", counts)
+		}
+
+		families, err := registry.Gather()
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		var total float64
+
+		for _, family := range families {
+			if family.GetName() == "http_variant_requests_total" {
+				for _, metric := range family.GetMetric() {
+					total += metric.GetCounter().GetValue()
+				}
+			}
+		}
+
+		if total != 4000 {
+			t.Fatalf("unexpected number of counted requests: %.0f", total)
+		}
+	})
+
+	t.Run("cookie", func(t *testing.T) {
+		policy := CanaryPolicy{Percent: 50, Cookie: "session"}
+
+		r := httptest.NewRequest(http.MethodGet, "/", nil)
+		r.AddCookie(&http.Cookie{Name: "session", Value: "abc"})
+
+		expected := policy.routeToCanary(r)
+		for i := 0; i < 20; i++ {
+			if got := policy.routeToCanary(r); got != expected {
+				t.Fatal("variant changed for the same cookie")
+			}
+		}
+	})
+
+	t.Run("logged", func(t *testing.T) {
+		buf := &bytes.Buffer{}
+
+		handler := AddMiddlewares(
+			stable,
+			Canary(canary, CanaryPolicy{Percent: 100, Name: "v2"}, WithRegisterer(prometheus.NewRegistry())),
+			NewLogger(WithLogger(slog.New(slog.NewJSONHandler(buf, nil)))),
+		)
+
+		_ = serve(handler, httptest.NewRequest(http.MethodGet, "/", nil))
+
+		logged := map[string]interface{}{}
+		if err := json.Unmarshal(buf.Bytes(), &logged); err != nil {
+			t.Fatalf("could not parse logged message: %s", err)
+		}
+
+		if logged["variant"] != "v2" {
+			t.Fatalf("unexpected variant logged: %v", logged["variant"])
+		}
+	})
+}
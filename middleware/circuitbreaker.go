@@ -0,0 +1,69 @@
+package middleware
+
+import (
+	"github.com/bombsimon/http-helpers/client"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// CircuitBreakerMetrics registers metrics for the circuit breaker with the
+// registerer set with WithRegisterer. The state of the breaker for each host
+// is exported as http_client_circuit_breaker_state with one series per state,
+// set to 1 for the current state, the state changes are counted by the state
+// changed to in http_client_circuit_breaker_transitions_total and rejected
+// requests are counted in http_client_circuit_breaker_rejected_total.
+func CircuitBreakerMetrics(breaker *client.CircuitBreaker, opts ...Option) {
+	options := newOptions(opts...)
+
+	options.registerer.MustRegister(&breakerCollector{
+		breaker: breaker,
+		state: prometheus.NewDesc(
+			"http_client_circuit_breaker_state",
+			"The state of the circuit breaker for each host, 1 for the current state.",
+			[]string{"host", "state"}, nil,
+		),
+		transitions: prometheus.NewDesc(
+			"http_client_circuit_breaker_transitions_total",
+			"A counter for state changes of the circuit breaker for each host by the state changed to.",
+			[]string{"host", "state"}, nil,
+		),
+		rejected: prometheus.NewDesc(
+			"http_client_circuit_breaker_rejected_total",
+			"A counter for requests rejected by an open circuit breaker.",
+			[]string{"host"}, nil,
+		),
+	})
+}
+
+// breakerCollector reads the stats of the breaker when scraped.
+type breakerCollector struct {
+	breaker     *client.CircuitBreaker
+	state       *prometheus.Desc
+	transitions *prometheus.Desc
+	rejected    *prometheus.Desc
+}
+
+// Describe implements prometheus.Collector.
+func (c *breakerCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- c.state
+	ch <- c.transitions
+	ch <- c.rejected
+}
+
+// Collect implements prometheus.Collector.
+func (c *breakerCollector) Collect(ch chan<- prometheus.Metric) {
+	for _, stats := range c.breaker.Stats() {
+		for _, state := range []client.BreakerState{client.StateClosed, client.StateOpen, client.StateHalfOpen} {
+			value := 0.0
+			if stats.State == state {
+				value = 1
+			}
+
+			ch <- prometheus.MustNewConstMetric(c.state, prometheus.GaugeValue, value, stats.Host, state.String())
+			ch <- prometheus.MustNewConstMetric(
+				c.transitions, prometheus.CounterValue, float64(stats.Transitions[state]), stats.Host, state.String(),
+			)
+		}
+
+		ch <- prometheus.MustNewConstMetric(c.rejected, prometheus.CounterValue, float64(stats.Rejected), stats.Host)
+	}
+}
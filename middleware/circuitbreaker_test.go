@@ -0,0 +1,43 @@
+package middleware
+
+import (
+	"errors"
+	"net/http"
+	"testing"
+
+	"github.com/bombsimon/http-helpers/client"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+func Test_CircuitBreakerMetrics(t *testing.T) {
+	registry := prometheus.NewRegistry()
+	breaker := client.NewCircuitBreaker(client.BreakerPolicy{FailureThreshold: 1})
+
+	CircuitBreakerMetrics(breaker, WithRegisterer(registry))
+
+	rt := client.WrapTransport(
+		client.RoundTripperFunc(func(r *http.Request) (*http.Response, error) {
+			return nil, errors.New("connection refused")
+		}),
+		breaker.Tripperware(),
+	)
+
+	for range 2 {
+		req, _ := http.NewRequest(http.MethodGet, "http://users.internal/", nil)
+		_, _ = rt.RoundTrip(req)
+	}
+
+	values := gatheredValues(t, registry)
+
+	for key, expected := range map[string]float64{
+		"http_client_circuit_breaker_state,host=users.internal,state=open":               1,
+		"http_client_circuit_breaker_state,host=users.internal,state=closed":             0,
+		"http_client_circuit_breaker_rejected_total,host=users.internal":                 1,
+		"http_client_circuit_breaker_transitions_total,host=users.internal,state=open":   1,
+		"http_client_circuit_breaker_transitions_total,host=users.internal,state=closed": 0,
+	} {
+		if values[key] != expected {
+			t.Fatalf("unexpected value for %s, got: %v, expected: %v", key, values[key], expected)
+		}
+	}
+}
@@ -0,0 +1,75 @@
+package middleware
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/bombsimon/http-helpers/client"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// ClientMetrics returns a client tripperware adding metrics for outgoing
+// requests to prometheus, registered with the registerer set with
+// WithRegisterer. Requests are counted in http_client_requests_total by host,
+// method and code, their latency until the response headers are received is
+// observed in http_client_request_duration_seconds by host, method and status
+// class and requests waiting for a response are tracked in
+// http_client_in_flight_requests by host. Requests failing without a response
+// use "error" as code and status class. Add it before client.Retry to observe
+// each attempt.
+func ClientMetrics(opts ...Option) client.Tripperware {
+	options := newOptions(opts...)
+
+	inFlightGauge := registerOrExisting(options.registerer, prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "http_client_in_flight_requests",
+			Help: "A gauge of outgoing requests currently waiting for a response.",
+		},
+		[]string{"host"},
+	))
+
+	counter := registerOrExisting(options.registerer, prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "http_client_requests_total",
+			Help: "A counter for outgoing requests.",
+		},
+		[]string{"host", "method", "code"},
+	))
+
+	duration := registerOrExisting(options.registerer, prometheus.NewHistogramVec(
+		options.withNativeHistograms(prometheus.HistogramOpts{
+			Name:    "http_client_request_duration_seconds",
+			Help:    "A histogram of latencies for outgoing requests.",
+			Buckets: defaultDurationBuckets,
+		}),
+		[]string{"host", "method", "status_class"},
+	))
+
+	return func(rt http.RoundTripper) http.RoundTripper {
+		return client.RoundTripperFunc(func(r *http.Request) (*http.Response, error) {
+			host := r.URL.Host
+			inFlight := inFlightGauge.WithLabelValues(host)
+
+			inFlight.Inc()
+			startTime := time.Now()
+
+			resp, err := rt.RoundTrip(r)
+
+			elapsed := time.Since(startTime).Seconds()
+			inFlight.Dec()
+
+			code, statusClass := "error", "error"
+			if err == nil {
+				code = strconv.Itoa(resp.StatusCode)
+				statusClass = fmt.Sprintf("%dxx", resp.StatusCode/100)
+			}
+
+			counter.WithLabelValues(host, r.Method, code).Inc()
+			duration.WithLabelValues(host, r.Method, statusClass).Observe(elapsed)
+
+			return resp, err
+		})
+	}
+}
@@ -0,0 +1,64 @@
+package middleware
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/bombsimon/http-helpers/client"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+func Test_ClientMetrics(t *testing.T) {
+	registry := prometheus.NewRegistry()
+
+	rt := client.WrapTransport(
+		client.RoundTripperFunc(func(r *http.Request) (*http.Response, error) {
+			if r.URL.Path == "/fail" {
+				return nil, errors.New("connection refused")
+			}
+
+			return &http.Response{StatusCode: http.StatusNotFound, Body: io.NopCloser(strings.NewReader("")), Request: r}, nil
+		}),
+		ClientMetrics(WithRegisterer(registry)),
+	)
+
+	for _, path := range []string{"/users", "/users", "/fail"} {
+		req, _ := http.NewRequest(http.MethodGet, "http://users.internal"+path, nil)
+		_, _ = rt.RoundTrip(req)
+	}
+
+	families, err := registry.Gather()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	values := map[string]float64{}
+
+	for _, family := range families {
+		for _, metric := range family.GetMetric() {
+			key := family.GetName()
+			for _, label := range metric.GetLabel() {
+				key += "," + label.GetName() + "=" + label.GetValue()
+			}
+
+			values[key] = metric.GetGauge().GetValue() +
+				metric.GetCounter().GetValue() +
+				float64(metric.GetHistogram().GetSampleCount())
+		}
+	}
+
+	for key, expected := range map[string]float64{
+		"http_client_requests_total,code=404,host=users.internal,method=GET":                     2,
+		"http_client_requests_total,code=error,host=users.internal,method=GET":                   1,
+		"http_client_request_duration_seconds,host=users.internal,method=GET,status_class=4xx":   2,
+		"http_client_request_duration_seconds,host=users.internal,method=GET,status_class=error": 1,
+		"http_client_in_flight_requests,host=users.internal":                                     0,
+	} {
+		if got, ok := values[key]; !ok || got != expected {
+			t.Fatalf("unexpected value for %s, got: %v, expected: %v", key, got, expected)
+		}
+	}
+}
@@ -0,0 +1,162 @@
+package middleware
+
+import (
+	"crypto/tls"
+	"net/http"
+	"net/http/httptrace"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/bombsimon/http-helpers/client"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// Phases observed by ClientTraceMetrics.
+const (
+	PhaseDNS          = "dns"
+	PhaseConnect      = "connect"
+	PhaseTLSHandshake = "tls_handshake"
+	PhaseFirstByte    = "first_byte"
+)
+
+// ClientTraceMetrics returns a client tripperware using httptrace to observe
+// the time spent in each phase of outgoing requests in
+// http_client_phase_duration_seconds by host and phase: the DNS lookup, the
+// connection, the TLS handshake and the time until the first response byte.
+// Phases not needed for a request, e.g. when reusing a connection, aren't
+// observed. Connections used are counted in http_client_connections_total by
+// host and whether they were reused, so the reuse ratio can be calculated and
+// slow requests attributed to the right phase. Metrics are registered with the
+// registerer set with WithRegisterer.
+func ClientTraceMetrics(opts ...Option) client.Tripperware {
+	options := newOptions(opts...)
+
+	phases := registerOrExisting(options.registerer, prometheus.NewHistogramVec(
+		options.withNativeHistograms(prometheus.HistogramOpts{
+			Name:    "http_client_phase_duration_seconds",
+			Help:    "A histogram of the time spent in each phase of outgoing requests.",
+			Buckets: []float64{.001, .005, .01, .025, .05, .1, .25, .5, 1, 2.5, 5, 10},
+		}),
+		[]string{"host", "phase"},
+	))
+
+	connections := registerOrExisting(options.registerer, prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "http_client_connections_total",
+			Help: "A counter for connections used by outgoing requests.",
+		},
+		[]string{"host", "reused"},
+	))
+
+	return func(rt http.RoundTripper) http.RoundTripper {
+		return client.RoundTripperFunc(func(r *http.Request) (*http.Response, error) {
+			host := r.URL.Host
+			timings := &phaseTimings{start: time.Now()}
+
+			trace := timings.clientTrace(func(reused bool) {
+				connections.WithLabelValues(host, strconv.FormatBool(reused)).Inc()
+			})
+
+			resp, err := rt.RoundTrip(r.WithContext(httptrace.WithClientTrace(r.Context(), trace)))
+
+			for phase, duration := range timings.durations() {
+				phases.WithLabelValues(host, phase).Observe(duration.Seconds())
+			}
+
+			return resp, err
+		})
+	}
+}
+
+// phaseTimings records the start and end of each phase. The trace hooks may be
+// called from other goroutines, e.g. when dialing.
+type phaseTimings struct {
+	mu    sync.Mutex
+	start time.Time
+	begin map[string]time.Time
+	done  map[string]time.Duration
+}
+
+func (p *phaseTimings) clientTrace(gotConn func(reused bool)) *httptrace.ClientTrace {
+	return &httptrace.ClientTrace{
+		DNSStart: func(httptrace.DNSStartInfo) {
+			p.phaseStart(PhaseDNS)
+		},
+		DNSDone: func(httptrace.DNSDoneInfo) {
+			p.phaseDone(PhaseDNS)
+		},
+		ConnectStart: func(_, _ string) {
+			p.phaseStart(PhaseConnect)
+		},
+		ConnectDone: func(_, _ string, err error) {
+			if err == nil {
+				p.phaseDone(PhaseConnect)
+			}
+		},
+		TLSHandshakeStart: func() {
+			p.phaseStart(PhaseTLSHandshake)
+		},
+		TLSHandshakeDone: func(_ tls.ConnectionState, err error) {
+			if err == nil {
+				p.phaseDone(PhaseTLSHandshake)
+			}
+		},
+		GotConn: func(info httptrace.GotConnInfo) {
+			gotConn(info.Reused)
+		},
+		GotFirstResponseByte: func() {
+			p.mu.Lock()
+			defer p.mu.Unlock()
+
+			p.setDone(PhaseFirstByte, time.Since(p.start))
+		},
+	}
+}
+
+// phaseStart records the first start of the phase, e.g. the first of multiple
+// connection attempts.
+func (p *phaseTimings) phaseStart(phase string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.begin == nil {
+		p.begin = make(map[string]time.Time)
+	}
+
+	if _, ok := p.begin[phase]; !ok {
+		p.begin[phase] = time.Now()
+	}
+}
+
+func (p *phaseTimings) phaseDone(phase string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if begin, ok := p.begin[phase]; ok {
+		p.setDone(phase, time.Since(begin))
+	}
+}
+
+// setDone records the duration of the phase once. The mutex must be held.
+func (p *phaseTimings) setDone(phase string, d time.Duration) {
+	if p.done == nil {
+		p.done = make(map[string]time.Duration)
+	}
+
+	if _, ok := p.done[phase]; !ok {
+		p.done[phase] = d
+	}
+}
+
+func (p *phaseTimings) durations() map[string]time.Duration {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	durations := make(map[string]time.Duration, len(p.done))
+	for phase, d := range p.done {
+		durations[phase] = d
+	}
+
+	return durations
+}
@@ -0,0 +1,73 @@
+package middleware
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/bombsimon/http-helpers/client"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+func Test_ClientTraceMetrics(t *testing.T) {
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = io.WriteString(w, "ok")
+	}))
+	defer server.Close()
+
+	// Use a host name to get a DNS lookup, the certificate is valid for
+	// example.com.
+	url := strings.Replace(server.URL, "127.0.0.1", "localhost", 1)
+	transport := server.Client().Transport.(*http.Transport).Clone()
+	transport.TLSClientConfig.ServerName = "example.com"
+
+	registry := prometheus.NewRegistry()
+	httpClient := &http.Client{
+		Transport: client.WrapTransport(transport, ClientTraceMetrics(WithRegisterer(registry))),
+	}
+
+	for range 3 {
+		resp, err := httpClient.Get(url)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		_, _ = io.ReadAll(resp.Body)
+		resp.Body.Close()
+	}
+
+	families, err := registry.Gather()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	values := map[string]float64{}
+
+	for _, family := range families {
+		for _, metric := range family.GetMetric() {
+			key := family.GetName()
+			for _, label := range metric.GetLabel() {
+				if label.GetName() != "host" {
+					key += "," + label.GetName() + "=" + label.GetValue()
+				}
+			}
+
+			values[key] = metric.GetCounter().GetValue() + float64(metric.GetHistogram().GetSampleCount())
+		}
+	}
+
+	for key, expected := range map[string]float64{
+		"http_client_connections_total,reused=false":             1,
+		"http_client_connections_total,reused=true":              2,
+		"http_client_phase_duration_seconds,phase=connect":       1,
+		"http_client_phase_duration_seconds,phase=tls_handshake": 1,
+		"http_client_phase_duration_seconds,phase=first_byte":    3,
+		"http_client_phase_duration_seconds,phase=dns":           1,
+	} {
+		if values[key] != expected {
+			t.Fatalf("unexpected value for %s, got: %v, expected: %v (%v)", key, values[key], expected, values)
+		}
+	}
+}
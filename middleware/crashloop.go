@@ -0,0 +1,183 @@
+package middleware
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/bombsimon/http-helpers/clock"
+)
+
+// CrashLoopPolicy configures crash loop detection in NewPanicRecovery.
+type CrashLoopPolicy struct {
+	// Threshold is the number of recovered panics for a route within the
+	// window allowed before the route is considered crash looping.
+	Threshold int
+
+	// Window is the period panics are counted in. Defaults to a minute.
+	Window time.Duration
+
+	// TripFor is how long requests to a crash looping route are rejected
+	// with 503 Service Unavailable without calling the handler. The route is
+	// only reported if zero.
+	TripFor time.Duration
+
+	// OnCrashLoop is called when a route starts crash looping, e.g. to page
+	// someone. The event is also logged as an error.
+	OnCrashLoop func(ctx context.Context, event CrashLoopEvent)
+}
+
+// CrashLoopEvent describes a route that started crash looping.
+type CrashLoopEvent struct {
+	// Route is the route, from WithRouteLabel, or the method and path.
+	Route string
+
+	// Panics is the number of panics within the window.
+	Panics int
+
+	// TrippedUntil is when the route accepts requests again, zero if the
+	// policy doesn't trip routes.
+	TrippedUntil time.Time
+}
+
+// WithCrashLoopDetection tracks recovered panics per route in
+// NewPanicRecovery. Set WithRouteLabel to track routes by pattern instead of
+// by method and path.
+func WithCrashLoopDetection(policy CrashLoopPolicy) Option {
+	return func(o *options) {
+		if policy.Window == 0 {
+			policy.Window = time.Minute
+		}
+
+		o.crashLoop = &policy
+	}
+}
+
+type crashLoopDetector struct {
+	policy CrashLoopPolicy
+	clock  clock.Clock
+	route  func(*http.Request) string
+
+	mu        sync.Mutex
+	routes    map[string]*crashLoopRoute
+	lastSweep time.Time
+}
+
+type crashLoopRoute struct {
+	panics       []time.Time
+	looping      bool
+	trippedUntil time.Time
+}
+
+func newCrashLoopDetector(options *options) *crashLoopDetector {
+	route := options.route
+	if route == nil {
+		route = func(r *http.Request) string {
+			return r.Method + " " + r.URL.Path
+		}
+	}
+
+	return &crashLoopDetector{
+		policy:    *options.crashLoop,
+		clock:     options.clock,
+		route:     route,
+		routes:    map[string]*crashLoopRoute{},
+		lastSweep: options.clock.Now(),
+	}
+}
+
+// tripped returns the time left if the route is tripped.
+func (d *crashLoopDetector) tripped(route string) (time.Duration, bool) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	state, ok := d.routes[route]
+	if !ok || state.trippedUntil.IsZero() {
+		return 0, false
+	}
+
+	now := d.clock.Now()
+	if !now.Before(state.trippedUntil) {
+		// Give the route a new chance once the circuit has cooled down.
+		delete(d.routes, route)
+		return 0, false
+	}
+
+	return state.trippedUntil.Sub(now), true
+}
+
+// panicked records a panic for the route and returns an event if the route
+// started crash looping.
+func (d *crashLoopDetector) panicked(route string) (CrashLoopEvent, bool) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	now := d.clock.Now()
+	cutoff := now.Add(-d.policy.Window)
+
+	if now.Sub(d.lastSweep) >= d.policy.Window {
+		d.sweep(now, cutoff)
+	}
+
+	state, ok := d.routes[route]
+	if !ok {
+		state = &crashLoopRoute{}
+		d.routes[route] = state
+	}
+
+	recent := state.panics[:0]
+	for _, t := range state.panics {
+		if t.After(cutoff) {
+			recent = append(recent, t)
+		}
+	}
+
+	state.panics = append(recent, now)
+
+	if len(state.panics) <= d.policy.Threshold {
+		state.looping = false
+		return CrashLoopEvent{}, false
+	}
+
+	if state.looping {
+		return CrashLoopEvent{}, false
+	}
+
+	state.looping = true
+
+	if d.policy.TripFor > 0 {
+		state.trippedUntil = now.Add(d.policy.TripFor)
+	}
+
+	return CrashLoopEvent{
+		Route:        route,
+		Panics:       len(state.panics),
+		TrippedUntil: state.trippedUntil,
+	}, true
+}
+
+// sweep removes the routes without panics within the window that aren't
+// tripped, so routes by path don't grow the map without bound. It's called at
+// most once per window so the cost is spread over the panics in between.
+func (d *crashLoopDetector) sweep(now, cutoff time.Time) {
+	for route, state := range d.routes {
+		if now.Before(state.trippedUntil) {
+			continue
+		}
+
+		if len(state.panics) == 0 || !state.panics[len(state.panics)-1].After(cutoff) {
+			delete(d.routes, route)
+		}
+	}
+
+	d.lastSweep = now
+}
+
+func writeTripped(w http.ResponseWriter, retryAfter time.Duration) {
+	seconds := int((retryAfter + time.Second - 1) / time.Second)
+
+	w.Header().Set("Retry-After", strconv.Itoa(seconds))
+	http.Error(w, http.StatusText(http.StatusServiceUnavailable), http.StatusServiceUnavailable)
+}
@@ -0,0 +1,114 @@
+package middleware
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/bombsimon/http-helpers/clock"
+)
+
+func Test_PanicRecoveryCrashLoop(t *testing.T) {
+	var (
+		clk    = clock.NewFake(time.Now())
+		calls  int
+		events []CrashLoopEvent
+	)
+
+	handler := AddMiddlewares(
+		http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			calls++
+
+			if r.URL.Path == "/broken" {
+				panic("broken")
+			}
+		}),
+		NewPanicRecovery(
+			WithLogger(slog.New(slog.NewTextHandler(io.Discard, nil))),
+			WithClock(clk),
+			WithCrashLoopDetection(CrashLoopPolicy{
+				Threshold: 2,
+				TripFor:   30 * time.Second,
+				OnCrashLoop: func(_ context.Context, event CrashLoopEvent) {
+					events = append(events, event)
+				},
+			}),
+		),
+	)
+
+	get := func(path string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, path, nil))
+
+		return rec
+	}
+
+	// Panics spread out over more than the window aren't a crash loop.
+	for i := 0; i < 3; i++ {
+		get("/broken")
+		clk.Advance(40 * time.Second)
+	}
+
+	if len(events) != 0 {
+		t.Fatalf("unexpected crash loop events: %+v", events)
+	}
+
+	for i := 0; i < 3; i++ {
+		get("/broken")
+	}
+
+	if len(events) != 1 || events[0].Route != "GET /broken" || events[0].Panics != 3 {
+		t.Fatalf("unexpected crash loop events: %+v", events)
+	}
+
+	calls = 0
+
+	rec := get("/broken")
+	if rec.Code != http.StatusServiceUnavailable || calls != 0 {
+		t.Fatalf("expected tripped route, got status: %d, calls: %d", rec.Code, calls)
+	}
+
+	if retryAfter := rec.Header().Get("Retry-After"); retryAfter != "30" {
+		t.Fatalf("unexpected Retry-After, got: %s, expected: 30", retryAfter)
+	}
+
+	if rec := get("/ok"); rec.Code != http.StatusOK || calls != 1 {
+		t.Fatalf("other routes should not be tripped, got status: %d", rec.Code)
+	}
+
+	clk.Advance(30 * time.Second)
+
+	get("/broken")
+
+	if calls != 2 {
+		t.Fatal("expected route to be called again after cooling down")
+	}
+}
+
+func Test_CrashLoopDetectorSweep(t *testing.T) {
+	clk := clock.NewFake(time.Now())
+	detector := newCrashLoopDetector(newOptions(
+		WithClock(clk),
+		WithCrashLoopDetection(CrashLoopPolicy{Threshold: 1, TripFor: 2 * time.Minute}),
+	))
+
+	for _, route := range []string{"GET /a", "GET /b", "GET /tripped", "GET /tripped"} {
+		detector.panicked(route)
+	}
+
+	clk.Advance(time.Minute)
+	detector.panicked("GET /c")
+
+	// Routes without panics within the window are removed unless tripped.
+	if len(detector.routes) != 2 {
+		t.Fatalf("unexpected number of routes, got: %d, expected: %d", len(detector.routes), 2)
+	}
+
+	if _, tripped := detector.tripped("GET /tripped"); !tripped {
+		t.Fatal("expected tripped route to be kept")
+	}
+}
@@ -0,0 +1,158 @@
+package middleware
+
+import (
+	"fmt"
+	"mime"
+	"net/http"
+	"slices"
+	"strings"
+
+	httphelpers "github.com/bombsimon/http-helpers"
+)
+
+// WithRequiredHeaders sets headers Enforce requires on every request, e.g.
+// "X-Api-Version".
+func WithRequiredHeaders(names ...string) Option {
+	return func(o *options) {
+		o.requiredHeaders = append(o.requiredHeaders, names...)
+	}
+}
+
+// WithContentTypes sets the media types Enforce accepts for requests with a
+// body, e.g. "application/json" or "image/*". Parameters such as charset are
+// ignored.
+func WithContentTypes(mediaTypes ...string) Option {
+	return func(o *options) {
+		o.contentTypes = append(o.contentTypes, mediaTypes...)
+	}
+}
+
+// WithMaxURLLength sets the maximum length in bytes of the path and query
+// accepted by Enforce.
+func WithMaxURLLength(n int) Option {
+	return func(o *options) {
+		o.maxURLLength = n
+	}
+}
+
+// WithAllowedMethods sets the methods Enforce allows for paths starting with
+// the prefix. The longest matching prefix wins and HEAD is allowed if GET is.
+func WithAllowedMethods(prefix string, methods ...string) Option {
+	return func(o *options) {
+		if o.allowedMethods == nil {
+			o.allowedMethods = map[string][]string{}
+		}
+
+		o.allowedMethods[prefix] = methods
+	}
+}
+
+// Enforce rejects requests not meeting the requirements set with
+// WithMaxURLLength (414 URI Too Long), WithAllowedMethods (405 Method Not
+// Allowed with the Allow header), WithRequiredHeaders (400 Bad Request) and
+// WithContentTypes (415 Unsupported Media Type) before the handler runs. The
+// rejection is an httphelpers.HTTPError stored with WriteError and rendered by
+// the ErrorHandler middleware, or by httphelpers.DefaultErrorMapper if there's
+// no ErrorHandler in the chain.
+func Enforce(opts ...Option) Middleware {
+	options := newOptions(opts...)
+
+	return options.skippable(func(h http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if err := enforce(w, r, options); err != nil {
+				rw := NewResponseWriter(w)
+				rw.WriteError(err)
+
+				if !rw.errorHandled {
+					writeError(rw, err, httphelpers.DefaultErrorMapper)
+				}
+
+				return
+			}
+
+			h.ServeHTTP(w, r)
+		})
+	})
+}
+
+// enforce returns an error if the request doesn't meet the requirements.
+func enforce(w http.ResponseWriter, r *http.Request, options *options) error {
+	if uri := r.URL.RequestURI(); options.maxURLLength > 0 && len(uri) > options.maxURLLength {
+		return httphelpers.Error(
+			http.StatusRequestURITooLong,
+			fmt.Errorf("URL exceeds %d bytes", options.maxURLLength),
+		)
+	}
+
+	if methods, ok := allowedMethods(r.URL.Path, options.allowedMethods); ok {
+		if slices.Contains(methods, http.MethodGet) && !slices.Contains(methods, http.MethodHead) {
+			methods = append(slices.Clip(methods), http.MethodHead)
+		}
+
+		if !slices.Contains(methods, r.Method) {
+			w.Header().Set("Allow", strings.Join(methods, ", "))
+
+			return httphelpers.Error(
+				http.StatusMethodNotAllowed,
+				fmt.Errorf("method %s not allowed", r.Method),
+			)
+		}
+	}
+
+	for _, name := range options.requiredHeaders {
+		if r.Header.Get(name) == "" {
+			return httphelpers.Error(
+				http.StatusBadRequest,
+				fmt.Errorf("missing required header %s", http.CanonicalHeaderKey(name)),
+			)
+		}
+	}
+
+	if len(options.contentTypes) > 0 && r.ContentLength != 0 {
+		mediaType, _, err := mime.ParseMediaType(r.Header.Get("Content-Type"))
+		if err != nil || !acceptsMediaType(options.contentTypes, mediaType) {
+			return httphelpers.Error(
+				http.StatusUnsupportedMediaType,
+				fmt.Errorf("unsupported content type %q", r.Header.Get("Content-Type")),
+			)
+		}
+	}
+
+	return nil
+}
+
+// allowedMethods returns the methods allowed for the longest prefix matching
+// the path.
+func allowedMethods(path string, byPrefix map[string][]string) ([]string, bool) {
+	var (
+		longest string
+		methods []string
+		found   bool
+	)
+
+	for prefix, m := range byPrefix {
+		if strings.HasPrefix(path, prefix) && (!found || len(prefix) > len(longest)) {
+			longest, methods, found = prefix, m, true
+		}
+	}
+
+	return methods, found
+}
+
+// acceptsMediaType returns true if the media type matches any of the accepted
+// types, which may be wildcards such as "image/*".
+func acceptsMediaType(accepted []string, mediaType string) bool {
+	for _, a := range accepted {
+		if strings.EqualFold(a, mediaType) {
+			return true
+		}
+
+		if prefix, ok := strings.CutSuffix(a, "/*"); ok &&
+			len(mediaType) > len(prefix) &&
+			strings.EqualFold(mediaType[:len(prefix)+1], prefix+"/") {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,133 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	httphelpers "github.com/bombsimon/http-helpers"
+)
+
+func Test_Enforce(t *testing.T) {
+	enforce := Enforce(
+		WithMaxURLLength(32),
+		WithAllowedMethods("/", http.MethodGet, http.MethodPost),
+		WithAllowedMethods("/admin/", http.MethodGet),
+		WithRequiredHeaders("x-api-version"),
+		WithContentTypes("application/json", "image/*"),
+	)
+
+	for _, tc := range []struct {
+		description    string
+		method         string
+		url            string
+		header         http.Header
+		body           string
+		expectedStatus int
+		expectedBody   string
+		expectedAllow  string
+	}{
+		{
+			description:    "valid request",
+			method:         http.MethodPost,
+			url:            "/users",
+			header:         http.Header{"X-Api-Version": {"2"}, "Content-Type": {"application/json; charset=utf-8"}},
+			body:           `{}`,
+			expectedStatus: http.StatusOK,
+		},
+		{
+			description:    "wildcard content type",
+			method:         http.MethodPost,
+			url:            "/avatars",
+			header:         http.Header{"X-Api-Version": {"2"}, "Content-Type": {"image/png"}},
+			body:           "png",
+			expectedStatus: http.StatusOK,
+		},
+		{
+			description:    "no body without content type",
+			method:         http.MethodGet,
+			url:            "/users",
+			header:         http.Header{"X-Api-Version": {"2"}},
+			expectedStatus: http.StatusOK,
+		},
+		{
+			description:    "head allowed with get",
+			method:         http.MethodHead,
+			url:            "/admin/users",
+			header:         http.Header{"X-Api-Version": {"2"}},
+			expectedStatus: http.StatusOK,
+		},
+		{
+			description:    "url too long",
+			method:         http.MethodGet,
+			url:            "/users?filter=" + strings.Repeat("a", 32),
+			expectedStatus: http.StatusRequestURITooLong,
+			expectedBody:   `{"error":"URL exceeds 32 bytes"}`,
+		},
+		{
+			description:    "method not allowed for prefix",
+			method:         http.MethodPost,
+			url:            "/admin/users",
+			expectedStatus: http.StatusMethodNotAllowed,
+			expectedBody:   `{"error":"method POST not allowed"}`,
+			expectedAllow:  "GET, HEAD",
+		},
+		{
+			description:    "missing header",
+			method:         http.MethodGet,
+			url:            "/users",
+			expectedStatus: http.StatusBadRequest,
+			expectedBody:   `{"error":"missing required header X-Api-Version"}`,
+		},
+		{
+			description:    "unsupported content type",
+			method:         http.MethodPost,
+			url:            "/users",
+			header:         http.Header{"X-Api-Version": {"2"}, "Content-Type": {"text/plain"}},
+			body:           "hello",
+			expectedStatus: http.StatusUnsupportedMediaType,
+			expectedBody:   `{"error":"unsupported content type \"text/plain\""}`,
+		},
+	} {
+		t.Run(tc.description, func(t *testing.T) {
+			req := httptest.NewRequest(tc.method, tc.url, strings.NewReader(tc.body))
+
+			for k, v := range tc.header {
+				req.Header[k] = v
+			}
+
+			rec := httptest.NewRecorder()
+			AddMiddlewares(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}), enforce).ServeHTTP(rec, req)
+
+			if rec.Code != tc.expectedStatus {
+				t.Fatalf("unexpected status, got: %d, expected: %d", rec.Code, tc.expectedStatus)
+			}
+
+			if got := strings.TrimSpace(rec.Body.String()); got != tc.expectedBody {
+				t.Fatalf("unexpected body, got: %s, expected: %s", got, tc.expectedBody)
+			}
+
+			if got := rec.Header().Get("Allow"); got != tc.expectedAllow {
+				t.Fatalf("unexpected Allow, got: %s, expected: %s", got, tc.expectedAllow)
+			}
+		})
+	}
+}
+
+func Test_EnforceWithErrorHandler(t *testing.T) {
+	handler := AddMiddlewares(
+		http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}),
+		Enforce(WithRequiredHeaders("X-Api-Version")),
+		ErrorHandler(WithErrorMapper(func(err error) (int, interface{}) {
+			return http.StatusTeapot, httphelpers.ErrorResponse{Error: err.Error()}
+		})),
+	)
+
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+
+	if rec.Code != http.StatusTeapot {
+		t.Fatalf("expected error to be rendered by the error handler, got: %d", rec.Code)
+	}
+}
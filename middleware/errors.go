@@ -0,0 +1,75 @@
+package middleware
+
+import (
+	"errors"
+	"net/http"
+
+	httphelpers "github.com/bombsimon/http-helpers"
+	"github.com/bombsimon/http-helpers/render"
+)
+
+// HandlerFunc is a handler that returns an error instead of writing it to the
+// response. HandlerFunc implements http.Handler, the returned error is stored
+// with WriteError and rendered by the ErrorHandler middleware. If there's no
+// ErrorHandler in the chain the error is rendered with
+// httphelpers.DefaultErrorMapper.
+type HandlerFunc func(w http.ResponseWriter, r *http.Request) error
+
+// ServeHTTP calls fn and stores the returned error, if any.
+func (fn HandlerFunc) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	rw := NewResponseWriter(w)
+
+	err := fn(rw.WithInterfaces(), r)
+	if err == nil {
+		return
+	}
+
+	rw.WriteError(err)
+
+	if !rw.errorHandled {
+		writeError(rw, err, httphelpers.DefaultErrorMapper)
+	}
+}
+
+// ErrorHandler renders errors stored with WriteError, e.g. returned from a
+// HandlerFunc, if nothing has been written to the response yet. Errors are
+// mapped to a status code and body with the errors registered with
+// WithErrorStatus and then the mapper set with WithErrorMapper. The Logger
+// middleware logs the error if it's added before the ErrorHandler.
+func ErrorHandler(opts ...Option) Middleware {
+	options := newOptions(opts...)
+
+	mapper := func(err error) (int, interface{}) {
+		for _, es := range options.errorStatuses {
+			if errors.Is(err, es.target) {
+				return es.status, httphelpers.ErrorResponse{Error: err.Error()}
+			}
+		}
+
+		return options.errorMapper(err)
+	}
+
+	return options.skippable(func(h http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			rw := NewResponseWriter(w)
+			rw.errorHandled = true
+
+			h.ServeHTTP(rw.WithInterfaces(), r)
+
+			if rw.responseError != nil {
+				writeError(rw, rw.responseError, mapper)
+			}
+		})
+	})
+}
+
+// writeError writes the error with the mapper unless the response is already
+// written.
+func writeError(rw *ResponseWriterWithInfo, err error, mapper httphelpers.ErrorMapper) {
+	if rw.Written() {
+		return
+	}
+
+	status, body := mapper(err)
+	_ = render.JSON(rw, status, body)
+}
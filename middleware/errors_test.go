@@ -0,0 +1,144 @@
+package middleware
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	httphelpers "github.com/bombsimon/http-helpers"
+)
+
+var errNotFound = errors.New("not found")
+
+func Test_ErrorHandler(t *testing.T) {
+	cases := []struct {
+		description    string
+		handler        HandlerFunc
+		middlewares    []Middleware
+		expectedStatus int
+		expectedBody   string
+	}{
+		{
+			description: "no error",
+			handler: func(w http.ResponseWriter, r *http.Request) error {
+				w.WriteHeader(http.StatusNoContent)
+				return nil
+			},
+			middlewares:    []Middleware{ErrorHandler()},
+			expectedStatus: http.StatusNoContent,
+		},
+		{
+			description: "registered error",
+			handler: func(w http.ResponseWriter, r *http.Request) error {
+				return errNotFound
+			},
+			middlewares: []Middleware{
+				ErrorHandler(WithErrorStatus(errNotFound, http.StatusNotFound)),
+			},
+			expectedStatus: http.StatusNotFound,
+			expectedBody:   "not found",
+		},
+		{
+			description: "http error with default mapper",
+			handler: func(w http.ResponseWriter, r *http.Request) error {
+				return httphelpers.Error(http.StatusConflict, errors.New("already exists"))
+			},
+			middlewares:    []Middleware{ErrorHandler()},
+			expectedStatus: http.StatusConflict,
+			expectedBody:   "already exists",
+		},
+		{
+			description: "custom mapper",
+			handler: func(w http.ResponseWriter, r *http.Request) error {
+				return errors.New("oops")
+			},
+			middlewares: []Middleware{
+				ErrorHandler(WithErrorMapper(func(err error) (int, interface{}) {
+					return http.StatusTeapot, httphelpers.ErrorResponse{Error: "custom"}
+				})),
+			},
+			expectedStatus: http.StatusTeapot,
+			expectedBody:   "custom",
+		},
+		{
+			description: "unknown error is not exposed",
+			handler: func(w http.ResponseWriter, r *http.Request) error {
+				return errors.New("secret")
+			},
+			middlewares:    []Middleware{ErrorHandler()},
+			expectedStatus: http.StatusInternalServerError,
+			expectedBody:   "Internal Server Error",
+		},
+		{
+			description: "without error handler",
+			handler: func(w http.ResponseWriter, r *http.Request) error {
+				return httphelpers.Error(http.StatusBadRequest, errors.New("bad"))
+			},
+			expectedStatus: http.StatusBadRequest,
+			expectedBody:   "bad",
+		},
+		{
+			description: "response already written",
+			handler: func(w http.ResponseWriter, r *http.Request) error {
+				w.WriteHeader(http.StatusAccepted)
+				return errors.New("oops")
+			},
+			middlewares:    []Middleware{ErrorHandler()},
+			expectedStatus: http.StatusAccepted,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.description, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			handler := AddMiddlewares(tc.handler, tc.middlewares...)
+			handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+
+			if rec.Code != tc.expectedStatus {
+				t.Fatalf("unexpected status, got: %d, expected: %d", rec.Code, tc.expectedStatus)
+			}
+
+			if tc.expectedBody == "" {
+				if rec.Body.Len() > 0 {
+					t.Fatalf("expected no body, got: %s", rec.Body.String())
+				}
+
+				return
+			}
+
+			var body httphelpers.ErrorResponse
+			if err := json.NewDecoder(rec.Body).Decode(&body); err != nil {
+				t.Fatalf("could not decode body: %s", err)
+			}
+
+			if body.Error != tc.expectedBody {
+				t.Fatalf("unexpected body, got: %s, expected: %s", body.Error, tc.expectedBody)
+			}
+		})
+	}
+}
+
+func Test_ErrorHandlerLogged(t *testing.T) {
+	buf := &bytes.Buffer{}
+
+	handler := AddMiddlewares(
+		HandlerFunc(func(w http.ResponseWriter, r *http.Request) error {
+			return errNotFound
+		}),
+		ErrorHandler(WithErrorStatus(errNotFound, http.StatusNotFound)),
+		NewLogger(WithLogger(slog.New(slog.NewTextHandler(buf, nil)))),
+	)
+
+	handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil))
+
+	for _, expected := range []string{"level=ERROR", "status=404", `error="not found"`} {
+		if !strings.Contains(buf.String(), expected) {
+			t.Fatalf("expected %s to be logged, got: %s", expected, buf.String())
+		}
+	}
+}
@@ -0,0 +1,47 @@
+package middleware
+
+import (
+	"net/http"
+	"strings"
+)
+
+// ProbePaths are paths commonly requested by health checks, metric scrapers
+// and browsers rather than users. Use with WithExcludedPaths to keep them out
+// of logs, metrics and traces.
+var ProbePaths = []string{"/healthz", "/readyz", "/livez", "/metrics", "/favicon.ico"}
+
+// WithExcludedPaths skips the middleware for requests with any of the paths.
+// It's meant for Logger, Prometheus and Tracing so probe traffic doesn't
+// pollute logs, metric cardinality and traces, and the same option can be
+// passed to all of them. The paths are added to any paths already excluded.
+func WithExcludedPaths(paths ...string) Option {
+	return func(o *options) {
+		o.excludedPaths = append(o.excludedPaths, paths...)
+	}
+}
+
+// WithExcludedPathPrefixes skips the middleware for requests with a path
+// starting with any of the prefixes, e.g. "/debug/". See WithExcludedPaths.
+func WithExcludedPathPrefixes(prefixes ...string) Option {
+	return func(o *options) {
+		o.excludedPathPrefixes = append(o.excludedPathPrefixes, prefixes...)
+	}
+}
+
+// excluded reports whether the request path is excluded with
+// WithExcludedPaths or WithExcludedPathPrefixes.
+func (o *options) excluded(r *http.Request) bool {
+	for _, path := range o.excludedPaths {
+		if r.URL.Path == path {
+			return true
+		}
+	}
+
+	for _, prefix := range o.excludedPathPrefixes {
+		if strings.HasPrefix(r.URL.Path, prefix) {
+			return true
+		}
+	}
+
+	return false
+}
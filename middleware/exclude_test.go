@@ -0,0 +1,62 @@
+package middleware
+
+import (
+	"bytes"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/bombsimon/http-helpers/tracing"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+func Test_ExcludedPaths(t *testing.T) {
+	var (
+		buf      = &bytes.Buffer{}
+		spans    int
+		registry = prometheus.NewRegistry()
+		excluded = []Option{WithExcludedPaths(ProbePaths...), WithExcludedPathPrefixes("/debug/")}
+	)
+
+	handler := AddMiddlewares(
+		http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}),
+		Tracing(func(tracing.Span) { spans++ }, excluded...),
+		Prometheus(append(excluded, WithRegisterer(registry))...),
+		NewLogger(append(excluded, WithLogger(slog.New(slog.NewJSONHandler(buf, nil))))...),
+	)
+
+	for _, path := range []string{"/healthz", "/metrics", "/favicon.ico", "/debug/pprof/", "/users", "/healthz/deep"} {
+		handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, path, nil))
+	}
+
+	if logged := strings.Count(buf.String(), "request processed"); logged != 2 {
+		t.Fatalf("expected two logged requests, got: %d\n%s", logged, buf.String())
+	}
+
+	if spans != 2 {
+		t.Fatalf("expected two spans, got: %d", spans)
+	}
+
+	families, err := registry.Gather()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var measured float64
+
+	for _, family := range families {
+		if family.GetName() != "http_requests_total" {
+			continue
+		}
+
+		for _, metric := range family.GetMetric() {
+			measured += metric.GetCounter().GetValue()
+		}
+	}
+
+	if measured != 2 {
+		t.Fatalf("expected two measured requests, got: %v", measured)
+	}
+}
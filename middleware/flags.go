@@ -0,0 +1,148 @@
+package middleware
+
+import (
+	"encoding/json"
+	"maps"
+	"net/http"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/bombsimon/http-helpers/httpctx"
+)
+
+// FlagProvider evaluates the feature flags for a request, e.g. based on the
+// tenant or user in the request context.
+type FlagProvider interface {
+	Flags(r *http.Request) (map[string]bool, error)
+}
+
+// FlagProviderFunc is a function used as a FlagProvider.
+type FlagProviderFunc func(r *http.Request) (map[string]bool, error)
+
+// Flags calls the function.
+func (fn FlagProviderFunc) Flags(r *http.Request) (map[string]bool, error) {
+	return fn(r)
+}
+
+// StaticFlags is a FlagProvider with the same flags for all requests.
+type StaticFlags map[string]bool
+
+// Flags returns the static flags.
+func (f StaticFlags) Flags(*http.Request) (map[string]bool, error) {
+	return f, nil
+}
+
+// FileFlags returns a FlagProvider reading the flags from a JSON file with an
+// object of flag names and booleans, e.g. {"new-checkout": true}. The file is
+// read again when it's modified so flags can be toggled without a restart. If
+// the file can't be read the last flags read are returned with the error.
+func FileFlags(path string) FlagProvider {
+	return &fileFlags{path: path}
+}
+
+type fileFlags struct {
+	path    string
+	mu      sync.Mutex
+	modTime time.Time
+	size    int64
+	flags   map[string]bool
+}
+
+func (f *fileFlags) Flags(*http.Request) (map[string]bool, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	info, err := os.Stat(f.path)
+	if err != nil {
+		return f.flags, err
+	}
+
+	if f.flags != nil && info.ModTime().Equal(f.modTime) && info.Size() == f.size {
+		return f.flags, nil
+	}
+
+	data, err := os.ReadFile(f.path)
+	if err != nil {
+		return f.flags, err
+	}
+
+	flags := map[string]bool{}
+	if err := json.Unmarshal(data, &flags); err != nil {
+		return f.flags, err
+	}
+
+	f.flags, f.modTime, f.size = flags, info.ModTime(), info.Size()
+
+	return f.flags, nil
+}
+
+// FeatureFlags evaluates the flags from the provider for each request and
+// stores them in the request context where handlers can read them with
+// httpctx.FlagEnabled. Errors from the provider are logged and the flags
+// returned with the error, if any, are used. All other flags are disabled.
+func FeatureFlags(provider FlagProvider, opts ...Option) Middleware {
+	options := newOptions(opts...)
+
+	return options.skippable(func(h http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			flags := options.evaluateFlags(provider, r)
+
+			h.ServeHTTP(w, r.WithContext(httpctx.WithFlags(r.Context(), flags)))
+		})
+	})
+}
+
+// RequireFlag blocks requests with 404 Not Found unless the flag is enabled so
+// endpoints can be rolled out incrementally. Use WithFlagStatus to return
+// another status, e.g. 403 Forbidden. The flags stored by FeatureFlags are used
+// if it's applied before, otherwise the flags are evaluated with the provider
+// and stored in the request context.
+func RequireFlag(provider FlagProvider, flag string, opts ...Option) Middleware {
+	options := newOptions(opts...)
+
+	return options.skippable(func(h http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			flags, ok := httpctx.Flags(r.Context())
+			if !ok {
+				flags = options.evaluateFlags(provider, r)
+				r = r.WithContext(httpctx.WithFlags(r.Context(), flags))
+			}
+
+			if !flags[flag] {
+				http.Error(w, http.StatusText(options.flagStatus), options.flagStatus)
+				return
+			}
+
+			h.ServeHTTP(w, r)
+		})
+	})
+}
+
+// WithFlagStatus sets the status returned by RequireFlag when the flag is
+// disabled. Defaults to 404 Not Found so disabled endpoints aren't revealed.
+func WithFlagStatus(status int) Option {
+	return func(o *options) {
+		o.flagStatus = status
+	}
+}
+
+// evaluateFlags returns a copy of the flags from the provider so handlers can't
+// modify the flags of other requests.
+func (o *options) evaluateFlags(provider FlagProvider, r *http.Request) map[string]bool {
+	flags, err := provider.Flags(r)
+	if err != nil {
+		o.logger.ErrorContext(
+			r.Context(), "could not evaluate feature flags",
+			"method", r.Method,
+			"path", r.URL.Path,
+			"error", err,
+		)
+	}
+
+	if flags == nil {
+		return map[string]bool{}
+	}
+
+	return maps.Clone(flags)
+}
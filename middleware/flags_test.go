@@ -0,0 +1,152 @@
+package middleware
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/bombsimon/http-helpers/httpctx"
+)
+
+func Test_RequireFlag(t *testing.T) {
+	provider := StaticFlags{"new-checkout": true, "beta": false}
+
+	tests := []struct {
+		name           string
+		flag           string
+		opts           []Option
+		expectedStatus int
+	}{
+		{
+			name:           "enabled",
+			flag:           "new-checkout",
+			expectedStatus: http.StatusOK,
+		},
+		{
+			name:           "disabled",
+			flag:           "beta",
+			expectedStatus: http.StatusNotFound,
+		},
+		{
+			name:           "unknown flag",
+			flag:           "unknown",
+			expectedStatus: http.StatusNotFound,
+		},
+		{
+			name:           "custom status",
+			flag:           "beta",
+			opts:           []Option{WithFlagStatus(http.StatusForbidden)},
+			expectedStatus: http.StatusForbidden,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			handler := AddMiddlewares(
+				http.HandlerFunc(func(http.ResponseWriter, *http.Request) {}),
+				RequireFlag(provider, tc.flag, tc.opts...),
+			)
+
+			rr := httptest.NewRecorder()
+			handler.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/", nil))
+
+			if rr.Code != tc.expectedStatus {
+				t.Fatalf("unexpected status, got: %d, expected: %d", rr.Code, tc.expectedStatus)
+			}
+		})
+	}
+}
+
+func Test_FeatureFlags(t *testing.T) {
+	calls := 0
+	provider := FlagProviderFunc(func(r *http.Request) (map[string]bool, error) {
+		calls++
+		return map[string]bool{"beta": r.Header.Get("X-Tenant") == "acme"}, nil
+	})
+
+	var enabled bool
+
+	handler := AddMiddlewares(
+		http.HandlerFunc(func(_ http.ResponseWriter, r *http.Request) {
+			enabled = httpctx.FlagEnabled(r.Context(), "beta")
+		}),
+		RequireFlag(provider, "beta"),
+		FeatureFlags(provider),
+	)
+
+	r := httptest.NewRequest(http.MethodGet, "/", nil)
+	r.Header.Set("X-Tenant", "acme")
+
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, r)
+
+	if rr.Code != http.StatusOK || !enabled {
+		t.Fatalf("expected flag to be enabled, got status %d", rr.Code)
+	}
+
+	if calls != 1 {
+		t.Fatalf("expected flags to be evaluated once, got: %d", calls)
+	}
+
+	failing := FlagProviderFunc(func(*http.Request) (map[string]bool, error) {
+		return nil, errors.New("provider unavailable")
+	})
+
+	rr = httptest.NewRecorder()
+	AddMiddlewares(http.NotFoundHandler(), RequireFlag(failing, "beta")).
+		ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/", nil))
+
+	if rr.Code != http.StatusNotFound {
+		t.Fatalf("expected flags to be disabled on error, got status %d", rr.Code)
+	}
+}
+
+func Test_FileFlags(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "flags.json")
+
+	if err := os.WriteFile(path, []byte(`{"beta": true}`), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	provider := FileFlags(path)
+	r := httptest.NewRequest(http.MethodGet, "/", nil)
+
+	flags, err := provider.Flags(r)
+	if err != nil || !flags["beta"] {
+		t.Fatalf("unexpected flags: %v, err: %v", flags, err)
+	}
+
+	if err := os.WriteFile(path, []byte(`{"beta": false, "new": true}`), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	// Ensure the modification time changes on file systems with coarse
+	// timestamps.
+	later := time.Now().Add(time.Second)
+	if err := os.Chtimes(path, later, later); err != nil {
+		t.Fatal(err)
+	}
+
+	flags, err = provider.Flags(r)
+	if err != nil || flags["beta"] || !flags["new"] {
+		t.Fatalf("expected flags to be reloaded, got: %v, err: %v", flags, err)
+	}
+
+	if err := os.WriteFile(path, []byte(`{`), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	later = later.Add(time.Second)
+	if err := os.Chtimes(path, later, later); err != nil {
+		t.Fatal(err)
+	}
+
+	flags, err = provider.Flags(r)
+	if err == nil || !flags["new"] {
+		t.Fatalf("expected last flags with error, got: %v, err: %v", flags, err)
+	}
+}
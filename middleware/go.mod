@@ -0,0 +1,27 @@
+module github.com/bombsimon/http-helpers/middleware
+
+go 1.22
+
+require (
+	github.com/bombsimon/http-helpers v0.0.0-20261016131149-d6f19dc9ad2b
+	github.com/prometheus/client_golang v1.22.0
+	github.com/prometheus/client_model v0.6.1
+	golang.org/x/text v0.22.0
+	golang.org/x/time v0.0.0-20220224211638-0e9765cccd65
+)
+
+require (
+	github.com/beorn7/perks v1.0.1 // indirect
+	github.com/cespare/xxhash/v2 v2.3.0 // indirect
+	github.com/munnerz/goautoneg v0.0.0-20191010083416-a7dc8b61c822 // indirect
+	github.com/prometheus/common v0.62.0 // indirect
+	github.com/prometheus/procfs v0.15.1 // indirect
+	golang.org/x/crypto v0.33.0 // indirect
+	golang.org/x/net v0.33.0 // indirect
+	golang.org/x/sys v0.30.0 // indirect
+	google.golang.org/protobuf v1.36.5 // indirect
+)
+
+// Use the parent module from the same checkout during development. Dependents
+// ignore replace directives and get the version required above.
+replace github.com/bombsimon/http-helpers => ../
@@ -0,0 +1,207 @@
+package middleware
+
+import (
+	"net/http"
+	"regexp"
+	"strings"
+)
+
+// HeaderAction is the action a HeaderRule does for the selected headers.
+type HeaderAction int
+
+const (
+	// HeaderSet sets the header named Name to Value, replacing any values.
+	HeaderSet HeaderAction = iota
+
+	// HeaderAdd adds Value to the header named Name.
+	HeaderAdd
+
+	// HeaderRemove removes the selected headers.
+	HeaderRemove
+
+	// HeaderRewrite replaces matches of ValueRegexp in the values of the
+	// selected headers with Value, or the whole values if ValueRegexp is nil.
+	HeaderRewrite
+)
+
+// HeaderRule is a rule applied by HeaderPolicy to the request or response
+// headers. Headers are selected by Name, NamePrefix or NameRegexp.
+type HeaderRule struct {
+	// Response applies the rule to the response headers, right before they're
+	// written, instead of to the request headers.
+	Response bool
+
+	// PathPrefix only applies the rule to requests with paths starting with
+	// the prefix, e.g. "/api/". The rule applies to all requests if empty.
+	PathPrefix string
+
+	// Name selects the header with the name, case insensitive.
+	Name string
+
+	// NamePrefix selects all headers with names starting with the prefix,
+	// case insensitive, e.g. "X-Internal-".
+	NamePrefix string
+
+	// NameRegexp selects all headers with canonical names matching the
+	// pattern.
+	NameRegexp *regexp.Regexp
+
+	// Action is the action done for the selected headers.
+	Action HeaderAction
+
+	// Value is the value set by HeaderSet and HeaderAdd and the replacement,
+	// which may refer to submatches as $1, used by HeaderRewrite.
+	Value string
+
+	// ValueRegexp is the pattern replaced by HeaderRewrite.
+	ValueRegexp *regexp.Regexp
+}
+
+// selects returns true if the rule selects the header with the canonical
+// name.
+func (h HeaderRule) selects(name string) bool {
+	switch {
+	case h.Name != "":
+		return strings.EqualFold(name, h.Name)
+	case h.NamePrefix != "":
+		return len(name) >= len(h.NamePrefix) && strings.EqualFold(name[:len(h.NamePrefix)], h.NamePrefix)
+	case h.NameRegexp != nil:
+		return h.NameRegexp.MatchString(name)
+	default:
+		return false
+	}
+}
+
+// apply applies the rule to the header.
+func (h HeaderRule) apply(header http.Header) {
+	switch h.Action {
+	case HeaderSet:
+		header.Set(h.Name, h.Value)
+		return
+	case HeaderAdd:
+		header.Add(h.Name, h.Value)
+		return
+	}
+
+	for name, values := range header {
+		if !h.selects(name) {
+			continue
+		}
+
+		if h.Action == HeaderRemove {
+			delete(header, name)
+			continue
+		}
+
+		for i, value := range values {
+			if h.ValueRegexp == nil {
+				values[i] = h.Value
+			} else {
+				values[i] = h.ValueRegexp.ReplaceAllString(value, h.Value)
+			}
+		}
+	}
+}
+
+// HeaderPolicy adds, removes and rewrites request and response headers with
+// the rules, in order, e.g. to strip Server and X-Powered-By from responses
+// or to set Cache-Control: no-store for the API.
+//
+//	middleware.HeaderPolicy([]middleware.HeaderRule{
+//		{Response: true, Name: "Server", Action: middleware.HeaderRemove},
+//		{Response: true, Name: "X-Powered-By", Action: middleware.HeaderRemove},
+//		{Response: true, PathPrefix: "/api/", Name: "Cache-Control", Action: middleware.HeaderSet, Value: "no-store"},
+//	})
+func HeaderPolicy(rules []HeaderRule, opts ...Option) Middleware {
+	options := newOptions(opts...)
+
+	return options.skippable(func(h http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			var responseRules []HeaderRule
+
+			for _, rule := range rules {
+				if !strings.HasPrefix(r.URL.Path, rule.PathPrefix) {
+					continue
+				}
+
+				if rule.Response {
+					responseRules = append(responseRules, rule)
+					continue
+				}
+
+				if r.Header == nil {
+					r.Header = http.Header{}
+				}
+
+				rule.apply(r.Header)
+			}
+
+			if len(responseRules) == 0 {
+				h.ServeHTTP(w, r)
+				return
+			}
+
+			rw := NewResponseWriter(w)
+			hw := &headerPolicyWriter{ResponseWriter: rw, rules: responseRules}
+
+			// The handler gets its own ResponseWriterWithInfo writing through
+			// the policy, errors written to it are passed on.
+			inner := NewResponseWriter(hw)
+			h.ServeHTTP(inner.WithInterfaces(), r)
+
+			if err := inner.Err(); err != nil {
+				rw.WriteError(err)
+			}
+
+			// Apply the rules if the handler didn't write anything.
+			hw.applyRules()
+		})
+	})
+}
+
+// headerPolicyWriter applies the rules to the response headers before they're
+// written.
+type headerPolicyWriter struct {
+	http.ResponseWriter
+
+	rules   []HeaderRule
+	applied bool
+}
+
+func (w *headerPolicyWriter) applyRules() {
+	if w.applied {
+		return
+	}
+
+	w.applied = true
+
+	for _, rule := range w.rules {
+		rule.apply(w.Header())
+	}
+}
+
+func (w *headerPolicyWriter) WriteHeader(code int) {
+	if code >= 200 || code == http.StatusSwitchingProtocols {
+		w.applyRules()
+	}
+
+	w.ResponseWriter.WriteHeader(code)
+}
+
+func (w *headerPolicyWriter) Write(b []byte) (int, error) {
+	w.applyRules()
+
+	return w.ResponseWriter.Write(b)
+}
+
+func (w *headerPolicyWriter) Flush() {
+	w.applyRules()
+
+	_ = http.NewResponseController(w.ResponseWriter).Flush()
+}
+
+// Unwrap returns the underlying response writer for http.ResponseController,
+// e.g. to hijack the connection.
+func (w *headerPolicyWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
@@ -0,0 +1,73 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"testing"
+)
+
+func Test_HeaderPolicy(t *testing.T) {
+	var received http.Header
+
+	handler := AddMiddlewares(
+		http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			received = r.Header.Clone()
+
+			w.Header().Set("Server", "nginx/1.25")
+			w.Header().Set("X-Powered-By", "PHP/8.3")
+			w.Header().Set("Cache-Control", "max-age=60")
+			w.Header().Set("Location", "http://internal.example.com/users/1")
+
+			if r.URL.Path != "/empty" {
+				_, _ = w.Write([]byte("ok"))
+			}
+		}),
+		HeaderPolicy([]HeaderRule{
+			{NamePrefix: "x-internal-", Action: HeaderRemove},
+			{Name: "X-Forwarded-Proto", Action: HeaderSet, Value: "https"},
+			{Response: true, NameRegexp: regexp.MustCompile(`^(Server|X-Powered-By)$`), Action: HeaderRemove},
+			{Response: true, PathPrefix: "/api/", Name: "cache-control", Action: HeaderSet, Value: "no-store"},
+			{Response: true, Name: "Vary", Action: HeaderAdd, Value: "Accept"},
+			{
+				Response:    true,
+				Name:        "Location",
+				Action:      HeaderRewrite,
+				ValueRegexp: regexp.MustCompile(`^http://internal\.example\.com`),
+				Value:       "https://example.com",
+			},
+		}),
+	)
+
+	for path, cacheControl := range map[string]string{
+		"/api/users": "no-store",
+		"/users":     "max-age=60",
+		"/empty":     "max-age=60",
+	} {
+		req := httptest.NewRequest(http.MethodGet, path, nil)
+		req.Header.Set("X-Internal-User", "admin")
+		req.Header.Set("X-Internal-Role", "root")
+		req.Header.Set("X-Forwarded-Proto", "http")
+
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+
+		if received.Get("X-Internal-User") != "" || received.Get("X-Internal-Role") != "" || received.Get("X-Forwarded-Proto") != "https" {
+			t.Fatalf("unexpected request headers: %v", received)
+		}
+
+		header := rec.Header()
+
+		if header.Get("Server") != "" || header.Get("X-Powered-By") != "" {
+			t.Fatalf("expected headers to be removed, got: %v", header)
+		}
+
+		if header.Get("Cache-Control") != cacheControl {
+			t.Fatalf("unexpected Cache-Control for %s, got: %s, expected: %s", path, header.Get("Cache-Control"), cacheControl)
+		}
+
+		if header.Get("Vary") != "Accept" || header.Get("Location") != "https://example.com/users/1" {
+			t.Fatalf("unexpected response headers: %v", header)
+		}
+	}
+}
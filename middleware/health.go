@@ -0,0 +1,51 @@
+package middleware
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/bombsimon/http-helpers/render"
+)
+
+// HealthCheck is a named check run by the health handler, e.g. pinging a
+// database.
+type HealthCheck struct {
+	Name  string
+	Check func(ctx context.Context) error
+}
+
+// HealthStatus is the body written by the health handler.
+type HealthStatus struct {
+	Status string            `json:"status"`
+	Checks map[string]string `json:"checks,omitempty"`
+}
+
+// Health returns a handler running all checks with the request context. If
+// all checks pass 200 OK is written, otherwise 503 Service Unavailable. The
+// result of each check is written as JSON.
+func Health(checks ...HealthCheck) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		status := HealthStatus{
+			Status: "ok",
+			Checks: map[string]string{},
+		}
+
+		code := http.StatusOK
+
+		for _, check := range checks {
+			if err := check.Check(r.Context()); err != nil {
+				status.Status = "unavailable"
+				status.Checks[check.Name] = err.Error()
+				code = http.StatusServiceUnavailable
+
+				continue
+			}
+
+			status.Checks[check.Name] = "ok"
+		}
+
+		if err := render.JSON(w, code, status); err != nil {
+			NewResponseWriter(w).WriteError(err)
+		}
+	})
+}
@@ -0,0 +1,42 @@
+package middleware
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func Test_Health(t *testing.T) {
+	var dbErr error
+
+	handler := Health(
+		HealthCheck{Name: "cache", Check: func(_ context.Context) error { return nil }},
+		HealthCheck{Name: "db", Check: func(_ context.Context) error { return dbErr }},
+	)
+
+	for _, tc := range []struct {
+		err            error
+		expectedStatus int
+		expectedDB     string
+	}{
+		{err: nil, expectedStatus: http.StatusOK, expectedDB: "ok"},
+		{err: errors.New("connection refused"), expectedStatus: http.StatusServiceUnavailable, expectedDB: "connection refused"},
+	} {
+		dbErr = tc.err
+
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/healthz", nil))
+
+		var status HealthStatus
+		if err := json.NewDecoder(rec.Body).Decode(&status); err != nil {
+			t.Fatalf("could not decode body: %s", err)
+		}
+
+		if rec.Code != tc.expectedStatus || status.Checks["db"] != tc.expectedDB || status.Checks["cache"] != "ok" {
+			t.Fatalf("unexpected health, status: %d, body: %+v", rec.Code, status)
+		}
+	}
+}
@@ -0,0 +1,129 @@
+package middleware
+
+import (
+	"context"
+	"log/slog"
+	"strconv"
+	"time"
+
+	"github.com/bombsimon/http-helpers/clock"
+	"github.com/bombsimon/http-helpers/kv"
+)
+
+// NewKVLimiterStore returns a LimiterStore keeping the counters in the
+// key-value store, e.g. Redis or memcached, so the limit is enforced across
+// processes. Each key is allowed limit events per fixed window, counted with
+// kv.Store.Increment. Events are allowed, and the error logged, if the store
+// fails or doesn't respond within the timeout set with WithKVTimeout. Use
+// WithClock to test it with a clock.Fake.
+func NewKVLimiterStore(store kv.Store, limit int, window time.Duration, opts ...Option) LimiterStore {
+	options := newOptions(opts...)
+
+	return &kvLimiterStore{
+		store:   store,
+		limit:   int64(limit),
+		window:  window,
+		timeout: options.kvTimeout,
+		clock:   options.clock,
+		logger:  options.logger,
+	}
+}
+
+// WithKVTimeout sets the timeout for calls to the key-value store by
+// NewKVLimiterStore and NewKVNonceStore. Defaults to 500 milliseconds.
+func WithKVTimeout(timeout time.Duration) Option {
+	return func(o *options) {
+		o.kvTimeout = timeout
+	}
+}
+
+type kvLimiterStore struct {
+	store   kv.Store
+	limit   int64
+	window  time.Duration
+	timeout time.Duration
+	clock   clock.Clock
+	logger  *slog.Logger
+}
+
+func (s *kvLimiterStore) Limiter(key string) Limiter {
+	return &kvLimiter{store: s, key: key}
+}
+
+type kvLimiter struct {
+	store *kvLimiterStore
+	key   string
+}
+
+func (l *kvLimiter) Allow() bool {
+	return l.reserve(l.store.clock.Now()) == 0
+}
+
+func (l *kvLimiter) TryAllow() (time.Duration, bool) {
+	retryAfter := l.reserve(l.store.clock.Now())
+	return retryAfter, retryAfter == 0
+}
+
+func (l *kvLimiter) Wait(ctx context.Context) error {
+	return waitFor(ctx, l.store.clock, l.reserve)
+}
+
+// reserve counts the event in the window of now and returns 0 if it's allowed,
+// otherwise how long until the next window.
+func (l *kvLimiter) reserve(now time.Time) time.Duration {
+	var (
+		s     = l.store
+		start = now.Truncate(s.window)
+		key   = "ratelimit:" + l.key + ":" + strconv.FormatInt(start.UnixNano(), 10)
+	)
+
+	// Limiters aren't called with the request context, so bound the call to
+	// not block the request if the store hangs.
+	ctx, cancel := context.WithTimeout(context.Background(), s.timeout)
+	defer cancel()
+
+	n, err := s.store.Increment(ctx, key, 1, s.window)
+	if err != nil {
+		s.logger.Error("could not count rate limited event", "key", l.key, "error", err)
+		return 0
+	}
+
+	if n <= s.limit {
+		return 0
+	}
+
+	return start.Add(s.window).Sub(now)
+}
+
+// NewKVNonceStore returns a NonceStore keeping the nonces in the key-value
+// store, e.g. Redis or memcached, to reject replays across instances. Calls
+// to the store are bounded by the timeout set with WithKVTimeout. Use
+// WithClock to set the clock.
+func NewKVNonceStore(store kv.Store, opts ...Option) NonceStore {
+	options := newOptions(opts...)
+
+	return &kvNonceStore{
+		store:   store,
+		timeout: options.kvTimeout,
+		clock:   options.clock,
+	}
+}
+
+type kvNonceStore struct {
+	store   kv.Store
+	timeout time.Duration
+	clock   clock.Clock
+}
+
+func (s *kvNonceStore) Use(ctx context.Context, nonce string, expires time.Time) (bool, error) {
+	// An expired nonce isn't remembered, like with the in-process store.
+	ttl := expires.Sub(s.clock.Now())
+	if ttl <= 0 {
+		return true, nil
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, s.timeout)
+	defer cancel()
+
+	return s.store.CompareAndSwap(ctx, "nonce:"+nonce, nil, []byte("1"), ttl)
+}
@@ -0,0 +1,101 @@
+package middleware
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"testing"
+	"time"
+
+	"github.com/bombsimon/http-helpers/clock"
+	"github.com/bombsimon/http-helpers/kv"
+)
+
+func Test_KVLimiterStore(t *testing.T) {
+	var (
+		clk   = clock.NewFake(time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC))
+		store = kv.NewMemoryStore(kv.WithClock(clk))
+	)
+
+	// Two stores with the same backend share the limit, like two processes.
+	first := NewKVLimiterStore(store, 2, time.Minute, WithClock(clk))
+	second := NewKVLimiterStore(store, 2, time.Minute, WithClock(clk))
+
+	for i, step := range []struct {
+		advance            time.Duration
+		limiter            LimiterStore
+		key                string
+		expectedAllowed    bool
+		expectedRetryAfter time.Duration
+	}{
+		{limiter: first, key: "a", expectedAllowed: true},
+		{limiter: second, key: "a", expectedAllowed: true},
+		{advance: 20 * time.Second, limiter: first, key: "a", expectedRetryAfter: 40 * time.Second},
+		{limiter: second, key: "b", expectedAllowed: true},
+		{advance: 40 * time.Second, limiter: second, key: "a", expectedAllowed: true},
+	} {
+		clk.Advance(step.advance)
+
+		retryAfter, allowed := step.limiter.Limiter(step.key).(RetryAfterLimiter).TryAllow()
+		if allowed != step.expectedAllowed || retryAfter != step.expectedRetryAfter {
+			t.Fatalf(
+				"unexpected result for step %d, got: %v (%s), expected: %v (%s)",
+				i, allowed, retryAfter, step.expectedAllowed, step.expectedRetryAfter,
+			)
+		}
+	}
+}
+
+func Test_KVNonceStore(t *testing.T) {
+	var (
+		ctx     = context.Background()
+		clk     = clock.NewFake(time.Now())
+		store   = NewKVNonceStore(kv.NewMemoryStore(kv.WithClock(clk)), WithClock(clk))
+		expires = clk.Now().Add(time.Minute)
+	)
+
+	for i, step := range []struct {
+		advance  time.Duration
+		nonce    string
+		expected bool
+	}{
+		{nonce: "a", expected: true},
+		{nonce: "a", expected: false},
+		{nonce: "b", expected: true},
+		{advance: time.Minute, nonce: "a", expected: true},
+	} {
+		clk.Advance(step.advance)
+
+		ok, err := store.Use(ctx, step.nonce, expires)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if ok != step.expected {
+			t.Fatalf("unexpected result for step %d, got: %v, expected: %v", i, ok, step.expected)
+		}
+	}
+}
+
+// hangingStore is a kv.Store not responding until the context is done.
+type hangingStore struct {
+	kv.Store
+}
+
+func (hangingStore) Increment(ctx context.Context, _ string, _ int64, _ time.Duration) (int64, error) {
+	<-ctx.Done()
+	return 0, ctx.Err()
+}
+
+func Test_KVLimiterStoreTimeout(t *testing.T) {
+	store := NewKVLimiterStore(
+		hangingStore{}, 1, time.Minute,
+		WithKVTimeout(10*time.Millisecond),
+		WithLogger(slog.New(slog.NewTextHandler(io.Discard, nil))),
+	)
+
+	// The limiter fails open when the store doesn't respond in time.
+	if !store.Limiter("a").Allow() {
+		t.Fatal("expected event to be allowed when the store times out")
+	}
+}
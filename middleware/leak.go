@@ -0,0 +1,294 @@
+package middleware
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"regexp"
+	"runtime/pprof"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// leakLabel is the pprof label used to attribute goroutines to routes.
+const leakLabel = "http_helpers_route"
+
+var leakLabelRe = regexp.MustCompile(`"` + leakLabel + `":("(?:[^"\\]|\\.)*")`)
+
+// LeakDetector detects goroutines leaked by handlers and response bodies never
+// closed by clients, e.g. during soak tests. Requests are served with a pprof
+// label containing the route which is inherited by all goroutines started by
+// the handler, so goroutines still running after the request is done are
+// reported with the route that started them. Use Transport to track response
+// bodies for a client. Serve the report as JSON by using the LeakDetector as
+// an http.Handler.
+type LeakDetector struct {
+	route    func(*http.Request) string
+	mu       sync.Mutex
+	inFlight map[string]int
+	bodies   map[*trackedBody]struct{}
+}
+
+// LeakReport contains the leaks found by the LeakDetector.
+type LeakReport struct {
+	Goroutines     []GoroutineLeak `json:"goroutines"`
+	UnclosedBodies []BodyLeak      `json:"unclosed_bodies"`
+}
+
+// GoroutineLeak is a number of goroutines with the same stack started by
+// requests to a route that are still running.
+type GoroutineLeak struct {
+	Route string   `json:"route"`
+	Count int      `json:"count"`
+	Stack []string `json:"stack"`
+}
+
+// BodyLeak is a number of response bodies for a URL that are not closed.
+type BodyLeak struct {
+	URL   string `json:"url"`
+	Count int    `json:"count"`
+}
+
+// NewLeakDetector creates a new LeakDetector. Routes are set with
+// WithRouteLabel and defaults to the method and path.
+func NewLeakDetector(opts ...Option) *LeakDetector {
+	options := newOptions(opts...)
+
+	route := options.route
+	if route == nil {
+		route = func(r *http.Request) string {
+			return r.Method + " " + r.URL.Path
+		}
+	}
+
+	return &LeakDetector{
+		route:    route,
+		inFlight: map[string]int{},
+		bodies:   map[*trackedBody]struct{}{},
+	}
+}
+
+// Middleware returns the middleware labeling goroutines with the route.
+func (d *LeakDetector) Middleware() Middleware {
+	return func(h http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			route := d.route(r)
+
+			d.mu.Lock()
+			d.inFlight[route]++
+			d.mu.Unlock()
+
+			defer func() {
+				d.mu.Lock()
+				defer d.mu.Unlock()
+
+				// Remove idle routes so routes with e.g. IDs in the path
+				// don't grow the map forever.
+				d.inFlight[route]--
+				if d.inFlight[route] == 0 {
+					delete(d.inFlight, route)
+				}
+			}()
+
+			pprof.Do(r.Context(), pprof.Labels(leakLabel, route), func(ctx context.Context) {
+				h.ServeHTTP(w, r.WithContext(ctx))
+			})
+		})
+	}
+}
+
+// Transport wraps the round tripper to track response bodies that are never
+// closed. If rt is nil, http.DefaultTransport is used.
+func (d *LeakDetector) Transport(rt http.RoundTripper) http.RoundTripper {
+	if rt == nil {
+		rt = http.DefaultTransport
+	}
+
+	return roundTripperFunc(func(req *http.Request) (*http.Response, error) {
+		resp, err := rt.RoundTrip(req)
+		if err != nil {
+			return resp, err
+		}
+
+		body := &trackedBody{
+			ReadCloser: resp.Body,
+			url:        req.Method + " " + req.URL.String(),
+			detector:   d,
+		}
+
+		d.mu.Lock()
+		d.bodies[body] = struct{}{}
+		d.mu.Unlock()
+
+		// Keep the body writable for protocol upgrades.
+		if rw, ok := resp.Body.(io.ReadWriteCloser); ok {
+			resp.Body = &trackedWritableBody{trackedBody: body, w: rw}
+		} else {
+			resp.Body = body
+		}
+
+		return resp, nil
+	})
+}
+
+// Report returns the leaks found so far. Goroutines of requests still in flight
+// are not reported but goroutines that exit shortly after the request, e.g.
+// asynchronous logging, are so give those time to finish before calling
+// Report.
+func (d *LeakDetector) Report() LeakReport {
+	report := LeakReport{
+		Goroutines:     []GoroutineLeak{},
+		UnclosedBodies: []BodyLeak{},
+	}
+
+	d.mu.Lock()
+
+	inFlight := make(map[string]int, len(d.inFlight))
+	for route, n := range d.inFlight {
+		inFlight[route] = n
+	}
+
+	bodies := map[string]int{}
+	for body := range d.bodies {
+		bodies[body.url]++
+	}
+
+	d.mu.Unlock()
+
+	for _, leak := range labeledGoroutines() {
+		// Skip the goroutines serving requests in flight.
+		if n := inFlight[leak.Route]; n > 0 {
+			skip := n
+			if skip > leak.Count {
+				skip = leak.Count
+			}
+
+			inFlight[leak.Route] -= skip
+			leak.Count -= skip
+		}
+
+		if leak.Count > 0 {
+			report.Goroutines = append(report.Goroutines, leak)
+		}
+	}
+
+	for url, n := range bodies {
+		report.UnclosedBodies = append(report.UnclosedBodies, BodyLeak{URL: url, Count: n})
+	}
+
+	sort.Slice(report.Goroutines, func(i, j int) bool {
+		return report.Goroutines[i].Count > report.Goroutines[j].Count
+	})
+
+	sort.Slice(report.UnclosedBodies, func(i, j int) bool {
+		return report.UnclosedBodies[i].URL < report.UnclosedBodies[j].URL
+	})
+
+	return report
+}
+
+// ServeHTTP writes the report as JSON.
+func (d *LeakDetector) ServeHTTP(w http.ResponseWriter, _ *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(d.Report())
+}
+
+// labeledGoroutines returns the goroutines with the route label from the
+// goroutine profile, grouped by stack.
+func labeledGoroutines() []GoroutineLeak {
+	var buf bytes.Buffer
+	_ = pprof.Lookup("goroutine").WriteTo(&buf, 1)
+
+	var (
+		leaks   []GoroutineLeak
+		current *GoroutineLeak
+	)
+
+	scanner := bufio.NewScanner(&buf)
+	for scanner.Scan() {
+		line := scanner.Text()
+
+		switch {
+		case line == "":
+			current = nil
+		case strings.HasPrefix(line, "# labels: "):
+			m := leakLabelRe.FindStringSubmatch(line)
+			if m == nil || current == nil {
+				current = nil
+				continue
+			}
+
+			route, err := strconv.Unquote(m[1])
+			if err != nil {
+				current = nil
+				continue
+			}
+
+			leaks = append(leaks, GoroutineLeak{Route: route, Count: current.Count})
+			current = &leaks[len(leaks)-1]
+		case strings.HasPrefix(line, "#\t"):
+			if current == nil || current.Route == "" {
+				continue
+			}
+
+			// Frames are formatted as #\t<pc>\t<function>+<offset>\t<file:line>.
+			// The columns may be padded with multiple tabs.
+			fields := strings.FieldsFunc(line, func(r rune) bool { return r == '\t' })
+			if len(fields) >= 4 {
+				function, _, _ := strings.Cut(fields[2], "+")
+				current.Stack = append(current.Stack, function+" "+fields[3])
+			}
+		default:
+			count, _, ok := strings.Cut(line, " @ ")
+			if !ok {
+				continue
+			}
+
+			n, err := strconv.Atoi(count)
+			if err != nil {
+				continue
+			}
+
+			current = &GoroutineLeak{Count: n}
+		}
+	}
+
+	return leaks
+}
+
+type roundTripperFunc func(*http.Request) (*http.Response, error)
+
+func (f roundTripperFunc) RoundTrip(r *http.Request) (*http.Response, error) {
+	return f(r)
+}
+
+type trackedBody struct {
+	io.ReadCloser
+	url      string
+	detector *LeakDetector
+	once     sync.Once
+}
+
+func (b *trackedBody) Close() error {
+	b.once.Do(func() {
+		b.detector.mu.Lock()
+		delete(b.detector.bodies, b)
+		b.detector.mu.Unlock()
+	})
+
+	return b.ReadCloser.Close()
+}
+
+type trackedWritableBody struct {
+	*trackedBody
+	w io.Writer
+}
+
+func (b *trackedWritableBody) Write(p []byte) (int, error) {
+	return b.w.Write(p)
+}
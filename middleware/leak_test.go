@@ -0,0 +1,78 @@
+package middleware
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func Test_LeakDetector(t *testing.T) {
+	var (
+		detector = NewLeakDetector()
+		release  = make(chan struct{})
+	)
+
+	defer close(release)
+
+	handler := AddMiddlewares(
+		http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.URL.Path == "/leak" {
+				go func() {
+					<-release
+				}()
+			}
+		}),
+		detector.Middleware(),
+	)
+
+	ts := httptest.NewServer(handler)
+	defer ts.Close()
+
+	client := &http.Client{Transport: detector.Transport(ts.Client().Transport)}
+
+	for _, path := range []string{"/leak", "/leak", "/ok"} {
+		resp, err := client.Get(ts.URL + path)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		// Forget to close one of the bodies.
+		if path == "/ok" {
+			continue
+		}
+
+		_, _ = io.Copy(io.Discard, resp.Body)
+		resp.Body.Close()
+	}
+
+	// Let the handlers return.
+	time.Sleep(50 * time.Millisecond)
+
+	report := detector.Report()
+
+	if len(report.Goroutines) != 1 {
+		t.Fatalf("unexpected goroutine leaks: %+v", report.Goroutines)
+	}
+
+	leak := report.Goroutines[0]
+	if leak.Route != "GET /leak" || leak.Count != 2 {
+		t.Fatalf("unexpected goroutine leak: %+v", leak)
+	}
+
+	if len(leak.Stack) == 0 || !strings.Contains(leak.Stack[0], "leak_test.go") {
+		t.Fatalf("expected stack to point to the leak, got: %v", leak.Stack)
+	}
+
+	if len(report.UnclosedBodies) != 1 || report.UnclosedBodies[0].URL != "GET "+ts.URL+"/ok" {
+		t.Fatalf("unexpected unclosed bodies: %+v", report.UnclosedBodies)
+	}
+	detector.mu.Lock()
+	defer detector.mu.Unlock()
+
+	if len(detector.inFlight) != 0 {
+		t.Fatalf("expected routes without requests in flight to be removed, got: %v", detector.inFlight)
+	}
+}
@@ -0,0 +1,174 @@
+package middleware
+
+import (
+	"context"
+	"math"
+	"sync"
+	"time"
+
+	"github.com/bombsimon/http-helpers/clock"
+)
+
+// NewSlidingWindowLimiter returns a Limiter allowing limit events per window
+// with a sliding window counter. The count of the previous fixed window is
+// weighted by how much of it still overlaps the sliding window, so unlike a
+// fixed window the limit can't be exceeded by bursts around the window
+// boundary, and unlike a sliding window log it only keeps two counters. Use
+// WithClock to test it with a clock.Fake. It panics if window isn't positive.
+func NewSlidingWindowLimiter(limit int, window time.Duration, opts ...Option) Limiter {
+	if window <= 0 {
+		panic("middleware: non-positive window for NewSlidingWindowLimiter")
+	}
+
+	return &slidingWindowLimiter{
+		limit:  limit,
+		window: window,
+		clock:  newOptions(opts...).clock,
+	}
+}
+
+type slidingWindowLimiter struct {
+	limit  int
+	window time.Duration
+	clock  clock.Clock
+
+	mu       sync.Mutex
+	start    time.Time
+	previous int
+	current  int
+}
+
+func (l *slidingWindowLimiter) Allow() bool {
+	return l.reserve(l.clock.Now()) == 0
+}
+
+func (l *slidingWindowLimiter) TryAllow() (time.Duration, bool) {
+	retryAfter := l.reserve(l.clock.Now())
+	return retryAfter, retryAfter == 0
+}
+
+func (l *slidingWindowLimiter) Wait(ctx context.Context) error {
+	return waitFor(ctx, l.clock, l.reserve)
+}
+
+// reserve counts the event and returns 0 if it's allowed at now, otherwise
+// it returns how long to wait before trying again.
+func (l *slidingWindowLimiter) reserve(now time.Time) time.Duration {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.start.IsZero() {
+		l.start = now.Truncate(l.window)
+	}
+
+	switch windows := now.Sub(l.start) / l.window; {
+	case windows == 1:
+		l.previous, l.current = l.current, 0
+		l.start = l.start.Add(l.window)
+	case windows > 1:
+		l.previous, l.current = 0, 0
+		l.start = l.start.Add(windows * l.window)
+	}
+
+	elapsed := now.Sub(l.start)
+	overlap := 1 - float64(elapsed)/float64(l.window)
+
+	if float64(l.previous)*overlap+float64(l.current+1) <= float64(l.limit) {
+		l.current++
+		return 0
+	}
+
+	if l.current >= l.limit || l.previous == 0 {
+		return l.window - elapsed
+	}
+
+	// Wait until enough of the previous window has slid out.
+	slid := 1 - float64(l.limit-l.current-1)/float64(l.previous)
+
+	return max(time.Duration(math.Ceil(slid*float64(l.window)))-elapsed, 1)
+}
+
+// NewGCRALimiter returns a Limiter allowing one event per interval with bursts
+// of up to burst events, using the generic cell rate algorithm (GCRA), i.e. a
+// leaky bucket as a meter. Events are spread out evenly instead of refilling a
+// bucket of tokens, and the only state is a single timestamp, see GCRA. Use
+// WithClock to test it with a clock.Fake.
+func NewGCRALimiter(interval time.Duration, burst int, opts ...Option) Limiter {
+	return &gcraLimiter{
+		interval: interval,
+		burst:    burst,
+		clock:    newOptions(opts...).clock,
+	}
+}
+
+type gcraLimiter struct {
+	interval time.Duration
+	burst    int
+	clock    clock.Clock
+
+	mu  sync.Mutex
+	tat time.Time
+}
+
+func (l *gcraLimiter) Allow() bool {
+	return l.reserve(l.clock.Now()) == 0
+}
+
+func (l *gcraLimiter) TryAllow() (time.Duration, bool) {
+	retryAfter := l.reserve(l.clock.Now())
+	return retryAfter, retryAfter == 0
+}
+
+func (l *gcraLimiter) Wait(ctx context.Context) error {
+	return waitFor(ctx, l.clock, l.reserve)
+}
+
+func (l *gcraLimiter) reserve(now time.Time) time.Duration {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	tat, retryAfter, ok := GCRA(l.tat, now, l.interval, l.burst)
+	if ok {
+		l.tat = tat
+	}
+
+	return retryAfter
+}
+
+// GCRA runs the generic cell rate algorithm for an event at now with the
+// theoretical arrival time tat from the previous event, the zero time for the
+// first one. If the event is allowed it returns the new theoretical arrival
+// time to store and true, otherwise how long to wait before it's allowed. A
+// LimiterStore shared between processes can store tat per key, e.g. in Redis
+// with a compare-and-set, to enforce the same limit as NewGCRALimiter.
+func GCRA(tat, now time.Time, interval time.Duration, burst int) (time.Time, time.Duration, bool) {
+	if tat.Before(now) {
+		tat = now
+	}
+
+	if wait := tat.Sub(now) - interval*time.Duration(burst-1); wait > 0 {
+		return tat, wait, false
+	}
+
+	return tat.Add(interval), 0, true
+}
+
+// waitFor calls reserve until the event is allowed, waiting the returned
+// duration between each call, or until the context is done.
+func waitFor(ctx context.Context, c clock.Clock, reserve func(now time.Time) time.Duration) error {
+	for {
+		delay := reserve(c.Now())
+		if delay <= 0 {
+			return nil
+		}
+
+		timer := c.NewTimer(delay)
+
+		select {
+		case <-timer.C():
+		case <-ctx.Done():
+			timer.Stop()
+			return ctx.Err()
+		}
+	}
+}
@@ -0,0 +1,116 @@
+package middleware
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/bombsimon/http-helpers/clock"
+)
+
+func Test_SlidingWindowLimiter(t *testing.T) {
+	clk := clock.NewFake(time.Date(2024, time.May, 1, 12, 0, 0, 0, time.UTC))
+	limiter := NewSlidingWindowLimiter(2, time.Second, WithClock(clk))
+
+	if !limiter.Allow() || !limiter.Allow() || limiter.Allow() {
+		t.Fatal("expected two events to be allowed in the window")
+	}
+
+	// The whole previous window still overlaps the sliding window.
+	clk.Advance(time.Second)
+
+	if limiter.Allow() {
+		t.Fatal("expected event to be limited by the previous window")
+	}
+
+	clk.Advance(500 * time.Millisecond)
+
+	if !limiter.Allow() || limiter.Allow() {
+		t.Fatal("expected one event to be allowed with half of the previous window left")
+	}
+
+	errCh := make(chan error, 1)
+
+	go func() {
+		errCh <- limiter.Wait(context.Background())
+	}()
+
+	clk.BlockUntil(1)
+	clk.Advance(500 * time.Millisecond)
+
+	if err := <-errCh; err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	clk.Advance(10 * time.Second)
+
+	if !limiter.Allow() || !limiter.Allow() {
+		t.Fatal("expected limit to be reset after idle windows")
+	}
+}
+
+func Test_SlidingWindowLimiterInvalidWindow(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Fatal("expected panic for a zero window")
+		}
+	}()
+
+	_ = NewSlidingWindowLimiter(10, 0)
+}
+
+func Test_GCRALimiter(t *testing.T) {
+	clk := clock.NewFake(time.Now())
+	limiter := NewGCRALimiter(time.Second, 2, WithClock(clk))
+
+	if !limiter.Allow() || !limiter.Allow() || limiter.Allow() {
+		t.Fatal("expected burst of two events to be allowed")
+	}
+
+	// Events leak out evenly, one per interval.
+	clk.Advance(time.Second)
+
+	if !limiter.Allow() || limiter.Allow() {
+		t.Fatal("expected one event to be allowed after an interval")
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	if err := limiter.Wait(ctx); err == nil {
+		t.Fatal("expected canceled wait to fail")
+	}
+
+	now := time.Now()
+
+	tat, retryAfter, ok := GCRA(time.Time{}, now, time.Second, 1)
+	if !ok || retryAfter != 0 || !tat.Equal(now.Add(time.Second)) {
+		t.Fatalf("unexpected result for first event: %s, %s, %t", tat, retryAfter, ok)
+	}
+
+	if _, retryAfter, ok := GCRA(tat, now.Add(400*time.Millisecond), time.Second, 1); ok || retryAfter != 600*time.Millisecond {
+		t.Fatalf("unexpected result for limited event: %s, %t", retryAfter, ok)
+	}
+}
+
+func Test_KeyedLimiterStore(t *testing.T) {
+	clk := clock.NewFake(time.Now())
+	store := NewKeyedLimiterStore(func() Limiter {
+		return NewGCRALimiter(time.Second, 1, WithClock(clk))
+	}, WithClock(clk), WithLimiterIdleTimeout(time.Minute))
+
+	if !store.Limiter("a").Allow() || store.Limiter("a").Allow() {
+		t.Fatal("expected limiter to be shared for the same key")
+	}
+
+	if !store.Limiter("b").Allow() {
+		t.Fatal("expected separate limiter for another key")
+	}
+
+	clk.Advance(time.Minute)
+	store.Limiter("c")
+
+	if n := len(store.(*limiterStore).limiters); n != 1 {
+		t.Fatalf("unexpected number of limiters after the idle timeout, got: %d, expected: %d", n, 1)
+	}
+}
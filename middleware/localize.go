@@ -0,0 +1,47 @@
+package middleware
+
+import (
+	"net/http"
+
+	"golang.org/x/text/language"
+
+	"github.com/bombsimon/http-helpers/httpctx"
+)
+
+// Localize picks the response language from the supported languages based on
+// the Accept-Language header and stores it in the request context, available
+// with httpctx.Language and, as a string such as "en-US", with
+// httpctx.Locale. The language is set in the Content-Language response header.
+// The first supported language is used if the header is missing or doesn't
+// match any of them.
+func Localize(supported []language.Tag, opts ...Option) Middleware {
+	options := newOptions(opts...)
+
+	if len(supported) == 0 {
+		supported = []language.Tag{language.English}
+	}
+
+	matcher := language.NewMatcher(supported)
+
+	return options.skippable(func(h http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Add("Vary", "Accept-Language")
+
+			accepted, _, err := language.ParseAcceptLanguage(r.Header.Get("Accept-Language"))
+
+			tag := supported[0]
+			if err == nil && len(accepted) > 0 {
+				// Use the supported tag instead of the matched one, which may
+				// have extensions such as the region of the accepted tag.
+				_, index, confidence := matcher.Match(accepted...)
+				if confidence != language.No {
+					tag = supported[index]
+				}
+			}
+
+			w.Header().Set("Content-Language", tag.String())
+
+			h.ServeHTTP(w, r.WithContext(httpctx.WithLanguage(r.Context(), tag)))
+		})
+	})
+}
@@ -0,0 +1,55 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"golang.org/x/text/language"
+
+	"github.com/bombsimon/http-helpers/httpctx"
+)
+
+func Test_Localize(t *testing.T) {
+	handler := AddMiddlewares(
+		http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			tag, _ := httpctx.Language(r.Context())
+			locale, _ := httpctx.Locale(r.Context())
+
+			if tag.String() != locale {
+				t.Errorf("expected locale %s to match the language %s", locale, tag)
+			}
+
+			_, _ = w.Write([]byte(tag.String()))
+		}),
+		Localize([]language.Tag{language.AmericanEnglish, language.Swedish, language.German}),
+	)
+
+	for acceptLanguage, expected := range map[string]string{
+		"":                          "en-US",
+		"sv":                        "sv",
+		"sv-FI":                     "sv",
+		"fr-CH, fr;q=0.9, de;q=0.7": "de",
+		"en-GB":                     "en-US",
+		"ja":                        "en-US",
+		"invalid;;q=x":              "en-US",
+	} {
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		req.Header.Set("Accept-Language", acceptLanguage)
+
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+
+		if rec.Body.String() != expected {
+			t.Fatalf("unexpected language for %q, got: %s, expected: %s", acceptLanguage, rec.Body.String(), expected)
+		}
+
+		if rec.Header().Get("Content-Language") != expected {
+			t.Fatalf("unexpected Content-Language for %q: %s", acceptLanguage, rec.Header().Get("Content-Language"))
+		}
+
+		if rec.Header().Get("Vary") != "Accept-Language" {
+			t.Fatalf("expected Vary header, got: %s", rec.Header().Get("Vary"))
+		}
+	}
+}
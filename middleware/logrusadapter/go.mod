@@ -0,0 +1,7 @@
+module github.com/bombsimon/http-helpers/middleware/logrusadapter
+
+go 1.22
+
+require github.com/sirupsen/logrus v1.8.1
+
+require golang.org/x/sys v0.30.0 // indirect
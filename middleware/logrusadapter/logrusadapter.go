@@ -0,0 +1,131 @@
+// Package logrusadapter adapts a logrus logger to log/slog so it can be used
+// with the middlewares, the client and the server. It's a separate module so
+// the middleware module doesn't depend on logrus.
+//
+//	logger := logrusadapter.New(logrus.StandardLogger())
+//
+//	handler := middleware.AddMiddlewares(
+//		router,
+//		middleware.NewPanicRecovery(middleware.WithLogger(logger)),
+//		middleware.NewLogger(middleware.WithLogger(logger)),
+//	)
+package logrusadapter
+
+import (
+	"context"
+	"log/slog"
+
+	"github.com/sirupsen/logrus"
+)
+
+// New returns a *slog.Logger writing records to the passed logrus logger.
+func New(logger logrus.FieldLogger) *slog.Logger {
+	return slog.New(NewHandler(logger))
+}
+
+// NewHandler returns a slog.Handler writing records to the passed logrus
+// logger. Attributes are added as logrus fields, with groups flattened into
+// dot separated keys.
+func NewHandler(logger logrus.FieldLogger) slog.Handler {
+	return &logrusHandler{logger: logger, fields: logrus.Fields{}}
+}
+
+type logrusHandler struct {
+	logger logrus.FieldLogger
+	fields logrus.Fields
+	group  string
+}
+
+func (h *logrusHandler) Enabled(_ context.Context, level slog.Level) bool {
+	switch l := h.logger.(type) {
+	case *logrus.Logger:
+		return l.IsLevelEnabled(logrusLevel(level))
+	case *logrus.Entry:
+		return l.Logger.IsLevelEnabled(logrusLevel(level))
+	default:
+		return true
+	}
+}
+
+func (h *logrusHandler) Handle(_ context.Context, r slog.Record) error {
+	fields := make(logrus.Fields, len(h.fields)+r.NumAttrs())
+	for k, v := range h.fields {
+		fields[k] = v
+	}
+
+	r.Attrs(func(attr slog.Attr) bool {
+		addField(fields, h.group, attr)
+		return true
+	})
+
+	entry := h.logger.WithFields(fields)
+
+	switch logrusLevel(r.Level) {
+	case logrus.DebugLevel:
+		entry.Debug(r.Message)
+	case logrus.WarnLevel:
+		entry.Warn(r.Message)
+	case logrus.ErrorLevel:
+		entry.Error(r.Message)
+	default:
+		entry.Info(r.Message)
+	}
+
+	return nil
+}
+
+func (h *logrusHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	fields := make(logrus.Fields, len(h.fields)+len(attrs))
+	for k, v := range h.fields {
+		fields[k] = v
+	}
+
+	for _, attr := range attrs {
+		addField(fields, h.group, attr)
+	}
+
+	return &logrusHandler{logger: h.logger, fields: fields, group: h.group}
+}
+
+func (h *logrusHandler) WithGroup(name string) slog.Handler {
+	if h.group != "" {
+		name = h.group + "." + name
+	}
+
+	return &logrusHandler{logger: h.logger, fields: h.fields, group: name}
+}
+
+func addField(fields logrus.Fields, group string, attr slog.Attr) {
+	attr.Value = attr.Value.Resolve()
+	if attr.Equal(slog.Attr{}) {
+		return
+	}
+
+	key := attr.Key
+	if group != "" {
+		key = group + "." + key
+	}
+
+	if attr.Value.Kind() == slog.KindGroup {
+		for _, a := range attr.Value.Group() {
+			addField(fields, key, a)
+		}
+
+		return
+	}
+
+	fields[key] = attr.Value.Any()
+}
+
+func logrusLevel(level slog.Level) logrus.Level {
+	switch {
+	case level >= slog.LevelError:
+		return logrus.ErrorLevel
+	case level >= slog.LevelWarn:
+		return logrus.WarnLevel
+	case level >= slog.LevelInfo:
+		return logrus.InfoLevel
+	default:
+		return logrus.DebugLevel
+	}
+}
@@ -0,0 +1,43 @@
+package logrusadapter
+
+import (
+	"bytes"
+	"encoding/json"
+	"log/slog"
+	"testing"
+
+	"github.com/sirupsen/logrus"
+)
+
+func Test_New(t *testing.T) {
+	var (
+		buf    = &bytes.Buffer{}
+		logger = logrus.New()
+	)
+
+	logger.SetOutput(buf)
+	logger.SetLevel(logrus.InfoLevel)
+	logger.Formatter = &logrus.JSONFormatter{}
+
+	log := New(logger).With("service", "api").WithGroup("request")
+
+	log.Debug("not logged")
+	log.Warn("request processed", slog.Int("status", 404), slog.Group("client", slog.String("ip", "10.0.0.1")))
+
+	var logged map[string]any
+	if err := json.Unmarshal(buf.Bytes(), &logged); err != nil {
+		t.Fatalf("expected a single JSON entry, got: %s", buf.String())
+	}
+
+	for k, v := range map[string]any{
+		"msg":               "request processed",
+		"level":             "warning",
+		"service":           "api",
+		"request.status":    float64(404),
+		"request.client.ip": "10.0.0.1",
+	} {
+		if logged[k] != v {
+			t.Fatalf("unexpected %s, got: %v, expected: %v", k, logged[k], v)
+		}
+	}
+}
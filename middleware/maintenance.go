@@ -0,0 +1,89 @@
+package middleware
+
+import (
+	"net/http"
+	"strconv"
+	"sync/atomic"
+
+	"github.com/bombsimon/http-helpers/render"
+)
+
+// MaintenanceStatus is the body written by the maintenance handler.
+type MaintenanceStatus struct {
+	Enabled bool `json:"enabled"`
+}
+
+// Maintenance is a toggle for maintenance mode. While enabled, the middleware
+// returned by Middleware responds 503 Service Unavailable to all requests not
+// skipped with WithSkipFunc. Toggle it with Enable and Disable or use
+// Maintenance as an http.Handler on an admin server.
+type Maintenance struct {
+	enabled atomic.Bool
+	options *options
+}
+
+// NewMaintenance creates a new Maintenance, disabled. Set the Retry-After
+// header sent while in maintenance mode with WithRetryAfter, it defaults to 60
+// seconds.
+func NewMaintenance(opts ...Option) *Maintenance {
+	return &Maintenance{options: newOptions(opts...)}
+}
+
+// Enable enables maintenance mode.
+func (m *Maintenance) Enable() {
+	if !m.enabled.Swap(true) {
+		m.options.logger.Info("maintenance mode enabled")
+	}
+}
+
+// Disable disables maintenance mode.
+func (m *Maintenance) Disable() {
+	if m.enabled.Swap(false) {
+		m.options.logger.Info("maintenance mode disabled")
+	}
+}
+
+// Enabled returns true if maintenance mode is enabled.
+func (m *Maintenance) Enabled() bool {
+	return m.enabled.Load()
+}
+
+// Middleware returns the middleware rejecting requests while maintenance mode
+// is enabled.
+func (m *Maintenance) Middleware() Middleware {
+	return m.options.skippable(func(h http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if !m.Enabled() {
+				h.ServeHTTP(w, r)
+				return
+			}
+
+			if m.options.retryAfter > 0 {
+				w.Header().Set("Retry-After", strconv.Itoa(int(m.options.retryAfter.Seconds())))
+			}
+
+			http.Error(w, http.StatusText(http.StatusServiceUnavailable), http.StatusServiceUnavailable)
+		})
+	})
+}
+
+// ServeHTTP writes the state as a MaintenanceStatus. PUT enables and DELETE
+// disables maintenance mode.
+func (m *Maintenance) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet, http.MethodHead:
+	case http.MethodPut:
+		m.Enable()
+	case http.MethodDelete:
+		m.Disable()
+	default:
+		w.Header().Set("Allow", "GET, HEAD, PUT, DELETE")
+		http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+
+		return
+	}
+
+	if err := render.JSON(w, http.StatusOK, MaintenanceStatus{Enabled: m.Enabled()}); err != nil {
+		NewResponseWriter(w).WriteError(err)
+	}
+}
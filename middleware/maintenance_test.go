@@ -0,0 +1,56 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func Test_Maintenance(t *testing.T) {
+	maintenance := NewMaintenance(WithSkipFunc(func(r *http.Request) bool {
+		return r.URL.Path == "/healthz"
+	}))
+
+	handler := maintenance.Middleware()(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	}))
+
+	serve := func(h http.Handler, method, path string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, httptest.NewRequest(method, path, nil))
+
+		return rec
+	}
+
+	for _, tc := range []struct {
+		toggle           string
+		path             string
+		expectedStatus   int
+		expectedEnabled  string
+		expectRetryAfter bool
+	}{
+		{toggle: http.MethodGet, path: "/", expectedStatus: http.StatusNoContent, expectedEnabled: `"enabled":false`},
+		{toggle: http.MethodPut, path: "/", expectedStatus: http.StatusServiceUnavailable, expectedEnabled: `"enabled":true`, expectRetryAfter: true},
+		{toggle: http.MethodPut, path: "/healthz", expectedStatus: http.StatusNoContent, expectedEnabled: `"enabled":true`},
+		{toggle: http.MethodDelete, path: "/", expectedStatus: http.StatusNoContent, expectedEnabled: `"enabled":false`},
+	} {
+		toggled := serve(maintenance, tc.toggle, "/maintenance")
+		if toggled.Code != http.StatusOK || !strings.Contains(toggled.Body.String(), tc.expectedEnabled) {
+			t.Fatalf("unexpected toggle response for %s: %d %s", tc.toggle, toggled.Code, toggled.Body.String())
+		}
+
+		rec := serve(handler, http.MethodGet, tc.path)
+		if rec.Code != tc.expectedStatus {
+			t.Fatalf("unexpected status for %s, got: %d, expected: %d", tc.path, rec.Code, tc.expectedStatus)
+		}
+
+		if got := rec.Header().Get("Retry-After"); (got == "60") != tc.expectRetryAfter {
+			t.Fatalf("unexpected Retry-After: %q", got)
+		}
+	}
+
+	if rec := serve(maintenance, http.MethodPost, "/maintenance"); rec.Code != http.StatusMethodNotAllowed {
+		t.Fatalf("unexpected status for POST, got: %d, expected: %d", rec.Code, http.StatusMethodNotAllowed)
+	}
+}
@@ -6,214 +6,324 @@ middlewares. Example usage with gorilla/mux:
 
 	func main() {
 		router := mux.NewRouter()
-		logger := logrus.New()
+		logger := slog.Default()
 
 		handers := middleware.AddMiddlewares(
 			router,
-			middleware.PanicRecovery(logger),
-			middleware.Logger(logger),
+			middleware.NewPanicRecovery(middleware.WithLogger(logger)),
+			middleware.NewLogger(middleware.WithLogger(logger)),
 		)
 
 		if err := http.ListenAndServe(":4080", handlers); err != nil {
-			logger.WithError(err).Error("could not start server...")
+			logger.Error("could not start server...", "error", err)
 		}
 	}
 */
 
 import (
-	"fmt"
+	"context"
+	"log/slog"
 	"net/http"
-	"strconv"
-	"sync"
 	"time"
 
-	"golang.org/x/time/rate"
-
+	"github.com/bombsimon/http-helpers/chain"
+	"github.com/bombsimon/http-helpers/httpctx"
 	"github.com/prometheus/client_golang/prometheus"
-	"github.com/prometheus/client_golang/prometheus/promauto"
-	"github.com/prometheus/client_golang/prometheus/promhttp"
-	"github.com/sirupsen/logrus"
 )
 
-// ResponseWriterWithInfo is a response writer that can hold additional
-// information which can help enrich code executed as middlewares.
-type ResponseWriterWithInfo struct {
-	http.ResponseWriter
-	statusCode    int
-	responseError error
-}
-
-// NewResponseWriter will convert the response writer to a
-// *ResponseWriterWithInfo if it is one, otherwise wrap the response writer in
-// such type.
-func NewResponseWriter(r http.ResponseWriter) *ResponseWriterWithInfo {
-	if rw, ok := r.(*ResponseWriterWithInfo); ok {
-		return rw
-	}
-
-	return &ResponseWriterWithInfo{
-		ResponseWriter: r,
-		statusCode:     http.StatusOK,
-	}
-}
-
-// WriteHeader will write the header to the response witer and store the status
-// that was written.
-func (r *ResponseWriterWithInfo) WriteHeader(code int) {
-	r.statusCode = code
-	r.ResponseWriter.WriteHeader(code)
-}
-
-// WriteError will store the error on the response writer.
-func (r *ResponseWriterWithInfo) WriteError(err error) {
-	r.responseError = err
-}
-
 // Middleware represents a middleware function which will add a handler before
-// the final http serve handler.
-type Middleware func(http.Handler) http.Handler
+// the final http serve handler. It's an alias for chain.Middleware.
+type Middleware = chain.Middleware
 
 // AddMiddlewares will add all middlewares in the passed orter and return a
 // handler which may be used for the http server. Since they're added in the
 // order they're passed, they will be executed in the reverse order.
 func AddMiddlewares(h http.Handler, middlewares ...Middleware) http.Handler {
-	for _, middleware := range middlewares {
-		h = middleware(h)
-	}
+	return chain.AddMiddlewares(h, middlewares...)
+}
 
-	return h
+// Logger creates a logger in a http.Handler for the HTTP server, logging with
+// the passed logger. Use logrusadapter.New to log with logrus.
+func Logger(logger *slog.Logger) Middleware {
+	return NewLogger(WithLogger(logger))
 }
 
-// Logger creates a logger in a http.Handler for the HTTP server.
-func Logger(logger logrus.FieldLogger) Middleware {
-	return func(h http.Handler) http.Handler {
+// NewLogger creates a logger in a http.Handler for the HTTP server configured
+// with the passed options. Use WithSlowRequestThreshold to log slow requests
+// as warnings with the runtime activity during the request,
+// WithStatusClassLevels and WithStatusLevels to log on a level based on the
+// response status and WithVersionField to log the version of the service.
+// Hijacked connections, e.g. WebSockets, are logged when the connection is
+// closed with the connection duration instead of when the handler returns.
+func NewLogger(opts ...Option) Middleware {
+	options := newOptions(opts...)
+
+	return options.skippable(func(h http.Handler) http.Handler {
 		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 			rw := NewResponseWriter(w)
 			startTime := time.Now()
 
-			h.ServeHTTP(rw, r)
+			var startSample runtimeSample
+			if options.slowRequestThreshold > 0 {
+				startSample = readRuntimeSample()
+			}
 
-			log := logger.WithFields(logrus.Fields{
-				"method":         r.Method,
-				"remote_address": r.RemoteAddr,
-				"path":           r.URL.String(),
-				"protocol":       r.Proto,
-				"content_length": r.ContentLength,
-				"status":         rw.statusCode,
-				"elapsed":        fmt.Sprintf("%.3f %s", time.Since(startTime).Seconds()*1000, "ms"),
-			})
+			h.ServeHTTP(rw.WithInterfaces(), r)
 
-			if rw.responseError != nil {
-				log.WithError(rw.responseError).Error("request processed")
-			} else {
-				log.Infof("request processed")
+			if rw.hijacked != nil {
+				rw.hijacked.afterClose(func() {
+					logRequest(r, rw, options, startTime, startSample)
+				})
+
+				return
 			}
+
+			logRequest(r, rw, options, startTime, startSample)
 		})
-	}
+	})
 }
 
-// PanicRecovery ensures that panics are handled.
-func PanicRecovery(logger logrus.FieldLogger) Middleware {
-	return func(h http.Handler) http.Handler {
-		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-			defer func() {
-				if r := recover(); r != nil {
-					logger.Errorf("panic recovered: %s", r)
-				}
-			}()
+// logRequest logs the request, or the hijacked connection, when it's done.
+func logRequest(r *http.Request, rw *ResponseWriterWithInfo, options *options, startTime time.Time, startSample runtimeSample) {
+	elapsed := time.Since(startTime)
+
+	attrs := []slog.Attr{
+		slog.String("method", r.Method),
+		slog.String("remote_address", r.RemoteAddr),
+		slog.String("path", r.URL.String()),
+		slog.String("protocol", r.Proto),
+		slog.Int64("content_length", r.ContentLength),
+		slog.Int("status", rw.statusCode),
+		slog.Int64("response_size", rw.BytesWritten()),
+		slog.Int64("bytes_sent", rw.BytesSent()),
+		slog.Duration("elapsed", elapsed),
+	}
 
-			h.ServeHTTP(w, r)
-		})
+	if options.versionField != "" {
+		attrs = append(attrs, slog.String("version", options.versionField))
 	}
-}
 
-//nolint:gochecknoglobals // Since promauto automatically registers metrics we
-//want to ensure they're only registered once to not panic.
-var metricsRegisterOnce = sync.Once{}
-
-// Prometheus will add metrics for the request to prometheus.
-func Prometheus() Middleware {
-	var (
-		inFlightGauge prometheus.Gauge
-		counter       *prometheus.CounterVec
-		duration      *prometheus.HistogramVec
-		responseSize  *prometheus.HistogramVec
-	)
-
-	// Only register the collectors once even if the middleware is used multiple
-	// times in the same process.
-	metricsRegisterOnce.Do(func() {
-		inFlightGauge = promauto.NewGauge(prometheus.GaugeOpts{
-			Name: "in_flight_requests",
-			Help: "A gauge of requests currently being served by the handler.",
-		})
+	if requestID, ok := httpctx.RequestID(r.Context()); ok {
+		attrs = append(attrs, slog.String("request_id", requestID))
+	}
 
-		counter = promauto.NewCounterVec(
-			prometheus.CounterOpts{
-				Name: "http_requests_total",
-				Help: "A counter for requests to the handler.",
-			},
-			[]string{"code", "method"},
+	if rw.variant != "" {
+		attrs = append(attrs, slog.String("variant", rw.variant))
+	}
+
+	if realIP, ok := httpctx.RealIP(r.Context()); ok {
+		attrs = append(attrs, slog.String("real_ip", realIP))
+	}
+
+	if trace, ok := httpctx.TraceFrom(r.Context()); ok {
+		attrs = append(attrs,
+			slog.String("trace_id", trace.TraceID),
+			slog.String("span_id", trace.SpanID),
 		)
+	}
 
-		duration = promauto.NewHistogramVec(
-			prometheus.HistogramOpts{
-				Name:    "request_duration_seconds",
-				Help:    "A histogram of latencies for requests.",
-				Buckets: []float64{.01, .1, .25, .5, 1, 2.5, 5, 10},
-			},
-			[]string{"method"},
+	if rw.hijacked != nil {
+		attrs = append(attrs,
+			slog.Bool("hijacked", true),
+			slog.Duration("connection_duration", time.Since(rw.hijacked.hijackedAt)),
 		)
+	}
 
-		responseSize = promauto.NewHistogramVec(
-			prometheus.HistogramOpts{
-				Name:    "response_size_bytes",
-				Help:    "A histogram of response sizes for requests.",
-				Buckets: []float64{200, 500, 900, 1500},
-			},
-			[]string{},
+	level := options.statusLevel(rw.statusCode)
+	if options.slowRequestThreshold > 0 && rw.hijacked == nil && elapsed >= options.slowRequestThreshold {
+		level = max(level, slog.LevelWarn)
+		attrs = append(attrs,
+			slog.Bool("slow", true),
+			readRuntimeSample().attrs(startSample),
 		)
-	})
+	}
+
+	if rw.responseError != nil {
+		level = slog.LevelError
+		attrs = append(attrs, slog.Any("error", rw.responseError))
+	}
+
+	options.logger.LogAttrs(r.Context(), level, "request processed", attrs...)
+}
+
+// statusLevel returns the level to log a response with the status code on,
+// before slow requests and response errors are taken into account.
+func (o *options) statusLevel(status int) slog.Level {
+	if level, ok := o.statusLevels[status]; ok {
+		return level
+	}
+
+	switch {
+	case !o.statusClassLevels:
+		return slog.LevelInfo
+	case status >= 500:
+		return slog.LevelError
+	case status >= 400:
+		return slog.LevelWarn
+	}
+
+	return slog.LevelInfo
+}
 
-	return func(h http.Handler) http.Handler {
+// PanicRecovery ensures that panics are handled, logging with the passed
+// logger. Use logrusadapter.New to log with logrus.
+func PanicRecovery(logger *slog.Logger) Middleware {
+	return NewPanicRecovery(WithLogger(logger))
+}
+
+// NewPanicRecovery ensures that panics are handled, configured with the passed
+// options. Use WithCrashLoopDetection to detect and stop routes panicking over
+// and over again. Recovered panics are counted in panics_recovered_total,
+// which is only registered if WithRegisterer is passed.
+func NewPanicRecovery(opts ...Option) Middleware {
+	options := newOptions(opts...)
+	logger := options.logger
+
+	recovered := registerOrExisting(options.optInRegisterer(), prometheus.NewCounter(prometheus.CounterOpts{
+		Name: "panics_recovered_total",
+		Help: "A counter for panics recovered by the panic recovery middleware.",
+	}))
+
+	var detector *crashLoopDetector
+	if options.crashLoop != nil {
+		detector = newCrashLoopDetector(options)
+	}
+
+	return options.skippable(func(h http.Handler) http.Handler {
 		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-			// Ensure we copy the handler so we don't wrap the same handler for
-			// each handler.
-			handler := h
+			if detector != nil {
+				if retryAfter, tripped := detector.tripped(detector.route(r)); tripped {
+					writeTripped(w, retryAfter)
+					return
+				}
+			}
 
-			handler = promhttp.InstrumentHandlerResponseSize(responseSize, handler)
-			handler = promhttp.InstrumentHandlerInFlight(inFlightGauge, handler)
-			handler = promhttp.InstrumentHandlerDuration(
-				duration.MustCurryWith(prometheus.Labels{"method": r.Method}),
-				handler,
-			)
+			defer func() {
+				p := recover()
+				if p == nil {
+					return
+				}
 
-			rw := NewResponseWriter(w)
+				recovered.Inc()
+				logger.ErrorContext(r.Context(), "panic recovered", slog.Any("panic", p))
+
+				if detector == nil {
+					return
+				}
+
+				event, looping := detector.panicked(detector.route(r))
+				if !looping {
+					return
+				}
+
+				logger.ErrorContext(
+					r.Context(), "route is crash looping",
+					slog.String("route", event.Route),
+					slog.Int("panics", event.Panics),
+					slog.Duration("window", detector.policy.Window),
+					slog.Time("tripped_until", event.TrippedUntil),
+				)
 
-			handler.ServeHTTP(rw, r)
+				if detector.policy.OnCrashLoop != nil {
+					detector.policy.OnCrashLoop(r.Context(), event)
+				}
+			}()
 
-			counter.WithLabelValues(strconv.Itoa(rw.statusCode), r.Method).Inc()
+			h.ServeHTTP(w, r)
 		})
-	}
+	})
 }
 
 // RateLimiter is a middleware that rate limits requests.
 func RateLimiter(interval time.Duration, limit, burst int) Middleware {
-	limiter := rate.NewLimiter(
-		rate.Every(interval),
-		limit,
-	)
+	return NewRateLimiter(WithRateLimit(interval, burst))
+}
+
+// NewRateLimiter is a middleware that rate limits requests, configured with the
+// passed options. Use WithRateLimit to set the limit, WithRateLimitKey to limit
+// requests by e.g. client and WithLimiterStore to share the limit between
+// processes. Rejected requests are counted in rate_limiter_rejected_total,
+// which is only registered if WithRegisterer is passed.
+func NewRateLimiter(opts ...Option) Middleware {
+	options := newOptions(opts...)
+	store := options.limiters()
+	overload := options.overloadSignal()
+
+	key := options.rateLimitKey
+	if key == nil {
+		key = func(*http.Request) string {
+			return ""
+		}
+	}
 
-	limiter.SetBurst(burst)
+	rejected := registerOrExisting(options.optInRegisterer(), prometheus.NewCounter(prometheus.CounterOpts{
+		Name: "rate_limiter_rejected_total",
+		Help: "A counter for requests rejected by the rate limiter.",
+	}))
 
-	return func(h http.Handler) http.Handler {
+	return options.skippable(func(h http.Handler) http.Handler {
 		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-			if !limiter.Allow() {
-				http.Error(w, http.StatusText(http.StatusTooManyRequests), http.StatusTooManyRequests)
+			k := key(r)
+			limiter := store.Limiter(k)
+
+			if l, ok := limiter.(RetryAfterLimiter); ok {
+				if retryAfter, allowed := l.TryAllow(); !allowed {
+					rejected.Inc()
+					overload.reject(w, http.StatusTooManyRequests, retryAfter)
+
+					return
+				}
+			} else if !limiter.Allow() {
+				rejected.Inc()
+				overload.reject(w, http.StatusTooManyRequests, options.interval)
+
 				return
 			}
 
 			h.ServeHTTP(w, r)
 		})
-	}
+	})
+}
+
+// DevWarnings logs warnings about incorrect usage of the response writer, such
+// as superfluous WriteHeader calls, together with the caller. This is intended
+// to be used during development.
+func DevWarnings(opts ...Option) Middleware {
+	options := newOptions(opts...)
+
+	return options.skippable(func(h http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			rw := NewResponseWriter(w)
+			rw.devLogger = options.logger
+
+			h.ServeHTTP(rw.WithInterfaces(), r)
+		})
+	})
+}
+
+// WriteStallTimeout cancels the request context if the handler doesn't make any
+// write progress within the passed window. The window is reset on every write,
+// and for every 256 KiB sent with io.ReaderFrom, e.g. sendfile, so long
+// responses are fine as long as they keep writing. Handlers streaming data
+// should stop when the context is done. Use WithClock to test the timeout
+// without waiting.
+func WriteStallTimeout(window time.Duration, opts ...Option) Middleware {
+	options := newOptions(opts...)
+
+	return options.skippable(func(h http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			ctx, cancel := context.WithCancel(r.Context())
+			defer cancel()
+
+			timer := options.clock.AfterFunc(window, cancel)
+			defer timer.Stop()
+
+			rw := NewResponseWriter(w)
+			rw.OnWrite(func(_ int, _ int64) {
+				timer.Reset(window)
+			})
+
+			h.ServeHTTP(rw.WithInterfaces(), r.WithContext(ctx))
+		})
+	})
 }
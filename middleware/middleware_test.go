@@ -4,25 +4,26 @@ import (
 	"bufio"
 	"bytes"
 	"encoding/json"
+	"io"
 	"log"
+	"log/slog"
 	"net/http"
 	"net/http/httptest"
+	"runtime"
 	"strings"
 	"testing"
 	"time"
 
-	"github.com/sirupsen/logrus"
+	"github.com/bombsimon/http-helpers/clock"
+	"github.com/bombsimon/http-helpers/httpctx"
 )
 
 func Test_Logger(t *testing.T) {
 	var (
-		logger = logrus.New()
 		buf    = &bytes.Buffer{}
+		logger = slog.New(slog.NewJSONHandler(buf, nil))
 	)
 
-	logger.SetOutput(buf)
-	logger.Formatter = &logrus.JSONFormatter{}
-
 	handlerWithMiddleware := AddMiddlewares(
 		http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}),
 		Logger(logger),
@@ -51,7 +52,7 @@ func Test_Logger(t *testing.T) {
 	for k, v := range map[string]interface{}{
 		"method":         "POST",
 		"msg":            "request processed",
-		"level":          "info",
+		"level":          "INFO",
 		"path":           "/",
 		"protocol":       "HTTP/1.1",
 		"content_length": float64(12),
@@ -62,6 +63,141 @@ func Test_Logger(t *testing.T) {
 	}
 }
 
+func Test_SlogLogger(t *testing.T) {
+	buf := &bytes.Buffer{}
+
+	handlerWithMiddleware := AddMiddlewares(
+		http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusTeapot)
+			_, _ = w.Write([]byte("short and stout"))
+		}),
+		NewLogger(WithLogger(slog.New(slog.NewJSONHandler(buf, nil))), WithVersionField("v1.2.3")),
+	)
+
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	ctx := httpctx.WithRequestID(req.Context(), "some-id")
+	ctx = httpctx.WithRealIP(ctx, "192.0.2.1")
+	ctx = httpctx.WithTrace(ctx, httpctx.Trace{TraceID: "trace", SpanID: "span"})
+	req = req.WithContext(ctx)
+
+	handlerWithMiddleware.ServeHTTP(httptest.NewRecorder(), req)
+
+	logged := map[string]interface{}{}
+	if err := json.Unmarshal(buf.Bytes(), &logged); err != nil {
+		t.Fatalf("could not parse logged message: %s", err)
+	}
+
+	for k, v := range map[string]interface{}{
+		"msg":           "request processed",
+		"level":         "INFO",
+		"method":        "GET",
+		"status":        float64(http.StatusTeapot),
+		"response_size": float64(15),
+		"bytes_sent":    float64(15),
+		"version":       "v1.2.3",
+		"request_id":    "some-id",
+		"real_ip":       "192.0.2.1",
+		"trace_id":      "trace",
+		"span_id":       "span",
+	} {
+		if logged[k] != v {
+			t.Fatalf("key mismatch: %s, got: %v", k, logged[k])
+		}
+	}
+}
+
+func Test_SlowRequest(t *testing.T) {
+	buf := &bytes.Buffer{}
+
+	handlerWithMiddleware := AddMiddlewares(
+		http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			runtime.GC()
+			time.Sleep(5 * time.Millisecond)
+		}),
+		NewLogger(
+			WithLogger(slog.New(slog.NewJSONHandler(buf, nil))),
+			WithSlowRequestThreshold(time.Millisecond),
+		),
+	)
+
+	handlerWithMiddleware.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil))
+
+	var logged struct {
+		Level   string                 `json:"level"`
+		Slow    bool                   `json:"slow"`
+		Runtime map[string]interface{} `json:"runtime"`
+	}
+
+	if err := json.Unmarshal(buf.Bytes(), &logged); err != nil {
+		t.Fatalf("could not parse logged message: %s", err)
+	}
+
+	if logged.Level != "WARN" || !logged.Slow {
+		t.Fatalf("expected slow request warning, got: %s", buf.String())
+	}
+
+	if cycles, _ := logged.Runtime["gc_cycles"].(float64); cycles < 1 {
+		t.Fatalf("expected the forced GC cycle to be logged, got: %v", logged.Runtime)
+	}
+
+	for _, key := range []string{"gc_pauses", "gc_pause_max", "sched_latency_p99", "sched_latency_max", "goroutines"} {
+		if _, ok := logged.Runtime[key]; !ok {
+			t.Fatalf("missing runtime stat %s, got: %v", key, logged.Runtime)
+		}
+	}
+}
+
+func Test_LoggerStatusLevels(t *testing.T) {
+	for _, tc := range []struct {
+		description   string
+		status        int
+		opts          []Option
+		expectedLevel string
+	}{
+		{description: "disabled", status: http.StatusInternalServerError, expectedLevel: "INFO"},
+		{description: "ok", status: http.StatusOK, opts: []Option{WithStatusClassLevels()}, expectedLevel: "INFO"},
+		{description: "client error", status: http.StatusBadRequest, opts: []Option{WithStatusClassLevels()}, expectedLevel: "WARN"},
+		{description: "server error", status: http.StatusBadGateway, opts: []Option{WithStatusClassLevels()}, expectedLevel: "ERROR"},
+		{
+			description:   "override",
+			status:        http.StatusNotFound,
+			opts:          []Option{WithStatusClassLevels(), WithStatusLevels(map[int]slog.Level{http.StatusNotFound: slog.LevelInfo})},
+			expectedLevel: "INFO",
+		},
+		{
+			description:   "override without classes",
+			status:        http.StatusTooManyRequests,
+			opts:          []Option{WithStatusLevels(map[int]slog.Level{http.StatusTooManyRequests: slog.LevelWarn})},
+			expectedLevel: "WARN",
+		},
+	} {
+		t.Run(tc.description, func(t *testing.T) {
+			buf := &bytes.Buffer{}
+
+			handlerWithMiddleware := AddMiddlewares(
+				http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+					w.WriteHeader(tc.status)
+				}),
+				NewLogger(append(tc.opts, WithLogger(slog.New(slog.NewJSONHandler(buf, nil))))...),
+			)
+
+			handlerWithMiddleware.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil))
+
+			var logged struct {
+				Level string `json:"level"`
+			}
+
+			if err := json.Unmarshal(buf.Bytes(), &logged); err != nil {
+				t.Fatalf("could not parse logged message: %s", err)
+			}
+
+			if logged.Level != tc.expectedLevel {
+				t.Fatalf("unexpected level, got: %s, expected: %s", logged.Level, tc.expectedLevel)
+			}
+		})
+	}
+}
+
 func Test_RateLimiter(t *testing.T) {
 	requestsAllowedBeforeRateLimiting := 2
 	expectedTimeBeforeRateLimiting := 10 * time.Millisecond
@@ -105,15 +241,55 @@ func Test_RateLimiter(t *testing.T) {
 	assertStatusCode(response.StatusCode, http.StatusOK)
 }
 
+func Test_NewRateLimiter(t *testing.T) {
+	requestsAllowedBeforeRateLimiting := 2
+	expectedTimeBeforeRateLimiting := 10 * time.Millisecond
+	clk := clock.NewFake(time.Now())
+
+	handlerWithMiddleware := AddMiddlewares(
+		http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}),
+		NewRateLimiter(
+			WithRateLimit(expectedTimeBeforeRateLimiting, requestsAllowedBeforeRateLimiting),
+			WithClock(clk),
+		),
+	)
+
+	ts := httptest.NewServer(handlerWithMiddleware)
+	defer ts.Close()
+
+	assertStatusCode := func(got, expected int) {
+		if got != expected {
+			t.Fatalf("unexpected status code, got: %v, expected: %v", got, expected)
+		}
+	}
+
+	// Do as many requests as we're allowed + 1. On the last one we are
+	// expected to be rate limited.
+	for i := 0; i <= requestsAllowedBeforeRateLimiting; i++ {
+		response, _ := http.Get(ts.URL)
+
+		expectedStatus := http.StatusOK
+		if i == requestsAllowedBeforeRateLimiting {
+			expectedStatus = http.StatusTooManyRequests
+		}
+
+		assertStatusCode(response.StatusCode, expectedStatus)
+	}
+
+	clk.Advance(expectedTimeBeforeRateLimiting)
+
+	// We should now be able to request again.
+	response, _ := http.Get(ts.URL)
+	assertStatusCode(response.StatusCode, http.StatusOK)
+}
+
 func Test_PanicRecovery(t *testing.T) {
 	var (
-		logger         = logrus.New()
 		buf            = &bytes.Buffer{}
+		logger         = slog.New(slog.NewTextHandler(buf, nil))
 		inPanicHandler = make(chan struct{})
 	)
 
-	logger.SetOutput(buf)
-
 	handlerWithMiddleware := AddMiddlewares(
 		http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 			go func() {
@@ -211,3 +387,223 @@ func Test_Order(t *testing.T) {
 		}
 	}
 }
+
+func Test_OnWrite(t *testing.T) {
+	var (
+		calls int
+		total int64
+	)
+
+	handlerWithMiddleware := AddMiddlewares(
+		http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			for i := 0; i < 3; i++ {
+				_, _ = w.Write([]byte("hello"))
+			}
+		}),
+		func(h http.Handler) http.Handler {
+			return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				rw := NewResponseWriter(w)
+				rw.OnWrite(func(n int, t int64) {
+					calls++
+					total = t
+				})
+
+				h.ServeHTTP(rw, r)
+			})
+		},
+	)
+
+	handlerWithMiddleware.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil))
+
+	if calls != 3 {
+		t.Fatalf("unexpected number of calls, got: %d, expected: 3", calls)
+	}
+
+	if total != 15 {
+		t.Fatalf("unexpected total, got: %d, expected: 15", total)
+	}
+}
+
+func Test_WriteStallTimeout(t *testing.T) {
+	var (
+		window = 10 * time.Second
+		clk    = clock.NewFake(time.Now())
+	)
+
+	handlerWithMiddleware := AddMiddlewares(
+		http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			_, _ = w.Write([]byte("first chunk"))
+			clk.Advance(window - time.Second)
+
+			// Writing resets the window.
+			_, _ = w.Write([]byte("second chunk"))
+			clk.Advance(window - time.Second)
+
+			if r.Context().Err() != nil {
+				t.Fatal("context cancelled while making progress")
+			}
+
+			clk.Advance(time.Second)
+
+			if r.Context().Err() == nil {
+				t.Fatal("context not cancelled when stalling")
+			}
+		}),
+		WriteStallTimeout(window, WithClock(clk)),
+	)
+
+	handlerWithMiddleware.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil))
+}
+
+// readFromRecorder is a recorder implementing io.ReaderFrom like the writer of
+// the http server.
+type readFromRecorder struct {
+	*httptest.ResponseRecorder
+}
+
+func (r readFromRecorder) ReadFrom(src io.Reader) (int64, error) {
+	return io.Copy(r.ResponseRecorder, src)
+}
+
+// slowReader advances the clock by a second for each read.
+type slowReader struct {
+	clock *clock.Fake
+	io.Reader
+}
+
+func (r slowReader) Read(p []byte) (int, error) {
+	r.clock.Advance(time.Second)
+	return r.Reader.Read(p)
+}
+
+func Test_WriteStallTimeoutReadFrom(t *testing.T) {
+	var (
+		window = 10 * time.Second
+		clk    = clock.NewFake(time.Now())
+	)
+
+	handlerWithMiddleware := AddMiddlewares(
+		http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			// Reading 1 MiB 32 KiB at a time takes longer than the window,
+			// but each chunk is read within it.
+			src := slowReader{clock: clk, Reader: bytes.NewReader(make([]byte, 1<<20))}
+
+			if _, err := io.Copy(w, src); err != nil {
+				t.Fatal(err)
+			}
+
+			if r.Context().Err() != nil {
+				t.Fatal("context cancelled while making progress")
+			}
+		}),
+		WriteStallTimeout(window, WithClock(clk)),
+	)
+
+	handlerWithMiddleware.ServeHTTP(
+		readFromRecorder{httptest.NewRecorder()},
+		httptest.NewRequest(http.MethodGet, "/", nil),
+	)
+}
+
+func Test_TimeToFirstByte(t *testing.T) {
+	rw := NewResponseWriter(httptest.NewRecorder())
+
+	if rw.TimeToFirstByte() != 0 {
+		t.Fatal("expected no time to first byte before writing")
+	}
+
+	time.Sleep(5 * time.Millisecond)
+	rw.WriteHeader(http.StatusAccepted)
+
+	ttfb := rw.TimeToFirstByte()
+	if ttfb < 5*time.Millisecond {
+		t.Fatalf("unexpected time to first byte: %s", ttfb)
+	}
+
+	_, _ = rw.Write([]byte("hello"))
+
+	if rw.TimeToFirstByte() != ttfb {
+		t.Fatal("time to first byte changed after subsequent write")
+	}
+}
+
+func Test_WithSkipFunc(t *testing.T) {
+	handlerWithMiddleware := AddMiddlewares(
+		http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}),
+		NewRateLimiter(
+			WithRateLimit(time.Hour, 1),
+			WithSkipFunc(func(r *http.Request) bool {
+				return r.URL.Path == "/healthz"
+			}),
+		),
+	)
+
+	for path, expectedStatus := range map[string]int{
+		"/":        http.StatusOK,
+		"/healthz": http.StatusOK,
+	} {
+		rec := httptest.NewRecorder()
+		handlerWithMiddleware.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, path, nil))
+
+		if rec.Code != expectedStatus {
+			t.Fatalf("unexpected status for %s: %d", path, rec.Code)
+		}
+	}
+
+	for path, expectedStatus := range map[string]int{
+		"/":        http.StatusTooManyRequests,
+		"/healthz": http.StatusOK,
+	} {
+		rec := httptest.NewRecorder()
+		handlerWithMiddleware.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, path, nil))
+
+		if rec.Code != expectedStatus {
+			t.Fatalf("unexpected status for %s: %d", path, rec.Code)
+		}
+	}
+}
+
+func Test_SuperfluousWriteHeader(t *testing.T) {
+	var (
+		buf    = &bytes.Buffer{}
+		logger = slog.New(slog.NewTextHandler(buf, nil))
+		rec    = httptest.NewRecorder()
+		status int
+	)
+
+	handlerWithMiddleware := AddMiddlewares(
+		http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if NewResponseWriter(w).Written() {
+				t.Fatal("expected nothing to be written")
+			}
+
+			w.WriteHeader(http.StatusEarlyHints)
+			w.WriteHeader(http.StatusCreated)
+			w.WriteHeader(http.StatusInternalServerError)
+
+			if !NewResponseWriter(w).Written() {
+				t.Fatal("expected header to be written")
+			}
+		}),
+		func(h http.Handler) http.Handler {
+			return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				rw := NewResponseWriter(w)
+				h.ServeHTTP(rw, r)
+				status = rw.statusCode
+			})
+		},
+		DevWarnings(WithLogger(logger)),
+	)
+
+	handlerWithMiddleware.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+
+	if status != http.StatusCreated {
+		t.Fatalf("unexpected status, got: %d, expected: %d", status, http.StatusCreated)
+	}
+
+	if !strings.Contains(buf.String(), "superfluous WriteHeader call") ||
+		!strings.Contains(buf.String(), "code=500") ||
+		!strings.Contains(buf.String(), "middleware_test.go") {
+		t.Fatalf("expected warning with caller, got: %s", buf.String())
+	}
+}
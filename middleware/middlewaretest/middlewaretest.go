@@ -0,0 +1,173 @@
+// Package middlewaretest provides utilities for testing middlewares, like
+// net/http/httptest does for handlers: a recording response writer, a fake
+// clock, helpers to assert the order middlewares are executed in and a harness
+// running table driven middleware tests.
+package middlewaretest
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/bombsimon/http-helpers/clock"
+	"github.com/bombsimon/http-helpers/middleware"
+)
+
+// Epoch is the time a clock created with NewClock starts at.
+var Epoch = time.Date(2024, time.January, 1, 0, 0, 0, 0, time.UTC)
+
+// Recorder is a *middleware.ResponseWriterWithInfo recording the response in
+// an *httptest.ResponseRecorder, so both the information seen by the
+// middlewares, such as the error written with WriteError, and the response
+// sent can be asserted.
+type Recorder struct {
+	*middleware.ResponseWriterWithInfo
+
+	// Recorded is the response written.
+	Recorded *httptest.ResponseRecorder
+}
+
+// NewRecorder returns an initialized Recorder.
+func NewRecorder() *Recorder {
+	recorded := httptest.NewRecorder()
+
+	return &Recorder{
+		ResponseWriterWithInfo: middleware.NewResponseWriter(recorded),
+		Recorded:               recorded,
+	}
+}
+
+// NewClock returns a clock.Fake set to Epoch. Pass it with WithClock to the
+// middlewares under test, e.g. the rate limiter and the timeouts, and advance
+// it instead of sleeping.
+func NewClock() *clock.Fake {
+	return clock.NewFake(Epoch)
+}
+
+// Order records the order middlewares and handlers are called in. It's safe
+// for concurrent use.
+type Order struct {
+	mu    sync.Mutex
+	calls []string
+}
+
+// NewOrder returns an empty Order.
+func NewOrder() *Order {
+	return &Order{}
+}
+
+// Middleware returns a middleware recording name when it's called, before
+// calling the next handler.
+func (o *Order) Middleware(name string) middleware.Middleware {
+	return func(h http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			o.record(name)
+			h.ServeHTTP(w, r)
+		})
+	}
+}
+
+// Handler returns a handler recording name when it's called.
+func (o *Order) Handler(name string) http.Handler {
+	return http.HandlerFunc(func(http.ResponseWriter, *http.Request) {
+		o.record(name)
+	})
+}
+
+// Calls returns the names recorded so far.
+func (o *Order) Calls() []string {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	return slices.Clone(o.calls)
+}
+
+// Assert fails the test unless exactly the expected names have been recorded,
+// in order.
+func (o *Order) Assert(t testing.TB, expected ...string) {
+	t.Helper()
+
+	if calls := o.Calls(); !slices.Equal(calls, expected) {
+		t.Fatalf("unexpected order, got: %v, expected: %v", calls, expected)
+	}
+}
+
+func (o *Order) record(name string) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	o.calls = append(o.calls, name)
+}
+
+// Case is a case run by RunMiddlewareTest. Expectations with zero values
+// aren't asserted.
+type Case struct {
+	// Name is the name of the subtest.
+	Name string
+
+	// Request is the request served. Defaults to GET /.
+	Request *http.Request
+
+	// Handler is the handler wrapped by the middleware. Defaults to a handler
+	// responding with 200 OK and no body.
+	Handler http.Handler
+
+	// ExpectedStatus is the status expected to be written.
+	ExpectedStatus int
+
+	// ExpectedHeaders are headers expected to be set on the response. Other
+	// headers may also be set.
+	ExpectedHeaders http.Header
+
+	// ExpectedBody is the body expected to be written.
+	ExpectedBody string
+
+	// Check is called with the recorder after the request has been served to
+	// assert anything else.
+	Check func(t *testing.T, rec *Recorder)
+}
+
+// RunMiddlewareTest runs each case as a subtest, serving the request with the
+// case handler wrapped in the middleware. The cases are run in order with the
+// same middleware, so state such as a rate limit carries over between them.
+func RunMiddlewareTest(t *testing.T, m middleware.Middleware, cases ...Case) {
+	t.Helper()
+
+	for _, tc := range cases {
+		t.Run(tc.Name, func(t *testing.T) {
+			request := tc.Request
+			if request == nil {
+				request = httptest.NewRequest(http.MethodGet, "/", http.NoBody)
+			}
+
+			handler := tc.Handler
+			if handler == nil {
+				handler = http.HandlerFunc(func(http.ResponseWriter, *http.Request) {})
+			}
+
+			rec := NewRecorder()
+			m(handler).ServeHTTP(rec.WithInterfaces(), request)
+
+			if tc.ExpectedStatus != 0 && rec.Recorded.Code != tc.ExpectedStatus {
+				t.Errorf("unexpected status, got: %d, expected: %d", rec.Recorded.Code, tc.ExpectedStatus)
+			}
+
+			for name, values := range tc.ExpectedHeaders {
+				if got := rec.Recorded.Header().Values(name); !slices.Equal(got, values) {
+					t.Errorf("unexpected %s header, got: %v, expected: %v", name, got, values)
+				}
+			}
+
+			if tc.ExpectedBody != "" && rec.Recorded.Body.String() != tc.ExpectedBody {
+				t.Errorf("unexpected body, got: %q, expected: %q", rec.Recorded.Body.String(), tc.ExpectedBody)
+			}
+
+			if tc.Check != nil {
+				tc.Check(t, rec)
+			}
+		})
+	}
+}
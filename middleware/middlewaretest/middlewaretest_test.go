@@ -0,0 +1,81 @@
+package middlewaretest
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/bombsimon/http-helpers/middleware"
+)
+
+func Test_RunMiddlewareTest(t *testing.T) {
+	clk := NewClock()
+
+	rateLimiter := middleware.NewRateLimiter(
+		middleware.WithRateLimit(time.Minute, 1),
+		middleware.WithClock(clk),
+	)
+
+	RunMiddlewareTest(t, rateLimiter,
+		Case{
+			Name: "allowed",
+			Handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.Header().Set("Content-Type", "text/plain")
+				_, _ = w.Write([]byte("ok"))
+			}),
+			ExpectedStatus:  http.StatusOK,
+			ExpectedHeaders: http.Header{"Content-Type": {"text/plain"}},
+			ExpectedBody:    "ok",
+		},
+		Case{
+			Name:           "rate limited",
+			ExpectedStatus: http.StatusTooManyRequests,
+			Check: func(t *testing.T, rec *Recorder) {
+				// Refill the limiter for the next case.
+				clk.Advance(time.Minute)
+			},
+		},
+		Case{
+			Name:           "allowed after interval",
+			ExpectedStatus: http.StatusOK,
+		},
+	)
+}
+
+func Test_Recorder(t *testing.T) {
+	errNotFound := errors.New("not found")
+
+	RunMiddlewareTest(t, func(h http.Handler) http.Handler { return h }, Case{
+		Name: "error",
+		Handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			middleware.NewResponseWriter(w).WriteError(errNotFound)
+			w.WriteHeader(http.StatusNotFound)
+		}),
+		ExpectedStatus: http.StatusNotFound,
+		Check: func(t *testing.T, rec *Recorder) {
+			if !errors.Is(rec.Err(), errNotFound) {
+				t.Fatalf("unexpected error: %v", rec.Err())
+			}
+
+			if rec.Status() != http.StatusNotFound {
+				t.Fatalf("unexpected status: %d", rec.Status())
+			}
+		},
+	})
+}
+
+func Test_Order(t *testing.T) {
+	order := NewOrder()
+
+	handler := middleware.AddMiddlewares(
+		order.Handler("handler"),
+		order.Middleware("one"),
+		order.Middleware("two"),
+	)
+
+	handler.ServeHTTP(NewRecorder(), httptest.NewRequest(http.MethodGet, "/", http.NoBody))
+
+	order.Assert(t, "two", "one", "handler")
+}
@@ -0,0 +1,30 @@
+package middleware
+
+import (
+	"net/http"
+
+	"github.com/bombsimon/http-helpers/httpctx"
+	"github.com/bombsimon/http-helpers/render"
+)
+
+// Negotiate picks the response media type from the offers based on the Accept
+// header and stores it in the request context, available with
+// httpctx.MediaType and used by render.Render. Requests not accepting any of
+// the offers are rejected with 406 Not Acceptable.
+func Negotiate(offers []string, opts ...Option) Middleware {
+	options := newOptions(opts...)
+
+	return options.skippable(func(h http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Add("Vary", "Accept")
+
+			mediaType := render.Negotiate(r.Header.Get("Accept"), offers...)
+			if mediaType == "" {
+				http.Error(w, http.StatusText(http.StatusNotAcceptable), http.StatusNotAcceptable)
+				return
+			}
+
+			h.ServeHTTP(w, r.WithContext(httpctx.WithMediaType(r.Context(), mediaType)))
+		})
+	})
+}
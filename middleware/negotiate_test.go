@@ -0,0 +1,64 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/bombsimon/http-helpers/render"
+)
+
+type negotiateUser struct {
+	Name string `json:"name" xml:"name"`
+}
+
+func Test_Negotiate(t *testing.T) {
+	handler := AddMiddlewares(
+		http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			_ = render.Render(w, r, http.StatusOK, negotiateUser{Name: "bob"})
+		}),
+		Negotiate([]string{render.MediaTypeJSON, render.MediaTypeXML}, WithSkipFunc(func(r *http.Request) bool {
+			return r.URL.Path == "/skip"
+		})),
+	)
+
+	for accept, expected := range map[string]string{
+		"":                                  "application/json; charset=utf-8",
+		"application/xml":                   "application/xml; charset=utf-8",
+		"text/html, application/*;q=0.9":    "application/json; charset=utf-8",
+		"application/json;q=0.5, */*;q=0.8": "application/xml; charset=utf-8",
+		"text/html":                         "",
+	} {
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		req.Header.Set("Accept", accept)
+
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+
+		if expected == "" {
+			if rec.Code != http.StatusNotAcceptable {
+				t.Fatalf("expected 406 for %s, got: %d", accept, rec.Code)
+			}
+
+			continue
+		}
+
+		if got := rec.Header().Get("Content-Type"); got != expected {
+			t.Fatalf("unexpected content type for %q, got: %s, expected: %s", accept, got, expected)
+		}
+
+		if rec.Header().Get("Vary") != "Accept" {
+			t.Fatal("expected Vary: Accept")
+		}
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/skip", nil)
+	req.Header.Set("Accept", "text/html")
+
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("unexpected status for skipped path, got: %d, expected: %d", rec.Code, http.StatusOK)
+	}
+}
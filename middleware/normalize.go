@@ -0,0 +1,92 @@
+package middleware
+
+import (
+	"net/http"
+	"path"
+	"strings"
+)
+
+// TrailingSlash is the policy for trailing slashes used by NormalizePath.
+type TrailingSlash int
+
+// Trailing slash policies.
+const (
+	// TrailingSlashKeep keeps the trailing slash as is.
+	TrailingSlashKeep TrailingSlash = iota
+
+	// TrailingSlashStrip removes the trailing slash.
+	TrailingSlashStrip
+
+	// TrailingSlashAdd adds a trailing slash.
+	TrailingSlashAdd
+)
+
+// WithTrailingSlash sets the trailing slash policy used by NormalizePath.
+// Defaults to TrailingSlashKeep.
+func WithTrailingSlash(policy TrailingSlash) Option {
+	return func(o *options) {
+		o.trailingSlash = policy
+	}
+}
+
+// NormalizePath canonicalizes the request path before it's routed so the same
+// resource isn't served on multiple paths. Duplicate slashes are collapsed,
+// dot segments are resolved and the trailing slash is stripped or added
+// depending on WithTrailingSlash. Requests to non-canonical paths are
+// redirected to the canonical path with 308 Permanent Redirect, which keeps
+// the method and body. The host is lowercased without redirecting.
+func NormalizePath(opts ...Option) Middleware {
+	options := newOptions(opts...)
+
+	return options.skippable(func(h http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			r.Host = strings.ToLower(r.Host)
+
+			escaped := r.URL.EscapedPath()
+
+			canonical := canonicalPath(escaped, options.trailingSlash)
+			if canonical != escaped {
+				target := canonical
+				if r.URL.RawQuery != "" {
+					target += "?" + r.URL.RawQuery
+				}
+
+				http.Redirect(w, r, target, http.StatusPermanentRedirect)
+
+				return
+			}
+
+			h.ServeHTTP(w, r)
+		})
+	})
+}
+
+func canonicalPath(p string, trailingSlash TrailingSlash) string {
+	if p == "" {
+		return "/"
+	}
+
+	hadSlash := strings.HasSuffix(p, "/")
+
+	// Clean also resolves dot segments and collapses duplicate slashes. The
+	// result always starts with a single slash so it can't be used as a
+	// protocol relative redirect.
+	p = path.Clean("/" + p)
+
+	if p == "/" {
+		return p
+	}
+
+	switch trailingSlash {
+	case TrailingSlashAdd:
+		return p + "/"
+	case TrailingSlashStrip:
+		return p
+	default:
+		if hadSlash {
+			return p + "/"
+		}
+
+		return p
+	}
+}
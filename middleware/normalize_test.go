@@ -0,0 +1,100 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func Test_NormalizePath(t *testing.T) {
+	tests := []struct {
+		name             string
+		opts             []Option
+		target           string
+		expectedLocation string
+	}{
+		{
+			name:   "canonical",
+			target: "/users/1",
+		},
+		{
+			name:             "duplicate slashes",
+			target:           "/users//1?expand=true",
+			expectedLocation: "/users/1?expand=true",
+		},
+		{
+			name:             "dot segments",
+			target:           "/users/./2/../1",
+			expectedLocation: "/users/1",
+		},
+		{
+			name:             "protocol relative",
+			target:           "//evil.example.com/",
+			expectedLocation: "/evil.example.com/",
+		},
+		{
+			name:   "trailing slash kept",
+			target: "/users/",
+		},
+		{
+			name:             "trailing slash stripped",
+			opts:             []Option{WithTrailingSlash(TrailingSlashStrip)},
+			target:           "/users/",
+			expectedLocation: "/users",
+		},
+		{
+			name:             "trailing slash added",
+			opts:             []Option{WithTrailingSlash(TrailingSlashAdd)},
+			target:           "/users",
+			expectedLocation: "/users/",
+		},
+		{
+			name:   "root",
+			opts:   []Option{WithTrailingSlash(TrailingSlashStrip)},
+			target: "/",
+		},
+		{
+			name:   "escaped slash kept",
+			target: "/files/a%2Fb",
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			var host string
+
+			handler := AddMiddlewares(
+				http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+					host = r.Host
+				}),
+				NormalizePath(tc.opts...),
+			)
+
+			req := httptest.NewRequest(http.MethodPost, tc.target, nil)
+			req.Host = "API.Example.com"
+
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, req)
+
+			if tc.expectedLocation == "" {
+				if rec.Code != http.StatusOK {
+					t.Fatalf("unexpected status, got: %d, expected: %d", rec.Code, http.StatusOK)
+				}
+
+				if host != "api.example.com" {
+					t.Fatalf("unexpected host, got: %s, expected: api.example.com", host)
+				}
+
+				return
+			}
+
+			if rec.Code != http.StatusPermanentRedirect {
+				t.Fatalf("unexpected status, got: %d, expected: %d", rec.Code, http.StatusPermanentRedirect)
+			}
+
+			if location := rec.Header().Get("Location"); location != tc.expectedLocation {
+				t.Fatalf("unexpected location, got: %s, expected: %s", location, tc.expectedLocation)
+			}
+		})
+	}
+}
@@ -0,0 +1,353 @@
+package middleware
+
+import (
+	"log/slog"
+	"net/http"
+	"net/netip"
+	"time"
+
+	httphelpers "github.com/bombsimon/http-helpers"
+	"github.com/bombsimon/http-helpers/cache"
+	"github.com/bombsimon/http-helpers/clock"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// Option is an option used to configure a middleware. All middlewares share
+// the same option type so the same option, e.g. WithLogger, can be used with
+// multiple middlewares. Options not relevant for a middleware are ignored.
+type Option func(*options)
+
+type options struct {
+	logger     *slog.Logger
+	skip       func(*http.Request) bool
+	registerer prometheus.Registerer
+	clock      clock.Clock
+
+	// registererSet is true if WithRegisterer was passed, metrics about the
+	// middlewares themselves are only registered then.
+	registererSet bool
+
+	// Path exclusion.
+	excludedPaths        []string
+	excludedPathPrefixes []string
+
+	// Rate limiter.
+	interval           time.Duration
+	burst              int
+	limiterStore       LimiterStore
+	rateLimitKey       func(*http.Request) string
+	rateLimitFailFast  bool
+	limiterIdleTimeout time.Duration
+
+	// Overload signal.
+	overload *OverloadSignal
+
+	// Adaptive limiter.
+	concurrencyInitial int
+	concurrencyMin     int
+	concurrencyMax     int
+
+	// Priority queueing.
+	queueSize    int
+	queueTimeout time.Duration
+
+	// Stats.
+	sampleSize int
+
+	// Signature verification.
+	nonceStore            NonceStore
+	signatureMaxSkew      time.Duration
+	requiredSignedHeaders []string
+
+	// Request ID and real IP.
+	requestIDHeader string
+	trustedProxies  []netip.Prefix
+
+	// HTTPS redirect.
+	canonicalHost       string
+	www                 WWW
+	redirectExemptPaths []string
+
+	// Request enforcement.
+	requiredHeaders []string
+	contentTypes    []string
+	maxURLLength    int
+	allowedMethods  map[string][]string
+
+	// Response cache.
+	staleWhileRevalidate time.Duration
+	staleIfError         time.Duration
+	cacheKey             func(*http.Request) string
+	cacheMaxEntries      int
+	cacheStore           cache.Store
+
+	// Timeout.
+	routeTimeouts map[string]time.Duration
+
+	// Logger.
+	slowRequestThreshold time.Duration
+	statusClassLevels    bool
+	statusLevels         map[int]slog.Level
+	versionField         string
+
+	// Path normalization.
+	trailingSlash TrailingSlash
+
+	// Panic recovery.
+	crashLoop *CrashLoopPolicy
+
+	// Admin guard.
+	allowedHosts  []string
+	adminToken    string
+	adminNetworks []netip.Prefix
+
+	// Feature flags.
+	flagStatus int
+
+	// Maintenance.
+	retryAfter time.Duration
+
+	// Error handler.
+	errorMapper   httphelpers.ErrorMapper
+	errorStatuses []errorStatus
+
+	// Prometheus.
+	route               func(*http.Request) string
+	routeBuckets        map[string][]float64
+	nativeBucketFactor  float64
+	nativeHistogramsSet bool
+
+	// Key-value store.
+	kvTimeout time.Duration
+}
+
+func newOptions(opts ...Option) *options {
+	o := &options{
+		logger:           slog.Default(),
+		registerer:       prometheus.DefaultRegisterer,
+		clock:            clock.Real(),
+		requestIDHeader:  "X-Request-Id",
+		interval:         time.Second,
+		burst:            1,
+		sampleSize:       1024,
+		signatureMaxSkew: 5 * time.Minute,
+		routeBuckets:     map[string][]float64{},
+		errorMapper:      httphelpers.DefaultErrorMapper,
+		flagStatus:       http.StatusNotFound,
+		retryAfter:       time.Minute,
+
+		concurrencyInitial: 20,
+		concurrencyMin:     1,
+		concurrencyMax:     1000,
+		queueSize:          100,
+		cacheMaxEntries:    1000,
+		limiterIdleTimeout: 10 * time.Minute,
+		kvTimeout:          500 * time.Millisecond,
+	}
+
+	for _, opt := range opts {
+		opt(o)
+	}
+
+	return o
+}
+
+// WithLogger sets the logger used by the middleware. Defaults to
+// slog.Default(). Use logrusadapter.New to log with logrus.
+func WithLogger(logger *slog.Logger) Option {
+	return func(o *options) {
+		o.logger = logger
+	}
+}
+
+// WithSkipFunc sets a function that will be called for each request. If the
+// function returns true the middleware will be skipped for that request.
+func WithSkipFunc(fn func(*http.Request) bool) Option {
+	return func(o *options) {
+		o.skip = fn
+	}
+}
+
+// WithRegisterer sets the Prometheus registerer used to register metrics.
+// Defaults to prometheus.DefaultRegisterer. Metrics about the middlewares
+// themselves, e.g. from NewRateLimiter, Cache and NewPanicRecovery, are only
+// registered if it's set.
+func WithRegisterer(registerer prometheus.Registerer) Option {
+	return func(o *options) {
+		o.registerer = registerer
+		o.registererSet = true
+	}
+}
+
+// optInRegisterer returns the registerer if it was set with WithRegisterer,
+// otherwise nil so the metrics aren't registered.
+func (o *options) optInRegisterer() prometheus.Registerer {
+	if !o.registererSet {
+		return nil
+	}
+
+	return o.registerer
+}
+
+// WithClock sets the clock used for timeouts and rate limiting. This is useful
+// to test them with a clock.Fake instead of waiting. Defaults to clock.Real().
+func WithClock(c clock.Clock) Option {
+	return func(o *options) {
+		o.clock = c
+	}
+}
+
+// WithRouteTimeouts sets the timeout used by Timeout for requests matching
+// the route patterns, using the same syntax as http.ServeMux, e.g.
+// "GET /exports/{id}".
+func WithRouteTimeouts(timeouts map[string]time.Duration) Option {
+	return func(o *options) {
+		o.routeTimeouts = timeouts
+	}
+}
+
+// WithSlowRequestThreshold logs requests taking at least the threshold as
+// warnings in NewLogger. Slow requests are annotated with the garbage
+// collection pauses and scheduling latency during the request, read from
+// runtime/metrics, to tell a slow handler from a stalled runtime.
+func WithSlowRequestThreshold(threshold time.Duration) Option {
+	return func(o *options) {
+		o.slowRequestThreshold = threshold
+	}
+}
+
+// WithStatusClassLevels logs responses with a 4xx status as warnings and with
+// a 5xx status as errors in NewLogger, without the handler calling WriteError.
+func WithStatusClassLevels() Option {
+	return func(o *options) {
+		o.statusClassLevels = true
+	}
+}
+
+// WithStatusLevels sets the level NewLogger logs responses with the status
+// codes on, overriding the level set by WithStatusClassLevels, e.g. to log 404
+// Not Found on the info level.
+func WithStatusLevels(levels map[int]slog.Level) Option {
+	return func(o *options) {
+		o.statusLevels = levels
+	}
+}
+
+// WithVersionField makes NewLogger log the version of the service with each
+// request, e.g. buildinfo.Read().Version, to tell which version served a
+// request when several versions are deployed.
+func WithVersionField(version string) Option {
+	return func(o *options) {
+		o.versionField = version
+	}
+}
+
+// WithRateLimit sets the rate limit to allow one request per interval with
+// bursts of up to burst requests.
+func WithRateLimit(interval time.Duration, burst int) Option {
+	return func(o *options) {
+		o.interval = interval
+		o.burst = burst
+	}
+}
+
+// WithRetryAfter sets the Retry-After header sent by Maintenance while in
+// maintenance mode. Set to 0 to not send the header.
+func WithRetryAfter(d time.Duration) Option {
+	return func(o *options) {
+		o.retryAfter = d
+	}
+}
+
+// WithSampleSize sets the number of recent requests used to calculate latency
+// quantiles in BasicStats. A size of 0 or less disables the quantiles.
+func WithSampleSize(n int) Option {
+	return func(o *options) {
+		o.sampleSize = n
+	}
+}
+
+// WithRequestIDHeader sets the header used to read and write the request ID.
+// Defaults to X-Request-Id.
+func WithRequestIDHeader(name string) Option {
+	return func(o *options) {
+		o.requestIDHeader = name
+	}
+}
+
+// WithTrustedProxies sets the proxies trusted to set the X-Forwarded-For and
+// X-Real-Ip headers in RealIP.
+func WithTrustedProxies(prefixes ...netip.Prefix) Option {
+	return func(o *options) {
+		o.trustedProxies = append(o.trustedProxies, prefixes...)
+	}
+}
+
+// WithAllowedHosts sets the host names, without port, accepted by AdminGuard
+// in addition to localhost and IP addresses.
+func WithAllowedHosts(hosts ...string) Option {
+	return func(o *options) {
+		o.allowedHosts = append(o.allowedHosts, hosts...)
+	}
+}
+
+// WithAdminToken requires AdminGuard requests to send the token as a bearer
+// token in the Authorization header.
+func WithAdminToken(token string) Option {
+	return func(o *options) {
+		o.adminToken = token
+	}
+}
+
+// WithAdminNetworks sets the networks AdminGuard accepts requests from.
+// Defaults to loopback and private addresses.
+func WithAdminNetworks(prefixes ...netip.Prefix) Option {
+	return func(o *options) {
+		o.adminNetworks = append(o.adminNetworks, prefixes...)
+	}
+}
+
+// WithErrorMapper sets the function used by ErrorHandler to map errors not
+// registered with WithErrorStatus to a status code and body. Defaults to
+// httphelpers.DefaultErrorMapper.
+func WithErrorMapper(mapper httphelpers.ErrorMapper) Option {
+	return func(o *options) {
+		o.errorMapper = mapper
+	}
+}
+
+// WithErrorStatus registers a status code for errors matching target with
+// errors.Is in ErrorHandler. The error message is returned to the client so
+// this should only be used for errors that are safe to expose. Errors are
+// matched in the order they're registered.
+func WithErrorStatus(target error, status int) Option {
+	return func(o *options) {
+		o.errorStatuses = append(o.errorStatuses, errorStatus{target: target, status: status})
+	}
+}
+
+type errorStatus struct {
+	target error
+	status int
+}
+
+// skippable wraps the middleware so it's skipped for requests matching the
+// skip function or the excluded paths, if any.
+func (o *options) skippable(m Middleware) Middleware {
+	if o.skip == nil && len(o.excludedPaths) == 0 && len(o.excludedPathPrefixes) == 0 {
+		return m
+	}
+
+	return func(h http.Handler) http.Handler {
+		wrapped := m(h)
+
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if o.excluded(r) || (o.skip != nil && o.skip(r)) {
+				h.ServeHTTP(w, r)
+				return
+			}
+
+			wrapped.ServeHTTP(w, r)
+		})
+	}
+}
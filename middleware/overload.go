@@ -0,0 +1,120 @@
+package middleware
+
+import (
+	"context"
+	"errors"
+	"math"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/bombsimon/http-helpers/clock"
+)
+
+// defaultOverloadWindow is how long the default OverloadSignal reports being
+// overloaded after the last shed request.
+const defaultOverloadWindow = 10 * time.Second
+
+// ErrOverloaded is returned by the HealthCheck of an OverloadSignal while
+// requests are being shed.
+var ErrOverloaded = errors.New("overloaded, shedding requests")
+
+// defaultOverload is the signal used by the limiters unless another one is set
+// with WithOverloadSignal.
+var defaultOverload = NewOverloadSignal(defaultOverloadWindow)
+
+// OverloadSignal is shared by the limiters, Prioritize, AdaptiveLimit and
+// NewRateLimiter, to reject requests consistently and to tell if the service
+// is shedding load. Rejected requests get a Retry-After header computed from
+// the state of the limiter rejecting them. Only requests shed with 503 Service
+// Unavailable, i.e. when the service is out of capacity, count as overload.
+// Requests exceeding a rate limit are rejected with 429 Too Many Requests but
+// don't, since the limit is usually per client.
+type OverloadSignal struct {
+	window time.Duration
+	clock  clock.Clock
+
+	mu     sync.Mutex
+	shedAt time.Time
+}
+
+// NewOverloadSignal creates a signal reporting being overloaded until the
+// window has passed since the last shed request. Use WithClock to test it with
+// a clock.Fake.
+func NewOverloadSignal(window time.Duration, opts ...Option) *OverloadSignal {
+	return &OverloadSignal{
+		window: window,
+		clock:  newOptions(opts...).clock,
+	}
+}
+
+// WithOverloadSignal sets the signal the limiters report shed requests to.
+// Defaults to a signal shared by all limiters in the process with a window of
+// 10 seconds, see Overloaded.
+func WithOverloadSignal(s *OverloadSignal) Option {
+	return func(o *options) {
+		o.overload = s
+	}
+}
+
+// Overloaded returns true if the limiters using the default signal shed a
+// request within the last 10 seconds.
+func Overloaded() bool {
+	return defaultOverload.Overloaded()
+}
+
+// OverloadHealthCheck returns a HealthCheck for the default signal, see
+// OverloadSignal.HealthCheck.
+func OverloadHealthCheck() HealthCheck {
+	return defaultOverload.HealthCheck()
+}
+
+// Overloaded returns true if a request was shed within the window.
+func (s *OverloadSignal) Overloaded() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return !s.shedAt.IsZero() && s.clock.Since(s.shedAt) < s.window
+}
+
+// HealthCheck returns a HealthCheck named "overload" failing with
+// ErrOverloaded while overloaded. Add it to the readiness Health handler to
+// take the instance out of rotation while it's shedding load.
+func (s *OverloadSignal) HealthCheck() HealthCheck {
+	return HealthCheck{
+		Name: "overload",
+		Check: func(context.Context) error {
+			if s.Overloaded() {
+				return ErrOverloaded
+			}
+
+			return nil
+		},
+	}
+}
+
+// overloadSignal returns the signal set with WithOverloadSignal or the default
+// signal.
+func (o *options) overloadSignal() *OverloadSignal {
+	if o.overload == nil {
+		return defaultOverload
+	}
+
+	return o.overload
+}
+
+// reject writes the status with a Retry-After header of retryAfter rounded up
+// to whole seconds, at least one second, and records shed requests.
+func (s *OverloadSignal) reject(w http.ResponseWriter, status int, retryAfter time.Duration) {
+	if status == http.StatusServiceUnavailable {
+		s.mu.Lock()
+		s.shedAt = s.clock.Now()
+		s.mu.Unlock()
+	}
+
+	seconds := int(math.Ceil(max(retryAfter, time.Second).Seconds()))
+
+	w.Header().Set("Retry-After", strconv.Itoa(seconds))
+	http.Error(w, http.StatusText(status), status)
+}
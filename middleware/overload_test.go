@@ -0,0 +1,103 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/bombsimon/http-helpers/clock"
+)
+
+func Test_OverloadSignal(t *testing.T) {
+	var (
+		clk     = clock.NewFake(time.Now())
+		signal  = NewOverloadSignal(10*time.Second, WithClock(clk))
+		started = make(chan struct{})
+		release = make(chan struct{})
+		done    = make(chan struct{})
+	)
+
+	handler := AddMiddlewares(
+		http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			close(started)
+			<-release
+		}),
+		AdaptiveLimit(WithConcurrencyLimits(1, 1, 1), WithOverloadSignal(signal)),
+	)
+
+	health := Health(signal.HealthCheck())
+
+	go func() {
+		defer close(done)
+		handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil))
+	}()
+
+	<-started
+
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+
+	close(release)
+	<-done
+
+	if rec.Code != http.StatusServiceUnavailable || rec.Header().Get("Retry-After") != "1" {
+		t.Fatalf("expected request to be shed, got: %d Retry-After %q", rec.Code, rec.Header().Get("Retry-After"))
+	}
+
+	if !signal.Overloaded() {
+		t.Fatal("expected to be overloaded after shedding a request")
+	}
+
+	rec = httptest.NewRecorder()
+	health.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/ready", nil))
+
+	if rec.Code != http.StatusServiceUnavailable {
+		t.Fatalf("expected readiness to fail while overloaded, got: %d", rec.Code)
+	}
+
+	clk.Advance(10 * time.Second)
+
+	if signal.Overloaded() {
+		t.Fatal("expected to not be overloaded after the window")
+	}
+}
+
+func Test_RateLimiterRetryAfter(t *testing.T) {
+	var (
+		clk    = clock.NewFake(time.Now())
+		signal = NewOverloadSignal(10*time.Second, WithClock(clk))
+	)
+
+	handler := AddMiddlewares(
+		http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}),
+		NewRateLimiter(
+			WithLimiterStore(NewKeyedLimiterStore(func() Limiter {
+				return NewGCRALimiter(5*time.Second, 1, WithClock(clk))
+			})),
+			WithOverloadSignal(signal),
+		),
+	)
+
+	for i, expected := range []struct {
+		status     int
+		retryAfter string
+	}{
+		{status: http.StatusOK},
+		{status: http.StatusTooManyRequests, retryAfter: "5"},
+	} {
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+
+		if rec.Code != expected.status || rec.Header().Get("Retry-After") != expected.retryAfter {
+			t.Fatalf(
+				"unexpected response for request %d, got: %d Retry-After %q, expected: %d Retry-After %q",
+				i, rec.Code, rec.Header().Get("Retry-After"), expected.status, expected.retryAfter,
+			)
+		}
+	}
+
+	if signal.Overloaded() {
+		t.Fatal("expected rate limited requests to not count as overload")
+	}
+}
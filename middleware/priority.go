@@ -0,0 +1,247 @@
+package middleware
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+// priorityLatencySmoothing is the weight of each request in the average time
+// requests are in flight, used to compute the Retry-After of rejected requests.
+const priorityLatencySmoothing = 0.1
+
+// Priority is the priority class of a request. Higher priorities are admitted
+// first by Prioritize.
+type Priority int
+
+// Common priority classes, any other values can be used as well.
+const (
+	PriorityLow Priority = iota
+	PriorityNormal
+	PriorityHigh
+	PriorityCritical
+)
+
+// Classifier returns the priority class of the request.
+type Classifier func(r *http.Request) Priority
+
+// PriorityFromHeader returns a Classifier looking up the value of the header,
+// e.g. a tier set by the API gateway, in priorities. Requests without a known
+// value get the fallback priority.
+func PriorityFromHeader(name string, priorities map[string]Priority, fallback Priority) Classifier {
+	return func(r *http.Request) Priority {
+		if priority, ok := priorities[r.Header.Get(name)]; ok {
+			return priority
+		}
+
+		return fallback
+	}
+}
+
+// PriorityFromPath returns a Classifier using the priority of the longest path
+// prefix matching the request, e.g. "/healthz". Requests not matching any
+// prefix get the fallback priority.
+func PriorityFromPath(prefixes map[string]Priority, fallback Priority) Classifier {
+	return func(r *http.Request) Priority {
+		priority, longest := fallback, -1
+
+		for prefix, p := range prefixes {
+			if len(prefix) > longest && strings.HasPrefix(r.URL.Path, prefix) {
+				priority, longest = p, len(prefix)
+			}
+		}
+
+		return priority
+	}
+}
+
+// WithQueueSize sets the number of requests each priority class can queue in
+// Prioritize. Defaults to 100.
+func WithQueueSize(n int) Option {
+	return func(o *options) {
+		o.queueSize = n
+	}
+}
+
+// WithQueueTimeout sets how long requests can wait in the queue in
+// Prioritize. Requests wait until they're canceled if not set.
+func WithQueueTimeout(d time.Duration) Option {
+	return func(o *options) {
+		o.queueTimeout = d
+	}
+}
+
+// Prioritize admits up to maxInFlight requests at a time. When saturated,
+// requests wait in a bounded queue per priority class, set by the classifier,
+// and the queued request with the highest priority is admitted first when a
+// request completes, so e.g. health checks and paid-tier traffic survive
+// overload. Requests are rejected with 503 Service Unavailable if the queue
+// for their class is full (WithQueueSize) or they time out in the queue
+// (WithQueueTimeout). A maxInFlight of 0 or less doesn't limit the requests.
+func Prioritize(maxInFlight int, classify Classifier, opts ...Option) Middleware {
+	if maxInFlight <= 0 {
+		return func(h http.Handler) http.Handler {
+			return h
+		}
+	}
+
+	options := newOptions(opts...)
+	overload := options.overloadSignal()
+	queue := &priorityQueue{
+		maxInFlight: maxInFlight,
+		queueSize:   options.queueSize,
+		waiting:     map[Priority][]*priorityWaiter{},
+	}
+
+	return options.skippable(func(h http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			ctx := r.Context()
+
+			if options.queueTimeout > 0 {
+				var cancel context.CancelFunc
+
+				ctx, cancel = options.clock.WithTimeout(ctx, options.queueTimeout)
+				defer cancel()
+			}
+
+			priority := classify(r)
+
+			if !queue.acquire(ctx, priority) {
+				overload.reject(w, http.StatusServiceUnavailable, queue.retryAfter(priority))
+				return
+			}
+
+			start := options.clock.Now()
+
+			defer func() {
+				queue.release(options.clock.Since(start))
+			}()
+
+			h.ServeHTTP(w, r)
+		})
+	})
+}
+
+// priorityQueue admits requests in priority order.
+type priorityQueue struct {
+	maxInFlight int
+	queueSize   int
+
+	mu       sync.Mutex
+	inFlight int
+	waiting  map[Priority][]*priorityWaiter
+	latency  float64
+}
+
+type priorityWaiter struct {
+	ready    chan struct{}
+	admitted bool
+}
+
+// acquire waits for a slot and returns true when admitted, or false if the
+// queue is full or the context is done first.
+func (q *priorityQueue) acquire(ctx context.Context, priority Priority) bool {
+	q.mu.Lock()
+
+	if q.inFlight < q.maxInFlight {
+		q.inFlight++
+		q.mu.Unlock()
+
+		return true
+	}
+
+	if len(q.waiting[priority]) >= q.queueSize {
+		q.mu.Unlock()
+		return false
+	}
+
+	waiter := &priorityWaiter{ready: make(chan struct{})}
+	q.waiting[priority] = append(q.waiting[priority], waiter)
+	q.mu.Unlock()
+
+	select {
+	case <-waiter.ready:
+		return true
+	case <-ctx.Done():
+	}
+
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	// The slot may have been handed over while the context was done, pass it
+	// on to the next request.
+	if waiter.admitted {
+		q.next()
+		return false
+	}
+
+	for i, w := range q.waiting[priority] {
+		if w == waiter {
+			q.waiting[priority] = append(q.waiting[priority][:i], q.waiting[priority][i+1:]...)
+			break
+		}
+	}
+
+	return false
+}
+
+// release hands the slot over to the next request and updates the average
+// time requests are in flight.
+func (q *priorityQueue) release(d time.Duration) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	if q.latency == 0 {
+		q.latency = float64(d)
+	}
+
+	q.latency += (float64(d) - q.latency) * priorityLatencySmoothing
+
+	q.next()
+}
+
+// retryAfter estimates how long it takes until a request with the priority
+// would be admitted, from the number of requests queued ahead of it and the
+// average time requests are in flight.
+func (q *priorityQueue) retryAfter(priority Priority) time.Duration {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	ahead := 0
+
+	for p, waiters := range q.waiting {
+		if p >= priority {
+			ahead += len(waiters)
+		}
+	}
+
+	return time.Duration(q.latency * float64(ahead+1) / float64(max(q.maxInFlight, 1)))
+}
+
+// next admits the first queued request with the highest priority, or frees
+// the slot if no request is queued. The lock must be held.
+func (q *priorityQueue) next() {
+	var (
+		highest Priority
+		found   bool
+	)
+
+	for priority, waiters := range q.waiting {
+		if len(waiters) > 0 && (!found || priority > highest) {
+			highest, found = priority, true
+		}
+	}
+
+	if !found {
+		q.inFlight--
+		return
+	}
+
+	waiter := q.waiting[highest][0]
+	q.waiting[highest] = q.waiting[highest][1:]
+
+	waiter.admitted = true
+	close(waiter.ready)
+}
@@ -0,0 +1,156 @@
+package middleware
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/bombsimon/http-helpers/clock"
+)
+
+func Test_PriorityClassifiers(t *testing.T) {
+	byHeader := PriorityFromHeader("X-Tier", map[string]Priority{"paid": PriorityHigh}, PriorityNormal)
+	byPath := PriorityFromPath(map[string]Priority{"/healthz": PriorityCritical, "/": PriorityNormal, "/batch/": PriorityLow}, PriorityNormal)
+
+	req := httptest.NewRequest(http.MethodGet, "/batch/jobs", nil)
+	req.Header.Set("X-Tier", "paid")
+
+	if byHeader(req) != PriorityHigh || byPath(req) != PriorityLow {
+		t.Fatalf("unexpected priorities: %d, %d", byHeader(req), byPath(req))
+	}
+
+	req = httptest.NewRequest(http.MethodGet, "/healthz", nil)
+
+	if byHeader(req) != PriorityNormal || byPath(req) != PriorityCritical {
+		t.Fatalf("unexpected priorities: %d, %d", byHeader(req), byPath(req))
+	}
+}
+
+func Test_PriorityQueueOrder(t *testing.T) {
+	queue := &priorityQueue{maxInFlight: 1, queueSize: 10, waiting: map[Priority][]*priorityWaiter{}}
+
+	if !queue.acquire(context.Background(), PriorityLow) {
+		t.Fatal("expected first request to be admitted")
+	}
+
+	var (
+		mu       sync.Mutex
+		admitted []Priority
+		wg       sync.WaitGroup
+	)
+
+	for _, priority := range []Priority{PriorityLow, PriorityNormal, PriorityCritical, PriorityHigh} {
+		wg.Add(1)
+
+		go func() {
+			defer wg.Done()
+
+			if queue.acquire(context.Background(), priority) {
+				mu.Lock()
+				admitted = append(admitted, priority)
+				mu.Unlock()
+
+				queue.release(0)
+			}
+		}()
+
+		// Wait for the request to be queued to keep the order within a class.
+		for queued := 0; queued != 1; {
+			queue.mu.Lock()
+			queued = len(queue.waiting[priority])
+			queue.mu.Unlock()
+		}
+	}
+
+	queue.release(0)
+	wg.Wait()
+
+	expected := []Priority{PriorityCritical, PriorityHigh, PriorityNormal, PriorityLow}
+	for i := range expected {
+		if admitted[i] != expected[i] {
+			t.Fatalf("unexpected order, got: %v, expected: %v", admitted, expected)
+		}
+	}
+
+	if queue.inFlight != 0 {
+		t.Fatalf("expected no requests in flight, got: %d", queue.inFlight)
+	}
+}
+
+func Test_Prioritize(t *testing.T) {
+	clk := clock.NewFake(time.Now())
+
+	var (
+		started = make(chan struct{})
+		done    = make(chan struct{})
+	)
+
+	handler := AddMiddlewares(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/block" {
+			started <- struct{}{}
+			<-done
+		}
+	}), Prioritize(1, PriorityFromPath(nil, PriorityNormal), WithQueueSize(1), WithQueueTimeout(time.Second), WithClock(clk)))
+
+	serve := func() <-chan int {
+		status := make(chan int, 1)
+
+		go func() {
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+			status <- rec.Code
+		}()
+
+		return status
+	}
+
+	go handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/block", nil))
+	<-started
+
+	// One request waits in the queue until it times out, the other finds the
+	// queue full.
+	first, second := serve(), serve()
+
+	var queued <-chan int
+
+	select {
+	case code := <-first:
+		if code != http.StatusServiceUnavailable {
+			t.Fatalf("expected full queue to be rejected, got: %d", code)
+		}
+
+		queued = second
+	case code := <-second:
+		if code != http.StatusServiceUnavailable {
+			t.Fatalf("expected full queue to be rejected, got: %d", code)
+		}
+
+		queued = first
+	}
+
+	clk.BlockUntil(2)
+	clk.Advance(time.Second)
+
+	if code := <-queued; code != http.StatusServiceUnavailable {
+		t.Fatalf("expected queued request to time out, got: %d", code)
+	}
+
+	close(done)
+}
+
+func Test_PrioritizeUnlimited(t *testing.T) {
+	handler := AddMiddlewares(
+		http.HandlerFunc(func(http.ResponseWriter, *http.Request) {}),
+		Prioritize(0, func(*http.Request) Priority { return PriorityNormal }),
+	)
+
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected request to be admitted without a limit, got: %d", rec.Code)
+	}
+}
@@ -0,0 +1,249 @@
+package middleware
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"runtime/metrics"
+	"runtime/pprof"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/bombsimon/http-helpers/clock"
+)
+
+// Profiles captured by ProfileCapture.
+const (
+	ProfileHeap      = "heap"
+	ProfileGoroutine = "goroutine"
+)
+
+// metricHeapBytes is the memory occupied by live and not yet swept objects.
+const metricHeapBytes = "/memory/classes/heap/objects:bytes"
+
+// ProfilePolicy configures when ProfileCapture captures profiles. Thresholds
+// that are zero are not checked.
+type ProfilePolicy struct {
+	// MaxInFlight captures a goroutine profile when the number of requests in
+	// flight reaches it.
+	MaxInFlight int
+
+	// MaxLatency captures a goroutine profile when a request takes longer.
+	MaxLatency time.Duration
+
+	// MaxHeapBytes captures a heap profile when the heap grows larger. The
+	// heap is checked at most once per second.
+	MaxHeapBytes uint64
+
+	// Interval is the minimum time between two captures. Defaults to one
+	// minute.
+	Interval time.Duration
+
+	// MaxProfiles is the number of profiles kept, the oldest profile is
+	// removed when a new one is captured. Defaults to 10.
+	MaxProfiles int
+}
+
+// Profile is a captured pprof profile.
+type Profile struct {
+	ID         int       `json:"id"`
+	Kind       string    `json:"kind"`
+	Reason     string    `json:"reason"`
+	CapturedAt time.Time `json:"captured_at"`
+	Size       int       `json:"size"`
+
+	data []byte
+}
+
+// ProfileCapture captures heap and goroutine profiles when the thresholds in
+// the policy are crossed, catching transient issues that are gone by the time
+// someone attaches a profiler. Profiles are captured in the background, at
+// most once per interval, and only the most recent are kept. Use the
+// ProfileCapture as an http.Handler on the admin server to list and download
+// the profiles.
+type ProfileCapture struct {
+	policy    ProfilePolicy
+	options   *options
+	clock     clock.Clock
+	inFlight  atomic.Int64
+	capturing atomic.Bool
+
+	mu          sync.Mutex
+	profiles    []Profile
+	nextID      int
+	lastCapture time.Time
+	lastHeap    time.Time
+}
+
+// NewProfileCapture creates a new ProfileCapture. Captures are logged with the
+// logger set with WithLogger and rate limited with the clock set with
+// WithClock.
+func NewProfileCapture(policy ProfilePolicy, opts ...Option) *ProfileCapture {
+	if policy.Interval <= 0 {
+		policy.Interval = time.Minute
+	}
+
+	if policy.MaxProfiles <= 0 {
+		policy.MaxProfiles = 10
+	}
+
+	options := newOptions(opts...)
+
+	return &ProfileCapture{
+		policy:  policy,
+		options: options,
+		clock:   options.clock,
+		nextID:  1,
+	}
+}
+
+// Middleware returns the middleware checking the thresholds for each request.
+func (c *ProfileCapture) Middleware() Middleware {
+	return func(h http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			inFlight := c.inFlight.Add(1)
+			defer c.inFlight.Add(-1)
+
+			if c.policy.MaxInFlight > 0 && inFlight >= int64(c.policy.MaxInFlight) {
+				c.trigger(ProfileGoroutine, fmt.Sprintf("%d requests in flight", inFlight))
+			}
+
+			start := c.clock.Now()
+
+			h.ServeHTTP(w, r)
+
+			if elapsed := c.clock.Since(start); c.policy.MaxLatency > 0 && elapsed > c.policy.MaxLatency {
+				c.trigger(ProfileGoroutine, fmt.Sprintf("%s %s took %s", r.Method, r.URL.Path, elapsed))
+			}
+
+			if c.policy.MaxHeapBytes > 0 && c.heapCheckDue() {
+				if heap := heapBytes(); heap > c.policy.MaxHeapBytes {
+					c.trigger(ProfileHeap, fmt.Sprintf("heap is %d bytes", heap))
+				}
+			}
+		})
+	}
+}
+
+// Capture captures a profile of the kind, ProfileHeap or ProfileGoroutine,
+// right away regardless of the interval.
+func (c *ProfileCapture) Capture(kind, reason string) (Profile, error) {
+	profile := pprof.Lookup(kind)
+	if profile == nil {
+		return Profile{}, fmt.Errorf("unknown profile %q", kind)
+	}
+
+	var buf bytes.Buffer
+	if err := profile.WriteTo(&buf, 0); err != nil {
+		return Profile{}, err
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	p := Profile{
+		ID:         c.nextID,
+		Kind:       kind,
+		Reason:     reason,
+		CapturedAt: c.clock.Now(),
+		Size:       buf.Len(),
+		data:       buf.Bytes(),
+	}
+
+	c.nextID++
+	c.lastCapture = p.CapturedAt
+	c.profiles = append(c.profiles, p)
+
+	if len(c.profiles) > c.policy.MaxProfiles {
+		c.profiles = c.profiles[len(c.profiles)-c.policy.MaxProfiles:]
+	}
+
+	return p, nil
+}
+
+// Profiles returns the captured profiles, oldest first.
+func (c *ProfileCapture) Profiles() []Profile {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return append([]Profile{}, c.profiles...)
+}
+
+// ServeHTTP lists the captured profiles as JSON. Pass the id query parameter
+// to download a profile, e.g. to open it with go tool pprof.
+func (c *ProfileCapture) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	id := r.URL.Query().Get("id")
+	if id == "" {
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(c.Profiles())
+
+		return
+	}
+
+	for _, p := range c.Profiles() {
+		if strconv.Itoa(p.ID) != id {
+			continue
+		}
+
+		w.Header().Set("Content-Type", "application/octet-stream")
+		w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="%s-%d.pb.gz"`, p.Kind, p.ID))
+		_, _ = w.Write(p.data)
+
+		return
+	}
+
+	http.NotFound(w, r)
+}
+
+// trigger captures a profile in the background unless a profile was captured
+// within the interval or a capture is already running.
+func (c *ProfileCapture) trigger(kind, reason string) {
+	c.mu.Lock()
+	due := c.lastCapture.IsZero() || c.clock.Since(c.lastCapture) >= c.policy.Interval
+	c.mu.Unlock()
+
+	if !due || !c.capturing.CompareAndSwap(false, true) {
+		return
+	}
+
+	go func() {
+		defer c.capturing.Store(false)
+
+		p, err := c.Capture(kind, reason)
+		if err != nil {
+			c.options.logger.Error("could not capture profile", "kind", kind, "reason", reason, "error", err)
+			return
+		}
+
+		c.options.logger.Warn("captured profile", "id", p.ID, "kind", kind, "reason", reason)
+	}()
+}
+
+// heapCheckDue returns true if the heap wasn't checked the last second.
+func (c *ProfileCapture) heapCheckDue() bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	now := c.clock.Now()
+	if now.Sub(c.lastHeap) < time.Second {
+		return false
+	}
+
+	c.lastHeap = now
+
+	return true
+}
+
+func heapBytes() uint64 {
+	sample := []metrics.Sample{{Name: metricHeapBytes}}
+	metrics.Read(sample)
+
+	if sample[0].Value.Kind() != metrics.KindUint64 {
+		return 0
+	}
+
+	return sample[0].Value.Uint64()
+}
@@ -0,0 +1,126 @@
+package middleware
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/bombsimon/http-helpers/clock"
+)
+
+func Test_ProfileCapture(t *testing.T) {
+	fake := clock.NewFake(time.Now())
+	capture := NewProfileCapture(ProfilePolicy{
+		MaxLatency:  time.Second,
+		Interval:    time.Minute,
+		MaxProfiles: 2,
+	}, WithClock(fake))
+
+	handler := AddMiddlewares(
+		http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.URL.Path == "/slow" {
+				fake.Advance(2 * time.Second)
+			}
+		}),
+		capture.Middleware(),
+	)
+
+	serve := func(path string) {
+		handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, path, nil))
+	}
+
+	waitFor := func(n int) {
+		t.Helper()
+
+		deadline := time.Now().Add(5 * time.Second)
+		for len(capture.Profiles()) != n {
+			if time.Now().After(deadline) {
+				t.Fatalf("expected %d profiles, got: %d", n, len(capture.Profiles()))
+			}
+
+			time.Sleep(time.Millisecond)
+		}
+
+		// Wait for the background capture to finish.
+		for capture.capturing.Load() {
+			time.Sleep(time.Millisecond)
+		}
+	}
+
+	serve("/fast")
+	serve("/slow")
+	waitFor(1)
+
+	// Rate limited within the interval.
+	serve("/slow")
+	time.Sleep(10 * time.Millisecond)
+	waitFor(1)
+
+	for i := 0; i < 3; i++ {
+		fake.Advance(time.Minute)
+		serve("/slow")
+		waitFor(2)
+	}
+
+	profiles := capture.Profiles()
+	if profiles[0].ID != 3 || profiles[1].ID != 4 {
+		t.Fatalf("expected only the two latest profiles to be kept, got: %+v", profiles)
+	}
+
+	if profiles[1].Kind != ProfileGoroutine || profiles[1].Reason != "GET /slow took 2s" {
+		t.Fatalf("unexpected profile: %+v", profiles[1])
+	}
+
+	rr := httptest.NewRecorder()
+	capture.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/debug/profiles", nil))
+
+	var listed []Profile
+	if err := json.NewDecoder(rr.Body).Decode(&listed); err != nil || len(listed) != 2 {
+		t.Fatalf("unexpected listing: %v, err: %v", listed, err)
+	}
+
+	rr = httptest.NewRecorder()
+	capture.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/debug/profiles?id="+strconv.Itoa(profiles[1].ID), nil))
+
+	if rr.Code != http.StatusOK || rr.Body.Len() != profiles[1].Size || rr.Body.Len() == 0 {
+		t.Fatalf("unexpected download, status: %d, size: %d", rr.Code, rr.Body.Len())
+	}
+
+	rr = httptest.NewRecorder()
+	capture.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/debug/profiles?id=1", nil))
+
+	if rr.Code != http.StatusNotFound {
+		t.Fatalf("expected removed profile to not be found, got: %d", rr.Code)
+	}
+
+	if _, err := capture.Capture(ProfileHeap, "manual"); err != nil {
+		t.Fatalf("could not capture heap profile: %v", err)
+	}
+
+	if _, err := capture.Capture("unknown", "manual"); err == nil {
+		t.Fatal("expected error for unknown profile")
+	}
+}
+
+func Test_ProfileCaptureHeap(t *testing.T) {
+	capture := NewProfileCapture(ProfilePolicy{MaxHeapBytes: 1})
+
+	AddMiddlewares(http.NotFoundHandler(), capture.Middleware()).
+		ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil))
+
+	deadline := time.Now().Add(5 * time.Second)
+	for len(capture.Profiles()) == 0 {
+		if time.Now().After(deadline) {
+			t.Fatal("expected heap profile to be captured")
+		}
+
+		time.Sleep(time.Millisecond)
+	}
+
+	if kind := capture.Profiles()[0].Kind; kind != ProfileHeap {
+		t.Fatalf("unexpected profile kind: %s", kind)
+	}
+}
@@ -0,0 +1,240 @@
+package middleware
+
+import (
+	"errors"
+	"fmt"
+	"maps"
+	"net/http"
+	"slices"
+	"strconv"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+// WithRouteLabel adds a route label to the duration histogram. The function
+// should return a low cardinality value such as the route pattern and not the
+// actual path.
+func WithRouteLabel(fn func(*http.Request) string) Option {
+	return func(o *options) {
+		o.route = fn
+	}
+}
+
+// WithRouteBuckets overrides the duration histogram buckets for a specific
+// route. The route is matched against the value returned by the function
+// passed to WithRouteLabel so that option must also be used.
+func WithRouteBuckets(route string, buckets []float64) Option {
+	return func(o *options) {
+		o.routeBuckets[route] = buckets
+	}
+}
+
+// WithNativeHistograms will make the histograms also emit native (sparse)
+// histograms with the passed bucket factor. The classic buckets are still
+// emitted to allow a smooth migration.
+func WithNativeHistograms(bucketFactor float64) Option {
+	return func(o *options) {
+		o.nativeHistogramsSet = true
+		o.nativeBucketFactor = bucketFactor
+	}
+}
+
+//nolint:gochecknoglobals // Default buckets used for latency histograms.
+var defaultDurationBuckets = []float64{.01, .1, .25, .5, 1, 2.5, 5, 10}
+
+// Prometheus will add metrics for the request to prometheus. Upgrade requests,
+// e.g. WebSockets, are counted but not added to the in-flight gauge or the
+// size histogram and hijacked connections aren't added to the latency
+// histograms. Using the middleware multiple times with the same registerer
+// shares the collectors, which panics if the duration histogram buckets or
+// native histogram settings differ from the first use.
+func Prometheus(opts ...Option) Middleware {
+	options := newOptions(opts...)
+	withNative := options.withNativeHistograms
+
+	durationLabels := []string{"method", "status_class"}
+	if options.route != nil {
+		durationLabels = append(durationLabels, "route")
+	}
+
+	// Collectors are registered with registerOrExisting so the middleware can
+	// be used multiple times in the same process without panicking.
+	inFlightGauge := registerOrExisting(options.registerer, prometheus.NewGauge(prometheus.GaugeOpts{
+		Name: "in_flight_requests",
+		Help: "A gauge of requests currently being served by the handler.",
+	}))
+
+	counter := registerOrExisting(options.registerer, prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "http_requests_total",
+			Help: "A counter for requests to the handler.",
+		},
+		[]string{"code", "method"},
+	))
+
+	newDuration := func(buckets []float64) *prometheus.HistogramVec {
+		return prometheus.NewHistogramVec(
+			withNative(prometheus.HistogramOpts{
+				Name:    "request_duration_seconds",
+				Help:    "A histogram of latencies for requests.",
+				Buckets: buckets,
+			}),
+			durationLabels,
+		)
+	}
+
+	durationHistograms := &routeHistograms{
+		defaultVec:   newDuration(defaultDurationBuckets),
+		routes:       map[string]*prometheus.HistogramVec{},
+		buckets:      options.routeBuckets,
+		nativeFactor: options.nativeBucketFactor,
+	}
+
+	for route, buckets := range options.routeBuckets {
+		durationHistograms.routes[route] = newDuration(buckets)
+	}
+
+	// The existing histograms would silently be used with other buckets than
+	// the ones passed.
+	duration := registerOrExisting(options.registerer, durationHistograms)
+	if !duration.sameBuckets(durationHistograms) {
+		panic(errors.New("middleware: request_duration_seconds is already registered with other buckets"))
+	}
+
+	firstByte := registerOrExisting(options.registerer, prometheus.NewHistogramVec(
+		withNative(prometheus.HistogramOpts{
+			Name:    "request_time_to_first_byte_seconds",
+			Help:    "A histogram of time until the first byte was written for requests.",
+			Buckets: defaultDurationBuckets,
+		}),
+		[]string{"method"},
+	))
+
+	responseSize := registerOrExisting(options.registerer, prometheus.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Name:    "response_size_bytes",
+			Help:    "A histogram of response sizes for requests.",
+			Buckets: []float64{200, 500, 900, 1500},
+		},
+		[]string{},
+	))
+
+	return options.skippable(func(h http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			// Ensure we copy the handler so we don't wrap the same handler for
+			// each handler.
+			handler := h
+
+			// Upgraded connections, e.g. WebSockets, live long and have no
+			// response body so they would skew the in-flight and size metrics.
+			if !isUpgrade(r) {
+				handler = promhttp.InstrumentHandlerInFlight(inFlightGauge, handler)
+			}
+
+			rw := NewResponseWriter(w)
+			startTime := time.Now()
+
+			handler.ServeHTTP(rw.WithInterfaces(), r)
+
+			counter.WithLabelValues(strconv.Itoa(rw.statusCode), r.Method).Inc()
+
+			if !isUpgrade(r) {
+				responseSize.WithLabelValues().Observe(float64(rw.BytesWritten()))
+			}
+
+			// The handler of a hijacked connection may run until the
+			// connection is closed so the latency isn't the request latency.
+			if rw.Hijacked() {
+				return
+			}
+
+			elapsed := time.Since(startTime).Seconds()
+			statusClass := fmt.Sprintf("%dxx", rw.statusCode/100)
+
+			if options.route != nil {
+				route := options.route(r)
+				duration.forRoute(route).WithLabelValues(r.Method, statusClass, route).Observe(elapsed)
+			} else {
+				duration.defaultVec.WithLabelValues(r.Method, statusClass).Observe(elapsed)
+			}
+
+			if ttfb := rw.TimeToFirstByte(); ttfb > 0 {
+				firstByte.WithLabelValues(r.Method).Observe(ttfb.Seconds())
+			}
+		})
+	})
+}
+
+// withNativeHistograms adds the native histogram settings from
+// WithNativeHistograms to the histogram options.
+func (o *options) withNativeHistograms(h prometheus.HistogramOpts) prometheus.HistogramOpts {
+	if o.nativeHistogramsSet {
+		h.NativeHistogramBucketFactor = o.nativeBucketFactor
+		h.NativeHistogramMaxBucketNumber = 100
+		h.NativeHistogramMinResetDuration = time.Hour
+	}
+
+	return h
+}
+
+// routeHistograms is a collector holding one histogram vector per route with
+// custom buckets and a default vector for all other routes. All vectors share
+// the same description so they're exported as a single metric.
+type routeHistograms struct {
+	defaultVec   *prometheus.HistogramVec
+	routes       map[string]*prometheus.HistogramVec
+	buckets      map[string][]float64
+	nativeFactor float64
+}
+
+// Describe implements prometheus.Collector.
+func (h *routeHistograms) Describe(ch chan<- *prometheus.Desc) {
+	h.defaultVec.Describe(ch)
+}
+
+// Collect implements prometheus.Collector.
+func (h *routeHistograms) Collect(ch chan<- prometheus.Metric) {
+	h.defaultVec.Collect(ch)
+
+	for _, vec := range h.routes {
+		vec.Collect(ch)
+	}
+}
+
+// sameBuckets returns true if the histograms were created with the same route
+// buckets and native histogram settings.
+func (h *routeHistograms) sameBuckets(other *routeHistograms) bool {
+	return h.nativeFactor == other.nativeFactor && maps.EqualFunc(h.buckets, other.buckets, slices.Equal)
+}
+
+func (h *routeHistograms) forRoute(route string) *prometheus.HistogramVec {
+	if vec, ok := h.routes[route]; ok {
+		return vec
+	}
+
+	return h.defaultVec
+}
+
+// registerOrExisting registers the collector on the registerer. If an
+// identical collector is already registered, that collector is returned
+// instead. The collector isn't registered if the registerer is nil.
+func registerOrExisting[T prometheus.Collector](registerer prometheus.Registerer, collector T) T {
+	if registerer == nil {
+		return collector
+	}
+
+	if err := registerer.Register(collector); err != nil {
+		var are prometheus.AlreadyRegisteredError
+		if errors.As(err, &are) {
+			if existing, ok := are.ExistingCollector.(T); ok {
+				return existing
+			}
+		}
+
+		panic(err)
+	}
+
+	return collector
+}
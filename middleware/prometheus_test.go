@@ -0,0 +1,175 @@
+package middleware
+
+import (
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+)
+
+func Test_Prometheus(t *testing.T) {
+	// Using the middleware multiple times with the default registerer should
+	// not panic.
+	_ = Prometheus()
+	_ = Prometheus()
+
+	registry := prometheus.NewRegistry()
+
+	handlerWithMiddleware := AddMiddlewares(
+		http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.URL.Path == "/missing" {
+				w.WriteHeader(http.StatusNotFound)
+			}
+		}),
+		Prometheus(
+			WithRegisterer(registry),
+			WithRouteLabel(func(r *http.Request) string { return r.URL.Path }),
+			WithRouteBuckets("/export", []float64{30, 60}),
+			WithNativeHistograms(1.1),
+		),
+	)
+
+	for _, path := range []string{"/", "/missing", "/export"} {
+		handlerWithMiddleware.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, path, nil))
+	}
+
+	families, err := registry.Gather()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var durations []*dto.Metric
+
+	for _, family := range families {
+		if family.GetName() == "request_duration_seconds" {
+			durations = family.GetMetric()
+		}
+	}
+
+	if len(durations) != 3 {
+		t.Fatalf("unexpected number of duration metrics: %d", len(durations))
+	}
+
+	for _, metric := range durations {
+		labels := map[string]string{}
+		for _, label := range metric.GetLabel() {
+			labels[label.GetName()] = label.GetValue()
+		}
+
+		expectedClass := "2xx"
+		if labels["route"] == "/missing" {
+			expectedClass = "4xx"
+		}
+
+		if labels["status_class"] != expectedClass {
+			t.Fatalf("unexpected status class for %s: %s", labels["route"], labels["status_class"])
+		}
+
+		histogram := metric.GetHistogram()
+		if histogram.GetSchema() == 0 && histogram.GetZeroThreshold() == 0 {
+			t.Fatal("native histogram not enabled")
+		}
+
+		if labels["route"] == "/export" && histogram.GetBucket()[0].GetUpperBound() != 30 {
+			t.Fatal("route buckets not used")
+		}
+	}
+}
+
+func Test_PrometheusOtherBuckets(t *testing.T) {
+	registry := prometheus.NewRegistry()
+
+	// Using the middleware again with the same buckets reuses the collectors.
+	_ = Prometheus(WithRegisterer(registry), WithRouteBuckets("/export", []float64{30, 60}))
+	_ = Prometheus(WithRegisterer(registry), WithRouteBuckets("/export", []float64{30, 60}))
+
+	defer func() {
+		if recover() == nil {
+			t.Fatal("expected panic when using other buckets")
+		}
+	}()
+
+	_ = Prometheus(WithRegisterer(registry), WithRouteBuckets("/export", []float64{10}))
+}
+
+func Test_MiddlewareMetrics(t *testing.T) {
+	registry := prometheus.NewRegistry()
+
+	handler := AddMiddlewares(
+		http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.URL.Path == "/panic" {
+				panic("oops")
+			}
+		}),
+		Cache(time.Minute, WithRegisterer(registry)),
+		NewRateLimiter(
+			WithRegisterer(registry),
+			WithRateLimit(time.Hour, 3),
+			WithRateLimitKey(func(r *http.Request) string { return "tenant" }),
+		),
+		NewPanicRecovery(WithRegisterer(registry), WithLogger(slog.New(slog.NewTextHandler(io.Discard, nil)))),
+	)
+
+	for _, path := range []string{"/", "/", "/panic", "/"} {
+		handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, path, nil))
+	}
+
+	values := gatheredValues(t, registry)
+
+	for key, expected := range map[string]float64{
+		"cache_requests_total,result=hit":  1,
+		"cache_requests_total,result=miss": 1,
+		"rate_limiter_rejected_total":      1,
+		"panics_recovered_total":           1,
+	} {
+		if values[key] != expected {
+			t.Fatalf("unexpected value for %s, got: %v, expected: %v", key, values[key], expected)
+		}
+	}
+
+	// The metrics are only registered when opting in with WithRegisterer.
+	_ = AddMiddlewares(http.NotFoundHandler(), Cache(time.Minute), NewRateLimiter(), NewPanicRecovery())
+
+	families, err := prometheus.DefaultGatherer.Gather()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, family := range families {
+		switch family.GetName() {
+		case "cache_requests_total", "rate_limiter_rejected_total", "panics_recovered_total":
+			t.Fatalf("unexpected metric registered without WithRegisterer: %s", family.GetName())
+		}
+	}
+}
+
+// gatheredValues returns the value of each gathered gauge and counter, keyed
+// by the metric name and its labels, e.g. "name,label=value".
+func gatheredValues(t *testing.T, registry *prometheus.Registry) map[string]float64 {
+	t.Helper()
+
+	families, err := registry.Gather()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	values := map[string]float64{}
+
+	for _, family := range families {
+		for _, metric := range family.GetMetric() {
+			key := family.GetName()
+			for _, label := range metric.GetLabel() {
+				key += "," + label.GetName() + "=" + label.GetValue()
+			}
+
+			values[key] = metric.GetGauge().GetValue() + metric.GetCounter().GetValue()
+		}
+	}
+
+	return values
+}
@@ -0,0 +1,43 @@
+package middleware
+
+import (
+	"net/http"
+
+	"github.com/bombsimon/http-helpers/httpctx"
+)
+
+// PropagateHeaders captures the named headers from the incoming request in the
+// request context, available with httpctx.PropagatedHeaders, so they're
+// attached to outgoing requests made with the client.PropagateHeaders
+// tripperware, e.g. the request ID, tenant and auth context. The request ID
+// header, set with WithRequestIDHeader, falls back to the ID set by the
+// RequestID middleware so generated IDs are propagated too if it's added
+// before this middleware.
+func PropagateHeaders(names []string, opts ...Option) Middleware {
+	options := newOptions(opts...)
+
+	return options.skippable(func(h http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			header := http.Header{}
+			if parent, ok := httpctx.PropagatedHeaders(r.Context()); ok {
+				header = parent.Clone()
+			}
+
+			for _, name := range names {
+				values := r.Header.Values(name)
+
+				if len(values) == 0 && http.CanonicalHeaderKey(name) == http.CanonicalHeaderKey(options.requestIDHeader) {
+					if requestID, ok := httpctx.RequestID(r.Context()); ok {
+						values = []string{requestID}
+					}
+				}
+
+				if len(values) > 0 {
+					header[http.CanonicalHeaderKey(name)] = values
+				}
+			}
+
+			h.ServeHTTP(w, r.WithContext(httpctx.WithPropagatedHeaders(r.Context(), header)))
+		})
+	})
+}
@@ -0,0 +1,63 @@
+package middleware
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/bombsimon/http-helpers/client"
+)
+
+func Test_PropagateHeaders(t *testing.T) {
+	var received http.Header
+
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		received = r.Header.Clone()
+	}))
+	defer upstream.Close()
+
+	httpClient := &http.Client{
+		Transport: client.WrapTransport(http.DefaultTransport, client.PropagateHeaders()),
+	}
+
+	handler := AddMiddlewares(
+		http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			req, _ := http.NewRequestWithContext(r.Context(), http.MethodGet, upstream.URL, nil)
+
+			resp, err := httpClient.Do(req)
+			if err != nil {
+				t.Error(err)
+				return
+			}
+
+			_, _ = io.Copy(io.Discard, resp.Body)
+			resp.Body.Close()
+		}),
+		PropagateHeaders([]string{"X-Request-Id", "x-tenant-id", "X-Missing"}),
+		RequestID(),
+	)
+
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Header.Set("X-Tenant-Id", "acme")
+	req.Header.Set("Authorization", "Bearer secret")
+
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, req)
+
+	if requestID := rr.Header().Get("X-Request-Id"); requestID == "" || received.Get("X-Request-Id") != requestID {
+		t.Fatalf("expected generated request ID %q to be propagated, got: %v", requestID, received)
+	}
+
+	if received.Get("X-Tenant-Id") != "acme" {
+		t.Fatalf("expected tenant to be propagated, got: %v", received)
+	}
+
+	if received.Get("Authorization") != "" {
+		t.Fatal("expected only the named headers to be propagated")
+	}
+
+	if _, ok := received["X-Missing"]; ok {
+		t.Fatal("expected missing headers to not be propagated")
+	}
+}
@@ -0,0 +1,258 @@
+package middleware
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"golang.org/x/time/rate"
+
+	"github.com/bombsimon/http-helpers/client"
+	"github.com/bombsimon/http-helpers/clock"
+)
+
+// ErrRateLimited is returned, wrapped with the host, by ClientRateLimiter with
+// WithRateLimitFailFast when the limit is exceeded.
+var ErrRateLimited = errors.New("rate limit exceeded")
+
+// Limiter limits the rate of events. It's implemented by *rate.Limiter and the
+// limiters returned by NewSlidingWindowLimiter and NewGCRALimiter.
+type Limiter interface {
+	Allow() bool
+	Wait(ctx context.Context) error
+}
+
+// RetryAfterLimiter is a Limiter that can tell how long to wait when an event
+// isn't allowed. NewRateLimiter uses it for the Retry-After header, limiters
+// not implementing it get the interval set with WithRateLimit. It's
+// implemented by the limiters returned by NewLimiterStore,
+// NewSlidingWindowLimiter and NewGCRALimiter.
+type RetryAfterLimiter interface {
+	Limiter
+
+	// TryAllow returns true if the event is allowed, otherwise how long to
+	// wait before it may be.
+	TryAllow() (time.Duration, bool)
+}
+
+// LimiterStore returns the limiter for a key. It's shared by NewRateLimiter and
+// ClientRateLimiter so the limits can be enforced across processes by
+// implementing it with a shared backend, e.g. Redis.
+type LimiterStore interface {
+	Limiter(key string) Limiter
+}
+
+// NewLimiterStore returns an in-process store creating a token bucket
+// *rate.Limiter for each key, allowing one event per interval with bursts of up
+// to burst events. Limiters not used within the idle timeout set with
+// WithLimiterIdleTimeout, or the time to refill the bucket if longer, are
+// evicted. Use WithClock to refill the limiters with a clock.Fake in tests
+// instead of sleeping.
+func NewLimiterStore(interval time.Duration, burst int, opts ...Option) LimiterStore {
+	options := newOptions(opts...)
+
+	// An evicted bucket is recreated full, so keep it until it's refilled.
+	options.limiterIdleTimeout = max(options.limiterIdleTimeout, interval*time.Duration(burst))
+
+	return newLimiterStore(func() Limiter {
+		return &clockLimiter{
+			limiter: rate.NewLimiter(rate.Every(interval), burst),
+			clock:   options.clock,
+		}
+	}, options)
+}
+
+// NewKeyedLimiterStore returns an in-process store creating a limiter with
+// newLimiter for each key, e.g. with NewSlidingWindowLimiter or
+// NewGCRALimiter. Limiters not used within the idle timeout set with
+// WithLimiterIdleTimeout are evicted, so it should be longer than the window
+// of the limiters. Use WithClock to evict them with a clock.Fake in tests.
+func NewKeyedLimiterStore(newLimiter func() Limiter, opts ...Option) LimiterStore {
+	return newLimiterStore(newLimiter, newOptions(opts...))
+}
+
+func newLimiterStore(newLimiter func() Limiter, options *options) *limiterStore {
+	return &limiterStore{
+		newLimiter:  newLimiter,
+		limiters:    make(map[string]*limiterEntry),
+		clock:       options.clock,
+		idleTimeout: options.limiterIdleTimeout,
+		lastSweep:   options.clock.Now(),
+	}
+}
+
+type limiterStore struct {
+	mu          sync.Mutex
+	newLimiter  func() Limiter
+	limiters    map[string]*limiterEntry
+	clock       clock.Clock
+	idleTimeout time.Duration
+	lastSweep   time.Time
+}
+
+type limiterEntry struct {
+	limiter  Limiter
+	lastUsed time.Time
+}
+
+func (s *limiterStore) Limiter(key string) Limiter {
+	now := s.clock.Now()
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	// Sweep at most once per idle timeout so the cost is spread over the
+	// calls in between.
+	if now.Sub(s.lastSweep) >= s.idleTimeout {
+		for k, entry := range s.limiters {
+			if now.Sub(entry.lastUsed) >= s.idleTimeout {
+				delete(s.limiters, k)
+			}
+		}
+
+		s.lastSweep = now
+	}
+
+	entry, ok := s.limiters[key]
+	if !ok {
+		entry = &limiterEntry{limiter: s.newLimiter()}
+		s.limiters[key] = entry
+	}
+
+	entry.lastUsed = now
+
+	return entry.limiter
+}
+
+// clockLimiter is a *rate.Limiter telling the time with a clock.Clock.
+type clockLimiter struct {
+	limiter *rate.Limiter
+	clock   clock.Clock
+}
+
+func (l *clockLimiter) Allow() bool {
+	return l.limiter.AllowN(l.clock.Now(), 1)
+}
+
+func (l *clockLimiter) TryAllow() (time.Duration, bool) {
+	now := l.clock.Now()
+
+	// The reservation is only not OK with a burst of 0 which never allows
+	// an event.
+	reservation := l.limiter.ReserveN(now, 1)
+	if !reservation.OK() {
+		return 0, false
+	}
+
+	if delay := reservation.DelayFrom(now); delay > 0 {
+		reservation.CancelAt(now)
+		return delay, false
+	}
+
+	return 0, true
+}
+
+func (l *clockLimiter) Wait(ctx context.Context) error {
+	now := l.clock.Now()
+
+	reservation := l.limiter.ReserveN(now, 1)
+	if !reservation.OK() {
+		return fmt.Errorf("rate: Wait(n=1) exceeds limiter's burst %d", l.limiter.Burst())
+	}
+
+	delay := reservation.DelayFrom(now)
+	if delay == 0 {
+		return nil
+	}
+
+	timer := l.clock.NewTimer(delay)
+	defer timer.Stop()
+
+	select {
+	case <-timer.C():
+		return nil
+	case <-ctx.Done():
+		reservation.CancelAt(l.clock.Now())
+		return ctx.Err()
+	}
+}
+
+// WithLimiterStore sets the store used by NewRateLimiter and ClientRateLimiter.
+// Defaults to NewLimiterStore with the limit set with WithRateLimit.
+func WithLimiterStore(store LimiterStore) Option {
+	return func(o *options) {
+		o.limiterStore = store
+	}
+}
+
+// WithLimiterIdleTimeout sets how long the limiter for a key is kept by
+// NewLimiterStore and NewKeyedLimiterStore without being used. Defaults to 10
+// minutes.
+func WithLimiterIdleTimeout(timeout time.Duration) Option {
+	return func(o *options) {
+		o.limiterIdleTimeout = timeout
+	}
+}
+
+// WithRateLimitKey sets the function returning the key to rate limit a request
+// by. NewRateLimiter defaults to the same key for all requests and
+// ClientRateLimiter to the host of the request.
+func WithRateLimitKey(fn func(*http.Request) string) Option {
+	return func(o *options) {
+		o.rateLimitKey = fn
+	}
+}
+
+// WithRateLimitFailFast makes ClientRateLimiter return an error wrapping
+// ErrRateLimited instead of waiting when the limit is exceeded.
+func WithRateLimitFailFast() Option {
+	return func(o *options) {
+		o.rateLimitFailFast = true
+	}
+}
+
+// ClientRateLimiter returns a client tripperware rate limiting outgoing
+// requests per host, e.g. to respect the quota of a third party API. Requests
+// wait until they're allowed or the request context is done, or fail
+// immediately with WithRateLimitFailFast. Use WithRateLimit to set the limit
+// and WithLimiterStore to share it between processes.
+func ClientRateLimiter(opts ...Option) client.Tripperware {
+	options := newOptions(opts...)
+	store := options.limiters()
+
+	key := options.rateLimitKey
+	if key == nil {
+		key = func(r *http.Request) string {
+			return r.URL.Host
+		}
+	}
+
+	return func(rt http.RoundTripper) http.RoundTripper {
+		return client.RoundTripperFunc(func(r *http.Request) (*http.Response, error) {
+			limiter := store.Limiter(key(r))
+
+			if options.rateLimitFailFast {
+				if !limiter.Allow() {
+					return nil, fmt.Errorf("%w: %s", ErrRateLimited, r.URL.Host)
+				}
+			} else if err := limiter.Wait(r.Context()); err != nil {
+				return nil, err
+			}
+
+			return rt.RoundTrip(r)
+		})
+	}
+}
+
+// limiters returns the store set with WithLimiterStore or an in-process store
+// with the limit set with WithRateLimit.
+func (o *options) limiters() LimiterStore {
+	if o.limiterStore != nil {
+		return o.limiterStore
+	}
+
+	return NewLimiterStore(o.interval, o.burst, WithClock(o.clock), WithLimiterIdleTimeout(o.limiterIdleTimeout))
+}
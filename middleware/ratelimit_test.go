@@ -0,0 +1,141 @@
+package middleware
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/bombsimon/http-helpers/client"
+	"github.com/bombsimon/http-helpers/clock"
+)
+
+func Test_ClientRateLimiter(t *testing.T) {
+	transport := client.RoundTripperFunc(func(r *http.Request) (*http.Response, error) {
+		return &http.Response{StatusCode: http.StatusOK, Body: http.NoBody, Request: r}, nil
+	})
+
+	send := func(ctx context.Context, rt http.RoundTripper, host string) error {
+		req, _ := http.NewRequestWithContext(ctx, http.MethodGet, "http://"+host+"/", nil)
+
+		_, err := rt.RoundTrip(req)
+
+		return err
+	}
+
+	t.Run("fail fast per host", func(t *testing.T) {
+		rt := client.WrapTransport(transport, ClientRateLimiter(
+			WithRateLimit(time.Hour, 1),
+			WithRateLimitFailFast(),
+		))
+
+		if err := send(context.Background(), rt, "a.example.com"); err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+
+		if err := send(context.Background(), rt, "a.example.com"); !errors.Is(err, ErrRateLimited) {
+			t.Fatalf("expected ErrRateLimited, got: %v", err)
+		}
+
+		if err := send(context.Background(), rt, "b.example.com"); err != nil {
+			t.Fatalf("expected hosts to be limited separately, got: %s", err)
+		}
+	})
+
+	t.Run("blocking", func(t *testing.T) {
+		clk := clock.NewFake(time.Now())
+		rt := client.WrapTransport(transport, ClientRateLimiter(
+			WithRateLimit(time.Minute, 1),
+			WithClock(clk),
+		))
+
+		if err := send(context.Background(), rt, "a.example.com"); err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+
+		done := make(chan error, 1)
+
+		go func() {
+			done <- send(context.Background(), rt, "a.example.com")
+		}()
+
+		// The second request waits for the limiter until the clock advances.
+		clk.BlockUntil(1)
+
+		select {
+		case err := <-done:
+			t.Fatalf("expected request to wait for the limiter, got: %v", err)
+		default:
+		}
+
+		clk.Advance(time.Minute)
+
+		if err := <-done; err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+
+		if err := send(ctx, rt, "a.example.com"); err == nil {
+			t.Fatal("expected error when the context is done")
+		}
+	})
+
+	t.Run("shared store", func(t *testing.T) {
+		store := NewLimiterStore(time.Hour, 1)
+
+		rt := client.WrapTransport(transport, ClientRateLimiter(
+			WithLimiterStore(store),
+			WithRateLimitFailFast(),
+			WithRateLimitKey(func(*http.Request) string { return "partner-api" }),
+		))
+
+		handler := AddMiddlewares(
+			http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}),
+			NewRateLimiter(
+				WithLimiterStore(store),
+				WithRateLimitKey(func(*http.Request) string { return "partner-api" }),
+			),
+		)
+
+		if err := send(context.Background(), rt, "a.example.com"); err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+
+		rr := httptest.NewRecorder()
+		handler.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/", nil))
+
+		if rr.Code != http.StatusTooManyRequests {
+			t.Fatalf("expected the limit to be shared through the store, got: %d", rr.Code)
+		}
+	})
+}
+
+func Test_LimiterStoreEviction(t *testing.T) {
+	clk := clock.NewFake(time.Now())
+	store := NewLimiterStore(time.Second, 1, WithClock(clk), WithLimiterIdleTimeout(time.Minute)).(*limiterStore)
+
+	for i := range 3 {
+		store.Limiter(strconv.Itoa(i))
+	}
+
+	clk.Advance(30 * time.Second)
+	store.Limiter("0")
+
+	// The sweep only evicts the limiters idle for the whole timeout.
+	clk.Advance(30 * time.Second)
+	store.Limiter("3")
+
+	if len(store.limiters) != 2 {
+		t.Fatalf("unexpected number of limiters, got: %d, expected: %d", len(store.limiters), 2)
+	}
+
+	// The idle timeout is at least the time to refill the bucket.
+	if store := NewLimiterStore(time.Hour, 2, WithLimiterIdleTimeout(time.Minute)).(*limiterStore); store.idleTimeout != 2*time.Hour {
+		t.Fatalf("unexpected idle timeout, got: %s, expected: %s", store.idleTimeout, 2*time.Hour)
+	}
+}
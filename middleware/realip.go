@@ -0,0 +1,74 @@
+package middleware
+
+import (
+	"net"
+	"net/http"
+	"net/netip"
+	"strings"
+
+	"github.com/bombsimon/http-helpers/httpctx"
+)
+
+// RealIP sets the client IP on the request context, available with
+// httpctx.RealIP. The X-Forwarded-For and X-Real-Ip headers are only used if
+// the request comes from a proxy added with WithTrustedProxies, otherwise the
+// remote address is used. X-Forwarded-For is read from right to left and the
+// first address not belonging to a trusted proxy is used so clients can't
+// spoof their IP by sending the header themselves.
+func RealIP(opts ...Option) Middleware {
+	options := newOptions(opts...)
+
+	return options.skippable(func(h http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			ip := realIP(r, options.trustedProxies)
+
+			h.ServeHTTP(w, r.WithContext(httpctx.WithRealIP(r.Context(), ip)))
+		})
+	})
+}
+
+func realIP(r *http.Request, trusted []netip.Prefix) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		host = r.RemoteAddr
+	}
+
+	remote, err := netip.ParseAddr(host)
+	if err != nil || !isTrusted(remote, trusted) {
+		return host
+	}
+
+	var forwarded []string
+	for _, value := range r.Header.Values("X-Forwarded-For") {
+		forwarded = append(forwarded, strings.Split(value, ",")...)
+	}
+
+	for i := len(forwarded) - 1; i >= 0; i-- {
+		addr, err := netip.ParseAddr(strings.TrimSpace(forwarded[i]))
+		if err != nil {
+			break
+		}
+
+		if !isTrusted(addr, trusted) {
+			return addr.String()
+		}
+	}
+
+	if addr, err := netip.ParseAddr(r.Header.Get("X-Real-Ip")); err == nil {
+		return addr.String()
+	}
+
+	return host
+}
+
+func isTrusted(addr netip.Addr, trusted []netip.Prefix) bool {
+	addr = addr.Unmap()
+
+	for _, prefix := range trusted {
+		if prefix.Contains(addr) {
+			return true
+		}
+	}
+
+	return false
+}
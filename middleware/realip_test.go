@@ -0,0 +1,87 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"testing"
+
+	"github.com/bombsimon/http-helpers/httpctx"
+)
+
+func Test_RealIP(t *testing.T) {
+	trusted := WithTrustedProxies(netip.MustParsePrefix("10.0.0.0/8"))
+
+	cases := []struct {
+		description string
+		remoteAddr  string
+		headers     map[string][]string
+		expected    string
+	}{
+		{
+			description: "no headers",
+			remoteAddr:  "192.0.2.1:1234",
+			expected:    "192.0.2.1",
+		},
+		{
+			description: "untrusted proxy",
+			remoteAddr:  "192.0.2.1:1234",
+			headers:     map[string][]string{"X-Forwarded-For": {"198.51.100.1"}},
+			expected:    "192.0.2.1",
+		},
+		{
+			description: "trusted proxy",
+			remoteAddr:  "10.0.0.1:1234",
+			headers:     map[string][]string{"X-Forwarded-For": {"198.51.100.1"}},
+			expected:    "198.51.100.1",
+		},
+		{
+			description: "spoofed header before trusted proxies",
+			remoteAddr:  "10.0.0.1:1234",
+			headers: map[string][]string{
+				"X-Forwarded-For": {"203.0.113.1, 198.51.100.1", "10.0.0.2"},
+			},
+			expected: "198.51.100.1",
+		},
+		{
+			description: "real ip header",
+			remoteAddr:  "10.0.0.1:1234",
+			headers:     map[string][]string{"X-Real-Ip": {"198.51.100.1"}},
+			expected:    "198.51.100.1",
+		},
+		{
+			description: "invalid forwarded for",
+			remoteAddr:  "10.0.0.1:1234",
+			headers:     map[string][]string{"X-Forwarded-For": {"unknown"}},
+			expected:    "10.0.0.1",
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.description, func(t *testing.T) {
+			var got string
+
+			handler := AddMiddlewares(
+				http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+					got, _ = httpctx.RealIP(r.Context())
+				}),
+				RealIP(trusted),
+			)
+
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			req.RemoteAddr = tc.remoteAddr
+
+			for k, values := range tc.headers {
+				for _, v := range values {
+					req.Header.Add(k, v)
+				}
+			}
+
+			handler.ServeHTTP(httptest.NewRecorder(), req)
+
+			if got != tc.expected {
+				t.Fatalf("unexpected real ip, got: %s, expected: %s", got, tc.expected)
+			}
+		})
+	}
+}
@@ -0,0 +1,144 @@
+package middleware
+
+import (
+	"net"
+	"net/http"
+	"net/netip"
+	"strings"
+)
+
+// acmeChallengePrefix is the path prefix of ACME HTTP-01 challenges, which
+// must be served over plain HTTP.
+const acmeChallengePrefix = "/.well-known/acme-challenge/"
+
+// WWW is how RedirectHTTPS normalizes the www. prefix of the host.
+type WWW int
+
+const (
+	// WWWKeep leaves the host as is.
+	WWWKeep WWW = iota
+
+	// WWWStrip redirects www.example.com to example.com.
+	WWWStrip
+
+	// WWWAdd redirects example.com to www.example.com.
+	WWWAdd
+)
+
+// WithCanonicalHost makes RedirectHTTPS redirect requests for any other host
+// to the host, e.g. "example.com".
+func WithCanonicalHost(host string) Option {
+	return func(o *options) {
+		o.canonicalHost = host
+	}
+}
+
+// WithWWW sets how RedirectHTTPS normalizes the www. prefix of the host.
+// Defaults to WWWKeep.
+func WithWWW(policy WWW) Option {
+	return func(o *options) {
+		o.www = policy
+	}
+}
+
+// WithRedirectExemptPaths sets path prefixes RedirectHTTPS never redirects,
+// e.g. "/healthz" for load balancer health checks over plain HTTP. ACME
+// HTTP-01 challenges are always exempt.
+func WithRedirectExemptPaths(prefixes ...string) Option {
+	return func(o *options) {
+		o.redirectExemptPaths = append(o.redirectExemptPaths, prefixes...)
+	}
+}
+
+// RedirectHTTPS redirects plain HTTP requests to HTTPS and, with
+// WithCanonicalHost or WithWWW, requests for other hosts to the canonical
+// host. Requests are considered HTTPS if they were received over TLS or have
+// X-Forwarded-Proto: https from a proxy added with WithTrustedProxies. GET and
+// HEAD requests are redirected with 301 Moved Permanently and other methods
+// with 308 Permanent Redirect so the method and body are kept, and the port is
+// dropped when redirecting to HTTPS. ACME HTTP-01 challenges and paths added
+// with WithRedirectExemptPaths are never redirected.
+func RedirectHTTPS(opts ...Option) Middleware {
+	options := newOptions(opts...)
+	exempt := append([]string{acmeChallengePrefix}, options.redirectExemptPaths...)
+
+	return options.skippable(func(h http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			for _, prefix := range exempt {
+				if strings.HasPrefix(r.URL.Path, prefix) {
+					h.ServeHTTP(w, r)
+					return
+				}
+			}
+
+			https := isHTTPS(r, options.trustedProxies)
+			host := canonicalHost(r.Host, options)
+
+			if https && host == r.Host {
+				h.ServeHTTP(w, r)
+				return
+			}
+
+			// The port of a plain HTTP request isn't the HTTPS port.
+			if hostname, _, err := net.SplitHostPort(host); err == nil && !https {
+				host = hostname
+				if strings.Contains(host, ":") {
+					host = "[" + host + "]"
+				}
+			}
+
+			status := http.StatusPermanentRedirect
+			if r.Method == http.MethodGet || r.Method == http.MethodHead {
+				status = http.StatusMovedPermanently
+			}
+
+			http.Redirect(w, r, "https://"+host+r.URL.RequestURI(), status)
+		})
+	})
+}
+
+// canonicalHost returns the host the request should be for.
+func canonicalHost(host string, options *options) string {
+	if options.canonicalHost != "" {
+		return options.canonicalHost
+	}
+
+	switch options.www {
+	case WWWStrip:
+		if len(host) > 4 && strings.EqualFold(host[:4], "www.") {
+			return host[4:]
+		}
+	case WWWAdd:
+		hostname := host
+		if h, _, err := net.SplitHostPort(host); err == nil {
+			hostname = h
+		}
+
+		if _, err := netip.ParseAddr(hostname); err != nil && hostname != "localhost" &&
+			!strings.HasPrefix(strings.ToLower(host), "www.") {
+			return "www." + host
+		}
+	}
+
+	return host
+}
+
+// isHTTPS returns true if the request was received over TLS, or forwarded
+// over HTTPS by a trusted proxy.
+func isHTTPS(r *http.Request, trusted []netip.Prefix) bool {
+	if r.TLS != nil {
+		return true
+	}
+
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		host = r.RemoteAddr
+	}
+
+	remote, err := netip.ParseAddr(host)
+	if err != nil || !isTrusted(remote, trusted) {
+		return false
+	}
+
+	return strings.EqualFold(r.Header.Get("X-Forwarded-Proto"), "https")
+}
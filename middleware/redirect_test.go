@@ -0,0 +1,124 @@
+package middleware
+
+import (
+	"crypto/tls"
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"testing"
+)
+
+func Test_RedirectHTTPS(t *testing.T) {
+	proxy := WithTrustedProxies(netip.MustParsePrefix("10.0.0.0/8"))
+
+	for _, tc := range []struct {
+		description      string
+		opts             []Option
+		method           string
+		url              string
+		remoteAddr       string
+		tls              bool
+		forwardedProto   string
+		expectedStatus   int
+		expectedLocation string
+	}{
+		{
+			description:      "plain http",
+			url:              "http://example.com:8080/users?page=2",
+			expectedStatus:   http.StatusMovedPermanently,
+			expectedLocation: "https://example.com/users?page=2",
+		},
+		{
+			description:      "plain http post keeps method",
+			method:           http.MethodPost,
+			url:              "http://example.com/users",
+			expectedStatus:   http.StatusPermanentRedirect,
+			expectedLocation: "https://example.com/users",
+		},
+		{
+			description:    "tls",
+			url:            "https://example.com/users",
+			tls:            true,
+			expectedStatus: http.StatusOK,
+		},
+		{
+			description:    "https from trusted proxy",
+			opts:           []Option{proxy},
+			url:            "http://example.com/users",
+			remoteAddr:     "10.0.0.1:1234",
+			forwardedProto: "https",
+			expectedStatus: http.StatusOK,
+		},
+		{
+			description:      "https from untrusted client",
+			opts:             []Option{proxy},
+			url:              "http://example.com/users",
+			forwardedProto:   "https",
+			expectedStatus:   http.StatusMovedPermanently,
+			expectedLocation: "https://example.com/users",
+		},
+		{
+			description:    "acme challenge",
+			url:            "http://example.com/.well-known/acme-challenge/token",
+			expectedStatus: http.StatusOK,
+		},
+		{
+			description:    "exempt health check",
+			opts:           []Option{WithRedirectExemptPaths("/healthz")},
+			url:            "http://10.0.0.5/healthz",
+			expectedStatus: http.StatusOK,
+		},
+		{
+			description:      "strip www",
+			opts:             []Option{WithWWW(WWWStrip)},
+			url:              "https://www.example.com/users",
+			tls:              true,
+			expectedStatus:   http.StatusMovedPermanently,
+			expectedLocation: "https://example.com/users",
+		},
+		{
+			description:      "add www",
+			opts:             []Option{WithWWW(WWWAdd)},
+			url:              "http://example.com/users",
+			expectedStatus:   http.StatusMovedPermanently,
+			expectedLocation: "https://www.example.com/users",
+		},
+		{
+			description:      "canonical host",
+			opts:             []Option{WithCanonicalHost("example.com")},
+			url:              "https://example.net/users",
+			tls:              true,
+			expectedStatus:   http.StatusMovedPermanently,
+			expectedLocation: "https://example.com/users",
+		},
+	} {
+		t.Run(tc.description, func(t *testing.T) {
+			method := tc.method
+			if method == "" {
+				method = http.MethodGet
+			}
+
+			req := httptest.NewRequest(method, tc.url, nil)
+			if !tc.tls {
+				req.TLS = nil
+			} else if req.TLS == nil {
+				req.TLS = &tls.ConnectionState{}
+			}
+
+			if tc.remoteAddr != "" {
+				req.RemoteAddr = tc.remoteAddr
+			}
+
+			if tc.forwardedProto != "" {
+				req.Header.Set("X-Forwarded-Proto", tc.forwardedProto)
+			}
+
+			rec := httptest.NewRecorder()
+			AddMiddlewares(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}), RedirectHTTPS(tc.opts...)).ServeHTTP(rec, req)
+
+			if rec.Code != tc.expectedStatus || rec.Header().Get("Location") != tc.expectedLocation {
+				t.Fatalf("unexpected response, got: %d %s, expected: %d %s", rec.Code, rec.Header().Get("Location"), tc.expectedStatus, tc.expectedLocation)
+			}
+		})
+	}
+}
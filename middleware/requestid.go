@@ -0,0 +1,40 @@
+package middleware
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"net/http"
+
+	"github.com/bombsimon/http-helpers/httpctx"
+)
+
+// maxRequestIDLength is the longest request ID accepted from the client.
+const maxRequestIDLength = 128
+
+// RequestID sets a request ID on the request context, available with
+// httpctx.RequestID, and on the response header. The ID from the request
+// header is used if set, otherwise a random ID is generated. The header
+// defaults to X-Request-Id and can be changed with WithRequestIDHeader.
+func RequestID(opts ...Option) Middleware {
+	options := newOptions(opts...)
+
+	return options.skippable(func(h http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			requestID := r.Header.Get(options.requestIDHeader)
+			if requestID == "" || len(requestID) > maxRequestIDLength {
+				requestID = newRequestID()
+			}
+
+			w.Header().Set(options.requestIDHeader, requestID)
+
+			h.ServeHTTP(w, r.WithContext(httpctx.WithRequestID(r.Context(), requestID)))
+		})
+	})
+}
+
+func newRequestID() string {
+	b := make([]byte, 16)
+	_, _ = rand.Read(b)
+
+	return hex.EncodeToString(b)
+}
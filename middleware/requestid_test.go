@@ -0,0 +1,45 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/bombsimon/http-helpers/httpctx"
+)
+
+func Test_RequestID(t *testing.T) {
+	var got string
+
+	handler := AddMiddlewares(
+		http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			got, _ = httpctx.RequestID(r.Context())
+		}),
+		RequestID(),
+	)
+
+	for incoming, expectGenerated := range map[string]bool{
+		"":                       true,
+		"some-id":                false,
+		strings.Repeat("a", 129): true,
+	} {
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		req.Header.Set("X-Request-Id", incoming)
+
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+
+		if rec.Header().Get("X-Request-Id") != got {
+			t.Fatalf("response header doesn't match context, got: %s, expected: %s", rec.Header().Get("X-Request-Id"), got)
+		}
+
+		if expectGenerated && len(got) != 32 {
+			t.Fatalf("expected generated request id, got: %s", got)
+		}
+
+		if !expectGenerated && got != incoming {
+			t.Fatalf("unexpected request id, got: %s, expected: %s", got, incoming)
+		}
+	}
+}
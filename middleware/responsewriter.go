@@ -0,0 +1,515 @@
+package middleware
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"log/slog"
+	"net"
+	"net/http"
+	"runtime"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// ResponseWriterWithInfo is a response writer that can hold additional
+// information which can help enrich code executed as middlewares.
+type ResponseWriterWithInfo struct {
+	http.ResponseWriter
+	statusCode    int
+	responseError error
+	bytesWritten  int64
+	onWrite       []func(n int, total int64)
+	createdAt     time.Time
+	firstByteAt   time.Time
+	wroteHeader   bool
+	devLogger     *slog.Logger
+	errorHandled  bool
+	variant       string
+	hijacked      *hijackState
+
+	withInterfaces http.ResponseWriter
+}
+
+// infoHolder is implemented by *ResponseWriterWithInfo and all the types
+// returned by WithInterfaces.
+type infoHolder interface {
+	withInfo() *ResponseWriterWithInfo
+}
+
+// NewResponseWriter will convert the response writer to a
+// *ResponseWriterWithInfo if it is one, otherwise wrap the response writer in
+// such type.
+func NewResponseWriter(r http.ResponseWriter) *ResponseWriterWithInfo {
+	if rw, ok := r.(infoHolder); ok {
+		return rw.withInfo()
+	}
+
+	return &ResponseWriterWithInfo{
+		ResponseWriter: r,
+		statusCode:     http.StatusOK,
+		createdAt:      time.Now(),
+	}
+}
+
+// WriteHeader will write the header to the response witer and store the status
+// that was written. Only the first call will be passed to the underlying
+// response writer, subsequent calls are ignored. Informational (1xx) status
+// codes other than 101 may be written multiple times before the final status.
+func (r *ResponseWriterWithInfo) WriteHeader(code int) {
+	if r.wroteHeader {
+		if r.devLogger != nil {
+			_, file, line, _ := runtime.Caller(1)
+			r.devLogger.Warn(
+				"superfluous WriteHeader call, status already written",
+				slog.Int("code", code),
+				slog.String("caller", fmt.Sprintf("%s:%d", file, line)),
+				slog.Int("status", r.statusCode),
+			)
+		}
+
+		return
+	}
+
+	r.markFirstByte()
+
+	if code >= 100 && code < 200 && code != http.StatusSwitchingProtocols {
+		r.ResponseWriter.WriteHeader(code)
+		return
+	}
+
+	r.wroteHeader = true
+	r.statusCode = code
+	r.ResponseWriter.WriteHeader(code)
+}
+
+// Write will write the data to the response writer, keep track of the number of
+// bytes written and call any registered OnWrite callbacks.
+func (r *ResponseWriterWithInfo) Write(b []byte) (int, error) {
+	r.markFirstByte()
+
+	// Writing without calling WriteHeader will implicitly write a 200.
+	r.wroteHeader = true
+
+	n, err := r.ResponseWriter.Write(b)
+	r.bytesWritten += int64(n)
+
+	for _, fn := range r.onWrite {
+		fn(n, r.bytesWritten)
+	}
+
+	return n, err
+}
+
+// Written returns true if the header has been written, either explicitly or
+// implicitly by writing to the body.
+func (r *ResponseWriterWithInfo) Written() bool {
+	return r.wroteHeader
+}
+
+// Status returns the status written, or 200 if nothing has been written yet.
+func (r *ResponseWriterWithInfo) Status() int {
+	return r.statusCode
+}
+
+// OnWrite registers a callback that will be called after each write with the
+// number of bytes written in that write and the total number of bytes written
+// so far. This can be used to track progress for long responses.
+func (r *ResponseWriterWithInfo) OnWrite(fn func(n int, total int64)) {
+	r.onWrite = append(r.onWrite, fn)
+}
+
+// BytesWritten returns the number of bytes written to the response body.
+func (r *ResponseWriterWithInfo) BytesWritten() int64 {
+	return r.bytesWritten
+}
+
+// TimeToFirstByte returns the time from when the response writer was created
+// until the header or the first byte of the body was written. If nothing has
+// been written yet, zero is returned.
+func (r *ResponseWriterWithInfo) TimeToFirstByte() time.Duration {
+	if r.firstByteAt.IsZero() {
+		return 0
+	}
+
+	return r.firstByteAt.Sub(r.createdAt)
+}
+
+func (r *ResponseWriterWithInfo) markFirstByte() {
+	if r.firstByteAt.IsZero() {
+		r.firstByteAt = time.Now()
+	}
+}
+
+// WriteError will store the error on the response writer.
+func (r *ResponseWriterWithInfo) WriteError(err error) {
+	r.responseError = err
+}
+
+// Err returns the error stored with WriteError, if any.
+func (r *ResponseWriterWithInfo) Err() error {
+	return r.responseError
+}
+
+// SetVariant stores the variant, e.g. "canary", serving the request on the
+// response writer so it's logged by the Logger middleware.
+func (r *ResponseWriterWithInfo) SetVariant(variant string) {
+	r.variant = variant
+}
+
+// Variant returns the variant serving the request, if set.
+func (r *ResponseWriterWithInfo) Variant() string {
+	return r.variant
+}
+
+// BytesSent returns the number of bytes sent by the handler, the bytes written
+// to the response body and, if the connection was hijacked, the bytes written
+// directly to the connection.
+func (r *ResponseWriterWithInfo) BytesSent() int64 {
+	if r.hijacked == nil {
+		return r.bytesWritten
+	}
+
+	return r.bytesWritten + r.hijacked.written.Load()
+}
+
+// Hijacked returns true if the connection was hijacked by the handler, e.g. to
+// upgrade it to a WebSocket. The response writer then only holds the status,
+// 101 Switching Protocols unless something else was written before, and the
+// middlewares measure the connection until it's closed instead of the request.
+func (r *ResponseWriterWithInfo) Hijacked() bool {
+	return r.hijacked != nil
+}
+
+// hijack marks the connection as hijacked and wraps it to track when it's
+// closed.
+func (r *ResponseWriterWithInfo) hijack(conn net.Conn) net.Conn {
+	r.hijacked = &hijackState{hijackedAt: time.Now()}
+
+	// The handler writes the upgrade response directly to the connection.
+	if !r.wroteHeader {
+		r.markFirstByte()
+		r.wroteHeader = true
+		r.statusCode = http.StatusSwitchingProtocols
+	}
+
+	return &hijackedConn{Conn: conn, state: r.hijacked}
+}
+
+// Unwrap returns the underlying response writer. This is used by
+// http.ResponseController.
+func (r *ResponseWriterWithInfo) Unwrap() http.ResponseWriter {
+	return r.ResponseWriter
+}
+
+// WithInterfaces returns a response writer backed by r that implements exactly
+// the optional interfaces (http.Flusher, http.Hijacker, http.Pusher and
+// io.ReaderFrom) that the underlying response writer implements. This should be
+// passed to the next handler so features such as streaming and sendfile aren't
+// disabled by the middleware. Calling NewResponseWriter with the returned
+// response writer returns r.
+func (r *ResponseWriterWithInfo) WithInterfaces() http.ResponseWriter {
+	if r.withInterfaces == nil {
+		r.withInterfaces = wrapWithInterfaces(r)
+	}
+
+	return r.withInterfaces
+}
+
+func (r *ResponseWriterWithInfo) withInfo() *ResponseWriterWithInfo {
+	return r
+}
+
+const (
+	supportsFlusher = 1 << iota
+	supportsHijacker
+	supportsPusher
+	supportsReaderFrom
+)
+
+// wrapWithInterfaces returns a type embedding the response writer with info
+// that also implements each optional interface the underlying response writer
+// implements. Since a type assertion only can see the methods of the type,
+// there's one type for each of the 16 combinations.
+func wrapWithInterfaces(r *ResponseWriterWithInfo) http.ResponseWriter {
+	var supports int
+
+	if _, ok := r.ResponseWriter.(http.Flusher); ok {
+		supports |= supportsFlusher
+	}
+
+	if _, ok := r.ResponseWriter.(http.Hijacker); ok {
+		supports |= supportsHijacker
+	}
+
+	if _, ok := r.ResponseWriter.(http.Pusher); ok {
+		supports |= supportsPusher
+	}
+
+	if _, ok := r.ResponseWriter.(io.ReaderFrom); ok {
+		supports |= supportsReaderFrom
+	}
+
+	switch supports {
+	case 0:
+		return struct{ *ResponseWriterWithInfo }{r}
+	case 1:
+		return struct {
+			*ResponseWriterWithInfo
+			http.Flusher
+		}{r, flusher{r}}
+	case 2:
+		return struct {
+			*ResponseWriterWithInfo
+			http.Hijacker
+		}{r, hijacker{r}}
+	case 3:
+		return struct {
+			*ResponseWriterWithInfo
+			http.Flusher
+			http.Hijacker
+		}{r, flusher{r}, hijacker{r}}
+	case 4:
+		return struct {
+			*ResponseWriterWithInfo
+			http.Pusher
+		}{r, pusher{r}}
+	case 5:
+		return struct {
+			*ResponseWriterWithInfo
+			http.Flusher
+			http.Pusher
+		}{r, flusher{r}, pusher{r}}
+	case 6:
+		return struct {
+			*ResponseWriterWithInfo
+			http.Hijacker
+			http.Pusher
+		}{r, hijacker{r}, pusher{r}}
+	case 7:
+		return struct {
+			*ResponseWriterWithInfo
+			http.Flusher
+			http.Hijacker
+			http.Pusher
+		}{r, flusher{r}, hijacker{r}, pusher{r}}
+	case 8:
+		return struct {
+			*ResponseWriterWithInfo
+			io.ReaderFrom
+		}{r, readerFrom{r}}
+	case 9:
+		return struct {
+			*ResponseWriterWithInfo
+			http.Flusher
+			io.ReaderFrom
+		}{r, flusher{r}, readerFrom{r}}
+	case 10:
+		return struct {
+			*ResponseWriterWithInfo
+			http.Hijacker
+			io.ReaderFrom
+		}{r, hijacker{r}, readerFrom{r}}
+	case 11:
+		return struct {
+			*ResponseWriterWithInfo
+			http.Flusher
+			http.Hijacker
+			io.ReaderFrom
+		}{r, flusher{r}, hijacker{r}, readerFrom{r}}
+	case 12:
+		return struct {
+			*ResponseWriterWithInfo
+			http.Pusher
+			io.ReaderFrom
+		}{r, pusher{r}, readerFrom{r}}
+	case 13:
+		return struct {
+			*ResponseWriterWithInfo
+			http.Flusher
+			http.Pusher
+			io.ReaderFrom
+		}{r, flusher{r}, pusher{r}, readerFrom{r}}
+	case 14:
+		return struct {
+			*ResponseWriterWithInfo
+			http.Hijacker
+			http.Pusher
+			io.ReaderFrom
+		}{r, hijacker{r}, pusher{r}, readerFrom{r}}
+	case 15:
+		return struct {
+			*ResponseWriterWithInfo
+			http.Flusher
+			http.Hijacker
+			http.Pusher
+			io.ReaderFrom
+		}{r, flusher{r}, hijacker{r}, pusher{r}, readerFrom{r}}
+	}
+
+	return r
+}
+
+type flusher struct {
+	rw *ResponseWriterWithInfo
+}
+
+func (f flusher) Flush() {
+	// Flushing will write the header if not already written.
+	f.rw.markFirstByte()
+	f.rw.wroteHeader = true
+
+	f.rw.ResponseWriter.(http.Flusher).Flush()
+}
+
+type hijacker struct {
+	rw *ResponseWriterWithInfo
+}
+
+func (h hijacker) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	conn, brw, err := h.rw.ResponseWriter.(http.Hijacker).Hijack()
+	if err != nil {
+		return conn, brw, err
+	}
+
+	conn = h.rw.hijack(conn)
+
+	// Count the bytes written through the buffered writer too. It's created
+	// for the hijacked connection so there's nothing buffered to discard.
+	if brw != nil && brw.Writer.Buffered() == 0 {
+		brw.Writer.Reset(conn)
+	}
+
+	return conn, brw, nil
+}
+
+// hijackState tracks a hijacked connection so middlewares can act when it's
+// closed, which may be after the handler returns.
+type hijackState struct {
+	mu         sync.Mutex
+	hijackedAt time.Time
+	written    atomic.Int64
+	closed     bool
+	onClose    []func()
+}
+
+// afterClose calls fn when the connection is closed, or directly if it's
+// already closed.
+func (s *hijackState) afterClose(fn func()) {
+	s.mu.Lock()
+
+	if !s.closed {
+		s.onClose = append(s.onClose, fn)
+		s.mu.Unlock()
+
+		return
+	}
+
+	s.mu.Unlock()
+
+	fn()
+}
+
+func (s *hijackState) close() {
+	s.mu.Lock()
+	s.closed = true
+	onClose := s.onClose
+	s.onClose = nil
+	s.mu.Unlock()
+
+	for _, fn := range onClose {
+		fn()
+	}
+}
+
+// hijackedConn is a hijacked connection counting the bytes written and
+// reporting when it's closed.
+type hijackedConn struct {
+	net.Conn
+	state *hijackState
+	once  sync.Once
+}
+
+func (c *hijackedConn) Write(b []byte) (int, error) {
+	n, err := c.Conn.Write(b)
+	c.state.written.Add(int64(n))
+
+	return n, err
+}
+
+func (c *hijackedConn) Close() error {
+	err := c.Conn.Close()
+	c.once.Do(c.state.close)
+
+	return err
+}
+
+type pusher struct {
+	rw *ResponseWriterWithInfo
+}
+
+func (p pusher) Push(target string, opts *http.PushOptions) error {
+	return p.rw.ResponseWriter.(http.Pusher).Push(target, opts)
+}
+
+// readFromChunkSize is how much is passed to the underlying ReadFrom at a time
+// when OnWrite callbacks are registered, so e.g. WriteStallTimeout sees the
+// progress of a long sendfile.
+const readFromChunkSize = 256 << 10
+
+type readerFrom struct {
+	rw *ResponseWriterWithInfo
+}
+
+func (rf readerFrom) ReadFrom(src io.Reader) (int64, error) {
+	rf.rw.markFirstByte()
+	rf.rw.wroteHeader = true
+
+	dst := rf.rw.ResponseWriter.(io.ReaderFrom)
+
+	if len(rf.rw.onWrite) == 0 {
+		n, err := dst.ReadFrom(src)
+		rf.rw.bytesWritten += n
+
+		return n, err
+	}
+
+	var total int64
+
+	// A limited *os.File is still sent with sendfile.
+	for {
+		n, err := dst.ReadFrom(&io.LimitedReader{R: src, N: readFromChunkSize})
+		total += n
+		rf.rw.bytesWritten += n
+
+		if n > 0 {
+			for _, fn := range rf.rw.onWrite {
+				fn(int(n), rf.rw.bytesWritten)
+			}
+		}
+
+		if err != nil || n < readFromChunkSize {
+			return total, err
+		}
+	}
+}
+
+// isUpgrade returns true for requests asking to upgrade the connection to
+// another protocol, e.g. WebSocket.
+func isUpgrade(r *http.Request) bool {
+	if r.Header.Get("Upgrade") == "" {
+		return false
+	}
+
+	for _, value := range r.Header.Values("Connection") {
+		for _, token := range strings.Split(value, ",") {
+			if strings.EqualFold(strings.TrimSpace(token), "upgrade") {
+				return true
+			}
+		}
+	}
+
+	return false
+}
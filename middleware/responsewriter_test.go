@@ -0,0 +1,98 @@
+package middleware
+
+import (
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/bombsimon/http-helpers/render"
+)
+
+func Test_WithInterfaces(t *testing.T) {
+	rw := NewResponseWriter(httptest.NewRecorder())
+	wrapped := rw.WithInterfaces()
+
+	if _, ok := wrapped.(http.Flusher); !ok {
+		t.Fatal("expected wrapped response writer to implement http.Flusher")
+	}
+
+	if _, ok := wrapped.(http.Hijacker); ok {
+		t.Fatal("expected wrapped response writer to not implement http.Hijacker")
+	}
+
+	if _, ok := wrapped.(io.ReaderFrom); ok {
+		t.Fatal("expected wrapped response writer to not implement io.ReaderFrom")
+	}
+
+	if NewResponseWriter(wrapped) != rw {
+		t.Fatal("expected the same response writer with info to be returned")
+	}
+
+	wrapped.(http.Flusher).Flush()
+
+	if !rw.Written() {
+		t.Fatal("expected flush to write header")
+	}
+}
+
+func Test_WithInterfacesServer(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+
+	var isHijacker, isReaderFrom, isFlusher bool
+
+	handlerWithMiddleware := AddMiddlewares(
+		http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			_, isHijacker = w.(http.Hijacker)
+			_, isReaderFrom = w.(io.ReaderFrom)
+			_, isFlusher = w.(http.Flusher)
+		}),
+		Logger(logger),
+		DevWarnings(WithLogger(logger)),
+	)
+
+	ts := httptest.NewServer(handlerWithMiddleware)
+	defer ts.Close()
+
+	response, err := http.Get(ts.URL)
+	if err != nil {
+		t.Fatal("could not send http request")
+	}
+
+	_ = response.Body.Close()
+
+	if !isHijacker || !isReaderFrom || !isFlusher {
+		t.Fatal("optional interfaces not preserved through middlewares")
+	}
+}
+
+func Test_StreamedResponse(t *testing.T) {
+	var rw *ResponseWriterWithInfo
+
+	items := make(chan int, 3)
+	for i := range 3 {
+		items <- i
+	}
+
+	close(items)
+
+	handler := AddMiddlewares(
+		http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			_ = render.NDJSON(w, r, items)
+		}),
+		func(h http.Handler) http.Handler {
+			return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				rw = NewResponseWriter(w)
+				h.ServeHTTP(rw.WithInterfaces(), r)
+			})
+		},
+	)
+
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+
+	if !rec.Flushed || rw.BytesWritten() != int64(rec.Body.Len()) || rec.Body.String() != "0\n1\n2\n" {
+		t.Fatalf("unexpected streamed response, flushed: %t, counted: %d, body: %q", rec.Flushed, rw.BytesWritten(), rec.Body.String())
+	}
+}
@@ -0,0 +1,117 @@
+package middleware
+
+import (
+	"net"
+	"net/http"
+	"net/netip"
+	"strings"
+)
+
+// NoIndex is the X-Robots-Tag set for hosts that shouldn't be indexed.
+const NoIndex = "noindex, nofollow"
+
+// nonProductionLabels are host labels marking a host as non-production, e.g.
+// "api.staging.example.com".
+var nonProductionLabels = map[string]bool{
+	"dev":         true,
+	"development": true,
+	"internal":    true,
+	"local":       true,
+	"localhost":   true,
+	"preview":     true,
+	"qa":          true,
+	"stage":       true,
+	"staging":     true,
+	"test":        true,
+}
+
+// RobotsPolicy configures the Robots middleware.
+type RobotsPolicy struct {
+	// Tag is the X-Robots-Tag set on responses from production hosts, e.g.
+	// "noindex" for an API or "noarchive". Nothing is set if empty.
+	Tag string
+
+	// ProductionHosts are the hosts allowed to be indexed. A host may start
+	// with "*." to match all subdomains. If set, all other hosts get NoIndex.
+	ProductionHosts []string
+}
+
+// Robots sets the X-Robots-Tag header from the policy. Requests to hosts that
+// aren't production hosts get NoIndex to prevent staging and internal
+// environments from being indexed by accident. Hosts are considered
+// non-production if they're IP addresses or not in
+// RobotsPolicy.ProductionHosts. If no production hosts are set, hosts with a
+// label other than the top-level domain such as "staging", "dev" or
+// "internal" are considered non-production. Use
+// chain.Chain.Group to set different policies for different routes. Handlers
+// may still replace the header.
+func Robots(policy RobotsPolicy, opts ...Option) Middleware {
+	options := newOptions(opts...)
+
+	return options.skippable(func(h http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			tag := policy.Tag
+			if !policy.isProduction(r.Host) {
+				tag = NoIndex
+			}
+
+			if tag != "" {
+				w.Header().Set("X-Robots-Tag", tag)
+			}
+
+			h.ServeHTTP(w, r)
+		})
+	})
+}
+
+func (p RobotsPolicy) isProduction(host string) bool {
+	if h, _, err := net.SplitHostPort(host); err == nil {
+		host = h
+	}
+
+	host = strings.ToLower(strings.TrimSuffix(host, "."))
+
+	if host == "" {
+		return false
+	}
+
+	if _, err := netip.ParseAddr(strings.Trim(host, "[]")); err == nil {
+		return false
+	}
+
+	if len(p.ProductionHosts) == 0 {
+		labels := strings.Split(host, ".")
+
+		// Skip the top-level domain, e.g. "go.dev", but not a single label
+		// such as "localhost".
+		if len(labels) > 1 {
+			labels = labels[:len(labels)-1]
+		}
+
+		for _, label := range labels {
+			if nonProductionLabels[label] {
+				return false
+			}
+		}
+
+		return true
+	}
+
+	for _, production := range p.ProductionHosts {
+		production = strings.ToLower(production)
+
+		if suffix, ok := strings.CutPrefix(production, "*."); ok {
+			if strings.HasSuffix(host, "."+suffix) {
+				return true
+			}
+
+			continue
+		}
+
+		if host == production {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,86 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func Test_Robots(t *testing.T) {
+	tests := []struct {
+		name     string
+		policy   RobotsPolicy
+		host     string
+		expected string
+	}{
+		{
+			name:     "production without tag",
+			host:     "example.com",
+			expected: "",
+		},
+		{
+			name:     "production with tag",
+			policy:   RobotsPolicy{Tag: "noarchive"},
+			host:     "www.example.com",
+			expected: "noarchive",
+		},
+		{
+			name:     "staging label",
+			policy:   RobotsPolicy{Tag: "noarchive"},
+			host:     "api.staging.example.com",
+			expected: NoIndex,
+		},
+		{
+			name:     "localhost with port",
+			host:     "localhost:8080",
+			expected: NoIndex,
+		},
+		{
+			name:     "ip address",
+			host:     "[::1]:8080",
+			expected: NoIndex,
+		},
+		{
+			name:     "production host",
+			policy:   RobotsPolicy{ProductionHosts: []string{"example.com", "*.example.com"}},
+			host:     "shop.example.com",
+			expected: "",
+		},
+		{
+			name:     "top-level domain isn't a label",
+			host:     "go.dev",
+			expected: "",
+		},
+		{
+			name:     "production host with a non-production label",
+			policy:   RobotsPolicy{ProductionHosts: []string{"docs.internal.example.com"}},
+			host:     "docs.internal.example.com",
+			expected: "",
+		},
+		{
+			name:     "not a production host",
+			policy:   RobotsPolicy{ProductionHosts: []string{"example.com"}},
+			host:     "example-preview.herokuapp.com",
+			expected: NoIndex,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			handler := AddMiddlewares(
+				http.HandlerFunc(func(http.ResponseWriter, *http.Request) {}),
+				Robots(tc.policy),
+			)
+
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			req.Host = tc.host
+
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, req)
+
+			if got := rec.Header().Get("X-Robots-Tag"); got != tc.expected {
+				t.Fatalf("unexpected X-Robots-Tag, got: %q, expected: %q", got, tc.expected)
+			}
+		})
+	}
+}
@@ -0,0 +1,135 @@
+package middleware
+
+import (
+	"log/slog"
+	"math"
+	"runtime/metrics"
+	"time"
+)
+
+// Runtime metrics read when a request is slow.
+const (
+	metricGCCycles       = "/gc/cycles/total:gc-cycles"
+	metricGCPauses       = "/sched/pauses/total/gc:seconds"
+	metricSchedLatencies = "/sched/latencies:seconds"
+	metricGoroutines     = "/sched/goroutines:goroutines"
+)
+
+// runtimeSample is a snapshot of the runtime metrics used to tell if a slow
+// request was caused by the handler or by the runtime being stalled by garbage
+// collection or an overloaded scheduler.
+type runtimeSample struct {
+	samples []metrics.Sample
+}
+
+func readRuntimeSample() runtimeSample {
+	s := runtimeSample{
+		samples: []metrics.Sample{
+			{Name: metricGCCycles},
+			{Name: metricGCPauses},
+			{Name: metricSchedLatencies},
+			{Name: metricGoroutines},
+		},
+	}
+
+	metrics.Read(s.samples)
+
+	return s
+}
+
+// attrs returns the runtime activity since the start sample: the number of GC
+// cycles, the number of GC pauses and the longest pause, the 99th percentile
+// and longest scheduling latency, i.e. the time goroutines waited to run, and
+// the current number of goroutines. Durations are approximated by the bucket
+// boundaries of the runtime histograms.
+func (s runtimeSample) attrs(start runtimeSample) slog.Attr {
+	var attrs []any
+
+	for i, sample := range s.samples {
+		value, before := sample.Value, start.samples[i].Value
+
+		switch sample.Name {
+		case metricGCCycles:
+			if value.Kind() == metrics.KindUint64 {
+				attrs = append(attrs, slog.Uint64("gc_cycles", value.Uint64()-before.Uint64()))
+			}
+		case metricGCPauses:
+			if value.Kind() == metrics.KindFloat64Histogram {
+				counts, buckets := histogramDiff(before.Float64Histogram(), value.Float64Histogram())
+				attrs = append(attrs,
+					slog.Uint64("gc_pauses", sum(counts)),
+					slog.Duration("gc_pause_max", quantile(counts, buckets, 1)),
+				)
+			}
+		case metricSchedLatencies:
+			if value.Kind() == metrics.KindFloat64Histogram {
+				counts, buckets := histogramDiff(before.Float64Histogram(), value.Float64Histogram())
+				attrs = append(attrs,
+					slog.Duration("sched_latency_p99", quantile(counts, buckets, 0.99)),
+					slog.Duration("sched_latency_max", quantile(counts, buckets, 1)),
+				)
+			}
+		case metricGoroutines:
+			if value.Kind() == metrics.KindUint64 {
+				attrs = append(attrs, slog.Uint64("goroutines", value.Uint64()))
+			}
+		}
+	}
+
+	return slog.Group("runtime", attrs...)
+}
+
+// histogramDiff returns the counts added to the histogram since before. The
+// bucket boundaries of a runtime metric never change.
+func histogramDiff(before, after *metrics.Float64Histogram) ([]uint64, []float64) {
+	counts := make([]uint64, len(after.Counts))
+
+	for i := range after.Counts {
+		counts[i] = after.Counts[i]
+		if i < len(before.Counts) {
+			counts[i] -= before.Counts[i]
+		}
+	}
+
+	return counts, after.Buckets
+}
+
+// quantile returns the upper boundary of the bucket with the quantile q, or the
+// lower boundary if the bucket is unbounded. Zero is returned for an empty
+// histogram.
+func quantile(counts []uint64, buckets []float64, q float64) time.Duration {
+	total := sum(counts)
+	if total == 0 {
+		return 0
+	}
+
+	rank := uint64(math.Ceil(q * float64(total)))
+
+	var seen uint64
+
+	for i, count := range counts {
+		seen += count
+		if seen < rank {
+			continue
+		}
+
+		boundary := buckets[i+1]
+		if math.IsInf(boundary, 1) {
+			boundary = buckets[i]
+		}
+
+		return time.Duration(boundary * float64(time.Second))
+	}
+
+	return 0
+}
+
+func sum(counts []uint64) uint64 {
+	var total uint64
+
+	for _, count := range counts {
+		total += count
+	}
+
+	return total
+}
@@ -0,0 +1,176 @@
+package middleware
+
+import (
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"net/http/httputil"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+const staticFileSize = 1 << 20
+
+func Test_ReadFromPassthrough(t *testing.T) {
+	path := writeStaticFile(t)
+
+	var writes []int
+
+	handler := fullChain(
+		http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			f, err := os.Open(path)
+			if err != nil {
+				t.Error(err)
+				return
+			}
+
+			defer f.Close()
+
+			// With io.ReaderFrom preserved this will use sendfile.
+			_, _ = io.Copy(w, f)
+		}),
+		func(h http.Handler) http.Handler {
+			return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				rw := NewResponseWriter(w)
+				rw.OnWrite(func(n int, _ int64) {
+					writes = append(writes, n)
+				})
+
+				h.ServeHTTP(rw.WithInterfaces(), r)
+			})
+		},
+	)
+
+	ts := httptest.NewServer(handler)
+	defer ts.Close()
+
+	response, err := http.Get(ts.URL)
+	if err != nil {
+		t.Fatal("could not send http request")
+	}
+
+	_, _ = io.Copy(io.Discard, response.Body)
+	_ = response.Body.Close()
+
+	// ReadFrom calls for 256 KiB chunks means the body never went through
+	// Write which would be called once per 32 KiB chunk.
+	if len(writes) != staticFileSize/readFromChunkSize {
+		t.Fatalf("expected a ReadFrom call per chunk, got writes: %v", writes)
+	}
+
+	for _, n := range writes {
+		if n != readFromChunkSize {
+			t.Fatalf("expected a ReadFrom call per chunk, got writes: %v", writes)
+		}
+	}
+}
+
+func Benchmark_StaticFile(b *testing.B) {
+	path := writeStaticFile(b)
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.ServeFile(w, r, path)
+	})
+
+	b.Run("bare", func(b *testing.B) { benchmarkHandler(b, handler) })
+	b.Run("chain", func(b *testing.B) { benchmarkHandler(b, fullChain(handler)) })
+}
+
+func Benchmark_Proxy(b *testing.B) {
+	path := writeStaticFile(b)
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.ServeFile(w, r, path)
+	}))
+
+	defer upstream.Close()
+
+	upstreamURL, _ := url.Parse(upstream.URL)
+	handler := httputil.NewSingleHostReverseProxy(upstreamURL)
+
+	b.Run("bare", func(b *testing.B) { benchmarkHandler(b, handler) })
+	b.Run("chain", func(b *testing.B) { benchmarkHandler(b, fullChain(handler)) })
+}
+
+// Test_BenchmarkComparison compares the benchmarks with and without the
+// middleware chain and fails if the chain is more than
+// BENCH_COMPARE_MAX_RATIO (default 2) times slower. This is meant to run in CI
+// and only runs if BENCH_COMPARE is set.
+func Test_BenchmarkComparison(t *testing.T) {
+	if os.Getenv("BENCH_COMPARE") == "" {
+		t.Skip("set BENCH_COMPARE to compare benchmarks")
+	}
+
+	maxRatio := 2.0
+	if v, err := strconv.ParseFloat(os.Getenv("BENCH_COMPARE_MAX_RATIO"), 64); err == nil {
+		maxRatio = v
+	}
+
+	path := writeStaticFile(t)
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.ServeFile(w, r, path)
+	})
+
+	bare := testing.Benchmark(func(b *testing.B) { benchmarkHandler(b, handler) })
+	chain := testing.Benchmark(func(b *testing.B) { benchmarkHandler(b, fullChain(handler)) })
+
+	ratio := float64(chain.NsPerOp()) / float64(bare.NsPerOp())
+	t.Logf("bare: %s, chain: %s, ratio: %.2f", bare, chain, ratio)
+
+	if ratio > maxRatio {
+		t.Fatalf("middleware chain is %.2f times slower, max allowed is %.2f", ratio, maxRatio)
+	}
+}
+
+func benchmarkHandler(b *testing.B, handler http.Handler) {
+	b.Helper()
+
+	ts := httptest.NewServer(handler)
+	defer ts.Close()
+
+	b.SetBytes(staticFileSize)
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		response, err := http.Get(ts.URL)
+		if err != nil {
+			b.Fatal(err)
+		}
+
+		_, _ = io.Copy(io.Discard, response.Body)
+		_ = response.Body.Close()
+	}
+}
+
+// fullChain wraps the handler with all the middlewares that wrap the response
+// writer, as well as any extra middlewares passed.
+func fullChain(h http.Handler, extra ...Middleware) http.Handler {
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+
+	return AddMiddlewares(
+		h,
+		append([]Middleware{
+			WriteStallTimeout(time.Minute),
+			DevWarnings(WithLogger(logger)),
+			Prometheus(WithRegisterer(prometheus.NewRegistry())),
+			Logger(logger),
+			PanicRecovery(logger),
+		}, extra...)...,
+	)
+}
+
+func writeStaticFile(tb testing.TB) string {
+	tb.Helper()
+
+	path := filepath.Join(tb.TempDir(), "static.bin")
+	if err := os.WriteFile(path, make([]byte, staticFileSize), 0o600); err != nil {
+		tb.Fatal(err)
+	}
+
+	return path
+}
@@ -0,0 +1,42 @@
+package middleware
+
+import (
+	"github.com/bombsimon/http-helpers/server"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// ShutdownMetrics returns a callback for server.WithShutdownProgress exporting
+// the progress of the graceful shutdown, registered with the registerer set
+// with WithRegisterer. The in-flight requests are exported as
+// shutdown_in_flight_requests, the time spent draining as
+// shutdown_duration_seconds and shutdown_timed_out is set to 1 if the wait
+// time was reached before all connections were drained.
+//
+//	err := server.Run(ctx, srv, server.WithShutdownProgress(time.Second, middleware.ShutdownMetrics()))
+func ShutdownMetrics(opts ...Option) func(server.ShutdownProgress) {
+	options := newOptions(opts...)
+
+	inFlight := registerOrExisting(options.registerer, prometheus.NewGauge(prometheus.GaugeOpts{
+		Name: "shutdown_in_flight_requests",
+		Help: "A gauge of requests still being processed while shutting down.",
+	}))
+
+	duration := registerOrExisting(options.registerer, prometheus.NewGauge(prometheus.GaugeOpts{
+		Name: "shutdown_duration_seconds",
+		Help: "A gauge of the time spent draining connections while shutting down.",
+	}))
+
+	timedOut := registerOrExisting(options.registerer, prometheus.NewGauge(prometheus.GaugeOpts{
+		Name: "shutdown_timed_out",
+		Help: "Set to 1 if the wait time was reached before all connections were drained.",
+	}))
+
+	return func(progress server.ShutdownProgress) {
+		inFlight.Set(float64(progress.InFlight))
+		duration.Set(progress.Elapsed.Seconds())
+
+		if progress.TimedOut {
+			timedOut.Set(1)
+		}
+	}
+}
@@ -0,0 +1,29 @@
+package middleware
+
+import (
+	"testing"
+	"time"
+
+	"github.com/bombsimon/http-helpers/server"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+func Test_ShutdownMetrics(t *testing.T) {
+	registry := prometheus.NewRegistry()
+	report := ShutdownMetrics(WithRegisterer(registry))
+
+	report(server.ShutdownProgress{InFlight: 3, Elapsed: time.Second})
+	report(server.ShutdownProgress{InFlight: 1, Elapsed: 2500 * time.Millisecond, Done: true, TimedOut: true})
+
+	values := gatheredValues(t, registry)
+
+	for key, expected := range map[string]float64{
+		"shutdown_in_flight_requests": 1,
+		"shutdown_duration_seconds":   2.5,
+		"shutdown_timed_out":          1,
+	} {
+		if values[key] != expected {
+			t.Fatalf("unexpected value for %s, got: %v, expected: %v", key, values[key], expected)
+		}
+	}
+}
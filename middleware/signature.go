@@ -0,0 +1,247 @@
+package middleware
+
+import (
+	"container/heap"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/bombsimon/http-helpers/client"
+	"github.com/bombsimon/http-helpers/clock"
+	"github.com/bombsimon/http-helpers/httpctx"
+)
+
+// SecretFunc returns the secret for the key ID of a signed request.
+type SecretFunc func(ctx context.Context, keyID string) (string, error)
+
+// NonceStore remembers the nonces of verified requests to reject replays.
+// Implement it with a shared backend, e.g. Redis, to reject replays across
+// instances.
+type NonceStore interface {
+	// Use marks the nonce as used until it expires. It returns false if the
+	// nonce has already been used.
+	Use(ctx context.Context, nonce string, expires time.Time) (bool, error)
+}
+
+// NewNonceStore returns an in-process NonceStore. Expired nonces are removed
+// when new nonces are added, in order of expiry. Use WithClock to set the
+// clock.
+func NewNonceStore(opts ...Option) NonceStore {
+	return &nonceStore{
+		clock:  newOptions(opts...).clock,
+		nonces: make(map[string]time.Time),
+	}
+}
+
+type nonceStore struct {
+	mu     sync.Mutex
+	clock  clock.Clock
+	nonces map[string]time.Time
+	expiry nonceExpiry
+}
+
+func (s *nonceStore) Use(_ context.Context, nonce string, expires time.Time) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := s.clock.Now()
+
+	if expiresAt, ok := s.nonces[nonce]; ok && now.Before(expiresAt) {
+		return false, nil
+	}
+
+	for len(s.expiry) > 0 && !now.Before(s.expiry[0].expires) {
+		next := heap.Pop(&s.expiry).(nonceEntry)
+
+		// The nonce may have been used again after it expired.
+		if s.nonces[next.nonce].Equal(next.expires) {
+			delete(s.nonces, next.nonce)
+		}
+	}
+
+	s.nonces[nonce] = expires
+	heap.Push(&s.expiry, nonceEntry{nonce: nonce, expires: expires})
+
+	return true, nil
+}
+
+type nonceEntry struct {
+	nonce   string
+	expires time.Time
+}
+
+// nonceExpiry is a heap of nonces ordered by expiry.
+type nonceExpiry []nonceEntry
+
+func (h nonceExpiry) Len() int           { return len(h) }
+func (h nonceExpiry) Less(i, j int) bool { return h[i].expires.Before(h[j].expires) }
+func (h nonceExpiry) Swap(i, j int)      { h[i], h[j] = h[j], h[i] }
+
+func (h *nonceExpiry) Push(x any) {
+	*h = append(*h, x.(nonceEntry))
+}
+
+func (h *nonceExpiry) Pop() any {
+	old := *h
+	entry := old[len(old)-1]
+	*h = old[:len(old)-1]
+
+	return entry
+}
+
+// WithNonceStore sets the store used by VerifySignature to reject replayed
+// requests. Defaults to NewNonceStore.
+func WithNonceStore(store NonceStore) Option {
+	return func(o *options) {
+		o.nonceStore = store
+	}
+}
+
+// WithSignatureMaxSkew sets how far the signature timestamp may be from the
+// current time. Defaults to 5 minutes.
+func WithSignatureMaxSkew(d time.Duration) Option {
+	return func(o *options) {
+		o.signatureMaxSkew = d
+	}
+}
+
+// WithRequiredSignedHeaders sets headers that must be signed, in addition to
+// the method, request URI, host, timestamp, nonce and payload hash, for
+// VerifySignature to accept a request.
+func WithRequiredSignedHeaders(names ...string) Option {
+	return func(o *options) {
+		o.requiredSignedHeaders = append(o.requiredSignedHeaders, names...)
+	}
+}
+
+// VerifySignature verifies requests signed with client.NewHMACSigner. The
+// signature must cover the method, request URI, host, body and the headers set
+// with WithRequiredSignedHeaders, the timestamp must be within the skew set
+// with WithSignatureMaxSkew and the nonce must not have been used before,
+// tracked in the store set with WithNonceStore. Requests failing verification
+// are rejected with 401 Unauthorized. The body is read into memory to hash it
+// so limit the body size before this middleware, e.g. with BufferBody which
+// lets other middlewares use the same buffer. The key ID of verified
+// requests is stored as the principal, available with
+// httpctx.Principal[string].
+func VerifySignature(secrets SecretFunc, opts ...Option) Middleware {
+	options := newOptions(opts...)
+
+	nonces := options.nonceStore
+	if nonces == nil {
+		nonces = NewNonceStore(WithClock(options.clock))
+	}
+
+	return options.skippable(func(h http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			keyID, ok := options.verifySignature(r, secrets, nonces)
+			if !ok {
+				w.Header().Set("WWW-Authenticate", "HMAC-SHA256")
+				http.Error(w, http.StatusText(http.StatusUnauthorized), http.StatusUnauthorized)
+
+				return
+			}
+
+			h.ServeHTTP(w, r.WithContext(httpctx.WithPrincipal(r.Context(), keyID)))
+		})
+	})
+}
+
+// verifySignature returns the key ID if the request has a valid signature. The
+// body of the request is replaced with a buffered copy unless it's already
+// buffered by BufferBody.
+func (o *options) verifySignature(r *http.Request, secrets SecretFunc, nonces NonceStore) (string, bool) {
+	params, ok := parseHMACAuthorization(r.Header.Get("Authorization"))
+	if !ok {
+		return "", false
+	}
+
+	var signedHeaders []string
+	if params["SignedHeaders"] != "" {
+		signedHeaders = strings.Split(params["SignedHeaders"], ";")
+	}
+
+	for _, name := range o.requiredSignedHeaders {
+		if !slices.Contains(signedHeaders, strings.ToLower(name)) {
+			return "", false
+		}
+	}
+
+	unix, err := strconv.ParseInt(r.Header.Get("X-Signature-Timestamp"), 10, 64)
+	if err != nil {
+		return "", false
+	}
+
+	timestamp := time.Unix(unix, 0)
+	if skew := o.clock.Since(timestamp); skew > o.signatureMaxSkew || skew < -o.signatureMaxSkew {
+		return "", false
+	}
+
+	nonce := r.Header.Get("X-Signature-Nonce")
+	if nonce == "" {
+		return "", false
+	}
+
+	body, err := readBody(nil, r, 0)
+	if err != nil {
+		return "", false
+	}
+
+	payloadHash := sha256.Sum256(body)
+	if r.Header.Get("X-Content-Sha256") != hex.EncodeToString(payloadHash[:]) {
+		return "", false
+	}
+
+	secret, err := secrets(r.Context(), params["KeyId"])
+	if err != nil || secret == "" {
+		return "", false
+	}
+
+	signature := client.HMACSignature(r, secret, signedHeaders)
+	if !hmac.Equal([]byte(signature), []byte(params["Signature"])) {
+		return "", false
+	}
+
+	// The nonce is only used once the signature is verified so it can't be
+	// used up by others. It must be remembered until the timestamp is outside
+	// of the allowed skew.
+	unused, err := nonces.Use(r.Context(), params["KeyId"]+":"+nonce, timestamp.Add(o.signatureMaxSkew))
+	if err != nil || !unused {
+		return "", false
+	}
+
+	return params["KeyId"], true
+}
+
+// parseHMACAuthorization parses the parameters of an HMAC-SHA256 Authorization
+// header. The KeyId and Signature parameters are required.
+func parseHMACAuthorization(authorization string) (map[string]string, bool) {
+	credentials, ok := strings.CutPrefix(authorization, "HMAC-SHA256 ")
+	if !ok {
+		return nil, false
+	}
+
+	params := make(map[string]string)
+
+	for _, param := range strings.Split(credentials, ",") {
+		name, value, ok := strings.Cut(strings.TrimSpace(param), "=")
+		if !ok {
+			return nil, false
+		}
+
+		params[name] = value
+	}
+
+	if params["KeyId"] == "" || params["Signature"] == "" {
+		return nil, false
+	}
+
+	return params, true
+}
@@ -0,0 +1,169 @@
+package middleware
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/bombsimon/http-helpers/client"
+	"github.com/bombsimon/http-helpers/clock"
+	"github.com/bombsimon/http-helpers/httpctx"
+)
+
+func Test_VerifySignature(t *testing.T) {
+	clk := clock.NewFake(time.Now())
+
+	secrets := func(_ context.Context, keyID string) (string, error) {
+		if keyID != "key-1" {
+			return "", errors.New("unknown key")
+		}
+
+		return "secret", nil
+	}
+
+	server := httptest.NewServer(AddMiddlewares(
+		http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			keyID, _ := httpctx.Principal[string](r.Context())
+			body, _ := io.ReadAll(r.Body)
+
+			_, _ = io.WriteString(w, keyID+":"+string(body))
+		}),
+		VerifySignature(secrets, WithClock(clk), WithRequiredSignedHeaders("Content-Type")),
+	))
+	defer server.Close()
+
+	// Capture the signed requests to be able to replay and modify them.
+	var signed *http.Request
+
+	send := func(r *http.Request) (int, string) {
+		t.Helper()
+
+		resp, err := http.DefaultTransport.RoundTrip(r)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		defer resp.Body.Close()
+
+		body, _ := io.ReadAll(resp.Body)
+
+		return resp.StatusCode, string(body)
+	}
+
+	sign := func(credentials client.CredentialsProvider, signedHeaders ...string) *http.Request {
+		t.Helper()
+
+		rt := client.WrapTransport(
+			client.RoundTripperFunc(func(r *http.Request) (*http.Response, error) {
+				signed = r
+				return &http.Response{StatusCode: http.StatusOK, Body: http.NoBody, Request: r}, nil
+			}),
+			client.Sign(client.NewHMACSigner(credentials, client.WithClock(clk), client.WithSignedHeaders(signedHeaders...))),
+		)
+
+		req, _ := http.NewRequest(http.MethodPost, server.URL+"/orders?dry_run=true", strings.NewReader(`{"id":1}`))
+		req.Header.Set("Content-Type", "application/json")
+
+		if _, err := rt.RoundTrip(req); err != nil {
+			t.Fatal(err)
+		}
+
+		return signed
+	}
+
+	withBody := func(r *http.Request) *http.Request {
+		r = r.Clone(r.Context())
+		r.Body, _ = r.GetBody()
+
+		return r
+	}
+
+	valid := client.StaticCredentials("key-1", "secret", "")
+
+	req := sign(valid, "Content-Type")
+
+	if status, body := send(withBody(req)); status != http.StatusOK || body != `key-1:{"id":1}` {
+		t.Fatalf("expected signed request to be accepted, got: %d, %s", status, body)
+	}
+
+	if status, _ := send(withBody(req)); status != http.StatusUnauthorized {
+		t.Fatalf("expected replayed request to be rejected, got: %d", status)
+	}
+
+	for name, modify := range map[string]func(r *http.Request){
+		"body": func(r *http.Request) {
+			r.Body = io.NopCloser(strings.NewReader(`{"id":2}`))
+			r.ContentLength = 8
+		},
+		"signed header": func(r *http.Request) { r.Header.Set("Content-Type", "text/plain") },
+		"path":          func(r *http.Request) { r.URL.Path = "/refunds" },
+		"query":         func(r *http.Request) { r.URL.RawQuery = "dry_run=false" },
+		"method":        func(r *http.Request) { r.Method = http.MethodPut },
+		"no signature":  func(r *http.Request) { r.Header.Del("Authorization") },
+	} {
+		req := withBody(sign(valid, "Content-Type"))
+		modify(req)
+
+		if status, _ := send(req); status != http.StatusUnauthorized {
+			t.Fatalf("expected request with modified %s to be rejected, got: %d", name, status)
+		}
+	}
+
+	if status, _ := send(withBody(sign(client.StaticCredentials("key-1", "wrong", ""), "Content-Type"))); status != http.StatusUnauthorized {
+		t.Fatalf("expected wrong secret to be rejected, got: %d", status)
+	}
+
+	if status, _ := send(withBody(sign(client.StaticCredentials("key-2", "secret", ""), "Content-Type"))); status != http.StatusUnauthorized {
+		t.Fatalf("expected unknown key to be rejected, got: %d", status)
+	}
+
+	if status, _ := send(withBody(sign(valid))); status != http.StatusUnauthorized {
+		t.Fatalf("expected request without required signed header to be rejected, got: %d", status)
+	}
+
+	// Requests signed too long ago are rejected.
+	req = sign(valid, "Content-Type")
+	clk.Advance(6 * time.Minute)
+
+	if status, _ := send(withBody(req)); status != http.StatusUnauthorized {
+		t.Fatalf("expected old request to be rejected, got: %d", status)
+	}
+}
+
+func Test_NonceStore(t *testing.T) {
+	clk := clock.NewFake(time.Now())
+	store := NewNonceStore(WithClock(clk))
+
+	for _, tc := range []struct {
+		advance  time.Duration
+		expected bool
+	}{
+		{expected: true},
+		{expected: false},
+		{advance: time.Minute, expected: true},
+	} {
+		clk.Advance(tc.advance)
+
+		if unused, _ := store.Use(context.Background(), "nonce", clk.Now().Add(time.Minute)); unused != tc.expected {
+			t.Fatalf("unexpected result after %s, got: %v, expected: %v", tc.advance, unused, tc.expected)
+		}
+	}
+
+	// Expired nonces are removed in order of expiry when new ones are added.
+	for i, expires := range []time.Duration{3 * time.Minute, time.Minute, 2 * time.Minute} {
+		_, _ = store.Use(context.Background(), strconv.Itoa(i), clk.Now().Add(expires))
+	}
+
+	clk.Advance(2 * time.Minute)
+	_, _ = store.Use(context.Background(), "new", clk.Now().Add(time.Minute))
+
+	if nonces := store.(*nonceStore).nonces; len(nonces) != 2 {
+		t.Fatalf("unexpected nonces after expiry, got: %v", nonces)
+	}
+}
@@ -0,0 +1,120 @@
+package stack
+
+import (
+	"net"
+	"net/http"
+
+	"github.com/bombsimon/http-helpers/debug"
+	"github.com/bombsimon/http-helpers/middleware"
+	"github.com/bombsimon/http-helpers/server"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+// AdminConfig configures an AdminServer.
+type AdminConfig struct {
+	// Addr is the address of the admin server. Defaults to "127.0.0.1:9090".
+	Addr string
+
+	// Token, if set, must be sent as a bearer token to the admin server.
+	Token string
+
+	// Hosts are the host names accepted in addition to localhost and IP
+	// addresses, see middleware.AdminGuard.
+	Hosts []string
+
+	// HealthChecks are run by the health endpoint.
+	HealthChecks []middleware.HealthCheck
+
+	// Gatherer is used to serve metrics. Defaults to the Prometheus default
+	// gatherer.
+	Gatherer prometheus.Gatherer
+
+	// Debug mounts the pprof, expvar, GC and build endpoints from the debug
+	// package on /debug/.
+	Debug bool
+
+	// Maintenance, if set, is served on /maintenance to toggle maintenance
+	// mode on the public server.
+	Maintenance *middleware.Maintenance
+}
+
+// AdminServer is an HTTP server for operational endpoints, run on an internal
+// address next to the public server so the endpoints are never reachable on
+// the public listener. It serves:
+//
+//   - /healthz with the health checks, see middleware.Health.
+//   - /metrics with the Prometheus metrics.
+//   - /maintenance to read and toggle maintenance mode, if set.
+//   - /debug/ with pprof, expvar, GC and build information, if enabled.
+//
+// The server only accepts requests from loopback and private addresses with a
+// local Host header, see middleware.AdminGuard.
+type AdminServer struct {
+	// Mux serves the admin endpoints. Register more endpoints here before
+	// calling Start.
+	Mux *http.ServeMux
+
+	// Server is the admin server serving Mux.
+	Server *http.Server
+}
+
+// NewAdminServer creates the admin server from the config. Nothing is started
+// until Start is called.
+func NewAdminServer(cfg AdminConfig) *AdminServer {
+	if cfg.Addr == "" {
+		cfg.Addr = "127.0.0.1:9090"
+	}
+
+	if cfg.Gatherer == nil {
+		cfg.Gatherer = prometheus.DefaultGatherer
+	}
+
+	mux := http.NewServeMux()
+	mux.Handle("/healthz", middleware.Health(cfg.HealthChecks...))
+	mux.Handle("/metrics", promhttp.HandlerFor(cfg.Gatherer, promhttp.HandlerOpts{}))
+
+	if cfg.Maintenance != nil {
+		mux.Handle("/maintenance", cfg.Maintenance)
+	}
+
+	if cfg.Debug {
+		debug.Mount(mux)
+	}
+
+	guard := middleware.AdminGuard(
+		middleware.WithAllowedHosts(cfg.Hosts...),
+		middleware.WithAdminToken(cfg.Token),
+	)
+
+	return &AdminServer{
+		Mux:    mux,
+		Server: server.New(cfg.Addr, guard(mux)),
+	}
+}
+
+// Start starts serving in a separate goroutine and adds the server to the
+// shutdown group, so it's shut down together with the public server. The
+// returned address and channel are the same as for
+// server.ListenAndServeNotify.
+//
+//	group := server.NewShutdownGroup()
+//	admin := stack.NewAdminServer(stack.AdminConfig{Maintenance: maintenance})
+//	if _, _, err := admin.Start(group); err != nil {
+//		log.Fatal(err)
+//	}
+//
+//	group.AddServer("public", public)
+//	go public.ListenAndServe()
+//
+//	<-group.GracefulShutdown(10*time.Second, logger)
+func (a *AdminServer) Start(group *server.ShutdownGroup) (net.Addr, <-chan error, error) {
+	addr, serveErr, err := server.ListenAndServeNotify(a.Server)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	group.AddServer("admin", a.Server)
+
+	return addr, serveErr, nil
+}
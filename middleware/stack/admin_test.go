@@ -0,0 +1,54 @@
+package stack
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/bombsimon/http-helpers/middleware"
+	"github.com/bombsimon/http-helpers/server"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+func Test_AdminServer(t *testing.T) {
+	maintenance := middleware.NewMaintenance()
+	admin := NewAdminServer(AdminConfig{
+		Addr:        "127.0.0.1:0",
+		Gatherer:    prometheus.NewRegistry(),
+		Maintenance: maintenance,
+	})
+
+	group := server.NewShutdownGroup()
+
+	addr, serveErr, err := admin.Start(group)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	req, err := http.NewRequest(http.MethodPut, "http://"+addr.String()+"/maintenance", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	body, _ := io.ReadAll(resp.Body)
+	resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK || !strings.Contains(string(body), `"enabled":true`) || !maintenance.Enabled() {
+		t.Fatalf("expected maintenance mode to be enabled, got: %d %s", resp.StatusCode, body)
+	}
+
+	if err := group.Shutdown(context.Background()); err != nil {
+		t.Fatalf("unexpected shutdown error: %s", err)
+	}
+
+	if err := <-serveErr; err != http.ErrServerClosed {
+		t.Fatalf("expected server to be closed, got: %v", err)
+	}
+}
@@ -0,0 +1,355 @@
+package stack
+
+/*
+A default, production ready, setup of the middlewares and servers in this
+repository. The public server is wrapped with request ID, real IP, logging,
+panic recovery, metrics and timeout middlewares and an admin server serves
+health checks, metrics and statistics on a separate, local, address protected
+from DNS rebinding. Both servers are shut down gracefully.
+
+	func main() {
+		router := http.NewServeMux()
+		router.HandleFunc("/hello", hello)
+
+		s, err := stack.New(stack.Config{
+			Handler: router,
+			HealthChecks: []middleware.HealthCheck{
+				{Name: "db", Check: db.PingContext},
+			},
+		})
+		if err != nil {
+			log.Fatal(err)
+		}
+
+		if err := s.Run(context.Background()); err != nil {
+			log.Fatal(err)
+		}
+	}
+
+Every part can be changed after New returns: add middlewares with
+Config.Middlewares, endpoints to Stack.AdminMux and change the servers before
+calling Run.
+*/
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"net/http"
+	"net/netip"
+	"time"
+
+	"github.com/bombsimon/http-helpers/chain"
+	"github.com/bombsimon/http-helpers/middleware"
+	"github.com/bombsimon/http-helpers/server"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// DisableAdmin can be used as Config.AdminAddr to not start the admin server.
+const DisableAdmin = "-"
+
+// Config configures the stack. Only Handler is required.
+type Config struct {
+	// Handler is the application handler served by the public server.
+	Handler http.Handler
+
+	// Addr is the address of the public server. Defaults to ":8080".
+	Addr string
+
+	// AdminAddr is the address of the admin server. Defaults to
+	// "127.0.0.1:9090". Set to DisableAdmin to not start the admin server.
+	AdminAddr string
+
+	// Logger is used by the middlewares and servers. Defaults to
+	// slog.Default().
+	Logger *slog.Logger
+
+	// ExcludedPaths are not logged or measured by the Logger and Prometheus
+	// middlewares, e.g. middleware.ProbePaths, see
+	// middleware.WithExcludedPaths.
+	ExcludedPaths []string
+
+	// Timeout is the deadline for each request. Defaults to 30 seconds.
+	Timeout time.Duration
+
+	// RouteTimeouts overrides Timeout for requests matching the route
+	// patterns, see middleware.WithRouteTimeouts.
+	RouteTimeouts map[string]time.Duration
+
+	// RateLimit, if set, rate limits all requests to the public server.
+	RateLimit *RateLimit
+
+	// TLSCertFile and TLSKeyFile, if set, make the public server serve HTTPS
+	// with the certificate, see server.RunTLS.
+	TLSCertFile string
+	TLSKeyFile  string
+
+	// WaitTime is the maximum time to wait for connections to drain when
+	// shutting down. Defaults to 10 seconds.
+	WaitTime time.Duration
+
+	// TrustedProxies are the proxies trusted to set the client IP in
+	// X-Forwarded-For and X-Real-Ip.
+	TrustedProxies []netip.Prefix
+
+	// ProxyProtocol makes the public server accept the PROXY protocol header
+	// from TrustedProxies, see server.WithProxyProtocol.
+	ProxyProtocol bool
+
+	// AdminToken, if set, must be sent as a bearer token to the admin server.
+	AdminToken string
+
+	// AdminHosts are the host names accepted by the admin server in addition
+	// to localhost and IP addresses, see middleware.AdminGuard.
+	AdminHosts []string
+
+	// HealthChecks are run by the health endpoint on the admin server.
+	HealthChecks []middleware.HealthCheck
+
+	// Debug mounts the pprof, expvar, GC and build endpoints from the debug
+	// package on /debug/ on the admin server.
+	Debug bool
+
+	// Profiles, if set, captures heap and goroutine profiles when the
+	// thresholds in the policy are crossed. The profiles are served on
+	// /debug/profiles on the admin server.
+	Profiles *middleware.ProfilePolicy
+
+	// Registry is used to register and serve metrics. Defaults to the
+	// Prometheus default registry.
+	Registry *prometheus.Registry
+
+	// Middlewares are run after the default middlewares, in the order
+	// they're passed, just before the handler.
+	Middlewares []middleware.Middleware
+
+	// ServerOptions are passed to server.Run for the public server.
+	ServerOptions []server.Option
+
+	// AdminServerOptions are passed to server.Run for the admin server. The
+	// admin server ignores signals and is shut down after the public server,
+	// so options such as server.WithSystemd and server.WithGracefulRestart
+	// belong in ServerOptions.
+	AdminServerOptions []server.Option
+}
+
+// RateLimit allows one request per interval with bursts of up to Burst
+// requests, see middleware.WithRateLimit.
+type RateLimit struct {
+	Interval time.Duration
+	Burst    int
+}
+
+// Stack is the wired handler and servers.
+type Stack struct {
+	// Chain is the chain of middlewares wrapping the handler.
+	Chain chain.Chain
+
+	// Handler is the handler wrapped with all middlewares.
+	Handler http.Handler
+
+	// Server is the public server serving Handler.
+	Server *http.Server
+
+	// AdminMux serves /healthz, /metrics, /maintenance and /stats on the
+	// admin server. Register more endpoints, e.g. pprof, here. The admin
+	// server only accepts requests from loopback and private addresses with a
+	// local Host header, see middleware.AdminGuard.
+	AdminMux *http.ServeMux
+
+	// Admin is the admin server, nil if disabled.
+	Admin *http.Server
+
+	// Stats holds the basic request statistics served on /stats.
+	Stats *middleware.Stats
+
+	// Maintenance toggles maintenance mode for the public server, also
+	// toggled on /maintenance on the admin server.
+	Maintenance *middleware.Maintenance
+
+	// Profiles holds the profiles served on /debug/profiles, nil unless
+	// Config.Profiles is set.
+	Profiles *middleware.ProfileCapture
+
+	options       []server.Option
+	publicOptions []server.Option
+	adminOptions  []server.Option
+	tls           bool
+}
+
+// New creates the stack from the config. Nothing is started until Run is
+// called. The config is validated with Config.Validate and a
+// server.StartupError with the ConfigErrors is returned if it's invalid.
+func New(cfg Config) (*Stack, error) {
+	// Building the chain with an invalid config could panic, e.g. for invalid
+	// route timeout patterns.
+	if err := cfg.Validate(); err != nil {
+		return nil, &server.StartupError{Err: err}
+	}
+
+	cfg = withDefaults(cfg)
+
+	var (
+		registerer prometheus.Registerer = prometheus.DefaultRegisterer
+		gatherer   prometheus.Gatherer   = prometheus.DefaultGatherer
+	)
+
+	if cfg.Registry != nil {
+		registerer, gatherer = cfg.Registry, cfg.Registry
+	}
+
+	stats := middleware.BasicStats()
+	maintenance := middleware.NewMaintenance(middleware.WithLogger(cfg.Logger))
+
+	excluded := middleware.WithExcludedPaths(cfg.ExcludedPaths...)
+
+	c := chain.New().
+		UseNamed("RequestID", middleware.RequestID()).
+		UseNamed("RealIP", middleware.RealIP(middleware.WithTrustedProxies(cfg.TrustedProxies...))).
+		UseNamed("Logger", middleware.NewLogger(middleware.WithLogger(cfg.Logger), excluded)).
+		UseNamed("PanicRecovery", middleware.NewPanicRecovery(middleware.WithLogger(cfg.Logger))).
+		UseNamed("Prometheus", middleware.Prometheus(middleware.WithRegisterer(registerer), excluded)).
+		UseNamed("BasicStats", stats.Middleware()).
+		UseNamed("Maintenance", maintenance.Middleware()).
+		UseNamed("Timeout", middleware.Timeout(cfg.Timeout, middleware.WithRouteTimeouts(cfg.RouteTimeouts)))
+
+	if cfg.RateLimit != nil {
+		c = c.UseNamed("RateLimiter", middleware.NewRateLimiter(
+			middleware.WithRateLimit(cfg.RateLimit.Interval, cfg.RateLimit.Burst),
+		))
+	}
+
+	var profiles *middleware.ProfileCapture
+	if cfg.Profiles != nil {
+		profiles = middleware.NewProfileCapture(*cfg.Profiles, middleware.WithLogger(cfg.Logger))
+		c = c.UseNamed("ProfileCapture", profiles.Middleware())
+	}
+
+	c = c.Use(cfg.Middlewares...)
+
+	handler := c.Then(cfg.Handler)
+
+	admin := NewAdminServer(AdminConfig{
+		Addr:         cfg.AdminAddr,
+		Token:        cfg.AdminToken,
+		Hosts:        cfg.AdminHosts,
+		HealthChecks: cfg.HealthChecks,
+		Gatherer:     gatherer,
+		Debug:        cfg.Debug,
+		Maintenance:  maintenance,
+	})
+
+	s := &Stack{
+		Chain:       c,
+		Handler:     handler,
+		Server:      server.New(cfg.Addr, handler),
+		AdminMux:    admin.Mux,
+		Stats:       stats,
+		Maintenance: maintenance,
+		Profiles:    profiles,
+		options: []server.Option{
+			server.WithSlogLogger(cfg.Logger),
+			server.WithWaitTime(cfg.WaitTime),
+		},
+		tls: cfg.TLSCertFile != "",
+	}
+
+	if s.tls {
+		s.publicOptions = append(s.publicOptions, server.WithCertFiles(cfg.TLSCertFile, cfg.TLSKeyFile))
+	}
+
+	if cfg.ProxyProtocol {
+		s.publicOptions = append(s.publicOptions, server.WithProxyProtocol(cfg.TrustedProxies...))
+	}
+
+	// Options such as systemd activation, graceful restarts and signal
+	// channels must only be used by one server.
+	s.publicOptions = append(s.publicOptions, cfg.ServerOptions...)
+	s.adminOptions = append(s.adminOptions, cfg.AdminServerOptions...)
+
+	s.AdminMux.Handle("/stats", stats)
+
+	if profiles != nil {
+		s.AdminMux.Handle("/debug/profiles", profiles)
+	}
+
+	if cfg.AdminAddr != DisableAdmin {
+		s.Admin = admin.Server
+	}
+
+	return s, nil
+}
+
+// Run starts the servers and blocks until they're shut down, either by a
+// signal or by the context being done. The admin server is shut down after the
+// public server so health checks and metrics are available while draining,
+// and the public server is shut down if the admin server fails.
+func (s *Stack) Run(ctx context.Context) error {
+	if s.Admin == nil {
+		return s.runPublic(ctx)
+	}
+
+	adminCtx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	publicCtx, cancelPublic := context.WithCancel(ctx)
+	defer cancelPublic()
+
+	adminErr := make(chan error, 1)
+
+	// The admin server ignores signals and is shut down when the public
+	// server is done.
+	adminOptions := append(append(append([]server.Option{}, s.options...), s.adminOptions...), server.WithSignals())
+
+	go func() {
+		err := server.Run(adminCtx, s.Admin, adminOptions...)
+
+		// Don't keep serving without health checks and metrics if the admin
+		// server fails, e.g. if the address is in use.
+		if err != nil {
+			cancelPublic()
+		}
+
+		adminErr <- err
+	}()
+
+	err := s.runPublic(publicCtx)
+
+	cancel()
+
+	return errors.Join(err, <-adminErr)
+}
+
+func (s *Stack) runPublic(ctx context.Context) error {
+	options := append(append([]server.Option{}, s.options...), s.publicOptions...)
+
+	if s.tls {
+		return server.RunTLS(ctx, s.Server, options...)
+	}
+
+	return server.Run(ctx, s.Server, options...)
+}
+
+func withDefaults(cfg Config) Config {
+	if cfg.Addr == "" {
+		cfg.Addr = ":8080"
+	}
+
+	if cfg.AdminAddr == "" {
+		cfg.AdminAddr = "127.0.0.1:9090"
+	}
+
+	if cfg.Logger == nil {
+		cfg.Logger = slog.Default()
+	}
+
+	if cfg.Timeout == 0 {
+		cfg.Timeout = 30 * time.Second
+	}
+
+	if cfg.WaitTime == 0 {
+		cfg.WaitTime = 10 * time.Second
+	}
+
+	return cfg
+}
@@ -0,0 +1,190 @@
+package stack
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/bombsimon/http-helpers/middleware"
+	"github.com/bombsimon/http-helpers/server"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+func Test_Stack(t *testing.T) {
+	var (
+		mu    sync.Mutex
+		addrs []string
+		ready = make(chan struct{}, 2)
+	)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	serverOptions := []server.Option{
+		server.WithLogger(nil),
+		server.OnReady(func(addr net.Addr) {
+			mu.Lock()
+			addrs = append(addrs, addr.String())
+			mu.Unlock()
+
+			ready <- struct{}{}
+		}),
+	}
+
+	s, err := New(Config{
+		Addr:      "127.0.0.1:0",
+		AdminAddr: "127.0.0.1:0",
+		Registry:  prometheus.NewRegistry(),
+		Profiles:  &middleware.ProfilePolicy{MaxInFlight: 100},
+		Debug:     true,
+		Handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			_, _ = w.Write([]byte("hello"))
+		}),
+		ServerOptions:      serverOptions,
+		AdminServerOptions: serverOptions,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	runErr := make(chan error, 1)
+
+	go func() {
+		runErr <- s.Run(ctx)
+	}()
+
+	<-ready
+	<-ready
+
+	get := func(url string) (*http.Response, string) {
+		resp, err := http.Get(url)
+		if err != nil {
+			t.Fatalf("could not get %s: %s", url, err)
+		}
+
+		defer resp.Body.Close()
+
+		body, _ := io.ReadAll(resp.Body)
+
+		return resp, string(body)
+	}
+
+	// Both servers listen on a random port so find the public server by
+	// asking for the handler.
+	var adminAddr string
+
+	mu.Lock()
+	defer mu.Unlock()
+
+	for _, addr := range addrs {
+		resp, body := get("http://" + addr + "/")
+		if body != "hello" {
+			adminAddr = addr
+			continue
+		}
+
+		if resp.Header.Get("X-Request-Id") == "" {
+			t.Fatal("expected request id to be set")
+		}
+	}
+
+	for path, expected := range map[string]string{
+		"/healthz":        `"status":"ok"`,
+		"/metrics":        "http_requests_total",
+		"/stats":          `"requests_total":1`,
+		"/maintenance":    `"enabled":false`,
+		"/debug/profiles": `[]`,
+		"/debug/build":    `"go_version"`,
+	} {
+		resp, body := get("http://" + adminAddr + path)
+		if resp.StatusCode != http.StatusOK || !strings.Contains(body, expected) {
+			t.Fatalf("unexpected response from %s: %d %s", path, resp.StatusCode, body)
+		}
+	}
+
+	// Requests to the admin server with a foreign Host are rejected to prevent
+	// DNS rebinding.
+	req, err := http.NewRequest(http.MethodGet, "http://"+adminAddr+"/metrics", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	req.Host = "attacker.example.com"
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	resp.Body.Close()
+
+	if resp.StatusCode != http.StatusForbidden {
+		t.Fatalf("unexpected status for foreign host, got: %d, expected: %d", resp.StatusCode, http.StatusForbidden)
+	}
+
+	cancel()
+
+	if err := <-runErr; err != nil {
+		t.Fatalf("unexpected error from run: %s", err)
+	}
+}
+
+func Test_StackAdminFailure(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer listener.Close()
+
+	s, err := New(Config{
+		Addr:               "127.0.0.1:0",
+		AdminAddr:          listener.Addr().String(),
+		Registry:           prometheus.NewRegistry(),
+		Handler:            http.NotFoundHandler(),
+		ServerOptions:      []server.Option{server.WithLogger(nil)},
+		AdminServerOptions: []server.Option{server.WithLogger(nil)},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	runErr := make(chan error, 1)
+
+	go func() {
+		runErr <- s.Run(context.Background())
+	}()
+
+	// The public server is shut down when the admin server can't listen.
+	select {
+	case err := <-runErr:
+		var startupErr *server.StartupError
+		if !errors.As(err, &startupErr) {
+			t.Fatalf("expected startup error, got: %v", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("expected run to return when the admin server fails")
+	}
+}
+
+func Test_StackServerOptions(t *testing.T) {
+	s, err := New(Config{
+		Handler:       http.NotFoundHandler(),
+		Registry:      prometheus.NewRegistry(),
+		ServerOptions: []server.Option{server.WithSystemd()},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Options like systemd activation must not be used by the admin server.
+	if len(s.publicOptions) != 1 || len(s.adminOptions) != 0 {
+		t.Fatalf("unexpected server options, public: %d, admin: %d", len(s.publicOptions), len(s.adminOptions))
+	}
+}
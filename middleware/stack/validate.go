@@ -0,0 +1,229 @@
+package stack
+
+import (
+	"crypto/tls"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// ConfigError is a problem with a field in the Config.
+type ConfigError struct {
+	Field   string
+	Message string
+}
+
+func (e ConfigError) Error() string {
+	return e.Field + ": " + e.Message
+}
+
+// ConfigErrors holds all the problems found by Config.Validate.
+type ConfigErrors []ConfigError
+
+// Error implements the error interface, listing one problem per line.
+func (e ConfigErrors) Error() string {
+	var sb strings.Builder
+
+	sb.WriteString("invalid stack config:")
+
+	for _, err := range e {
+		sb.WriteString("\n  - ")
+		sb.WriteString(err.Error())
+	}
+
+	return sb.String()
+}
+
+// Validate checks the whole config and returns all problems found as
+// ConfigErrors, so they can be fixed at once instead of being discovered one
+// at a time or by the first request. Zero values are replaced by their
+// defaults before the config is checked. Run validates the config before
+// starting the servers.
+func (cfg Config) Validate() error {
+	cfg = withDefaults(cfg)
+
+	var errs ConfigErrors
+
+	add := func(field, format string, args ...interface{}) {
+		errs = append(errs, ConfigError{Field: field, Message: fmt.Sprintf(format, args...)})
+	}
+
+	if cfg.Handler == nil {
+		add("Handler", "is required")
+	}
+
+	addrErr := validateAddr(cfg.Addr)
+	if addrErr != nil {
+		add("Addr", "%s", addrErr)
+	}
+
+	if cfg.AdminAddr != DisableAdmin {
+		if err := validateAddr(cfg.AdminAddr); err != nil {
+			add("AdminAddr", "%s", err)
+		} else if addrErr == nil && addrsOverlap(cfg.Addr, cfg.AdminAddr) {
+			add("AdminAddr", "%q overlaps with Addr %q", cfg.AdminAddr, cfg.Addr)
+		}
+	}
+
+	if cfg.Timeout < 0 {
+		add("Timeout", "must be positive, got %s", cfg.Timeout)
+	}
+
+	if cfg.WaitTime < 0 {
+		add("WaitTime", "must be positive, got %s", cfg.WaitTime)
+	}
+
+	errs = append(errs, validateRouteTimeouts(cfg)...)
+
+	if rl := cfg.RateLimit; rl != nil {
+		if rl.Interval <= 0 {
+			add("RateLimit.Interval", "must be positive, got %s", rl.Interval)
+		}
+
+		if rl.Burst < 1 {
+			add("RateLimit.Burst", "must be at least 1 to allow any requests, got %d", rl.Burst)
+		}
+	}
+
+	errs = append(errs, validateTLS(cfg)...)
+
+	for i, prefix := range cfg.TrustedProxies {
+		if !prefix.IsValid() {
+			add(fmt.Sprintf("TrustedProxies[%d]", i), "is not a valid prefix")
+		}
+	}
+
+	if cfg.ProxyProtocol && len(cfg.TrustedProxies) == 0 {
+		add("ProxyProtocol", "requires TrustedProxies to accept the header from")
+	}
+
+	if p := cfg.Profiles; p != nil {
+		if p.MaxInFlight < 0 || p.MaxLatency < 0 || p.Interval < 0 || p.MaxProfiles < 0 {
+			add("Profiles", "thresholds must not be negative")
+		}
+	}
+
+	if len(errs) > 0 {
+		return errs
+	}
+
+	return nil
+}
+
+func validateAddr(addr string) error {
+	_, port, err := net.SplitHostPort(addr)
+	if err != nil {
+		return fmt.Errorf("%q is not a valid address: %w", addr, err)
+	}
+
+	if _, err := net.LookupPort("tcp", port); err != nil {
+		return fmt.Errorf("%q has an invalid port", addr)
+	}
+
+	return nil
+}
+
+// addrsOverlap returns true if both addresses use the same port on the same
+// host or if either listens on all interfaces. Port 0 never overlaps.
+func addrsOverlap(a, b string) bool {
+	hostA, portA, _ := net.SplitHostPort(a)
+	hostB, portB, _ := net.SplitHostPort(b)
+
+	if n, err := strconv.Atoi(portA); err == nil && n == 0 {
+		return false
+	}
+
+	if portA != portB {
+		return false
+	}
+
+	return hostA == hostB || isUnspecified(hostA) || isUnspecified(hostB)
+}
+
+func isUnspecified(host string) bool {
+	if host == "" {
+		return true
+	}
+
+	ip := net.ParseIP(host)
+
+	return ip != nil && ip.IsUnspecified()
+}
+
+// validateRouteTimeouts checks that the timeouts are positive and that the
+// patterns are valid and don't conflict, the same way http.ServeMux does.
+func validateRouteTimeouts(cfg Config) []ConfigError {
+	patterns := make([]string, 0, len(cfg.RouteTimeouts))
+	for pattern := range cfg.RouteTimeouts {
+		patterns = append(patterns, pattern)
+	}
+
+	sort.Strings(patterns)
+
+	var (
+		errs []ConfigError
+		mux  = http.NewServeMux()
+	)
+
+	for _, pattern := range patterns {
+		field := fmt.Sprintf("RouteTimeouts[%q]", pattern)
+
+		if timeout := cfg.RouteTimeouts[pattern]; timeout <= 0 {
+			errs = append(errs, ConfigError{Field: field, Message: fmt.Sprintf("must be positive, got %s", timeout)})
+		}
+
+		if err := registerPattern(mux, pattern); err != nil {
+			errs = append(errs, ConfigError{Field: field, Message: err.Error()})
+		}
+	}
+
+	return errs
+}
+
+// registerPattern registers the pattern on the mux, returning the panic from
+// http.ServeMux for invalid or conflicting patterns as an error.
+func registerPattern(mux *http.ServeMux, pattern string) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("%v", r)
+		}
+	}()
+
+	mux.Handle(pattern, http.NotFoundHandler())
+
+	return nil
+}
+
+func validateTLS(cfg Config) []ConfigError {
+	if cfg.TLSCertFile == "" && cfg.TLSKeyFile == "" {
+		return nil
+	}
+
+	if cfg.TLSCertFile == "" || cfg.TLSKeyFile == "" {
+		return []ConfigError{{Field: "TLSCertFile", Message: "TLSCertFile and TLSKeyFile must be set together"}}
+	}
+
+	var errs []ConfigError
+
+	if _, err := os.Stat(cfg.TLSCertFile); err != nil {
+		errs = append(errs, ConfigError{Field: "TLSCertFile", Message: err.Error()})
+	}
+
+	if _, err := os.Stat(cfg.TLSKeyFile); err != nil {
+		errs = append(errs, ConfigError{Field: "TLSKeyFile", Message: err.Error()})
+	}
+
+	if len(errs) > 0 {
+		return errs
+	}
+
+	if _, err := tls.LoadX509KeyPair(cfg.TLSCertFile, cfg.TLSKeyFile); err != nil {
+		return []ConfigError{{Field: "TLSCertFile", Message: err.Error()}}
+	}
+
+	return nil
+}
@@ -0,0 +1,77 @@
+package stack
+
+import (
+	"errors"
+	"net/http"
+	"net/netip"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/bombsimon/http-helpers/server"
+)
+
+func Test_Validate(t *testing.T) {
+	if err := (Config{Handler: http.NotFoundHandler()}).Validate(); err != nil {
+		t.Fatalf("expected default config to be valid, got: %s", err)
+	}
+
+	missing := filepath.Join(t.TempDir(), "missing.pem")
+
+	cfg := Config{
+		Addr:      ":9090",
+		AdminAddr: "127.0.0.1:9090",
+		Timeout:   -time.Second,
+		RouteTimeouts: map[string]time.Duration{
+			"/exports/{id}":   time.Minute,
+			"/exports/{name}": 0,
+		},
+		RateLimit:      &RateLimit{Interval: time.Second},
+		TLSCertFile:    missing,
+		TLSKeyFile:     missing,
+		TrustedProxies: []netip.Prefix{{}},
+	}
+
+	err := cfg.Validate()
+
+	var errs ConfigErrors
+	if !errors.As(err, &errs) {
+		t.Fatalf("expected ConfigErrors, got: %v", err)
+	}
+
+	fields := make([]string, 0, len(errs))
+	for _, e := range errs {
+		fields = append(fields, e.Field)
+	}
+
+	expected := []string{
+		"Handler",
+		"AdminAddr",
+		"Timeout",
+		`RouteTimeouts["/exports/{name}"]`,
+		`RouteTimeouts["/exports/{name}"]`,
+		"RateLimit.Burst",
+		"TLSCertFile",
+		"TLSKeyFile",
+		"TrustedProxies[0]",
+	}
+
+	if strings.Join(fields, ",") != strings.Join(expected, ",") {
+		t.Fatalf("unexpected fields, got: %v, expected: %v", fields, expected)
+	}
+
+	if !strings.Contains(err.Error(), "invalid stack config:\n  - Handler: is required\n") {
+		t.Fatalf("unexpected error message: %s", err)
+	}
+
+	proxyErr := Config{Handler: http.NotFoundHandler(), ProxyProtocol: true}.Validate()
+	if !errors.As(proxyErr, &errs) || len(errs) != 1 || errs[0].Field != "ProxyProtocol" {
+		t.Fatalf("expected ProxyProtocol to require trusted proxies, got: %v", proxyErr)
+	}
+
+	s, newErr := New(cfg)
+	if s != nil || !errors.As(newErr, &errs) || server.ExitCode(newErr) != server.ExitStartupFailed {
+		t.Fatalf("expected New to fail with the config errors, got: %v", newErr)
+	}
+}
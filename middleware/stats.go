@@ -0,0 +1,148 @@
+package middleware
+
+import (
+	"encoding/json"
+	"net/http"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// Stats collects basic request statistics in-process. It's a lightweight
+// alternative to the Prometheus middleware for small deployments. Use
+// Middleware to collect statistics and serve the statistics as JSON by using
+// Stats as an http.Handler.
+type Stats struct {
+	mu        sync.Mutex
+	startedAt time.Time
+	total     int64
+	inFlight  int64
+	byStatus  map[int]int64
+	latencies []time.Duration
+	next      int
+}
+
+// StatsSnapshot is a snapshot of the collected statistics.
+type StatsSnapshot struct {
+	UptimeSeconds float64          `json:"uptime_seconds"`
+	RequestsTotal int64            `json:"requests_total"`
+	InFlight      int64            `json:"in_flight"`
+	ByStatus      map[string]int64 `json:"by_status"`
+	LatencyMS     LatencyQuantiles `json:"latency_ms"`
+}
+
+// LatencyQuantiles holds latency quantiles in milliseconds, calculated from
+// the most recent requests.
+type LatencyQuantiles struct {
+	P50 float64 `json:"p50"`
+	P90 float64 `json:"p90"`
+	P99 float64 `json:"p99"`
+	Max float64 `json:"max"`
+}
+
+// BasicStats creates a new Stats. Latency quantiles are calculated from the
+// most recent requests, the number of requests to keep is set with
+// WithSampleSize and defaults to 1024. A sample size of 0 or less disables the
+// quantiles.
+func BasicStats(opts ...Option) *Stats {
+	options := newOptions(opts...)
+
+	return &Stats{
+		startedAt: time.Now(),
+		byStatus:  map[int]int64{},
+		latencies: make([]time.Duration, 0, max(options.sampleSize, 0)),
+	}
+}
+
+// Middleware returns the middleware collecting the statistics.
+func (s *Stats) Middleware() Middleware {
+	return func(h http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			rw := NewResponseWriter(w)
+			startTime := time.Now()
+
+			s.mu.Lock()
+			s.inFlight++
+			s.mu.Unlock()
+
+			// Panicking requests are still counted and not left in flight.
+			defer func() {
+				s.observe(rw.statusCode, time.Since(startTime))
+			}()
+
+			h.ServeHTTP(rw.WithInterfaces(), r)
+		})
+	}
+}
+
+func (s *Stats) observe(status int, elapsed time.Duration) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.inFlight--
+	s.total++
+	s.byStatus[status]++
+
+	// Keep the latencies in a ring buffer so we only use the most recent
+	// requests.
+	if len(s.latencies) < cap(s.latencies) {
+		s.latencies = append(s.latencies, elapsed)
+		return
+	}
+
+	if len(s.latencies) > 0 {
+		s.latencies[s.next] = elapsed
+		s.next = (s.next + 1) % len(s.latencies)
+	}
+}
+
+// Snapshot returns the current statistics.
+func (s *Stats) Snapshot() StatsSnapshot {
+	s.mu.Lock()
+
+	snapshot := StatsSnapshot{
+		UptimeSeconds: time.Since(s.startedAt).Seconds(),
+		RequestsTotal: s.total,
+		InFlight:      s.inFlight,
+		ByStatus:      make(map[string]int64, len(s.byStatus)),
+	}
+
+	for status, count := range s.byStatus {
+		snapshot.ByStatus[strconv.Itoa(status)] = count
+	}
+
+	latencies := make([]time.Duration, len(s.latencies))
+	copy(latencies, s.latencies)
+
+	s.mu.Unlock()
+
+	if len(latencies) == 0 {
+		return snapshot
+	}
+
+	sort.Slice(latencies, func(i, j int) bool { return latencies[i] < latencies[j] })
+
+	quantile := func(q float64) float64 {
+		idx := int(q * float64(len(latencies)-1))
+		return float64(latencies[idx].Microseconds()) / 1000
+	}
+
+	snapshot.LatencyMS = LatencyQuantiles{
+		P50: quantile(0.5),
+		P90: quantile(0.9),
+		P99: quantile(0.99),
+		Max: quantile(1),
+	}
+
+	return snapshot
+}
+
+// ServeHTTP serves the statistics as JSON.
+func (s *Stats) ServeHTTP(w http.ResponseWriter, _ *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	if err := json.NewEncoder(w).Encode(s.Snapshot()); err != nil {
+		NewResponseWriter(w).WriteError(err)
+	}
+}
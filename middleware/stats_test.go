@@ -0,0 +1,83 @@
+package middleware
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func Test_BasicStats(t *testing.T) {
+	stats := BasicStats(WithSampleSize(2))
+
+	handlerWithMiddleware := AddMiddlewares(
+		http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.URL.Path == "/missing" {
+				w.WriteHeader(http.StatusNotFound)
+			}
+		}),
+		stats.Middleware(),
+	)
+
+	for _, path := range []string{"/", "/", "/missing"} {
+		handlerWithMiddleware.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, path, nil))
+	}
+
+	rec := httptest.NewRecorder()
+	stats.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/stats", nil))
+
+	var snapshot StatsSnapshot
+	if err := json.NewDecoder(rec.Body).Decode(&snapshot); err != nil {
+		t.Fatal("could not decode stats")
+	}
+
+	if snapshot.RequestsTotal != 3 || snapshot.InFlight != 0 {
+		t.Fatalf("unexpected totals: %+v", snapshot)
+	}
+
+	if snapshot.ByStatus["200"] != 2 || snapshot.ByStatus["404"] != 1 {
+		t.Fatalf("unexpected status counts: %+v", snapshot.ByStatus)
+	}
+
+	if len(stats.latencies) != 2 {
+		t.Fatalf("expected only the most recent latencies to be kept, got %d", len(stats.latencies))
+	}
+}
+
+func Test_BasicStatsNoSamples(t *testing.T) {
+	for _, size := range []int{0, -1} {
+		stats := BasicStats(WithSampleSize(size))
+
+		AddMiddlewares(http.HandlerFunc(func(http.ResponseWriter, *http.Request) {}), stats.Middleware()).
+			ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil))
+
+		if snapshot := stats.Snapshot(); snapshot.RequestsTotal != 1 || snapshot.LatencyMS != (LatencyQuantiles{}) {
+			t.Fatalf("unexpected stats for sample size %d: %+v", size, snapshot)
+		}
+	}
+}
+
+func Test_BasicStatsPanic(t *testing.T) {
+	stats := BasicStats()
+
+	handler := AddMiddlewares(
+		http.HandlerFunc(func(http.ResponseWriter, *http.Request) {
+			panic("boom")
+		}),
+		stats.Middleware(),
+	)
+
+	func() {
+		defer func() {
+			if recover() == nil {
+				t.Fatal("expected the panic to propagate")
+			}
+		}()
+
+		handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil))
+	}()
+
+	if snapshot := stats.Snapshot(); snapshot.RequestsTotal != 1 || snapshot.InFlight != 0 {
+		t.Fatalf("expected panicking request to be counted, got: %+v", snapshot)
+	}
+}
@@ -0,0 +1,126 @@
+package middleware
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/bombsimon/http-helpers/clock"
+)
+
+type timeoutKey struct{}
+
+// timeoutState is stored in the request context by Timeout so WithTimeout can
+// replace the deadline.
+type timeoutState struct {
+	// base is the request context before the deadline was set.
+	base  context.Context
+	ctx   context.Context
+	clock clock.Clock
+}
+
+// Timeout sets a deadline on the request context. Handlers should stop when
+// the context is done. If the deadline is exceeded and the handler returns
+// without writing a response, 503 Service Unavailable is written. Routes
+// registered with WithRouteTimeout get their own timeout and handlers wrapped
+// with WithTimeout replace the deadline. Long-lived routes, e.g. Server-Sent
+// Events and WebSockets, are exempted by wrapping the handler with
+// WithoutTimeout. Use WithClock to test the timeout without waiting.
+func Timeout(timeout time.Duration, opts ...Option) Middleware {
+	options := newOptions(opts...)
+
+	var routes *http.ServeMux
+	if len(options.routeTimeouts) > 0 {
+		// Use a mux to match the patterns the same way the router does.
+		routes = http.NewServeMux()
+		for pattern := range options.routeTimeouts {
+			routes.Handle(pattern, http.NotFoundHandler())
+		}
+	}
+
+	return options.skippable(func(h http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			d := timeout
+
+			if routes != nil {
+				if _, pattern := routes.Handler(r); pattern != "" {
+					d = options.routeTimeouts[pattern]
+				}
+			}
+
+			ctx, cancel := options.clock.WithTimeout(r.Context(), d)
+			defer cancel()
+
+			state := &timeoutState{base: r.Context(), ctx: ctx, clock: options.clock}
+			ctx = context.WithValue(ctx, timeoutKey{}, state)
+
+			rw := NewResponseWriter(w)
+
+			h.ServeHTTP(rw.WithInterfaces(), r.WithContext(ctx))
+
+			if errors.Is(state.ctx.Err(), context.DeadlineExceeded) && !rw.Written() {
+				http.Error(rw, http.StatusText(http.StatusServiceUnavailable), http.StatusServiceUnavailable)
+			}
+		})
+	})
+}
+
+// WithTimeout wraps the handler so it gets its own timeout, starting when the
+// handler is called, instead of the one set by the Timeout middleware. This
+// can be used for routes that need a longer deadline, e.g. exports or
+// uploads. If there's no Timeout middleware the timeout is added to the
+// request context as is.
+func WithTimeout(h http.Handler, timeout time.Duration) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		state, ok := r.Context().Value(timeoutKey{}).(*timeoutState)
+		if !ok {
+			ctx, cancel := context.WithTimeout(r.Context(), timeout)
+			defer cancel()
+
+			h.ServeHTTP(w, r.WithContext(ctx))
+
+			return
+		}
+
+		// Derive the deadline from the context before the Timeout middleware
+		// so it can be extended, but keep the values added since.
+		deadline, cancel := state.clock.WithTimeout(state.base, timeout)
+		defer cancel()
+
+		ctx := valuesContext{Context: deadline, values: r.Context()}
+		state.ctx = ctx
+
+		h.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+// WithoutTimeout wraps the handler so it doesn't get the deadline set by the
+// Timeout middleware, for long-lived routes such as Server-Sent Events and
+// WebSockets. The exemption is set on the route by the server so clients
+// can't lift the deadline on other routes.
+func WithoutTimeout(h http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		state, ok := r.Context().Value(timeoutKey{}).(*timeoutState)
+		if !ok {
+			h.ServeHTTP(w, r)
+			return
+		}
+
+		ctx := valuesContext{Context: state.base, values: r.Context()}
+		state.ctx = ctx
+
+		h.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+// valuesContext is a context with the deadline and cancellation from the
+// embedded context and the values from another context.
+type valuesContext struct {
+	context.Context
+	values context.Context
+}
+
+func (c valuesContext) Value(key any) any {
+	return c.values.Value(key)
+}
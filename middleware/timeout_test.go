@@ -0,0 +1,142 @@
+package middleware
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/bombsimon/http-helpers/clock"
+	"github.com/bombsimon/http-helpers/httpctx"
+)
+
+func Test_Timeout(t *testing.T) {
+	clk := clock.NewFake(time.Now())
+
+	handler := AddMiddlewares(
+		http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.URL.Path == "/fast" {
+				w.WriteHeader(http.StatusNoContent)
+				return
+			}
+
+			clk.Advance(time.Minute)
+
+			if !errors.Is(r.Context().Err(), context.DeadlineExceeded) {
+				t.Error("context not cancelled after timeout")
+			}
+		}),
+		Timeout(time.Minute, WithClock(clk)),
+	)
+
+	for path, expectedStatus := range map[string]int{
+		"/fast": http.StatusNoContent,
+		"/slow": http.StatusServiceUnavailable,
+	} {
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, path, nil))
+
+		if rec.Code != expectedStatus {
+			t.Fatalf("unexpected status for %s, got: %d, expected: %d", path, rec.Code, expectedStatus)
+		}
+	}
+}
+
+func Test_TimeoutOverrides(t *testing.T) {
+	clk := clock.NewFake(time.Now())
+
+	slow := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		clk.Advance(time.Minute)
+
+		if r.Context().Err() != nil {
+			return
+		}
+
+		if _, ok := httpctx.RequestID(r.Context()); !ok {
+			t.Error("context values lost when overriding timeout")
+		}
+
+		w.WriteHeader(http.StatusOK)
+	})
+
+	router := http.NewServeMux()
+	router.Handle("/slow", slow)
+	router.Handle("/exports/{id}", slow)
+	router.Handle("/uploads", WithTimeout(slow, 2*time.Minute))
+
+	handler := AddMiddlewares(
+		router,
+		RequestID(),
+		Timeout(
+			30*time.Second,
+			WithClock(clk),
+			WithRouteTimeouts(map[string]time.Duration{
+				"GET /exports/{id}": 2 * time.Minute,
+			}),
+		),
+	)
+
+	for path, expectedStatus := range map[string]int{
+		"/slow":       http.StatusServiceUnavailable,
+		"/exports/42": http.StatusOK,
+		"/uploads":    http.StatusOK,
+	} {
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, path, nil))
+
+		if rec.Code != expectedStatus {
+			t.Fatalf("unexpected status for %s, got: %d, expected: %d", path, rec.Code, expectedStatus)
+		}
+	}
+}
+
+func Test_WithoutTimeout(t *testing.T) {
+	clk := clock.NewFake(time.Now())
+
+	router := http.NewServeMux()
+	router.Handle("/events", WithoutTimeout(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		clk.Advance(time.Hour)
+
+		if r.Context().Err() != nil {
+			t.Error("event stream cancelled by timeout")
+		}
+	})))
+	router.Handle("/", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		clk.Advance(time.Hour)
+	}))
+
+	handler := Timeout(time.Minute, WithClock(clk))(router)
+
+	// The request headers don't lift the deadline on other routes.
+	for i, step := range []struct {
+		path           string
+		headers        map[string]string
+		expectedStatus int
+	}{
+		{path: "/events", expectedStatus: http.StatusOK},
+		{
+			path:           "/other",
+			headers:        map[string]string{"Accept": "text/event-stream"},
+			expectedStatus: http.StatusServiceUnavailable,
+		},
+		{
+			path:           "/other",
+			headers:        map[string]string{"Connection": "Upgrade", "Upgrade": "websocket"},
+			expectedStatus: http.StatusServiceUnavailable,
+		},
+	} {
+		req := httptest.NewRequest(http.MethodGet, step.path, nil)
+		for k, v := range step.headers {
+			req.Header.Set(k, v)
+		}
+
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+
+		if rec.Code != step.expectedStatus {
+			t.Fatalf("unexpected status for step %d, got: %d, expected: %d", i, rec.Code, step.expectedStatus)
+		}
+	}
+}
@@ -0,0 +1,63 @@
+package middleware
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/bombsimon/http-helpers/httpctx"
+	"github.com/bombsimon/http-helpers/tracing"
+)
+
+// Tracing starts a server span for each request, continuing the trace from the
+// traceparent header or starting a new trace. The trace and span ID are
+// stored in the request context, where the Logger middleware, if applied
+// before, and client.Tracing read them, and sampled spans are passed to the
+// exporter when the handler returns. The span is named after the method and,
+// with WithRouteLabel, the route.
+func Tracing(exporter tracing.Exporter, opts ...Option) Middleware {
+	options := newOptions(opts...)
+
+	return options.skippable(func(h http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			span := httpctx.Trace{TraceID: tracing.NewTraceID(), Sampled: true}
+
+			parent, ok := tracing.Parse(r.Header.Get(tracing.TraceparentHeader))
+			if ok {
+				span.TraceID, span.Sampled = parent.TraceID, parent.Sampled
+			}
+
+			span.SpanID = tracing.NewSpanID()
+
+			rw := NewResponseWriter(w)
+			startTime := time.Now()
+
+			h.ServeHTTP(rw.WithInterfaces(), r.WithContext(httpctx.WithTrace(r.Context(), span)))
+
+			if exporter == nil || !span.Sampled {
+				return
+			}
+
+			name := r.Method
+			if options.route != nil {
+				name += " " + options.route(r)
+			}
+
+			exporter(tracing.Span{
+				Name:         name,
+				Kind:         tracing.KindServer,
+				TraceID:      span.TraceID,
+				SpanID:       span.SpanID,
+				ParentSpanID: parent.SpanID,
+				Start:        startTime,
+				End:          time.Now(),
+				Status:       rw.statusCode,
+				Err:          rw.responseError,
+				Attributes: map[string]string{
+					"http.method": r.Method,
+					"http.host":   r.Host,
+					"http.path":   r.URL.Path,
+				},
+			})
+		})
+	})
+}
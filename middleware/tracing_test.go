@@ -0,0 +1,97 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	"github.com/bombsimon/http-helpers/client"
+	"github.com/bombsimon/http-helpers/tracing"
+)
+
+func Test_Tracing(t *testing.T) {
+	var (
+		mu    sync.Mutex
+		spans []tracing.Span
+	)
+
+	exporter := func(span tracing.Span) {
+		mu.Lock()
+		defer mu.Unlock()
+
+		spans = append(spans, span)
+	}
+
+	upstream := httptest.NewServer(AddMiddlewares(
+		http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusNoContent)
+		}),
+		Tracing(exporter),
+	))
+	defer upstream.Close()
+
+	httpClient := &http.Client{Transport: client.WrapTransport(nil, client.Tracing(exporter))}
+
+	handler := AddMiddlewares(
+		http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			req, _ := http.NewRequestWithContext(r.Context(), http.MethodGet, upstream.URL, nil)
+
+			resp, err := httpClient.Do(req)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			resp.Body.Close()
+		}),
+		Tracing(exporter),
+	)
+
+	r := httptest.NewRequest(http.MethodGet, "/", nil)
+	r.Header.Set(tracing.TraceparentHeader, "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+
+	handler.ServeHTTP(httptest.NewRecorder(), r)
+
+	// Spans are exported when they end: upstream server, client, server.
+	if len(spans) != 3 {
+		t.Fatalf("expected three spans, got: %d", len(spans))
+	}
+
+	upstreamSpan, clientSpan, serverSpan := spans[0], spans[1], spans[2]
+
+	for _, span := range spans {
+		if span.TraceID != "4bf92f3577b34da6a3ce929d0e0e4736" {
+			t.Fatalf("expected all spans in the same trace, got: %s", span.TraceID)
+		}
+	}
+
+	if serverSpan.Kind != tracing.KindServer || serverSpan.ParentSpanID != "00f067aa0ba902b7" {
+		t.Fatalf("unexpected server span: %+v", serverSpan)
+	}
+
+	if clientSpan.Kind != tracing.KindClient || clientSpan.ParentSpanID != serverSpan.SpanID {
+		t.Fatalf("expected client span to be a child of the server span: %+v", clientSpan)
+	}
+
+	if upstreamSpan.ParentSpanID != clientSpan.SpanID || upstreamSpan.Status != http.StatusNoContent {
+		t.Fatalf("expected upstream span to be a child of the client span: %+v", upstreamSpan)
+	}
+}
+
+func Test_TracingNotSampled(t *testing.T) {
+	var exported int
+
+	handler := AddMiddlewares(
+		http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}),
+		Tracing(func(tracing.Span) { exported++ }),
+	)
+
+	r := httptest.NewRequest(http.MethodGet, "/", nil)
+	r.Header.Set(tracing.TraceparentHeader, "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-00")
+
+	handler.ServeHTTP(httptest.NewRecorder(), r)
+
+	if exported != 0 {
+		t.Fatalf("expected unsampled span not to be exported, got: %d", exported)
+	}
+}
@@ -0,0 +1,316 @@
+package middleware
+
+import (
+	"bytes"
+	"encoding/json"
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// Transformer transforms buffered response bodies matching the content types
+// and status.
+type Transformer struct {
+	// ContentTypes are the media types to transform, e.g. "application/json"
+	// or "text/*". All content types are transformed if empty.
+	ContentTypes []string
+
+	// Status returns true for the statuses to transform. All statuses are
+	// transformed if nil.
+	Status func(status int) bool
+
+	// Transform returns the new body. The header can be modified, e.g. to
+	// change the content type. Content-Length is set after all transformers
+	// have run.
+	Transform func(r *http.Request, status int, header http.Header, body []byte) ([]byte, error)
+}
+
+// matches returns true if the transformer should transform the response.
+func (t Transformer) matches(status int, header http.Header) bool {
+	if t.Status != nil && !t.Status(status) {
+		return false
+	}
+
+	if len(t.ContentTypes) == 0 {
+		return true
+	}
+
+	mediaType, _, _ := mime.ParseMediaType(header.Get("Content-Type"))
+
+	for _, contentType := range t.ContentTypes {
+		if contentType == "*/*" || contentType == mediaType ||
+			strings.HasSuffix(contentType, "/*") && strings.HasPrefix(mediaType, strings.TrimSuffix(contentType, "*")) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Transform buffers responses matching any of the transformers and runs the
+// matching transformers on the body, in order, before writing it with a new
+// Content-Length. Other responses are streamed as is. Responses with a
+// Content-Encoding, e.g. compressed by the handler, responses to HEAD requests
+// and responses without a body aren't transformed, so add compression
+// middlewares before Transform to compress the transformed body. If a
+// transformer fails the error is stored with WriteError and 500 Internal
+// Server Error is written instead.
+func Transform(transformers []Transformer, opts ...Option) Middleware {
+	options := newOptions(opts...)
+
+	return options.skippable(func(h http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.Method == http.MethodHead {
+				h.ServeHTTP(w, r)
+				return
+			}
+
+			rw := NewResponseWriter(w)
+			bw := &transformWriter{
+				ResponseWriter: rw,
+				match: func(status int, header http.Header) bool {
+					if !bodyAllowed(status) || header.Get("Content-Encoding") != "" && header.Get("Content-Encoding") != "identity" {
+						return false
+					}
+
+					for _, t := range transformers {
+						if t.matches(status, header) {
+							return true
+						}
+					}
+
+					return false
+				},
+			}
+
+			// The handler gets its own ResponseWriterWithInfo writing to the
+			// buffer, errors written to it are passed on.
+			inner := NewResponseWriter(bw)
+			h.ServeHTTP(inner.WithInterfaces(), r)
+
+			if err := inner.Err(); err != nil {
+				rw.WriteError(err)
+			}
+
+			if !bw.wroteHeader {
+				bw.WriteHeader(http.StatusOK)
+			}
+
+			if !bw.buffering {
+				return
+			}
+
+			body := bw.buf.Bytes()
+			header := rw.Header()
+
+			for _, t := range transformers {
+				if !t.matches(bw.status, header) {
+					continue
+				}
+
+				var err error
+				if body, err = t.Transform(r, bw.status, header, body); err != nil {
+					rw.WriteError(err)
+					header.Del("Content-Length")
+					http.Error(rw, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+
+					return
+				}
+			}
+
+			header.Set("Content-Length", strconv.Itoa(len(body)))
+			rw.WriteHeader(bw.status)
+			_, _ = rw.Write(body)
+		})
+	})
+}
+
+// transformWriter buffers the response if it matches a transformer once the
+// status and headers are written, and otherwise writes it through.
+type transformWriter struct {
+	http.ResponseWriter
+
+	match       func(status int, header http.Header) bool
+	status      int
+	wroteHeader bool
+	buffering   bool
+	buf         bytes.Buffer
+}
+
+func (w *transformWriter) WriteHeader(code int) {
+	if w.wroteHeader {
+		return
+	}
+
+	if code >= 100 && code < 200 && code != http.StatusSwitchingProtocols {
+		w.ResponseWriter.WriteHeader(code)
+		return
+	}
+
+	w.wroteHeader = true
+	w.status = code
+	w.buffering = w.match(code, w.Header())
+
+	if !w.buffering {
+		w.ResponseWriter.WriteHeader(code)
+	}
+}
+
+func (w *transformWriter) Write(b []byte) (int, error) {
+	if !w.wroteHeader {
+		if w.Header().Get("Content-Type") == "" {
+			w.Header().Set("Content-Type", http.DetectContentType(b))
+		}
+
+		w.WriteHeader(http.StatusOK)
+	}
+
+	if w.buffering {
+		return w.buf.Write(b)
+	}
+
+	return w.ResponseWriter.Write(b)
+}
+
+// Flush flushes responses written through. Buffered responses are written when
+// the handler returns.
+func (w *transformWriter) Flush() {
+	if !w.wroteHeader {
+		w.WriteHeader(http.StatusOK)
+	}
+
+	if !w.buffering {
+		_ = http.NewResponseController(w.ResponseWriter).Flush()
+	}
+}
+
+// bodyAllowed returns false for statuses that must not have a body.
+func bodyAllowed(status int) bool {
+	return status >= 200 && status != http.StatusNoContent && status != http.StatusNotModified
+}
+
+// RedactJSON returns a transformer replacing the values of the fields, at any
+// depth, in JSON responses with "[REDACTED]", e.g. to keep secrets returned by
+// an upstream from reaching clients. Bodies that aren't valid JSON are left
+// as is.
+func RedactJSON(fields ...string) Transformer {
+	redact := make(map[string]struct{}, len(fields))
+	for _, field := range fields {
+		redact[field] = struct{}{}
+	}
+
+	return Transformer{
+		ContentTypes: []string{"application/json"},
+		Transform: func(_ *http.Request, _ int, _ http.Header, body []byte) ([]byte, error) {
+			decoder := json.NewDecoder(bytes.NewReader(body))
+			decoder.UseNumber()
+
+			var v any
+			if err := decoder.Decode(&v); err != nil {
+				return body, nil
+			}
+
+			return json.Marshal(redactValue(v, redact))
+		},
+	}
+}
+
+func redactValue(v any, redact map[string]struct{}) any {
+	switch v := v.(type) {
+	case map[string]any:
+		for key, value := range v {
+			if _, ok := redact[key]; ok {
+				v[key] = "[REDACTED]"
+			} else {
+				v[key] = redactValue(value, redact)
+			}
+		}
+	case []any:
+		for i, value := range v {
+			v[i] = redactValue(value, redact)
+		}
+	}
+
+	return v
+}
+
+// WrapJSON returns a transformer wrapping successful JSON responses in an
+// envelope object with the body as the value of the key, e.g. {"data": ...}.
+func WrapJSON(key string) Transformer {
+	return Transformer{
+		ContentTypes: []string{"application/json"},
+		Status: func(status int) bool {
+			return status < 300
+		},
+		Transform: func(_ *http.Request, _ int, _ http.Header, body []byte) ([]byte, error) {
+			if !json.Valid(body) {
+				return body, nil
+			}
+
+			encodedKey, err := json.Marshal(key)
+			if err != nil {
+				return nil, err
+			}
+
+			var buf bytes.Buffer
+
+			buf.WriteString("{")
+			buf.Write(encodedKey)
+			buf.WriteString(":")
+			buf.Write(bytes.TrimSpace(body))
+			buf.WriteString("}\n")
+
+			return buf.Bytes(), nil
+		},
+	}
+}
+
+// InjectHTML returns a transformer inserting the snippet, e.g. a script tag,
+// before the closing body tag of HTML responses, or at the end if there is
+// none.
+func InjectHTML(snippet string) Transformer {
+	return Transformer{
+		ContentTypes: []string{"text/html"},
+		Transform: func(_ *http.Request, _ int, _ http.Header, body []byte) ([]byte, error) {
+			i := lastIndexFoldASCII(body, "</body>")
+			if i < 0 {
+				return append(body, snippet...), nil
+			}
+
+			injected := make([]byte, 0, len(body)+len(snippet))
+			injected = append(injected, body[:i]...)
+			injected = append(injected, snippet...)
+
+			return append(injected, body[i:]...), nil
+		},
+	}
+}
+
+// lastIndexFoldASCII returns the index of the last instance of the lower case
+// ASCII substr in s, ignoring ASCII case. Unlike searching a lower cased copy
+// the index is always valid for s, lowering some UTF-8 characters changes
+// their length.
+func lastIndexFoldASCII(s []byte, substr string) int {
+	for i := len(s) - len(substr); i >= 0; i-- {
+		match := true
+
+		for j := 0; j < len(substr); j++ {
+			c := s[i+j]
+			if c >= 'A' && c <= 'Z' {
+				c += 'a' - 'A'
+			}
+
+			if c != substr[j] {
+				match = false
+				break
+			}
+		}
+
+		if match {
+			return i
+		}
+	}
+
+	return -1
+}
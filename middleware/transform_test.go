@@ -0,0 +1,152 @@
+package middleware
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func Test_Transform(t *testing.T) {
+	transformers := []Transformer{
+		RedactJSON("password"),
+		WrapJSON("data"),
+		InjectHTML(`<script src="/analytics.js"></script>`),
+	}
+
+	for _, tc := range []struct {
+		name     string
+		method   string
+		handler  http.HandlerFunc
+		status   int
+		expected string
+	}{
+		{
+			name: "redact and wrap json",
+			handler: func(w http.ResponseWriter, r *http.Request) {
+				w.Header().Set("Content-Type", "application/json")
+				w.Header().Set("Content-Length", "48")
+				_, _ = io.WriteString(w, `{"user":{"name":"bob","password":"hunter2"}}`)
+			},
+			status:   http.StatusOK,
+			expected: `{"data":{"user":{"name":"bob","password":"[REDACTED]"}}}` + "\n",
+		},
+		{
+			name: "only redact errors",
+			handler: func(w http.ResponseWriter, r *http.Request) {
+				w.Header().Set("Content-Type", "application/json; charset=utf-8")
+				w.WriteHeader(http.StatusBadRequest)
+				_, _ = io.WriteString(w, `[{"password":"hunter2","id":1.50}]`)
+			},
+			status:   http.StatusBadRequest,
+			expected: `[{"id":1.50,"password":"[REDACTED]"}]`,
+		},
+		{
+			name: "inject sniffed html",
+			handler: func(w http.ResponseWriter, r *http.Request) {
+				_, _ = io.WriteString(w, "<html><body>")
+				_, _ = io.WriteString(w, "hello</BODY></html>")
+			},
+			status:   http.StatusOK,
+			expected: `<html><body>hello<script src="/analytics.js"></script></BODY></html>`,
+		},
+		{
+			name: "inject html with multi-byte text",
+			handler: func(w http.ResponseWriter, r *http.Request) {
+				w.Header().Set("Content-Type", "text/html; charset=utf-8")
+				_, _ = io.WriteString(w, "<body>"+strings.Repeat("Ⱥ", 20)+"</body>")
+			},
+			status:   http.StatusOK,
+			expected: "<body>" + strings.Repeat("Ⱥ", 20) + `<script src="/analytics.js"></script></body>`,
+		},
+		{
+			name: "compressed responses are left as is",
+			handler: func(w http.ResponseWriter, r *http.Request) {
+				w.Header().Set("Content-Type", "application/json")
+				w.Header().Set("Content-Encoding", "gzip")
+				_, _ = io.WriteString(w, "compressed")
+			},
+			status:   http.StatusOK,
+			expected: "compressed",
+		},
+		{
+			name: "other content types are streamed",
+			handler: func(w http.ResponseWriter, r *http.Request) {
+				w.Header().Set("Content-Type", "text/plain")
+				_, _ = io.WriteString(w, "plain")
+				http.NewResponseController(w).Flush()
+			},
+			status:   http.StatusOK,
+			expected: "plain",
+		},
+		{
+			name:   "head requests are left as is",
+			method: http.MethodHead,
+			handler: func(w http.ResponseWriter, r *http.Request) {
+				w.Header().Set("Content-Type", "application/json")
+				w.WriteHeader(http.StatusOK)
+			},
+			status:   http.StatusOK,
+			expected: "",
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			method := tc.method
+			if method == "" {
+				method = http.MethodGet
+			}
+
+			rec := httptest.NewRecorder()
+			AddMiddlewares(tc.handler, Transform(transformers)).ServeHTTP(rec, httptest.NewRequest(method, "/", nil))
+
+			if rec.Code != tc.status {
+				t.Fatalf("unexpected status, got: %d, expected: %d", rec.Code, tc.status)
+			}
+
+			if rec.Body.String() != tc.expected {
+				t.Fatalf("unexpected body, got: %s, expected: %s", rec.Body.String(), tc.expected)
+			}
+
+			if length := rec.Header().Get("Content-Length"); length != "" && length != strconv.Itoa(len(tc.expected)) {
+				t.Fatalf("unexpected Content-Length, got: %s, expected: %d", length, len(tc.expected))
+			}
+		})
+	}
+}
+
+func Test_TransformError(t *testing.T) {
+	errTransform := errors.New("transform failed")
+
+	var rw *ResponseWriterWithInfo
+
+	handler := AddMiddlewares(
+		http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			_, _ = io.WriteString(w, "body")
+		}),
+		Transform([]Transformer{{
+			Transform: func(*http.Request, int, http.Header, []byte) ([]byte, error) {
+				return nil, errTransform
+			},
+		}}),
+		func(h http.Handler) http.Handler {
+			return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				rw = NewResponseWriter(w)
+				h.ServeHTTP(rw, r)
+			})
+		},
+	)
+
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+
+	if rec.Code != http.StatusInternalServerError {
+		t.Fatalf("unexpected status: %d", rec.Code)
+	}
+
+	if !errors.Is(rw.Err(), errTransform) {
+		t.Fatalf("expected error to be stored, got: %v", rw.Err())
+	}
+}
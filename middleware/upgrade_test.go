@@ -0,0 +1,148 @@
+package middleware
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// syncBuffer is a buffer safe to write from the goroutine closing a hijacked
+// connection.
+type syncBuffer struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+func (b *syncBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	return b.buf.Write(p)
+}
+
+func (b *syncBuffer) Bytes() []byte {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	return append([]byte{}, b.buf.Bytes()...)
+}
+
+const upgradeResponse = "HTTP/1.1 101 Switching Protocols\r\nUpgrade: websocket\r\nConnection: Upgrade\r\n\r\n"
+
+func Test_Upgrade(t *testing.T) {
+	var (
+		buf      = &syncBuffer{}
+		registry = prometheus.NewRegistry()
+		deadline = make(chan bool, 1)
+	)
+
+	handler := AddMiddlewares(
+		WithoutTimeout(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			_, hasDeadline := r.Context().Deadline()
+			deadline <- hasDeadline
+
+			conn, brw, err := http.NewResponseController(w).Hijack()
+			if err != nil {
+				t.Error(err)
+				return
+			}
+
+			_, _ = brw.WriteString(upgradeResponse)
+			_ = brw.Flush()
+
+			// Serve the connection after the handler returns, like many
+			// WebSocket libraries do.
+			go func() {
+				defer conn.Close()
+
+				_, _ = io.Copy(io.Discard, conn)
+			}()
+		})),
+		Timeout(time.Millisecond),
+		Prometheus(WithRegisterer(registry)),
+		NewLogger(WithLogger(slog.New(slog.NewJSONHandler(buf, nil)))),
+	)
+
+	server := httptest.NewServer(handler)
+	defer server.Close()
+
+	conn, err := net.Dial("tcp", server.Listener.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	req, _ := http.NewRequest(http.MethodGet, server.URL+"/ws", nil)
+	req.Header.Set("Connection", "Upgrade")
+	req.Header.Set("Upgrade", "websocket")
+
+	if err := req.Write(conn); err != nil {
+		t.Fatal(err)
+	}
+
+	resp, err := http.ReadResponse(bufio.NewReader(conn), req)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if resp.StatusCode != http.StatusSwitchingProtocols {
+		t.Fatalf("unexpected status, got: %d", resp.StatusCode)
+	}
+
+	if <-deadline {
+		t.Fatal("expected upgrade route to not get a deadline")
+	}
+
+	// Wait past the timeout to make sure the connection isn't logged when the
+	// handler returns.
+	time.Sleep(10 * time.Millisecond)
+
+	if len(buf.Bytes()) != 0 {
+		t.Fatalf("expected nothing to be logged before the connection is closed, got: %s", buf.Bytes())
+	}
+
+	conn.Close()
+
+	for start := time.Now(); len(buf.Bytes()) == 0; time.Sleep(time.Millisecond) {
+		if time.Since(start) > time.Second {
+			t.Fatal("expected connection to be logged when closed")
+		}
+	}
+
+	logged := map[string]interface{}{}
+	if err := json.Unmarshal(buf.Bytes(), &logged); err != nil {
+		t.Fatalf("could not parse logged message: %s", err)
+	}
+
+	if logged["status"] != float64(http.StatusSwitchingProtocols) || logged["hijacked"] != true || logged["connection_duration"] == nil {
+		t.Fatalf("unexpected log: %v", logged)
+	}
+
+	// The upgrade response is written through the buffered writer.
+	if logged["response_size"] != float64(0) || logged["bytes_sent"] != float64(len(upgradeResponse)) {
+		t.Fatalf("unexpected sizes, got: %v and %v, expected: 0 and %d", logged["response_size"], logged["bytes_sent"], len(upgradeResponse))
+	}
+
+	families, err := registry.Gather()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	series := map[string]int{}
+	for _, family := range families {
+		series[family.GetName()] = len(family.GetMetric())
+	}
+
+	if series["request_duration_seconds"] != 0 || series["http_requests_total"] != 1 {
+		t.Fatalf("expected hijacked connection to be counted without latency, got: %v", series)
+	}
+}
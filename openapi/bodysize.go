@@ -0,0 +1,90 @@
+package openapi
+
+import (
+	"encoding/json"
+	"math"
+
+	"github.com/getkin/kin-openapi/openapi3"
+	"github.com/getkin/kin-openapi/routers"
+)
+
+// ExtensionMaxBodySize is the extension setting the maximum request body size
+// in bytes on an operation or request body in the spec.
+const ExtensionMaxBodySize = "x-max-body-size"
+
+// maxBodySize returns the maximum request body size for the route. Overrides
+// set with WithMaxBodySize are used first, then the size from the spec and
+// last the default.
+func (o *options) maxBodySize(route *routers.Route) int64 {
+	if n, ok := o.maxBodySizes[route.Method+" "+route.Path]; ok {
+		return n
+	}
+
+	if id := route.Operation.OperationID; id != "" {
+		if n, ok := o.maxBodySizes[id]; ok {
+			return n
+		}
+	}
+
+	if n, ok := specMaxBodySize(route.Operation); ok {
+		return n
+	}
+
+	return o.defaultMaxBodySize
+}
+
+// specMaxBodySize returns the size set with ExtensionMaxBodySize on the
+// operation or request body or, for binary bodies, the largest maxLength.
+func specMaxBodySize(operation *openapi3.Operation) (int64, bool) {
+	if n, ok := extensionSize(operation.Extensions); ok {
+		return n, true
+	}
+
+	if operation.RequestBody == nil || operation.RequestBody.Value == nil {
+		return 0, false
+	}
+
+	body := operation.RequestBody.Value
+
+	if n, ok := extensionSize(body.Extensions); ok {
+		return n, true
+	}
+
+	var (
+		largest int64
+		found   bool
+	)
+
+	for _, media := range body.Content {
+		if media.Schema == nil || media.Schema.Value == nil {
+			continue
+		}
+
+		schema := media.Schema.Value
+		if !schema.Type.Is(openapi3.TypeString) || schema.Format != "binary" || schema.MaxLength == nil {
+			continue
+		}
+
+		if n := int64(min(*schema.MaxLength, math.MaxInt64)); n > largest {
+			largest, found = n, true
+		}
+	}
+
+	return largest, found
+}
+
+func extensionSize(extensions map[string]any) (int64, bool) {
+	switch v := extensions[ExtensionMaxBodySize].(type) {
+	case float64:
+		return int64(v), v > 0
+	case int:
+		return int64(v), v > 0
+	case int64:
+		return v, v > 0
+	case json.Number:
+		n, err := v.Int64()
+		return n, err == nil && n > 0
+	default:
+		return 0, false
+	}
+}
@@ -0,0 +1,159 @@
+package openapi
+
+import (
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/bombsimon/http-helpers/httpctx"
+	"github.com/getkin/kin-openapi/openapi3"
+)
+
+const uploadSpec = `
+openapi: 3.0.3
+info:
+  title: Uploads
+  version: 1.0.0
+paths:
+  /notes:
+    post:
+      operationId: createNote
+      x-max-body-size: 16
+      requestBody:
+        content:
+          application/json:
+            schema:
+              type: object
+      responses:
+        '204':
+          description: Created
+  /avatar:
+    put:
+      requestBody:
+        content:
+          image/png:
+            schema:
+              type: string
+              format: binary
+              maxLength: 8
+      responses:
+        '204':
+          description: Updated
+`
+
+func Test_ValidatorBodySize(t *testing.T) {
+	doc, err := openapi3.NewLoader().LoadFromData([]byte(uploadSpec))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name                string
+		opts                []Option
+		method              string
+		target              string
+		contentType         string
+		body                string
+		expectedStatus      int
+		expectedMaxBodySize int64
+	}{
+		{
+			name:                "size from extension",
+			method:              http.MethodPost,
+			target:              "/notes",
+			contentType:         "application/json",
+			body:                `{"text":"hi"}`,
+			expectedStatus:      http.StatusNoContent,
+			expectedMaxBodySize: 16,
+		},
+		{
+			name:           "too large for extension",
+			method:         http.MethodPost,
+			target:         "/notes",
+			contentType:    "application/json",
+			body:           `{"text":"hello world"}`,
+			expectedStatus: http.StatusRequestEntityTooLarge,
+		},
+		{
+			name:                "overridden by operation id",
+			opts:                []Option{WithMaxBodySize("createNote", 64)},
+			method:              http.MethodPost,
+			target:              "/notes",
+			contentType:         "application/json",
+			body:                `{"text":"hello world"}`,
+			expectedStatus:      http.StatusNoContent,
+			expectedMaxBodySize: 64,
+		},
+		{
+			name:           "unsupported content type",
+			method:         http.MethodPost,
+			target:         "/notes",
+			contentType:    "text/plain",
+			body:           "hi",
+			expectedStatus: http.StatusUnsupportedMediaType,
+		},
+		{
+			name:                "size from binary max length",
+			method:              http.MethodPut,
+			target:              "/avatar",
+			contentType:         "image/png",
+			body:                "12345678",
+			expectedStatus:      http.StatusNoContent,
+			expectedMaxBodySize: 8,
+		},
+		{
+			name:           "too large for binary max length",
+			method:         http.MethodPut,
+			target:         "/avatar",
+			contentType:    "image/png",
+			body:           "123456789",
+			expectedStatus: http.StatusRequestEntityTooLarge,
+		},
+		{
+			name:                "overridden by method and path",
+			opts:                []Option{WithMaxBodySize("POST /notes", 32)},
+			method:              http.MethodPost,
+			target:              "/notes",
+			contentType:         "application/json",
+			body:                `{"text":"hello world"}`,
+			expectedStatus:      http.StatusNoContent,
+			expectedMaxBodySize: 32,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			var maxBodySize int64
+
+			validator, err := Validator(doc, append(tc.opts, WithLogger(slog.New(slog.NewTextHandler(io.Discard, nil))))...)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			handler := validator(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				maxBodySize, _ = httpctx.MaxBodySize(r.Context())
+				w.WriteHeader(http.StatusNoContent)
+			}))
+
+			req := httptest.NewRequest(tc.method, tc.target, strings.NewReader(tc.body))
+			req.Header.Set("Content-Type", tc.contentType)
+
+			// Unknown length so the limit is enforced while reading.
+			req.ContentLength = -1
+
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, req)
+
+			if rec.Code != tc.expectedStatus {
+				t.Fatalf("unexpected status, got: %d, expected: %d, body: %s", rec.Code, tc.expectedStatus, rec.Body.String())
+			}
+
+			if maxBodySize != tc.expectedMaxBodySize {
+				t.Fatalf("unexpected max body size in context, got: %d, expected: %d", maxBodySize, tc.expectedMaxBodySize)
+			}
+		})
+	}
+}
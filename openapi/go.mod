@@ -0,0 +1,27 @@
+module github.com/bombsimon/http-helpers/openapi
+
+// kin-openapi requires go 1.22.5, the other modules only need go 1.22.
+go 1.22.5
+
+require (
+	github.com/bombsimon/http-helpers v0.0.0-20261016131149-d6f19dc9ad2b
+	github.com/getkin/kin-openapi v0.133.0
+)
+
+require (
+	github.com/go-openapi/jsonpointer v0.21.0 // indirect
+	github.com/go-openapi/swag v0.23.0 // indirect
+	github.com/josharian/intern v1.0.0 // indirect
+	github.com/mailru/easyjson v0.7.7 // indirect
+	github.com/mohae/deepcopy v0.0.0-20170929034955-c48cc78d4826 // indirect
+	github.com/oasdiff/yaml v0.0.0-20250309154309-f31be36b4037 // indirect
+	github.com/oasdiff/yaml3 v0.0.0-20250309153720-d2182401db90 // indirect
+	github.com/perimeterx/marshmallow v1.1.5 // indirect
+	github.com/woodsbury/decimal128 v1.3.0 // indirect
+	golang.org/x/text v0.22.0 // indirect
+	gopkg.in/yaml.v3 v3.0.1 // indirect
+)
+
+// Use the parent module from the same checkout during development. Dependents
+// ignore replace directives and get the version required above.
+replace github.com/bombsimon/http-helpers => ../
@@ -0,0 +1,356 @@
+package openapi
+
+/*
+A middleware validating requests, and optionally responses, against an OpenAPI
+3 spec to keep the implementation and the spec in sync. Requests not matching
+the spec are rejected with 400 Bad Request before reaching the handler.
+
+	validator, err := openapi.Load("openapi.yaml", openapi.WithResponseValidation())
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	handler := chain.AddMiddlewares(router, validator)
+
+This is a separate module so kin-openapi is only pulled in when it's used.
+*/
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+
+	"github.com/bombsimon/http-helpers/chain"
+	"github.com/bombsimon/http-helpers/httpctx"
+	"github.com/bombsimon/http-helpers/render"
+	"github.com/getkin/kin-openapi/openapi3"
+	"github.com/getkin/kin-openapi/openapi3filter"
+	"github.com/getkin/kin-openapi/routers"
+	"github.com/getkin/kin-openapi/routers/legacy"
+)
+
+// maxResponseBytes is the largest response body buffered for validation.
+// Larger responses aren't validated.
+const maxResponseBytes = 1 << 20
+
+// ErrorResponse is the body written when a request doesn't match the spec.
+type ErrorResponse struct {
+	Error string `json:"error"`
+}
+
+// Load loads the spec at path and returns the Validator middleware for it.
+func Load(path string, opts ...Option) (chain.Middleware, error) {
+	doc, err := openapi3.NewLoader().LoadFromFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	return Validator(doc, opts...)
+}
+
+// Validator returns a middleware validating the path and query parameters,
+// headers and body of each request against the operation in the spec. Invalid
+// requests are rejected with 400 Bad Request and logged. Bodies with a content
+// type not in the spec are rejected with 415 Unsupported Media Type and bodies
+// larger than the maximum size for the operation with 413 Request Entity Too
+// Large. The size is read from the ExtensionMaxBodySize extension or the
+// maxLength of binary bodies, can be overridden with WithMaxBodySize and is
+// stored with httpctx.WithMaxBodySize so the bind package uses the same
+// limit. Requests to routes not in the spec are passed to the handler unless
+// WithRejectUnknownRoutes is used. Security requirements are not validated.
+// Routes are matched including the servers in the spec, if any.
+func Validator(doc *openapi3.T, opts ...Option) (chain.Middleware, error) {
+	if err := doc.Validate(context.Background()); err != nil {
+		return nil, err
+	}
+
+	router, err := legacy.NewRouter(doc)
+	if err != nil {
+		return nil, err
+	}
+
+	options := newOptions(opts...)
+
+	return func(h http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if options.skip != nil && options.skip(r) {
+				h.ServeHTTP(w, r)
+				return
+			}
+
+			route, pathParams, err := router.FindRoute(r)
+			if err != nil {
+				if !options.rejectUnknown {
+					h.ServeHTTP(w, r)
+					return
+				}
+
+				status := http.StatusNotFound
+				if pathExists(router, r) {
+					status = http.StatusMethodNotAllowed
+				}
+
+				http.Error(w, http.StatusText(status), status)
+
+				return
+			}
+
+			maxBodySize := options.maxBodySize(route)
+			if r.ContentLength > maxBodySize {
+				writeError(w, http.StatusRequestEntityTooLarge, fmt.Sprintf("request body must not be larger than %d bytes", maxBodySize))
+				return
+			}
+
+			if !supportedContentType(route, r) {
+				writeError(w, http.StatusUnsupportedMediaType, fmt.Sprintf("unsupported content type %s", r.Header.Get("Content-Type")))
+				return
+			}
+
+			r.Body = http.MaxBytesReader(w, r.Body, maxBodySize)
+			r = r.WithContext(httpctx.WithMaxBodySize(r.Context(), maxBodySize))
+
+			input := &openapi3filter.RequestValidationInput{
+				Request:    r,
+				PathParams: pathParams,
+				Route:      route,
+				Options: &openapi3filter.Options{
+					AuthenticationFunc: openapi3filter.NoopAuthenticationFunc,
+				},
+			}
+
+			if err := openapi3filter.ValidateRequest(r.Context(), input); err != nil {
+				var maxBytesErr *http.MaxBytesError
+				if errors.As(err, &maxBytesErr) {
+					writeError(w, http.StatusRequestEntityTooLarge, fmt.Sprintf("request body must not be larger than %d bytes", maxBytesErr.Limit))
+					return
+				}
+
+				options.logger.WarnContext(
+					r.Context(), "request doesn't match the OpenAPI spec",
+					"method", r.Method,
+					"path", r.URL.Path,
+					"error", err,
+				)
+
+				writeError(w, http.StatusBadRequest, err.Error())
+
+				return
+			}
+
+			if !options.validateResponses {
+				h.ServeHTTP(w, r)
+				return
+			}
+
+			rec := &recorder{ResponseWriter: w, status: http.StatusOK}
+			h.ServeHTTP(rec.withInterfaces(), r)
+
+			if rec.truncated || rec.hijacked {
+				return
+			}
+
+			responseInput := &openapi3filter.ResponseValidationInput{
+				RequestValidationInput: input,
+				Status:                 rec.status,
+				Header:                 w.Header(),
+				Options: &openapi3filter.Options{
+					IncludeResponseStatus: true,
+				},
+			}
+
+			responseInput.SetBodyBytes(rec.body.Bytes())
+
+			if err := openapi3filter.ValidateResponse(r.Context(), responseInput); err != nil {
+				options.logger.ErrorContext(
+					r.Context(), "response doesn't match the OpenAPI spec",
+					"method", r.Method,
+					"path", r.URL.Path,
+					"status", rec.status,
+					"error", err,
+				)
+			}
+		})
+	}, nil
+}
+
+// supportedContentType returns true if the request has no body or the content
+// type of the body is in the spec.
+func supportedContentType(route *routers.Route, r *http.Request) bool {
+	if r.ContentLength == 0 || r.Body == nil || r.Body == http.NoBody {
+		return true
+	}
+
+	body := route.Operation.RequestBody
+	if body == nil || body.Value == nil || len(body.Value.Content) == 0 {
+		return true
+	}
+
+	return body.Value.Content.Get(r.Header.Get("Content-Type")) != nil
+}
+
+func writeError(w http.ResponseWriter, status int, message string) {
+	_ = render.JSON(w, status, ErrorResponse{Error: message})
+}
+
+// pathExists returns true if the path of the request is in the spec for
+// another method.
+func pathExists(router routers.Router, r *http.Request) bool {
+	for _, method := range []string{
+		http.MethodGet, http.MethodHead, http.MethodPost, http.MethodPut,
+		http.MethodPatch, http.MethodDelete, http.MethodOptions, http.MethodTrace,
+	} {
+		if method == r.Method {
+			continue
+		}
+
+		probe := r.WithContext(r.Context())
+		probe.Method = method
+
+		if _, _, err := router.FindRoute(probe); err == nil {
+			return true
+		}
+	}
+
+	return false
+}
+
+// recorder writes the response to the client while keeping a copy of the
+// status and body for validation.
+type recorder struct {
+	http.ResponseWriter
+	status      int
+	wroteHeader bool
+	body        bytes.Buffer
+	truncated   bool
+	hijacked    bool
+}
+
+func (r *recorder) WriteHeader(status int) {
+	if !r.wroteHeader {
+		r.status = status
+		r.wroteHeader = true
+	}
+
+	r.ResponseWriter.WriteHeader(status)
+}
+
+func (r *recorder) Write(b []byte) (int, error) {
+	r.wroteHeader = true
+
+	if !r.truncated {
+		if r.body.Len()+len(b) > maxResponseBytes {
+			r.truncated = true
+			r.body.Reset()
+		} else {
+			r.body.Write(b)
+		}
+	}
+
+	return r.ResponseWriter.Write(b)
+}
+
+// Unwrap returns the original response writer so http.ResponseController can
+// be used with the recorder.
+func (r *recorder) Unwrap() http.ResponseWriter {
+	return r.ResponseWriter
+}
+
+// Flush flushes the original response writer if it supports it.
+func (r *recorder) Flush() {
+	if f, ok := r.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+// withInterfaces returns the recorder implementing the optional interfaces,
+// http.Hijacker, http.Pusher and io.ReaderFrom, that the original response
+// writer implements so upgrades and server push keep working.
+func (r *recorder) withInterfaces() http.ResponseWriter {
+	_, canHijack := r.ResponseWriter.(http.Hijacker)
+	_, canPush := r.ResponseWriter.(http.Pusher)
+	_, canReadFrom := r.ResponseWriter.(io.ReaderFrom)
+
+	switch {
+	case canHijack && canPush && canReadFrom:
+		return struct {
+			*recorder
+			http.Hijacker
+			http.Pusher
+			io.ReaderFrom
+		}{r, hijacker{r}, pusher{r}, readerFrom{r}}
+	case canHijack && canPush:
+		return struct {
+			*recorder
+			http.Hijacker
+			http.Pusher
+		}{r, hijacker{r}, pusher{r}}
+	case canHijack && canReadFrom:
+		return struct {
+			*recorder
+			http.Hijacker
+			io.ReaderFrom
+		}{r, hijacker{r}, readerFrom{r}}
+	case canPush && canReadFrom:
+		return struct {
+			*recorder
+			http.Pusher
+			io.ReaderFrom
+		}{r, pusher{r}, readerFrom{r}}
+	case canHijack:
+		return struct {
+			*recorder
+			http.Hijacker
+		}{r, hijacker{r}}
+	case canPush:
+		return struct {
+			*recorder
+			http.Pusher
+		}{r, pusher{r}}
+	case canReadFrom:
+		return struct {
+			*recorder
+			io.ReaderFrom
+		}{r, readerFrom{r}}
+	}
+
+	return r
+}
+
+type hijacker struct {
+	rec *recorder
+}
+
+// Hijack hijacks the original connection. The response isn't validated since
+// it's no longer HTTP.
+func (h hijacker) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	h.rec.hijacked = true
+
+	return h.rec.ResponseWriter.(http.Hijacker).Hijack()
+}
+
+type pusher struct {
+	rec *recorder
+}
+
+func (p pusher) Push(target string, opts *http.PushOptions) error {
+	return p.rec.ResponseWriter.(http.Pusher).Push(target, opts)
+}
+
+type readerFrom struct {
+	rec *recorder
+}
+
+// ReadFrom copies through Write so the body is recorded for validation.
+func (f readerFrom) ReadFrom(src io.Reader) (int64, error) {
+	return io.Copy(writerOnly{f.rec}, src)
+}
+
+// writerOnly hides the ReadFrom method so io.Copy uses Write.
+type writerOnly struct {
+	io.Writer
+}
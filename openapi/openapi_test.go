@@ -0,0 +1,214 @@
+package openapi
+
+import (
+	"bytes"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/getkin/kin-openapi/openapi3"
+)
+
+const spec = `
+openapi: 3.0.3
+info:
+  title: Users
+  version: 1.0.0
+paths:
+  /users/{id}:
+    parameters:
+      - name: id
+        in: path
+        required: true
+        schema:
+          type: integer
+    put:
+      requestBody:
+        required: true
+        content:
+          application/json:
+            schema:
+              type: object
+              required: [name]
+              properties:
+                name:
+                  type: string
+      responses:
+        '200':
+          description: OK
+          content:
+            application/json:
+              schema:
+                type: object
+                required: [id]
+                properties:
+                  id:
+                    type: integer
+`
+
+func newValidator(t *testing.T, opts ...Option) http.Handler {
+	t.Helper()
+
+	doc, err := openapi3.NewLoader().LoadFromData([]byte(spec))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	validator, err := Validator(doc, opts...)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return validator(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+
+		if r.URL.Query().Get("broken") != "" {
+			_, _ = w.Write([]byte(`{"id":"one"}`))
+			return
+		}
+
+		_, _ = w.Write([]byte(`{"id":1}`))
+	}))
+}
+
+func Test_Validator(t *testing.T) {
+	tests := []struct {
+		name           string
+		method         string
+		target         string
+		body           string
+		opts           []Option
+		expectedStatus int
+	}{
+		{
+			name:           "valid",
+			method:         http.MethodPut,
+			target:         "/users/1",
+			body:           `{"name":"bob"}`,
+			expectedStatus: http.StatusOK,
+		},
+		{
+			name:           "invalid path parameter",
+			method:         http.MethodPut,
+			target:         "/users/bob",
+			body:           `{"name":"bob"}`,
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name:           "invalid body",
+			method:         http.MethodPut,
+			target:         "/users/1",
+			body:           `{"name":1}`,
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name:           "unknown route",
+			method:         http.MethodGet,
+			target:         "/posts",
+			expectedStatus: http.StatusOK,
+		},
+		{
+			name:           "unknown route rejected",
+			method:         http.MethodGet,
+			target:         "/posts",
+			opts:           []Option{WithRejectUnknownRoutes()},
+			expectedStatus: http.StatusNotFound,
+		},
+		{
+			name:           "unknown method rejected",
+			method:         http.MethodDelete,
+			target:         "/users/1",
+			opts:           []Option{WithRejectUnknownRoutes()},
+			expectedStatus: http.StatusMethodNotAllowed,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			req := httptest.NewRequest(tc.method, tc.target, strings.NewReader(tc.body))
+			req.Header.Set("Content-Type", "application/json")
+
+			newValidator(t, tc.opts...).ServeHTTP(rec, req)
+
+			if rec.Code != tc.expectedStatus {
+				t.Fatalf("unexpected status, got: %d, expected: %d, body: %s", rec.Code, tc.expectedStatus, rec.Body.String())
+			}
+		})
+	}
+}
+
+func Test_ValidatorResponse(t *testing.T) {
+	logs := &bytes.Buffer{}
+	handler := newValidator(t,
+		WithResponseValidation(),
+		WithLogger(slog.New(slog.NewTextHandler(logs, nil))),
+	)
+
+	for target, expectViolation := range map[string]bool{
+		"/users/1":          false,
+		"/users/1?broken=1": true,
+	} {
+		logs.Reset()
+
+		rec := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodPut, target, strings.NewReader(`{"name":"bob"}`))
+		req.Header.Set("Content-Type", "application/json")
+
+		handler.ServeHTTP(rec, req)
+
+		if rec.Code != http.StatusOK {
+			t.Fatalf("unexpected status, got: %d, expected: %d", rec.Code, http.StatusOK)
+		}
+
+		if violation := strings.Contains(logs.String(), "response doesn't match the OpenAPI spec"); violation != expectViolation {
+			t.Fatalf("unexpected violation for %s, got: %t, expected: %t, logs: %s", target, violation, expectViolation, logs.String())
+		}
+	}
+}
+
+func Test_ValidatorResponseInterfaces(t *testing.T) {
+	doc, err := openapi3.NewLoader().LoadFromData([]byte(spec))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	validator, err := Validator(doc, WithResponseValidation())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	handler := validator(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if _, ok := w.(http.Hijacker); !ok {
+			t.Error("expected response writer to implement http.Hijacker")
+		}
+
+		if _, ok := w.(io.ReaderFrom); !ok {
+			t.Error("expected response writer to implement io.ReaderFrom")
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = io.Copy(w, strings.NewReader(`{"id":1}`))
+	}))
+
+	server := httptest.NewServer(handler)
+	defer server.Close()
+
+	req, _ := http.NewRequest(http.MethodPut, server.URL+"/users/1", strings.NewReader(`{"name":"bob"}`))
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer resp.Body.Close()
+
+	body, _ := io.ReadAll(resp.Body)
+	if string(body) != `{"id":1}` {
+		t.Fatalf("unexpected body, got: %s, expected: %s", body, `{"id":1}`)
+	}
+}
@@ -0,0 +1,85 @@
+package openapi
+
+import (
+	"log/slog"
+	"net/http"
+
+	"github.com/bombsimon/http-helpers/bind"
+)
+
+// Option is an option used to configure the validator.
+type Option func(*options)
+
+type options struct {
+	logger            *slog.Logger
+	validateResponses bool
+	rejectUnknown     bool
+	skip              func(*http.Request) bool
+
+	defaultMaxBodySize int64
+	maxBodySizes       map[string]int64
+}
+
+func newOptions(opts ...Option) *options {
+	o := &options{
+		logger:             slog.Default(),
+		defaultMaxBodySize: bind.DefaultMaxBytes,
+		maxBodySizes:       map[string]int64{},
+	}
+
+	for _, opt := range opts {
+		opt(o)
+	}
+
+	return o
+}
+
+// WithLogger sets the logger used to log contract violations. Defaults to
+// slog.Default().
+func WithLogger(logger *slog.Logger) Option {
+	return func(o *options) {
+		o.logger = logger
+	}
+}
+
+// WithResponseValidation validates responses against the spec. Responses not
+// matching the spec are logged but still written to the client.
+func WithResponseValidation() Option {
+	return func(o *options) {
+		o.validateResponses = true
+	}
+}
+
+// WithRejectUnknownRoutes rejects requests to paths not in the spec with 404
+// Not Found and methods not in the spec with 405 Method Not Allowed. By default
+// they're passed to the handler without validation.
+func WithRejectUnknownRoutes() Option {
+	return func(o *options) {
+		o.rejectUnknown = true
+	}
+}
+
+// WithSkipFunc sets a function that will be called for each request. If the
+// function returns true the request isn't validated.
+func WithSkipFunc(fn func(*http.Request) bool) Option {
+	return func(o *options) {
+		o.skip = fn
+	}
+}
+
+// WithDefaultMaxBodySize sets the maximum request body size for operations
+// without a size in the spec. Defaults to bind.DefaultMaxBytes.
+func WithDefaultMaxBodySize(n int64) Option {
+	return func(o *options) {
+		o.defaultMaxBodySize = n
+	}
+}
+
+// WithMaxBodySize overrides the maximum request body size for an operation,
+// identified by its operation ID or method and path as in the spec, e.g.
+// "POST /users/{id}/avatar".
+func WithMaxBodySize(operation string, n int64) Option {
+	return func(o *options) {
+		o.maxBodySizes[operation] = n
+	}
+}
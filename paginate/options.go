@@ -0,0 +1,58 @@
+package paginate
+
+// Option is an option used to configure how pagination is parsed.
+type Option func(*options)
+
+type options struct {
+	defaultLimit int
+	maxLimit     int
+	sortFields   []string
+	defaultSort  string
+	filters      []string
+}
+
+func newOptions(opts ...Option) *options {
+	o := &options{
+		defaultLimit: 20,
+		maxLimit:     100,
+	}
+
+	for _, opt := range opts {
+		opt(o)
+	}
+
+	return o
+}
+
+// WithDefaultLimit sets the limit used when the request doesn't set one.
+// Defaults to 20.
+func WithDefaultLimit(limit int) Option {
+	return func(o *options) {
+		o.defaultLimit = limit
+	}
+}
+
+// WithMaxLimit sets the largest limit allowed. Defaults to 100.
+func WithMaxLimit(limit int) Option {
+	return func(o *options) {
+		o.maxLimit = limit
+	}
+}
+
+// WithSort sets the fields the result can be sorted by and the sort used when
+// the request doesn't set one, e.g. "-created_at". Sorting isn't allowed by
+// default.
+func WithSort(defaultSort string, fields ...string) Option {
+	return func(o *options) {
+		o.defaultSort = defaultSort
+		o.sortFields = fields
+	}
+}
+
+// WithFilters sets the query parameters the result can be filtered by. Other
+// query parameters are ignored.
+func WithFilters(names ...string) Option {
+	return func(o *options) {
+		o.filters = names
+	}
+}
@@ -0,0 +1,171 @@
+package paginate
+
+/*
+Helpers to parse pagination, sorting and filtering from the query string and to
+write paginated responses. Both limit and offset and cursor based pagination is
+supported.
+
+	// GET /users?limit=10&offset=20&sort=-created_at,name&role=admin
+	page, err := paginate.Parse(r,
+		paginate.WithSort("-created_at", "created_at", "name"),
+		paginate.WithFilters("role"),
+	)
+	if err != nil {
+		// err is a *bind.Error with status 400.
+	}
+
+	users, total := store.ListUsers(ctx, page.Limit, page.Offset, page.Sort, page.Filters)
+
+	paginate.SetLinks(w, r, page, total)
+	render.JSON(w, http.StatusOK, paginate.NewEnvelope(users, page, total))
+*/
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/bombsimon/http-helpers/bind"
+)
+
+// Query parameters used for pagination and sorting.
+const (
+	ParamLimit  = "limit"
+	ParamOffset = "offset"
+	ParamCursor = "cursor"
+	ParamSort   = "sort"
+)
+
+// Page is the pagination, sorting and filtering parsed from a request.
+type Page struct {
+	// Limit is the maximum number of items to return.
+	Limit int
+
+	// Offset is the number of items to skip. Always zero when Cursor is set.
+	Offset int
+
+	// Cursor is the opaque cursor to continue from, if any.
+	Cursor string
+
+	// Sort is the fields to sort by, in order.
+	Sort []SortField
+
+	// Filters is the allowed filters set in the request.
+	Filters url.Values
+}
+
+// SortField is a field to sort by.
+type SortField struct {
+	Field string
+	Desc  bool
+}
+
+// String returns the field prefixed with "-" if it's sorted in descending
+// order, the same format as in the query string.
+func (s SortField) String() string {
+	if s.Desc {
+		return "-" + s.Field
+	}
+
+	return s.Field
+}
+
+// Parse parses the limit, offset, cursor and sort query parameters and the
+// filters allowed with WithFilters. The limit must be between 1 and the max
+// limit, the offset can't be negative and offset and cursor can't be used
+// together. Sort is a comma separated list of fields allowed with WithSort,
+// prefixed with "-" to sort in descending order. Errors from the request are
+// *bind.Error with status 400 Bad Request. A default or max limit below 1 is
+// rejected with an error that isn't a *bind.Error.
+func Parse(r *http.Request, opts ...Option) (*Page, error) {
+	options := newOptions(opts...)
+	if options.defaultLimit < 1 || options.maxLimit < 1 {
+		return nil, fmt.Errorf(
+			"paginate: default limit %d and max limit %d must be at least 1",
+			options.defaultLimit, options.maxLimit,
+		)
+	}
+
+	query := r.URL.Query()
+
+	page := &Page{
+		Limit:   options.defaultLimit,
+		Cursor:  query.Get(ParamCursor),
+		Filters: url.Values{},
+	}
+
+	if v := query.Get(ParamLimit); v != "" {
+		limit, err := strconv.Atoi(v)
+		if err != nil || limit < 1 || limit > options.maxLimit {
+			return nil, badRequest("%s must be a number between 1 and %d", ParamLimit, options.maxLimit)
+		}
+
+		page.Limit = limit
+	}
+
+	if v := query.Get(ParamOffset); v != "" {
+		if page.Cursor != "" {
+			return nil, badRequest("%s and %s can't be used together", ParamOffset, ParamCursor)
+		}
+
+		offset, err := strconv.Atoi(v)
+		if err != nil || offset < 0 {
+			return nil, badRequest("%s must be a positive number", ParamOffset)
+		}
+
+		page.Offset = offset
+	}
+
+	sort := options.defaultSort
+	if v := query.Get(ParamSort); v != "" {
+		sort = v
+	}
+
+	if sort != "" {
+		fields, err := parseSort(sort, options.sortFields)
+		if err != nil {
+			return nil, err
+		}
+
+		page.Sort = fields
+	}
+
+	for _, name := range options.filters {
+		if values, ok := query[name]; ok {
+			page.Filters[name] = values
+		}
+	}
+
+	return page, nil
+}
+
+func parseSort(sort string, allowed []string) ([]SortField, error) {
+	var fields []SortField
+
+	for _, part := range strings.Split(sort, ",") {
+		field := SortField{Field: strings.TrimSpace(part)}
+		field.Field, field.Desc = strings.CutPrefix(field.Field, "-")
+
+		if !slices.Contains(allowed, field.Field) {
+			if len(allowed) == 0 {
+				return nil, badRequest("sorting is not supported")
+			}
+
+			return nil, badRequest("can't sort by %q, must be one of %s", field.Field, strings.Join(allowed, ", "))
+		}
+
+		fields = append(fields, field)
+	}
+
+	return fields, nil
+}
+
+func badRequest(format string, args ...interface{}) error {
+	return &bind.Error{
+		Status:  http.StatusBadRequest,
+		Message: fmt.Sprintf(format, args...),
+	}
+}
@@ -0,0 +1,222 @@
+package paginate
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"reflect"
+	"testing"
+
+	"github.com/bombsimon/http-helpers/bind"
+)
+
+func Test_Parse(t *testing.T) {
+	opts := []Option{
+		WithMaxLimit(50),
+		WithSort("-created_at", "created_at", "name"),
+		WithFilters("role"),
+	}
+
+	tests := []struct {
+		name          string
+		query         string
+		expected      *Page
+		expectedError string
+	}{
+		{
+			name:  "defaults",
+			query: "",
+			expected: &Page{
+				Limit:   20,
+				Sort:    []SortField{{Field: "created_at", Desc: true}},
+				Filters: url.Values{},
+			},
+		},
+		{
+			name:  "all parameters",
+			query: "limit=10&offset=30&sort=name,-created_at&role=admin&role=owner&other=1",
+			expected: &Page{
+				Limit:  10,
+				Offset: 30,
+				Sort: []SortField{
+					{Field: "name"},
+					{Field: "created_at", Desc: true},
+				},
+				Filters: url.Values{"role": {"admin", "owner"}},
+			},
+		},
+		{
+			name:  "cursor",
+			query: "cursor=abc",
+			expected: &Page{
+				Limit:   20,
+				Cursor:  "abc",
+				Sort:    []SortField{{Field: "created_at", Desc: true}},
+				Filters: url.Values{},
+			},
+		},
+		{
+			name:          "limit too large",
+			query:         "limit=51",
+			expectedError: "limit must be a number between 1 and 50",
+		},
+		{
+			name:          "invalid limit",
+			query:         "limit=ten",
+			expectedError: "limit must be a number between 1 and 50",
+		},
+		{
+			name:          "negative offset",
+			query:         "offset=-1",
+			expectedError: "offset must be a positive number",
+		},
+		{
+			name:          "offset and cursor",
+			query:         "offset=1&cursor=abc",
+			expectedError: "offset and cursor can't be used together",
+		},
+		{
+			name:          "unknown sort field",
+			query:         "sort=password",
+			expectedError: `can't sort by "password", must be one of created_at, name`,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			page, err := Parse(httptest.NewRequest(http.MethodGet, "/users?"+tc.query, nil), opts...)
+			if tc.expectedError != "" {
+				var bindErr *bind.Error
+				if !errors.As(err, &bindErr) || bindErr.Status != http.StatusBadRequest {
+					t.Fatalf("expected bind error with status 400, got: %v", err)
+				}
+
+				if err.Error() != tc.expectedError {
+					t.Fatalf("unexpected error, got: %s, expected: %s", err, tc.expectedError)
+				}
+
+				return
+			}
+
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if !reflect.DeepEqual(page, tc.expected) {
+				t.Fatalf("unexpected page, got: %+v, expected: %+v", page, tc.expected)
+			}
+		})
+	}
+}
+
+func Test_ParseSortNotAllowed(t *testing.T) {
+	if _, err := Parse(httptest.NewRequest(http.MethodGet, "/?sort=name", nil)); err == nil || err.Error() != "sorting is not supported" {
+		t.Fatalf("unexpected error: %v", err)
+	}
+}
+
+func Test_ParseInvalidLimits(t *testing.T) {
+	for _, opt := range []Option{WithDefaultLimit(0), WithMaxLimit(-1)} {
+		_, err := Parse(httptest.NewRequest(http.MethodGet, "/", nil), opt)
+
+		var bindErr *bind.Error
+		if err == nil || errors.As(err, &bindErr) {
+			t.Fatalf("expected configuration error, got: %v", err)
+		}
+	}
+}
+
+func Test_SetLinks(t *testing.T) {
+	tests := []struct {
+		target   string
+		total    int
+		expected string
+	}{
+		{
+			target: "/users?limit=10&role=admin",
+			total:  25,
+			expected: `</users?limit=10&offset=0&role=admin>; rel="first", ` +
+				`</users?limit=10&offset=10&role=admin>; rel="next", ` +
+				`</users?limit=10&offset=20&role=admin>; rel="last"`,
+		},
+		{
+			target: "/users?limit=10&offset=15",
+			total:  25,
+			expected: `</users?limit=10&offset=0>; rel="first", ` +
+				`</users?limit=10&offset=5>; rel="prev", ` +
+				`</users?limit=10&offset=20>; rel="last"`,
+		},
+		{
+			target:   "/users",
+			total:    0,
+			expected: `</users?limit=20&offset=0>; rel="first"`,
+		},
+	}
+
+	for _, tc := range tests {
+		r := httptest.NewRequest(http.MethodGet, tc.target, nil)
+
+		page, err := Parse(r, WithFilters("role"))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		rec := httptest.NewRecorder()
+		SetLinks(rec, r, page, tc.total)
+
+		if got := rec.Header().Get("Link"); got != tc.expected {
+			t.Fatalf("unexpected links for %s\ngot:      %s\nexpected: %s", tc.target, got, tc.expected)
+		}
+	}
+
+	// A page without a limit has no last page.
+	rec := httptest.NewRecorder()
+	SetLinks(rec, httptest.NewRequest(http.MethodGet, "/users", nil), &Page{}, 25)
+
+	if got := rec.Header().Get("Link"); got != `</users?limit=0&offset=0>; rel="first"` {
+		t.Fatalf("unexpected links for page without limit, got: %s", got)
+	}
+}
+
+func Test_SetCursorLinks(t *testing.T) {
+	r := httptest.NewRequest(http.MethodGet, "/events?cursor=abc&limit=5", nil)
+
+	page, err := Parse(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	rec := httptest.NewRecorder()
+	SetCursorLinks(rec, r, page, "def")
+
+	expected := `</events?cursor=def&limit=5>; rel="next"`
+	if got := rec.Header().Get("Link"); got != expected {
+		t.Fatalf("unexpected link, got: %s, expected: %s", got, expected)
+	}
+}
+
+func Test_Envelope(t *testing.T) {
+	page := &Page{Limit: 2, Offset: 2}
+
+	body, err := json.Marshal(NewEnvelope([]string(nil), page, 4))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := `{"data":[],"pagination":{"limit":2,"offset":2,"total":4}}`
+	if string(body) != expected {
+		t.Fatalf("unexpected body, got: %s, expected: %s", body, expected)
+	}
+
+	body, err = json.Marshal(NewCursorEnvelope([]int{1, 2}, page, "next"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected = `{"data":[1,2],"pagination":{"limit":2,"next_cursor":"next"}}`
+	if string(body) != expected {
+		t.Fatalf("unexpected body, got: %s, expected: %s", body, expected)
+	}
+}
@@ -0,0 +1,121 @@
+package paginate
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// Envelope is a paginated response body.
+type Envelope[T any] struct {
+	Data       []T  `json:"data"`
+	Pagination Meta `json:"pagination"`
+}
+
+// Meta describes the page in an Envelope.
+type Meta struct {
+	Limit      int    `json:"limit"`
+	Offset     int    `json:"offset,omitempty"`
+	Total      *int   `json:"total,omitempty"`
+	NextCursor string `json:"next_cursor,omitempty"`
+}
+
+// NewEnvelope returns an envelope for limit and offset pagination with the
+// total number of items.
+func NewEnvelope[T any](data []T, page *Page, total int) Envelope[T] {
+	return Envelope[T]{
+		Data: nonNil(data),
+		Pagination: Meta{
+			Limit:  page.Limit,
+			Offset: page.Offset,
+			Total:  &total,
+		},
+	}
+}
+
+// NewCursorEnvelope returns an envelope for cursor pagination. The next cursor
+// should be empty on the last page.
+func NewCursorEnvelope[T any](data []T, page *Page, nextCursor string) Envelope[T] {
+	return Envelope[T]{
+		Data: nonNil(data),
+		Pagination: Meta{
+			Limit:      page.Limit,
+			NextCursor: nextCursor,
+		},
+	}
+}
+
+// SetLinks sets the Link header with the first, prev, next and last pages for
+// limit and offset pagination. Links not applicable to the page, e.g. prev on
+// the first page, are left out. The links keep all other query parameters from
+// the request.
+func SetLinks(w http.ResponseWriter, r *http.Request, page *Page, total int) {
+	links := map[string]int{"first": 0}
+
+	if page.Offset > 0 {
+		links["prev"] = max(page.Offset-page.Limit, 0)
+	}
+
+	// Pages not from Parse may have no limit to step and divide the total by.
+	if page.Limit > 0 && page.Offset+page.Limit < total {
+		links["next"] = page.Offset + page.Limit
+	}
+
+	if page.Limit > 0 && total > 0 {
+		links["last"] = (total - 1) / page.Limit * page.Limit
+	}
+
+	var header []string
+
+	for _, rel := range []string{"first", "prev", "next", "last"} {
+		offset, ok := links[rel]
+		if !ok {
+			continue
+		}
+
+		header = append(header, link(r, rel, map[string]string{
+			ParamOffset: strconv.Itoa(offset),
+			ParamLimit:  strconv.Itoa(page.Limit),
+		}))
+	}
+
+	w.Header().Set("Link", strings.Join(header, ", "))
+}
+
+// SetCursorLinks sets the Link header with the next page for cursor
+// pagination. Nothing is set if the next cursor is empty.
+func SetCursorLinks(w http.ResponseWriter, r *http.Request, page *Page, nextCursor string) {
+	if nextCursor == "" {
+		return
+	}
+
+	w.Header().Set("Link", link(r, "next", map[string]string{
+		ParamCursor: nextCursor,
+		ParamLimit:  strconv.Itoa(page.Limit),
+	}))
+}
+
+// link returns a link to the request URL with the parameters replaced.
+func link(r *http.Request, rel string, params map[string]string) string {
+	u := *r.URL
+	query := u.Query()
+
+	query.Del(ParamOffset)
+	query.Del(ParamCursor)
+
+	for k, v := range params {
+		query.Set(k, v)
+	}
+
+	u.RawQuery = query.Encode()
+
+	return "<" + u.String() + `>; rel="` + rel + `"`
+}
+
+func nonNil[T any](data []T) []T {
+	if data == nil {
+		return []T{}
+	}
+
+	return data
+}
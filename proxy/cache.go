@@ -0,0 +1,185 @@
+package proxy
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/bombsimon/http-helpers/cache"
+	"github.com/bombsimon/http-helpers/clock"
+)
+
+// maxCachedBodySize is the largest response body the caching mode stores,
+// larger responses are streamed to the client without being cached.
+const maxCachedBodySize = 10 << 20
+
+// WithCache enables the caching mode, storing GET responses from the upstream
+// in the store as allowed by their Cache-Control, Expires, ETag and
+// Last-Modified headers, see cache.NewEntry. Fresh responses are served from
+// the cache and stale responses are revalidated with a conditional request
+// (If-None-Match and If-Modified-Since), serving the cached response if the
+// upstream responds with 304 Not Modified. The stale-while-revalidate and
+// stale-if-error directives are obeyed, and requests with an Authorization
+// header or Cache-Control: no-store aren't cached. The X-Cache response header
+// is set to HIT, STALE, REVALIDATED or MISS.
+func WithCache(store cache.Store) Option {
+	return func(o *options) {
+		o.cacheStore = store
+	}
+}
+
+// WithClock sets the clock used by the caching mode to tell if responses are
+// fresh. Defaults to clock.Real().
+func WithClock(c clock.Clock) Option {
+	return func(o *options) {
+		o.clock = c
+	}
+}
+
+// cachingTransport serves requests from the store, sending the requests that
+// aren't fresh to the upstream with the base transport.
+type cachingTransport struct {
+	base  http.RoundTripper
+	store cache.Store
+	clock clock.Clock
+
+	// refreshing holds the keys revalidated in the background.
+	refreshing sync.Map
+}
+
+func (t *cachingTransport) RoundTrip(r *http.Request) (*http.Response, error) {
+	if r.Method != http.MethodGet ||
+		r.Header.Get("Authorization") != "" ||
+		strings.Contains(strings.ToLower(r.Header.Get("Cache-Control")), "no-store") {
+		return t.base.RoundTrip(r)
+	}
+
+	key := strings.TrimSpace(r.Host + " " + r.URL.String())
+	entry, ok := t.store.Get(r.Context(), key)
+	now := t.clock.Now()
+
+	switch {
+	case !ok:
+		return t.fetch(r, key, nil)
+	case entry.Fresh(now):
+		return entry.Response(r, now, "HIT"), nil
+	case entry.ServableWhileRevalidating(now):
+		if _, loaded := t.refreshing.LoadOrStore(key, struct{}{}); !loaded {
+			req := r.Clone(context.WithoutCancel(r.Context()))
+
+			go func() {
+				defer t.refreshing.Delete(key)
+
+				if resp, err := t.fetch(req, key, entry); err == nil {
+					_, _ = io.Copy(io.Discard, resp.Body)
+					_ = resp.Body.Close()
+				}
+			}()
+		}
+
+		return entry.Response(r, now, "STALE"), nil
+	}
+
+	return t.fetch(r, key, entry)
+}
+
+// fetch sends the request to the upstream, as a conditional request if there's
+// a stale entry to revalidate, and stores the response if it's cacheable. The
+// stale entry is returned if the upstream fails and it may be served on
+// errors.
+func (t *cachingTransport) fetch(r *http.Request, key string, stale *cache.Entry) (*http.Response, error) {
+	out := r
+
+	if stale != nil && stale.Revalidatable() {
+		out = r.Clone(r.Context())
+		out.Header.Del("If-None-Match")
+		out.Header.Del("If-Modified-Since")
+
+		if etag := stale.Header.Get("ETag"); etag != "" {
+			out.Header.Set("If-None-Match", etag)
+		}
+
+		if lastModified := stale.Header.Get("Last-Modified"); lastModified != "" {
+			out.Header.Set("If-Modified-Since", lastModified)
+		}
+	}
+
+	resp, err := t.base.RoundTrip(out)
+	now := t.clock.Now()
+
+	switch {
+	case err != nil:
+		if stale != nil && stale.ServableOnError(now) {
+			return stale.Response(r, now, "STALE"), nil
+		}
+
+		return nil, err
+	case resp.StatusCode >= 500 && stale != nil && stale.ServableOnError(now):
+		_ = resp.Body.Close()
+		return stale.Response(r, now, "STALE"), nil
+	case resp.StatusCode == http.StatusNotModified && out != r:
+		_ = resp.Body.Close()
+		return t.revalidated(r.Context(), key, stale, resp.Header, now).Response(r, now, "REVALIDATED"), nil
+	}
+
+	return t.storeResponse(r.Context(), key, resp, now)
+}
+
+// revalidated stores the stale entry with the headers from the 304 Not
+// Modified response, which may update its freshness.
+func (t *cachingTransport) revalidated(ctx context.Context, key string, stale *cache.Entry, updated http.Header, now time.Time) *cache.Entry {
+	header := stale.Header.Clone()
+	for k, v := range updated {
+		header[k] = v
+	}
+
+	entry, ok := cache.NewEntry(stale.Status, header, stale.Body, now, cache.Policy{})
+	if !ok {
+		return &cache.Entry{Status: stale.Status, Header: header, Body: stale.Body, Stored: now}
+	}
+
+	t.store.Set(context.WithoutCancel(ctx), key, entry)
+
+	return entry
+}
+
+// storeResponse buffers and stores the response if it's cacheable and not too
+// large. Other responses are returned as is.
+func (t *cachingTransport) storeResponse(ctx context.Context, key string, resp *http.Response, now time.Time) (*http.Response, error) {
+	entry, ok := cache.NewEntry(resp.StatusCode, resp.Header.Clone(), nil, now, cache.Policy{})
+	if !ok {
+		return resp, nil
+	}
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, maxCachedBodySize+1))
+	if err != nil {
+		_ = resp.Body.Close()
+		return nil, err
+	}
+
+	if len(body) > maxCachedBodySize {
+		resp.Body = &multiReadCloser{Reader: io.MultiReader(bytes.NewReader(body), resp.Body), Closer: resp.Body}
+		return resp, nil
+	}
+
+	_ = resp.Body.Close()
+
+	entry.Body = body
+	t.store.Set(context.WithoutCancel(ctx), key, entry)
+
+	resp.Body = io.NopCloser(bytes.NewReader(body))
+	resp.ContentLength = int64(len(body))
+	resp.Header.Set("X-Cache", "MISS")
+
+	return resp, nil
+}
+
+// multiReadCloser reads the buffered start of a body and then the rest of it.
+type multiReadCloser struct {
+	io.Reader
+	io.Closer
+}
@@ -0,0 +1,92 @@
+package proxy
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/bombsimon/http-helpers/cache"
+	"github.com/bombsimon/http-helpers/clock"
+)
+
+func Test_ProxyCache(t *testing.T) {
+	var (
+		clk         = clock.NewFake(time.Now())
+		requests    atomic.Int32
+		conditional atomic.Int32
+		failing     atomic.Bool
+	)
+
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+
+		if failing.Load() {
+			http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+			return
+		}
+
+		switch r.URL.Path {
+		case "/private":
+			w.Header().Set("Cache-Control", "no-store")
+		default:
+			w.Header().Set("Cache-Control", "max-age=60, stale-if-error=600")
+			w.Header().Set("ETag", `"v1"`)
+
+			if r.Header.Get("If-None-Match") == `"v1"` {
+				conditional.Add(1)
+				w.WriteHeader(http.StatusNotModified)
+
+				return
+			}
+		}
+
+		_, _ = io.WriteString(w, "body "+r.URL.Path)
+	}))
+	defer upstream.Close()
+
+	handler := New(mustParse(t, upstream.URL), WithCache(cache.NewMemoryStore(10)), WithClock(clk))
+
+	for i, step := range []struct {
+		advance             time.Duration
+		path                string
+		fail                bool
+		expectedCache       string
+		expectedRequests    int32
+		expectedConditional int32
+	}{
+		{path: "/users", expectedCache: "MISS", expectedRequests: 1},
+		{path: "/users", expectedCache: "HIT", expectedRequests: 1},
+		{advance: 61 * time.Second, path: "/users", expectedCache: "REVALIDATED", expectedRequests: 2, expectedConditional: 1},
+		{path: "/users", expectedCache: "HIT", expectedRequests: 2, expectedConditional: 1},
+		{advance: 61 * time.Second, path: "/users", fail: true, expectedCache: "STALE", expectedRequests: 3, expectedConditional: 1},
+		{path: "/private", expectedCache: "", expectedRequests: 4, expectedConditional: 1},
+		{path: "/private", expectedCache: "", expectedRequests: 5, expectedConditional: 1},
+	} {
+		clk.Advance(step.advance)
+		failing.Store(step.fail)
+
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, step.path, nil))
+
+		expectedBody := fmt.Sprintf("body %s", step.path)
+
+		if rec.Code != http.StatusOK || rec.Body.String() != expectedBody {
+			t.Fatalf("unexpected response for step %d, got: %d %s", i, rec.Code, rec.Body.String())
+		}
+
+		if rec.Header().Get("X-Cache") != step.expectedCache {
+			t.Fatalf("unexpected X-Cache for step %d, got: %q, expected: %q", i, rec.Header().Get("X-Cache"), step.expectedCache)
+		}
+
+		if requests.Load() != step.expectedRequests || conditional.Load() != step.expectedConditional {
+			t.Fatalf(
+				"unexpected upstream requests for step %d, got: %d (%d conditional), expected: %d (%d conditional)",
+				i, requests.Load(), conditional.Load(), step.expectedRequests, step.expectedConditional,
+			)
+		}
+	}
+}
@@ -0,0 +1,117 @@
+package proxy
+
+import (
+	"net"
+	"net/http/httputil"
+	"strings"
+)
+
+// WithTrustedHops trusts the n proxies in front of this proxy, keeping the
+// last n addresses of the incoming X-Forwarded-For header, and the last n
+// elements of the Forwarded header with WithForwardedHeader, before the client
+// address is appended. The first kept address is then the client seen by the
+// outermost trusted proxy, so chained proxies attribute requests to the right
+// client without trusting addresses the client set itself.
+func WithTrustedHops(n int) Option {
+	return func(o *options) {
+		o.trustedHops = n
+	}
+}
+
+// WithForwardedHeader sets the RFC 7239 Forwarded header, with the client
+// address, the requested host and the protocol, in addition to the
+// X-Forwarded headers. The incoming Forwarded header is trusted like the
+// X-Forwarded-For header, see WithTrustedHops and WithTrustForwardedHeaders.
+func WithForwardedHeader() Option {
+	return func(o *options) {
+		o.forwardedHeader = true
+	}
+}
+
+// setForwarded sets the X-Forwarded and, if enabled, Forwarded headers on the
+// outgoing request, keeping the trusted part of the incoming headers.
+func setForwarded(pr *httputil.ProxyRequest, options *options) {
+	if xff := trustedElements(pr.In.Header.Values("X-Forwarded-For"), options); len(xff) > 0 {
+		pr.Out.Header.Set("X-Forwarded-For", strings.Join(xff, ", "))
+	}
+
+	pr.SetXForwarded()
+
+	if !options.forwardedHeader {
+		return
+	}
+
+	proto := "http"
+	if pr.In.TLS != nil {
+		proto = "https"
+	}
+
+	element := "for=" + forwardedNode(pr.In.RemoteAddr) +
+		";host=" + quoteForwarded(pr.In.Host) +
+		";proto=" + proto
+
+	pr.Out.Header.Set("Forwarded", strings.Join(
+		append(trustedElements(pr.In.Header.Values("Forwarded"), options), element),
+		", ",
+	))
+}
+
+// trustedElements returns the comma separated elements of the header values
+// added by trusted proxies: all elements with WithTrustForwardedHeaders, the
+// last trusted hops with WithTrustedHops and none otherwise.
+func trustedElements(values []string, options *options) []string {
+	if !options.trustForwarded && options.trustedHops <= 0 {
+		return nil
+	}
+
+	var elements []string
+
+	for _, value := range values {
+		for _, element := range strings.Split(value, ",") {
+			if element = strings.TrimSpace(element); element != "" {
+				elements = append(elements, element)
+			}
+		}
+	}
+
+	if !options.trustForwarded && len(elements) > options.trustedHops {
+		elements = elements[len(elements)-options.trustedHops:]
+	}
+
+	return elements
+}
+
+// forwardedNode returns the client address as a Forwarded node, quoting IPv6
+// addresses in brackets as required by RFC 7239.
+func forwardedNode(remoteAddr string) string {
+	host, _, err := net.SplitHostPort(remoteAddr)
+	if err != nil {
+		host = remoteAddr
+	}
+
+	if strings.Contains(host, ":") {
+		return `"[` + host + `]"`
+	}
+
+	return quoteForwarded(host)
+}
+
+// quoteForwarded quotes the value unless it's a token.
+func quoteForwarded(value string) string {
+	if value != "" && strings.IndexFunc(value, func(r rune) bool {
+		return !isTokenChar(r)
+	}) < 0 {
+		return value
+	}
+
+	return `"` + strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace(value) + `"`
+}
+
+func isTokenChar(r rune) bool {
+	switch {
+	case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9':
+		return true
+	}
+
+	return strings.ContainsRune("!#$%&'*+-.^_`|~", r)
+}
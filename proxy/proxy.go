@@ -0,0 +1,264 @@
+package proxy
+
+/*
+A reverse proxy based on httputil.ReverseProxy with defaults suitable for
+proxying to internal upstreams. Upstream errors are written as 502 Bad Gateway,
+or 504 Gateway Timeout for timeouts, and stored on the response writer with
+WriteError so the Logger and Prometheus middlewares report proxy failures like
+any other failing request.
+
+	upstream, _ := url.Parse("http://users.internal:8080")
+	router.Handle("/users/", proxy.New(upstream,
+		proxy.WithTimeout(5*time.Second),
+		proxy.WithRetries(2),
+	))
+*/
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httputil"
+	"net/url"
+	"time"
+
+	"github.com/bombsimon/http-helpers/cache"
+	"github.com/bombsimon/http-helpers/clock"
+)
+
+// Error is the error stored on the response writer when the upstream request
+// fails.
+type Error struct {
+	// Upstream is the host of the upstream.
+	Upstream string
+
+	// Status is the status written to the client, 502 or 504.
+	Status int
+
+	Err error
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("proxy to %s failed: %s", e.Upstream, e.Err)
+}
+
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+// errorWriter is implemented by response writers that can store an error, such
+// as middleware.ResponseWriterWithInfo.
+type errorWriter interface {
+	WriteError(err error)
+}
+
+// Option is an option used to configure the proxy.
+type Option func(*options)
+
+type options struct {
+	transport       http.RoundTripper
+	timeout         time.Duration
+	retries         int
+	preserveHost    bool
+	trustForwarded  bool
+	trustedHops     int
+	forwardedHeader bool
+	modifyResponse  func(*http.Response) error
+	flushInterval   time.Duration
+	cacheStore      cache.Store
+	clock           clock.Clock
+}
+
+// WithTransport sets the transport used to send requests to the upstream.
+// Defaults to http.DefaultTransport.
+func WithTransport(transport http.RoundTripper) Option {
+	return func(o *options) {
+		o.transport = transport
+	}
+}
+
+// WithTimeout sets the timeout for each request to the upstream, including
+// reading the response body. Requests timing out get 504 Gateway Timeout.
+// Defaults to no timeout other than the request context.
+func WithTimeout(timeout time.Duration) Option {
+	return func(o *options) {
+		o.timeout = timeout
+	}
+}
+
+// WithRetries retries requests with idempotent methods (GET, HEAD, OPTIONS,
+// TRACE, PUT and DELETE) up to n times if the upstream can't be reached or
+// times out. Requests with a body are only retried if the body can be
+// recreated with Request.GetBody. Responses from the upstream, including 5xx
+// responses, are never retried.
+func WithRetries(n int) Option {
+	return func(o *options) {
+		o.retries = n
+	}
+}
+
+// WithPreserveHost sends the Host header of the incoming request to the
+// upstream instead of the host of the upstream URL.
+func WithPreserveHost() Option {
+	return func(o *options) {
+		o.preserveHost = true
+	}
+}
+
+// WithTrustForwardedHeaders appends the client address to the
+// X-Forwarded-For header of the incoming request instead of replacing it. Only
+// use this when the proxy is behind a trusted proxy, e.g. with the RealIP
+// middleware, since clients can set the header to anything. Use
+// WithTrustedHops to only trust the addresses added by the proxies in front.
+func WithTrustForwardedHeaders() Option {
+	return func(o *options) {
+		o.trustForwarded = true
+	}
+}
+
+// WithModifyResponse sets a function modifying responses from the upstream,
+// see httputil.ReverseProxy.ModifyResponse. Returned errors are handled like
+// upstream errors.
+func WithModifyResponse(fn func(*http.Response) error) Option {
+	return func(o *options) {
+		o.modifyResponse = fn
+	}
+}
+
+// WithFlushInterval sets how often the response is flushed to the client, see
+// httputil.ReverseProxy.FlushInterval. Streamed responses, such as
+// Server-Sent Events, are always flushed immediately.
+func WithFlushInterval(interval time.Duration) Option {
+	return func(o *options) {
+		o.flushInterval = interval
+	}
+}
+
+// New returns a reverse proxy sending requests to the target. The path of
+// the target is joined with the request path, the Host header is set to the
+// target host unless WithPreserveHost is used and the X-Forwarded-For,
+// X-Forwarded-Host and X-Forwarded-Proto headers are set. Hop-by-hop headers,
+// including the headers listed in Connection, are stripped from requests and
+// responses by httputil.ReverseProxy. Failing upstream
+// requests are written as 502 Bad Gateway, or 504 Gateway Timeout if the
+// request timed out, and an *Error is stored on the response writer.
+func New(target *url.URL, opts ...Option) *httputil.ReverseProxy {
+	options := &options{transport: http.DefaultTransport, clock: clock.Real()}
+	for _, opt := range opts {
+		opt(options)
+	}
+
+	var rt http.RoundTripper = &transport{
+		base:    options.transport,
+		timeout: options.timeout,
+		retries: options.retries,
+	}
+
+	if options.cacheStore != nil {
+		rt = &cachingTransport{base: rt, store: options.cacheStore, clock: options.clock}
+	}
+
+	return &httputil.ReverseProxy{
+		Rewrite: func(pr *httputil.ProxyRequest) {
+			pr.SetURL(target)
+			setForwarded(pr, options)
+
+			if options.preserveHost {
+				pr.Out.Host = pr.In.Host
+			}
+		},
+		Transport:      rt,
+		FlushInterval:  options.flushInterval,
+		ModifyResponse: options.modifyResponse,
+		ErrorHandler: func(w http.ResponseWriter, r *http.Request, err error) {
+			status := http.StatusBadGateway
+			if isTimeout(err) {
+				status = http.StatusGatewayTimeout
+			}
+
+			if ew, ok := w.(errorWriter); ok {
+				ew.WriteError(&Error{Upstream: target.Host, Status: status, Err: err})
+			}
+
+			http.Error(w, http.StatusText(status), status)
+		},
+	}
+}
+
+func isTimeout(err error) bool {
+	var netErr net.Error
+
+	return errors.Is(err, context.DeadlineExceeded) || errors.As(err, &netErr) && netErr.Timeout()
+}
+
+// transport applies the timeout to and retries each request.
+type transport struct {
+	base    http.RoundTripper
+	timeout time.Duration
+	retries int
+}
+
+func (t *transport) RoundTrip(r *http.Request) (*http.Response, error) {
+	for attempt := 0; ; attempt++ {
+		resp, err := t.roundTrip(r)
+		if err == nil || attempt >= t.retries || !retryable(r) || r.Context().Err() != nil {
+			return resp, err
+		}
+
+		if r.GetBody != nil {
+			body, bodyErr := r.GetBody()
+			if bodyErr != nil {
+				return nil, err
+			}
+
+			r = r.Clone(r.Context())
+			r.Body = body
+		}
+	}
+}
+
+func (t *transport) roundTrip(r *http.Request) (*http.Response, error) {
+	if t.timeout <= 0 {
+		return t.base.RoundTrip(r)
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), t.timeout)
+
+	resp, err := t.base.RoundTrip(r.WithContext(ctx))
+	if err != nil {
+		cancel()
+		return nil, err
+	}
+
+	resp.Body = &cancelBody{ReadCloser: resp.Body, cancel: cancel}
+
+	return resp, nil
+}
+
+// retryable returns true if the request has an idempotent method and a body
+// that can be sent again.
+func retryable(r *http.Request) bool {
+	switch r.Method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions, http.MethodTrace, http.MethodPut, http.MethodDelete:
+	default:
+		return false
+	}
+
+	return r.Body == nil || r.Body == http.NoBody || r.GetBody != nil
+}
+
+// cancelBody cancels the context of the upstream request when the response
+// body is closed.
+type cancelBody struct {
+	io.ReadCloser
+	cancel context.CancelFunc
+}
+
+func (b *cancelBody) Close() error {
+	defer b.cancel()
+
+	return b.ReadCloser.Close()
+}
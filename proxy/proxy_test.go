@@ -0,0 +1,179 @@
+package proxy
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+type recorderWithError struct {
+	*httptest.ResponseRecorder
+	err error
+}
+
+func (r *recorderWithError) WriteError(err error) {
+	r.err = err
+}
+
+type roundTripperFunc func(*http.Request) (*http.Response, error)
+
+func (fn roundTripperFunc) RoundTrip(r *http.Request) (*http.Response, error) {
+	return fn(r)
+}
+
+func mustParse(t *testing.T, rawURL string) *url.URL {
+	t.Helper()
+
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return u
+}
+
+func Test_Proxy(t *testing.T) {
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		for _, header := range []string{"X-Forwarded-For", "X-Forwarded-Host", "X-Forwarded-Proto", "Forwarded", "X-Internal"} {
+			w.Header().Set("Got-"+header, r.Header.Get(header))
+		}
+
+		_, _ = io.WriteString(w, r.Host+" "+r.URL.Path)
+	}))
+	defer upstream.Close()
+
+	target := mustParse(t, upstream.URL+"/api")
+
+	for _, tc := range []struct {
+		description       string
+		opts              []Option
+		expectedXFF       string
+		expectedForwarded string
+		expectHost        string
+	}{
+		{
+			description: "forwarded headers replaced",
+			expectedXFF: "192.0.2.1",
+			expectHost:  target.Host,
+		},
+		{
+			description: "forwarded headers trusted and host preserved",
+			opts:        []Option{WithTrustForwardedHeaders(), WithPreserveHost()},
+			expectedXFF: "198.51.100.9, 203.0.113.7, 192.0.2.1",
+			expectHost:  "example.com",
+		},
+		{
+			description:       "forwarded header",
+			opts:              []Option{WithForwardedHeader()},
+			expectedXFF:       "192.0.2.1",
+			expectedForwarded: "for=192.0.2.1;host=example.com;proto=http",
+			expectHost:        target.Host,
+		},
+		{
+			description:       "trusted hops",
+			opts:              []Option{WithTrustedHops(1), WithForwardedHeader()},
+			expectedXFF:       "203.0.113.7, 192.0.2.1",
+			expectedForwarded: `for="[2001:db8::7]", for=192.0.2.1;host=example.com;proto=http`,
+			expectHost:        target.Host,
+		},
+	} {
+		t.Run(tc.description, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "http://example.com/users", nil)
+			req.Header.Set("X-Forwarded-For", "198.51.100.9, 203.0.113.7")
+			req.Header.Set("Forwarded", `for=198.51.100.9, for="[2001:db8::7]"`)
+
+			// Hop-by-hop headers, and the headers listed in Connection, are
+			// never forwarded.
+			req.Header.Set("Connection", "X-Internal")
+			req.Header.Set("X-Internal", "secret")
+
+			rec := httptest.NewRecorder()
+			New(target, tc.opts...).ServeHTTP(rec, req)
+
+			if rec.Code != http.StatusOK || rec.Body.String() != tc.expectHost+" /api/users" {
+				t.Fatalf("unexpected response: %d %s", rec.Code, rec.Body.String())
+			}
+
+			for header, expected := range map[string]string{
+				"Got-X-Forwarded-For":   tc.expectedXFF,
+				"Got-X-Forwarded-Host":  "example.com",
+				"Got-X-Forwarded-Proto": "http",
+				"Got-Forwarded":         tc.expectedForwarded,
+				"Got-X-Internal":        "",
+			} {
+				if got := rec.Header().Get(header); got != expected {
+					t.Fatalf("unexpected %s, got: %s, expected: %s", header, got, expected)
+				}
+			}
+		})
+	}
+}
+
+func Test_ProxyErrors(t *testing.T) {
+	slow := httptest.NewServer(http.HandlerFunc(func(_ http.ResponseWriter, r *http.Request) {
+		<-r.Context().Done()
+	}))
+	defer slow.Close()
+
+	closed := httptest.NewServer(http.NotFoundHandler())
+	closed.Close()
+
+	for _, tc := range []struct {
+		description    string
+		target         string
+		expectedStatus int
+	}{
+		{description: "unreachable upstream", target: closed.URL, expectedStatus: http.StatusBadGateway},
+		{description: "upstream timeout", target: slow.URL, expectedStatus: http.StatusGatewayTimeout},
+	} {
+		t.Run(tc.description, func(t *testing.T) {
+			rec := &recorderWithError{ResponseRecorder: httptest.NewRecorder()}
+			New(mustParse(t, tc.target), WithTimeout(50*time.Millisecond)).
+				ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+
+			if rec.Code != tc.expectedStatus {
+				t.Fatalf("unexpected status, got: %d, expected: %d", rec.Code, tc.expectedStatus)
+			}
+
+			var proxyErr *Error
+			if !errors.As(rec.err, &proxyErr) || proxyErr.Status != tc.expectedStatus {
+				t.Fatalf("expected proxy error to be stored, got: %v", rec.err)
+			}
+		})
+	}
+}
+
+func Test_ProxyRetries(t *testing.T) {
+	var attempts atomic.Int32
+
+	failing := roundTripperFunc(func(r *http.Request) (*http.Response, error) {
+		attempts.Add(1)
+		return nil, errors.New("connection refused")
+	})
+
+	for _, tc := range []struct {
+		method           string
+		body             string
+		expectedAttempts int32
+	}{
+		{method: http.MethodGet, expectedAttempts: 3},
+		{method: http.MethodPost, body: "data", expectedAttempts: 1},
+		{method: http.MethodPut, body: "data", expectedAttempts: 1},
+	} {
+		attempts.Store(0)
+
+		rec := httptest.NewRecorder()
+		New(mustParse(t, "http://upstream"), WithTransport(failing), WithRetries(2)).
+			ServeHTTP(rec, httptest.NewRequest(tc.method, "/", strings.NewReader(tc.body)))
+
+		if rec.Code != http.StatusBadGateway || attempts.Load() != tc.expectedAttempts {
+			t.Fatalf("unexpected result for %s, status: %d, attempts: %d", tc.method, rec.Code, attempts.Load())
+		}
+	}
+}
@@ -0,0 +1,173 @@
+package httphelpers
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// RangeSource is content that can be read in ranges without reading it all,
+// e.g. an object in object storage fetched with ranged GET requests.
+type RangeSource interface {
+	// Size returns the size of the content in bytes.
+	Size() int64
+
+	// ReadRange returns a reader for length bytes of the content starting at
+	// offset. The reader may be closed before all bytes are read so it should
+	// stream the content.
+	ReadRange(ctx context.Context, offset, length int64) (io.ReadCloser, error)
+}
+
+// ServeRange serves the content like http.ServeContent, with Accept-Ranges,
+// single and multipart Range requests, If-Range and conditional requests, but
+// reads only the requested ranges from the source. Each range is read with one
+// ReadRange call for the length of the range and closed once the range has
+// been served. Set the ETag header before calling
+// ServeRange to validate If-Range and If-None-Match with it, otherwise the
+// modification time is used if not zero. The content type is detected from the
+// name, or by reading the start of the content, unless the Content-Type header
+// is set.
+//
+// Ranges refer to the bytes of the content as is, so compression must not be
+// applied to the response. Compression middlewares should skip responses with
+// a Content-Range header, or the source can store compressed content and set
+// Content-Encoding itself.
+func ServeRange(w http.ResponseWriter, r *http.Request, name string, modtime time.Time, src RangeSource) {
+	size := src.Size()
+	content := &rangeSeeker{
+		ctx:     r.Context(),
+		src:     src,
+		size:    size,
+		lengths: rangeLengths(r.Header.Get("Range"), size),
+	}
+	defer content.closeBody()
+
+	http.ServeContent(w, r, name, modtime, content)
+}
+
+// rangeLengths returns the length of each range in the Range header by its
+// start. Invalid ranges are skipped, http.ServeContent rejects them.
+func rangeLengths(header string, size int64) map[int64]int64 {
+	spec, ok := strings.CutPrefix(header, "bytes=")
+	if !ok {
+		return nil
+	}
+
+	lengths := make(map[int64]int64)
+
+	for _, ra := range strings.Split(spec, ",") {
+		first, last, ok := strings.Cut(strings.TrimSpace(ra), "-")
+		if !ok {
+			continue
+		}
+
+		var start, end int64
+
+		if first == "" {
+			// A suffix range of the last n bytes.
+			n, err := strconv.ParseInt(last, 10, 64)
+			if err != nil || n <= 0 {
+				continue
+			}
+
+			start, end = max(size-n, 0), size
+		} else {
+			var err error
+			if start, err = strconv.ParseInt(first, 10, 64); err != nil || start >= size {
+				continue
+			}
+
+			end = size
+			if last != "" {
+				n, err := strconv.ParseInt(last, 10, 64)
+				if err != nil || n < start {
+					continue
+				}
+
+				end = min(n+1, size)
+			}
+		}
+
+		lengths[start] = max(lengths[start], end-start)
+	}
+
+	return lengths
+}
+
+// rangeSeeker adapts a RangeSource to an io.ReadSeeker. Seeking only moves
+// the offset, the range from the offset is opened on the first read after a
+// seek and closed on the next seek. The range is the requested length if a
+// requested range starts at the offset, otherwise to the end of the content.
+type rangeSeeker struct {
+	ctx     context.Context
+	src     RangeSource
+	size    int64
+	lengths map[int64]int64
+	offset  int64
+	end     int64
+	body    io.ReadCloser
+}
+
+func (s *rangeSeeker) Seek(offset int64, whence int) (int64, error) {
+	switch whence {
+	case io.SeekCurrent:
+		offset += s.offset
+	case io.SeekEnd:
+		offset += s.size
+	}
+
+	if offset < 0 {
+		return 0, errors.New("httphelpers: negative position")
+	}
+
+	if offset != s.offset {
+		s.closeBody()
+		s.offset = offset
+	}
+
+	return offset, nil
+}
+
+func (s *rangeSeeker) Read(p []byte) (int, error) {
+	if s.offset >= s.size {
+		return 0, io.EOF
+	}
+
+	if s.body == nil {
+		length, ok := s.lengths[s.offset]
+		if !ok {
+			length = s.size - s.offset
+		}
+
+		body, err := s.src.ReadRange(s.ctx, s.offset, length)
+		if err != nil {
+			return 0, err
+		}
+
+		s.body = body
+		s.end = s.offset + length
+	}
+
+	n, err := s.body.Read(p)
+	s.offset += int64(n)
+
+	// More than the requested range is read if the ranges are ignored, e.g.
+	// for a changed If-Range, so continue with the rest of the content.
+	if errors.Is(err, io.EOF) && s.offset == s.end && s.end < s.size {
+		s.closeBody()
+		err = nil
+	}
+
+	return n, err
+}
+
+func (s *rangeSeeker) closeBody() {
+	if s.body != nil {
+		_ = s.body.Close()
+		s.body = nil
+	}
+}
@@ -0,0 +1,134 @@
+package httphelpers
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+type bytesSource struct {
+	data  []byte
+	reads [][2]int64
+}
+
+func (s *bytesSource) Size() int64 {
+	return int64(len(s.data))
+}
+
+func (s *bytesSource) ReadRange(_ context.Context, offset, length int64) (io.ReadCloser, error) {
+	s.reads = append(s.reads, [2]int64{offset, length})
+
+	return io.NopCloser(bytes.NewReader(s.data[offset : offset+length])), nil
+}
+
+func Test_ServeRange(t *testing.T) {
+	data := []byte(strings.Repeat("0123456789", 100))
+	modtime := time.Date(2024, time.May, 1, 12, 0, 0, 0, time.UTC)
+
+	serve := func(src *bytesSource, header http.Header) *httptest.ResponseRecorder {
+		t.Helper()
+
+		req := httptest.NewRequest(http.MethodGet, "/files/data.txt", nil)
+		req.Header = header
+
+		rec := httptest.NewRecorder()
+		rec.Header().Set("ETag", `"v1"`)
+
+		ServeRange(rec, req, "data.txt", modtime, src)
+
+		return rec
+	}
+
+	t.Run("full", func(t *testing.T) {
+		src := &bytesSource{data: data}
+		rec := serve(src, http.Header{})
+
+		if rec.Code != http.StatusOK || !bytes.Equal(rec.Body.Bytes(), data) || rec.Header().Get("Accept-Ranges") != "bytes" {
+			t.Fatalf("unexpected response: %d, %v", rec.Code, rec.Header())
+		}
+	})
+
+	t.Run("single range", func(t *testing.T) {
+		src := &bytesSource{data: data}
+		rec := serve(src, http.Header{"Range": {"bytes=995-"}})
+
+		if rec.Code != http.StatusPartialContent || rec.Body.String() != "56789" || rec.Header().Get("Content-Range") != "bytes 995-999/1000" {
+			t.Fatalf("unexpected response: %d, %s, %v", rec.Code, rec.Body.String(), rec.Header())
+		}
+
+		if len(src.reads) != 1 || src.reads[0] != [2]int64{995, 5} {
+			t.Fatalf("expected a single read of the range, got: %v", src.reads)
+		}
+	})
+
+	t.Run("range length", func(t *testing.T) {
+		src := &bytesSource{data: data}
+		rec := serve(src, http.Header{"Range": {"bytes=10-19"}})
+
+		if rec.Code != http.StatusPartialContent || rec.Body.String() != "0123456789" {
+			t.Fatalf("unexpected response: %d, %s", rec.Code, rec.Body.String())
+		}
+
+		if len(src.reads) != 1 || src.reads[0] != [2]int64{10, 10} {
+			t.Fatalf("expected a single read of the range length, got: %v", src.reads)
+		}
+	})
+
+	t.Run("multiple ranges", func(t *testing.T) {
+		src := &bytesSource{data: data}
+		rec := serve(src, http.Header{"Range": {"bytes=0-1,500-502"}})
+
+		mediaType, params, _ := mime.ParseMediaType(rec.Header().Get("Content-Type"))
+		if rec.Code != http.StatusPartialContent || mediaType != "multipart/byteranges" {
+			t.Fatalf("unexpected response: %d, %v", rec.Code, rec.Header())
+		}
+
+		var parts []string
+
+		reader := multipart.NewReader(rec.Body, params["boundary"])
+		for {
+			part, err := reader.NextPart()
+			if err != nil {
+				break
+			}
+
+			body, _ := io.ReadAll(part)
+			parts = append(parts, part.Header.Get("Content-Range")+"="+string(body))
+		}
+
+		if strings.Join(parts, ",") != "bytes 0-1/1000=01,bytes 500-502/1000=012" {
+			t.Fatalf("unexpected parts: %v", parts)
+		}
+
+		if len(src.reads) != 2 || src.reads[0] != [2]int64{0, 2} || src.reads[1] != [2]int64{500, 3} {
+			t.Fatalf("expected a read per range, got: %v", src.reads)
+		}
+	})
+
+	t.Run("if-range", func(t *testing.T) {
+		if rec := serve(&bytesSource{data: data}, http.Header{"Range": {"bytes=0-1"}, "If-Range": {`"v1"`}}); rec.Code != http.StatusPartialContent {
+			t.Fatalf("expected range for matching If-Range, got: %d", rec.Code)
+		}
+
+		if rec := serve(&bytesSource{data: data}, http.Header{"Range": {"bytes=0-1"}, "If-Range": {`"v0"`}}); rec.Code != http.StatusOK || rec.Body.Len() != len(data) {
+			t.Fatalf("expected full content for changed If-Range, got: %d", rec.Code)
+		}
+	})
+
+	t.Run("not modified and unsatisfiable", func(t *testing.T) {
+		if rec := serve(&bytesSource{data: data}, http.Header{"If-None-Match": {`"v1"`}}); rec.Code != http.StatusNotModified {
+			t.Fatalf("expected 304, got: %d", rec.Code)
+		}
+
+		if rec := serve(&bytesSource{data: data}, http.Header{"Range": {"bytes=2000-"}}); rec.Code != http.StatusRequestedRangeNotSatisfiable {
+			t.Fatalf("expected 416, got: %d", rec.Code)
+		}
+	})
+}
@@ -0,0 +1,73 @@
+package render
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"io"
+	"mime"
+	"sync"
+)
+
+// Media types with built-in encoders.
+const (
+	MediaTypeJSON = "application/json"
+	MediaTypeXML  = "application/xml"
+)
+
+// EncodeFunc encodes v to w.
+type EncodeFunc func(w io.Writer, v interface{}) error
+
+type encoder struct {
+	contentType string
+	encode      EncodeFunc
+}
+
+var (
+	encodersMu sync.RWMutex
+	encoders   = map[string]encoder{
+		MediaTypeJSON: {contentType: "application/json; charset=utf-8", encode: encodeJSON},
+		MediaTypeXML:  {contentType: "application/xml; charset=utf-8", encode: encodeXML},
+	}
+)
+
+// Register registers the encoder used for the media type. The content type is
+// set on the response and may include parameters such as the charset.
+// Registering a media type again replaces the encoder.
+func Register(mediaType, contentType string, fn EncodeFunc) {
+	encodersMu.Lock()
+	defer encodersMu.Unlock()
+
+	encoders[mediaType] = encoder{contentType: contentType, encode: fn}
+}
+
+// Supports returns true if there's an encoder registered for the media type.
+// Parameters in the media type are ignored.
+func Supports(mediaType string) bool {
+	_, ok := lookup(mediaType)
+	return ok
+}
+
+func lookup(mediaType string) (encoder, bool) {
+	if parsed, _, err := mime.ParseMediaType(mediaType); err == nil {
+		mediaType = parsed
+	}
+
+	encodersMu.RLock()
+	defer encodersMu.RUnlock()
+
+	e, ok := encoders[mediaType]
+
+	return e, ok
+}
+
+func encodeJSON(w io.Writer, v interface{}) error {
+	return json.NewEncoder(w).Encode(v)
+}
+
+func encodeXML(w io.Writer, v interface{}) error {
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+
+	return xml.NewEncoder(w).Encode(v)
+}
@@ -0,0 +1,208 @@
+package render
+
+import (
+	"context"
+	"errors"
+	"io"
+	"io/fs"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// Image media types, in the order they're preferred by Image.
+const (
+	MediaTypeAVIF = "image/avif"
+	MediaTypeWebP = "image/webp"
+	MediaTypeJPEG = "image/jpeg"
+	MediaTypePNG  = "image/png"
+)
+
+// imageExtensions maps image media types to the file extensions used by
+// ImageFiles.
+var imageExtensions = []struct {
+	mediaType string
+	ext       string
+}{
+	{MediaTypeAVIF, ".avif"},
+	{MediaTypeWebP, ".webp"},
+	{MediaTypeJPEG, ".jpg"},
+	{MediaTypeJPEG, ".jpeg"},
+	{MediaTypePNG, ".png"},
+}
+
+// ImageSource is an image available in one or more formats, e.g. a thumbnail
+// stored as AVIF, WebP and JPEG.
+type ImageSource interface {
+	// Formats returns the media types the image is available in, most
+	// preferred first. The last format is served to clients not listing any
+	// of the formats explicitly, so it should be supported by all clients.
+	Formats() []string
+
+	// Open returns the image in one of the formats. fs.ErrNotExist results in
+	// 404 Not Found.
+	Open(ctx context.Context, mediaType string) (io.ReadCloser, error)
+}
+
+// Image writes the image in the format best matching the Accept header.
+// Formats explicitly accepted by the client are preferred, in the order
+// returned by the source, so a client accepting "image/webp,*/*" gets WebP even
+// if AVIF is available. Clients only accepting wildcards get the last format.
+// Vary: Accept is always set so caches store each format separately. If no
+// format is acceptable 406 Not Acceptable is written and if the image isn't
+// available in any format 404 Not Found. Errors opening or writing the image
+// are stored on the response writer and returned.
+func Image(w http.ResponseWriter, r *http.Request, src ImageSource) error {
+	w.Header().Add("Vary", "Accept")
+
+	formats := src.Formats()
+	if len(formats) == 0 {
+		http.Error(w, http.StatusText(http.StatusNotFound), http.StatusNotFound)
+		return nil
+	}
+
+	mediaType := NegotiateImage(r.Header.Get("Accept"), formats...)
+	if mediaType == "" {
+		http.Error(w, http.StatusText(http.StatusNotAcceptable), http.StatusNotAcceptable)
+		return nil
+	}
+
+	img, err := src.Open(r.Context(), mediaType)
+	if err != nil {
+		storeError(w, err)
+
+		if errors.Is(err, fs.ErrNotExist) {
+			http.Error(w, http.StatusText(http.StatusNotFound), http.StatusNotFound)
+			return err
+		}
+
+		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+
+		return err
+	}
+
+	defer img.Close()
+
+	w.Header().Set("Content-Type", mediaType)
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+
+	if f, ok := img.(fs.File); ok {
+		if info, err := f.Stat(); err == nil {
+			w.Header().Set("Content-Length", strconv.FormatInt(info.Size(), 10))
+		}
+	}
+
+	w.WriteHeader(http.StatusOK)
+
+	if r.Method == http.MethodHead {
+		return nil
+	}
+
+	if _, err := io.Copy(w, img); err != nil {
+		storeError(w, err)
+		return err
+	}
+
+	return nil
+}
+
+// NegotiateImage returns the image format to serve for the Accept header.
+// Unlike Negotiate, formats only matched by a wildcard are never picked over
+// the last format since most clients send "*/*" without supporting newer
+// formats such as AVIF. An empty string is returned if no format is
+// acceptable.
+func NegotiateImage(accept string, formats ...string) string {
+	if len(formats) == 0 {
+		return ""
+	}
+
+	fallback := formats[len(formats)-1]
+
+	if strings.TrimSpace(accept) == "" {
+		return fallback
+	}
+
+	ranges := parseAccept(accept)
+
+	var (
+		best  string
+		bestQ float64
+	)
+
+	for _, format := range formats {
+		if q, explicit := explicitQuality(ranges, format); explicit && q > bestQ {
+			best, bestQ = format, q
+		}
+	}
+
+	if best != "" {
+		return best
+	}
+
+	if quality(ranges, fallback) > 0 {
+		return fallback
+	}
+
+	return ""
+}
+
+// explicitQuality returns the quality of the range exactly matching the media
+// type and true, or false if there's no such range.
+func explicitQuality(ranges []mediaRange, mediaType string) (float64, bool) {
+	typ, subtype, _ := strings.Cut(mediaType, "/")
+
+	for _, r := range ranges {
+		if r.typ == typ && r.subtype == subtype {
+			return r.q, true
+		}
+	}
+
+	return 0, false
+}
+
+// ImageFiles returns an ImageSource serving the files in fsys named name with
+// an image extension, e.g. "thumbs/cat.avif" and "thumbs/cat.jpg" for the name
+// "thumbs/cat". Formats are preferred in the order AVIF, WebP, JPEG and PNG.
+func ImageFiles(fsys fs.FS, name string) ImageSource {
+	return imageFiles{fsys: fsys, name: name}
+}
+
+type imageFiles struct {
+	fsys fs.FS
+	name string
+}
+
+func (i imageFiles) Formats() []string {
+	var formats []string
+
+	for _, e := range imageExtensions {
+		if _, err := fs.Stat(i.fsys, i.name+e.ext); err != nil {
+			continue
+		}
+
+		if len(formats) > 0 && formats[len(formats)-1] == e.mediaType {
+			continue
+		}
+
+		formats = append(formats, e.mediaType)
+	}
+
+	return formats
+}
+
+func (i imageFiles) Open(_ context.Context, mediaType string) (io.ReadCloser, error) {
+	for _, e := range imageExtensions {
+		if e.mediaType != mediaType {
+			continue
+		}
+
+		f, err := i.fsys.Open(i.name + e.ext)
+		if errors.Is(err, fs.ErrNotExist) {
+			continue
+		}
+
+		return f, err
+	}
+
+	return nil, fs.ErrNotExist
+}
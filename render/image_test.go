@@ -0,0 +1,107 @@
+package render
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"testing/fstest"
+)
+
+func Test_NegotiateImage(t *testing.T) {
+	formats := []string{MediaTypeAVIF, MediaTypeWebP, MediaTypeJPEG}
+
+	for accept, expected := range map[string]string{
+		"":                                     MediaTypeJPEG,
+		"*/*":                                  MediaTypeJPEG,
+		"image/*":                              MediaTypeJPEG,
+		"image/avif,image/webp,*/*":            MediaTypeAVIF,
+		"image/webp,*/*":                       MediaTypeWebP,
+		"image/avif;q=0.5,image/webp":          MediaTypeWebP,
+		"image/webp;q=0,image/*":               MediaTypeJPEG,
+		"image/png":                            "",
+		"image/jpeg;q=0,*/*":                   "",
+		"text/html,image/avif;q=0.9,*/*;q=0.8": MediaTypeAVIF,
+	} {
+		if got := NegotiateImage(accept, formats...); got != expected {
+			t.Fatalf("unexpected format for %q, got: %s, expected: %s", accept, got, expected)
+		}
+	}
+}
+
+func Test_Image(t *testing.T) {
+	fsys := fstest.MapFS{
+		"thumbs/cat.webp": {Data: []byte("webp")},
+		"thumbs/cat.jpg":  {Data: []byte("jpeg")},
+	}
+
+	tests := []struct {
+		name           string
+		image          string
+		accept         string
+		expectedStatus int
+		expectedType   string
+		expectedBody   string
+	}{
+		{
+			name:           "webp",
+			image:          "thumbs/cat",
+			accept:         "image/avif,image/webp,*/*",
+			expectedStatus: http.StatusOK,
+			expectedType:   MediaTypeWebP,
+			expectedBody:   "webp",
+		},
+		{
+			name:           "fallback",
+			image:          "thumbs/cat",
+			accept:         "*/*",
+			expectedStatus: http.StatusOK,
+			expectedType:   MediaTypeJPEG,
+			expectedBody:   "jpeg",
+		},
+		{
+			name:           "not acceptable",
+			image:          "thumbs/cat",
+			accept:         "image/png",
+			expectedStatus: http.StatusNotAcceptable,
+		},
+		{
+			name:           "not found",
+			image:          "thumbs/dog",
+			expectedStatus: http.StatusNotFound,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			req.Header.Set("Accept", tc.accept)
+
+			_ = Image(rec, req, ImageFiles(fsys, tc.image))
+
+			if rec.Code != tc.expectedStatus {
+				t.Fatalf("unexpected status, got: %d, expected: %d", rec.Code, tc.expectedStatus)
+			}
+
+			if vary := rec.Header().Get("Vary"); vary != "Accept" {
+				t.Fatalf("unexpected Vary, got: %s, expected: Accept", vary)
+			}
+
+			if tc.expectedStatus != http.StatusOK {
+				return
+			}
+
+			if got := rec.Header().Get("Content-Type"); got != tc.expectedType {
+				t.Fatalf("unexpected content type, got: %s, expected: %s", got, tc.expectedType)
+			}
+
+			if got := rec.Header().Get("Content-Length"); got != "4" {
+				t.Fatalf("unexpected content length, got: %s, expected: 4", got)
+			}
+
+			if rec.Body.String() != tc.expectedBody {
+				t.Fatalf("unexpected body, got: %s, expected: %s", rec.Body.String(), tc.expectedBody)
+			}
+		})
+	}
+}
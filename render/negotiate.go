@@ -0,0 +1,100 @@
+package render
+
+import (
+	"mime"
+	"strconv"
+	"strings"
+)
+
+// Negotiate returns the offer best matching the Accept header. Offers are
+// media types such as "application/json" and are preferred in the order
+// they're passed if the client accepts them equally. If the header is empty
+// the first offer is returned. An empty string is returned if no offer is
+// acceptable.
+func Negotiate(accept string, offers ...string) string {
+	if len(offers) == 0 {
+		return ""
+	}
+
+	if strings.TrimSpace(accept) == "" {
+		return offers[0]
+	}
+
+	ranges := parseAccept(accept)
+
+	var (
+		best  string
+		bestQ float64
+	)
+
+	for _, offer := range offers {
+		if q := quality(ranges, offer); q > bestQ {
+			best, bestQ = offer, q
+		}
+	}
+
+	return best
+}
+
+type mediaRange struct {
+	typ, subtype string
+	q            float64
+}
+
+func parseAccept(accept string) []mediaRange {
+	var ranges []mediaRange
+
+	for _, part := range strings.Split(accept, ",") {
+		mediaType, params, err := mime.ParseMediaType(strings.TrimSpace(part))
+		if err != nil {
+			continue
+		}
+
+		typ, subtype, _ := strings.Cut(mediaType, "/")
+		r := mediaRange{typ: typ, subtype: subtype, q: 1}
+
+		if qs, ok := params["q"]; ok {
+			q, err := strconv.ParseFloat(qs, 64)
+			if err != nil {
+				continue
+			}
+
+			r.q = q
+		}
+
+		ranges = append(ranges, r)
+	}
+
+	return ranges
+}
+
+// quality returns the quality of the most specific range matching the offer.
+func quality(ranges []mediaRange, offer string) float64 {
+	typ, subtype, _ := strings.Cut(offer, "/")
+
+	var (
+		q           float64
+		specificity = -1
+	)
+
+	for _, r := range ranges {
+		var s int
+
+		switch {
+		case r.typ == typ && r.subtype == subtype:
+			s = 2
+		case r.typ == typ && r.subtype == "*":
+			s = 1
+		case r.typ == "*" && r.subtype == "*":
+			s = 0
+		default:
+			continue
+		}
+
+		if s > specificity {
+			q, specificity = r.q, s
+		}
+	}
+
+	return q
+}
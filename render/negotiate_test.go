@@ -0,0 +1,22 @@
+package render
+
+import "testing"
+
+func Test_Negotiate(t *testing.T) {
+	offers := []string{"application/json", "application/xml", "application/msgpack"}
+
+	for accept, expected := range map[string]string{
+		"":                                      "application/json",
+		"*/*":                                   "application/json",
+		"application/xml":                       "application/xml",
+		"application/msgpack, application/json": "application/json",
+		"application/json;q=0.1, application/*": "application/xml",
+		"application/xml;q=0, */*":              "application/json",
+		"text/html":                             "",
+		"text/html, application/xml;q=0.9, */*;q=0.1": "application/xml",
+	} {
+		if got := Negotiate(accept, offers...); got != expected {
+			t.Fatalf("unexpected media type for %q, got: %s, expected: %s", accept, got, expected)
+		}
+	}
+}
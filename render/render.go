@@ -0,0 +1,99 @@
+package render
+
+/*
+Helpers to render responses. The response is encoded before anything is
+written so encoding errors result in a 500 Internal Server Error instead of a
+partial response.
+
+	func handler(w http.ResponseWriter, r *http.Request) {
+		render.JSON(w, http.StatusOK, map[string]string{"hello": "world"})
+	}
+
+Use Render to encode the response in the media type negotiated by the
+middleware.Negotiate middleware. JSON and XML are supported out of the box and
+more formats can be added with Register, e.g. by importing the msgpack and
+protobuf packages in the formats module.
+*/
+
+import (
+	"bytes"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/bombsimon/http-helpers/httpctx"
+)
+
+// errorWriter is implemented by response writers that can store an error, such
+// as middleware.ResponseWriterWithInfo.
+type errorWriter interface {
+	WriteError(err error)
+}
+
+// JSON writes v encoded as JSON with the passed status code. If v can't be
+// encoded, 500 Internal Server Error is written instead and the error is stored
+// on the response writer, if it supports it, so it's logged by the Logger
+// middleware. The encoding error is also returned.
+func JSON(w http.ResponseWriter, status int, v interface{}) error {
+	return As(w, status, MediaTypeJSON, v)
+}
+
+// XML writes v encoded as XML, including the XML header, with the passed
+// status code. Encoding errors are handled like in JSON.
+func XML(w http.ResponseWriter, status int, v interface{}) error {
+	return As(w, status, MediaTypeXML, v)
+}
+
+// As writes v encoded with the encoder registered for the media type with the
+// passed status code. Encoding errors are handled like in JSON. An error is
+// returned without writing anything if there's no encoder for the media type.
+func As(w http.ResponseWriter, status int, mediaType string, v interface{}) error {
+	encoder, ok := lookup(mediaType)
+	if !ok {
+		return fmt.Errorf("render: no encoder registered for %s", mediaType)
+	}
+
+	buf := &bytes.Buffer{}
+	if err := encoder.encode(buf, v); err != nil {
+		return writeEncodeError(w, err)
+	}
+
+	write(w, status, encoder.contentType, buf.Bytes())
+
+	return nil
+}
+
+// Render writes v encoded in the media type stored in the request context by
+// the Negotiate middleware, defaulting to JSON.
+func Render(w http.ResponseWriter, r *http.Request, status int, v interface{}) error {
+	mediaType, ok := httpctx.MediaType(r.Context())
+	if !ok {
+		mediaType = MediaTypeJSON
+	}
+
+	return As(w, status, mediaType, v)
+}
+
+func write(w http.ResponseWriter, status int, contentType string, body []byte) {
+	w.Header().Set("Content-Type", contentType)
+	w.Header().Set("Content-Length", strconv.Itoa(len(body)))
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	w.WriteHeader(status)
+
+	if _, err := w.Write(body); err != nil {
+		storeError(w, err)
+	}
+}
+
+func writeEncodeError(w http.ResponseWriter, err error) error {
+	storeError(w, err)
+	http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+
+	return err
+}
+
+func storeError(w http.ResponseWriter, err error) {
+	if ew, ok := w.(errorWriter); ok {
+		ew.WriteError(err)
+	}
+}
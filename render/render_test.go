@@ -0,0 +1,110 @@
+package render
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+type recorderWithError struct {
+	*httptest.ResponseRecorder
+	err error
+}
+
+func (r *recorderWithError) WriteError(err error) {
+	r.err = err
+}
+
+func Test_JSON(t *testing.T) {
+	rec := httptest.NewRecorder()
+
+	if err := JSON(rec, http.StatusCreated, map[string]int{"id": 1}); err != nil {
+		t.Fatal(err)
+	}
+
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("unexpected status, got: %d, expected: %d", rec.Code, http.StatusCreated)
+	}
+
+	for header, expected := range map[string]string{
+		"Content-Type":           "application/json; charset=utf-8",
+		"Content-Length":         "9",
+		"X-Content-Type-Options": "nosniff",
+	} {
+		if got := rec.Header().Get(header); got != expected {
+			t.Fatalf("unexpected %s, got: %s, expected: %s", header, got, expected)
+		}
+	}
+
+	if rec.Body.String() != "{\"id\":1}\n" {
+		t.Fatalf("unexpected body: %s", rec.Body.String())
+	}
+}
+
+func Test_JSONEncodeError(t *testing.T) {
+	rec := &recorderWithError{ResponseRecorder: httptest.NewRecorder()}
+
+	err := JSON(rec, http.StatusOK, map[string]interface{}{"fn": func() {}})
+	if err == nil {
+		t.Fatal("expected encode error")
+	}
+
+	if rec.Code != http.StatusInternalServerError {
+		t.Fatalf("unexpected status, got: %d, expected: %d", rec.Code, http.StatusInternalServerError)
+	}
+
+	if !errors.Is(rec.err, err) {
+		t.Fatalf("error not stored on response writer, got: %v", rec.err)
+	}
+}
+
+type item struct {
+	ID int `xml:"id"`
+}
+
+func Test_XML(t *testing.T) {
+	rec := httptest.NewRecorder()
+
+	if err := XML(rec, http.StatusOK, item{ID: 1}); err != nil {
+		t.Fatal(err)
+	}
+
+	if ct := rec.Header().Get("Content-Type"); ct != "application/xml; charset=utf-8" {
+		t.Fatalf("unexpected content type: %s", ct)
+	}
+
+	expected := `<?xml version="1.0" encoding="UTF-8"?>` + "\n<item><id>1</id></item>"
+	if rec.Body.String() != expected {
+		t.Fatalf("unexpected body: %s", rec.Body.String())
+	}
+}
+
+func Test_As(t *testing.T) {
+	Register("text/plain", "text/plain; charset=utf-8", func(w io.Writer, v interface{}) error {
+		_, err := fmt.Fprint(w, v)
+		return err
+	})
+
+	rec := httptest.NewRecorder()
+
+	if err := As(rec, http.StatusOK, "text/plain", "hello"); err != nil {
+		t.Fatal(err)
+	}
+
+	if rec.Body.String() != "hello" {
+		t.Fatalf("unexpected body: %s", rec.Body.String())
+	}
+
+	rec = httptest.NewRecorder()
+
+	if err := As(rec, http.StatusOK, "application/unknown", "hello"); err == nil {
+		t.Fatal("expected error for unknown media type")
+	}
+
+	if rec.Body.Len() > 0 {
+		t.Fatal("expected nothing to be written for unknown media type")
+	}
+}
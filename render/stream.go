@@ -0,0 +1,96 @@
+package render
+
+import (
+	"encoding/json"
+	"net/http"
+)
+
+// MediaTypeNDJSON is the media type of newline delimited JSON.
+const MediaTypeNDJSON = "application/x-ndjson"
+
+// NDJSON streams the items received on the channel as newline delimited JSON,
+// one JSON value per line, with 200 OK. Each item is flushed to the client as
+// soon as it's written, through any middleware implementing http.Flusher or
+// supporting http.ResponseController, so compression middlewares must flush
+// their buffers on Flush. The headers are flushed before the first item.
+//
+// NDJSON returns when the channel is closed, or with the context error if the
+// request context is done, e.g. when the client disconnects. The channel isn't
+// drained so the producer should stop on the same context. Since the status is
+// already written, an item that can't be encoded or written ends the stream and
+// the error is stored on the response writer, if it supports it, and returned.
+func NDJSON[T any](w http.ResponseWriter, r *http.Request, items <-chan T) error {
+	return stream(w, r, MediaTypeNDJSON, items, nil, []byte("\n"), nil)
+}
+
+// StreamJSONArray streams the items received on the channel as the elements of
+// a JSON array, flushing each element like NDJSON. The array is only closed
+// when the channel is closed, so a stream ended by an error or a disconnect is
+// invalid JSON and can't be mistaken for a complete response.
+func StreamJSONArray[T any](w http.ResponseWriter, r *http.Request, items <-chan T) error {
+	return stream(w, r, MediaTypeJSON, items, []byte("["), []byte(","), []byte("]"))
+}
+
+// stream writes the start, the encoded items separated by sep, or followed by
+// it if start is nil, and the end.
+func stream[T any](w http.ResponseWriter, r *http.Request, mediaType string, items <-chan T, start, sep, end []byte) error {
+	w.Header().Set("Content-Type", mediaType)
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	w.Header().Del("Content-Length")
+	w.WriteHeader(http.StatusOK)
+
+	rc := http.NewResponseController(w)
+
+	fail := func(err error) error {
+		storeError(w, err)
+		return err
+	}
+
+	if _, err := w.Write(start); err != nil {
+		return fail(err)
+	}
+
+	_ = rc.Flush()
+
+	var buf []byte
+
+	for i := 0; ; i++ {
+		select {
+		case <-r.Context().Done():
+			return r.Context().Err()
+		case item, ok := <-items:
+			if !ok {
+				if _, err := w.Write(end); err != nil {
+					return fail(err)
+				}
+
+				_ = rc.Flush()
+
+				return nil
+			}
+
+			b, err := json.Marshal(item)
+			if err != nil {
+				return fail(err)
+			}
+
+			buf = buf[:0]
+
+			if start != nil && i > 0 {
+				buf = append(buf, sep...)
+			}
+
+			buf = append(buf, b...)
+
+			if start == nil {
+				buf = append(buf, sep...)
+			}
+
+			if _, err := w.Write(buf); err != nil {
+				return fail(err)
+			}
+
+			_ = rc.Flush()
+		}
+	}
+}
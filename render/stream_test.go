@@ -0,0 +1,85 @@
+package render
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func Test_Stream(t *testing.T) {
+	for _, tc := range []struct {
+		name        string
+		stream      func(w http.ResponseWriter, r *http.Request, items <-chan item) error
+		contentType string
+		expected    string
+	}{
+		{
+			name:        "ndjson",
+			stream:      NDJSON[item],
+			contentType: MediaTypeNDJSON,
+			expected:    "{\"ID\":1}\n{\"ID\":2}\n",
+		},
+		{
+			name:        "json array",
+			stream:      StreamJSONArray[item],
+			contentType: MediaTypeJSON,
+			expected:    `[{"ID":1},{"ID":2}]`,
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			items := make(chan item, 2)
+			items <- item{ID: 1}
+			items <- item{ID: 2}
+			close(items)
+
+			rec := httptest.NewRecorder()
+			if err := tc.stream(rec, httptest.NewRequest(http.MethodGet, "/", nil), items); err != nil {
+				t.Fatalf("unexpected error: %s", err)
+			}
+
+			if rec.Body.String() != tc.expected || !rec.Flushed {
+				t.Fatalf("unexpected body, got: %s, expected: %s", rec.Body.String(), tc.expected)
+			}
+
+			if rec.Header().Get("Content-Type") != tc.contentType || rec.Header().Get("Content-Length") != "" {
+				t.Fatalf("unexpected headers: %v", rec.Header())
+			}
+		})
+	}
+}
+
+func Test_StreamErrors(t *testing.T) {
+	t.Run("disconnect", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		items := make(chan item, 1)
+		items <- item{ID: 1}
+
+		rec := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodGet, "/", nil).WithContext(ctx)
+
+		cancel()
+
+		if err := StreamJSONArray(rec, req, items); !errors.Is(err, context.Canceled) {
+			t.Fatalf("expected context error, got: %v", err)
+		}
+	})
+
+	t.Run("encode error", func(t *testing.T) {
+		items := make(chan any, 2)
+		items <- 1
+		items <- func() {}
+
+		rec := &recorderWithError{ResponseRecorder: httptest.NewRecorder()}
+
+		err := NDJSON(rec, httptest.NewRequest(http.MethodGet, "/", nil), items)
+		if err == nil || !errors.Is(rec.err, err) {
+			t.Fatalf("expected encode error to be stored, got: %v, stored: %v", err, rec.err)
+		}
+
+		if rec.Code != http.StatusOK || rec.Body.String() != "1\n" {
+			t.Fatalf("expected stream to end after the first item, got: %d %q", rec.Code, rec.Body.String())
+		}
+	})
+}
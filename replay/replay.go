@@ -0,0 +1,315 @@
+package replay
+
+/*
+Recording of sampled request and response pairs in a replayable format and a
+replayer sending the recorded traffic to a handler or server, e.g. to check
+that a refactored service still responds the same way.
+
+	f, _ := os.Create("traffic.jsonl")
+	handler := chain.AddMiddlewares(router, replay.Recorder(replay.NewJSONLinesSink(f),
+		replay.WithSampleRate(0.01),
+	))
+
+	// Later, in a test.
+	exchanges, _ := replay.ReadJSONLines(f)
+	report, _ := replay.Replay(ctx, exchanges, replay.ToHandler(newRouter))
+	for _, diff := range report.Diffs {
+		t.Errorf("%s %s: %s", diff.Method, diff.URL, diff.Reason)
+	}
+*/
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"math/rand/v2"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/bombsimon/http-helpers/chain"
+)
+
+// Exchange is a recorded request and response.
+type Exchange struct {
+	Time     time.Time     `json:"time"`
+	Duration time.Duration `json:"duration"`
+	Request  Request       `json:"request"`
+	Response Response      `json:"response"`
+}
+
+// Request is a recorded request. The URL is the request URI, e.g.
+// "/users?limit=10".
+type Request struct {
+	Method    string      `json:"method"`
+	URL       string      `json:"url"`
+	Host      string      `json:"host,omitempty"`
+	Header    http.Header `json:"header,omitempty"`
+	Body      []byte      `json:"body,omitempty"`
+	Truncated bool        `json:"truncated,omitempty"`
+}
+
+// Response is a recorded response.
+type Response struct {
+	Status    int         `json:"status"`
+	Header    http.Header `json:"header,omitempty"`
+	Body      []byte      `json:"body,omitempty"`
+	Truncated bool        `json:"truncated,omitempty"`
+}
+
+// Sink stores recorded exchanges. Sinks must be safe for concurrent use.
+type Sink interface {
+	Write(ctx context.Context, exchange Exchange) error
+}
+
+// SinkFunc is a function used as a Sink.
+type SinkFunc func(ctx context.Context, exchange Exchange) error
+
+// Write calls the function.
+func (fn SinkFunc) Write(ctx context.Context, exchange Exchange) error {
+	return fn(ctx, exchange)
+}
+
+// NewJSONLinesSink returns a Sink writing each exchange as a line of JSON to w.
+// Read the exchanges back with ReadJSONLines.
+func NewJSONLinesSink(w io.Writer) Sink {
+	return &jsonLinesSink{enc: json.NewEncoder(w)}
+}
+
+type jsonLinesSink struct {
+	mu  sync.Mutex
+	enc *json.Encoder
+}
+
+func (s *jsonLinesSink) Write(_ context.Context, exchange Exchange) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.enc.Encode(exchange)
+}
+
+// ReadJSONLines reads the exchanges written by a JSON lines sink.
+func ReadJSONLines(r io.Reader) ([]Exchange, error) {
+	var exchanges []Exchange
+
+	dec := json.NewDecoder(bufio.NewReader(r))
+	for {
+		var exchange Exchange
+		if err := dec.Decode(&exchange); err == io.EOF {
+			return exchanges, nil
+		} else if err != nil {
+			return exchanges, err
+		}
+
+		exchanges = append(exchanges, exchange)
+	}
+}
+
+// Option is an option used to configure the Recorder.
+type Option func(*options)
+
+type options struct {
+	sampleRate    float64
+	maxBodyBytes  int
+	redactHeaders []string
+	skip          func(*http.Request) bool
+	logger        *slog.Logger
+}
+
+// WithSampleRate sets the fraction of requests, between 0 and 1, to record.
+// Defaults to 1, all requests.
+func WithSampleRate(rate float64) Option {
+	return func(o *options) {
+		o.sampleRate = rate
+	}
+}
+
+// WithMaxBodyBytes sets the maximum number of bytes recorded of request and
+// response bodies. Longer bodies are truncated and marked as such. Defaults to
+// 64 KiB.
+func WithMaxBodyBytes(n int) Option {
+	return func(o *options) {
+		o.maxBodyBytes = n
+	}
+}
+
+// WithRedactHeaders adds headers whose values are replaced with "REDACTED".
+// Authorization, Cookie and Set-Cookie are always redacted.
+func WithRedactHeaders(headers ...string) Option {
+	return func(o *options) {
+		o.redactHeaders = append(o.redactHeaders, headers...)
+	}
+}
+
+// WithSkipFunc sets a function that will be called for each request. If the
+// function returns true the request is not recorded.
+func WithSkipFunc(fn func(*http.Request) bool) Option {
+	return func(o *options) {
+		o.skip = fn
+	}
+}
+
+// WithLogger sets the logger used to log errors from the sink. Defaults to
+// slog.Default().
+func WithLogger(logger *slog.Logger) Option {
+	return func(o *options) {
+		o.logger = logger
+	}
+}
+
+func newOptions(opts ...Option) *options {
+	o := &options{
+		sampleRate:    1,
+		maxBodyBytes:  64 << 10,
+		redactHeaders: []string{"Authorization", "Cookie", "Set-Cookie"},
+		logger:        slog.Default(),
+	}
+
+	for _, opt := range opts {
+		opt(o)
+	}
+
+	return o
+}
+
+// Recorder returns a middleware recording a sample of the requests and their
+// responses to the sink. The request body is copied as the handler reads it so
+// handlers work as usual, bodies not read to the end are marked as truncated.
+// Exchanges are written to the sink after the response is written. Redacted
+// headers are replayed with the value "REDACTED".
+func Recorder(sink Sink, opts ...Option) chain.Middleware {
+	options := newOptions(opts...)
+
+	return func(h http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if options.skip != nil && options.skip(r) || rand.Float64() >= options.sampleRate {
+				h.ServeHTTP(w, r)
+				return
+			}
+
+			reqBody := &limitedBuffer{max: options.maxBodyBytes}
+			tee := &teeBody{buf: reqBody, eof: true}
+
+			if r.Body != nil && r.Body != http.NoBody {
+				tee.ReadCloser, tee.eof = r.Body, false
+				r.Body = tee
+			}
+
+			rec := &recorder{ResponseWriter: w, status: http.StatusOK, body: &limitedBuffer{max: options.maxBodyBytes}}
+			start := time.Now()
+
+			h.ServeHTTP(rec, r)
+
+			exchange := Exchange{
+				Time:     start,
+				Duration: time.Since(start),
+				Request: Request{
+					Method:    r.Method,
+					URL:       r.URL.RequestURI(),
+					Host:      r.Host,
+					Header:    options.redact(r.Header),
+					Body:      reqBody.Bytes(),
+					Truncated: reqBody.truncated || !tee.eof,
+				},
+				Response: Response{
+					Status:    rec.status,
+					Header:    options.redact(w.Header()),
+					Body:      rec.body.Bytes(),
+					Truncated: rec.body.truncated,
+				},
+			}
+
+			if err := sink.Write(r.Context(), exchange); err != nil {
+				options.logger.ErrorContext(r.Context(), "could not record exchange", "error", err)
+			}
+		})
+	}
+}
+
+func (o *options) redact(header http.Header) http.Header {
+	header = header.Clone()
+
+	for _, name := range o.redactHeaders {
+		if values := header.Values(name); len(values) > 0 {
+			header.Set(name, "REDACTED")
+		}
+	}
+
+	return header
+}
+
+// limitedBuffer keeps the first max bytes written to it.
+type limitedBuffer struct {
+	bytes.Buffer
+	max       int
+	truncated bool
+}
+
+func (b *limitedBuffer) Write(p []byte) (int, error) {
+	if room := b.max - b.Len(); len(p) > room {
+		b.truncated = true
+		p = p[:max(room, 0)]
+	}
+
+	return b.Buffer.Write(p)
+}
+
+// teeBody copies the request body as it's read. A body not read until EOF by
+// the handler is incomplete.
+type teeBody struct {
+	io.ReadCloser
+	buf *limitedBuffer
+	eof bool
+}
+
+func (b *teeBody) Read(p []byte) (int, error) {
+	n, err := b.ReadCloser.Read(p)
+	_, _ = b.buf.Write(p[:n])
+
+	if err == io.EOF {
+		b.eof = true
+	}
+
+	return n, err
+}
+
+// recorder writes the response to the client while keeping the status and a
+// copy of the body.
+type recorder struct {
+	http.ResponseWriter
+	status      int
+	wroteHeader bool
+	body        *limitedBuffer
+}
+
+func (r *recorder) WriteHeader(status int) {
+	if !r.wroteHeader {
+		r.status = status
+		r.wroteHeader = true
+	}
+
+	r.ResponseWriter.WriteHeader(status)
+}
+
+func (r *recorder) Write(b []byte) (int, error) {
+	r.wroteHeader = true
+	_, _ = r.body.Write(b)
+
+	return r.ResponseWriter.Write(b)
+}
+
+// Unwrap returns the original response writer so http.ResponseController can
+// be used with the recorder.
+func (r *recorder) Unwrap() http.ResponseWriter {
+	return r.ResponseWriter
+}
+
+// Flush flushes the original response writer if it supports it.
+func (r *recorder) Flush() {
+	if f, ok := r.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
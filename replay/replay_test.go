@@ -0,0 +1,116 @@
+package replay
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func Test_RecordAndReplay(t *testing.T) {
+	echo := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/missing" {
+			http.NotFound(w, r)
+			return
+		}
+
+		body, _ := io.ReadAll(r.Body)
+
+		w.Header().Set("Set-Cookie", "session=secret")
+		w.WriteHeader(http.StatusCreated)
+		_, _ = w.Write(append([]byte(r.URL.Query().Get("prefix")), body...))
+	})
+
+	buf := &bytes.Buffer{}
+	handler := Recorder(NewJSONLinesSink(buf), WithSkipFunc(func(r *http.Request) bool {
+		return r.URL.Path == "/skip"
+	}))(echo)
+
+	for _, r := range []*http.Request{
+		httptest.NewRequest(http.MethodPost, "/echo?prefix=hi+", strings.NewReader("there")),
+		httptest.NewRequest(http.MethodGet, "/missing", nil),
+		httptest.NewRequest(http.MethodGet, "/skip", nil),
+	} {
+		r.Header.Set("Authorization", "Bearer secret")
+		handler.ServeHTTP(httptest.NewRecorder(), r)
+	}
+
+	exchanges, err := ReadJSONLines(buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(exchanges) != 2 {
+		t.Fatalf("expected 2 recorded exchanges, got: %d", len(exchanges))
+	}
+
+	first := exchanges[0]
+	if first.Request.URL != "/echo?prefix=hi+" || string(first.Request.Body) != "there" ||
+		first.Response.Status != http.StatusCreated || string(first.Response.Body) != "hi there" {
+		t.Fatalf("unexpected exchange: %+v", first)
+	}
+
+	if got := first.Request.Header.Get("Authorization"); got != "REDACTED" {
+		t.Fatalf("expected authorization to be redacted, got: %s", got)
+	}
+
+	if got := first.Response.Header.Get("Set-Cookie"); got != "REDACTED" {
+		t.Fatalf("expected cookie to be redacted, got: %s", got)
+	}
+
+	report, err := Replay(context.Background(), exchanges, ToHandler(echo))
+	if err != nil || report.Replayed != 2 || len(report.Diffs) != 0 {
+		t.Fatalf("expected replay without diffs, got: %+v, err: %v", report, err)
+	}
+
+	changed := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = io.WriteString(w, "changed")
+	})
+
+	server := httptest.NewServer(changed)
+	defer server.Close()
+
+	report, err = Replay(context.Background(), exchanges, ToURL(server.URL, nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(report.Diffs) != 2 || report.Diffs[0].Reason != "status 200, recorded 201" {
+		t.Fatalf("unexpected diffs: %+v", report.Diffs)
+	}
+}
+
+func Test_RecorderTruncates(t *testing.T) {
+	var exchanges []Exchange
+
+	sink := SinkFunc(func(_ context.Context, exchange Exchange) error {
+		exchanges = append(exchanges, exchange)
+		return nil
+	})
+
+	handler := Recorder(sink, WithMaxBodyBytes(4))(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/read" {
+			_, _ = io.ReadAll(r.Body)
+		}
+
+		_, _ = io.WriteString(w, "a long response")
+	}))
+
+	handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodPost, "/read", strings.NewReader("ab")))
+	handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodPost, "/unread", strings.NewReader("ab")))
+
+	if req := exchanges[0].Request; req.Truncated || string(req.Body) != "ab" {
+		t.Fatalf("unexpected request: %+v", req)
+	}
+
+	if resp := exchanges[0].Response; !resp.Truncated || string(resp.Body) != "a lo" {
+		t.Fatalf("expected truncated response, got: %+v", resp)
+	}
+
+	if !exchanges[1].Request.Truncated {
+		t.Fatal("expected unread request body to be marked as truncated")
+	}
+}
@@ -0,0 +1,197 @@
+package replay
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"time"
+)
+
+// Target sends a replayed request and returns the response.
+type Target func(r *http.Request) (*http.Response, error)
+
+// ToHandler returns a Target serving the requests with the handler in
+// process.
+func ToHandler(h http.Handler) Target {
+	return func(r *http.Request) (*http.Response, error) {
+		rr := httptest.NewRecorder()
+		h.ServeHTTP(rr, r)
+
+		return rr.Result(), nil
+	}
+}
+
+// ToURL returns a Target sending the requests to the server at the base URL,
+// e.g. "http://localhost:8080", with the client. If client is nil,
+// http.DefaultClient is used.
+func ToURL(base string, client *http.Client) Target {
+	if client == nil {
+		client = http.DefaultClient
+	}
+
+	return func(r *http.Request) (*http.Response, error) {
+		u, err := url.Parse(base)
+		if err != nil {
+			return nil, err
+		}
+
+		r.URL.Scheme, r.URL.Host = u.Scheme, u.Host
+		r.RequestURI = ""
+
+		return client.Do(r)
+	}
+}
+
+// ReplayOption is an option used to configure Replay.
+type ReplayOption func(*replayOptions)
+
+type replayOptions struct {
+	realTime    bool
+	compareBody bool
+}
+
+// WithRealTime sends the requests with the same time between them as when
+// they were recorded instead of one after the other.
+func WithRealTime() ReplayOption {
+	return func(o *replayOptions) {
+		o.realTime = true
+	}
+}
+
+// WithoutBodyComparison only compares the status of the responses, e.g. when
+// bodies contain timestamps or generated IDs.
+func WithoutBodyComparison() ReplayOption {
+	return func(o *replayOptions) {
+		o.compareBody = false
+	}
+}
+
+// Report is the result of a replay.
+type Report struct {
+	// Replayed is the number of exchanges replayed.
+	Replayed int
+
+	// Diffs is the exchanges where the response differs from the recorded
+	// response.
+	Diffs []Diff
+}
+
+// Diff is a replayed exchange where the response differs.
+type Diff struct {
+	// Index is the index of the exchange in the replayed exchanges.
+	Index  int
+	Method string
+	URL    string
+
+	// Reason describes the difference, e.g. "status 500, recorded 200".
+	Reason string
+}
+
+// Replay sends the recorded requests, in order, to the target and compares
+// the responses with the recorded responses. The status and, unless
+// WithoutBodyComparison is used, the body must be the same. Exchanges with a
+// truncated body are only compared by status since the full request couldn't
+// be replayed. Replay stops if the context is done.
+func Replay(ctx context.Context, exchanges []Exchange, target Target, opts ...ReplayOption) (*Report, error) {
+	options := &replayOptions{compareBody: true}
+	for _, opt := range opts {
+		opt(options)
+	}
+
+	report := &Report{}
+
+	var previous time.Time
+
+	for i, exchange := range exchanges {
+		if options.realTime && !previous.IsZero() {
+			if err := sleep(ctx, exchange.Time.Sub(previous)); err != nil {
+				return report, err
+			}
+		}
+
+		previous = exchange.Time
+
+		if err := ctx.Err(); err != nil {
+			return report, err
+		}
+
+		reason, err := replayOne(ctx, exchange, target, options)
+		if err != nil {
+			reason = err.Error()
+		}
+
+		report.Replayed++
+
+		if reason != "" {
+			report.Diffs = append(report.Diffs, Diff{
+				Index:  i,
+				Method: exchange.Request.Method,
+				URL:    exchange.Request.URL,
+				Reason: reason,
+			})
+		}
+	}
+
+	return report, nil
+}
+
+// replayOne replays the exchange and returns the difference, if any.
+func replayOne(ctx context.Context, exchange Exchange, target Target, options *replayOptions) (string, error) {
+	r, err := http.NewRequestWithContext(ctx, exchange.Request.Method, exchange.Request.URL, bytes.NewReader(exchange.Request.Body))
+	if err != nil {
+		return "", fmt.Errorf("could not create request: %w", err)
+	}
+
+	r.Host = exchange.Request.Host
+	r.RequestURI = exchange.Request.URL
+
+	for name, values := range exchange.Request.Header {
+		r.Header[name] = values
+	}
+
+	resp, err := target(r)
+	if err != nil {
+		return "", fmt.Errorf("request failed: %w", err)
+	}
+
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return "", fmt.Errorf("could not read response: %w", err)
+	}
+
+	if resp.StatusCode != exchange.Response.Status {
+		return fmt.Sprintf("status %d, recorded %d", resp.StatusCode, exchange.Response.Status), nil
+	}
+
+	if !options.compareBody || exchange.Request.Truncated || exchange.Response.Truncated {
+		return "", nil
+	}
+
+	if !bytes.Equal(body, exchange.Response.Body) {
+		return fmt.Sprintf("body differs, got %d bytes, recorded %d bytes", len(body), len(exchange.Response.Body)), nil
+	}
+
+	return "", nil
+}
+
+func sleep(ctx context.Context, d time.Duration) error {
+	if d <= 0 {
+		return nil
+	}
+
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
@@ -0,0 +1,20 @@
+// Package respond has helpers to write HTTP responses.
+//
+// Deprecated: Use the render package instead, respond.JSON is the same as
+// render.JSON.
+package respond
+
+import (
+	"net/http"
+
+	"github.com/bombsimon/http-helpers/render"
+)
+
+// JSON writes v encoded as JSON with the passed status code. The content type
+// is set to application/json. See render.JSON for how encoding errors are
+// handled.
+//
+// Deprecated: Use render.JSON instead.
+func JSON(w http.ResponseWriter, status int, v interface{}) error {
+	return render.JSON(w, status, v)
+}
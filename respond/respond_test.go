@@ -0,0 +1,27 @@
+package respond
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func Test_JSON(t *testing.T) {
+	rec := httptest.NewRecorder()
+
+	if err := JSON(rec, http.StatusCreated, map[string]int{"id": 1}); err != nil {
+		t.Fatal(err)
+	}
+
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("unexpected status, got: %d, expected: %d", rec.Code, http.StatusCreated)
+	}
+
+	if ct := rec.Header().Get("Content-Type"); ct != "application/json; charset=utf-8" {
+		t.Fatalf("unexpected content type: %s", ct)
+	}
+
+	if rec.Body.String() != "{\"id\":1}\n" {
+		t.Fatalf("unexpected body: %s", rec.Body.String())
+	}
+}
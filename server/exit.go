@@ -0,0 +1,108 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+)
+
+// Exit codes returned by ExitCode so orchestrators and scripts can tell why the
+// server stopped without parsing logs.
+const (
+	// ExitOK is returned when the server was shut down and drained cleanly.
+	ExitOK = 0
+
+	// ExitError is returned for errors not covered by the other codes, e.g.
+	// the server stopping by itself with an error.
+	ExitError = 1
+
+	// ExitStartupFailed is returned when the server couldn't start, e.g.
+	// because the address was already in use.
+	ExitStartupFailed = 2
+
+	// ExitForcedClose is returned when the connections couldn't be drained
+	// within the wait time or the shutdown was forced by another signal.
+	ExitForcedClose = 3
+
+	// ExitHookFailed is returned when the server drained cleanly but a
+	// shutdown hook failed.
+	ExitHookFailed = 4
+)
+
+// ErrForcedClose is returned by Run when the connections couldn't be drained
+// within the wait time.
+var ErrForcedClose = errors.New("connections not drained within the wait time")
+
+// StartupError is returned by Run when the server couldn't start.
+type StartupError struct {
+	Err error
+}
+
+func (e *StartupError) Error() string {
+	return fmt.Sprintf("could not start server: %s", e.Err)
+}
+
+func (e *StartupError) Unwrap() error {
+	return e.Err
+}
+
+// HookError is returned by Run when a shutdown hook failed.
+type HookError struct {
+	Hook string
+	Err  error
+}
+
+func (e *HookError) Error() string {
+	return fmt.Sprintf("shutdown hook %s failed: %s", e.Hook, e.Err)
+}
+
+func (e *HookError) Unwrap() error {
+	return e.Err
+}
+
+// ExitCode returns the exit code for the error returned by Run. If the error
+// matches more than one code the most severe is returned, in the order
+// startup failure, forced close and hook failure.
+func ExitCode(err error) int {
+	var (
+		startupErr *StartupError
+		hookErr    *HookError
+	)
+
+	switch {
+	case err == nil:
+		return ExitOK
+	case errors.As(err, &startupErr):
+		return ExitStartupFailed
+	case errors.Is(err, ErrForcedClose):
+		return ExitForcedClose
+	case errors.As(err, &hookErr):
+		return ExitHookFailed
+	default:
+		return ExitError
+	}
+}
+
+// RunAndExit calls Run and exits the process with the exit code for the
+// returned error, see ExitCode. The error is logged before exiting.
+func RunAndExit(ctx context.Context, server *http.Server, opts ...Option) {
+	options := newOptions(opts...)
+
+	err := Run(ctx, server, opts...)
+	if err != nil {
+		options.logError("server stopped with error", "error", err)
+	}
+
+	options.exit(ExitCode(err))
+}
+
+// forcedClose wraps errors from a shutdown that didn't finish within the wait
+// time with ErrForcedClose.
+func forcedClose(err error) error {
+	if errors.Is(err, context.DeadlineExceeded) {
+		return fmt.Errorf("%w: %w", ErrForcedClose, err)
+	}
+
+	return err
+}
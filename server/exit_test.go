@@ -0,0 +1,71 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+)
+
+func Test_ExitCode(t *testing.T) {
+	hookErr := &HookError{Hook: "flush", Err: errors.New("boom")}
+
+	for _, tc := range []struct {
+		err      error
+		expected int
+	}{
+		{err: nil, expected: ExitOK},
+		{err: errors.New("serve failed"), expected: ExitError},
+		{err: &StartupError{Err: errors.New("address in use")}, expected: ExitStartupFailed},
+		{err: forcedClose(context.DeadlineExceeded), expected: ExitForcedClose},
+		{err: hookErr, expected: ExitHookFailed},
+		{err: errors.Join(hookErr, forcedClose(context.DeadlineExceeded)), expected: ExitForcedClose},
+		{err: fmt.Errorf("wrapped: %w", hookErr), expected: ExitHookFailed},
+	} {
+		if got := ExitCode(tc.err); got != tc.expected {
+			t.Fatalf("unexpected exit code for %v, got: %d, expected: %d", tc.err, got, tc.expected)
+		}
+	}
+}
+
+func Test_ShutdownErrors(t *testing.T) {
+	options := newOptions(
+		WithLogger(nil),
+		WithWaitTime(10*time.Millisecond),
+		OnDrainComplete("flush", time.Second, func(context.Context) error {
+			return errors.New("could not flush")
+		}),
+	)
+
+	err := shutdown(ShutdownFunc(func(ctx context.Context) error {
+		<-ctx.Done()
+		return ctx.Err()
+	}), options)
+
+	if !errors.Is(err, ErrForcedClose) {
+		t.Fatalf("expected forced close, got: %v", err)
+	}
+
+	var hookErr *HookError
+	if !errors.As(err, &hookErr) || hookErr.Hook != "flush" {
+		t.Fatalf("expected hook error, got: %v", err)
+	}
+}
+
+func Test_RunAndExit(t *testing.T) {
+	var code int
+
+	withExit := func(o *options) {
+		o.exit = func(c int) {
+			code = c
+		}
+	}
+
+	RunAndExit(context.Background(), &http.Server{Addr: "127.0.0.1:-1"}, WithLogger(nil), withExit)
+
+	if code != ExitStartupFailed {
+		t.Fatalf("unexpected exit code, got: %d, expected: %d", code, ExitStartupFailed)
+	}
+}
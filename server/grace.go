@@ -0,0 +1,98 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// ErrShuttingDown is the cause of request contexts canceled by
+// WithRequestGrace, see context.Cause.
+var ErrShuttingDown = errors.New("server shutting down")
+
+type shutdownKey struct{}
+
+type requestGraceOptions struct {
+	grace        time.Duration
+	shuttingDown chan struct{}
+	ctx          context.Context
+	cancel       context.CancelCauseFunc
+	once         sync.Once
+}
+
+// WithRequestGrace cancels the context of requests still in flight when the
+// grace period has passed since the server started draining, with
+// ErrShuttingDown as the cause. This lets long-running handlers, e.g. streams
+// and long polls, end cleanly before the wait time cuts their connections
+// off, so the grace should be shorter than the wait time. Handlers can use
+// ShuttingDown to stop even earlier. Enabling this sets the server
+// BaseContext, wrapping any BaseContext already set.
+func WithRequestGrace(grace time.Duration) Option {
+	return func(o *options) {
+		o.requestGrace = &requestGraceOptions{grace: grace}
+	}
+}
+
+// ShuttingDown returns a channel which is closed when the server serving the
+// request starts draining. It returns nil, which blocks forever in a select,
+// if the server isn't run with WithRequestGrace.
+func ShuttingDown(ctx context.Context) <-chan struct{} {
+	ch, _ := ctx.Value(shutdownKey{}).(chan struct{})
+	return ch
+}
+
+// propagateShutdown sets the server BaseContext so the request contexts can be
+// notified and canceled when shutting down if WithRequestGrace is used.
+func propagateShutdown(server *http.Server, options *options) {
+	g := options.requestGrace
+	if g == nil {
+		return
+	}
+
+	g.shuttingDown = make(chan struct{})
+	g.ctx, g.cancel = context.WithCancelCause(context.Background())
+
+	baseContext := server.BaseContext
+
+	server.BaseContext = func(l net.Listener) context.Context {
+		base := context.Background()
+		if baseContext != nil {
+			base = baseContext(l)
+		}
+
+		ctx, cancel := context.WithCancelCause(context.WithValue(base, shutdownKey{}, g.shuttingDown))
+		context.AfterFunc(g.ctx, func() {
+			cancel(context.Cause(g.ctx))
+		})
+
+		return ctx
+	}
+}
+
+// startRequestGrace notifies the requests that the server is draining and
+// cancels them after the grace period. The returned function cancels the
+// requests that are still in flight right away, e.g. when the wait time is
+// reached.
+func startRequestGrace(options *options) func() {
+	g := options.requestGrace
+	if g == nil || g.cancel == nil {
+		return func() {}
+	}
+
+	g.once.Do(func() {
+		close(g.shuttingDown)
+	})
+
+	timer := options.clock.AfterFunc(g.grace, func() {
+		options.logInfo("request grace period passed, canceling in-flight requests")
+		g.cancel(ErrShuttingDown)
+	})
+
+	return func() {
+		timer.Stop()
+		g.cancel(ErrShuttingDown)
+	}
+}
@@ -0,0 +1,88 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/bombsimon/http-helpers/clock"
+)
+
+func Test_RequestGrace(t *testing.T) {
+	var (
+		clk      = clock.NewFake(time.Now())
+		started  = make(chan struct{})
+		notified = make(chan struct{})
+		cause    = make(chan error, 1)
+		addrChan = make(chan net.Addr, 1)
+		runErr   = make(chan error, 1)
+	)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	server := &http.Server{
+		Addr: "127.0.0.1:0",
+		Handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			close(started)
+
+			<-ShuttingDown(r.Context())
+			close(notified)
+
+			<-r.Context().Done()
+			cause <- context.Cause(r.Context())
+		}),
+	}
+
+	go func() {
+		runErr <- Run(
+			ctx,
+			server,
+			WithLogger(nil),
+			WithClock(clk),
+			WithWaitTime(time.Minute),
+			WithRequestGrace(5*time.Second),
+			OnReady(func(addr net.Addr) { addrChan <- addr }),
+		)
+	}()
+
+	addr := <-addrChan
+
+	go func() {
+		resp, err := http.Get("http://" + addr.String())
+		if err == nil {
+			resp.Body.Close()
+		}
+	}()
+
+	<-started
+	cancel()
+	<-notified
+
+	select {
+	case <-cause:
+		t.Fatal("expected request to not be canceled before the grace period")
+	default:
+	}
+
+	// Wait for the wait time and the grace period timers.
+	clk.BlockUntil(2)
+	clk.Advance(5 * time.Second)
+
+	if err := <-cause; !errors.Is(err, ErrShuttingDown) {
+		t.Fatalf("unexpected cause, got: %v, expected: %s", err, ErrShuttingDown)
+	}
+
+	if err := <-runErr; err != nil {
+		t.Fatalf("expected the server to drain, got: %s", err)
+	}
+}
+
+func Test_ShuttingDownWithoutGrace(t *testing.T) {
+	if ch := ShuttingDown(context.Background()); ch != nil {
+		t.Fatal("expected nil channel without WithRequestGrace")
+	}
+}
@@ -0,0 +1,150 @@
+package server
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Shutdowner is anything that can be shut down gracefully. *http.Server
+// implements this interface.
+type Shutdowner interface {
+	Shutdown(ctx context.Context) error
+}
+
+// ShutdownFunc is a function implementing the Shutdowner interface.
+type ShutdownFunc func(ctx context.Context) error
+
+// Shutdown calls f(ctx).
+func (f ShutdownFunc) Shutdown(ctx context.Context) error {
+	return f(ctx)
+}
+
+// ShutdownErrors holds all the errors from a ShutdownGroup shutdown.
+type ShutdownErrors []error
+
+// Error implements the error interface.
+func (e ShutdownErrors) Error() string {
+	messages := make([]string, 0, len(e))
+	for _, err := range e {
+		messages = append(messages, err.Error())
+	}
+
+	return strings.Join(messages, "; ")
+}
+
+// Unwrap returns all the errors so they can be used with errors.Is and
+// errors.As.
+func (e ShutdownErrors) Unwrap() []error {
+	return e
+}
+
+type namedShutdowner struct {
+	name       string
+	shutdowner Shutdowner
+}
+
+// ShutdownGroup coordinates shutdown of multiple servers and other resources.
+// All members of the group are shut down concurrently.
+type ShutdownGroup struct {
+	mu      sync.Mutex
+	members []namedShutdowner
+}
+
+// NewShutdownGroup creates a new empty ShutdownGroup.
+func NewShutdownGroup() *ShutdownGroup {
+	return &ShutdownGroup{}
+}
+
+// Add adds a Shutdowner to the group.
+func (g *ShutdownGroup) Add(name string, s Shutdowner) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	g.members = append(g.members, namedShutdowner{
+		name:       name,
+		shutdowner: s,
+	})
+}
+
+// AddServer adds an HTTP server to the group.
+func (g *ShutdownGroup) AddServer(name string, server *http.Server) {
+	g.Add(name, server)
+}
+
+// AddCloser adds an io.Closer to the group. Since Close doesn't take a context
+// the group will stop waiting for it when the context is done but the Close
+// call itself will not be interrupted.
+func (g *ShutdownGroup) AddCloser(name string, closer io.Closer) {
+	g.Add(name, ShutdownFunc(func(ctx context.Context) error {
+		errCh := make(chan error, 1)
+
+		go func() {
+			errCh <- closer.Close()
+		}()
+
+		select {
+		case err := <-errCh:
+			return err
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}))
+}
+
+// AddFunc adds a shutdown function to the group.
+func (g *ShutdownGroup) AddFunc(name string, fn func(ctx context.Context) error) {
+	g.Add(name, ShutdownFunc(fn))
+}
+
+// Shutdown shuts down all members of the group concurrently and waits for all
+// of them to finish. All errors are returned as ShutdownErrors.
+func (g *ShutdownGroup) Shutdown(ctx context.Context) error {
+	g.mu.Lock()
+	members := make([]namedShutdowner, len(g.members))
+	copy(members, g.members)
+	g.mu.Unlock()
+
+	var (
+		wg   = sync.WaitGroup{}
+		mu   = sync.Mutex{}
+		errs ShutdownErrors
+	)
+
+	for _, member := range members {
+		wg.Add(1)
+
+		go func(member namedShutdowner) {
+			defer wg.Done()
+
+			if err := member.shutdowner.Shutdown(ctx); err != nil {
+				mu.Lock()
+				errs = append(errs, fmt.Errorf("%s: %w", member.name, err))
+				mu.Unlock()
+			}
+		}(member)
+	}
+
+	wg.Wait()
+
+	if len(errs) > 0 {
+		return errs
+	}
+
+	return nil
+}
+
+// GracefulShutdown will enable graceful shutdown for all members of the group
+// in the same way as the package level GracefulShutdown does for a single
+// server.
+func (g *ShutdownGroup) GracefulShutdown(
+	waitTime time.Duration,
+	logger ShutdownLogger,
+	opts ...Option,
+) chan struct{} {
+	return gracefulShutdown(g, waitTime, logger, opts...)
+}
@@ -0,0 +1,63 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+type closerFunc func() error
+
+func (f closerFunc) Close() error {
+	return f()
+}
+
+func Test_ShutdownGroup(t *testing.T) {
+	var (
+		group     = NewShutdownGroup()
+		calls     int32
+		errFailed = errors.New("failed")
+		release   = make(chan struct{})
+	)
+
+	group.AddServer("public", &http.Server{})
+	group.AddServer("admin", &http.Server{})
+	group.AddCloser("closer", closerFunc(func() error {
+		atomic.AddInt32(&calls, 1)
+		return nil
+	}))
+	group.AddFunc("blocking", func(ctx context.Context) error {
+		atomic.AddInt32(&calls, 1)
+
+		// This will only be released if all members are shut down
+		// concurrently.
+		<-release
+
+		return nil
+	})
+	group.AddFunc("releasing", func(ctx context.Context) error {
+		atomic.AddInt32(&calls, 1)
+		close(release)
+
+		return errFailed
+	})
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	err := group.Shutdown(ctx)
+	if !errors.Is(err, errFailed) {
+		t.Fatalf("expected aggregated error to contain failure, got: %v", err)
+	}
+
+	if err.Error() != "releasing: failed" {
+		t.Fatalf("unexpected error message: %s", err)
+	}
+
+	if atomic.LoadInt32(&calls) != 3 {
+		t.Fatalf("unexpected number of calls: %d", calls)
+	}
+}
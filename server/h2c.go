@@ -0,0 +1,31 @@
+package server
+
+import (
+	"net/http"
+
+	"golang.org/x/net/http2"
+	"golang.org/x/net/http2/h2c"
+)
+
+// WithH2C enables HTTP/2 without TLS (h2c) for servers started with Run. This
+// is useful for internal HTTP/2 clients such as gRPC gateways that don't use
+// TLS.
+func WithH2C() Option {
+	return func(o *options) {
+		o.h2c = true
+	}
+}
+
+// enableH2C returns a wrapper for the server's handler serving h2c. The HTTP/2
+// server is configured on the HTTP server so h2c connections are gracefully
+// closed when the server shuts down.
+func enableH2C(server *http.Server) (func(http.Handler) http.Handler, error) {
+	h2s := &http2.Server{}
+	if err := http2.ConfigureServer(server, h2s); err != nil {
+		return nil, err
+	}
+
+	return func(handler http.Handler) http.Handler {
+		return h2c.NewHandler(handler, h2s)
+	}, nil
+}
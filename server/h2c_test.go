@@ -0,0 +1,55 @@
+package server
+
+import (
+	"context"
+	"crypto/tls"
+	"net"
+	"net/http"
+	"testing"
+
+	"golang.org/x/net/http2"
+)
+
+func Test_H2C(t *testing.T) {
+	var protoMajor int
+
+	ctx, cancel := context.WithCancel(context.Background())
+
+	err := Run(
+		ctx,
+		&http.Server{
+			Addr: "127.0.0.1:0",
+			Handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				protoMajor = r.ProtoMajor
+			}),
+		},
+		WithH2C(),
+		OnReady(func(addr net.Addr) {
+			defer cancel()
+
+			client := &http.Client{
+				Transport: &http2.Transport{
+					AllowHTTP: true,
+					DialTLSContext: func(ctx context.Context, network, addr string, _ *tls.Config) (net.Conn, error) {
+						return (&net.Dialer{}).DialContext(ctx, network, addr)
+					},
+				},
+			}
+
+			response, err := client.Get("http://" + addr.String())
+			if err != nil {
+				t.Errorf("could not send h2c request: %s", err)
+				return
+			}
+
+			_ = response.Body.Close()
+		}),
+	)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	if protoMajor != 2 {
+		t.Fatalf("expected HTTP/2 request, got HTTP/%d", protoMajor)
+	}
+}
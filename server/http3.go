@@ -0,0 +1,71 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+)
+
+// altSvcMaxAge is how long, in seconds, clients may remember that HTTP/3 is
+// available.
+const altSvcMaxAge = 24 * 60 * 60
+
+// HTTP3Server is an HTTP/3 server serving QUIC on a UDP socket, e.g.
+// *http3.Server from github.com/quic-go/quic-go/http3. Serve should return
+// http.ErrServerClosed after Shutdown.
+type HTTP3Server interface {
+	Serve(conn net.PacketConn) error
+	Shutdown(ctx context.Context) error
+}
+
+// WithHTTP3 makes RunTLS also serve HTTP/3 with the server on a UDP socket
+// bound to the same address as the TCP listener. Responses over TCP get an
+// Alt-Svc header advertising HTTP/3 on the port so clients can switch, and the
+// HTTP/3 server is shut down gracefully together with the HTTP server.
+// Configure the HTTP/3 server with the same handler and a TLS config for QUIC,
+// e.g.
+//
+//	h3 := &http3.Server{
+//		Handler:   handler,
+//		TLSConfig: http3.ConfigureTLSConfig(tlsConfig),
+//	}
+func WithHTTP3(h3 HTTP3Server) Option {
+	return func(o *options) {
+		o.http3 = h3
+	}
+}
+
+// serveHTTP3 serves the HTTP/3 server on a UDP socket bound to the address of
+// the TCP listener. It returns a Shutdowner shutting down the HTTP/3 server and
+// closing the socket, and a wrapper for the HTTP server's handler advertising
+// HTTP/3 with Alt-Svc.
+func serveHTTP3(addr net.Addr, options *options) (Shutdowner, func(http.Handler) http.Handler, error) {
+	conn, err := net.ListenPacket("udp", addr.String())
+	if err != nil {
+		return nil, nil, err
+	}
+
+	port := conn.LocalAddr().(*net.UDPAddr).Port
+	altSvc := fmt.Sprintf(`h3=":%d"; ma=%d`, port, altSvcMaxAge)
+
+	advertise := func(handler http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Alt-Svc", altSvc)
+			handler.ServeHTTP(w, r)
+		})
+	}
+
+	h3 := options.http3
+
+	go func() {
+		if err := h3.Serve(conn); err != nil && !errors.Is(err, http.ErrServerClosed) && !errors.Is(err, net.ErrClosed) {
+			options.logError("http3 server stopped", "error", err)
+		}
+	}()
+
+	return ShutdownFunc(func(ctx context.Context) error {
+		return errors.Join(h3.Shutdown(ctx), conn.Close())
+	}), advertise, nil
+}
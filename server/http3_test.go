@@ -0,0 +1,94 @@
+package server
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"net"
+	"net/http"
+	"sync"
+	"testing"
+)
+
+type fakeHTTP3Server struct {
+	mu       sync.Mutex
+	addr     net.Addr
+	shutdown chan struct{}
+}
+
+func (s *fakeHTTP3Server) Serve(conn net.PacketConn) error {
+	s.mu.Lock()
+	s.addr = conn.LocalAddr()
+	s.mu.Unlock()
+
+	<-s.shutdown
+
+	return http.ErrServerClosed
+}
+
+func (s *fakeHTTP3Server) Shutdown(context.Context) error {
+	close(s.shutdown)
+	return nil
+}
+
+func Test_RunTLSWithHTTP3(t *testing.T) {
+	certFile, keyFile := writeSelfSignedCert(t)
+	h3 := &fakeHTTP3Server{shutdown: make(chan struct{})}
+
+	var altSvc string
+
+	ctx, cancel := context.WithCancel(context.Background())
+
+	err := RunTLS(
+		ctx,
+		&http.Server{
+			Addr:    "127.0.0.1:0",
+			Handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}),
+		},
+		WithCertFiles(certFile, keyFile),
+		WithHTTP3(h3),
+		OnReady(func(addr net.Addr) {
+			defer cancel()
+
+			client := &http.Client{
+				Transport: &http.Transport{
+					TLSClientConfig: &tls.Config{
+						//nolint:gosec // Self signed certificate in test.
+						InsecureSkipVerify: true,
+					},
+				},
+			}
+
+			response, err := client.Get("https://" + addr.String())
+			if err != nil {
+				t.Errorf("could not send https request: %s", err)
+				return
+			}
+
+			_ = response.Body.Close()
+
+			altSvc = response.Header.Get("Alt-Svc")
+			expected := fmt.Sprintf(`h3=":%d"; ma=86400`, addr.(*net.TCPAddr).Port)
+
+			if altSvc != expected {
+				t.Errorf("unexpected Alt-Svc, got: %s, expected: %s", altSvc, expected)
+			}
+
+			h3.mu.Lock()
+			defer h3.mu.Unlock()
+
+			if h3.addr == nil || h3.addr.Network() != "udp" || h3.addr.(*net.UDPAddr).Port != addr.(*net.TCPAddr).Port {
+				t.Errorf("expected http3 to be served on the same UDP port, got: %v", h3.addr)
+			}
+		}),
+	)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	select {
+	case <-h3.shutdown:
+	default:
+		t.Fatal("expected http3 server to be shut down")
+	}
+}
@@ -0,0 +1,97 @@
+package server
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"strings"
+)
+
+// WithSlogLogger sets the logger used to log the shutdown process. This
+// replaces any logger set with WithLogger. Run and RunTLS use slog.Default()
+// if no logger is set.
+func WithSlogLogger(logger *slog.Logger) Option {
+	return func(o *options) {
+		o.logger = logger
+	}
+}
+
+// shutdownLoggerHandler is a slog.Handler writing records to a ShutdownLogger.
+// The attributes are added to the message as key=value pairs.
+type shutdownLoggerHandler struct {
+	logger ShutdownLogger
+	attrs  []slog.Attr
+	group  string
+}
+
+func (h *shutdownLoggerHandler) Enabled(_ context.Context, level slog.Level) bool {
+	return level >= slog.LevelInfo
+}
+
+func (h *shutdownLoggerHandler) Handle(_ context.Context, r slog.Record) error {
+	var sb strings.Builder
+
+	sb.WriteString(r.Message)
+
+	for _, attr := range h.attrs {
+		writeAttr(&sb, "", attr)
+	}
+
+	r.Attrs(func(attr slog.Attr) bool {
+		writeAttr(&sb, h.group, attr)
+		return true
+	})
+
+	if r.Level >= slog.LevelError {
+		h.logger.Errorf("%s", sb.String())
+	} else {
+		h.logger.Infof("%s", sb.String())
+	}
+
+	return nil
+}
+
+func (h *shutdownLoggerHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	withAttrs := make([]slog.Attr, 0, len(h.attrs)+len(attrs))
+	withAttrs = append(withAttrs, h.attrs...)
+
+	for _, attr := range attrs {
+		if h.group != "" {
+			attr.Key = h.group + "." + attr.Key
+		}
+
+		withAttrs = append(withAttrs, attr)
+	}
+
+	return &shutdownLoggerHandler{logger: h.logger, attrs: withAttrs, group: h.group}
+}
+
+func (h *shutdownLoggerHandler) WithGroup(name string) slog.Handler {
+	if h.group != "" {
+		name = h.group + "." + name
+	}
+
+	return &shutdownLoggerHandler{logger: h.logger, attrs: h.attrs, group: name}
+}
+
+func writeAttr(sb *strings.Builder, group string, attr slog.Attr) {
+	attr.Value = attr.Value.Resolve()
+	if attr.Equal(slog.Attr{}) {
+		return
+	}
+
+	key := attr.Key
+	if group != "" {
+		key = group + "." + key
+	}
+
+	if attr.Value.Kind() == slog.KindGroup {
+		for _, a := range attr.Value.Group() {
+			writeAttr(sb, key, a)
+		}
+
+		return
+	}
+
+	fmt.Fprintf(sb, " %s=%v", key, attr.Value.Any())
+}
@@ -0,0 +1,59 @@
+package server
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strings"
+	"testing"
+	"time"
+)
+
+type recordingLogger struct {
+	infos  []string
+	errors []string
+}
+
+func (l *recordingLogger) Infof(format string, args ...interface{}) {
+	l.infos = append(l.infos, fmt.Sprintf(format, args...))
+}
+
+func (l *recordingLogger) Errorf(format string, args ...interface{}) {
+	l.errors = append(l.errors, fmt.Sprintf(format, args...))
+}
+
+func Test_ShutdownLoggerAdapter(t *testing.T) {
+	logger := &recordingLogger{}
+	options := newOptions(
+		WithLogger(logger),
+		OnDrainComplete("db", time.Second, func(_ context.Context) error {
+			return errors.New("boom")
+		}),
+	)
+
+	_ = shutdown(ShutdownFunc(func(_ context.Context) error { return nil }), options)
+
+	if len(logger.infos) == 0 || logger.infos[0] != "shutting down server, draining connections" {
+		t.Fatalf("unexpected info logs: %v", logger.infos)
+	}
+
+	if len(logger.errors) != 1 || logger.errors[0] != "shutdown hook failed hook=db error=boom" {
+		t.Fatalf("unexpected error logs: %v", logger.errors)
+	}
+}
+
+func Test_WithSlogLogger(t *testing.T) {
+	buf := &bytes.Buffer{}
+	options := newOptions(
+		WithLogger(nil),
+		WithSlogLogger(slog.New(slog.NewTextHandler(buf, nil))),
+	)
+
+	_ = shutdown(ShutdownFunc(func(_ context.Context) error { return nil }), options)
+
+	if !strings.Contains(buf.String(), `level=INFO msg="shutting down server, draining connections"`) {
+		t.Fatalf("unexpected log output: %s", buf.String())
+	}
+}
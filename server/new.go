@@ -0,0 +1,79 @@
+package server
+
+import (
+	"net/http"
+	"time"
+)
+
+type httpOptions struct {
+	readHeaderTimeout time.Duration
+	readTimeout       time.Duration
+	writeTimeout      time.Duration
+	idleTimeout       time.Duration
+	maxHeaderBytes    int
+}
+
+// New creates a *http.Server with timeouts set to safe defaults to protect
+// against slow clients (slowloris), which the zero value server doesn't. The
+// defaults are:
+//
+//   - ReadHeaderTimeout: 5 seconds
+//   - ReadTimeout: 30 seconds
+//   - WriteTimeout: 30 seconds
+//   - IdleTimeout: 120 seconds
+//   - MaxHeaderBytes: 1 MB
+//
+// Each of them can be changed with the corresponding option, where zero means
+// no limit (or the standard library default for MaxHeaderBytes). Other options
+// are ignored.
+func New(addr string, handler http.Handler, opts ...Option) *http.Server {
+	options := newOptions(opts...)
+
+	return &http.Server{
+		Addr:              addr,
+		Handler:           handler,
+		ReadHeaderTimeout: options.http.readHeaderTimeout,
+		ReadTimeout:       options.http.readTimeout,
+		WriteTimeout:      options.http.writeTimeout,
+		IdleTimeout:       options.http.idleTimeout,
+		MaxHeaderBytes:    options.http.maxHeaderBytes,
+	}
+}
+
+// WithReadHeaderTimeout sets the time allowed to read the request headers.
+func WithReadHeaderTimeout(timeout time.Duration) Option {
+	return func(o *options) {
+		o.http.readHeaderTimeout = timeout
+	}
+}
+
+// WithReadTimeout sets the time allowed to read the entire request, including
+// the body.
+func WithReadTimeout(timeout time.Duration) Option {
+	return func(o *options) {
+		o.http.readTimeout = timeout
+	}
+}
+
+// WithWriteTimeout sets the time allowed to write the response. Handlers
+// streaming long responses should set this to zero or extend the deadline
+// with http.ResponseController.
+func WithWriteTimeout(timeout time.Duration) Option {
+	return func(o *options) {
+		o.http.writeTimeout = timeout
+	}
+}
+
+// WithIdleTimeout sets the time to keep idle keep-alive connections open.
+func WithIdleTimeout(timeout time.Duration) Option {
+	return func(o *options) {
+		o.http.idleTimeout = timeout
+	}
+}
+
+// WithMaxHeaderBytes sets the maximum size of the request headers.
+func WithMaxHeaderBytes(n int) Option {
+	return func(o *options) {
+		o.http.maxHeaderBytes = n
+	}
+}
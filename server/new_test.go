@@ -0,0 +1,57 @@
+package server
+
+import (
+	"bufio"
+	"net"
+	"net/http"
+	"testing"
+	"time"
+)
+
+func Test_New(t *testing.T) {
+	server := New(":8080", http.NotFoundHandler(), WithWriteTimeout(0))
+
+	if server.ReadHeaderTimeout != 5*time.Second {
+		t.Fatalf("unexpected read header timeout: %s", server.ReadHeaderTimeout)
+	}
+
+	if server.WriteTimeout != 0 {
+		t.Fatalf("unexpected write timeout: %s", server.WriteTimeout)
+	}
+
+	if server.MaxHeaderBytes != 1<<20 {
+		t.Fatalf("unexpected max header bytes: %d", server.MaxHeaderBytes)
+	}
+}
+
+func Test_NewSlowHeaders(t *testing.T) {
+	server := New("127.0.0.1:0", http.NotFoundHandler(), WithReadHeaderTimeout(100*time.Millisecond))
+
+	addr, _, err := ListenAndServeNotify(server)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer server.Close()
+
+	conn, err := net.Dial("tcp", addr.String())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer conn.Close()
+
+	// Start a request but never finish the headers.
+	if _, err := conn.Write([]byte("GET / HTTP/1.1\r\nHost: localhost\r\n")); err != nil {
+		t.Fatal(err)
+	}
+
+	_ = conn.SetReadDeadline(time.Now().Add(2 * time.Second))
+
+	// The server should close the connection once the timeout is reached.
+	if _, err := bufio.NewReader(conn).ReadString('\n'); err == nil {
+		t.Fatal("expected connection to be closed")
+	} else if ne, ok := err.(net.Error); ok && ne.Timeout() {
+		t.Fatal("server didn't close the connection")
+	}
+}
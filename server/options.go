@@ -0,0 +1,171 @@
+package server
+
+import (
+	"context"
+	"log/slog"
+	"net"
+	"net/netip"
+	"os"
+	"syscall"
+	"time"
+
+	"github.com/bombsimon/http-helpers/clock"
+)
+
+// ShutdownHookFunc is a function executed as part of the graceful shutdown.
+// The passed context will be cancelled when the hook's timeout is reached.
+type ShutdownHookFunc func(ctx context.Context) error
+
+// Option is an option used to configure the server helpers.
+type Option func(*options)
+
+type shutdownHook struct {
+	name    string
+	timeout time.Duration
+	fn      ShutdownHookFunc
+}
+
+type options struct {
+	waitTime        time.Duration
+	logger          *slog.Logger
+	onShutdownStart []shutdownHook
+	onDrainComplete []shutdownHook
+	signals         []os.Signal
+	signalChannel   <-chan os.Signal
+	forceQuit       bool
+	exit            func(code int)
+	onReady         []func(addr net.Addr)
+	tls             tlsOptions
+	http            httpOptions
+	h2c             bool
+	http3           HTTP3Server
+	systemd         bool
+	progress        *progressOptions
+	restart         *restartOptions
+	requestGrace    *requestGraceOptions
+	proxyProtocol   []netip.Prefix
+	clock           clock.Clock
+}
+
+func newOptions(opts ...Option) *options {
+	o := &options{
+		waitTime:  10 * time.Second,
+		logger:    slog.Default(),
+		signals:   []os.Signal{syscall.SIGTERM, syscall.SIGINT},
+		forceQuit: true,
+		exit:      os.Exit,
+		clock:     clock.Real(),
+		tls: tlsOptions{
+			challengeAddr: ":http",
+		},
+		http: httpOptions{
+			readHeaderTimeout: 5 * time.Second,
+			readTimeout:       30 * time.Second,
+			writeTimeout:      30 * time.Second,
+			idleTimeout:       120 * time.Second,
+			maxHeaderBytes:    1 << 20,
+		},
+	}
+
+	for _, opt := range opts {
+		opt(o)
+	}
+
+	return o
+}
+
+// WithWaitTime sets the maximum time to wait for connections to drain when
+// shutting down. Defaults to 10 seconds.
+func WithWaitTime(waitTime time.Duration) Option {
+	return func(o *options) {
+		o.waitTime = waitTime
+	}
+}
+
+// WithClock sets the clock used for the wait time, shutdown hook timeouts and
+// progress reporting. This is useful to test the shutdown with a clock.Fake
+// instead of waiting for real timeouts. Defaults to clock.Real().
+func WithClock(c clock.Clock) Option {
+	return func(o *options) {
+		o.clock = c
+	}
+}
+
+// WithLogger sets the logger used to log the shutdown process. Passing nil
+// disables logging. Use WithSlogLogger to log with a *slog.Logger.
+func WithLogger(logger ShutdownLogger) Option {
+	return func(o *options) {
+		if logger == nil {
+			o.logger = nil
+			return
+		}
+
+		o.logger = slog.New(&shutdownLoggerHandler{logger: logger})
+	}
+}
+
+// WithSignals sets the signals that will trigger the shutdown. This replaces
+// the default signals which are SIGTERM and SIGINT. Passing no signals makes
+// Run only shut down the server when the context is done, or a value is sent
+// on the channel set with WithSignalChannel.
+func WithSignals(signals ...os.Signal) Option {
+	return func(o *options) {
+		o.signals = signals
+	}
+}
+
+// WithoutForceQuit disables the default behavior where receiving a second
+// signal while shutting down exits the process immediately.
+func WithoutForceQuit() Option {
+	return func(o *options) {
+		o.forceQuit = false
+	}
+}
+
+// OnShutdownStart registers a hook that will be executed when the shutdown
+// starts, before the server stops accepting new connections. This is a good
+// place to deregister from service discovery. Hooks are executed in the order
+// they're registered and each hook gets its own timeout.
+func OnShutdownStart(name string, timeout time.Duration, fn ShutdownHookFunc) Option {
+	return func(o *options) {
+		o.onShutdownStart = append(o.onShutdownStart, shutdownHook{
+			name:    name,
+			timeout: timeout,
+			fn:      fn,
+		})
+	}
+}
+
+// OnDrainComplete registers a hook that will be executed after all connections
+// are drained (or the wait time is reached). This is a good place to flush
+// buffers or close database pools. Hooks are executed in the order they're
+// registered and each hook gets its own timeout.
+func OnDrainComplete(name string, timeout time.Duration, fn ShutdownHookFunc) Option {
+	return func(o *options) {
+		o.onDrainComplete = append(o.onDrainComplete, shutdownHook{
+			name:    name,
+			timeout: timeout,
+			fn:      fn,
+		})
+	}
+}
+
+// OnReady registers a callback that will be called with the bound address once
+// the server is accepting connections.
+func OnReady(fn func(addr net.Addr)) Option {
+	return func(o *options) {
+		o.onReady = append(o.onReady, fn)
+	}
+}
+
+func (o *options) logInfo(msg string, args ...any) {
+	if o.logger != nil {
+		o.logger.Info(msg, args...)
+	}
+}
+
+func (o *options) logError(msg string, args ...any) {
+	if o.logger != nil {
+		o.logger.Error(msg, args...)
+	}
+}
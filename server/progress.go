@@ -0,0 +1,141 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"sync/atomic"
+	"time"
+)
+
+// ShutdownProgress describes the progress of a graceful shutdown.
+type ShutdownProgress struct {
+	// InFlight is the number of requests still being processed. It's only
+	// tracked for servers passed to GracefulShutdown, Run or RunTLS.
+	InFlight int64
+
+	// Elapsed is the time since the server started draining.
+	Elapsed time.Duration
+
+	// Done is true for the final report when the shutdown has finished.
+	Done bool
+
+	// TimedOut is true if the wait time was reached before all connections
+	// were drained. Only set for the final report.
+	TimedOut bool
+}
+
+type progressOptions struct {
+	interval time.Duration
+	fn       func(ShutdownProgress)
+	inFlight atomic.Int64
+}
+
+// WithShutdownProgress reports the progress of the shutdown every interval
+// while draining and once when the shutdown is done. The progress is logged
+// and passed to fn, if not nil, which can be used to e.g. update metrics.
+// Enabling this wraps the server handler to count the in-flight requests.
+func WithShutdownProgress(interval time.Duration, fn func(ShutdownProgress)) Option {
+	return func(o *options) {
+		o.progress = &progressOptions{
+			interval: interval,
+			fn:       fn,
+		}
+	}
+}
+
+// trackInFlight returns a wrapper for the server handler counting the
+// in-flight requests, or nil if progress reporting isn't enabled.
+func trackInFlight(options *options) func(http.Handler) http.Handler {
+	if options.progress == nil {
+		return nil
+	}
+
+	inFlight := &options.progress.inFlight
+
+	return func(handler http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			inFlight.Add(1)
+			defer inFlight.Add(-1)
+
+			handler.ServeHTTP(w, r)
+		})
+	}
+}
+
+// reportProgress reports the progress every interval until the returned
+// function is called with the shutdown error, which makes the final report.
+func reportProgress(options *options) func(err error) {
+	start := options.clock.Now()
+
+	if options.progress == nil {
+		return func(err error) {
+			logShutdownResult(ShutdownProgress{
+				Elapsed:  options.clock.Since(start),
+				Done:     true,
+				TimedOut: errors.Is(err, context.DeadlineExceeded),
+			}, options)
+		}
+	}
+
+	progress := options.progress
+	stop := make(chan struct{})
+	stopped := make(chan struct{})
+
+	report := func(p ShutdownProgress) {
+		if progress.fn != nil {
+			progress.fn(p)
+		}
+	}
+
+	go func() {
+		defer close(stopped)
+
+		if progress.interval <= 0 {
+			<-stop
+			return
+		}
+
+		ticker := options.clock.NewTicker(progress.interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ticker.C():
+				p := ShutdownProgress{
+					InFlight: progress.inFlight.Load(),
+					Elapsed:  options.clock.Since(start),
+				}
+
+				options.logInfo("draining connections", "in_flight", p.InFlight, "elapsed", p.Elapsed)
+				report(p)
+			case <-stop:
+				return
+			}
+		}
+	}()
+
+	return func(err error) {
+		close(stop)
+		<-stopped
+
+		p := ShutdownProgress{
+			InFlight: progress.inFlight.Load(),
+			Elapsed:  options.clock.Since(start),
+			Done:     true,
+			TimedOut: errors.Is(err, context.DeadlineExceeded),
+		}
+
+		logShutdownResult(p, options)
+		report(p)
+	}
+}
+
+func logShutdownResult(p ShutdownProgress, options *options) {
+	if p.TimedOut {
+		options.logError("wait time reached before connections were drained", "in_flight", p.InFlight, "elapsed", p.Elapsed)
+		return
+	}
+
+	options.logInfo("all connections drained", "elapsed", p.Elapsed)
+}
@@ -0,0 +1,111 @@
+package server
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/bombsimon/http-helpers/clock"
+)
+
+func Test_ShutdownProgress(t *testing.T) {
+	const (
+		interval = 50 * time.Millisecond
+		waitTime = 10 * time.Second
+	)
+
+	tests := []struct {
+		name             string
+		timeout          bool
+		expectedTimedOut bool
+	}{
+		{
+			name:             "drained",
+			expectedTimedOut: false,
+		},
+		{
+			name:             "timed out",
+			timeout:          true,
+			expectedTimedOut: true,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			var (
+				clk      = clock.NewFake(time.Now())
+				reports  = make(chan ShutdownProgress, 1000)
+				started  = make(chan struct{})
+				release  = make(chan struct{})
+				addrChan = make(chan net.Addr, 1)
+				runErr   = make(chan error, 1)
+			)
+
+			defer close(release)
+
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+
+			server := &http.Server{
+				Addr: "127.0.0.1:0",
+				Handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+					close(started)
+					<-release
+				}),
+			}
+
+			go func() {
+				runErr <- Run(
+					ctx,
+					server,
+					WithLogger(nil),
+					WithClock(clk),
+					WithWaitTime(waitTime),
+					OnReady(func(addr net.Addr) { addrChan <- addr }),
+					WithShutdownProgress(interval, func(p ShutdownProgress) {
+						reports <- p
+					}),
+				)
+			}()
+
+			addr := <-addrChan
+
+			go func() {
+				resp, err := http.Get("http://" + addr.String())
+				if err == nil {
+					resp.Body.Close()
+				}
+			}()
+
+			<-started
+			cancel()
+
+			// Wait for the shutdown timeout and the progress ticker.
+			clk.BlockUntil(2)
+			clk.Advance(interval)
+
+			if first := <-reports; first.InFlight != 1 || first.Done || first.Elapsed != interval {
+				t.Fatalf("unexpected first report: %+v", first)
+			}
+
+			if tc.timeout {
+				clk.Advance(waitTime)
+			} else {
+				release <- struct{}{}
+			}
+
+			<-runErr
+
+			var last ShutdownProgress
+			for len(reports) > 0 {
+				last = <-reports
+			}
+
+			if !last.Done || last.TimedOut != tc.expectedTimedOut {
+				t.Fatalf("unexpected final report: %+v", last)
+			}
+		})
+	}
+}
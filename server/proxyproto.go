@@ -0,0 +1,242 @@
+package server
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/netip"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// proxyHeaderTimeout is the time a trusted peer has to send the PROXY
+// protocol header.
+const proxyHeaderTimeout = 5 * time.Second
+
+// proxyV2Signature starts every PROXY protocol v2 header.
+var proxyV2Signature = []byte("\r\n\r\n\x00\r\nQUIT\n")
+
+// ErrInvalidProxyHeader is returned when reading from a connection with an
+// invalid PROXY protocol header.
+var ErrInvalidProxyHeader = errors.New("invalid PROXY protocol header")
+
+// WithProxyProtocol accepts the PROXY protocol header, version 1 and 2, sent by
+// load balancers such as HAProxy and AWS NLB in front of the server. The
+// header is only read from connections from the trusted networks, and the
+// client address in it is used as the remote address of the connection and
+// thereby r.RemoteAddr. Connections from other addresses are served as is.
+//
+// The RealIP middleware only trusts X-Forwarded-For from r.RemoteAddr, which
+// now is the client, so pass the load balancers to WithTrustedProxies as well
+// only if they also set X-Forwarded-For.
+func WithProxyProtocol(trusted ...netip.Prefix) Option {
+	return func(o *options) {
+		o.proxyProtocol = append(o.proxyProtocol, trusted...)
+	}
+}
+
+// ProxyProtocolListener wraps the listener to read the PROXY protocol header
+// from connections from the trusted networks, see WithProxyProtocol. The
+// header is read by the connection's first Read or RemoteAddr call so a slow
+// peer doesn't block Accept.
+func ProxyProtocolListener(listener net.Listener, trusted ...netip.Prefix) net.Listener {
+	return &proxyListener{Listener: listener, trusted: trusted}
+}
+
+type proxyListener struct {
+	net.Listener
+	trusted []netip.Prefix
+}
+
+func (l *proxyListener) Accept() (net.Conn, error) {
+	conn, err := l.Listener.Accept()
+	if err != nil {
+		return nil, err
+	}
+
+	addr, ok := conn.RemoteAddr().(*net.TCPAddr)
+	if !ok || !l.isTrusted(addr.AddrPort().Addr().Unmap()) {
+		return conn, nil
+	}
+
+	return &proxyConn{Conn: conn, reader: bufio.NewReader(conn)}, nil
+}
+
+func (l *proxyListener) isTrusted(addr netip.Addr) bool {
+	for _, prefix := range l.trusted {
+		if prefix.Contains(addr) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// proxyConn is a connection from a trusted peer which may start with a PROXY
+// protocol header.
+type proxyConn struct {
+	net.Conn
+	reader *bufio.Reader
+
+	once       sync.Once
+	remoteAddr net.Addr
+	err        error
+}
+
+func (c *proxyConn) Read(b []byte) (int, error) {
+	c.once.Do(c.readHeader)
+
+	if c.err != nil {
+		return 0, c.err
+	}
+
+	return c.reader.Read(b)
+}
+
+// RemoteAddr returns the client address from the PROXY protocol header or
+// the address of the peer if there's no header.
+func (c *proxyConn) RemoteAddr() net.Addr {
+	c.once.Do(c.readHeader)
+
+	if c.remoteAddr != nil {
+		return c.remoteAddr
+	}
+
+	return c.Conn.RemoteAddr()
+}
+
+func (c *proxyConn) readHeader() {
+	if err := c.Conn.SetReadDeadline(time.Now().Add(proxyHeaderTimeout)); err != nil {
+		c.err = err
+		return
+	}
+
+	c.remoteAddr, c.err = readProxyHeader(c.reader)
+
+	if err := c.Conn.SetReadDeadline(time.Time{}); err != nil && c.err == nil {
+		c.err = err
+	}
+}
+
+// readProxyHeader reads a PROXY protocol header, if the connection starts with
+// one, and returns the client address. A nil address is returned if there's
+// no header or it doesn't contain an address, e.g. for health checks.
+func readProxyHeader(r *bufio.Reader) (net.Addr, error) {
+	first, err := r.Peek(1)
+	if err != nil {
+		return nil, err
+	}
+
+	switch first[0] {
+	case 'P':
+		if prefix, err := r.Peek(6); err == nil && string(prefix) == "PROXY " {
+			return readProxyV1(r)
+		}
+	case '\r':
+		if prefix, err := r.Peek(len(proxyV2Signature)); err == nil && bytes.Equal(prefix, proxyV2Signature) {
+			return readProxyV2(r)
+		}
+	}
+
+	return nil, nil
+}
+
+// readProxyV1 reads a header such as "PROXY TCP4 192.0.2.1 10.0.0.1 56324
+// 443\r\n".
+func readProxyV1(r *bufio.Reader) (net.Addr, error) {
+	// The longest valid header is 107 bytes.
+	var line []byte
+
+	for len(line) <= 107 {
+		b, err := r.ReadByte()
+		if err != nil {
+			return nil, err
+		}
+
+		line = append(line, b)
+		if b == '\n' {
+			break
+		}
+	}
+
+	fields := strings.Fields(strings.TrimSuffix(string(line), "\r\n"))
+	if !bytes.HasSuffix(line, []byte("\r\n")) || len(fields) < 2 {
+		return nil, ErrInvalidProxyHeader
+	}
+
+	if fields[1] == "UNKNOWN" {
+		return nil, nil
+	}
+
+	if len(fields) != 6 || fields[1] != "TCP4" && fields[1] != "TCP6" {
+		return nil, ErrInvalidProxyHeader
+	}
+
+	addr, err := netip.ParseAddr(fields[2])
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidProxyHeader, err)
+	}
+
+	port, err := strconv.ParseUint(fields[4], 10, 16)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidProxyHeader, err)
+	}
+
+	return net.TCPAddrFromAddrPort(netip.AddrPortFrom(addr, uint16(port))), nil
+}
+
+// readProxyV2 reads a binary header.
+func readProxyV2(r *bufio.Reader) (net.Addr, error) {
+	header := make([]byte, len(proxyV2Signature)+4)
+	if _, err := io.ReadFull(r, header); err != nil {
+		return nil, err
+	}
+
+	versionCommand, family := header[12], header[13]
+	length := int(binary.BigEndian.Uint16(header[14:]))
+
+	payload := make([]byte, length)
+	if _, err := io.ReadFull(r, payload); err != nil {
+		return nil, err
+	}
+
+	if versionCommand>>4 != 2 {
+		return nil, ErrInvalidProxyHeader
+	}
+
+	// LOCAL connections, e.g. health checks from the load balancer, and
+	// unsupported families keep the address of the peer.
+	switch versionCommand & 0x0f {
+	case 0:
+		return nil, nil
+	case 1:
+	default:
+		return nil, ErrInvalidProxyHeader
+	}
+
+	var addrLen int
+
+	switch family >> 4 {
+	case 1:
+		addrLen = 4
+	case 2:
+		addrLen = 16
+	default:
+		return nil, nil
+	}
+
+	if len(payload) < 2*addrLen+4 {
+		return nil, ErrInvalidProxyHeader
+	}
+
+	addr, _ := netip.AddrFromSlice(payload[:addrLen])
+	port := binary.BigEndian.Uint16(payload[2*addrLen:])
+
+	return net.TCPAddrFromAddrPort(netip.AddrPortFrom(addr, port)), nil
+}
@@ -0,0 +1,155 @@
+package server
+
+import (
+	"bufio"
+	"context"
+	"encoding/binary"
+	"io"
+	"net"
+	"net/http"
+	"net/netip"
+	"testing"
+	"time"
+)
+
+func proxyV2Header(command byte, src netip.AddrPort) []byte {
+	header := append([]byte{}, proxyV2Signature...)
+	header = append(header, 0x20|command, 0x11, 0, 12)
+	header = append(header, src.Addr().AsSlice()...)
+	header = append(header, 10, 0, 0, 1)
+	header = binary.BigEndian.AppendUint16(header, src.Port())
+
+	return binary.BigEndian.AppendUint16(header, 443)
+}
+
+func Test_ProxyProtocol(t *testing.T) {
+	client := netip.MustParseAddrPort("192.0.2.1:56324")
+
+	for _, tc := range []struct {
+		description    string
+		trusted        string
+		header         []byte
+		expectedStatus int
+		expectedAddr   string
+	}{
+		{
+			description:    "v1 header",
+			trusted:        "127.0.0.0/8",
+			header:         []byte("PROXY TCP4 192.0.2.1 10.0.0.1 56324 443\r\n"),
+			expectedStatus: http.StatusOK,
+			expectedAddr:   client.String(),
+		},
+		{
+			description:    "v2 header",
+			trusted:        "127.0.0.0/8",
+			header:         proxyV2Header(1, client),
+			expectedStatus: http.StatusOK,
+			expectedAddr:   client.String(),
+		},
+		{
+			description:    "v2 local command keeps peer address",
+			trusted:        "127.0.0.0/8",
+			header:         proxyV2Header(0, client),
+			expectedStatus: http.StatusOK,
+			expectedAddr:   "127.0.0.1",
+		},
+		{
+			description:    "no header keeps peer address",
+			trusted:        "127.0.0.0/8",
+			expectedStatus: http.StatusOK,
+			expectedAddr:   "127.0.0.1",
+		},
+		{
+			description:    "header from untrusted peer is not read",
+			trusted:        "10.0.0.0/8",
+			header:         []byte("PROXY TCP4 192.0.2.1 10.0.0.1 56324 443\r\n"),
+			expectedStatus: http.StatusBadRequest,
+		},
+	} {
+		t.Run(tc.description, func(t *testing.T) {
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+
+			addrCh := make(chan net.Addr, 1)
+			runErr := make(chan error, 1)
+
+			go func() {
+				runErr <- Run(
+					ctx,
+					&http.Server{
+						Addr: "127.0.0.1:0",
+						Handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+							_, _ = io.WriteString(w, r.RemoteAddr)
+						}),
+					},
+					WithLogger(nil),
+					WithSignals(),
+					WithProxyProtocol(netip.MustParsePrefix(tc.trusted)),
+					OnReady(func(addr net.Addr) {
+						addrCh <- addr
+					}),
+				)
+			}()
+
+			conn, err := net.Dial("tcp", (<-addrCh).String())
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			defer conn.Close()
+
+			_ = conn.SetDeadline(time.Now().Add(5 * time.Second))
+
+			request := "GET / HTTP/1.1\r\nHost: example.com\r\nConnection: close\r\n\r\n"
+			if _, err := conn.Write(append(tc.header, request...)); err != nil {
+				t.Fatal(err)
+			}
+
+			resp, err := http.ReadResponse(bufio.NewReader(conn), nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			body, _ := io.ReadAll(resp.Body)
+			resp.Body.Close()
+
+			if resp.StatusCode != tc.expectedStatus {
+				t.Fatalf("unexpected status, got: %d, expected: %d", resp.StatusCode, tc.expectedStatus)
+			}
+
+			if tc.expectedAddr != "" {
+				host, _, _ := net.SplitHostPort(string(body))
+				if string(body) != tc.expectedAddr && host != tc.expectedAddr {
+					t.Fatalf("unexpected remote address, got: %s, expected: %s", body, tc.expectedAddr)
+				}
+			}
+
+			cancel()
+
+			if err := <-runErr; err != nil {
+				t.Fatalf("unexpected error from run: %s", err)
+			}
+		})
+	}
+}
+
+func Test_ProxyProtocolInvalidHeader(t *testing.T) {
+	for _, header := range []string{
+		"PROXY TCP4 not-an-ip 10.0.0.1 56324 443\r\n",
+		"PROXY TCP4 192.0.2.1 10.0.0.1 56324\r\n",
+		"PROXY TCP4 192.0.2.1 10.0.0.1 56324 443\n",
+	} {
+		server, client := net.Pipe()
+
+		go func() {
+			_, _ = client.Write([]byte(header))
+			client.Close()
+		}()
+
+		if _, err := readProxyHeader(bufio.NewReader(server)); err == nil {
+			t.Fatalf("expected error for header %q", header)
+		}
+
+		server.Close()
+	}
+}
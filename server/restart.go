@@ -0,0 +1,103 @@
+package server
+
+import (
+	"errors"
+	"net"
+	"os"
+	"os/exec"
+)
+
+// restartEnv is set for the new process started by a graceful restart to tell
+// it to use the inherited listener.
+const restartEnv = "HTTP_HELPERS_RESTART"
+
+type restartOptions struct {
+	listener net.Listener
+	command  func() (*exec.Cmd, error)
+}
+
+// WithGracefulRestart enables zero-downtime restarts for Run and RunTLS. When
+// SIGUSR2 is received, the binary is started again with the listening socket
+// passed as a file descriptor, and the current process is gracefully shut down
+// once the new process has started. The new process must also use this option
+// to use the inherited socket. Only the main listener is passed so this can't
+// be combined with the autocert challenge server. Restarts are not supported
+// on Windows.
+func WithGracefulRestart() Option {
+	return func(o *options) {
+		o.restart = &restartOptions{
+			command: restartCommand,
+		}
+	}
+}
+
+// inheritedListener returns the listener passed by a graceful restart, if any.
+func inheritedListener() (net.Listener, error) {
+	if os.Getenv(restartEnv) == "" {
+		return nil, nil
+	}
+
+	_ = os.Unsetenv(restartEnv)
+
+	f := os.NewFile(uintptr(listenFDsStart), "inherited")
+
+	// FileListener duplicates the file descriptor so we close the original
+	// one.
+	defer f.Close()
+
+	return net.FileListener(f)
+}
+
+// restart starts a new process with the listener passed as the first extra
+// file descriptor.
+func restart(options *options) error {
+	filer, ok := options.restart.listener.(interface {
+		File() (*os.File, error)
+	})
+	if !ok {
+		return errors.New("listener doesn't support passing the file descriptor")
+	}
+
+	f, err := filer.File()
+	if err != nil {
+		return err
+	}
+
+	defer f.Close()
+
+	cmd, err := options.restart.command()
+	if err != nil {
+		return err
+	}
+
+	cmd.ExtraFiles = []*os.File{f}
+	cmd.Env = append(os.Environ(), restartEnv+"=1")
+
+	if err := cmd.Start(); err != nil {
+		return err
+	}
+
+	options.logInfo("started new process, shutting down", "pid", cmd.Process.Pid)
+
+	// Reap the new process if it exits before we do.
+	go func() {
+		_ = cmd.Wait()
+	}()
+
+	return nil
+}
+
+// restartCommand starts the current binary with the same arguments.
+func restartCommand() (*exec.Cmd, error) {
+	path, err := os.Executable()
+	if err != nil {
+		return nil, err
+	}
+
+	cmd := exec.Command(path, os.Args[1:]...)
+	cmd.Stdin = os.Stdin
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+
+	return cmd, nil
+}
@@ -0,0 +1,8 @@
+//go:build !unix
+
+package server
+
+import "os"
+
+// restartSignal is nil since graceful restarts are not supported.
+var restartSignal os.Signal
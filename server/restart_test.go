@@ -0,0 +1,116 @@
+//go:build unix
+
+package server
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"os"
+	"os/exec"
+	"os/signal"
+	"syscall"
+	"testing"
+	"time"
+)
+
+func Test_GracefulRestart(t *testing.T) {
+	// When started by the restart, serve with the inherited listener for a
+	// while and then exit.
+	if os.Getenv(restartEnv) != "" {
+		ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+		defer cancel()
+
+		server := &http.Server{
+			Handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				fmt.Fprint(w, "new")
+			}),
+		}
+
+		if err := Run(ctx, server, WithGracefulRestart(), WithLogger(nil)); err != nil {
+			t.Fatal(err)
+		}
+
+		return
+	}
+
+	// Ensure SIGUSR2 never kills the test process.
+	ignored := make(chan os.Signal, 1)
+	signal.Notify(ignored, syscall.SIGUSR2)
+
+	defer signal.Stop(ignored)
+
+	var (
+		addrChan = make(chan net.Addr, 1)
+		runErr   = make(chan error, 1)
+		child    = make(chan *exec.Cmd, 1)
+	)
+
+	server := &http.Server{
+		Addr: "127.0.0.1:0",
+		Handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			fmt.Fprint(w, "old")
+		}),
+	}
+
+	withTestCommand := func(o *options) {
+		o.restart.command = func() (*exec.Cmd, error) {
+			cmd := exec.Command(os.Args[0], "-test.run=^Test_GracefulRestart$")
+			child <- cmd
+
+			return cmd, nil
+		}
+	}
+
+	go func() {
+		runErr <- Run(
+			context.Background(),
+			server,
+			WithGracefulRestart(),
+			withTestCommand,
+			WithLogger(nil),
+			OnReady(func(addr net.Addr) { addrChan <- addr }),
+		)
+	}()
+
+	addr := <-addrChan
+
+	// Ensure the signal handler is registered before we send the signal.
+	time.Sleep(50 * time.Millisecond)
+
+	if err := syscall.Kill(os.Getpid(), syscall.SIGUSR2); err != nil {
+		t.Fatal(err)
+	}
+
+	select {
+	case err := <-runErr:
+		if err != nil {
+			t.Fatal(err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("old server did not shut down")
+	}
+
+	// The old process is shut down but the listener is still served by the new
+	// one.
+	resp, err := http.Get("http://" + addr.String())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	body, _ := io.ReadAll(resp.Body)
+	resp.Body.Close()
+
+	if string(body) != "new" {
+		t.Fatalf("unexpected response, got: %s, expected: new", body)
+	}
+
+	// Wait for the new process to exit.
+	cmd := <-child
+
+	for i := 0; i < 50 && syscall.Kill(cmd.Process.Pid, 0) == nil; i++ {
+		time.Sleep(100 * time.Millisecond)
+	}
+}
@@ -0,0 +1,11 @@
+//go:build unix
+
+package server
+
+import (
+	"os"
+	"syscall"
+)
+
+// restartSignal triggers a graceful restart when enabled.
+var restartSignal os.Signal = syscall.SIGUSR2
@@ -0,0 +1,227 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
+)
+
+// ListenAndServeNotify creates the listener for the server and starts serving
+// in a separate goroutine. When this function returns without an error, the
+// server is accepting connections. The address the server is bound to is
+// returned which is useful when listening on port 0. The returned channel will
+// receive the error returned by Serve when the server stops.
+func ListenAndServeNotify(server *http.Server) (net.Addr, <-chan error, error) {
+	return listenAndServe(server, newOptions())
+}
+
+func listenAndServe(server *http.Server, options *options) (net.Addr, <-chan error, error) {
+	listener, err := listen(server, options)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	serveErr := make(chan error, 1)
+
+	go func() {
+		serveErr <- server.Serve(listener)
+	}()
+
+	return listener.Addr(), serveErr, nil
+}
+
+// listen creates the listener for the server. If the process was started by a
+// graceful restart or systemd passed a socket, that socket is used instead.
+// The listener reads the PROXY protocol header if WithProxyProtocol is used.
+func listen(server *http.Server, options *options) (net.Listener, error) {
+	listener, err := createListener(server, options)
+	if err != nil {
+		return nil, err
+	}
+
+	if options.restart != nil {
+		options.restart.listener = listener
+	}
+
+	if len(options.proxyProtocol) > 0 {
+		listener = ProxyProtocolListener(listener, options.proxyProtocol...)
+	}
+
+	return listener, nil
+}
+
+func createListener(server *http.Server, options *options) (net.Listener, error) {
+	if options.restart != nil {
+		listener, err := inheritedListener()
+		if err != nil {
+			return nil, err
+		}
+
+		if listener != nil {
+			return listener, nil
+		}
+	}
+
+	if options.systemd {
+		listeners, err := SystemdListeners()
+		if err != nil {
+			return nil, err
+		}
+
+		if len(listeners) > 0 {
+			return listeners[0], nil
+		}
+	}
+
+	addr := server.Addr
+	if addr == "" {
+		addr = ":http"
+	}
+
+	return net.Listen("tcp", addr)
+}
+
+// runHandler is the handler set on the server by Run, RunTLS and
+// GracefulShutdown, wrapping the handler set by the caller.
+type runHandler struct {
+	http.Handler
+	caller http.Handler
+}
+
+// wrapHandler sets the handler of the server to the handler set by the caller
+// wrapped with the wrappers, in order and skipping nil wrappers. If the server
+// has been run before its handler is wrapped again from the caller's handler,
+// not on top of the previous wrapping. It must be called before the server
+// starts serving.
+func wrapHandler(server *http.Server, wrappers ...func(http.Handler) http.Handler) {
+	caller := server.Handler
+	if h, ok := caller.(*runHandler); ok {
+		caller = h.caller
+	}
+
+	handler := caller
+	if handler == nil {
+		handler = http.DefaultServeMux
+	}
+
+	for _, wrap := range wrappers {
+		if wrap != nil {
+			handler = wrap(handler)
+		}
+	}
+
+	server.Handler = &runHandler{Handler: handler, caller: caller}
+}
+
+// ready is called when the server is accepting connections.
+func ready(addr net.Addr, options *options) {
+	for _, fn := range options.onReady {
+		fn(addr)
+	}
+
+	if options.systemd {
+		if err := SdNotify("READY=1"); err != nil {
+			options.logError("could not notify systemd", "error", err)
+		}
+	}
+}
+
+// Run starts the server and blocks until it's shut down. The shutdown is
+// triggered either by a signal or by the passed context being done. If the
+// server fails to start, a StartupError is returned. Otherwise the errors from
+// the shutdown, if any, are returned: ErrForcedClose if the connections
+// couldn't be drained within the wait time and a HookError for each failing
+// shutdown hook. Use ExitCode to get an exit code for the error.
+func Run(ctx context.Context, server *http.Server, opts ...Option) error {
+	options := newOptions(opts...)
+
+	propagateShutdown(server, options)
+
+	var h2c func(http.Handler) http.Handler
+
+	if options.h2c {
+		var err error
+
+		if h2c, err = enableH2C(server); err != nil {
+			return &StartupError{Err: err}
+		}
+	}
+
+	wrapHandler(server, trackInFlight(options), h2c)
+
+	addr, serveErr, err := listenAndServe(server, options)
+	if err != nil {
+		return &StartupError{Err: err}
+	}
+
+	ready(addr, options)
+
+	return waitAndShutdown(ctx, server, serveErr, options)
+}
+
+// waitAndShutdown waits until the server stops by itself, a signal is received
+// or the context is done and then shuts the server down.
+func waitAndShutdown(ctx context.Context, server Shutdowner, serveErr <-chan error, options *options) error {
+	signals := make(chan os.Signal, 2)
+
+	// Notify relays all signals if none are passed.
+	if len(options.signals) > 0 {
+		signal.Notify(signals, options.signals...)
+		defer signal.Stop(signals)
+	}
+
+	stopRelay := make(chan struct{})
+	defer close(stopRelay)
+
+	relaySignals(options, signals, stopRelay)
+
+	// A nil channel blocks forever so restarts are ignored unless enabled.
+	var restartSignals chan os.Signal
+
+	if options.restart != nil && restartSignal != nil {
+		restartSignals = make(chan os.Signal, 1)
+
+		signal.Notify(restartSignals, restartSignal)
+		defer signal.Stop(restartSignals)
+	}
+
+wait:
+	for {
+		select {
+		case err := <-serveErr:
+			if errors.Is(err, http.ErrServerClosed) {
+				return nil
+			}
+
+			return err
+		case sig := <-signals:
+			options.logInfo("received signal", "signal", sig)
+			break wait
+		case <-restartSignals:
+			if err := restart(options); err != nil {
+				options.logError("could not restart", "error", err)
+				continue
+			}
+
+			break wait
+		case <-ctx.Done():
+			break wait
+		}
+	}
+
+	done := make(chan struct{})
+	defer close(done)
+
+	forceQuitOnSignal(signals, done, options)
+
+	shutdownErr := shutdown(server, options)
+
+	if err := <-serveErr; err != nil && !errors.Is(err, http.ErrServerClosed) {
+		return errors.Join(err, shutdownErr)
+	}
+
+	return shutdownErr
+}
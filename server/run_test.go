@@ -0,0 +1,133 @@
+package server
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"syscall"
+	"testing"
+	"time"
+)
+
+func Test_Run(t *testing.T) {
+	var (
+		drained   bool
+		responded bool
+	)
+
+	ctx, cancel := context.WithCancel(context.Background())
+
+	err := Run(
+		ctx,
+		&http.Server{
+			Addr: "127.0.0.1:0",
+			Handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				responded = true
+			}),
+		},
+		WithWaitTime(time.Second),
+		OnReady(func(addr net.Addr) {
+			defer cancel()
+
+			response, err := http.Get("http://" + addr.String())
+			if err != nil {
+				t.Errorf("server not ready: %s", err)
+				return
+			}
+
+			_ = response.Body.Close()
+		}),
+		OnDrainComplete("drained", time.Second, func(_ context.Context) error {
+			drained = true
+			return nil
+		}),
+	)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	if !drained {
+		t.Fatal("shutdown hooks not executed")
+	}
+
+	if !responded {
+		t.Fatal("server never responded")
+	}
+}
+
+func Test_RunStartupFailure(t *testing.T) {
+	err := Run(context.Background(), &http.Server{Addr: "127.0.0.1:-1"})
+	if err == nil {
+		t.Fatal("expected error when server can't start")
+	}
+}
+
+func Test_RunSignalChannel(t *testing.T) {
+	var (
+		signals  = make(chan os.Signal, 1)
+		exitCode = make(chan int, 1)
+	)
+
+	withExit := func(o *options) {
+		o.exit = func(code int) {
+			exitCode <- code
+		}
+	}
+
+	err := Run(
+		context.Background(),
+		&http.Server{Addr: "127.0.0.1:0"},
+		WithSignals(),
+		WithSignalChannel(signals),
+		WithLogger(nil),
+		withExit,
+		OnReady(func(net.Addr) {
+			signals <- syscall.SIGTERM
+		}),
+		// A second signal during the shutdown forces the exit.
+		OnShutdownStart("second signal", time.Second, func(ctx context.Context) error {
+			signals <- syscall.SIGTERM
+
+			select {
+			case code := <-exitCode:
+				if code != ExitForcedClose {
+					t.Errorf("unexpected exit code: %d", code)
+				}
+			case <-ctx.Done():
+				t.Error("second signal didn't force the exit")
+			}
+
+			return nil
+		}),
+	)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+}
+
+func Test_WrapHandler(t *testing.T) {
+	var wrapped int
+
+	wrap := func(h http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			wrapped++
+			h.ServeHTTP(w, r)
+		})
+	}
+
+	server := &http.Server{Handler: http.NotFoundHandler()}
+
+	// Running the server again wraps the caller's handler again instead of
+	// the previous wrapping.
+	for range 2 {
+		wrapHandler(server, wrap, nil)
+	}
+
+	server.Handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil))
+
+	if wrapped != 1 {
+		t.Fatalf("unexpected number of wrappers called, got: %d, expected: 1", wrapped)
+	}
+}
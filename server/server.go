@@ -6,31 +6,49 @@ function. Perofrming a signal interruption will drain the connections and close
 the server. Example usage:
 
 	func main() {
-		server := &http.Server{
-			Addr: ":4080",
+		srv := &http.Server{
+			Addr:    ":4080",
 			Handler: mux.NewRouter(),
 		}
 
 		idleConnsClosed := server.GracefulShutdown(
-			server,         // The HTTP server
+			srv,            // The HTTP server
 			10*time.Second, // Wait time
-			logrus.New(),   // Optional logger
+			logger,         // Optional ShutdownLogger
 		)
 
-		if err := server.ListenAndServe(); err != nil {
+		if err := srv.ListenAndServe(); err != nil {
 			panic(err)
 		}
 
 		<-idleConnsClosed
 	}
+
+The same thing can be achieved with Run which also returns any error from
+starting the server:
+
+	func main() {
+		srv := &http.Server{
+			Addr:    ":4080",
+			Handler: mux.NewRouter(),
+		}
+
+		if err := server.Run(
+			context.Background(),
+			srv,
+			server.WithSlogLogger(slog.Default()),
+		); err != nil {
+			panic(err)
+		}
+	}
 */
 
 import (
 	"context"
+	"errors"
 	"net/http"
 	"os"
 	"os/signal"
-	"syscall"
 	"time"
 )
 
@@ -40,37 +58,124 @@ type ShutdownLogger interface {
 	Errorf(format string, args ...interface{})
 }
 
-// GracefulShutdown will enable graceful shutdown on the passed server.
-func GracefulShutdown(server *http.Server, waitTime time.Duration, logger ShutdownLogger) chan struct{} {
+// GracefulShutdown will enable graceful shutdown on the passed server. It sets
+// the handler and base context of the server, e.g. to count in-flight requests
+// with WithShutdownProgress, so it must be called before the server starts
+// serving.
+func GracefulShutdown(
+	server *http.Server,
+	waitTime time.Duration,
+	logger ShutdownLogger,
+	opts ...Option,
+) chan struct{} {
+	return gracefulShutdown(server, waitTime, logger, opts...)
+}
+
+func gracefulShutdown(
+	server Shutdowner,
+	waitTime time.Duration,
+	logger ShutdownLogger,
+	opts ...Option,
+) chan struct{} {
+	options := newOptions(append([]Option{WithWaitTime(waitTime), WithLogger(logger)}, opts...)...)
+
+	if s, ok := server.(*http.Server); ok {
+		wrapHandler(s, trackInFlight(options))
+		propagateShutdown(s, options)
+	}
+
 	// Channel used to wait for draining. This channel will be returned and
 	// should be used to block during shutdown.
 	idleConnsClosed := make(chan struct{})
 
 	go func() {
-		gracefulStop := make(chan os.Signal, 1)
+		gracefulStop := make(chan os.Signal, 2)
 
-		signal.Notify(gracefulStop, syscall.SIGTERM)
-		signal.Notify(gracefulStop, syscall.SIGINT)
+		if len(options.signals) > 0 {
+			signal.Notify(gracefulStop, options.signals...)
+			defer signal.Stop(gracefulStop)
+		}
 
-		<-gracefulStop
+		relaySignals(options, gracefulStop, idleConnsClosed)
 
-		if logger != nil {
-			logger.Infof("shutting down server, draining connections")
-		}
+		<-gracefulStop
 
-		// Create a context with a timeout so we never wait longer than 10
-		// seconds.
-		ctx, cancelFunc := context.WithTimeout(context.Background(), waitTime)
-		defer cancelFunc()
+		forceQuitOnSignal(gracefulStop, idleConnsClosed, options)
 
-		if err := server.Shutdown(ctx); err != nil {
-			if logger != nil {
-				logger.Errorf("could not shut down server gracefully: %s", err)
-			}
-		}
+		_ = shutdown(server, options)
 
 		close(idleConnsClosed)
 	}()
 
 	return idleConnsClosed
 }
+
+// forceQuitOnSignal will exit the process if another signal is received before
+// done is closed. If we get another signal while we're draining the user most
+// likely don't want to wait.
+func forceQuitOnSignal(signals <-chan os.Signal, done <-chan struct{}, options *options) {
+	if !options.forceQuit {
+		return
+	}
+
+	go func() {
+		select {
+		case sig := <-signals:
+			options.logError("received signal during shutdown, forcing exit", "signal", sig)
+			options.exit(ExitForcedClose)
+		case <-done:
+		}
+	}()
+}
+
+func shutdown(server Shutdowner, options *options) error {
+	if options.systemd {
+		if err := SdNotify("STOPPING=1"); err != nil {
+			options.logError("could not notify systemd", "error", err)
+		}
+	}
+
+	startErr := runHooks(options.onShutdownStart, options)
+
+	options.logInfo("shutting down server, draining connections")
+
+	// Create a context with a timeout so we never wait longer than the
+	// configured wait time.
+	ctx, cancelFunc := options.clock.WithTimeout(context.Background(), options.waitTime)
+	defer cancelFunc()
+
+	done := reportProgress(options)
+	cancelRequests := startRequestGrace(options)
+
+	err := server.Shutdown(ctx)
+	cancelRequests()
+
+	if err != nil {
+		options.logError("could not shut down server gracefully", "error", err)
+	}
+
+	done(err)
+
+	drainErr := runHooks(options.onDrainComplete, options)
+
+	return errors.Join(startErr, forcedClose(err), drainErr)
+}
+
+// runHooks will run each hook in order. A failing hook will not stop the
+// remaining hooks from being executed. The errors are returned as HookError.
+func runHooks(hooks []shutdownHook, options *options) error {
+	var errs []error
+
+	for _, hook := range hooks {
+		ctx, cancelFunc := options.clock.WithTimeout(context.Background(), hook.timeout)
+
+		if err := hook.fn(ctx); err != nil {
+			options.logError("shutdown hook failed", "hook", hook.name, "error", err)
+			errs = append(errs, &HookError{Hook: hook.name, Err: err})
+		}
+
+		cancelFunc()
+	}
+
+	return errors.Join(errs...)
+}
@@ -1,20 +1,30 @@
 package server
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"io/ioutil"
+	"log"
 	"net/http"
+	"os"
+	"strings"
 	"sync"
 	"syscall"
 	"testing"
 	"time"
-
-	"github.com/sirupsen/logrus"
 )
 
-const (
-	serverURI = ":1337"
-)
+// stdLogger is a ShutdownLogger using the standard library logger.
+type stdLogger struct{}
+
+func (stdLogger) Infof(format string, args ...interface{}) {
+	log.Printf(format, args...)
+}
+
+func (stdLogger) Errorf(format string, args ...interface{}) {
+	log.Printf(format, args...)
+}
 
 func Test_GracefulShutdown(t *testing.T) {
 	var (
@@ -38,44 +48,43 @@ func Test_GracefulShutdown(t *testing.T) {
 
 	// Create a server with the DefaultServerMux.
 	server := &http.Server{
-		Addr:    serverURI,
+		Addr:    "127.0.0.1:0",
 		Handler: http.DefaultServeMux,
 	}
 
 	// Create our idle chan which will block until all connections are drained.
-	idleChan := GracefulShutdown(server, 5*time.Second, logrus.New())
-
-	// Start the server in a go routine and ensure there's no error.
-	go func() {
-		if err := server.ListenAndServe(); err != nil {
-			if err != http.ErrServerClosed {
-				t.Fatal(err)
-			}
-		}
-	}()
-
-	// TODO: I haven't found a way to tell when the server has actually started
-	// so for now I'll just wait a bit before performing my requests.
-	time.Sleep(500 * time.Millisecond)
+	// The shutdown is triggered with a virtual signal so the test doesn't
+	// signal the whole process.
+	signals := make(chan os.Signal, 1)
+	idleChan := GracefulShutdown(server, 5*time.Second, stdLogger{}, WithSignals(), WithSignalChannel(signals))
+
+	// Start the server, when this returns the server is accepting connections.
+	addr, _, err := ListenAndServeNotify(server)
+	if err != nil {
+		t.Fatal(err)
+	}
 
 	for i := 0; i < expctedCalls; i++ {
 		wg.Add(1)
 
 		go func() {
-			result, err := http.Get("http://127.0.0.1:1337")
+			result, err := http.Get("http://" + addr.String())
 			if err != nil {
-				t.Fatal("could not send http request")
+				t.Error("could not send http request")
+				return
 			}
 
 			defer result.Body.Close()
 
 			b, err := ioutil.ReadAll(result.Body)
 			if err != nil {
-				t.Fatal("could not read response")
+				t.Error("could not read response")
+				return
 			}
 
 			if string(b) != "sorry for the delay..." {
-				t.Fatal("unexpected response")
+				t.Error("unexpected response")
+				return
 			}
 
 			timesCalled++
@@ -86,10 +95,8 @@ func Test_GracefulShutdown(t *testing.T) {
 	// processed)
 	wg.Wait()
 
-	// Let's kill the process too see how graceful we are.
-	if err := syscall.Kill(syscall.Getpid(), syscall.SIGINT); err != nil {
-		t.Fatal("could not send SIGINT")
-	}
+	// Let's interrupt the server to see how graceful we are.
+	signals <- syscall.SIGINT
 
 	// Block until server is shut.
 	<-idleChan
@@ -99,3 +106,87 @@ func Test_GracefulShutdown(t *testing.T) {
 		t.Fatal("did not get response from all request")
 	}
 }
+
+func Test_ShutdownHooks(t *testing.T) {
+	var (
+		called  []string
+		options = newOptions(WithLogger(stdLogger{}))
+	)
+
+	hook := func(name string) ShutdownHookFunc {
+		return func(ctx context.Context) error {
+			called = append(called, name)
+
+			if _, ok := ctx.Deadline(); !ok {
+				t.Fatal("hook context has no deadline")
+			}
+
+			if name == "failing" {
+				return errors.New("i failed")
+			}
+
+			return nil
+		}
+	}
+
+	for _, opt := range []Option{
+		OnDrainComplete("close-db", time.Second, hook("close-db")),
+		OnShutdownStart("deregister", time.Second, hook("deregister")),
+		OnShutdownStart("failing", time.Second, hook("failing")),
+		OnDrainComplete("flush", time.Second, hook("flush")),
+	} {
+		opt(options)
+	}
+
+	_ = shutdown(&http.Server{}, options)
+
+	expected := []string{"deregister", "failing", "close-db", "flush"}
+	if strings.Join(called, ",") != strings.Join(expected, ",") {
+		t.Fatalf("unexpected hook order, got: %v, expected: %v", called, expected)
+	}
+}
+
+func Test_ForceQuit(t *testing.T) {
+	var (
+		exitCode = make(chan int, 1)
+		group    = NewShutdownGroup()
+		draining = make(chan struct{})
+		signals  = make(chan os.Signal, 1)
+	)
+
+	group.AddFunc("slow", func(ctx context.Context) error {
+		close(draining)
+		<-ctx.Done()
+
+		return nil
+	})
+
+	withExit := func(o *options) {
+		o.exit = func(code int) {
+			exitCode <- code
+		}
+	}
+
+	idleChan := group.GracefulShutdown(
+		100*time.Millisecond,
+		stdLogger{},
+		WithSignals(),
+		WithSignalChannel(signals),
+		withExit,
+	)
+
+	signals <- syscall.SIGTERM
+
+	<-draining
+
+	signals <- syscall.SIGTERM
+
+	select {
+	case code := <-exitCode:
+		if code != ExitForcedClose {
+			t.Fatalf("unexpected exit code: %d", code)
+		}
+	case <-idleChan:
+		t.Fatal("shutdown completed without forcing exit")
+	}
+}
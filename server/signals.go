@@ -0,0 +1,42 @@
+package server
+
+import "os"
+
+// WithSignalChannel sets a channel whose values are handled like the signals
+// set with WithSignals: the first value triggers the shutdown and, unless
+// WithoutForceQuit is used, a second value during the shutdown exits the
+// process. This lets applications and tests start the shutdown without
+// sending a real signal to the whole process. Combine with WithSignals() to
+// only shut down from the channel.
+func WithSignalChannel(signals <-chan os.Signal) Option {
+	return func(o *options) {
+		o.signalChannel = signals
+	}
+}
+
+// relaySignals forwards the values from the channel set with
+// WithSignalChannel, if any, to the signal channel until stop is closed.
+func relaySignals(options *options, to chan<- os.Signal, stop <-chan struct{}) {
+	if options.signalChannel == nil {
+		return
+	}
+
+	go func() {
+		for {
+			select {
+			case sig, ok := <-options.signalChannel:
+				if !ok {
+					return
+				}
+
+				select {
+				case to <- sig:
+				case <-stop:
+					return
+				}
+			case <-stop:
+				return
+			}
+		}
+	}()
+}
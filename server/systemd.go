@@ -0,0 +1,95 @@
+package server
+
+import (
+	"net"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// The first file descriptor passed by systemd socket activation.
+const listenFDsStart = 3
+
+// WithSystemd enables systemd integration. If the process was started with
+// socket activation, the first passed socket is used instead of listening on
+// the server's address. systemd is notified with READY=1 when the server is
+// accepting connections and STOPPING=1 when the shutdown starts.
+func WithSystemd() Option {
+	return func(o *options) {
+		o.systemd = true
+	}
+}
+
+// SystemdListeners returns the listeners passed by systemd socket activation.
+// If the process wasn't socket activated, no listeners are returned. The
+// environment variables are unset so they aren't passed to child processes.
+func SystemdListeners() ([]net.Listener, error) {
+	return activatedListeners(listenFDsStart)
+}
+
+func activatedListeners(firstFD int) ([]net.Listener, error) {
+	defer func() {
+		_ = os.Unsetenv("LISTEN_PID")
+		_ = os.Unsetenv("LISTEN_FDS")
+		_ = os.Unsetenv("LISTEN_FDNAMES")
+	}()
+
+	pid, err := strconv.Atoi(os.Getenv("LISTEN_PID"))
+	if err != nil || pid != os.Getpid() {
+		return nil, nil
+	}
+
+	n, err := strconv.Atoi(os.Getenv("LISTEN_FDS"))
+	if err != nil || n == 0 {
+		return nil, nil
+	}
+
+	names := strings.Split(os.Getenv("LISTEN_FDNAMES"), ":")
+	listeners := make([]net.Listener, 0, n)
+
+	for i := 0; i < n; i++ {
+		name := "LISTEN_FD_" + strconv.Itoa(firstFD+i)
+		if i < len(names) && names[i] != "" {
+			name = names[i]
+		}
+
+		f := os.NewFile(uintptr(firstFD+i), name)
+
+		// FileListener duplicates the file descriptor so we close the
+		// original one.
+		listener, err := net.FileListener(f)
+		_ = f.Close()
+
+		if err != nil {
+			for _, l := range listeners {
+				_ = l.Close()
+			}
+
+			return nil, err
+		}
+
+		listeners = append(listeners, listener)
+	}
+
+	return listeners, nil
+}
+
+// SdNotify sends the state, e.g. "READY=1", to systemd. If the process isn't
+// managed by systemd (NOTIFY_SOCKET isn't set) this is a no-op.
+func SdNotify(state string) error {
+	socket := os.Getenv("NOTIFY_SOCKET")
+	if socket == "" {
+		return nil
+	}
+
+	conn, err := net.DialUnix("unixgram", nil, &net.UnixAddr{Name: socket, Net: "unixgram"})
+	if err != nil {
+		return err
+	}
+
+	defer conn.Close()
+
+	_, err = conn.Write([]byte(state))
+
+	return err
+}
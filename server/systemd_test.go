@@ -0,0 +1,86 @@
+package server
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+	"time"
+)
+
+func Test_ActivatedListeners(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer listener.Close()
+
+	f, err := listener.(*net.TCPListener).File()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	t.Setenv("LISTEN_PID", strconv.Itoa(os.Getpid()))
+	t.Setenv("LISTEN_FDS", "1")
+	t.Setenv("LISTEN_FDNAMES", "http")
+
+	listeners, err := activatedListeners(int(f.Fd()))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(listeners) != 1 {
+		t.Fatalf("unexpected number of listeners: %d", len(listeners))
+	}
+
+	defer listeners[0].Close()
+
+	if listeners[0].Addr().String() != listener.Addr().String() {
+		t.Fatalf("unexpected address: %s", listeners[0].Addr())
+	}
+
+	if os.Getenv("LISTEN_FDS") != "" {
+		t.Fatal("expected environment to be unset")
+	}
+}
+
+func Test_SdNotify(t *testing.T) {
+	socket := filepath.Join(t.TempDir(), "notify.sock")
+
+	conn, err := net.ListenUnixgram("unixgram", &net.UnixAddr{Name: socket, Net: "unixgram"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer conn.Close()
+
+	t.Setenv("NOTIFY_SOCKET", socket)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	go func() {
+		_ = Run(ctx, &http.Server{Addr: "127.0.0.1:0"}, WithSystemd())
+	}()
+
+	buf := make([]byte, 64)
+
+	for _, expected := range []string{"READY=1", "STOPPING=1"} {
+		_ = conn.SetReadDeadline(time.Now().Add(time.Second))
+
+		n, err := conn.Read(buf)
+		if err != nil {
+			t.Fatalf("did not get %s: %s", expected, err)
+		}
+
+		if string(buf[:n]) != expected {
+			t.Fatalf("unexpected state, got: %s, expected: %s", buf[:n], expected)
+		}
+
+		cancel()
+	}
+}
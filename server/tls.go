@@ -0,0 +1,213 @@
+package server
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"net/http"
+	"slices"
+	"time"
+
+	"golang.org/x/crypto/acme"
+	"golang.org/x/crypto/acme/autocert"
+)
+
+// TLSPreset is a preset of TLS settings.
+type TLSPreset int
+
+// Available TLS presets, based on the Mozilla server side TLS recommendations.
+const (
+	// TLSPresetIntermediate supports TLS 1.2 and 1.3 with only AEAD cipher
+	// suites and forward secrecy. This is the default.
+	TLSPresetIntermediate TLSPreset = iota
+
+	// TLSPresetModern only supports TLS 1.3.
+	TLSPresetModern
+)
+
+// Config returns a new *tls.Config with the settings for the preset.
+func (p TLSPreset) Config() *tls.Config {
+	if p == TLSPresetModern {
+		return &tls.Config{
+			MinVersion: tls.VersionTLS13,
+		}
+	}
+
+	return &tls.Config{
+		MinVersion: tls.VersionTLS12,
+		CurvePreferences: []tls.CurveID{
+			tls.X25519,
+			tls.CurveP256,
+			tls.CurveP384,
+		},
+		CipherSuites: []uint16{
+			tls.TLS_ECDHE_ECDSA_WITH_AES_128_GCM_SHA256,
+			tls.TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256,
+			tls.TLS_ECDHE_ECDSA_WITH_AES_256_GCM_SHA384,
+			tls.TLS_ECDHE_RSA_WITH_AES_256_GCM_SHA384,
+			tls.TLS_ECDHE_ECDSA_WITH_CHACHA20_POLY1305,
+			tls.TLS_ECDHE_RSA_WITH_CHACHA20_POLY1305,
+		},
+	}
+}
+
+type tlsOptions struct {
+	certFile      string
+	keyFile       string
+	preset        TLSPreset
+	autocert      bool
+	domains       []string
+	cacheDir      string
+	challengeAddr string
+}
+
+// WithCertFiles sets the certificate and key files used by RunTLS.
+func WithCertFiles(certFile, keyFile string) Option {
+	return func(o *options) {
+		o.tls.certFile = certFile
+		o.tls.keyFile = keyFile
+	}
+}
+
+// WithTLSPreset sets the TLS preset used by RunTLS if the server doesn't
+// already have a TLSConfig.
+func WithTLSPreset(preset TLSPreset) Option {
+	return func(o *options) {
+		o.tls.preset = preset
+	}
+}
+
+// WithAutocert makes RunTLS fetch certificates from Let's Encrypt for the
+// passed domains. Only the listed domains are allowed. Certificates are cached
+// in cacheDir which should be persisted between restarts to not hit rate
+// limits.
+func WithAutocert(cacheDir string, domains ...string) Option {
+	return func(o *options) {
+		o.tls.autocert = true
+		o.tls.cacheDir = cacheDir
+		o.tls.domains = domains
+	}
+}
+
+// WithHTTPChallengeAddr sets the address for the HTTP server used for HTTP-01
+// challenges when using WithAutocert. The server also redirects all other
+// requests to HTTPS. Defaults to ":http", set to an empty string to only use
+// TLS-ALPN-01 challenges.
+func WithHTTPChallengeAddr(addr string) Option {
+	return func(o *options) {
+		o.tls.challengeAddr = addr
+	}
+}
+
+// RunTLS works like Run but serves HTTPS. The certificate is either read from
+// the files set with WithCertFiles or fetched with WithAutocert. If the server
+// doesn't have a TLSConfig, one is created from the TLS preset, otherwise it's
+// cloned so the caller's config isn't modified. Use WithHTTP3 to serve HTTP/3
+// as well.
+func RunTLS(ctx context.Context, server *http.Server, opts ...Option) error {
+	options := newOptions(opts...)
+
+	// ServeTLS would only fail on a bad certificate once the server has
+	// started, load it first to report it as a startup failure.
+	if options.tls.certFile != "" || options.tls.keyFile != "" {
+		if _, err := tls.LoadX509KeyPair(options.tls.certFile, options.tls.keyFile); err != nil {
+			return &StartupError{Err: err}
+		}
+	}
+
+	propagateShutdown(server, options)
+
+	if server.TLSConfig == nil {
+		server.TLSConfig = options.tls.preset.Config()
+	} else {
+		server.TLSConfig = server.TLSConfig.Clone()
+	}
+
+	if server.Addr == "" {
+		server.Addr = ":https"
+	}
+
+	var manager *autocert.Manager
+
+	if options.tls.autocert {
+		manager = &autocert.Manager{
+			Prompt:     autocert.AcceptTOS,
+			HostPolicy: autocert.HostWhitelist(options.tls.domains...),
+			Cache:      autocert.DirCache(options.tls.cacheDir),
+		}
+
+		server.TLSConfig.GetCertificate = manager.GetCertificate
+
+		if !slices.Contains(server.TLSConfig.NextProtos, acme.ALPNProto) {
+			server.TLSConfig.NextProtos = append(server.TLSConfig.NextProtos, acme.ALPNProto)
+		}
+	}
+
+	listener, err := listen(server, options)
+	if err != nil {
+		return &StartupError{Err: err}
+	}
+
+	// The servers serving next to the HTTPS server are only started once its
+	// listener is up, and stopped if it can't be served.
+	others := NewShutdownGroup()
+
+	var advertiseHTTP3 func(http.Handler) http.Handler
+
+	if options.http3 != nil {
+		var h3 Shutdowner
+
+		h3, advertiseHTTP3, err = serveHTTP3(listener.Addr(), options)
+		if err != nil {
+			_ = listener.Close()
+			return &StartupError{Err: err}
+		}
+
+		others.Add("http3", h3)
+	}
+
+	if manager != nil && options.tls.challengeAddr != "" {
+		challengeServer := &http.Server{
+			Addr:              options.tls.challengeAddr,
+			Handler:           manager.HTTPHandler(nil),
+			ReadHeaderTimeout: 10 * time.Second,
+		}
+
+		_, challengeErr, err := ListenAndServeNotify(challengeServer)
+		if err != nil {
+			_ = listener.Close()
+			_ = others.Shutdown(context.Background())
+
+			return &StartupError{Err: err}
+		}
+
+		go func() {
+			if err := <-challengeErr; !errors.Is(err, http.ErrServerClosed) {
+				options.logError("challenge server stopped", "error", err)
+			}
+		}()
+
+		others.AddServer("challenge", challengeServer)
+	}
+
+	wrapHandler(server, trackInFlight(options), advertiseHTTP3)
+
+	serveErr := make(chan error, 1)
+
+	go func() {
+		err := server.ServeTLS(listener, options.tls.certFile, options.tls.keyFile)
+		if !errors.Is(err, http.ErrServerClosed) {
+			_ = others.Shutdown(context.Background())
+		}
+
+		serveErr <- err
+	}()
+
+	group := NewShutdownGroup()
+	group.AddServer("https", server)
+	group.Add("others", others)
+
+	ready(listener.Addr(), options)
+
+	return waitAndShutdown(ctx, group, serveErr, options)
+}
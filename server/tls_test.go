@@ -0,0 +1,163 @@
+package server
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func Test_RunTLS(t *testing.T) {
+	certFile, keyFile := writeSelfSignedCert(t)
+
+	var tlsVersion uint16
+
+	ctx, cancel := context.WithCancel(context.Background())
+
+	err := RunTLS(
+		ctx,
+		&http.Server{
+			Addr:    "127.0.0.1:0",
+			Handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}),
+		},
+		WithCertFiles(certFile, keyFile),
+		WithTLSPreset(TLSPresetModern),
+		OnReady(func(addr net.Addr) {
+			defer cancel()
+
+			client := &http.Client{
+				Transport: &http.Transport{
+					TLSClientConfig: &tls.Config{
+						//nolint:gosec // Self signed certificate in test.
+						InsecureSkipVerify: true,
+					},
+				},
+			}
+
+			response, err := client.Get("https://" + addr.String())
+			if err != nil {
+				t.Errorf("could not send https request: %s", err)
+				return
+			}
+
+			_ = response.Body.Close()
+
+			tlsVersion = response.TLS.Version
+		}),
+	)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	if tlsVersion != tls.VersionTLS13 {
+		t.Fatalf("unexpected TLS version: %x", tlsVersion)
+	}
+}
+
+func writeSelfSignedCert(t *testing.T) (string, string) {
+	t.Helper()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "localhost"},
+		IPAddresses:  []net.IP{net.ParseIP("127.0.0.1")},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var (
+		dir      = t.TempDir()
+		certFile = filepath.Join(dir, "cert.pem")
+		keyFile  = filepath.Join(dir, "key.pem")
+	)
+
+	if err := os.WriteFile(certFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := os.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	return certFile, keyFile
+}
+
+func Test_RunTLSStartupFailure(t *testing.T) {
+	inUse, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer inUse.Close()
+
+	challenge, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	challengeAddr := challenge.Addr().String()
+	_ = challenge.Close()
+
+	tlsConfig := &tls.Config{MinVersion: tls.VersionTLS12}
+
+	err = RunTLS(
+		context.Background(),
+		&http.Server{Addr: inUse.Addr().String(), TLSConfig: tlsConfig},
+		WithAutocert(t.TempDir(), "example.com"),
+		WithHTTPChallengeAddr(challengeAddr),
+	)
+	if ExitCode(err) != ExitStartupFailed {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if tlsConfig.GetCertificate != nil || len(tlsConfig.NextProtos) != 0 {
+		t.Fatal("expected the TLS config to not be modified")
+	}
+
+	// The challenge server must not be left serving.
+	listener, err := net.Listen("tcp", challengeAddr)
+	if err != nil {
+		t.Fatalf("expected challenge address to be free: %s", err)
+	}
+
+	_ = listener.Close()
+}
+
+func Test_RunTLSBadCertificate(t *testing.T) {
+	certFile, _ := writeSelfSignedCert(t)
+
+	err := RunTLS(
+		context.Background(),
+		&http.Server{Addr: "127.0.0.1:0"},
+		WithCertFiles(certFile, certFile),
+	)
+	if ExitCode(err) != ExitStartupFailed {
+		t.Fatalf("unexpected exit code for %v, got: %d, expected: %d", err, ExitCode(err), ExitStartupFailed)
+	}
+}
@@ -0,0 +1,295 @@
+package sse
+
+/*
+Server-Sent Events. An EventStream writes events in the text/event-stream
+format, flushing each event to the client and sending heartbeat comments so
+idle connections aren't closed by proxies. Clients reconnecting send the ID of
+the last event they received which is available with LastEventID so the
+stream can resume where it left off.
+
+	func events(w http.ResponseWriter, r *http.Request) {
+		stream, err := sse.NewEventStream(w, r)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		defer stream.Close()
+
+		for msg := range broker.Subscribe(r.Context(), stream.LastEventID()) {
+			if err := stream.Send(sse.Event{ID: msg.ID, Event: "message", Data: msg.Text}); err != nil {
+				return
+			}
+		}
+	}
+
+Wrap the handler with middleware.WithoutTimeout so the stream isn't cut off by
+the deadline set by the Timeout middleware.
+*/
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/bombsimon/http-helpers/clock"
+)
+
+// ContentType is the content type of event streams.
+const ContentType = "text/event-stream"
+
+var (
+	// ErrStreamingUnsupported is returned by NewEventStream if the response
+	// writer can't be flushed.
+	ErrStreamingUnsupported = errors.New("sse: response writer doesn't support flushing")
+
+	// ErrClosed is returned when sending to a closed stream.
+	ErrClosed = errors.New("sse: stream is closed")
+)
+
+// errorWriter is implemented by response writers that can store an error, such
+// as middleware.ResponseWriterWithInfo.
+type errorWriter interface {
+	WriteError(err error)
+}
+
+// Event is an event sent to the client.
+type Event struct {
+	// ID is stored by the client and sent as Last-Event-ID when reconnecting.
+	// Line breaks are removed.
+	ID string
+
+	// Event is the event type, the client defaults to "message". Line breaks
+	// are removed.
+	Event string
+
+	// Data is the event data. Multi-line data is sent as multiple data
+	// fields and joined by the client.
+	Data string
+
+	// Retry, if set, tells the client how long to wait before reconnecting.
+	Retry time.Duration
+}
+
+// Option is an option used to configure an EventStream.
+type Option func(*options)
+
+type options struct {
+	heartbeat time.Duration
+	clock     clock.Clock
+}
+
+// WithHeartbeat sets how often a comment is sent to keep idle connections
+// open. Defaults to 15 seconds, set to 0 to disable heartbeats.
+func WithHeartbeat(interval time.Duration) Option {
+	return func(o *options) {
+		o.heartbeat = interval
+	}
+}
+
+// WithClock sets the clock used for heartbeats. Defaults to clock.Real().
+func WithClock(c clock.Clock) Option {
+	return func(o *options) {
+		o.clock = c
+	}
+}
+
+// EventStream writes events to the client. It's safe for concurrent use.
+type EventStream struct {
+	mu          sync.Mutex
+	w           http.ResponseWriter
+	rc          *http.ResponseController
+	lastEventID string
+	closed      bool
+	done        chan struct{}
+	wg          sync.WaitGroup
+}
+
+// NewEventStream writes the event stream headers and starts the heartbeat.
+// The response writer must support flushing, directly or through an Unwrap
+// method such as the one on middleware.ResponseWriterWithInfo, otherwise
+// ErrStreamingUnsupported is returned without writing anything. Call Close
+// before the handler returns to stop the heartbeat.
+func NewEventStream(w http.ResponseWriter, r *http.Request, opts ...Option) (*EventStream, error) {
+	options := &options{
+		heartbeat: 15 * time.Second,
+		clock:     clock.Real(),
+	}
+
+	for _, opt := range opts {
+		opt(options)
+	}
+
+	s := &EventStream{
+		w:           w,
+		rc:          http.NewResponseController(w),
+		lastEventID: r.Header.Get("Last-Event-ID"),
+		done:        make(chan struct{}),
+	}
+
+	if !canFlush(w) {
+		storeError(w, ErrStreamingUnsupported)
+		return nil, ErrStreamingUnsupported
+	}
+
+	w.Header().Set("Content-Type", ContentType)
+	w.Header().Set("Cache-Control", "no-cache")
+	// Disable response buffering in nginx.
+	w.Header().Set("X-Accel-Buffering", "no")
+	w.Header().Del("Content-Length")
+	w.WriteHeader(http.StatusOK)
+
+	if err := s.rc.Flush(); err != nil {
+		storeError(w, err)
+		return nil, err
+	}
+
+	if options.heartbeat > 0 {
+		s.wg.Add(1)
+
+		go s.heartbeat(r, options)
+	}
+
+	return s, nil
+}
+
+// LastEventID returns the ID of the last event received by the client, sent
+// in the Last-Event-ID header when reconnecting, or an empty string for new
+// clients.
+func (s *EventStream) LastEventID() string {
+	return s.lastEventID
+}
+
+// Send writes the event and flushes it to the client.
+func (s *EventStream) Send(event Event) error {
+	var sb strings.Builder
+
+	if event.ID != "" {
+		writeField(&sb, "id", singleLine(event.ID))
+	}
+
+	if event.Event != "" {
+		writeField(&sb, "event", singleLine(event.Event))
+	}
+
+	if event.Retry > 0 {
+		writeField(&sb, "retry", strconv.FormatInt(event.Retry.Milliseconds(), 10))
+	}
+
+	for _, line := range splitLines(event.Data) {
+		writeField(&sb, "data", line)
+	}
+
+	sb.WriteString("\n")
+
+	return s.write(sb.String())
+}
+
+// Comment writes a comment, ignored by the client, and flushes it.
+func (s *EventStream) Comment(text string) error {
+	var sb strings.Builder
+
+	for _, line := range splitLines(text) {
+		sb.WriteString(": ")
+		sb.WriteString(line)
+		sb.WriteString("\n")
+	}
+
+	sb.WriteString("\n")
+
+	return s.write(sb.String())
+}
+
+// Close stops the heartbeat and waits for it to finish so nothing is written
+// after the handler returns. Events can't be sent after the stream is closed.
+func (s *EventStream) Close() {
+	s.mu.Lock()
+
+	if !s.closed {
+		s.closed = true
+		close(s.done)
+	}
+
+	s.mu.Unlock()
+
+	s.wg.Wait()
+}
+
+func (s *EventStream) write(data string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.closed {
+		return ErrClosed
+	}
+
+	if _, err := fmt.Fprint(s.w, data); err != nil {
+		return err
+	}
+
+	return s.rc.Flush()
+}
+
+func (s *EventStream) heartbeat(r *http.Request, options *options) {
+	defer s.wg.Done()
+
+	ticker := options.clock.NewTicker(options.heartbeat)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-s.done:
+			return
+		case <-r.Context().Done():
+			return
+		case <-ticker.C():
+			if err := s.Comment("heartbeat"); err != nil {
+				return
+			}
+		}
+	}
+}
+
+func writeField(sb *strings.Builder, name, value string) {
+	sb.WriteString(name)
+	sb.WriteString(": ")
+	sb.WriteString(value)
+	sb.WriteString("\n")
+}
+
+// splitLines splits on all line endings allowed by the event stream format.
+func splitLines(s string) []string {
+	s = strings.ReplaceAll(s, "\r\n", "\n")
+	s = strings.ReplaceAll(s, "\r", "\n")
+
+	return strings.Split(s, "\n")
+}
+
+func singleLine(s string) string {
+	return strings.NewReplacer("\r", "", "\n", "").Replace(s)
+}
+
+// canFlush returns true if the response writer, or a response writer it
+// wraps, can be flushed, the same way http.ResponseController looks for it.
+func canFlush(w http.ResponseWriter) bool {
+	for {
+		switch t := w.(type) {
+		case http.Flusher, interface{ FlushError() error }:
+			return true
+		case interface{ Unwrap() http.ResponseWriter }:
+			w = t.Unwrap()
+		default:
+			return false
+		}
+	}
+}
+
+func storeError(w http.ResponseWriter, err error) {
+	if ew, ok := w.(errorWriter); ok {
+		ew.WriteError(err)
+	}
+}
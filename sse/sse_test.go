@@ -0,0 +1,122 @@
+package sse
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/bombsimon/http-helpers/clock"
+)
+
+// lockedRecorder is a response recorder safe to read while the heartbeat
+// writes to it.
+type lockedRecorder struct {
+	mu  sync.Mutex
+	rec *httptest.ResponseRecorder
+}
+
+func (r *lockedRecorder) Header() http.Header {
+	return r.rec.Header()
+}
+
+func (r *lockedRecorder) WriteHeader(status int) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.rec.WriteHeader(status)
+}
+
+func (r *lockedRecorder) Write(b []byte) (int, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return r.rec.Write(b)
+}
+
+func (r *lockedRecorder) Flush() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.rec.Flush()
+}
+
+func (r *lockedRecorder) String() string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return r.rec.Body.String()
+}
+
+func Test_EventStream(t *testing.T) {
+	clk := clock.NewFake(time.Now())
+	rec := &lockedRecorder{rec: httptest.NewRecorder()}
+
+	req := httptest.NewRequest(http.MethodGet, "/events", nil)
+	req.Header.Set("Last-Event-ID", "41")
+
+	stream, err := NewEventStream(rec, req, WithClock(clk))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if stream.LastEventID() != "41" {
+		t.Fatalf("unexpected last event id: %s", stream.LastEventID())
+	}
+
+	if got := rec.Header().Get("Content-Type"); got != ContentType || !rec.rec.Flushed {
+		t.Fatalf("expected flushed event stream, got content type: %s", got)
+	}
+
+	if err := stream.Send(Event{ID: "42", Event: "update\n", Data: "first\nsecond", Retry: 3 * time.Second}); err != nil {
+		t.Fatal(err)
+	}
+
+	expected := "id: 42\nevent: update\nretry: 3000\ndata: first\ndata: second\n\n"
+	if got := rec.String(); got != expected {
+		t.Fatalf("unexpected event, got: %q, expected: %q", got, expected)
+	}
+
+	clk.BlockUntil(1)
+	clk.Advance(15 * time.Second)
+
+	for deadline := time.Now().Add(time.Second); !strings.HasSuffix(rec.String(), ": heartbeat\n\n"); {
+		if time.Now().After(deadline) {
+			t.Fatalf("expected heartbeat, got: %q", rec.String())
+		}
+
+		time.Sleep(time.Millisecond)
+	}
+
+	stream.Close()
+
+	if err := stream.Send(Event{Data: "late"}); !errors.Is(err, ErrClosed) {
+		t.Fatalf("expected ErrClosed, got: %v", err)
+	}
+}
+
+type nonFlusher struct {
+	http.ResponseWriter
+	err error
+}
+
+func (w *nonFlusher) WriteError(err error) {
+	w.err = err
+}
+
+func Test_EventStreamUnsupported(t *testing.T) {
+	rec := httptest.NewRecorder()
+	w := &nonFlusher{ResponseWriter: rec}
+
+	_, err := NewEventStream(w, httptest.NewRequest(http.MethodGet, "/events", nil))
+	if !errors.Is(err, ErrStreamingUnsupported) || !errors.Is(w.err, ErrStreamingUnsupported) {
+		t.Fatalf("expected ErrStreamingUnsupported, got: %v", err)
+	}
+
+	if rec.Code != http.StatusOK || rec.Header().Get("Content-Type") != "" {
+		t.Fatal("expected nothing to be written")
+	}
+}
@@ -0,0 +1,333 @@
+package httphelpers
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"html"
+	"io"
+	"io/fs"
+	"net/http"
+	"net/url"
+	"path"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// hashedAsset matches filenames with a content hash, e.g. "app.3f2a9c1b.js" or
+// "app-3f2a9c1b.js".
+var hashedAsset = regexp.MustCompile(`[.-][0-9a-fA-F]{8,}\.[^.]+$`)
+
+// StaticOption is an option used to configure StaticFiles and SPA.
+type StaticOption func(*staticOptions)
+
+type staticOptions struct {
+	immutable        func(name string) bool
+	cacheControl     string
+	directoryListing bool
+	excludedPrefixes []string
+}
+
+func newStaticOptions(opts ...StaticOption) *staticOptions {
+	o := &staticOptions{
+		immutable:    hashedAsset.MatchString,
+		cacheControl: "no-cache",
+	}
+
+	for _, opt := range opts {
+		opt(o)
+	}
+
+	return o
+}
+
+// WithImmutable sets the function deciding if a file is a hashed asset that
+// never changes. Defaults to files with at least 8 hex characters before the
+// extension, e.g. "app.3f2a9c1b.js".
+func WithImmutable(fn func(name string) bool) StaticOption {
+	return func(o *staticOptions) {
+		o.immutable = fn
+	}
+}
+
+// WithCacheControl sets the Cache-Control header for files that aren't hashed
+// assets. Defaults to "no-cache" so clients revalidate with the ETag.
+func WithCacheControl(cacheControl string) StaticOption {
+	return func(o *staticOptions) {
+		o.cacheControl = cacheControl
+	}
+}
+
+// WithExcludedPrefixes sets the path prefixes that SPA doesn't serve the
+// index.html for, e.g. "/api/".
+func WithExcludedPrefixes(prefixes ...string) StaticOption {
+	return func(o *staticOptions) {
+		o.excludedPrefixes = append(o.excludedPrefixes, prefixes...)
+	}
+}
+
+// WithDirectoryListing lists the files in directories without an index.html.
+// Directories are not found by default.
+func WithDirectoryListing() StaticOption {
+	return func(o *staticOptions) {
+		o.directoryListing = true
+	}
+}
+
+// StaticFiles returns a handler serving the files in root, e.g. an embed.FS.
+// Use http.StripPrefix to serve the files under a prefix. Only GET and HEAD
+// are allowed.
+//
+// Paths are cleaned and files and directories starting with a dot are never
+// served so neither "../" nor ".git" can be used to read other files.
+// Directories serve their index.html, if any.
+//
+// Every file gets a strong ETag from its content, so conditional and range
+// requests are handled by http.ServeContent, and hashed assets are cached as
+// immutable for a year. The ETag is calculated once per file and
+// modification time.
+func StaticFiles(root fs.FS, opts ...StaticOption) http.Handler {
+	s := &staticFiles{root: root, options: newStaticOptions(opts...)}
+
+	return http.HandlerFunc(s.serveHTTP)
+}
+
+// SPA returns a handler serving a single-page application. Files in root are
+// served like with StaticFiles and all other paths get the index.html in the
+// root so the application can route them on the client. Paths with a file
+// extension, e.g. a missing "app.js", and paths starting with a prefix
+// excluded with WithExcludedPrefixes, e.g. "/api/", aren't found instead.
+func SPA(root fs.FS, opts ...StaticOption) http.Handler {
+	s := &staticFiles{root: root, options: newStaticOptions(opts...)}
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet && r.Method != http.MethodHead ||
+			path.Ext(r.URL.Path) != "" ||
+			s.excluded(r.URL.Path) ||
+			s.exists(r.URL.Path) {
+			s.serveHTTP(w, r)
+			return
+		}
+
+		info, err := fs.Stat(root, "index.html")
+		if err != nil {
+			http.NotFound(w, r)
+			return
+		}
+
+		s.serveFile(w, r, "index.html", info)
+	})
+}
+
+type staticFiles struct {
+	root    fs.FS
+	options *staticOptions
+	etags   sync.Map
+}
+
+type etagKey struct {
+	name    string
+	size    int64
+	modTime time.Time
+}
+
+func (s *staticFiles) serveHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		w.Header().Set("Allow", "GET, HEAD")
+		http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+
+		return
+	}
+
+	name, ok := staticName(r.URL.Path)
+	if !ok {
+		http.NotFound(w, r)
+		return
+	}
+
+	info, err := fs.Stat(s.root, name)
+	if err != nil {
+		http.NotFound(w, r)
+		return
+	}
+
+	if info.IsDir() {
+		// Relative links in the index and listing need the trailing slash.
+		if !strings.HasSuffix(r.URL.Path, "/") {
+			http.Redirect(w, r, path.Base(r.URL.Path)+"/", http.StatusMovedPermanently)
+			return
+		}
+
+		s.serveDir(w, r, name)
+
+		return
+	}
+
+	s.serveFile(w, r, name, info)
+}
+
+func (s *staticFiles) serveDir(w http.ResponseWriter, r *http.Request, name string) {
+	index := path.Join(name, "index.html")
+
+	if info, err := fs.Stat(s.root, index); err == nil && !info.IsDir() {
+		s.serveFile(w, r, index, info)
+		return
+	}
+
+	if !s.options.directoryListing {
+		http.NotFound(w, r)
+		return
+	}
+
+	entries, err := fs.ReadDir(s.root, name)
+	if err != nil {
+		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		return
+	}
+
+	sort.Slice(entries, func(i, j int) bool {
+		return entries[i].Name() < entries[j].Name()
+	})
+
+	buf := &bytes.Buffer{}
+	buf.WriteString("<!doctype html>\n<pre>\n")
+
+	for _, entry := range entries {
+		entryName := entry.Name()
+		if strings.HasPrefix(entryName, ".") {
+			continue
+		}
+
+		if entry.IsDir() {
+			entryName += "/"
+		}
+
+		link := url.URL{Path: entryName}
+		fmt.Fprintf(buf, "<a href=\"%s\">%s</a>\n", html.EscapeString(link.String()), html.EscapeString(entryName))
+	}
+
+	buf.WriteString("</pre>\n")
+
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Header().Set("Cache-Control", "no-cache")
+	_, _ = w.Write(buf.Bytes())
+}
+
+func (s *staticFiles) serveFile(w http.ResponseWriter, r *http.Request, name string, info fs.FileInfo) {
+	f, err := s.root.Open(name)
+	if err != nil {
+		http.NotFound(w, r)
+		return
+	}
+
+	defer f.Close()
+
+	content, ok := f.(io.ReadSeeker)
+	if !ok {
+		data, err := io.ReadAll(f)
+		if err != nil {
+			http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+			return
+		}
+
+		content = bytes.NewReader(data)
+	}
+
+	etag, err := s.etag(name, info, content)
+	if err != nil {
+		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("ETag", etag)
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+
+	if s.options.immutable(path.Base(name)) {
+		w.Header().Set("Cache-Control", "public, max-age=31536000, immutable")
+	} else if s.options.cacheControl != "" {
+		w.Header().Set("Cache-Control", s.options.cacheControl)
+	}
+
+	http.ServeContent(w, r, name, info.ModTime(), content)
+}
+
+// etag returns the ETag for the file, hashing the content the first time the
+// file is served. The content is rewound after hashing.
+func (s *staticFiles) etag(name string, info fs.FileInfo, content io.ReadSeeker) (string, error) {
+	key := etagKey{name: name, size: info.Size(), modTime: info.ModTime()}
+
+	if etag, ok := s.etags.Load(key); ok {
+		return etag.(string), nil
+	}
+
+	h := sha256.New()
+	if _, err := io.Copy(h, content); err != nil {
+		return "", err
+	}
+
+	if _, err := content.Seek(0, io.SeekStart); err != nil {
+		return "", err
+	}
+
+	etag := `"` + hex.EncodeToString(h.Sum(nil)[:16]) + `"`
+	s.etags.Store(key, etag)
+
+	return etag, nil
+}
+
+// excluded returns true if the path starts with an excluded prefix.
+func (s *staticFiles) excluded(urlPath string) bool {
+	for _, prefix := range s.options.excludedPrefixes {
+		if strings.HasPrefix(urlPath, prefix) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// exists returns true if the path is a file, or a directory with an
+// index.html, that can be served.
+func (s *staticFiles) exists(urlPath string) bool {
+	name, ok := staticName(urlPath)
+	if !ok {
+		return false
+	}
+
+	info, err := fs.Stat(s.root, name)
+	if err != nil {
+		return false
+	}
+
+	if info.IsDir() {
+		info, err = fs.Stat(s.root, path.Join(name, "index.html"))
+		return err == nil && !info.IsDir()
+	}
+
+	return true
+}
+
+// staticName returns the name in the file system for the URL path. False is
+// returned for paths that must not be served, such as dotfiles.
+func staticName(urlPath string) (string, bool) {
+	name := strings.TrimPrefix(path.Clean("/"+urlPath), "/")
+	if name == "" {
+		name = "."
+	}
+
+	if !fs.ValidPath(name) {
+		return "", false
+	}
+
+	for _, part := range strings.Split(name, "/") {
+		if part != "." && strings.HasPrefix(part, ".") {
+			return "", false
+		}
+	}
+
+	return name, true
+}
@@ -0,0 +1,176 @@
+package httphelpers
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"testing/fstest"
+)
+
+func Test_StaticFiles(t *testing.T) {
+	fsys := fstest.MapFS{
+		"index.html":               {Data: []byte("<h1>home</h1>")},
+		"assets/app.3f2a9c1b.js":   {Data: []byte("console.log(1)")},
+		"assets/style.css":         {Data: []byte("body{}")},
+		"docs/readme.txt":          {Data: []byte("read me")},
+		".env":                     {Data: []byte("SECRET=1")},
+		"assets/.hidden/secret.js": {Data: []byte("secret")},
+	}
+
+	tests := []struct {
+		name                 string
+		method               string
+		target               string
+		opts                 []StaticOption
+		expectedStatus       int
+		expectedBody         string
+		expectedCacheControl string
+	}{
+		{
+			name:                 "index",
+			target:               "/",
+			expectedStatus:       http.StatusOK,
+			expectedBody:         "<h1>home</h1>",
+			expectedCacheControl: "no-cache",
+		},
+		{
+			name:                 "hashed asset",
+			target:               "/assets/app.3f2a9c1b.js",
+			expectedStatus:       http.StatusOK,
+			expectedBody:         "console.log(1)",
+			expectedCacheControl: "public, max-age=31536000, immutable",
+		},
+		{
+			name:                 "custom cache control",
+			target:               "/assets/style.css",
+			opts:                 []StaticOption{WithCacheControl("max-age=60")},
+			expectedStatus:       http.StatusOK,
+			expectedBody:         "body{}",
+			expectedCacheControl: "max-age=60",
+		},
+		{
+			name:           "traversal",
+			target:         "/assets/../../etc/passwd",
+			expectedStatus: http.StatusNotFound,
+		},
+		{
+			name:           "dotfile",
+			target:         "/.env",
+			expectedStatus: http.StatusNotFound,
+		},
+		{
+			name:           "dot directory",
+			target:         "/assets/.hidden/secret.js",
+			expectedStatus: http.StatusNotFound,
+		},
+		{
+			name:           "directory without index",
+			target:         "/docs/",
+			expectedStatus: http.StatusNotFound,
+		},
+		{
+			name:                 "directory listing",
+			target:               "/assets/",
+			opts:                 []StaticOption{WithDirectoryListing()},
+			expectedStatus:       http.StatusOK,
+			expectedBody:         "<!doctype html>\n<pre>\n<a href=\"app.3f2a9c1b.js\">app.3f2a9c1b.js</a>\n<a href=\"style.css\">style.css</a>\n</pre>\n",
+			expectedCacheControl: "no-cache",
+		},
+		{
+			name:           "directory redirect",
+			target:         "/docs",
+			expectedStatus: http.StatusMovedPermanently,
+		},
+		{
+			name:           "method not allowed",
+			method:         http.MethodPost,
+			target:         "/index.html",
+			expectedStatus: http.StatusMethodNotAllowed,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			method := tc.method
+			if method == "" {
+				method = http.MethodGet
+			}
+
+			rec := httptest.NewRecorder()
+			StaticFiles(fsys, tc.opts...).ServeHTTP(rec, httptest.NewRequest(method, tc.target, nil))
+
+			if rec.Code != tc.expectedStatus {
+				t.Fatalf("unexpected status, got: %d, expected: %d", rec.Code, tc.expectedStatus)
+			}
+
+			if tc.expectedStatus != http.StatusOK {
+				return
+			}
+
+			if rec.Body.String() != tc.expectedBody {
+				t.Fatalf("unexpected body, got: %q, expected: %q", rec.Body.String(), tc.expectedBody)
+			}
+
+			if got := rec.Header().Get("Cache-Control"); got != tc.expectedCacheControl {
+				t.Fatalf("unexpected Cache-Control, got: %s, expected: %s", got, tc.expectedCacheControl)
+			}
+		})
+	}
+}
+
+func Test_StaticFilesETag(t *testing.T) {
+	handler := StaticFiles(fstest.MapFS{
+		"style.css": {Data: []byte("body{}")},
+	})
+
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/style.css", nil))
+
+	etag := rec.Header().Get("ETag")
+	if !strings.HasPrefix(etag, `"`) || len(etag) != 34 {
+		t.Fatalf("unexpected ETag: %s", etag)
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/style.css", nil)
+	req.Header.Set("If-None-Match", etag)
+
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusNotModified {
+		t.Fatalf("unexpected status, got: %d, expected: %d", rec.Code, http.StatusNotModified)
+	}
+}
+
+func Test_SPA(t *testing.T) {
+	handler := SPA(fstest.MapFS{
+		"index.html":             {Data: []byte("<div id=app></div>")},
+		"assets/app.3f2a9c1b.js": {Data: []byte("console.log(1)")},
+	}, WithExcludedPrefixes("/api/"))
+
+	tests := []struct {
+		target         string
+		expectedStatus int
+		expectedBody   string
+	}{
+		{target: "/", expectedStatus: http.StatusOK, expectedBody: "<div id=app></div>"},
+		{target: "/users/1/settings", expectedStatus: http.StatusOK, expectedBody: "<div id=app></div>"},
+		{target: "/assets/app.3f2a9c1b.js", expectedStatus: http.StatusOK, expectedBody: "console.log(1)"},
+		{target: "/assets/missing.js", expectedStatus: http.StatusNotFound},
+		{target: "/api/users", expectedStatus: http.StatusNotFound},
+	}
+
+	for _, tc := range tests {
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, tc.target, nil))
+
+		if rec.Code != tc.expectedStatus {
+			t.Fatalf("unexpected status for %s, got: %d, expected: %d", tc.target, rec.Code, tc.expectedStatus)
+		}
+
+		if tc.expectedBody != "" && rec.Body.String() != tc.expectedBody {
+			t.Fatalf("unexpected body for %s, got: %s, expected: %s", tc.target, rec.Body.String(), tc.expectedBody)
+		}
+	}
+}
@@ -0,0 +1,176 @@
+package tracing
+
+/*
+Minimal distributed tracing with W3C Trace Context propagation. The Tracing
+middleware starts a server span for each incoming request, continuing the
+trace from the traceparent header, and client.Tracing starts a client span for
+each outgoing request with the server span as parent and sends it in the
+traceparent header, and optionally the B3 headers, so a trace spans all
+services. Finished spans are passed to an Exporter, e.g. an adapter to
+OpenTelemetry or a log.
+
+	exporter := func(span tracing.Span) {
+		logger.Info("span", "name", span.Name, "trace_id", span.TraceID, "duration", span.End.Sub(span.Start))
+	}
+
+	handler := middleware.AddMiddlewares(router, middleware.Tracing(exporter))
+	httpClient := &http.Client{
+		Transport: client.WrapTransport(nil, client.Tracing(exporter)),
+	}
+
+The trace of the current request is stored in the context and read with
+httpctx.TraceFrom.
+*/
+
+import (
+	"encoding/hex"
+	"math/rand/v2"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/bombsimon/http-helpers/httpctx"
+)
+
+// Propagation headers.
+const (
+	TraceparentHeader = "traceparent"
+	B3TraceIDHeader   = "X-B3-TraceId"
+	B3SpanIDHeader    = "X-B3-SpanId"
+	B3ParentIDHeader  = "X-B3-ParentSpanId"
+	B3SampledHeader   = "X-B3-Sampled"
+)
+
+// Kind is the kind of a span.
+type Kind string
+
+// Kinds of spans.
+const (
+	KindServer Kind = "server"
+	KindClient Kind = "client"
+)
+
+// Span is a finished span.
+type Span struct {
+	Name         string
+	Kind         Kind
+	TraceID      string
+	SpanID       string
+	ParentSpanID string
+	Start        time.Time
+	End          time.Time
+
+	// Status is the status of the response, 0 if there was none.
+	Status int
+
+	// Err is the error of the request, if any.
+	Err error
+
+	// Attributes holds attributes such as the method and host.
+	Attributes map[string]string
+}
+
+// Exporter receives finished, sampled spans. It's called synchronously when
+// the span ends so it shouldn't block.
+type Exporter func(Span)
+
+// NewTraceID returns a random 16 byte trace ID in hex.
+func NewTraceID() string {
+	return randomHex(16)
+}
+
+// NewSpanID returns a random 8 byte span ID in hex.
+func NewSpanID() string {
+	return randomHex(8)
+}
+
+// Parse parses a traceparent header. The span ID is the ID of the parent span
+// in the calling service.
+func Parse(traceparent string) (httpctx.Trace, bool) {
+	parts := strings.Split(strings.TrimSpace(traceparent), "-")
+	if len(parts) < 4 || len(parts[0]) != 2 || parts[0] == "ff" {
+		return httpctx.Trace{}, false
+	}
+
+	// Version 00 has exactly four fields, later versions may add more.
+	if parts[0] == "00" && len(parts) != 4 {
+		return httpctx.Trace{}, false
+	}
+
+	traceID, spanID, flags := parts[1], parts[2], parts[3]
+	if !isHex(parts[0]) || !isHex(traceID) || !isHex(spanID) || !isHex(flags) ||
+		len(traceID) != 32 || len(spanID) != 16 || len(flags) != 2 ||
+		isZero(traceID) || isZero(spanID) {
+		return httpctx.Trace{}, false
+	}
+
+	flagBits, _ := hex.DecodeString(flags)
+
+	return httpctx.Trace{
+		TraceID: traceID,
+		SpanID:  spanID,
+		Sampled: flagBits[0]&1 == 1,
+	}, true
+}
+
+// Format formats the trace as a version 00 traceparent header.
+func Format(trace httpctx.Trace) string {
+	flags := "00"
+	if trace.Sampled {
+		flags = "01"
+	}
+
+	return "00-" + trace.TraceID + "-" + trace.SpanID + "-" + flags
+}
+
+// Inject sets the traceparent header for the trace.
+func Inject(header http.Header, trace httpctx.Trace) {
+	header.Set(TraceparentHeader, Format(trace))
+}
+
+// InjectB3 sets the B3 multi headers for the trace, used by e.g. Zipkin and
+// older Envoy deployments.
+func InjectB3(header http.Header, trace httpctx.Trace, parentSpanID string) {
+	header.Set(B3TraceIDHeader, trace.TraceID)
+	header.Set(B3SpanIDHeader, trace.SpanID)
+
+	if parentSpanID != "" {
+		header.Set(B3ParentIDHeader, parentSpanID)
+	}
+
+	sampled := "0"
+	if trace.Sampled {
+		sampled = "1"
+	}
+
+	header.Set(B3SampledHeader, sampled)
+}
+
+func randomHex(n int) string {
+	b := make([]byte, n)
+
+	for {
+		for i := range b {
+			b[i] = byte(rand.Uint32())
+		}
+
+		// All zero IDs are invalid.
+		if id := hex.EncodeToString(b); !isZero(id) {
+			return id
+		}
+	}
+}
+
+func isHex(s string) bool {
+	for _, c := range s {
+		if (c < '0' || c > '9') && (c < 'a' || c > 'f') {
+			return false
+		}
+	}
+
+	return true
+}
+
+func isZero(s string) bool {
+	return strings.Trim(s, "0") == ""
+}
@@ -0,0 +1,71 @@
+package tracing
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/bombsimon/http-helpers/httpctx"
+)
+
+func Test_Parse(t *testing.T) {
+	for _, tc := range []struct {
+		traceparent string
+		expected    httpctx.Trace
+		ok          bool
+	}{
+		{
+			traceparent: "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01",
+			expected:    httpctx.Trace{TraceID: "4bf92f3577b34da6a3ce929d0e0e4736", SpanID: "00f067aa0ba902b7", Sampled: true},
+			ok:          true,
+		},
+		{
+			traceparent: "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-00",
+			expected:    httpctx.Trace{TraceID: "4bf92f3577b34da6a3ce929d0e0e4736", SpanID: "00f067aa0ba902b7"},
+			ok:          true,
+		},
+		{
+			traceparent: "01-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01-future",
+			expected:    httpctx.Trace{TraceID: "4bf92f3577b34da6a3ce929d0e0e4736", SpanID: "00f067aa0ba902b7", Sampled: true},
+			ok:          true,
+		},
+		{traceparent: ""},
+		{traceparent: "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01-extra"},
+		{traceparent: "ff-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01"},
+		{traceparent: "00-00000000000000000000000000000000-00f067aa0ba902b7-01"},
+		{traceparent: "00-4bf92f3577b34da6a3ce929d0e0e4736-0000000000000000-01"},
+		{traceparent: "00-4BF92F3577B34DA6A3CE929D0E0E4736-00f067aa0ba902b7-01"},
+		{traceparent: "00-4bf92f3577b34da6a3ce929d0e0e473-00f067aa0ba902b7-01"},
+	} {
+		trace, ok := Parse(tc.traceparent)
+		if ok != tc.ok || trace != tc.expected {
+			t.Fatalf("unexpected result for %q, got: %+v (%t), expected: %+v (%t)", tc.traceparent, trace, ok, tc.expected, tc.ok)
+		}
+
+		if ok && tc.traceparent[:2] == "00" && Format(trace) != tc.traceparent {
+			t.Fatalf("unexpected format, got: %s, expected: %s", Format(trace), tc.traceparent)
+		}
+	}
+}
+
+func Test_Inject(t *testing.T) {
+	trace := httpctx.Trace{TraceID: NewTraceID(), SpanID: NewSpanID(), Sampled: true}
+	header := http.Header{}
+
+	Inject(header, trace)
+	InjectB3(header, trace, "00f067aa0ba902b7")
+
+	if parsed, ok := Parse(header.Get(TraceparentHeader)); !ok || parsed != trace {
+		t.Fatalf("unexpected traceparent: %s", header.Get(TraceparentHeader))
+	}
+
+	for name, expected := range map[string]string{
+		"X-B3-TraceId":      trace.TraceID,
+		"X-B3-SpanId":       trace.SpanID,
+		"X-B3-ParentSpanId": "00f067aa0ba902b7",
+		"X-B3-Sampled":      "1",
+	} {
+		if got := header.Get(name); got != expected {
+			t.Fatalf("unexpected %s, got: %s, expected: %s", name, got, expected)
+		}
+	}
+}
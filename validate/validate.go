@@ -0,0 +1,252 @@
+package validate
+
+/*
+A small struct validator driven by `validate` struct tags. Rules are separated
+by commas and fields are named by their `json` tag in the errors so they can be
+returned to the client as is.
+
+	type CreateUserRequest struct {
+		Name  string   `json:"name" validate:"required,max=64"`
+		Email string   `json:"email" validate:"required,email"`
+		Role  string   `json:"role" validate:"oneof=admin user"`
+		Tags  []string `json:"tags" validate:"max=10"`
+	}
+
+	if err := validate.Struct(req); err != nil {
+		var fieldErrs validate.Errors
+		if errors.As(err, &fieldErrs) {
+			// Return the field errors to the client.
+		}
+	}
+
+Supported rules are required, min, max and len (the value for numbers and the
+length for strings, slices and maps), oneof and email. Nested structs are
+validated and named with dots, e.g. "address.city".
+*/
+
+import (
+	"fmt"
+	"net/mail"
+	"reflect"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// FieldError describes a field failing a rule.
+type FieldError struct {
+	Field   string `json:"field"`
+	Rule    string `json:"rule"`
+	Param   string `json:"param,omitempty"`
+	Message string `json:"message"`
+}
+
+// Errors is returned by Struct with all fields failing validation.
+type Errors []FieldError
+
+func (e Errors) Error() string {
+	messages := make([]string, 0, len(e))
+	for _, fe := range e {
+		messages = append(messages, fe.Field+": "+fe.Message)
+	}
+
+	return strings.Join(messages, ", ")
+}
+
+// Struct validates the struct, or pointer to struct, v. If any field fails
+// validation Errors is returned. Other errors, e.g. an unknown rule, are
+// programming errors.
+func Struct(v interface{}) error {
+	rv := reflect.ValueOf(v)
+	for rv.Kind() == reflect.Pointer {
+		if rv.IsNil() {
+			return nil
+		}
+
+		rv = rv.Elem()
+	}
+
+	if rv.Kind() != reflect.Struct {
+		return fmt.Errorf("validate: expected a struct, got %s", rv.Kind())
+	}
+
+	var errs Errors
+	if err := validateStruct(rv, "", &errs); err != nil {
+		return err
+	}
+
+	if len(errs) > 0 {
+		return errs
+	}
+
+	return nil
+}
+
+func validateStruct(rv reflect.Value, prefix string, errs *Errors) error {
+	for i := 0; i < rv.NumField(); i++ {
+		field := rv.Type().Field(i)
+		if !field.IsExported() {
+			continue
+		}
+
+		name := fieldName(field)
+		if name == "-" {
+			continue
+		}
+
+		value := rv.Field(i)
+
+		for _, rule := range splitRules(field.Tag.Get("validate")) {
+			fe, err := check(value, rule)
+			if err != nil {
+				return fmt.Errorf("validate: field %s: %w", field.Name, err)
+			}
+
+			if fe != nil {
+				fe.Field = prefix + name
+				*errs = append(*errs, *fe)
+
+				// Only report the first failing rule for each field.
+				break
+			}
+		}
+
+		nested := value
+		if nested.Kind() == reflect.Pointer && !nested.IsNil() {
+			nested = nested.Elem()
+		}
+
+		if nested.Kind() == reflect.Struct {
+			if err := validateStruct(nested, prefix+name+".", errs); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+func fieldName(field reflect.StructField) string {
+	name, _, _ := strings.Cut(field.Tag.Get("json"), ",")
+	if name == "" {
+		return field.Name
+	}
+
+	return name
+}
+
+func splitRules(tag string) []string {
+	if tag == "" {
+		return nil
+	}
+
+	return strings.Split(tag, ",")
+}
+
+// check returns a FieldError if the value fails the rule.
+func check(value reflect.Value, rule string) (*FieldError, error) {
+	name, param, _ := strings.Cut(rule, "=")
+	fe := &FieldError{Rule: name, Param: param}
+
+	if name == "required" {
+		if value.IsZero() {
+			fe.Message = "is required"
+			return fe, nil
+		}
+
+		return nil, nil
+	}
+
+	// Nil pointers are only checked by required.
+	for value.Kind() == reflect.Pointer {
+		if value.IsNil() {
+			return nil, nil
+		}
+
+		value = value.Elem()
+	}
+
+	switch name {
+	case "min", "max", "len":
+		limit, err := strconv.ParseFloat(param, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid %s parameter %q", name, param)
+		}
+
+		size, isLength, err := measure(value)
+		if err != nil {
+			return nil, err
+		}
+
+		if ok, message := compare(name, size, limit, isLength); !ok {
+			fe.Message = message + " " + param
+			return fe, nil
+		}
+	case "oneof":
+		options := strings.Fields(param)
+		if !value.IsZero() && !contains(options, fmt.Sprint(value.Interface())) {
+			fe.Message = "must be one of " + strings.Join(options, ", ")
+			return fe, nil
+		}
+	case "email":
+		if value.Kind() != reflect.String {
+			return nil, fmt.Errorf("email requires a string, got %s", value.Kind())
+		}
+
+		if s := value.String(); s != "" {
+			if addr, err := mail.ParseAddress(s); err != nil || addr.Address != s {
+				fe.Message = "must be a valid email address"
+				return fe, nil
+			}
+		}
+	default:
+		return nil, fmt.Errorf("unknown rule %q", name)
+	}
+
+	return nil, nil
+}
+
+// measure returns the value of numbers and the length of strings, slices and
+// maps.
+func measure(value reflect.Value) (float64, bool, error) {
+	switch value.Kind() {
+	case reflect.String:
+		return float64(utf8.RuneCountInString(value.String())), true, nil
+	case reflect.Slice, reflect.Array, reflect.Map:
+		return float64(value.Len()), true, nil
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return float64(value.Int()), false, nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return float64(value.Uint()), false, nil
+	case reflect.Float32, reflect.Float64:
+		return value.Float(), false, nil
+	default:
+		return 0, false, fmt.Errorf("can't measure %s", value.Kind())
+	}
+}
+
+func compare(rule string, size, limit float64, isLength bool) (bool, string) {
+	prefix := "must be"
+	if isLength {
+		prefix = "length must be"
+	}
+
+	switch rule {
+	case "min":
+		return size >= limit, prefix + " at least"
+	case "max":
+		return size <= limit, prefix + " at most"
+	default:
+		return size == limit, prefix
+	}
+}
+
+func contains(options []string, s string) bool {
+	for _, option := range options {
+		if option == s {
+			return true
+		}
+	}
+
+	return false
+}
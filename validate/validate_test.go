@@ -0,0 +1,100 @@
+package validate
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+type address struct {
+	City string `json:"city" validate:"required"`
+}
+
+type user struct {
+	Name    string   `json:"name" validate:"required,min=2,max=5"`
+	Email   string   `json:"email,omitempty" validate:"email"`
+	Age     int      `json:"age" validate:"min=18"`
+	Role    string   `json:"role" validate:"oneof=admin user"`
+	Tags    []string `json:"tags" validate:"max=2"`
+	Code    *string  `json:"code" validate:"len=3"`
+	Address address  `json:"address"`
+	Ignored string   `json:"-" validate:"required"`
+}
+
+func Test_Struct(t *testing.T) {
+	code := "ab"
+
+	tests := []struct {
+		name     string
+		value    interface{}
+		expected Errors
+	}{
+		{
+			name: "valid",
+			value: &user{
+				Name:    "bob",
+				Email:   "bob@example.com",
+				Age:     20,
+				Role:    "admin",
+				Address: address{City: "Stockholm"},
+			},
+		},
+		{
+			name:  "invalid",
+			value: user{Name: "b", Email: "bob", Age: 17, Role: "root", Tags: []string{"a", "b", "c"}, Code: &code},
+			expected: Errors{
+				{Field: "name", Rule: "min", Param: "2", Message: "length must be at least 2"},
+				{Field: "email", Rule: "email", Message: "must be a valid email address"},
+				{Field: "age", Rule: "min", Param: "18", Message: "must be at least 18"},
+				{Field: "role", Rule: "oneof", Param: "admin user", Message: "must be one of admin, user"},
+				{Field: "tags", Rule: "max", Param: "2", Message: "length must be at most 2"},
+				{Field: "code", Rule: "len", Param: "3", Message: "length must be 3"},
+				{Field: "address.city", Rule: "required", Message: "is required"},
+			},
+		},
+		{
+			name:  "only first failing rule is reported",
+			value: user{Age: 18, Address: address{City: "Stockholm"}},
+			expected: Errors{
+				{Field: "name", Rule: "required", Message: "is required"},
+			},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			err := Struct(tc.value)
+			if tc.expected == nil {
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+
+				return
+			}
+
+			var errs Errors
+			if !errors.As(err, &errs) {
+				t.Fatalf("expected Errors, got: %v", err)
+			}
+
+			if !reflect.DeepEqual(errs, tc.expected) {
+				t.Fatalf("unexpected errors, got: %+v, expected: %+v", errs, tc.expected)
+			}
+		})
+	}
+}
+
+func Test_StructInvalidRule(t *testing.T) {
+	err := Struct(struct {
+		Name string `validate:"uppercase"`
+	}{})
+
+	var errs Errors
+	if err == nil || errors.As(err, &errs) {
+		t.Fatalf("expected a non validation error, got: %v", err)
+	}
+
+	if err := Struct("not a struct"); err == nil {
+		t.Fatal("expected error for non struct")
+	}
+}